@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"ds2api/internal/config"
+	"ds2api/internal/deepseek/mockupstream"
+	dsprotocol "ds2api/internal/deepseek/protocol"
+)
+
+func main() {
+	var opts mockupstream.Options
+	addr := "127.0.0.1:7861"
+	var slowSeconds int
+	var chunkDelayMS int
+
+	flag.StringVar(&addr, "addr", addr, "Listen address")
+	flag.StringVar(&opts.SamplesDir, "samples", "tests/raw_stream_samples", "Raw stream samples directory (empty disables replay)")
+	flag.StringVar(&opts.Sample, "sample", "", "Sample id replayed by default (empty uses the scripted reply)")
+	flag.StringVar(&opts.Reply, "reply", mockupstream.DefaultReply, "Scripted reply text")
+	flag.Int64Var(&opts.PowDifficulty, "pow-difficulty", mockupstream.DefaultPowDifficulty, "PoW challenge difficulty")
+	flag.IntVar(&slowSeconds, "slow-first-byte", 2, "Delay in seconds for the slow_first_byte scenario")
+	flag.IntVar(&chunkDelayMS, "chunk-delay-ms", 0, "Delay in milliseconds between streamed SSE blocks")
+	flag.Parse()
+	opts.SlowFirstByte = time.Duration(slowSeconds) * time.Second
+	opts.ChunkDelay = time.Duration(chunkDelayMS) * time.Millisecond
+
+	mock, err := mockupstream.New(opts)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mock,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	config.Logger.Info("starting ds2api mock upstream", "bind", addr, "samples", len(mock.Samples()), "hint", dsprotocol.UpstreamBaseURLEnv+"=http://"+addr)
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		config.Logger.Error("mock upstream stopped unexpectedly", "error", err)
+		os.Exit(1)
+	}
+}
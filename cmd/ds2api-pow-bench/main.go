@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"ds2api/pow"
+)
+
+func main() {
+	algorithm := pow.AlgorithmDeepSeekHashV1
+	difficulty := int64(pow.DefaultDifficulty)
+	rounds := 3
+	parallel := 1
+
+	flag.StringVar(&algorithm, "algorithm", algorithm, "PoW algorithm ("+strings.Join(pow.Algorithms(), ", ")+")")
+	flag.Int64Var(&difficulty, "difficulty", difficulty, "Search range per solve; each solve scans the whole range")
+	flag.IntVar(&rounds, "rounds", rounds, "Solves per goroutine")
+	flag.IntVar(&parallel, "parallel", parallel, "Concurrent goroutines (use the CPU count to measure host throughput)")
+	flag.Parse()
+
+	result, err := pow.Benchmark(context.Background(), algorithm, difficulty, rounds, parallel)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	fmt.Printf("algorithm:   %s\n", result.Algorithm)
+	fmt.Printf("cpus:        %d\n", runtime.NumCPU())
+	fmt.Printf("parallel:    %d x %d rounds, difficulty %d\n", result.Parallel, result.Rounds, result.Difficulty)
+	fmt.Printf("hashes:      %d in %d ms\n", result.Hashes, result.DurationMS)
+	fmt.Printf("throughput:  %.0f H/s (%.0f H/s per goroutine)\n", result.HashesPerSecond, result.PerWorkerHashesPerSecond)
+	fmt.Printf("worst solve: %.0f ms at difficulty %d on one goroutine\n", float64(pow.DefaultDifficulty)/result.PerWorkerHashesPerSecond*1000, pow.DefaultDifficulty)
+}
@@ -14,6 +14,7 @@ import (
 	"ds2api/internal/auth"
 	"ds2api/internal/config"
 	"ds2api/internal/server"
+	"ds2api/internal/tracing"
 	"ds2api/internal/webui"
 )
 
@@ -60,13 +61,35 @@ func main() {
 		}
 	}()
 
-	// Wait for interrupt signal (Ctrl+C / SIGTERM).
+	// Wait for interrupt signal (Ctrl+C / SIGTERM) or POST /admin/drain.
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
-	sig := <-quit
-	config.Logger.Info("shutdown signal received", "signal", sig.String())
+	select {
+	case sig := <-quit:
+		config.Logger.Info("shutdown signal received", "signal", sig.String())
+		app.Drain.Start("signal: " + sig.String())
+	case <-app.Drain.Started():
+		config.Logger.Info("drain requested via admin API")
+	}
 
-	// Graceful shutdown: allow up to 10 seconds for in-flight requests to complete.
+	// Drain: readiness fails and new completions get 503 while in-flight
+	// requests holding account slots finish, up to the drain deadline. A
+	// second signal skips the wait.
+	drainCtx, stopDrain := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-quit:
+			stopDrain()
+		case <-drainCtx.Done():
+		}
+	}()
+	config.Logger.Info("draining in-flight requests", "timeout", app.Drain.Timeout().String(), "active", app.Pool.InUse())
+	if err := app.Drain.Wait(drainCtx); err != nil {
+		config.Logger.Warn("drain ended before in-flight requests finished", "active", app.Pool.InUse(), "error", err)
+	}
+	stopDrain()
+
+	// Graceful shutdown: allow up to 10 seconds for remaining connections.
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -74,6 +97,9 @@ func main() {
 		config.Logger.Error("graceful shutdown failed, forcing exit", "error", err)
 		os.Exit(1)
 	}
+	if err := tracing.Shutdown(ctx); err != nil {
+		config.Logger.Warn("flushing trace spans failed", "error", err)
+	}
 	config.Logger.Info("server gracefully stopped")
 }
 
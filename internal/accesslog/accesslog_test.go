@@ -0,0 +1,107 @@
+package accesslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func serveLogged(t *testing.T, opts Options, status int, annotate func(*Entry)) map[string]any {
+	t.Helper()
+	var buf bytes.Buffer
+	opts.Output = &buf
+	h := Middleware(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		annotate(FromContext(r.Context()))
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("data: ok\n\n"))
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil))
+	if buf.Len() == 0 {
+		return nil
+	}
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("access log is not JSON: %v: %s", err, buf.String())
+	}
+	return line
+}
+
+func TestMiddlewareLogsGatewayContext(t *testing.T) {
+	line := serveLogged(t, Options{SampleRate: 1}, http.StatusOK, func(e *Entry) {
+		e.SetCaller("caller:abc")
+		e.SetAccount("acc@example.com")
+		e.SetRequest("openai_chat", "gpt-4o", "deepseek-chat", true)
+		e.AddRetry()
+		e.AddContinue()
+		e.AddContinue()
+		e.Finish("stop", map[string]any{"prompt_tokens": 12, "completion_tokens": 34})
+	})
+	want := map[string]any{
+		"msg":             "http_request",
+		"status":          float64(200),
+		"surface":         "openai_chat",
+		"caller_id":       "caller:abc",
+		"account_id":      "acc@example.com",
+		"requested_model": "gpt-4o",
+		"resolved_model":  "deepseek-chat",
+		"stream":          true,
+		"prompt_tokens":   float64(12),
+		"output_tokens":   float64(34),
+		"finish_reason":   "stop",
+		"retries":         float64(1),
+		"continues":       float64(2),
+	}
+	for k, v := range want {
+		if line[k] != v {
+			t.Fatalf("%s = %#v, want %#v (line %v)", k, line[k], v, line)
+		}
+	}
+	for _, k := range []string{"ttfb_ms", "latency_ms", "request_id"} {
+		if _, ok := line[k]; !ok {
+			t.Fatalf("missing %s in %v", k, line)
+		}
+	}
+	if _, ok := line["prompt"]; ok {
+		t.Fatalf("prompt must not be logged unless enabled: %v", line)
+	}
+}
+
+func TestMiddlewareSamplesOnlySuccessfulRequests(t *testing.T) {
+	noop := func(*Entry) {}
+	if line := serveLogged(t, Options{SampleRate: 0}, http.StatusOK, noop); line != nil {
+		t.Fatalf("expected sampled-out success, got %v", line)
+	}
+	if line := serveLogged(t, Options{SampleRate: 0}, http.StatusBadGateway, noop); line == nil {
+		t.Fatal("expected errors to bypass sampling")
+	}
+}
+
+func TestPromptLoggingIsCapped(t *testing.T) {
+	line := serveLogged(t, Options{SampleRate: 1, PromptMaxBytes: 5}, http.StatusOK, func(e *Entry) {
+		e.SetPrompt("héllo world")
+		e.Finish("stop", map[string]any{"input_tokens": 3, "output_tokens": 4})
+	})
+	if line["prompt"] != "héll" || line["prompt_truncated"] != true {
+		t.Fatalf("unexpected prompt fields: %v", line)
+	}
+	if line["prompt_tokens"] != nil {
+		t.Fatalf("token fields belong to completion requests only: %v", line)
+	}
+}
+
+func TestSkipAndNilEntry(t *testing.T) {
+	var buf bytes.Buffer
+	h := Middleware(Options{SampleRate: 1, Output: &buf, Skip: func(r *http.Request) bool {
+		return strings.HasPrefix(r.URL.Path, "/admin/")
+	}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).AddRetry()
+		w.WriteHeader(http.StatusOK)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/admin/chat-history", nil))
+	if buf.Len() != 0 {
+		t.Fatalf("expected skipped request to be silent, got %s", buf.String())
+	}
+}
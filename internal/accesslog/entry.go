@@ -0,0 +1,164 @@
+// Package accesslog writes one structured JSON line per completed request,
+// enriched with gateway context that handlers record on the request's Entry.
+package accesslog
+
+import (
+	"context"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+type ctxKey struct{}
+
+// Entry collects gateway context for one request. All methods are safe on a
+// nil Entry, so call sites never need to check whether access logging is on.
+type Entry struct {
+	promptLimit int
+
+	mu              sync.Mutex
+	surface         string
+	callerID        string
+	accountID       string
+	requestedModel  string
+	resolvedModel   string
+	stream          bool
+	promptTokens    int
+	outputTokens    int
+	finishReason    string
+	retries         int
+	continues       int
+	prompt          string
+	promptTruncated bool
+	firstByte       time.Time
+}
+
+func WithEntry(ctx context.Context, e *Entry) context.Context {
+	return context.WithValue(ctx, ctxKey{}, e)
+}
+
+// FromContext returns the request's Entry, or nil when access logging is off.
+func FromContext(ctx context.Context) *Entry {
+	if ctx == nil {
+		return nil
+	}
+	e, _ := ctx.Value(ctxKey{}).(*Entry)
+	return e
+}
+
+// SetCaller records the hashed caller identity.
+func (e *Entry) SetCaller(callerID string) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	e.callerID = callerID
+	e.mu.Unlock()
+}
+
+// SetAccount records the managed account serving the request; a later call
+// after an account switch overwrites it.
+func (e *Entry) SetAccount(accountID string) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	e.accountID = accountID
+	e.mu.Unlock()
+}
+
+// SetRequest records the normalized request shape.
+func (e *Entry) SetRequest(surface, requestedModel, resolvedModel string, stream bool) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	e.surface = surface
+	e.requestedModel = requestedModel
+	e.resolvedModel = resolvedModel
+	e.stream = stream
+	e.mu.Unlock()
+}
+
+// SetPrompt keeps the prompt when prompt logging is enabled, capped at the
+// configured byte limit on a rune boundary.
+func (e *Entry) SetPrompt(prompt string) {
+	if e == nil || e.promptLimit <= 0 {
+		return
+	}
+	truncated := false
+	if len(prompt) > e.promptLimit {
+		cut := e.promptLimit
+		for cut > 0 && !utf8.RuneStart(prompt[cut]) {
+			cut--
+		}
+		prompt = prompt[:cut]
+		truncated = true
+	}
+	e.mu.Lock()
+	e.prompt = prompt
+	e.promptTruncated = truncated
+	e.mu.Unlock()
+}
+
+// Finish records the final outcome. usage may use OpenAI chat
+// (prompt_tokens/completion_tokens) or Responses/Claude
+// (input_tokens/output_tokens) key names.
+func (e *Entry) Finish(finishReason string, usage map[string]any) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if finishReason != "" {
+		e.finishReason = finishReason
+	}
+	if n, ok := usageInt(usage, "prompt_tokens", "input_tokens"); ok {
+		e.promptTokens = n
+	}
+	if n, ok := usageInt(usage, "completion_tokens", "output_tokens"); ok {
+		e.outputTokens = n
+	}
+}
+
+// AddRetry counts a synthetic empty-output retry.
+func (e *Entry) AddRetry() {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	e.retries++
+	e.mu.Unlock()
+}
+
+// AddContinue counts an upstream auto-continue round.
+func (e *Entry) AddContinue() {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	e.continues++
+	e.mu.Unlock()
+}
+
+func (e *Entry) markFirstByte(now time.Time) {
+	e.mu.Lock()
+	if e.firstByte.IsZero() {
+		e.firstByte = now
+	}
+	e.mu.Unlock()
+}
+
+func usageInt(usage map[string]any, keys ...string) (int, bool) {
+	for _, key := range keys {
+		switch v := usage[key].(type) {
+		case int:
+			return v, true
+		case int64:
+			return int(v), true
+		case float64:
+			return int(v), true
+		}
+	}
+	return 0, false
+}
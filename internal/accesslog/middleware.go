@@ -0,0 +1,139 @@
+package accesslog
+
+import (
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"ds2api/internal/tracing"
+)
+
+const (
+	// SampleRateEnv sets the fraction (0-1) of successful requests that are
+	// logged. Responses with status >= 400 are always logged.
+	SampleRateEnv = "DS2API_ACCESS_LOG_SAMPLE_RATE"
+	// PromptMaxBytesEnv enables prompt logging, capped at this many bytes.
+	PromptMaxBytesEnv = "DS2API_ACCESS_LOG_PROMPT_MAX_BYTES"
+)
+
+type Options struct {
+	SampleRate     float64
+	PromptMaxBytes int
+	// Skip excludes noisy requests from the access log entirely.
+	Skip   func(*http.Request) bool
+	Output io.Writer
+}
+
+// OptionsFromEnv reads SampleRateEnv and PromptMaxBytesEnv. Invalid values
+// fall back to logging every request without prompts.
+func OptionsFromEnv() Options {
+	opts := Options{SampleRate: 1}
+	if raw := strings.TrimSpace(os.Getenv(SampleRateEnv)); raw != "" {
+		if v, err := strconv.ParseFloat(raw, 64); err == nil && v >= 0 && v <= 1 {
+			opts.SampleRate = v
+		}
+	}
+	if raw := strings.TrimSpace(os.Getenv(PromptMaxBytesEnv)); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			opts.PromptMaxBytes = n
+		}
+	}
+	return opts
+}
+
+// Middleware attaches an Entry to each request and logs it as JSON once the
+// handler returns.
+func Middleware(opts Options) func(http.Handler) http.Handler {
+	out := opts.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	logger := slog.New(slog.NewJSONHandler(out, nil))
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if opts.Skip != nil && opts.Skip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			entry := &Entry{promptLimit: opts.PromptMaxBytes}
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(firstByteWriter{entry})
+			start := time.Now()
+			defer func() {
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+				if status < http.StatusBadRequest && opts.SampleRate < 1 && rand.Float64() >= opts.SampleRate {
+					return
+				}
+				logger.LogAttrs(r.Context(), slog.LevelInfo, "http_request", entry.attrs(r, status, ww.BytesWritten(), start, time.Now())...)
+			}()
+			next.ServeHTTP(ww, r.WithContext(WithEntry(r.Context(), entry)))
+		})
+	}
+}
+
+// firstByteWriter receives a copy of the response body and only notes when
+// the first byte was written.
+type firstByteWriter struct{ e *Entry }
+
+func (f firstByteWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		f.e.markFirstByte(time.Now())
+	}
+	return len(p), nil
+}
+
+func (e *Entry) attrs(r *http.Request, status, bytes int, start, end time.Time) []slog.Attr {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	attrs := []slog.Attr{
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.String("remote_ip", r.RemoteAddr),
+		slog.Int("status", status),
+		slog.Int("bytes", bytes),
+		slog.Int64("latency_ms", end.Sub(start).Milliseconds()),
+	}
+	if traceID := tracing.TraceIDFromContext(r.Context()); traceID != "" {
+		attrs = append(attrs, slog.String("trace_id", traceID))
+	}
+	if !e.firstByte.IsZero() {
+		attrs = append(attrs, slog.Int64("ttfb_ms", e.firstByte.Sub(start).Milliseconds()))
+	}
+	attrs = appendString(attrs, "surface", e.surface)
+	attrs = appendString(attrs, "caller_id", e.callerID)
+	attrs = appendString(attrs, "account_id", e.accountID)
+	attrs = appendString(attrs, "requested_model", e.requestedModel)
+	attrs = appendString(attrs, "resolved_model", e.resolvedModel)
+	if e.surface != "" {
+		attrs = append(attrs,
+			slog.Bool("stream", e.stream),
+			slog.Int("prompt_tokens", e.promptTokens),
+			slog.Int("output_tokens", e.outputTokens),
+			slog.Int("retries", e.retries),
+			slog.Int("continues", e.continues),
+		)
+	}
+	attrs = appendString(attrs, "finish_reason", e.finishReason)
+	if e.prompt != "" {
+		attrs = append(attrs, slog.String("prompt", e.prompt), slog.Bool("prompt_truncated", e.promptTruncated))
+	}
+	return attrs
+}
+
+func appendString(attrs []slog.Attr, key, value string) []slog.Attr {
+	if value == "" {
+		return attrs
+	}
+	return append(attrs, slog.String(key, value))
+}
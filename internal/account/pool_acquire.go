@@ -2,6 +2,8 @@ package account
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"ds2api/internal/config"
 )
@@ -17,6 +19,12 @@ func (p *Pool) AcquireWait(ctx context.Context, target string, exclude map[strin
 		ctx = context.Background()
 	}
 	exclude = normalizeExclude(exclude)
+	caller, class := p.resolveCaller(ctx)
+	if class.WaitTimeoutSeconds > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(class.WaitTimeoutSeconds)*time.Second)
+		defer cancel()
+	}
 	for {
 		if ctx.Err() != nil {
 			return config.Account{}, false
@@ -27,21 +35,24 @@ func (p *Pool) AcquireWait(ctx context.Context, target string, exclude map[strin
 			p.mu.Unlock()
 			return acc, true
 		}
-		if !p.canQueueLocked(target, exclude) {
+		if !p.canQueueLocked(target, exclude, class) {
+			p.countLocked(class.Name, false)
 			p.mu.Unlock()
 			return config.Account{}, false
 		}
-		waiter := make(chan struct{})
-		p.waiters = append(p.waiters, waiter)
+		w := p.enqueueWaiterLocked(caller)
 		p.mu.Unlock()
 
 		select {
 		case <-ctx.Done():
 			p.mu.Lock()
-			p.removeWaiterLocked(waiter)
+			p.removeWaiterLocked(w)
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				p.countLocked(class.Name, true)
+			}
 			p.mu.Unlock()
 			return config.Account{}, false
-		case <-waiter:
+		case <-w.ch:
 		}
 	}
 }
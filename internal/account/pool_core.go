@@ -12,7 +12,11 @@ type Pool struct {
 	mu                     sync.Mutex
 	queue                  []string
 	inUse                  map[string]int
-	waiters                []chan struct{}
+	waiters                []*waiter
+	flowFinish             map[string]float64
+	vtime                  float64
+	seq                    uint64
+	classStats             map[string]*classCounters
 	maxInflightPerAccount  int
 	recommendedConcurrency int
 	maxQueueSize           int
@@ -27,6 +31,8 @@ func NewPool(store *config.Store) *Pool {
 	p := &Pool{
 		store:                 store,
 		inUse:                 map[string]int{},
+		flowFinish:            map[string]float64{},
+		classStats:            map[string]*classCounters{},
 		maxInflightPerAccount: maxPer,
 	}
 	p.Reset()
@@ -99,6 +105,13 @@ func (p *Pool) Release(accountID string) {
 	p.notifyWaiterLocked()
 }
 
+// InUse returns the number of occupied in-flight slots across all accounts.
+func (p *Pool) InUse() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.currentInUseLocked()
+}
+
 func (p *Pool) Status() map[string]any {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -128,5 +141,68 @@ func (p *Pool) Status() map[string]any {
 		"recommended_concurrency":  p.recommendedConcurrency,
 		"waiting":                  len(p.waiters),
 		"max_queue_size":           p.maxQueueSize,
+		"queue_classes":            p.queueClassesLocked(),
+		"waiting_callers":          p.waitingCallersLocked(),
+	}
+}
+
+// queueClassesLocked reports each scheduling class with its limits, current
+// waiters and rejection counters.
+func (p *Pool) queueClassesLocked() []map[string]any {
+	classes := []config.PriorityClass{{Name: config.DefaultPriorityClass, Weight: 1}}
+	if p.store != nil {
+		classes = p.store.SchedulingClasses()
+	}
+	out := make([]map[string]any, 0, len(classes))
+	for _, class := range classes {
+		stats := classCounters{}
+		if c := p.classStats[class.Name]; c != nil {
+			stats = *c
+		}
+		out = append(out, map[string]any{
+			"name":                 class.Name,
+			"weight":               class.Weight,
+			"max_queue":            class.MaxQueue,
+			"wait_timeout_seconds": class.WaitTimeoutSeconds,
+			"waiting":              p.classWaitingLocked(class.Name),
+			"rejected":             stats.Rejected,
+			"timed_out":            stats.TimedOut,
+		})
+	}
+	return out
+}
+
+// waitingCallersLocked groups current waiters by caller, most waiters first.
+func (p *Pool) waitingCallersLocked() []map[string]any {
+	type group struct {
+		caller  Caller
+		waiting int
+	}
+	groups := map[string]*group{}
+	order := []string{}
+	for _, w := range p.waiters {
+		g := groups[w.caller.ID]
+		if g == nil {
+			g = &group{caller: w.caller}
+			groups[w.caller.ID] = g
+			order = append(order, w.caller.ID)
+		}
+		g.waiting++
+	}
+	sort.SliceStable(order, func(i, j int) bool { return groups[order[i]].waiting > groups[order[j]].waiting })
+	out := make([]map[string]any, 0, len(order))
+	for _, id := range order {
+		g := groups[id]
+		name := g.caller.Name
+		if name == "" {
+			name = g.caller.ID
+		}
+		out = append(out, map[string]any{
+			"caller":  name,
+			"class":   g.caller.Class,
+			"weight":  g.caller.Weight,
+			"waiting": g.waiting,
+		})
 	}
+	return out
 }
@@ -0,0 +1,101 @@
+package account
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"ds2api/internal/config"
+)
+
+func newPriorityPoolForTest(t *testing.T) *Pool {
+	t.Helper()
+	t.Setenv("DS2API_ACCOUNT_MAX_INFLIGHT", "1")
+	t.Setenv("DS2API_ACCOUNT_MAX_QUEUE", "10")
+	t.Setenv("DS2API_CONFIG_JSON", `{
+		"keys":["k1"],
+		"accounts":[{"email":"acc1@example.com","token":"token1"}],
+		"scheduling":{"default_class":"interactive","classes":[
+			{"name":"interactive","weight":1},
+			{"name":"batch","weight":1,"max_queue":1,"wait_timeout_seconds":1}
+		]}
+	}`)
+	return NewPool(config.LoadStore())
+}
+
+func TestPoolWakesWaitersFairlyAcrossCallers(t *testing.T) {
+	pool := newPriorityPoolForTest(t)
+	if _, ok := pool.Acquire("", nil); !ok {
+		t.Fatal("expected initial acquire")
+	}
+
+	served := make(chan string, 4)
+	enqueue := func(caller, label string, waiting int) {
+		ctx := WithCaller(context.Background(), Caller{ID: caller, Name: caller})
+		go func() {
+			if _, ok := pool.AcquireWait(ctx, "", nil); ok {
+				served <- label
+			}
+		}()
+		waitForWaitingCount(t, pool, waiting)
+	}
+	enqueue("bulk", "bulk-1", 1)
+	enqueue("bulk", "bulk-2", 2)
+	enqueue("bulk", "bulk-3", 3)
+	enqueue("user", "user-1", 4)
+
+	callers := pool.Status()["waiting_callers"].([]map[string]any)
+	if len(callers) != 2 || callers[0]["caller"] != "bulk" || callers[0]["waiting"] != 3 {
+		t.Fatalf("unexpected waiting callers: %#v", callers)
+	}
+
+	var order []string
+	for range 4 {
+		pool.Release("acc1@example.com")
+		select {
+		case label := <-served:
+			order = append(order, label)
+		case <-time.After(time.Second):
+			t.Fatalf("waiter not served, order so far %v", order)
+		}
+	}
+	want := []string{"bulk-1", "user-1", "bulk-2", "bulk-3"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("expected fair order %v, got %v", want, order)
+		}
+	}
+}
+
+func TestPoolClassQueueLimitAndWaitTimeout(t *testing.T) {
+	pool := newPriorityPoolForTest(t)
+	if _, ok := pool.Acquire("", nil); !ok {
+		t.Fatal("expected initial acquire")
+	}
+	batch := WithCaller(context.Background(), Caller{ID: "job", Class: "batch"})
+
+	done := make(chan bool, 1)
+	go func() {
+		_, ok := pool.AcquireWait(batch, "", nil)
+		done <- ok
+	}()
+	waitForWaitingCount(t, pool, 1)
+
+	if _, ok := pool.AcquireWait(batch, "", nil); ok {
+		t.Fatal("expected the second batch waiter to be rejected by the class queue limit")
+	}
+	select {
+	case ok := <-done:
+		if ok {
+			t.Fatal("expected the batch waiter to time out")
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("batch waiter did not time out")
+	}
+
+	for _, class := range pool.Status()["queue_classes"].([]map[string]any) {
+		if class["name"] == "batch" && (class["rejected"] != int64(1) || class["timed_out"] != int64(1) || class["waiting"] != 0) {
+			t.Fatalf("unexpected batch class status: %#v", class)
+		}
+	}
+}
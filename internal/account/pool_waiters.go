@@ -1,6 +1,66 @@
 package account
 
-func (p *Pool) canQueueLocked(target string, exclude map[string]bool) bool {
+import (
+	"context"
+
+	"ds2api/internal/config"
+)
+
+// Caller identifies who is waiting for an account. Waiters are woken by
+// weighted fair queuing across callers: each caller's requests get virtual
+// finish tags spaced 1/weight apart, and the smallest tag is woken first, so
+// a caller with many queued requests cannot starve the others.
+type Caller struct {
+	ID     string
+	Name   string
+	Class  string
+	Weight int
+}
+
+type callerCtxKey struct{}
+
+// WithCaller attaches the scheduling identity used by AcquireWait.
+func WithCaller(ctx context.Context, c Caller) context.Context {
+	return context.WithValue(ctx, callerCtxKey{}, c)
+}
+
+func CallerFromContext(ctx context.Context) (Caller, bool) {
+	if ctx == nil {
+		return Caller{}, false
+	}
+	c, ok := ctx.Value(callerCtxKey{}).(Caller)
+	return c, ok
+}
+
+type waiter struct {
+	ch     chan struct{}
+	caller Caller
+	class  string
+	start  float64
+	finish float64
+	seq    uint64
+}
+
+type classCounters struct {
+	Rejected int64
+	TimedOut int64
+}
+
+// resolveCaller fills in the class and weight used for ctx's caller.
+func (p *Pool) resolveCaller(ctx context.Context) (Caller, config.PriorityClass) {
+	c, _ := CallerFromContext(ctx)
+	class := config.PriorityClass{Name: config.DefaultPriorityClass, Weight: 1}
+	if p.store != nil {
+		class = p.store.SchedulingClass(c.Class)
+	}
+	c.Class = class.Name
+	if c.Weight <= 0 {
+		c.Weight = class.Weight
+	}
+	return c, class
+}
+
+func (p *Pool) canQueueLocked(target string, exclude map[string]bool, class config.PriorityClass) bool {
 	if target != "" {
 		if exclude[target] {
 			return false
@@ -12,32 +72,95 @@ func (p *Pool) canQueueLocked(target string, exclude map[string]bool) bool {
 	if p.maxQueueSize <= 0 {
 		return false
 	}
-	return len(p.waiters) < p.maxQueueSize
+	if len(p.waiters) >= p.maxQueueSize {
+		return false
+	}
+	return class.MaxQueue <= 0 || p.classWaitingLocked(class.Name) < class.MaxQueue
+}
+
+func (p *Pool) classWaitingLocked(class string) int {
+	n := 0
+	for _, w := range p.waiters {
+		if w.class == class {
+			n++
+		}
+	}
+	return n
+}
+
+func (p *Pool) enqueueWaiterLocked(c Caller) *waiter {
+	start := max(p.vtime, p.flowFinish[c.ID])
+	p.seq++
+	w := &waiter{
+		ch:     make(chan struct{}),
+		caller: c,
+		class:  c.Class,
+		start:  start,
+		finish: start + 1/float64(c.Weight),
+		seq:    p.seq,
+	}
+	p.flowFinish[c.ID] = w.finish
+	p.waiters = append(p.waiters, w)
+	return w
 }
 
+// notifyWaiterLocked wakes the waiter with the smallest finish tag, falling
+// back to arrival order on ties.
 func (p *Pool) notifyWaiterLocked() {
 	if len(p.waiters) == 0 {
 		return
 	}
-	waiter := p.waiters[0]
-	p.waiters = p.waiters[1:]
-	close(waiter)
+	best := 0
+	for i, w := range p.waiters[1:] {
+		b := p.waiters[best]
+		if w.finish < b.finish || (w.finish == b.finish && w.seq < b.seq) {
+			best = i + 1
+		}
+	}
+	w := p.waiters[best]
+	p.waiters = append(p.waiters[:best], p.waiters[best+1:]...)
+	p.vtime = w.finish
+	for id, finish := range p.flowFinish {
+		if finish <= p.vtime {
+			delete(p.flowFinish, id)
+		}
+	}
+	close(w.ch)
 }
 
-func (p *Pool) removeWaiterLocked(waiter chan struct{}) bool {
+func (p *Pool) removeWaiterLocked(target *waiter) bool {
 	for i, w := range p.waiters {
-		if w != waiter {
+		if w != target {
 			continue
 		}
 		p.waiters = append(p.waiters[:i], p.waiters[i+1:]...)
+		// Give the slot back to the caller if nothing was queued after it.
+		if p.flowFinish[w.caller.ID] == w.finish {
+			p.flowFinish[w.caller.ID] = w.start
+		}
 		return true
 	}
 	return false
 }
 
 func (p *Pool) drainWaitersLocked() {
-	for _, waiter := range p.waiters {
-		close(waiter)
+	for _, w := range p.waiters {
+		close(w.ch)
 	}
 	p.waiters = nil
+	p.flowFinish = map[string]float64{}
+	p.vtime = 0
+}
+
+func (p *Pool) countLocked(class string, timedOut bool) {
+	c := p.classStats[class]
+	if c == nil {
+		c = &classCounters{}
+		p.classStats[class] = c
+	}
+	if timedOut {
+		c.TimedOut++
+	} else {
+		c.Rejected++
+	}
 }
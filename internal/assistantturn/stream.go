@@ -33,6 +33,7 @@ type AccumulatorOptions struct {
 	ThinkingEnabled       bool
 	SearchEnabled         bool
 	StripReferenceMarkers bool
+	Limits                sse.OutputLimits
 }
 
 func NewAccumulator(opts AccumulatorOptions) *Accumulator {
@@ -41,6 +42,7 @@ func NewAccumulator(opts AccumulatorOptions) *Accumulator {
 			ThinkingEnabled:       opts.ThinkingEnabled,
 			SearchEnabled:         opts.SearchEnabled,
 			StripReferenceMarkers: opts.StripReferenceMarkers,
+			Limiter:               sse.NewOutputLimiter(opts.Limits),
 		},
 	}
 }
@@ -62,3 +64,28 @@ func (a *Accumulator) Snapshot() (rawText, text, rawThinking, thinking, detectio
 		a.inner.Thinking.String(),
 		a.inner.ToolDetectionThinking.String()
 }
+
+// CitationMarks returns where stripped citation markers sat in the visible
+// text accumulated so far.
+func (a *Accumulator) CitationMarks() []shared.CitationMark {
+	if a == nil {
+		return nil
+	}
+	return a.inner.CitationMarks
+}
+
+// Flush releases text held back for stop-sequence matching.
+func (a *Accumulator) Flush() shared.StreamAccumulatorResult {
+	if a == nil {
+		return shared.StreamAccumulatorResult{}
+	}
+	return a.inner.Flush()
+}
+
+// Limit reports which local output limit ended the stream, if any.
+func (a *Accumulator) Limit() (sse.LimitReason, string) {
+	if a == nil {
+		return sse.LimitNone, ""
+	}
+	return a.inner.Limiter.Reason(), a.inner.Limiter.StopSequence()
+}
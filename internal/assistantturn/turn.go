@@ -17,6 +17,7 @@ const (
 	StopReasonStop          StopReason = "stop"
 	StopReasonToolCalls     StopReason = "tool_calls"
 	StopReasonContentFilter StopReason = "content_filter"
+	StopReasonLength        StopReason = "length"
 	StopReasonError         StopReason = "error"
 )
 
@@ -44,11 +45,17 @@ type Turn struct {
 	ToolCalls         []toolcall.ParsedToolCall
 	ParsedToolCalls   toolcall.ToolCallParseResult
 	CitationLinks     map[int]string
+	Citations         []sse.Citation
+	CitationSpans     []shared.CitationSpan
 	ContentFilter     bool
 	ResponseMessageID int
 	StopReason        StopReason
-	Usage             Usage
-	Error             *OutputError
+	// LimitReason is set when local stop/max-token enforcement ended the
+	// turn; StopSequence is the matched stop string.
+	LimitReason  sse.LimitReason
+	StopSequence string
+	Usage        Usage
+	Error        *OutputError
 }
 
 type FinalizeOptions struct {
@@ -84,24 +91,36 @@ type StreamSnapshot struct {
 	DetectionThinking     string
 	ContentFilter         bool
 	CitationLinks         map[int]string
+	Citations             []sse.Citation
+	CitationMarks         []shared.CitationMark
 	ResponseMessageID     int
 	AlreadyEmittedCalls   bool
 	AdditionalToolCalls   []toolcall.ParsedToolCall
 	AlreadyEmittedToolRaw bool
+	LimitReason           sse.LimitReason
+	StopSequence          string
 }
 
 func BuildTurnFromCollected(result sse.CollectResult, opts BuildOptions) Turn {
 	thinking := shared.CleanVisibleOutput(result.Thinking, opts.StripReferenceMarkers)
-	text := shared.CleanVisibleOutput(result.Text, opts.StripReferenceMarkers)
+	text := shared.CleanVisibleOutput(result.Text, opts.StripReferenceMarkers && !opts.SearchEnabled)
+	citations := resolveCitations(result.Citations, result.CitationLinks)
+	var spans []shared.CitationSpan
 	if opts.SearchEnabled {
-		text = shared.ReplaceCitationMarkersWithLinks(text, result.CitationLinks)
+		if opts.StripReferenceMarkers {
+			var marks []shared.CitationMark
+			text, marks = shared.StripCitationMarkers(text)
+			spans = shared.CitationSpansFromMarks(text, marks, citations)
+		} else {
+			text, spans = shared.LinkCitationMarkers(text, citations)
+		}
 	}
 
 	parsed := shared.DetectAssistantToolCalls(result.Text, text, result.Thinking, result.ToolDetectionThinking, opts.ToolNames)
 	calls := toolcall.NormalizeParsedToolCallsForSchemas(parsed.Calls, opts.ToolsRaw)
 	parsed.Calls = calls
 
-	stopReason := StopReasonStop
+	stopReason := limitStopReason(result.LimitReason)
 	if result.ContentFilter {
 		stopReason = StopReasonContentFilter
 	}
@@ -120,9 +139,13 @@ func BuildTurnFromCollected(result sse.CollectResult, opts BuildOptions) Turn {
 		ToolCalls:         calls,
 		ParsedToolCalls:   parsed,
 		CitationLinks:     result.CitationLinks,
+		Citations:         citations,
+		CitationSpans:     spans,
 		ContentFilter:     result.ContentFilter,
 		ResponseMessageID: result.ResponseMessageID,
 		StopReason:        stopReason,
+		LimitReason:       result.LimitReason,
+		StopSequence:      result.StopSequence,
 	}
 	turn.Usage = BuildUsage(opts.Model, opts.Prompt, thinking, text, opts.RefFileTokens)
 	turn.Error = ValidateTurn(turn, opts.ToolChoice)
@@ -135,8 +158,14 @@ func BuildTurnFromCollected(result sse.CollectResult, opts BuildOptions) Turn {
 func BuildTurnFromStreamSnapshot(snapshot StreamSnapshot, opts BuildOptions) Turn {
 	thinking := shared.CleanVisibleOutput(snapshot.VisibleThinking, opts.StripReferenceMarkers)
 	text := shared.CleanVisibleOutput(snapshot.VisibleText, opts.StripReferenceMarkers)
+	citations := resolveCitations(snapshot.Citations, snapshot.CitationLinks)
+	var spans []shared.CitationSpan
 	if opts.SearchEnabled {
-		text = shared.ReplaceCitationMarkersWithLinks(text, snapshot.CitationLinks)
+		if opts.StripReferenceMarkers {
+			spans = shared.CitationSpansFromMarks(text, snapshot.CitationMarks, citations)
+		} else {
+			text, spans = shared.LinkCitationMarkers(text, citations)
+		}
 	}
 
 	parsed := shared.DetectAssistantToolCalls(snapshot.RawText, text, snapshot.RawThinking, snapshot.DetectionThinking, opts.ToolNames)
@@ -147,7 +176,7 @@ func BuildTurnFromStreamSnapshot(snapshot StreamSnapshot, opts BuildOptions) Tur
 	calls = toolcall.NormalizeParsedToolCallsForSchemas(calls, opts.ToolsRaw)
 	parsed.Calls = calls
 
-	stopReason := StopReasonStop
+	stopReason := limitStopReason(snapshot.LimitReason)
 	if snapshot.ContentFilter {
 		stopReason = StopReasonContentFilter
 	}
@@ -166,9 +195,13 @@ func BuildTurnFromStreamSnapshot(snapshot StreamSnapshot, opts BuildOptions) Tur
 		ToolCalls:         calls,
 		ParsedToolCalls:   parsed,
 		CitationLinks:     snapshot.CitationLinks,
+		Citations:         citations,
+		CitationSpans:     spans,
 		ContentFilter:     snapshot.ContentFilter,
 		ResponseMessageID: snapshot.ResponseMessageID,
 		StopReason:        stopReason,
+		LimitReason:       snapshot.LimitReason,
+		StopSequence:      snapshot.StopSequence,
 	}
 	turn.Usage = BuildUsage(opts.Model, opts.Prompt, thinking, text, opts.RefFileTokens)
 	if !snapshot.AlreadyEmittedCalls && !snapshot.AlreadyEmittedToolRaw {
@@ -180,6 +213,22 @@ func BuildTurnFromStreamSnapshot(snapshot StreamSnapshot, opts BuildOptions) Tur
 	return turn
 }
 
+func limitStopReason(reason sse.LimitReason) StopReason {
+	if reason == sse.LimitMaxTokens {
+		return StopReasonLength
+	}
+	return StopReasonStop
+}
+
+// resolveCitations prefers the structured sources and falls back to bare
+// links for callers that only carry the index->URL map.
+func resolveCitations(citations []sse.Citation, links map[int]string) []sse.Citation {
+	if len(citations) > 0 {
+		return citations
+	}
+	return shared.CitationsFromLinks(links)
+}
+
 func BuildUsage(model, prompt, thinking, text string, refFileTokens int) Usage {
 	inputTokens := util.CountPromptTokens(prompt, model) + refFileTokens
 	reasoningTokens := util.CountOutputTokens(thinking, model)
@@ -203,7 +252,8 @@ func ValidateTurn(turn Turn, policy promptcompat.ToolChoicePolicy) *OutputError
 	if len(turn.ToolCalls) > 0 {
 		return nil
 	}
-	if strings.TrimSpace(turn.Text) != "" {
+	// A stop sequence or token cap may legitimately leave no visible text.
+	if strings.TrimSpace(turn.Text) != "" || turn.LimitReason != sse.LimitNone {
 		return nil
 	}
 	status, message, code := UpstreamEmptyOutputDetail(turn.ContentFilter, turn.Text, turn.Thinking)
@@ -227,6 +277,7 @@ func UpstreamEmptyOutputDetail(contentFilter bool, text, thinking string) (int,
 func ShouldRetryEmptyOutput(turn Turn, attempts, maxAttempts int) bool {
 	return attempts < maxAttempts &&
 		!turn.ContentFilter &&
+		turn.LimitReason == sse.LimitNone &&
 		len(turn.ToolCalls) == 0 &&
 		strings.TrimSpace(turn.Text) == ""
 }
@@ -279,6 +330,8 @@ func FinishReason(turn Turn) string {
 		return "tool_calls"
 	case StopReasonContentFilter:
 		return "content_filter"
+	case StopReasonLength:
+		return "length"
 	default:
 		return "stop"
 	}
@@ -139,3 +139,20 @@ func TestFinalizeTurnContentFilterOutcome(t *testing.T) {
 		t.Fatalf("expected content filter failure, got %#v", outcome)
 	}
 }
+
+func TestBuildTurnFromCollectedReportsCitationSpans(t *testing.T) {
+	turn := BuildTurnFromCollected(sse.CollectResult{
+		Text:      "Paris is big.[citation:1]",
+		Citations: []sse.Citation{{Index: 1, URL: "https://example.com/p", Title: "Paris"}},
+	}, BuildOptions{Model: "deepseek-v4-flash-search", Prompt: "prompt", SearchEnabled: true})
+	if turn.Text != "Paris is big.[1](https://example.com/p)" {
+		t.Fatalf("text mismatch: %q", turn.Text)
+	}
+	if len(turn.Citations) != 1 || len(turn.CitationSpans) != 1 {
+		t.Fatalf("expected one citation and span, got %#v %#v", turn.Citations, turn.CitationSpans)
+	}
+	span := turn.CitationSpans[0]
+	if span.Start != 0 || span.End != len(turn.Text) || span.Citation.Title != "Paris" {
+		t.Fatalf("unexpected span: %#v", span)
+	}
+}
@@ -75,6 +75,10 @@ func CreateJWT(expireHours int) (string, error) {
 }
 
 func CreateJWTWithStore(expireHours int, store AdminConfigReader) (string, error) {
+	return createAdminJWT(expireHours, store, nil, 0)
+}
+
+func createAdminJWT(expireHours int, store AdminConfigReader, claims map[string]any, subjectValidAfter int64) (string, error) {
 	if expireHours <= 0 {
 		expireHours = jwtExpireHours(store)
 	}
@@ -86,9 +90,15 @@ func CreateJWTWithStore(expireHours int, store AdminConfigReader) (string, error
 			issuedAt = validAfter + 1
 		}
 	}
+	if subjectValidAfter >= issuedAt {
+		issuedAt = subjectValidAfter + 1
+	}
 	expireAt := time.Unix(issuedAt, 0).Add(time.Duration(expireHours) * time.Hour).Unix()
 	header := map[string]any{"alg": "HS256", "typ": "JWT"}
 	payload := map[string]any{"iat": issuedAt, "exp": expireAt, "role": "admin"}
+	for k, v := range claims {
+		payload[k] = v
+	}
 	h, _ := json.Marshal(header)
 	p, _ := json.Marshal(payload)
 	headerB64 := rawB64Encode(h)
@@ -124,6 +134,11 @@ func VerifyJWTWithStore(token string, store AdminConfigReader) (map[string]any,
 	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
 		return nil, errors.New("invalid payload")
 	}
+	// Other signed blobs (e.g. OIDC login state) share the secret but are
+	// never admin sessions.
+	if role, _ := payload["role"].(string); role != "admin" {
+		return nil, errors.New("invalid token type")
+	}
 	exp, _ := payload["exp"].(float64)
 	if int64(exp) < time.Now().Unix() {
 		return nil, errors.New("token expired")
@@ -145,21 +160,8 @@ func VerifyAdminRequest(r *http.Request) error {
 }
 
 func VerifyAdminRequestWithStore(r *http.Request, store AdminConfigReader) error {
-	authHeader := strings.TrimSpace(r.Header.Get("Authorization"))
-	if !strings.HasPrefix(strings.ToLower(authHeader), "bearer ") {
-		return errors.New("authentication required")
-	}
-	token := strings.TrimSpace(authHeader[7:])
-	if token == "" {
-		return errors.New("authentication required")
-	}
-	if VerifyAdminCredential(token, store) {
-		return nil
-	}
-	if _, err := VerifyJWTWithStore(token, store); err == nil {
-		return nil
-	}
-	return errors.New("invalid credentials")
+	_, err := AuthenticateAdminRequest(r, store)
+	return err
 }
 
 func VerifyAdminCredential(candidate string, store AdminConfigReader) bool {
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"ds2api/internal/config"
+)
+
+const adminOIDCIdentityProvider = "oidc"
+
+// AdminOIDCReader is implemented by stores that can turn admin SSO on and off.
+type AdminOIDCReader interface {
+	AdminOIDCEnabled() bool
+}
+
+// CreateOIDCAdminJWT mints an admin session for an SSO identity. The role is
+// carried in the token because SSO users have no local record.
+func CreateOIDCAdminJWT(subject, role string, expireHours int, store AdminConfigReader) (string, error) {
+	subject = strings.TrimSpace(subject)
+	role = config.NormalizeAdminRole(role)
+	if subject == "" || role == "" {
+		return "", errors.New("oidc identity is incomplete")
+	}
+	return createAdminJWT(expireHours, store, map[string]any{"sub": subject, "admin_role": role, "idp": adminOIDCIdentityProvider}, 0)
+}
+
+func adminPrincipalFromOIDCJWT(payload map[string]any, store AdminConfigReader) (AdminPrincipal, error) {
+	reader, ok := store.(AdminOIDCReader)
+	if !ok || !reader.AdminOIDCEnabled() {
+		return AdminPrincipal{}, errors.New("single sign-on is disabled")
+	}
+	sub, _ := payload["sub"].(string)
+	roleClaim, _ := payload["admin_role"].(string)
+	role := config.NormalizeAdminRole(roleClaim)
+	if strings.TrimSpace(sub) == "" || role == "" {
+		return AdminPrincipal{}, errors.New("invalid token")
+	}
+	return AdminPrincipal{Subject: strings.TrimSpace(sub), Role: role, Method: adminOIDCIdentityProvider}, nil
+}
+
+// SignState packs short-lived login state (for example an OIDC PKCE verifier)
+// into an HMAC-signed value. Purpose binds the blob to one use; such blobs
+// are rejected by VerifyJWTWithStore.
+func SignState(purpose string, claims map[string]any, ttl time.Duration, store AdminConfigReader) (string, error) {
+	payload := map[string]any{"purpose": purpose, "exp": time.Now().Add(ttl).Unix()}
+	for k, v := range claims {
+		if k == "purpose" || k == "exp" || k == "role" {
+			continue
+		}
+		payload[k] = v
+	}
+	p, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	msg := rawB64Encode([]byte(`{"alg":"HS256","typ":"STATE"}`)) + "." + rawB64Encode(p)
+	return msg + "." + rawB64Encode(signHS256(msg, store)), nil
+}
+
+// VerifyState checks a value produced by SignState for the same purpose.
+func VerifyState(purpose, token string, store AdminConfigReader) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("invalid state")
+	}
+	actual, err := rawB64Decode(parts[2])
+	if err != nil || !hmac.Equal(signHS256(parts[0]+"."+parts[1], store), actual) {
+		return nil, errors.New("invalid state")
+	}
+	raw, err := rawB64Decode(parts[1])
+	if err != nil {
+		return nil, errors.New("invalid state")
+	}
+	var payload map[string]any
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, errors.New("invalid state")
+	}
+	if got, _ := payload["purpose"].(string); got != purpose {
+		return nil, errors.New("invalid state")
+	}
+	exp, _ := payload["exp"].(float64)
+	if int64(exp) < time.Now().Unix() {
+		return nil, errors.New("state expired")
+	}
+	return payload, nil
+}
@@ -84,3 +84,66 @@ func TestVerifyJWTWithStoreSameSecondInvalidationAndRelogin(t *testing.T) {
 		t.Fatalf("expected new token valid after invalidation cutoff: %v", err)
 	}
 }
+
+func TestAdminUserPasswordHashRoundTrip(t *testing.T) {
+	hash, err := HashAdminUserPassword("s3cret-pass")
+	if err != nil {
+		t.Fatalf("hash failed: %v", err)
+	}
+	if !verifyAdminUserPassword("s3cret-pass", hash) {
+		t.Fatal("expected password to verify")
+	}
+	if verifyAdminUserPassword("wrong-pass", hash) {
+		t.Fatal("expected wrong password to fail")
+	}
+	other, _ := HashAdminUserPassword("s3cret-pass")
+	if other == hash {
+		t.Fatal("expected salted hashes to differ")
+	}
+}
+
+func TestAdminRoleAllows(t *testing.T) {
+	if !AdminRoleAllows("owner", "viewer") || !AdminRoleAllows("operator", "operator") {
+		t.Fatal("expected higher or equal roles to pass")
+	}
+	if AdminRoleAllows("viewer", "operator") || AdminRoleAllows("root", "viewer") || AdminRoleAllows("owner", "") {
+		t.Fatal("expected lower or unknown roles to fail")
+	}
+}
+
+func TestAuthenticateAdminRequestAPITokenExpiry(t *testing.T) {
+	plain, hash, err := GenerateAdminAPIToken()
+	if err != nil {
+		t.Fatalf("generate token failed: %v", err)
+	}
+	t.Setenv("DS2API_CONFIG_JSON", `{"admin":{"api_tokens":[{"id":"t1","token_hash":"`+hash+`","role":"viewer","expires_at_unix":1}]}}`)
+	store := config.LoadStore()
+	req, _ := http.NewRequest(http.MethodGet, "/admin/queue/status", nil)
+	req.Header.Set("Authorization", "Bearer "+plain)
+	if _, err := AuthenticateAdminRequest(req, store); err == nil {
+		t.Fatal("expected expired api token to be rejected")
+	}
+	if err := store.Update(func(c *config.Config) error {
+		c.Admin.APITokens[0].ExpiresAtUnix = 0
+		return nil
+	}); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	p, err := AuthenticateAdminRequest(req, store)
+	if err != nil || p.Role != "viewer" || p.TokenID != "t1" {
+		t.Fatalf("unexpected principal %#v err=%v", p, err)
+	}
+}
+
+func TestLegacyJWTWithoutSubjectStaysOwner(t *testing.T) {
+	token, _ := CreateJWT(1)
+	payload, err := VerifyJWT(token)
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	delete(payload, "sub")
+	p, err := AdminPrincipalFromJWT(payload, nil)
+	if err != nil || p.Role != "owner" {
+		t.Fatalf("expected owner principal, got %#v err=%v", p, err)
+	}
+}
@@ -0,0 +1,269 @@
+package auth
+
+import (
+	"context"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ds2api/internal/config"
+)
+
+const (
+	AdminAPITokenPrefix = "ds2a_"
+
+	adminUserHashScheme     = "pbkdf2-sha256"
+	adminUserHashIterations = 120000
+	adminUserHashKeyLen     = 32
+
+	legacyAdminSubject = "admin"
+)
+
+var errAdminTokenExpired = errors.New("token expired")
+
+// AdminIdentityReader is implemented by stores that carry named admin users
+// and scoped admin API tokens. Stores without it only accept the legacy key.
+type AdminIdentityReader interface {
+	AdminUsers() []config.AdminUser
+	AdminAPITokens() []config.AdminAPIToken
+}
+
+// AdminPrincipal describes who is calling an admin endpoint.
+type AdminPrincipal struct {
+	Subject string `json:"username"`
+	Role    string `json:"role"`
+	Method  string `json:"method"`
+	TokenID string `json:"token_id,omitempty"`
+}
+
+type adminPrincipalCtxKey struct{}
+
+func WithAdminPrincipal(ctx context.Context, p AdminPrincipal) context.Context {
+	return context.WithValue(ctx, adminPrincipalCtxKey{}, p)
+}
+
+func AdminPrincipalFromContext(ctx context.Context) (AdminPrincipal, bool) {
+	p, ok := ctx.Value(adminPrincipalCtxKey{}).(AdminPrincipal)
+	return p, ok
+}
+
+// AdminRoleAllows reports whether a caller holding role have may access an
+// endpoint that requires role need. Unknown roles never pass.
+func AdminRoleAllows(have, need string) bool {
+	haveRank := adminRoleRank(have)
+	needRank := adminRoleRank(need)
+	return haveRank > 0 && needRank > 0 && haveRank >= needRank
+}
+
+func adminRoleRank(role string) int {
+	switch config.NormalizeAdminRole(role) {
+	case config.AdminRoleViewer:
+		return 1
+	case config.AdminRoleOperator:
+		return 2
+	case config.AdminRoleOwner:
+		return 3
+	}
+	return 0
+}
+
+func NormalizeAdminUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// HashAdminUserPassword produces a salted PBKDF2 hash suitable for
+// config.AdminUser.PasswordHash.
+func HashAdminUserPassword(raw string) (string, error) {
+	if raw == "" {
+		return "", errors.New("password cannot be empty")
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key, err := pbkdf2.Key(sha256.New, raw, salt, adminUserHashIterations, adminUserHashKeyLen)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s$%d$%s$%s", adminUserHashScheme, adminUserHashIterations, hex.EncodeToString(salt), hex.EncodeToString(key)), nil
+}
+
+func verifyAdminUserPassword(candidate, encoded string) bool {
+	parts := strings.Split(strings.TrimSpace(encoded), "$")
+	if len(parts) != 4 || parts[0] != adminUserHashScheme {
+		// Accept the single-password formats so users can be seeded by hand.
+		return verifyAdminPasswordHash(candidate, encoded)
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return false
+	}
+	salt, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	want, err := hex.DecodeString(parts[3])
+	if err != nil || len(want) == 0 {
+		return false
+	}
+	got, err := pbkdf2.Key(sha256.New, candidate, salt, iterations, len(want))
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
+
+// AuthenticateAdminUser checks a username/password pair against the
+// configured admin users.
+func AuthenticateAdminUser(username, password string, store AdminConfigReader) (AdminPrincipal, bool) {
+	user, ok := findAdminUser(store, username)
+	if !ok || user.Disabled || password == "" {
+		return AdminPrincipal{}, false
+	}
+	if !verifyAdminUserPassword(password, user.PasswordHash) {
+		return AdminPrincipal{}, false
+	}
+	return AdminPrincipal{Subject: NormalizeAdminUsername(user.Username), Role: config.NormalizeAdminRole(user.Role), Method: "password"}, true
+}
+
+// LegacyAdminPrincipal is the identity behind the shared admin key.
+func LegacyAdminPrincipal(method string) AdminPrincipal {
+	return AdminPrincipal{Subject: legacyAdminSubject, Role: config.AdminRoleOwner, Method: method}
+}
+
+// CreateAdminJWT mints an admin session for p. Sessions issued to named users
+// are bound to that user and re-checked against the store on every request.
+func CreateAdminJWT(p AdminPrincipal, expireHours int, store AdminConfigReader) (string, error) {
+	if p.Subject == "" || p.Subject == legacyAdminSubject {
+		return createAdminJWT(expireHours, store, map[string]any{"sub": legacyAdminSubject, "admin_role": config.AdminRoleOwner}, 0)
+	}
+	user, ok := findAdminUser(store, p.Subject)
+	if !ok {
+		return "", errors.New("admin user not found")
+	}
+	claims := map[string]any{"sub": NormalizeAdminUsername(user.Username), "admin_role": config.NormalizeAdminRole(user.Role)}
+	return createAdminJWT(expireHours, store, claims, user.JWTValidAfterUnix)
+}
+
+// AdminPrincipalFromJWT resolves the caller behind a verified JWT payload.
+// Tokens minted before named users existed carry no subject and stay owner.
+func AdminPrincipalFromJWT(payload map[string]any, store AdminConfigReader) (AdminPrincipal, error) {
+	if idp, _ := payload["idp"].(string); idp == adminOIDCIdentityProvider {
+		return adminPrincipalFromOIDCJWT(payload, store)
+	}
+	sub, _ := payload["sub"].(string)
+	sub = NormalizeAdminUsername(sub)
+	if sub == "" || sub == legacyAdminSubject {
+		return LegacyAdminPrincipal("jwt"), nil
+	}
+	user, ok := findAdminUser(store, sub)
+	if !ok || user.Disabled {
+		return AdminPrincipal{}, errors.New("admin user is not active")
+	}
+	if user.JWTValidAfterUnix > 0 {
+		iat, _ := payload["iat"].(float64)
+		if int64(iat) <= user.JWTValidAfterUnix {
+			return AdminPrincipal{}, errAdminTokenExpired
+		}
+	}
+	role := config.NormalizeAdminRole(user.Role)
+	if role == "" {
+		return AdminPrincipal{}, errors.New("admin user has no valid role")
+	}
+	return AdminPrincipal{Subject: sub, Role: role, Method: "jwt"}, nil
+}
+
+// GenerateAdminAPIToken returns a new plaintext admin API token and the hash
+// to persist for it.
+func GenerateAdminAPIToken() (string, string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	plain := AdminAPITokenPrefix + hex.EncodeToString(buf)
+	return plain, HashAdminAPIToken(plain), nil
+}
+
+func HashAdminAPIToken(plain string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(plain)))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func authenticateAdminAPIToken(candidate string, store AdminConfigReader) (AdminPrincipal, error) {
+	reader, ok := store.(AdminIdentityReader)
+	if !ok {
+		return AdminPrincipal{}, errors.New("invalid credentials")
+	}
+	hash := HashAdminAPIToken(candidate)
+	now := time.Now().Unix()
+	for _, token := range reader.AdminAPITokens() {
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(strings.TrimSpace(token.TokenHash))) != 1 {
+			continue
+		}
+		if token.ExpiresAtUnix > 0 && token.ExpiresAtUnix <= now {
+			return AdminPrincipal{}, errAdminTokenExpired
+		}
+		role := config.NormalizeAdminRole(token.Role)
+		if role == "" {
+			return AdminPrincipal{}, errors.New("invalid credentials")
+		}
+		subject := strings.TrimSpace(token.Name)
+		if subject == "" {
+			subject = token.ID
+		}
+		return AdminPrincipal{Subject: subject, Role: role, Method: "api_token", TokenID: token.ID}, nil
+	}
+	return AdminPrincipal{}, errors.New("invalid credentials")
+}
+
+// AuthenticateAdminRequest resolves the bearer credential on r to an admin
+// principal. It accepts the legacy admin key, admin JWTs and admin API tokens.
+func AuthenticateAdminRequest(r *http.Request, store AdminConfigReader) (AdminPrincipal, error) {
+	authHeader := strings.TrimSpace(r.Header.Get("Authorization"))
+	if !strings.HasPrefix(strings.ToLower(authHeader), "bearer ") {
+		return AdminPrincipal{}, errors.New("authentication required")
+	}
+	token := strings.TrimSpace(authHeader[7:])
+	if token == "" {
+		return AdminPrincipal{}, errors.New("authentication required")
+	}
+	if strings.HasPrefix(token, AdminAPITokenPrefix) {
+		p, err := authenticateAdminAPIToken(token, store)
+		if err == nil || errors.Is(err, errAdminTokenExpired) {
+			return p, err
+		}
+	}
+	if VerifyAdminCredential(token, store) {
+		return LegacyAdminPrincipal("admin_key"), nil
+	}
+	payload, err := VerifyJWTWithStore(token, store)
+	if err != nil {
+		return AdminPrincipal{}, errors.New("invalid credentials")
+	}
+	return AdminPrincipalFromJWT(payload, store)
+}
+
+func findAdminUser(store AdminConfigReader, username string) (config.AdminUser, bool) {
+	reader, ok := store.(AdminIdentityReader)
+	if !ok {
+		return config.AdminUser{}, false
+	}
+	username = NormalizeAdminUsername(username)
+	if username == "" {
+		return config.AdminUser{}, false
+	}
+	for _, user := range reader.AdminUsers() {
+		if NormalizeAdminUsername(user.Username) == username {
+			return user, true
+		}
+	}
+	return config.AdminUser{}, false
+}
@@ -10,8 +10,11 @@ import (
 	"sync"
 	"time"
 
+	"ds2api/internal/accesslog"
 	"ds2api/internal/account"
 	"ds2api/internal/config"
+	"ds2api/internal/inflight"
+	"ds2api/internal/tracing"
 )
 
 type ctxKey string
@@ -31,6 +34,9 @@ type RequestAuth struct {
 	Account        config.Account
 	TriedAccounts  map[string]bool
 	resolver       *Resolver
+	// additional marks a fan-out slot taken by DetermineAdditional; its
+	// account changes are not reported to the request's observers.
+	additional bool
 }
 
 type LoginFunc func(ctx context.Context, acc config.Account) (string, error)
@@ -54,12 +60,27 @@ func NewResolver(store *config.Store, pool *account.Pool, login LoginFunc) *Reso
 }
 
 func (r *Resolver) Determine(req *http.Request) (*RequestAuth, error) {
+	return r.determine(req, false)
+}
+
+// DetermineAdditional acquires another pool slot for the caller of req, for
+// requests that fan out over several accounts. Unlike Determine it leaves
+// the request's access log and in-flight entry on the primary account.
+func (r *Resolver) DetermineAdditional(req *http.Request) (*RequestAuth, error) {
+	return r.determine(req, true)
+}
+
+func (r *Resolver) determine(req *http.Request, additional bool) (*RequestAuth, error) {
 	callerKey := extractCallerToken(req)
 	if callerKey == "" {
 		return nil, ErrUnauthorized
 	}
 	callerID := callerTokenID(callerKey)
 	ctx := req.Context()
+	if !additional {
+		accesslog.FromContext(ctx).SetCaller(callerID)
+		inflight.FromContext(ctx).SetCaller(callerID)
+	}
 	if !r.Store.HasAPIKey(callerKey) {
 		return &RequestAuth{
 			UseConfigToken: false,
@@ -70,10 +91,23 @@ func (r *Resolver) Determine(req *http.Request) (*RequestAuth, error) {
 		}, nil
 	}
 	target := strings.TrimSpace(req.Header.Get("X-Ds2-Target-Account"))
+	ctx = account.WithCaller(ctx, r.schedulingCaller(req, callerKey, callerID))
+	if !additional {
+		inflight.FromContext(ctx).SetStage(inflight.StageWaitingAccount)
+	}
+	ctx, span := tracing.Start(ctx, "auth.acquire_account")
+	defer span.End()
 	a, err := r.acquireManagedRequestAuth(ctx, callerID, target)
 	if err != nil {
+		span.RecordError(err)
 		return nil, err
 	}
+	span.SetAttributes(tracing.String("ds2api.account_id", a.AccountID))
+	a.additional = additional
+	if !additional {
+		accesslog.FromContext(ctx).SetAccount(a.AccountID)
+		inflight.FromContext(ctx).SetAccount(a.AccountID)
+	}
 	return a, nil
 }
 
@@ -117,6 +151,23 @@ func (r *Resolver) acquireManagedRequestAuth(ctx context.Context, callerID, targ
 	}
 }
 
+// schedulingCaller maps a managed API key to its wait-queue identity. The key's
+// priority class can be overridden by X-Ds2-Priority when the config allows it.
+func (r *Resolver) schedulingCaller(req *http.Request, callerKey, callerID string) account.Caller {
+	c := account.Caller{ID: callerID}
+	if key, ok := r.Store.APIKeyInfo(callerKey); ok {
+		c.Name = key.Name
+		c.Class = key.Priority
+		c.Weight = key.Weight
+	}
+	if r.Store.SchedulingAllowPriorityHeader() {
+		if class := strings.TrimSpace(req.Header.Get("X-Ds2-Priority")); class != "" {
+			c.Class = class
+		}
+	}
+	return c
+}
+
 // DetermineCaller resolves caller identity without acquiring any pooled account.
 // Use this for local-cache lookup routes that only need tenant isolation.
 func (r *Resolver) DetermineCaller(req *http.Request) (*RequestAuth, error) {
@@ -125,6 +176,8 @@ func (r *Resolver) DetermineCaller(req *http.Request) (*RequestAuth, error) {
 		return nil, ErrUnauthorized
 	}
 	callerID := callerTokenID(callerKey)
+	accesslog.FromContext(req.Context()).SetCaller(callerID)
+	inflight.FromContext(req.Context()).SetCaller(callerID)
 	a := &RequestAuth{
 		UseConfigToken: false,
 		CallerID:       callerID,
@@ -148,8 +201,11 @@ func FromContext(ctx context.Context) (*RequestAuth, bool) {
 }
 
 func (r *Resolver) loginAndPersist(ctx context.Context, a *RequestAuth) error {
+	ctx, span := tracing.StartClient(ctx, "auth.login", tracing.String("ds2api.account_id", a.AccountID))
+	defer span.End()
 	token, err := r.Login(ctx, a.Account)
 	if err != nil {
+		span.RecordError(err)
 		return err
 	}
 	a.Account.Token = token
@@ -162,9 +218,12 @@ func (r *Resolver) RefreshToken(ctx context.Context, a *RequestAuth) bool {
 	if !a.UseConfigToken || a.AccountID == "" {
 		return false
 	}
+	ctx, span := tracing.Start(ctx, "auth.refresh_token", tracing.String("ds2api.account_id", a.AccountID))
+	defer span.End()
 	_ = r.Store.UpdateAccountToken(a.AccountID, "")
 	a.Account.Token = ""
 	if err := r.loginAndPersist(ctx, a); err != nil {
+		span.RecordError(err)
 		config.Logger.Error("[refresh_token] failed", "account", a.AccountID, "error", err)
 		return false
 	}
@@ -204,6 +263,10 @@ func (r *Resolver) SwitchAccount(ctx context.Context, a *RequestAuth) bool {
 			r.Pool.Release(a.AccountID)
 			continue
 		}
+		if !a.additional {
+			accesslog.FromContext(ctx).SetAccount(a.AccountID)
+			inflight.FromContext(ctx).SetAccount(a.AccountID)
+		}
 		return true
 	}
 }
@@ -4,12 +4,14 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"net/http/httptest"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"ds2api/internal/account"
 	"ds2api/internal/config"
+	"ds2api/internal/inflight"
 )
 
 func newTestResolver(t *testing.T) *Resolver {
@@ -89,6 +91,26 @@ func TestDetermineCallerWithManagedKeySkipsAccountAcquire(t *testing.T) {
 	}
 }
 
+func TestSchedulingCallerUsesKeyPriorityAndAllowedHeader(t *testing.T) {
+	t.Setenv("DS2API_CONFIG_JSON", `{
+		"api_keys":[{"key":"batch-key","name":"nightly","priority":"batch","weight":3}],
+		"accounts":[{"email":"acc@example.com","password":"pwd","token":"account-token"}],
+		"scheduling":{"allow_priority_header":true,"classes":[{"name":"interactive"},{"name":"batch"}]}
+	}`)
+	store := config.LoadStore()
+	r := NewResolver(store, account.NewPool(store), nil)
+	req, _ := http.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+
+	c := r.schedulingCaller(req, "batch-key", callerTokenID("batch-key"))
+	if c.Name != "nightly" || c.Class != "batch" || c.Weight != 3 || c.ID != callerTokenID("batch-key") {
+		t.Fatalf("unexpected caller from key: %+v", c)
+	}
+	req.Header.Set("X-Ds2-Priority", "interactive")
+	if c := r.schedulingCaller(req, "batch-key", callerTokenID("batch-key")); c.Class != "interactive" {
+		t.Fatalf("expected header to pick the class, got %+v", c)
+	}
+}
+
 func TestCallerTokenIDStable(t *testing.T) {
 	a := callerTokenID("token-a")
 	b := callerTokenID("token-a")
@@ -395,3 +417,44 @@ func TestDetermineManagedAccountReturnsLastEnsureErrorWhenAllFail(t *testing.T)
 		t.Fatalf("expected auth-style ensure error, got ErrNoAccount")
 	}
 }
+
+func TestDetermineAdditionalKeepsRequestOnPrimaryAccount(t *testing.T) {
+	t.Setenv("DS2API_CONFIG_JSON", `{
+		"keys":["managed-key"],
+		"accounts":[
+			{"email":"one@example.com","password":"pwd","token":"one-token"},
+			{"email":"two@example.com","password":"pwd","token":"two-token"}
+		]
+	}`)
+	store := config.LoadStore()
+	pool := account.NewPool(store)
+	resolver := NewResolver(store, pool, func(_ context.Context, acc config.Account) (string, error) {
+		return "fresh-" + acc.Email, nil
+	})
+	registry := inflight.NewRegistry()
+
+	var primary, extra *RequestAuth
+	var snapshot inflight.Snapshot
+	handler := registry.Middleware(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		var err error
+		if primary, err = resolver.Determine(req); err != nil {
+			t.Fatalf("determine failed: %v", err)
+		}
+		if extra, err = resolver.DetermineAdditional(req); err != nil {
+			t.Fatalf("determine additional failed: %v", err)
+		}
+		snapshot = registry.List()[0]
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	req.Header.Set("x-api-key", "managed-key")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	defer resolver.Release(primary)
+	defer resolver.Release(extra)
+
+	if primary.AccountID == extra.AccountID {
+		t.Fatalf("expected a second account, both got %q", primary.AccountID)
+	}
+	if snapshot.AccountID != primary.AccountID {
+		t.Fatalf("expected in-flight entry on primary %q, got %q", primary.AccountID, snapshot.AccountID)
+	}
+}
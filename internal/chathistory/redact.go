@@ -0,0 +1,177 @@
+package chathistory
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Built-in redaction detectors. They favour precision over recall so that
+// ordinary numbers and identifiers in prompts survive untouched.
+const (
+	DetectorAPIKey = "api_key"
+	DetectorEmail  = "email"
+	DetectorPhone  = "phone"
+)
+
+var builtinDetectors = map[string]*regexp.Regexp{
+	DetectorAPIKey: regexp.MustCompile(`\b(?:sk-(?:ant-|proj-)?[A-Za-z0-9_-]{16,}|AKIA[0-9A-Z]{16}|AIza[0-9A-Za-z_-]{35}|gh[pousr]_[A-Za-z0-9]{36,}|xox[abprs]-[A-Za-z0-9-]{10,})\b|(?i:bearer\s+[A-Za-z0-9._~+/-]{20,}=*)`),
+	DetectorEmail:  regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	DetectorPhone:  regexp.MustCompile(`\+\d{1,3}[ -]?\(?\d{1,4}\)?(?:[ -]?\d{2,4}){2,3}\b|\b1[3-9]\d{9}\b|\(\d{3}\) ?\d{3}-\d{4}\b|\b\d{3}-\d{3}-\d{4}\b`),
+}
+
+// RedactionRule is a user supplied regular expression. Matches are replaced
+// with Replacement, or "[REDACTED:<name>]" when it is empty.
+type RedactionRule struct {
+	Name        string `json:"name,omitempty"`
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// Redaction lists the detectors and custom rules applied to every entry
+// before it is persisted.
+type Redaction struct {
+	Detectors []string        `json:"detectors,omitempty"`
+	Rules     []RedactionRule `json:"rules,omitempty"`
+}
+
+func (r Redaction) Enabled() bool {
+	return len(r.Detectors) > 0 || len(r.Rules) > 0
+}
+
+type compiledRule struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+type redactor struct {
+	rules []compiledRule
+}
+
+// BuiltinDetectors returns the names accepted in Redaction.Detectors.
+func BuiltinDetectors() []string {
+	out := make([]string, 0, len(builtinDetectors))
+	for name := range builtinDetectors {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func normalizeRedaction(in Redaction) (Redaction, error) {
+	out := Redaction{}
+	seen := map[string]struct{}{}
+	for _, name := range in.Detectors {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if _, ok := builtinDetectors[name]; !ok {
+			return Redaction{}, fmt.Errorf("unknown redaction detector: %s", name)
+		}
+		if _, dup := seen[name]; dup {
+			continue
+		}
+		seen[name] = struct{}{}
+		out.Detectors = append(out.Detectors, name)
+	}
+	sort.Strings(out.Detectors)
+	for i, rule := range in.Rules {
+		rule.Name = strings.TrimSpace(rule.Name)
+		if strings.TrimSpace(rule.Pattern) == "" {
+			return Redaction{}, fmt.Errorf("redaction rule %d: pattern is required", i)
+		}
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return Redaction{}, fmt.Errorf("redaction rule %d: %w", i, err)
+		}
+		out.Rules = append(out.Rules, rule)
+	}
+	return out, nil
+}
+
+// compileRedaction builds a redactor. Invalid custom patterns are skipped so
+// a hand-edited index file cannot stop the store from loading.
+func compileRedaction(in Redaction) *redactor {
+	if !in.Enabled() {
+		return nil
+	}
+	r := &redactor{}
+	for _, name := range in.Detectors {
+		if re, ok := builtinDetectors[strings.ToLower(strings.TrimSpace(name))]; ok {
+			r.rules = append(r.rules, compiledRule{re: re, replacement: "[REDACTED:" + name + "]"})
+		}
+	}
+	for _, rule := range in.Rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			continue
+		}
+		replacement := rule.Replacement
+		if replacement == "" {
+			replacement = "[REDACTED]"
+			if rule.Name != "" {
+				replacement = "[REDACTED:" + rule.Name + "]"
+			}
+		}
+		r.rules = append(r.rules, compiledRule{re: re, replacement: replacement})
+	}
+	if len(r.rules) == 0 {
+		return nil
+	}
+	return r
+}
+
+func (r *redactor) apply(text string) string {
+	if r == nil || text == "" {
+		return text
+	}
+	for _, rule := range r.rules {
+		text = rule.re.ReplaceAllLiteralString(text, rule.replacement)
+	}
+	return text
+}
+
+// redactedPrefix caches the redacted form of the leading complete lines of a
+// field that grows with every update.
+type redactedPrefix struct {
+	raw   int
+	clean string
+}
+
+type streamRedaction struct {
+	reasoning redactedPrefix
+	content   redactedPrefix
+}
+
+// applyAppended redacts text, which must extend the text seen by the previous
+// call with the same prefix. Only the bytes after the last cached line break
+// are scanned. A match spanning a cached line break is left to the full pass
+// Update runs when the entry finishes.
+func (r *redactor) applyAppended(prefix *redactedPrefix, text string) string {
+	if r == nil || text == "" {
+		*prefix = redactedPrefix{}
+		return text
+	}
+	if prefix.raw > len(text) {
+		*prefix = redactedPrefix{}
+	}
+	tail := text[prefix.raw:]
+	if cut := strings.LastIndexByte(tail, '\n'); cut >= 0 {
+		prefix.clean += r.apply(tail[:cut+1])
+		prefix.raw += cut + 1
+		tail = tail[cut+1:]
+	}
+	return prefix.clean + r.apply(tail)
+}
+
+func cloneRedaction(in Redaction) Redaction {
+	out := Redaction{}
+	if len(in.Detectors) > 0 {
+		out.Detectors = append([]string(nil), in.Detectors...)
+	}
+	if len(in.Rules) > 0 {
+		out.Rules = append([]RedactionRule(nil), in.Rules...)
+	}
+	return out
+}
@@ -0,0 +1,273 @@
+package chathistory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"ds2api/internal/config"
+	"ds2api/internal/util"
+)
+
+const (
+	DefaultCompactInterval = 5 * time.Minute
+	MinEntryChars          = 256
+	truncatedMarker        = "\n...[truncated]"
+)
+
+// Retention bounds how much history is kept. Zero values disable the
+// corresponding rule; the entry-count limit still applies on top.
+type Retention struct {
+	MaxAgeHours   int   `json:"max_age_hours,omitempty"`
+	MaxTotalBytes int64 `json:"max_total_bytes,omitempty"`
+	MaxEntryChars int   `json:"max_entry_chars,omitempty"`
+}
+
+func (r Retention) Enabled() bool {
+	return r.MaxAgeHours > 0 || r.MaxTotalBytes > 0 || r.MaxEntryChars > 0
+}
+
+// CompactResult reports what a compaction pass changed.
+type CompactResult struct {
+	Removed    int   `json:"removed"`
+	Rewritten  int   `json:"rewritten"`
+	Entries    int   `json:"entries"`
+	TotalBytes int64 `json:"total_bytes"`
+}
+
+func validateRetention(r Retention) error {
+	if r.MaxAgeHours < 0 || r.MaxTotalBytes < 0 || r.MaxEntryChars < 0 {
+		return errors.New("retention values must not be negative")
+	}
+	if r.MaxEntryChars > 0 && r.MaxEntryChars < MinEntryChars {
+		return fmt.Errorf("max_entry_chars must be 0 or at least %d", MinEntryChars)
+	}
+	return nil
+}
+
+func (s *Store) SetRetention(retention Retention) (File, error) {
+	if s == nil {
+		return File{}, errors.New("chat history store is nil")
+	}
+	if err := validateRetention(retention); err != nil {
+		return File{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return File{}, s.err
+	}
+	s.state.Retention = retention
+	s.compactLocked(time.Now())
+	s.nextRevisionLocked()
+	if err := s.saveLocked(); err != nil {
+		return File{}, err
+	}
+	return cloneFile(s.state), nil
+}
+
+func (s *Store) SetRedaction(redaction Redaction) (File, error) {
+	if s == nil {
+		return File{}, errors.New("chat history store is nil")
+	}
+	normalized, err := normalizeRedaction(redaction)
+	if err != nil {
+		return File{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return File{}, s.err
+	}
+	s.state.Redaction = normalized
+	s.redactor = compileRedaction(normalized)
+	s.redacting = nil
+	s.compactLocked(time.Now())
+	s.nextRevisionLocked()
+	if err := s.saveLocked(); err != nil {
+		return File{}, err
+	}
+	return cloneFile(s.state), nil
+}
+
+// Compact applies redaction, truncation, max age and the byte cap to entries
+// that are already stored. Streaming entries are rewritten but never removed.
+func (s *Store) Compact() (CompactResult, error) {
+	if s == nil {
+		return CompactResult{}, errors.New("chat history store is nil")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return CompactResult{}, s.err
+	}
+	result := s.compactLocked(time.Now())
+	if result.Removed == 0 && result.Rewritten == 0 {
+		return result, nil
+	}
+	s.nextRevisionLocked()
+	if err := s.saveLocked(); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// StartCompactor runs Compact every interval until ctx is cancelled.
+func (s *Store) StartCompactor(ctx context.Context, interval time.Duration) {
+	if s == nil {
+		return
+	}
+	if interval <= 0 {
+		interval = DefaultCompactInterval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			result, err := s.Compact()
+			if err != nil {
+				config.Logger.Warn("[chat_history] compaction failed", "error", err)
+				continue
+			}
+			if result.Removed > 0 || result.Rewritten > 0 {
+				config.Logger.Info("[chat_history] compacted", "removed", result.Removed, "rewritten", result.Rewritten, "entries", result.Entries, "bytes", result.TotalBytes)
+			}
+		}
+	}()
+}
+
+func (s *Store) compactLocked(now time.Time) CompactResult {
+	result := CompactResult{}
+	policy := s.state.Retention
+	if policy.Enabled() || s.redactor != nil {
+		for id, item := range s.details {
+			sanitized, changed := s.sanitizeEntryLocked(item)
+			if changed {
+				s.details[id] = sanitized
+				s.markDetailDirtyLocked(id)
+				result.Rewritten++
+			}
+		}
+	}
+	if policy.MaxAgeHours > 0 {
+		cutoff := now.Add(-time.Duration(policy.MaxAgeHours) * time.Hour).UnixMilli()
+		for id, item := range s.details {
+			if item.Status != "streaming" && item.UpdatedAt < cutoff {
+				s.markDetailDeletedLocked(id)
+				delete(s.details, id)
+				result.Removed++
+			}
+		}
+	}
+	sizes := make(map[string]int64, len(s.details))
+	for id, item := range s.details {
+		sizes[id] = detailSize(item)
+		result.TotalBytes += sizes[id]
+	}
+	if policy.MaxTotalBytes > 0 && result.TotalBytes > policy.MaxTotalBytes {
+		oldest := make([]Entry, 0, len(s.details))
+		for _, item := range s.details {
+			oldest = append(oldest, item)
+		}
+		sort.Slice(oldest, func(i, j int) bool {
+			if oldest[i].CreatedAt == oldest[j].CreatedAt {
+				return oldest[i].ID < oldest[j].ID
+			}
+			return oldest[i].CreatedAt < oldest[j].CreatedAt
+		})
+		for _, item := range oldest {
+			if result.TotalBytes <= policy.MaxTotalBytes {
+				break
+			}
+			if item.Status == "streaming" {
+				continue
+			}
+			s.markDetailDeletedLocked(item.ID)
+			delete(s.details, item.ID)
+			result.TotalBytes -= sizes[item.ID]
+			result.Removed++
+		}
+	}
+	if result.Removed > 0 || result.Rewritten > 0 {
+		s.rebuildIndexLocked()
+	}
+	result.Entries = len(s.details)
+	return result
+}
+
+// sanitizeEntryLocked redacts and truncates every free-text field of item.
+func (s *Store) sanitizeEntryLocked(item Entry) (Entry, bool) {
+	changed := false
+	clean := func(text string) string {
+		out := s.truncateLocked(s.redactor.apply(text))
+		if out != text {
+			changed = true
+		}
+		return out
+	}
+	item.UserInput = clean(item.UserInput)
+	item.HistoryText = clean(item.HistoryText)
+	item.FinalPrompt = clean(item.FinalPrompt)
+	item.ReasoningContent = clean(item.ReasoningContent)
+	item.Content = clean(item.Content)
+	item.Error = clean(item.Error)
+	if len(item.Messages) > 0 {
+		messages := cloneMessages(item.Messages)
+		for i := range messages {
+			messages[i].Content = clean(messages[i].Content)
+		}
+		item.Messages = messages
+	}
+	return item, changed
+}
+
+// sanitizeStreamingLocked is the Update path while an entry is still
+// streaming. Start already sanitized the request fields, and the redactor only
+// scans output appended since the last complete line, so a long stream costs
+// linear rather than quadratic time. Update runs the full sanitizeEntryLocked
+// pass once the entry leaves "streaming".
+func (s *Store) sanitizeStreamingLocked(item Entry, reasoningChanged, contentChanged bool) Entry {
+	state := s.redacting[item.ID]
+	if state == nil {
+		state = &streamRedaction{}
+		if s.redacting == nil {
+			s.redacting = map[string]*streamRedaction{}
+		}
+		s.redacting[item.ID] = state
+	}
+	if reasoningChanged {
+		item.ReasoningContent = s.truncateLocked(s.redactor.applyAppended(&state.reasoning, item.ReasoningContent))
+	}
+	if contentChanged {
+		item.Content = s.truncateLocked(s.redactor.applyAppended(&state.content, item.Content))
+	}
+	item.Error = s.truncateLocked(s.redactor.apply(item.Error))
+	return item
+}
+
+func (s *Store) truncateLocked(text string) string {
+	limit := s.state.Retention.MaxEntryChars
+	if limit <= 0 || len(text) <= limit {
+		return text
+	}
+	if truncated, ok := util.TruncateRunes(text, limit); ok {
+		return truncated + truncatedMarker
+	}
+	return text
+}
+
+func detailSize(item Entry) int64 {
+	payload, err := json.MarshalIndent(detailEnvelope{Version: FileVersion, Item: item}, "", "  ")
+	if err != nil {
+		return 0
+	}
+	return int64(len(payload) + 1)
+}
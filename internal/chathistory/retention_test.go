@@ -0,0 +1,155 @@
+package chathistory
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedactionAppliesBeforePersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chat_history.json")
+	store := New(path)
+	if _, err := store.SetRedaction(Redaction{
+		Detectors: []string{"email", "API_KEY", "phone"},
+		Rules:     []RedactionRule{{Name: "ticket", Pattern: `TICKET-\d+`}},
+	}); err != nil {
+		t.Fatalf("set redaction failed: %v", err)
+	}
+	entry, err := store.Start(StartParams{
+		UserInput:   "mail me at alice@example.com about TICKET-42",
+		Messages:    []Message{{Role: "user", Content: "key sk-abcdefghijklmnopqrstuvwx please"}},
+		FinalPrompt: "call +1 415-555-0100 or 13812345678",
+	})
+	if err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	if _, err := store.Update(entry.ID, UpdateParams{Status: "success", Content: "sent to bob@example.org", Completed: true}); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+
+	reloaded := New(path)
+	got, err := reloaded.Get(entry.ID)
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if got.UserInput != "mail me at [REDACTED:email] about [REDACTED:ticket]" {
+		t.Fatalf("unexpected user input: %q", got.UserInput)
+	}
+	if strings.Contains(got.Messages[0].Content, "sk-") || !strings.Contains(got.Messages[0].Content, "[REDACTED:api_key]") {
+		t.Fatalf("expected api key redaction, got %q", got.Messages[0].Content)
+	}
+	if strings.Contains(got.FinalPrompt, "555") || strings.Contains(got.FinalPrompt, "13812345678") {
+		t.Fatalf("expected phone redaction, got %q", got.FinalPrompt)
+	}
+	if got.Content != "sent to [REDACTED:email]" {
+		t.Fatalf("unexpected content: %q", got.Content)
+	}
+	snapshot, _ := reloaded.Snapshot()
+	if len(snapshot.Redaction.Detectors) != 3 || len(snapshot.Redaction.Rules) != 1 {
+		t.Fatalf("expected redaction settings to persist, got %#v", snapshot.Redaction)
+	}
+
+	if _, err := store.SetRedaction(Redaction{Detectors: []string{"ssn"}}); err == nil {
+		t.Fatalf("expected unknown detector to fail")
+	}
+	if _, err := store.SetRedaction(Redaction{Rules: []RedactionRule{{Pattern: "("}}}); err == nil {
+		t.Fatalf("expected invalid pattern to fail")
+	}
+}
+
+func TestStreamingUpdatesRedactAppendedOutput(t *testing.T) {
+	store := New(filepath.Join(t.TempDir(), "chat_history.json"))
+	if _, err := store.SetRedaction(Redaction{Detectors: []string{"email"}}); err != nil {
+		t.Fatalf("set redaction failed: %v", err)
+	}
+	entry, err := store.Start(StartParams{UserInput: "hi"})
+	if err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	content := ""
+	for _, delta := range []string{"line one a@example.com\n", "split b@exa", "mple.com\n", "tail c@example.com"} {
+		content += delta
+		got, err := store.Update(entry.ID, UpdateParams{Status: "streaming", Content: content})
+		if err != nil {
+			t.Fatalf("update failed: %v", err)
+		}
+		if strings.Contains(got.Content, "@example.com") {
+			t.Fatalf("streaming content leaked an address: %q", got.Content)
+		}
+	}
+	if cached := store.redacting[entry.ID].content.raw; cached != len("line one a@example.com\nsplit b@example.com\n") {
+		t.Fatalf("expected completed lines to be cached, got %d bytes", cached)
+	}
+	got, err := store.Update(entry.ID, UpdateParams{Status: "success", Content: content, Completed: true})
+	if err != nil {
+		t.Fatalf("final update failed: %v", err)
+	}
+	want := "line one [REDACTED:email]\nsplit [REDACTED:email]\ntail [REDACTED:email]"
+	if got.Content != want {
+		t.Fatalf("unexpected final content: %q", got.Content)
+	}
+	if _, ok := store.redacting[entry.ID]; ok {
+		t.Fatalf("expected streaming redaction state to be dropped on finish")
+	}
+}
+
+func TestRetentionTruncatesAndCompacts(t *testing.T) {
+	store := New(filepath.Join(t.TempDir(), "chat_history.json"))
+	var ids []string
+	for i := 0; i < 4; i++ {
+		entry, err := store.Start(StartParams{UserInput: "question"})
+		if err != nil {
+			t.Fatalf("start failed: %v", err)
+		}
+		if _, err := store.Update(entry.ID, UpdateParams{Status: "success", Content: strings.Repeat("x", 1000), Completed: true}); err != nil {
+			t.Fatalf("update failed: %v", err)
+		}
+		ids = append(ids, entry.ID)
+	}
+
+	if _, err := store.SetRetention(Retention{MaxEntryChars: 10}); err == nil {
+		t.Fatalf("expected too-small max_entry_chars to fail")
+	}
+	if _, err := store.SetRetention(Retention{MaxEntryChars: 300}); err != nil {
+		t.Fatalf("set retention failed: %v", err)
+	}
+	got, _ := store.Get(ids[0])
+	if len([]rune(got.Content)) != 300+len([]rune(truncatedMarker)) || !strings.HasSuffix(got.Content, truncatedMarker) {
+		t.Fatalf("expected existing entry to be truncated, got %d chars", len(got.Content))
+	}
+	if result, err := store.Compact(); err != nil || result.Rewritten != 0 {
+		t.Fatalf("expected truncation to be idempotent, got %#v err=%v", result, err)
+	}
+
+	// Age the oldest entry past the cutoff.
+	store.mu.Lock()
+	old := store.details[ids[0]]
+	old.UpdatedAt = time.Now().Add(-3 * time.Hour).UnixMilli()
+	store.details[ids[0]] = old
+	store.mu.Unlock()
+	if _, err := store.SetRetention(Retention{MaxEntryChars: 300, MaxAgeHours: 2}); err != nil {
+		t.Fatalf("set retention failed: %v", err)
+	}
+	if _, err := store.Get(ids[0]); err == nil {
+		t.Fatalf("expected aged entry to be removed")
+	}
+
+	size := detailSize(got)
+	if _, err := store.SetRetention(Retention{MaxEntryChars: 300, MaxTotalBytes: size*2 + 1}); err != nil {
+		t.Fatalf("set retention failed: %v", err)
+	}
+	result, err := store.Compact()
+	if err != nil {
+		t.Fatalf("compact failed: %v", err)
+	}
+	if result.Entries != 2 || result.TotalBytes > size*2+1 {
+		t.Fatalf("expected byte cap to keep two entries, got %#v", result)
+	}
+	if _, err := store.Get(ids[1]); err == nil {
+		t.Fatalf("expected oldest remaining entry to be evicted first")
+	}
+	if _, err := store.Get(ids[3]); err != nil {
+		t.Fatalf("expected newest entry to survive: %v", err)
+	}
+}
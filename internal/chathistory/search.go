@@ -0,0 +1,385 @@
+package chathistory
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+	MaxExportEntries   = 1000
+)
+
+var (
+	ErrInvalidCursor  = errors.New("invalid chat history cursor")
+	ErrEntryNotFound  = errors.New("chat history entry not found")
+	ErrExportTooLarge = errors.New("too many chat history entries to export")
+)
+
+// Query selects history entries. Text is split into terms that must all
+// appear (case-insensitively) in the user input, final prompt, content or
+// error; the remaining fields are exact, case-insensitive filters.
+type Query struct {
+	Text         string
+	Surface      string
+	Model        string
+	AccountID    string
+	CallerID     string
+	Status       string
+	FinishReason string
+	Since        int64
+	Until        int64
+	Cursor       string
+	Limit        int
+}
+
+type SearchResult struct {
+	Items      []SummaryEntry `json:"items"`
+	Total      int            `json:"total"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// searchIndex maps normalized terms to entry ids. It is updated
+// incrementally from rebuildIndexLocked: entries whose revision changed are
+// re-tokenized and entries that disappeared are dropped. Streaming entries
+// change on every delta, so they stay out of the index until they finish and
+// Search scans them directly.
+type searchIndex struct {
+	postings map[string]map[string]struct{}
+	terms    map[string][]string
+	indexed  map[string]int64
+	// vocab is the sorted key set of postings, rebuilt lazily after the
+	// vocabulary changes so prefix lookups can binary search.
+	vocab []string
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		postings: map[string]map[string]struct{}{},
+		terms:    map[string][]string{},
+		indexed:  map[string]int64{},
+	}
+}
+
+func (idx *searchIndex) sync(details map[string]Entry) {
+	for id := range idx.indexed {
+		if _, ok := details[id]; !ok {
+			idx.remove(id)
+		}
+	}
+	for id, item := range details {
+		if rev, ok := idx.indexed[id]; ok && rev == item.Revision {
+			continue
+		}
+		idx.remove(id)
+		if item.Status == "streaming" {
+			continue
+		}
+		terms := uniqueTerms(searchableText(item))
+		for _, term := range terms {
+			ids := idx.postings[term]
+			if ids == nil {
+				ids = map[string]struct{}{}
+				idx.postings[term] = ids
+				idx.vocab = nil
+			}
+			ids[id] = struct{}{}
+		}
+		idx.terms[id] = terms
+		idx.indexed[id] = item.Revision
+	}
+}
+
+func (idx *searchIndex) remove(id string) {
+	for _, term := range idx.terms[id] {
+		if ids := idx.postings[term]; ids != nil {
+			delete(ids, id)
+			if len(ids) == 0 {
+				delete(idx.postings, term)
+				idx.vocab = nil
+			}
+		}
+	}
+	delete(idx.terms, id)
+	delete(idx.indexed, id)
+}
+
+func (idx *searchIndex) has(id string) bool {
+	_, ok := idx.indexed[id]
+	return ok
+}
+
+func (idx *searchIndex) sortedVocab() []string {
+	if idx.vocab == nil {
+		idx.vocab = make([]string, 0, len(idx.postings))
+		for term := range idx.postings {
+			idx.vocab = append(idx.vocab, term)
+		}
+		sort.Strings(idx.vocab)
+	}
+	return idx.vocab
+}
+
+// candidates returns the indexed ids whose terms cover every query term,
+// treating query terms as prefixes of indexed terms. A nil result with
+// ok=false means the query has no indexable terms.
+func (idx *searchIndex) candidates(queryTerms []string) (map[string]struct{}, bool) {
+	if len(queryTerms) == 0 {
+		return nil, false
+	}
+	vocab := idx.sortedVocab()
+	var out map[string]struct{}
+	for _, q := range queryTerms {
+		matched := map[string]struct{}{}
+		for i := sort.SearchStrings(vocab, q); i < len(vocab) && strings.HasPrefix(vocab[i], q); i++ {
+			for id := range idx.postings[vocab[i]] {
+				matched[id] = struct{}{}
+			}
+		}
+		if out == nil {
+			out = matched
+		} else {
+			for id := range out {
+				if _, ok := matched[id]; !ok {
+					delete(out, id)
+				}
+			}
+		}
+		if len(out) == 0 {
+			break
+		}
+	}
+	return out, true
+}
+
+func searchableText(item Entry) string {
+	return strings.Join([]string{item.UserInput, item.FinalPrompt, item.Content, item.Error}, "\n")
+}
+
+// tokenize lowercases text and splits it on anything that is not a letter
+// or digit. Han, Hiragana, Katakana and Hangul runes become single-rune
+// terms so CJK text is searchable without a dictionary.
+func tokenize(text string) []string {
+	var out []string
+	var cur strings.Builder
+	flush := func() {
+		if cur.Len() > 0 {
+			out = append(out, cur.String())
+			cur.Reset()
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case isCJK(r):
+			flush()
+			out = append(out, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			cur.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return out
+}
+
+func uniqueTerms(text string) []string {
+	tokens := tokenize(text)
+	seen := make(map[string]struct{}, len(tokens))
+	out := make([]string, 0, len(tokens))
+	for _, tok := range tokens {
+		if _, ok := seen[tok]; ok {
+			continue
+		}
+		seen[tok] = struct{}{}
+		out = append(out, tok)
+	}
+	return out
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+// Search filters and pages the retained entries, newest first.
+func (s *Store) Search(q Query) (SearchResult, error) {
+	if s == nil {
+		return SearchResult{}, errors.New("chat history store is nil")
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	if limit > MaxSearchLimit {
+		limit = MaxSearchLimit
+	}
+	afterCreated, afterID, hasCursor, err := decodeCursor(q.Cursor)
+	if err != nil {
+		return SearchResult{}, err
+	}
+	phrases := queryPhrases(q.Text)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return SearchResult{}, s.err
+	}
+	index := s.searchIndexLocked()
+	candidates, useIndex := index.candidates(uniqueTerms(q.Text))
+	matches := make([]SummaryEntry, 0)
+	for id, item := range s.details {
+		if useIndex && index.has(id) {
+			if _, ok := candidates[id]; !ok {
+				continue
+			}
+		}
+		if !matchesFilters(item, q) || !containsAllPhrases(item, phrases) {
+			continue
+		}
+		matches = append(matches, summaryFromEntry(item))
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].CreatedAt == matches[j].CreatedAt {
+			return matches[i].ID > matches[j].ID
+		}
+		return matches[i].CreatedAt > matches[j].CreatedAt
+	})
+
+	result := SearchResult{Items: []SummaryEntry{}, Total: len(matches)}
+	start := 0
+	if hasCursor {
+		start = sort.Search(len(matches), func(i int) bool {
+			m := matches[i]
+			return m.CreatedAt < afterCreated || (m.CreatedAt == afterCreated && m.ID < afterID)
+		})
+	}
+	end := start + limit
+	if end > len(matches) {
+		end = len(matches)
+	}
+	result.Items = append(result.Items, matches[start:end]...)
+	if end < len(matches) {
+		last := matches[end-1]
+		result.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+	return result, nil
+}
+
+// Export returns full entries for ids in the order requested. Unknown ids
+// are reported as an error so callers do not silently ship partial data.
+func (s *Store) Export(ids []string) ([]Entry, error) {
+	if s == nil {
+		return nil, errors.New("chat history store is nil")
+	}
+	if len(ids) > MaxExportEntries {
+		return nil, fmt.Errorf("%w: at most %d entries can be exported at once", ErrExportTooLarge, MaxExportEntries)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	out := make([]Entry, 0, len(ids))
+	seen := make(map[string]struct{}, len(ids))
+	for _, raw := range ids {
+		id := strings.TrimSpace(raw)
+		if id == "" {
+			continue
+		}
+		if _, dup := seen[id]; dup {
+			continue
+		}
+		seen[id] = struct{}{}
+		item, ok := s.details[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrEntryNotFound, id)
+		}
+		out = append(out, cloneEntry(item))
+	}
+	return out, nil
+}
+
+func (s *Store) searchIndexLocked() *searchIndex {
+	if s.index == nil {
+		s.index = newSearchIndex()
+		s.index.sync(s.details)
+	}
+	return s.index
+}
+
+func matchesFilters(item Entry, q Query) bool {
+	if !fieldMatches(item.Surface, q.Surface) || !fieldMatches(item.Model, q.Model) ||
+		!fieldMatches(item.AccountID, q.AccountID) || !fieldMatches(item.CallerID, q.CallerID) ||
+		!fieldMatches(item.Status, q.Status) || !fieldMatches(item.FinishReason, q.FinishReason) {
+		return false
+	}
+	if q.Since > 0 && item.CreatedAt < q.Since {
+		return false
+	}
+	if q.Until > 0 && item.CreatedAt > q.Until {
+		return false
+	}
+	return true
+}
+
+func fieldMatches(have, want string) bool {
+	want = strings.TrimSpace(want)
+	return want == "" || strings.EqualFold(strings.TrimSpace(have), want)
+}
+
+// queryPhrases splits the query on whitespace; each phrase must occur as a
+// substring, which keeps results exact after the index prefilter.
+func queryPhrases(text string) []string {
+	fields := strings.Fields(strings.ToLower(text))
+	out := fields[:0]
+	for _, f := range fields {
+		if len(tokenize(f)) > 0 {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func containsAllPhrases(item Entry, phrases []string) bool {
+	if len(phrases) == 0 {
+		return true
+	}
+	haystack := strings.ToLower(searchableText(item))
+	for _, p := range phrases {
+		if !strings.Contains(haystack, p) {
+			return false
+		}
+	}
+	return true
+}
+
+func encodeCursor(createdAt int64, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(createdAt, 10) + ":" + id))
+}
+
+func decodeCursor(cursor string) (int64, string, bool, error) {
+	cursor = strings.TrimSpace(cursor)
+	if cursor == "" {
+		return 0, "", false, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, "", false, ErrInvalidCursor
+	}
+	createdRaw, id, ok := strings.Cut(string(raw), ":")
+	if !ok || id == "" {
+		return 0, "", false, ErrInvalidCursor
+	}
+	createdAt, err := strconv.ParseInt(createdRaw, 10, 64)
+	if err != nil {
+		return 0, "", false, ErrInvalidCursor
+	}
+	return createdAt, id, true, nil
+}
@@ -0,0 +1,159 @@
+package chathistory
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func seedSearchStore(t *testing.T) (*Store, []Entry) {
+	t.Helper()
+	store := New(filepath.Join(t.TempDir(), "chat_history.json"))
+	seeds := []struct {
+		start  StartParams
+		update UpdateParams
+	}{
+		{StartParams{Surface: "openai.chat", Model: "deepseek-v4-flash", AccountID: "a@x", UserInput: "call the weather tool"}, UpdateParams{Status: "error", Error: "tool get_weather failed: timeout", FinishReason: "error"}},
+		{StartParams{Surface: "claude.messages", Model: "deepseek-v4-pro", AccountID: "b@x", UserInput: "写一首诗"}, UpdateParams{Status: "success", Content: "春眠不觉晓", FinishReason: "stop"}},
+		{StartParams{Surface: "openai.chat", Model: "deepseek-v4-flash", AccountID: "b@x", UserInput: "tooling question"}, UpdateParams{Status: "success", Content: "use get_weather", FinishReason: "stop"}},
+	}
+	entries := make([]Entry, 0, len(seeds))
+	for _, seed := range seeds {
+		started, err := store.Start(seed.start)
+		if err != nil {
+			t.Fatalf("start failed: %v", err)
+		}
+		seed.update.Completed = true
+		updated, err := store.Update(started.ID, seed.update)
+		if err != nil {
+			t.Fatalf("update failed: %v", err)
+		}
+		entries = append(entries, updated)
+	}
+	return store, entries
+}
+
+func searchIDs(t *testing.T, store *Store, q Query) []string {
+	t.Helper()
+	res, err := store.Search(q)
+	if err != nil {
+		t.Fatalf("search %#v failed: %v", q, err)
+	}
+	ids := make([]string, 0, len(res.Items))
+	for _, item := range res.Items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func TestSearchMatchesTextAcrossFieldsAndFilters(t *testing.T) {
+	store, entries := seedSearchStore(t)
+
+	if got := searchIDs(t, store, Query{Text: "get_weather FAILED"}); len(got) != 1 || got[0] != entries[0].ID {
+		t.Fatalf("expected only the failed tool call, got %v", got)
+	}
+	if got := searchIDs(t, store, Query{Text: "get_weather"}); len(got) != 2 {
+		t.Fatalf("expected error and content matches, got %v", got)
+	}
+	if got := searchIDs(t, store, Query{Text: "tool"}); len(got) != 2 {
+		t.Fatalf("expected prefix match on tool/tooling, got %v", got)
+	}
+	if got := searchIDs(t, store, Query{Text: "觉晓"}); len(got) != 1 || got[0] != entries[1].ID {
+		t.Fatalf("expected CJK match, got %v", got)
+	}
+	if got := searchIDs(t, store, Query{Surface: "OpenAI.Chat", Status: "success"}); len(got) != 1 || got[0] != entries[2].ID {
+		t.Fatalf("expected filtered match, got %v", got)
+	}
+	if got := searchIDs(t, store, Query{AccountID: "b@x", Since: entries[2].CreatedAt}); len(got) != 1 || got[0] != entries[2].ID {
+		t.Fatalf("expected time-range match, got %v", got)
+	}
+	if got := searchIDs(t, store, Query{Text: "missing"}); len(got) != 0 {
+		t.Fatalf("expected no matches, got %v", got)
+	}
+}
+
+func TestSearchIndexTracksUpdatesAndDeletes(t *testing.T) {
+	store, entries := seedSearchStore(t)
+	if _, err := store.Update(entries[1].ID, UpdateParams{Status: "success", Content: "rewritten answer", Completed: true}); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if got := searchIDs(t, store, Query{Text: "rewritten"}); len(got) != 1 {
+		t.Fatalf("expected reindexed content, got %v", got)
+	}
+	if err := store.Delete(entries[0].ID); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if got := searchIDs(t, store, Query{Text: "timeout"}); len(got) != 0 {
+		t.Fatalf("expected deleted entry to leave the index, got %v", got)
+	}
+	if _, ok := store.index.indexed[entries[0].ID]; ok {
+		t.Fatalf("expected deleted entry to be dropped from index")
+	}
+}
+
+func TestSearchIndexSkipsStreamingEntries(t *testing.T) {
+	store, _ := seedSearchStore(t)
+	live, err := store.Start(StartParams{UserInput: "live question"})
+	if err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	if _, err := store.Update(live.ID, UpdateParams{Status: "streaming", Content: "partial forecast"}); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if got := searchIDs(t, store, Query{Text: "forecast"}); len(got) != 1 || got[0] != live.ID {
+		t.Fatalf("expected streaming entry to be found by scan, got %v", got)
+	}
+	if got := searchIDs(t, store, Query{Text: "tool"}); len(got) != 2 {
+		t.Fatalf("expected streaming entry not to match unrelated terms, got %v", got)
+	}
+	if store.index.has(live.ID) {
+		t.Fatalf("expected streaming entry to stay out of the index")
+	}
+	if _, err := store.Update(live.ID, UpdateParams{Status: "success", Content: "partial forecast done", Completed: true}); err != nil {
+		t.Fatalf("final update failed: %v", err)
+	}
+	if got := searchIDs(t, store, Query{Text: "fore"}); len(got) != 1 || got[0] != live.ID {
+		t.Fatalf("expected finished entry to be indexed, got %v", got)
+	}
+	if !store.index.has(live.ID) {
+		t.Fatalf("expected finished entry to be indexed")
+	}
+}
+
+func TestSearchCursorPaging(t *testing.T) {
+	store, _ := seedSearchStore(t)
+	var all []string
+	cursor := ""
+	for page := 0; page < 5; page++ {
+		res, err := store.Search(Query{Limit: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("search failed: %v", err)
+		}
+		if res.Total != 3 {
+			t.Fatalf("expected total=3, got %d", res.Total)
+		}
+		for _, item := range res.Items {
+			all = append(all, item.ID)
+		}
+		if res.NextCursor == "" {
+			break
+		}
+		cursor = res.NextCursor
+	}
+	if len(all) != 3 || all[0] == all[1] || all[1] == all[2] {
+		t.Fatalf("expected three distinct paged items, got %v", all)
+	}
+	if _, err := store.Search(Query{Cursor: "not-a-cursor!"}); err != ErrInvalidCursor {
+		t.Fatalf("expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestExportErrorsWrapSentinels(t *testing.T) {
+	store, entries := seedSearchStore(t)
+	if _, err := store.Export([]string{entries[0].ID, "chat_missing"}); !errors.Is(err, ErrEntryNotFound) {
+		t.Fatalf("expected ErrEntryNotFound, got %v", err)
+	}
+	if _, err := store.Export(make([]string, MaxExportEntries+1)); !errors.Is(err, ErrExportTooLarge) {
+		t.Fatalf("expected ErrExportTooLarge, got %v", err)
+	}
+}
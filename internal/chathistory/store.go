@@ -57,6 +57,7 @@ type Entry struct {
 	ElapsedMs        int64          `json:"elapsed_ms,omitempty"`
 	FinishReason     string         `json:"finish_reason,omitempty"`
 	Usage            map[string]any `json:"usage,omitempty"`
+	ReplayOf         string         `json:"replay_of,omitempty"`
 }
 
 type Message struct {
@@ -81,14 +82,17 @@ type SummaryEntry struct {
 	StatusCode     int    `json:"status_code,omitempty"`
 	ElapsedMs      int64  `json:"elapsed_ms,omitempty"`
 	FinishReason   string `json:"finish_reason,omitempty"`
+	ReplayOf       string `json:"replay_of,omitempty"`
 	DetailRevision int64  `json:"detail_revision"`
 }
 
 type File struct {
-	Version  int            `json:"version"`
-	Limit    int            `json:"limit"`
-	Revision int64          `json:"revision"`
-	Items    []SummaryEntry `json:"items"`
+	Version   int            `json:"version"`
+	Limit     int            `json:"limit"`
+	Retention Retention      `json:"retention"`
+	Redaction Redaction      `json:"redaction"`
+	Revision  int64          `json:"revision"`
+	Items     []SummaryEntry `json:"items"`
 }
 
 type StartParams struct {
@@ -101,6 +105,7 @@ type StartParams struct {
 	Messages    []Message
 	HistoryText string
 	FinalPrompt string
+	ReplayOf    string
 }
 
 type UpdateParams struct {
@@ -138,6 +143,9 @@ type Store struct {
 	details   map[string]Entry
 	dirty     map[string]struct{}
 	deleted   map[string]struct{}
+	index     *searchIndex
+	redactor  *redactor
+	redacting map[string]*streamRedaction
 	err       error
 }
 
@@ -281,7 +289,9 @@ func (s *Store) Start(params StartParams) (Entry, error) {
 		Messages:    cloneMessages(params.Messages),
 		HistoryText: params.HistoryText,
 		FinalPrompt: strings.TrimSpace(params.FinalPrompt),
+		ReplayOf:    strings.TrimSpace(params.ReplayOf),
 	}
+	entry, _ = s.sanitizeEntryLocked(entry)
 	s.details[entry.ID] = entry
 	s.markDetailDirtyLocked(entry.ID)
 	s.rebuildIndexLocked()
@@ -314,10 +324,12 @@ func (s *Store) Update(id string, params UpdateParams) (Entry, error) {
 	if params.Status != "" {
 		item.Status = params.Status
 	}
-	if params.ReasoningContent != "" || item.ReasoningContent == "" {
+	reasoningChanged := params.ReasoningContent != "" || item.ReasoningContent == ""
+	if reasoningChanged {
 		item.ReasoningContent = params.ReasoningContent
 	}
-	if params.Content != "" || item.Content == "" {
+	contentChanged := params.Content != "" || item.Content == ""
+	if contentChanged {
 		item.Content = params.Content
 	}
 	item.Error = strings.TrimSpace(params.Error)
@@ -330,6 +342,12 @@ func (s *Store) Update(id string, params UpdateParams) (Entry, error) {
 	if params.Completed {
 		item.CompletedAt = now
 	}
+	if item.Status == "streaming" {
+		item = s.sanitizeStreamingLocked(item, reasoningChanged, contentChanged)
+	} else {
+		delete(s.redacting, target)
+		item, _ = s.sanitizeEntryLocked(item)
+	}
 	s.details[target] = item
 	s.markDetailDirtyLocked(target)
 	s.rebuildIndexLocked()
@@ -357,6 +375,7 @@ func (s *Store) Delete(id string) error {
 	}
 	s.markDetailDeletedLocked(target)
 	delete(s.details, target)
+	delete(s.redacting, target)
 	s.nextRevisionLocked()
 	s.rebuildIndexLocked()
 	if err := s.saveLocked(); err != nil {
@@ -378,6 +397,7 @@ func (s *Store) Clear() error {
 		s.markDetailDeletedLocked(id)
 	}
 	s.details = map[string]Entry{}
+	s.redacting = nil
 	s.nextRevisionLocked()
 	s.rebuildIndexLocked()
 	if err := s.saveLocked(); err != nil {
@@ -451,7 +471,12 @@ func (s *Store) loadLocked() error {
 	if !isAllowedLimit(state.Limit) {
 		state.Limit = DefaultLimit
 	}
+	if err := validateRetention(state.Retention); err != nil {
+		config.Logger.Warn("[chat_history] ignoring invalid retention", "path", s.path, "error", err)
+		state.Retention = Retention{}
+	}
 	s.state = cloneFile(state)
+	s.redactor = compileRedaction(state.Redaction)
 	s.details = map[string]Entry{}
 	for _, item := range state.Items {
 		detail, err := readDetailFile(filepath.Join(s.detailDir, item.ID+".json"))
@@ -561,11 +586,7 @@ func (s *Store) rebuildIndexLocked() {
 	if s.state.Limit < DisabledLimit || !isAllowedLimit(s.state.Limit) {
 		s.state.Limit = DefaultLimit
 	}
-	if s.state.Limit == DisabledLimit {
-		s.state.Items = summaries
-		return
-	}
-	if len(summaries) > s.state.Limit {
+	if s.state.Limit != DisabledLimit && len(summaries) > s.state.Limit {
 		keep := make(map[string]struct{}, s.state.Limit)
 		for _, item := range summaries[:s.state.Limit] {
 			keep[item.ID] = struct{}{}
@@ -579,6 +600,7 @@ func (s *Store) rebuildIndexLocked() {
 		summaries = summaries[:s.state.Limit]
 	}
 	s.state.Items = summaries
+	s.searchIndexLocked().sync(s.details)
 }
 
 func (s *Store) nextRevisionLocked() int64 {
@@ -608,6 +630,7 @@ func summaryFromEntry(item Entry) SummaryEntry {
 		StatusCode:     item.StatusCode,
 		ElapsedMs:      item.ElapsedMs,
 		FinishReason:   item.FinishReason,
+		ReplayOf:       item.ReplayOf,
 		DetailRevision: item.Revision,
 	}
 }
@@ -774,10 +797,12 @@ func sortedDetailIDs(ids map[string]struct{}) []string {
 
 func cloneFile(in File) File {
 	out := File{
-		Version:  in.Version,
-		Limit:    in.Limit,
-		Revision: in.Revision,
-		Items:    make([]SummaryEntry, len(in.Items)),
+		Version:   in.Version,
+		Limit:     in.Limit,
+		Retention: in.Retention,
+		Redaction: cloneRedaction(in.Redaction),
+		Revision:  in.Revision,
+		Items:     make([]SummaryEntry, len(in.Items)),
 	}
 	copy(out.Items, in.Items)
 	return out
@@ -0,0 +1,31 @@
+package completionruntime
+
+import (
+	"context"
+	"errors"
+
+	"ds2api/internal/auth"
+	"ds2api/internal/httpapi/openai/history"
+	"ds2api/internal/promptcompat"
+)
+
+// ContextSummarizer returns the history.SummarizeFunc the OpenAI surfaces
+// pass to history.FitContextWindow. The summary completion runs on the
+// request's own account; sessionDone, when set, receives its upstream
+// session afterwards so the caller can apply auto_delete. It lives here
+// rather than in history because history cannot import this package.
+func ContextSummarizer(ds DeepSeekCaller, store history.CurrentInputConfigReader, a *auth.RequestAuth, sessionDone func(ctx context.Context, sessionID string)) history.SummarizeFunc {
+	return func(ctx context.Context, req promptcompat.StandardRequest) (string, error) {
+		result, outErr := ExecuteNonStreamWithRetry(ctx, ds, a, req, Options{
+			RetryEnabled:     true,
+			CurrentInputFile: store,
+		})
+		if result.SessionID != "" && sessionDone != nil {
+			sessionDone(ctx, result.SessionID)
+		}
+		if outErr != nil {
+			return "", errors.New(outErr.Message)
+		}
+		return result.Turn.Text, nil
+	}
+}
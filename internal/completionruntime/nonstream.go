@@ -7,12 +7,14 @@ import (
 	"net/http"
 	"strings"
 
+	"ds2api/internal/accesslog"
 	"ds2api/internal/assistantturn"
 	"ds2api/internal/auth"
 	"ds2api/internal/config"
 	dsclient "ds2api/internal/deepseek/client"
 	"ds2api/internal/httpapi/openai/history"
 	"ds2api/internal/httpapi/openai/shared"
+	"ds2api/internal/inflight"
 	"ds2api/internal/promptcompat"
 	"ds2api/internal/sse"
 )
@@ -30,6 +32,9 @@ type Options struct {
 	RetryEnabled          bool
 	RetryMaxAttempts      int
 	CurrentInputFile      history.CurrentInputConfigReader
+	// OnPart, when set, receives thinking and text as they are collected,
+	// including output of empty-output retries.
+	OnPart func(sse.ContentPart)
 }
 
 type NonStreamResult struct {
@@ -52,6 +57,10 @@ func StartCompletion(ctx context.Context, ds DeepSeekCaller, a *auth.RequestAuth
 	if maxAttempts <= 0 {
 		maxAttempts = 3
 	}
+	entry := accesslog.FromContext(ctx)
+	entry.SetRequest(stdReq.Surface, stdReq.RequestedModel, stdReq.ResolvedModel, stdReq.Stream)
+	entry.SetPrompt(stdReq.FinalPrompt)
+	inflight.FromContext(ctx).SetRequest(stdReq.Surface, stdReq.RequestedModel, stdReq.ResolvedModel, stdReq.Stream)
 	var prepErr *assistantturn.OutputError
 	stdReq, prepErr = prepareCurrentInputFile(ctx, ds, a, stdReq, opts)
 	if prepErr != nil {
@@ -122,7 +131,10 @@ func ExecuteNonStreamWithRetry(ctx context.Context, ds DeepSeekCaller, a *auth.R
 			ToolDetectionThinking: turn.DetectionThinking,
 			ContentFilter:         turn.ContentFilter,
 			CitationLinks:         turn.CitationLinks,
+			Citations:             turn.Citations,
 			ResponseMessageID:     turn.ResponseMessageID,
+			LimitReason:           turn.LimitReason,
+			StopSequence:          turn.StopSequence,
 		}, buildOptions(stdReq, usagePrompt, opts))
 
 		retryMax := opts.RetryMaxAttempts
@@ -134,6 +146,7 @@ func ExecuteNonStreamWithRetry(ctx context.Context, ds DeepSeekCaller, a *auth.R
 		}
 
 		attempts++
+		accesslog.FromContext(ctx).AddRetry()
 		config.Logger.Info("[completion_runtime_empty_retry] attempting synthetic retry", "surface", stdReq.Surface, "stream", false, "retry_attempt", attempts, "parent_message_id", turn.ResponseMessageID)
 		retryPow, powErr := ds.GetPow(ctx, a, maxAttempts)
 		if powErr != nil {
@@ -164,7 +177,7 @@ func collectAttempt(resp *http.Response, stdReq promptcompat.StandardRequest, us
 		}
 		return assistantturn.Turn{}, &assistantturn.OutputError{Status: resp.StatusCode, Message: message, Code: "error"}
 	}
-	result := sse.CollectStream(resp, stdReq.Thinking, false)
+	result := sse.CollectStreamObserved(resp, stdReq.Thinking, false, stdReq.OutputLimits(), opts.OnPart)
 	return assistantturn.BuildTurnFromCollected(result, buildOptions(stdReq, usagePrompt, opts)), nil
 }
 
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"slices"
 	"strings"
 )
@@ -29,10 +30,10 @@ func (c Config) MarshalJSON() ([]byte, error) {
 	if len(c.ModelAliases) > 0 {
 		m["model_aliases"] = c.ModelAliases
 	}
-	if strings.TrimSpace(c.Admin.PasswordHash) != "" || c.Admin.JWTExpireHours > 0 || c.Admin.JWTValidAfterUnix > 0 {
+	if strings.TrimSpace(c.Admin.PasswordHash) != "" || c.Admin.JWTExpireHours > 0 || c.Admin.JWTValidAfterUnix > 0 || len(c.Admin.Users) > 0 || len(c.Admin.APITokens) > 0 || !c.Admin.OIDC.IsZero() {
 		m["admin"] = c.Admin
 	}
-	if c.Runtime.AccountMaxInflight > 0 || c.Runtime.AccountMaxQueue > 0 || c.Runtime.GlobalMaxInflight > 0 || c.Runtime.TokenRefreshIntervalHours > 0 {
+	if c.Runtime.AccountMaxInflight > 0 || c.Runtime.AccountMaxQueue > 0 || c.Runtime.GlobalMaxInflight > 0 || c.Runtime.TokenRefreshIntervalHours > 0 || c.Runtime.MaxChoices > 0 || strings.TrimSpace(c.Runtime.ChoiceAccountPolicy) != "" {
 		m["runtime"] = c.Runtime
 	}
 	if c.Responses.StoreTTLSeconds > 0 {
@@ -48,6 +49,18 @@ func (c Config) MarshalJSON() ([]byte, error) {
 	if c.ThinkingInjection.Enabled != nil || strings.TrimSpace(c.ThinkingInjection.Prompt) != "" {
 		m["thinking_injection"] = c.ThinkingInjection
 	}
+	if c.ContextLimit.Enabled || c.ContextLimit.DefaultTokens > 0 || len(c.ContextLimit.Models) > 0 || strings.TrimSpace(c.ContextLimit.Strategy) != "" || c.ContextLimit.KeepLastTurns > 0 || c.ContextLimit.ToolResultMaxChars > 0 {
+		m["context_limit"] = c.ContextLimit
+	}
+	if c.Prefetch != (PrefetchConfig{}) {
+		m["prefetch"] = c.Prefetch
+	}
+	if c.SessionCleanup != (SessionCleanupConfig{}) {
+		m["session_cleanup"] = c.SessionCleanup
+	}
+	if strings.TrimSpace(c.Scheduling.DefaultClass) != "" || c.Scheduling.AllowPriorityHeader || len(c.Scheduling.Classes) > 0 {
+		m["scheduling"] = c.Scheduling
+	}
 	if strings.TrimSpace(c.Vercel.Token) != "" || strings.TrimSpace(c.Vercel.ProjectID) != "" || strings.TrimSpace(c.Vercel.TeamID) != "" {
 		m["vercel"] = NormalizeVercelConfig(c.Vercel)
 	}
@@ -128,6 +141,22 @@ func (c *Config) UnmarshalJSON(b []byte) error {
 			if err := json.Unmarshal(v, &c.ThinkingInjection); err != nil {
 				return fmt.Errorf("invalid field %q: %w", k, err)
 			}
+		case "context_limit":
+			if err := json.Unmarshal(v, &c.ContextLimit); err != nil {
+				return fmt.Errorf("invalid field %q: %w", k, err)
+			}
+		case "prefetch":
+			if err := json.Unmarshal(v, &c.Prefetch); err != nil {
+				return fmt.Errorf("invalid field %q: %w", k, err)
+			}
+		case "session_cleanup":
+			if err := json.Unmarshal(v, &c.SessionCleanup); err != nil {
+				return fmt.Errorf("invalid field %q: %w", k, err)
+			}
+		case "scheduling":
+			if err := json.Unmarshal(v, &c.Scheduling); err != nil {
+				return fmt.Errorf("invalid field %q: %w", k, err)
+			}
 		case "vercel":
 			if err := json.Unmarshal(v, &c.Vercel); err != nil {
 				return fmt.Errorf("invalid field %q: %w", k, err)
@@ -158,7 +187,7 @@ func (c Config) Clone() Config {
 		Accounts:     slices.Clone(c.Accounts),
 		Proxies:      slices.Clone(c.Proxies),
 		ModelAliases: cloneStringMap(c.ModelAliases),
-		Admin:        c.Admin,
+		Admin:        c.Admin.Clone(),
 		Runtime:      c.Runtime,
 		Responses:    c.Responses,
 		Embeddings:   c.Embeddings,
@@ -171,6 +200,21 @@ func (c Config) Clone() Config {
 			Enabled: cloneBoolPtr(c.ThinkingInjection.Enabled),
 			Prompt:  c.ThinkingInjection.Prompt,
 		},
+		ContextLimit: ContextLimitConfig{
+			Enabled:            c.ContextLimit.Enabled,
+			DefaultTokens:      c.ContextLimit.DefaultTokens,
+			Models:             maps.Clone(c.ContextLimit.Models),
+			Strategy:           c.ContextLimit.Strategy,
+			KeepLastTurns:      c.ContextLimit.KeepLastTurns,
+			ToolResultMaxChars: c.ContextLimit.ToolResultMaxChars,
+		},
+		Prefetch:       c.Prefetch,
+		SessionCleanup: c.SessionCleanup,
+		Scheduling: SchedulingConfig{
+			DefaultClass:        c.Scheduling.DefaultClass,
+			AllowPriorityHeader: c.Scheduling.AllowPriorityHeader,
+			Classes:             slices.Clone(c.Scheduling.Classes),
+		},
 		Vercel:           c.Vercel,
 		VercelSyncHash:   c.VercelSyncHash,
 		VercelSyncTime:   c.VercelSyncTime,
@@ -4,6 +4,7 @@ import (
 	"crypto/sha1"
 	"encoding/hex"
 	"fmt"
+	"slices"
 	"strings"
 )
 
@@ -20,6 +21,10 @@ type Config struct {
 	AutoDelete        AutoDeleteConfig        `json:"auto_delete"`
 	CurrentInputFile  CurrentInputFileConfig  `json:"current_input_file,omitempty"`
 	ThinkingInjection ThinkingInjectionConfig `json:"thinking_injection,omitempty"`
+	ContextLimit      ContextLimitConfig      `json:"context_limit,omitempty"`
+	Prefetch          PrefetchConfig          `json:"prefetch,omitempty"`
+	SessionCleanup    SessionCleanupConfig    `json:"session_cleanup,omitempty"`
+	Scheduling        SchedulingConfig        `json:"scheduling,omitempty"`
 	Vercel            VercelConfig            `json:"vercel,omitempty"`
 	VercelSyncHash    string                  `json:"_vercel_sync_hash,omitempty"`
 	VercelSyncTime    int64                   `json:"_vercel_sync_time,omitempty"`
@@ -40,6 +45,10 @@ type APIKey struct {
 	Key    string `json:"key"`
 	Name   string `json:"name,omitempty"`
 	Remark string `json:"remark,omitempty"`
+	// Priority names the scheduling class of requests made with this key;
+	// Weight overrides that class's fair-share weight.
+	Priority string `json:"priority,omitempty"`
+	Weight   int    `json:"weight,omitempty"`
 }
 
 type Proxy struct {
@@ -143,18 +152,113 @@ func (c *Config) normalizeModelAliases() {
 }
 
 type AdminConfig struct {
-	PasswordHash      string `json:"password_hash,omitempty"`
-	JWTExpireHours    int    `json:"jwt_expire_hours,omitempty"`
+	PasswordHash      string          `json:"password_hash,omitempty"`
+	JWTExpireHours    int             `json:"jwt_expire_hours,omitempty"`
+	JWTValidAfterUnix int64           `json:"jwt_valid_after_unix,omitempty"`
+	Users             []AdminUser     `json:"users,omitempty"`
+	APITokens         []AdminAPIToken `json:"api_tokens,omitempty"`
+	OIDC              AdminOIDCConfig `json:"oidc,omitempty"`
+}
+
+// AdminOIDCConfig enables OpenID Connect single sign-on for the admin panel.
+// A successful login is exchanged for the regular admin JWT.
+type AdminOIDCConfig struct {
+	Enabled             bool                   `json:"enabled,omitempty"`
+	DisplayName         string                 `json:"display_name,omitempty"`
+	Issuer              string                 `json:"issuer,omitempty"`
+	ClientID            string                 `json:"client_id,omitempty"`
+	ClientSecret        string                 `json:"client_secret,omitempty"`
+	RedirectURL         string                 `json:"redirect_url,omitempty"`
+	Scopes              []string               `json:"scopes,omitempty"`
+	AllowedEmailDomains []string               `json:"allowed_email_domains,omitempty"`
+	AllowedGroups       []string               `json:"allowed_groups,omitempty"`
+	GroupsClaim         string                 `json:"groups_claim,omitempty"`
+	RoleMappings        []AdminOIDCRoleMapping `json:"role_mappings,omitempty"`
+	DefaultRole         string                 `json:"default_role,omitempty"`
+}
+
+// AdminOIDCRoleMapping grants Role when the ID token claim Claim equals Value
+// (or contains it, for list claims such as groups).
+type AdminOIDCRoleMapping struct {
+	Claim string `json:"claim"`
+	Value string `json:"value"`
+	Role  string `json:"role"`
+}
+
+func (o AdminOIDCConfig) IsZero() bool {
+	return !o.Enabled && strings.TrimSpace(o.Issuer) == "" && strings.TrimSpace(o.ClientID) == ""
+}
+
+func (o AdminOIDCConfig) Clone() AdminOIDCConfig {
+	o.Scopes = slices.Clone(o.Scopes)
+	o.AllowedEmailDomains = slices.Clone(o.AllowedEmailDomains)
+	o.AllowedGroups = slices.Clone(o.AllowedGroups)
+	o.RoleMappings = slices.Clone(o.RoleMappings)
+	return o
+}
+
+// AdminUser is a named admin panel login. The legacy shared admin key keeps
+// working alongside these users and always maps to the owner role.
+type AdminUser struct {
+	Username          string `json:"username"`
+	PasswordHash      string `json:"password_hash"`
+	Role              string `json:"role"`
+	Disabled          bool   `json:"disabled,omitempty"`
 	JWTValidAfterUnix int64  `json:"jwt_valid_after_unix,omitempty"`
 }
 
+// AdminAPIToken is a long-lived admin credential for automation. Only the
+// SHA-256 hash of the secret is stored; the plaintext is returned once.
+type AdminAPIToken struct {
+	ID            string `json:"id"`
+	Name          string `json:"name,omitempty"`
+	TokenHash     string `json:"token_hash"`
+	Role          string `json:"role"`
+	CreatedBy     string `json:"created_by,omitempty"`
+	CreatedAtUnix int64  `json:"created_at_unix,omitempty"`
+	ExpiresAtUnix int64  `json:"expires_at_unix,omitempty"`
+}
+
+const (
+	AdminRoleViewer   = "viewer"
+	AdminRoleOperator = "operator"
+	AdminRoleOwner    = "owner"
+)
+
+// NormalizeAdminRole lower-cases a role name and returns "" for unknown roles.
+func NormalizeAdminRole(role string) string {
+	role = strings.ToLower(strings.TrimSpace(role))
+	switch role {
+	case AdminRoleViewer, AdminRoleOperator, AdminRoleOwner:
+		return role
+	}
+	return ""
+}
+
+func (a AdminConfig) Clone() AdminConfig {
+	a.Users = slices.Clone(a.Users)
+	a.APITokens = slices.Clone(a.APITokens)
+	a.OIDC = a.OIDC.Clone()
+	return a
+}
+
 type RuntimeConfig struct {
 	AccountMaxInflight        int `json:"account_max_inflight,omitempty"`
 	AccountMaxQueue           int `json:"account_max_queue,omitempty"`
 	GlobalMaxInflight         int `json:"global_max_inflight,omitempty"`
 	TokenRefreshIntervalHours int `json:"token_refresh_interval_hours,omitempty"`
+	// MaxChoices caps OpenAI n / Gemini candidateCount per request.
+	MaxChoices int `json:"max_choices,omitempty"`
+	// ChoiceAccountPolicy is "same" (all choices share the request's
+	// account) or "spread" (each extra choice acquires its own pool slot).
+	ChoiceAccountPolicy string `json:"choice_account_policy,omitempty"`
 }
 
+const (
+	ChoiceAccountPolicySame   = "same"
+	ChoiceAccountPolicySpread = "spread"
+)
+
 type ResponsesConfig struct {
 	StoreTTLSeconds int `json:"store_ttl_seconds,omitempty"`
 }
@@ -178,6 +282,86 @@ type ThinkingInjectionConfig struct {
 	Prompt  string `json:"prompt,omitempty"`
 }
 
+// ContextLimitConfig sets the per-model prompt token budget. When Enabled,
+// chat and responses requests whose prompt exceeds the budget are first
+// shrunk with Strategy, then rejected locally with context_length_exceeded if
+// they still do not fit.
+type ContextLimitConfig struct {
+	Enabled       bool           `json:"enabled,omitempty"`
+	DefaultTokens int            `json:"default_tokens,omitempty"`
+	Models        map[string]int `json:"models,omitempty"`
+	// Strategy is "reject" (default), "drop_oldest", "trim_tool_results" or
+	// "summarize".
+	Strategy           string `json:"strategy,omitempty"`
+	KeepLastTurns      int    `json:"keep_last_turns,omitempty"`
+	ToolResultMaxChars int    `json:"tool_result_max_chars,omitempty"`
+}
+
+// DefaultContextLimitTokens is the budget used when neither the model nor
+// context_limit.default_tokens sets one.
+const DefaultContextLimitTokens = 131072
+
+const (
+	ContextStrategyReject          = "reject"
+	ContextStrategyDropOldest      = "drop_oldest"
+	ContextStrategyTrimToolResults = "trim_tool_results"
+	ContextStrategySummarize       = "summarize"
+)
+
+// PrefetchConfig controls the per-account warm pool of pre-created chat
+// sessions and pre-solved PoW answers for managed accounts.
+type PrefetchConfig struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// Sessions and Pows are how many ready items each account keeps (1-2,
+	// default 1).
+	Sessions int `json:"sessions,omitempty"`
+	Pows     int `json:"pows,omitempty"`
+	// SessionMaxAgeSeconds discards pooled sessions older than this
+	// (default 600).
+	SessionMaxAgeSeconds int `json:"session_max_age_seconds,omitempty"`
+}
+
+// SessionCleanupConfig schedules deletion of old upstream chat sessions on
+// managed accounts. A session is deleted when it is older than MaxAgeHours or
+// falls beyond the KeepNewest most recent ones; at least one must be set.
+type SessionCleanupConfig struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// IntervalMinutes is the time between runs (default 60).
+	IntervalMinutes int `json:"interval_minutes,omitempty"`
+	MaxAgeHours     int `json:"max_age_hours,omitempty"`
+	KeepNewest      int `json:"keep_newest,omitempty"`
+	// DeletesPerSecond rate-limits deletes per account (default 2).
+	DeletesPerSecond int `json:"deletes_per_second,omitempty"`
+	// MaxPages bounds how many session list pages are read per account
+	// (default 20).
+	MaxPages int `json:"max_pages,omitempty"`
+}
+
+// SchedulingConfig assigns requests waiting for a managed account to priority
+// classes. Waiters are woken by weighted fair queuing across callers, and
+// each class has its own queue limit and wait timeout.
+type SchedulingConfig struct {
+	// DefaultClass applies to keys without a priority (default: the first
+	// class).
+	DefaultClass string `json:"default_class,omitempty"`
+	// AllowPriorityHeader lets callers pick a class with X-Ds2-Priority.
+	AllowPriorityHeader bool            `json:"allow_priority_header,omitempty"`
+	Classes             []PriorityClass `json:"classes,omitempty"`
+}
+
+type PriorityClass struct {
+	Name string `json:"name"`
+	// Weight is the fair-share weight of each caller in the class (1-100,
+	// default 1).
+	Weight int `json:"weight,omitempty"`
+	// MaxQueue caps waiters of this class on top of the global queue limit;
+	// 0 means only the global limit applies.
+	MaxQueue int `json:"max_queue,omitempty"`
+	// WaitTimeoutSeconds bounds how long a request waits for an account; 0
+	// waits until the request is cancelled.
+	WaitTimeoutSeconds int `json:"wait_timeout_seconds,omitempty"`
+}
+
 type VercelConfig struct {
 	Token     string `json:"token,omitempty"`
 	ProjectID string `json:"project_id,omitempty"`
@@ -2,10 +2,12 @@ package config
 
 import (
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestAccountIdentifierRequiresEmailOrMobile(t *testing.T) {
@@ -359,6 +361,100 @@ func TestRuntimeTokenRefreshIntervalHoursUsesConfigValue(t *testing.T) {
 	}
 }
 
+func TestRuntimeChoiceSettingsRoundTrip(t *testing.T) {
+	cfg := Config{Runtime: RuntimeConfig{MaxChoices: 8, ChoiceAccountPolicy: ChoiceAccountPolicySpread}}
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var decoded Config
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if decoded.Runtime.MaxChoices != 8 || decoded.Runtime.ChoiceAccountPolicy != ChoiceAccountPolicySpread {
+		t.Fatalf("expected choice settings to survive a round trip, got %+v", decoded.Runtime)
+	}
+}
+
+func TestContextLimitTokensFallsBackToBaseModelAndDefault(t *testing.T) {
+	t.Setenv("DS2API_CONFIG_JSON", `{
+		"context_limit":{"enabled":true,"default_tokens":65536,"models":{"deepseek-v4-pro":262144}}
+	}`)
+	store := LoadStore()
+	if !store.ContextLimitEnabled() {
+		t.Fatal("expected context limit to be enabled")
+	}
+	if got := store.ContextLimitTokens("deepseek-v4-pro-nothinking"); got != 262144 {
+		t.Fatalf("expected -nothinking to use base model budget, got %d", got)
+	}
+	if got := store.ContextLimitTokens("deepseek-v4-flash"); got != 65536 {
+		t.Fatalf("expected default budget, got %d", got)
+	}
+	if err := ValidateConfig(Config{ContextLimit: ContextLimitConfig{Models: map[string]int{"deepseek-v4-pro": 10}}}); err == nil {
+		t.Fatal("expected tiny model budget to be rejected")
+	}
+	if err := ValidateConfig(Config{ContextLimit: ContextLimitConfig{Strategy: "shrink"}}); err == nil {
+		t.Fatal("expected unknown strategy to be rejected")
+	}
+	if got := store.ContextLimitStrategy(); got != ContextStrategyReject {
+		t.Fatalf("expected reject as the default strategy, got %q", got)
+	}
+}
+
+func TestPrefetchDefaultsAndAutoDeleteAll(t *testing.T) {
+	t.Setenv("DS2API_CONFIG_JSON", `{"prefetch":{"enabled":true},"auto_delete":{"mode":"all"}}`)
+	store := LoadStore()
+	if !store.PrefetchEnabled() || store.PrefetchPows() != 1 || store.PrefetchSessionMaxAge() != 10*time.Minute {
+		t.Fatalf("unexpected prefetch defaults: %+v", store.Snapshot().Prefetch)
+	}
+	if got := store.PrefetchSessions(); got != 0 {
+		t.Fatalf("expected no session prefetch with auto_delete all, got %d", got)
+	}
+	if err := ValidateConfig(Config{Prefetch: PrefetchConfig{Sessions: 3}}); err == nil {
+		t.Fatal("expected more than two prefetched sessions to be rejected")
+	}
+}
+
+func TestSessionCleanupDefaultsAndValidation(t *testing.T) {
+	t.Setenv("DS2API_CONFIG_JSON", `{"session_cleanup":{"enabled":true,"keep_newest":50}}`)
+	store := LoadStore()
+	if !store.SessionCleanupEnabled() || store.SessionCleanupInterval() != time.Hour || store.SessionCleanupDeletesPerSecond() != 2 || store.SessionCleanupMaxPages() != 20 {
+		t.Fatalf("unexpected session cleanup defaults: %+v", store.Snapshot().SessionCleanup)
+	}
+	if store.SessionCleanupKeepNewest() != 50 || store.SessionCleanupMaxAge() != 0 {
+		t.Fatalf("unexpected session cleanup criteria: %+v", store.Snapshot().SessionCleanup)
+	}
+	if err := ValidateConfig(Config{SessionCleanup: SessionCleanupConfig{Enabled: true}}); err == nil {
+		t.Fatal("expected enabled cleanup without criteria to be rejected")
+	}
+}
+
+func TestSchedulingClassesAndKeyPriority(t *testing.T) {
+	t.Setenv("DS2API_CONFIG_JSON", `{
+		"api_keys":[{"key":"k1","name":"batch-job","priority":"batch","weight":2},{"key":"k2"}],
+		"scheduling":{"default_class":"interactive","classes":[
+			{"name":"interactive","weight":4},
+			{"name":"batch","max_queue":5,"wait_timeout_seconds":10}
+		]}
+	}`)
+	store := LoadStore()
+	if key, ok := store.APIKeyInfo("k1"); !ok || key.Priority != "batch" || key.Weight != 2 {
+		t.Fatalf("expected key priority metadata to load, got %+v", key)
+	}
+	if got := store.SchedulingClass("batch"); got.Weight != 1 || got.MaxQueue != 5 || got.WaitTimeoutSeconds != 10 {
+		t.Fatalf("unexpected batch class: %+v", got)
+	}
+	if got := store.SchedulingClass("unknown"); got.Name != "interactive" || got.Weight != 4 {
+		t.Fatalf("expected unknown class to fall back to default, got %+v", got)
+	}
+	if err := ValidateConfig(Config{APIKeys: []APIKey{{Key: "k", Priority: "missing"}}}); err == nil {
+		t.Fatal("expected key priority without a matching class to be rejected")
+	}
+	if got := (&Store{}).SchedulingClass(""); got.Name != DefaultPriorityClass || got.Weight != 1 {
+		t.Fatalf("unexpected implicit class: %+v", got)
+	}
+}
+
 func TestStoreUpdateAccountTokenKeepsIdentifierResolvable(t *testing.T) {
 	t.Setenv("DS2API_CONFIG_JSON", `{
 		"accounts":[{"email":"user@example.com","password":"p"}]
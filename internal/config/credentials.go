@@ -64,9 +64,11 @@ func normalizeAPIKeys(items []APIKey) []APIKey {
 		}
 		seen[key] = struct{}{}
 		out = append(out, APIKey{
-			Key:    key,
-			Name:   strings.TrimSpace(item.Name),
-			Remark: strings.TrimSpace(item.Remark),
+			Key:      key,
+			Name:     strings.TrimSpace(item.Name),
+			Remark:   strings.TrimSpace(item.Remark),
+			Priority: strings.TrimSpace(item.Priority),
+			Weight:   item.Weight,
 		})
 	}
 	if len(out) == 0 {
@@ -92,9 +94,11 @@ func apiKeysFromStrings(keys []string, meta map[string]APIKey) []APIKey {
 		seen[key] = struct{}{}
 		if item, ok := meta[key]; ok {
 			out = append(out, APIKey{
-				Key:    key,
-				Name:   strings.TrimSpace(item.Name),
-				Remark: strings.TrimSpace(item.Remark),
+				Key:      key,
+				Name:     strings.TrimSpace(item.Name),
+				Remark:   strings.TrimSpace(item.Remark),
+				Priority: strings.TrimSpace(item.Priority),
+				Weight:   item.Weight,
 			})
 			continue
 		}
@@ -138,9 +142,11 @@ func apiKeyMap(items []APIKey) map[string]APIKey {
 			continue
 		}
 		out[key] = APIKey{
-			Key:    key,
-			Name:   strings.TrimSpace(item.Name),
-			Remark: strings.TrimSpace(item.Remark),
+			Key:      key,
+			Name:     strings.TrimSpace(item.Name),
+			Remark:   strings.TrimSpace(item.Remark),
+			Priority: strings.TrimSpace(item.Priority),
+			Weight:   item.Weight,
 		}
 	}
 	return out
@@ -153,6 +159,8 @@ func equalAPIKeys(a, b []APIKey) bool {
 	return slices.EqualFunc(a, b, func(x, y APIKey) bool {
 		return strings.TrimSpace(x.Key) == strings.TrimSpace(y.Key) &&
 			strings.TrimSpace(x.Name) == strings.TrimSpace(y.Name) &&
-			strings.TrimSpace(x.Remark) == strings.TrimSpace(y.Remark)
+			strings.TrimSpace(x.Remark) == strings.TrimSpace(y.Remark) &&
+			strings.TrimSpace(x.Priority) == strings.TrimSpace(y.Priority) &&
+			x.Weight == y.Weight
 	})
 }
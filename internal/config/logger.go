@@ -20,8 +20,17 @@ func newLogger() *slog.Logger {
 	default:
 		level.Set(slog.LevelInfo)
 	}
-	h := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: level})
-	return slog.New(h)
+	opts := &slog.HandlerOptions{Level: level}
+	if LogFormatJSON() {
+		return slog.New(slog.NewJSONHandler(os.Stdout, opts))
+	}
+	return slog.New(slog.NewTextHandler(os.Stdout, opts))
+}
+
+// LogFormatJSON reports whether LOG_FORMAT selects JSON for both the app
+// logger and the HTTP access log. The default is text.
+func LogFormatJSON() bool {
+	return strings.EqualFold(strings.TrimSpace(os.Getenv("LOG_FORMAT")), "json")
 }
 
 func RefreshLogger() {
@@ -2,8 +2,10 @@ package config
 
 import (
 	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 )
 
 func (s *Store) ModelAliases() map[string]string {
@@ -84,6 +86,36 @@ func (s *Store) AdminJWTValidAfterUnix() int64 {
 	return s.cfg.Admin.JWTValidAfterUnix
 }
 
+func (s *Store) AdminUsers() []AdminUser {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.cfg.Admin.Users)
+}
+
+func (s *Store) AdminAPITokens() []AdminAPIToken {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.cfg.Admin.APITokens)
+}
+
+// AdminOIDC returns the admin SSO settings. DS2API_OIDC_CLIENT_SECRET, when
+// set, takes precedence over the stored client secret.
+func (s *Store) AdminOIDC() AdminOIDCConfig {
+	s.mu.RLock()
+	cfg := s.cfg.Admin.OIDC.Clone()
+	s.mu.RUnlock()
+	if secret := strings.TrimSpace(os.Getenv("DS2API_OIDC_CLIENT_SECRET")); secret != "" {
+		cfg.ClientSecret = secret
+	}
+	return cfg
+}
+
+func (s *Store) AdminOIDCEnabled() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg.Admin.OIDC.Enabled
+}
+
 func (s *Store) RuntimeAccountMaxInflight() int {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -141,6 +173,29 @@ func (s *Store) RuntimeTokenRefreshIntervalHours() int {
 	return 6
 }
 
+func (s *Store) RuntimeMaxChoices() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.cfg.Runtime.MaxChoices > 0 {
+		return s.cfg.Runtime.MaxChoices
+	}
+	if raw := strings.TrimSpace(os.Getenv("DS2API_MAX_CHOICES")); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 4
+}
+
+func (s *Store) RuntimeChoiceAccountPolicy() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if policy := strings.ToLower(strings.TrimSpace(s.cfg.Runtime.ChoiceAccountPolicy)); policy == ChoiceAccountPolicySpread {
+		return policy
+	}
+	return ChoiceAccountPolicySame
+}
+
 func (s *Store) AutoDeleteSessions() bool {
 	return s.AutoDeleteMode() != "none"
 }
@@ -160,6 +215,218 @@ func (s *Store) CurrentInputFileMinChars() int {
 	return s.cfg.CurrentInputFile.MinChars
 }
 
+func (s *Store) ContextLimitEnabled() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg.ContextLimit.Enabled
+}
+
+// ContextLimitTokens returns the prompt token budget for a resolved model. A
+// "-nothinking" variant falls back to its base model's entry, then to the
+// configured default.
+func (s *Store) ContextLimitTokens(model string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	model = strings.ToLower(strings.TrimSpace(model))
+	for _, key := range []string{model, strings.TrimSuffix(model, "-nothinking")} {
+		if n := s.cfg.ContextLimit.Models[key]; n > 0 {
+			return n
+		}
+	}
+	if n := s.cfg.ContextLimit.DefaultTokens; n > 0 {
+		return n
+	}
+	return DefaultContextLimitTokens
+}
+
+func (s *Store) ContextLimitStrategy() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if strategy := strings.ToLower(strings.TrimSpace(s.cfg.ContextLimit.Strategy)); strategy != "" {
+		return strategy
+	}
+	return ContextStrategyReject
+}
+
+func (s *Store) ContextLimitKeepLastTurns() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.cfg.ContextLimit.KeepLastTurns > 0 {
+		return s.cfg.ContextLimit.KeepLastTurns
+	}
+	return 4
+}
+
+func (s *Store) ContextLimitToolResultMaxChars() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.cfg.ContextLimit.ToolResultMaxChars > 0 {
+		return s.cfg.ContextLimit.ToolResultMaxChars
+	}
+	return 4000
+}
+
+func (s *Store) PrefetchEnabled() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg.Prefetch.Enabled
+}
+
+// PrefetchSessions returns how many pre-created sessions each account keeps.
+// It is 0 when auto_delete mode is "all", because every request would wipe
+// the pooled sessions anyway.
+func (s *Store) PrefetchSessions() int {
+	if s.AutoDeleteMode() == "all" {
+		return 0
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.cfg.Prefetch.Sessions > 0 {
+		return s.cfg.Prefetch.Sessions
+	}
+	return 1
+}
+
+func (s *Store) PrefetchPows() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.cfg.Prefetch.Pows > 0 {
+		return s.cfg.Prefetch.Pows
+	}
+	return 1
+}
+
+func (s *Store) PrefetchSessionMaxAge() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.cfg.Prefetch.SessionMaxAgeSeconds > 0 {
+		return time.Duration(s.cfg.Prefetch.SessionMaxAgeSeconds) * time.Second
+	}
+	return 10 * time.Minute
+}
+
+func (s *Store) SessionCleanupEnabled() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg.SessionCleanup.Enabled
+}
+
+func (s *Store) SessionCleanupInterval() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.cfg.SessionCleanup.IntervalMinutes > 0 {
+		return time.Duration(s.cfg.SessionCleanup.IntervalMinutes) * time.Minute
+	}
+	return time.Hour
+}
+
+// SessionCleanupMaxAge returns the age past which sessions are deleted, or 0
+// when age is not a criterion.
+func (s *Store) SessionCleanupMaxAge() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return time.Duration(s.cfg.SessionCleanup.MaxAgeHours) * time.Hour
+}
+
+// SessionCleanupKeepNewest returns how many recent sessions are always kept,
+// or 0 when count is not a criterion.
+func (s *Store) SessionCleanupKeepNewest() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg.SessionCleanup.KeepNewest
+}
+
+func (s *Store) SessionCleanupDeletesPerSecond() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.cfg.SessionCleanup.DeletesPerSecond > 0 {
+		return s.cfg.SessionCleanup.DeletesPerSecond
+	}
+	return 2
+}
+
+func (s *Store) SessionCleanupMaxPages() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.cfg.SessionCleanup.MaxPages > 0 {
+		return s.cfg.SessionCleanup.MaxPages
+	}
+	return 20
+}
+
+// DefaultPriorityClass is the implicit class used when scheduling.classes is
+// empty.
+const DefaultPriorityClass = "default"
+
+// SchedulingClass resolves a class by name with defaults applied. Empty or
+// unknown names fall back to the default class.
+func (s *Store) SchedulingClass(name string) PriorityClass {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	classes := s.cfg.Scheduling.Classes
+	if len(classes) == 0 {
+		return PriorityClass{Name: DefaultPriorityClass, Weight: 1}
+	}
+	pick := -1
+	for _, want := range []string{strings.TrimSpace(name), strings.TrimSpace(s.cfg.Scheduling.DefaultClass)} {
+		for i, class := range classes {
+			if want != "" && strings.TrimSpace(class.Name) == want {
+				pick = i
+				break
+			}
+		}
+		if pick >= 0 {
+			break
+		}
+	}
+	if pick < 0 {
+		pick = 0
+	}
+	class := classes[pick]
+	class.Name = strings.TrimSpace(class.Name)
+	if class.Weight <= 0 {
+		class.Weight = 1
+	}
+	return class
+}
+
+// SchedulingClasses lists the configured classes with defaults applied, or
+// the implicit default class.
+func (s *Store) SchedulingClasses() []PriorityClass {
+	s.mu.RLock()
+	names := make([]string, 0, len(s.cfg.Scheduling.Classes))
+	for _, class := range s.cfg.Scheduling.Classes {
+		names = append(names, class.Name)
+	}
+	s.mu.RUnlock()
+	if len(names) == 0 {
+		return []PriorityClass{s.SchedulingClass("")}
+	}
+	out := make([]PriorityClass, 0, len(names))
+	for _, name := range names {
+		out = append(out, s.SchedulingClass(name))
+	}
+	return out
+}
+
+func (s *Store) SchedulingAllowPriorityHeader() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg.Scheduling.AllowPriorityHeader
+}
+
+// APIKeyInfo returns the configured record for key k.
+func (s *Store) APIKeyInfo(k string) (APIKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, item := range s.cfg.APIKeys {
+		if item.Key == k {
+			return item, true
+		}
+	}
+	return APIKey{}, false
+}
+
 func (s *Store) ThinkingInjectionEnabled() bool {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
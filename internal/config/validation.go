@@ -27,6 +27,18 @@ func ValidateConfig(c Config) error {
 	if err := ValidateCurrentInputFileConfig(c.CurrentInputFile); err != nil {
 		return err
 	}
+	if err := ValidateContextLimitConfig(c.ContextLimit); err != nil {
+		return err
+	}
+	if err := ValidatePrefetchConfig(c.Prefetch); err != nil {
+		return err
+	}
+	if err := ValidateSessionCleanupConfig(c.SessionCleanup); err != nil {
+		return err
+	}
+	if err := ValidateSchedulingConfig(c.Scheduling, c.APIKeys); err != nil {
+		return err
+	}
 	if err := ValidateAccountProxyReferences(c.Accounts, c.Proxies); err != nil {
 		return err
 	}
@@ -80,7 +92,72 @@ func ValidateAccountProxyReferences(accounts []Account, proxies []Proxy) error {
 }
 
 func ValidateAdminConfig(admin AdminConfig) error {
-	return ValidateIntRange("admin.jwt_expire_hours", admin.JWTExpireHours, 1, 720, false)
+	if err := ValidateIntRange("admin.jwt_expire_hours", admin.JWTExpireHours, 1, 720, false); err != nil {
+		return err
+	}
+	usernames := make(map[string]struct{}, len(admin.Users))
+	for _, user := range admin.Users {
+		name := strings.ToLower(strings.TrimSpace(user.Username))
+		if err := ValidateTrimmedString("admin.users.username", name, true); err != nil {
+			return err
+		}
+		if name == "admin" {
+			return fmt.Errorf("admin.users.username %q is reserved", name)
+		}
+		if _, ok := usernames[name]; ok {
+			return fmt.Errorf("duplicate admin username: %s", name)
+		}
+		usernames[name] = struct{}{}
+		if err := ValidateTrimmedString("admin.users.password_hash", user.PasswordHash, true); err != nil {
+			return err
+		}
+		if NormalizeAdminRole(user.Role) == "" {
+			return fmt.Errorf("admin.users.role must be one of viewer, operator, owner")
+		}
+	}
+	tokenIDs := make(map[string]struct{}, len(admin.APITokens))
+	for _, token := range admin.APITokens {
+		id := strings.TrimSpace(token.ID)
+		if err := ValidateTrimmedString("admin.api_tokens.id", id, true); err != nil {
+			return err
+		}
+		if _, ok := tokenIDs[id]; ok {
+			return fmt.Errorf("duplicate admin api token id: %s", id)
+		}
+		tokenIDs[id] = struct{}{}
+		if err := ValidateTrimmedString("admin.api_tokens.token_hash", token.TokenHash, true); err != nil {
+			return err
+		}
+		if NormalizeAdminRole(token.Role) == "" {
+			return fmt.Errorf("admin.api_tokens.role must be one of viewer, operator, owner")
+		}
+	}
+	return ValidateAdminOIDCConfig(admin.OIDC)
+}
+
+func ValidateAdminOIDCConfig(oidc AdminOIDCConfig) error {
+	if !oidc.Enabled {
+		return nil
+	}
+	issuer := strings.TrimSpace(oidc.Issuer)
+	if !strings.HasPrefix(issuer, "https://") && !strings.HasPrefix(issuer, "http://") {
+		return fmt.Errorf("admin.oidc.issuer must be an http(s) URL")
+	}
+	if err := ValidateTrimmedString("admin.oidc.client_id", oidc.ClientID, true); err != nil {
+		return err
+	}
+	if role := strings.TrimSpace(oidc.DefaultRole); role != "" && NormalizeAdminRole(role) == "" {
+		return fmt.Errorf("admin.oidc.default_role must be one of viewer, operator, owner")
+	}
+	for _, m := range oidc.RoleMappings {
+		if err := ValidateTrimmedString("admin.oidc.role_mappings.claim", m.Claim, true); err != nil {
+			return err
+		}
+		if NormalizeAdminRole(m.Role) == "" {
+			return fmt.Errorf("admin.oidc.role_mappings.role must be one of viewer, operator, owner")
+		}
+	}
+	return nil
 }
 
 func ValidateRuntimeConfig(runtime RuntimeConfig) error {
@@ -96,12 +173,27 @@ func ValidateRuntimeConfig(runtime RuntimeConfig) error {
 	if err := ValidateIntRange("runtime.token_refresh_interval_hours", runtime.TokenRefreshIntervalHours, 1, 720, false); err != nil {
 		return err
 	}
+	if err := ValidateIntRange("runtime.max_choices", runtime.MaxChoices, 1, 16, false); err != nil {
+		return err
+	}
+	if err := ValidateChoiceAccountPolicy(runtime.ChoiceAccountPolicy); err != nil {
+		return err
+	}
 	if runtime.AccountMaxInflight > 0 && runtime.GlobalMaxInflight > 0 && runtime.GlobalMaxInflight < runtime.AccountMaxInflight {
 		return fmt.Errorf("runtime.global_max_inflight must be >= runtime.account_max_inflight")
 	}
 	return nil
 }
 
+func ValidateChoiceAccountPolicy(policy string) error {
+	switch strings.ToLower(strings.TrimSpace(policy)) {
+	case "", ChoiceAccountPolicySame, ChoiceAccountPolicySpread:
+		return nil
+	default:
+		return fmt.Errorf("runtime.choice_account_policy must be one of same, spread")
+	}
+}
+
 func ValidateResponsesConfig(responses ResponsesConfig) error {
 	return ValidateIntRange("responses.store_ttl_seconds", responses.StoreTTLSeconds, 30, 86400, false)
 }
@@ -121,6 +213,96 @@ func ValidateCurrentInputFileConfig(currentInputFile CurrentInputFileConfig) err
 	return nil
 }
 
+func ValidateContextLimitConfig(contextLimit ContextLimitConfig) error {
+	if err := ValidateIntRange("context_limit.default_tokens", contextLimit.DefaultTokens, 1024, 100000000, false); err != nil {
+		return err
+	}
+	for model, tokens := range contextLimit.Models {
+		if strings.TrimSpace(model) == "" {
+			return fmt.Errorf("context_limit.models keys must not be empty")
+		}
+		if err := ValidateIntRange("context_limit.models."+model, tokens, 1024, 100000000, true); err != nil {
+			return err
+		}
+	}
+	switch strings.ToLower(strings.TrimSpace(contextLimit.Strategy)) {
+	case "", ContextStrategyReject, ContextStrategyDropOldest, ContextStrategyTrimToolResults, ContextStrategySummarize:
+	default:
+		return fmt.Errorf("context_limit.strategy must be one of reject, drop_oldest, trim_tool_results, summarize")
+	}
+	if err := ValidateIntRange("context_limit.keep_last_turns", contextLimit.KeepLastTurns, 1, 1000, false); err != nil {
+		return err
+	}
+	return ValidateIntRange("context_limit.tool_result_max_chars", contextLimit.ToolResultMaxChars, 200, 10000000, false)
+}
+
+func ValidatePrefetchConfig(prefetch PrefetchConfig) error {
+	if err := ValidateIntRange("prefetch.sessions", prefetch.Sessions, 1, 2, false); err != nil {
+		return err
+	}
+	if err := ValidateIntRange("prefetch.pows", prefetch.Pows, 1, 2, false); err != nil {
+		return err
+	}
+	return ValidateIntRange("prefetch.session_max_age_seconds", prefetch.SessionMaxAgeSeconds, 30, 86400, false)
+}
+
+func ValidateSessionCleanupConfig(cleanup SessionCleanupConfig) error {
+	if err := ValidateIntRange("session_cleanup.interval_minutes", cleanup.IntervalMinutes, 5, 10080, false); err != nil {
+		return err
+	}
+	if err := ValidateIntRange("session_cleanup.max_age_hours", cleanup.MaxAgeHours, 1, 8760, false); err != nil {
+		return err
+	}
+	if err := ValidateIntRange("session_cleanup.keep_newest", cleanup.KeepNewest, 1, 10000, false); err != nil {
+		return err
+	}
+	if err := ValidateIntRange("session_cleanup.deletes_per_second", cleanup.DeletesPerSecond, 1, 20, false); err != nil {
+		return err
+	}
+	if err := ValidateIntRange("session_cleanup.max_pages", cleanup.MaxPages, 1, 500, false); err != nil {
+		return err
+	}
+	if cleanup.Enabled && cleanup.MaxAgeHours == 0 && cleanup.KeepNewest == 0 {
+		return fmt.Errorf("session_cleanup requires max_age_hours or keep_newest when enabled")
+	}
+	return nil
+}
+
+func ValidateSchedulingConfig(scheduling SchedulingConfig, keys []APIKey) error {
+	names := map[string]bool{}
+	for i, class := range scheduling.Classes {
+		name := strings.TrimSpace(class.Name)
+		if name == "" {
+			return fmt.Errorf("scheduling.classes[%d].name is required", i)
+		}
+		if names[name] {
+			return fmt.Errorf("scheduling.classes[%d].name %q is duplicated", i, name)
+		}
+		names[name] = true
+		if err := ValidateIntRange(fmt.Sprintf("scheduling.classes[%d].weight", i), class.Weight, 1, 100, false); err != nil {
+			return err
+		}
+		if err := ValidateIntRange(fmt.Sprintf("scheduling.classes[%d].max_queue", i), class.MaxQueue, 1, 100000, false); err != nil {
+			return err
+		}
+		if err := ValidateIntRange(fmt.Sprintf("scheduling.classes[%d].wait_timeout_seconds", i), class.WaitTimeoutSeconds, 1, 3600, false); err != nil {
+			return err
+		}
+	}
+	if name := strings.TrimSpace(scheduling.DefaultClass); name != "" && !names[name] {
+		return fmt.Errorf("scheduling.default_class %q is not a defined class", name)
+	}
+	for _, key := range keys {
+		if name := strings.TrimSpace(key.Priority); name != "" && !names[name] {
+			return fmt.Errorf("api key %q priority %q is not a defined scheduling class", key.Name, name)
+		}
+		if err := ValidateIntRange("api key weight", key.Weight, 1, 100, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func ValidateIntRange(name string, value, min, max int, required bool) error {
 	if value == 0 && !required {
 		return nil
@@ -16,6 +16,24 @@ func TestValidateConfigRejectsInvalidValues(t *testing.T) {
 			cfg:  Config{Admin: AdminConfig{JWTExpireHours: 721}},
 			want: "admin.jwt_expire_hours",
 		},
+		{
+			name: "admin user role",
+			cfg:  Config{Admin: AdminConfig{Users: []AdminUser{{Username: "ops", PasswordHash: "x", Role: "root"}}}},
+			want: "admin.users.role",
+		},
+		{
+			name: "admin user duplicate",
+			cfg: Config{Admin: AdminConfig{Users: []AdminUser{
+				{Username: "ops", PasswordHash: "x", Role: "viewer"},
+				{Username: "OPS", PasswordHash: "y", Role: "owner"},
+			}}},
+			want: "duplicate admin username",
+		},
+		{
+			name: "admin api token hash",
+			cfg:  Config{Admin: AdminConfig{APITokens: []AdminAPIToken{{ID: "t1", Role: "viewer"}}}},
+			want: "admin.api_tokens.token_hash",
+		},
 		{
 			name: "runtime relation",
 			cfg: Config{Runtime: RuntimeConfig{
@@ -0,0 +1,331 @@
+// Package contextwindow shrinks an OpenAI-style message list to fit a prompt
+// token budget. System prompts, the most recent turns and the pairing
+// between assistant tool calls and their tool results are always preserved:
+// history is only removed a whole user turn at a time.
+package contextwindow
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"ds2api/internal/config"
+)
+
+// SummaryPrefix introduces the message that replaces summarised turns.
+const SummaryPrefix = "Summary of the earlier conversation (older turns were condensed to fit the context window):\n\n"
+
+// Options controls one Fit call.
+type Options struct {
+	// Strategy is one of the config.ContextStrategy* values.
+	Strategy string
+	// Budget is the prompt token limit the messages must fit in.
+	Budget int
+	// KeepLastTurns user turns at the end are never modified.
+	KeepLastTurns int
+	// ToolResultMaxChars caps tool results under trim_tool_results.
+	ToolResultMaxChars int
+	// Count returns the prompt tokens the given messages produce.
+	Count func(messages []any) int
+	// Tokens, when positive, is the caller's count of the input messages,
+	// so Fit does not count them again.
+	Tokens int
+	// Summarize condenses older messages into text for the summarize
+	// strategy.
+	Summarize func(ctx context.Context, messages []any) (string, error)
+}
+
+// Report describes what Fit did. Strategy is empty when nothing changed.
+type Report struct {
+	Strategy           string
+	TokensBefore       int
+	TokensAfter        int
+	DroppedMessages    int
+	TrimmedToolResults int
+	SummarizedMessages int
+}
+
+// Applied reports whether the messages were modified.
+func (r Report) Applied() bool {
+	return r.Strategy != ""
+}
+
+// Header renders the report for the X-Ds2api-Context-Window response header.
+func (r Report) Header() string {
+	parts := []string{
+		r.Strategy,
+		"tokens_before=" + strconv.Itoa(r.TokensBefore),
+		"tokens_after=" + strconv.Itoa(r.TokensAfter),
+	}
+	if r.DroppedMessages > 0 {
+		parts = append(parts, "dropped_messages="+strconv.Itoa(r.DroppedMessages))
+	}
+	if r.TrimmedToolResults > 0 {
+		parts = append(parts, "trimmed_tool_results="+strconv.Itoa(r.TrimmedToolResults))
+	}
+	if r.SummarizedMessages > 0 {
+		parts = append(parts, "summarized_messages="+strconv.Itoa(r.SummarizedMessages))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Fit applies opts.Strategy when messages are over opts.Budget and returns
+// the new message list. It never fails because the result is still over
+// budget; callers decide whether to reject what is left.
+func Fit(ctx context.Context, messages []any, opts Options) ([]any, Report, error) {
+	report := Report{TokensBefore: opts.Tokens}
+	if report.TokensBefore <= 0 {
+		report.TokensBefore = opts.Count(messages)
+	}
+	report.TokensAfter = report.TokensBefore
+	if opts.Budget <= 0 || report.TokensBefore <= opts.Budget {
+		return messages, report, nil
+	}
+	var (
+		out    []any
+		tokens int
+		err    error
+	)
+	switch opts.Strategy {
+	case config.ContextStrategyDropOldest:
+		out, tokens = dropOldest(messages, opts, &report)
+	case config.ContextStrategyTrimToolResults:
+		out, tokens = trimToolResults(messages, opts, &report)
+	case config.ContextStrategySummarize:
+		out, err = summarize(ctx, messages, opts, &report)
+	default:
+		return messages, report, nil
+	}
+	if err != nil {
+		return messages, report, err
+	}
+	if report.DroppedMessages+report.TrimmedToolResults+report.SummarizedMessages == 0 {
+		return messages, report, nil
+	}
+	if opts.Strategy == config.ContextStrategySummarize {
+		tokens = opts.Count(out)
+	}
+	report.Strategy = opts.Strategy
+	report.TokensAfter = tokens
+	return out, report, nil
+}
+
+// shortestPrefix finds the fewest of n turns that must be changed for
+// build's result to fit the budget, counting O(log n) candidates instead of
+// one per turn. Changing more turns never adds tokens, so the search is
+// monotonic. When even all n turns are not enough, all n are changed. It
+// returns the chosen k and the token count of build(k).
+func shortestPrefix(n int, opts Options, build func(k int) []any) (int, int) {
+	counts := map[int]int{}
+	count := func(k int) int {
+		if c, ok := counts[k]; ok {
+			return c
+		}
+		counts[k] = opts.Count(build(k))
+		return counts[k]
+	}
+	k := sort.Search(n, func(i int) bool { return count(i+1) <= opts.Budget }) + 1
+	if k > n {
+		k = n
+	}
+	return k, count(k)
+}
+
+// turn is a run of messages starting at a user message (or at the start of
+// the conversation) and ending before the next user message, so an
+// assistant tool call and its tool results always share a turn.
+type turn struct {
+	start, end int
+}
+
+// splitTurns returns the turns that may be modified, oldest first. The last
+// keepLast turns are excluded.
+func splitTurns(messages []any, keepLast int) []turn {
+	var turns []turn
+	for i, raw := range messages {
+		if roleOf(raw) == "user" || len(turns) == 0 {
+			turns = append(turns, turn{start: i, end: i + 1})
+			continue
+		}
+		turns[len(turns)-1].end = i + 1
+	}
+	if keepLast < 1 {
+		keepLast = 1
+	}
+	if len(turns) <= keepLast {
+		return nil
+	}
+	return turns[:len(turns)-keepLast]
+}
+
+// dropOldest removes the oldest modifiable turns, keeping pinned messages,
+// until the rest fits the budget.
+func dropOldest(messages []any, opts Options, report *Report) ([]any, int) {
+	turns := splitTurns(messages, opts.KeepLastTurns)
+	if len(turns) == 0 {
+		return messages, report.TokensBefore
+	}
+	build := func(k int) []any {
+		dropped := make([]bool, len(messages))
+		for i := 0; i < turns[k-1].end; i++ {
+			dropped[i] = !isPinned(messages[i])
+		}
+		return without(messages, dropped)
+	}
+	k, tokens := shortestPrefix(len(turns), opts, build)
+	out := build(k)
+	report.DroppedMessages = len(messages) - len(out)
+	return out, tokens
+}
+
+// trimToolResults shortens tool results in the oldest modifiable turns until
+// the messages fit the budget.
+func trimToolResults(messages []any, opts Options, report *Report) ([]any, int) {
+	limit := opts.ToolResultMaxChars
+	turns := splitTurns(messages, opts.KeepLastTurns)
+	if limit <= 0 || len(turns) == 0 {
+		return messages, report.TokensBefore
+	}
+	// Trim every candidate once; a prefix of turns then takes the trimmed
+	// copies.
+	trimmed := map[int]map[string]any{}
+	for i := 0; i < turns[len(turns)-1].end; i++ {
+		msg, ok := messages[i].(map[string]any)
+		if !ok || roleOf(msg) != "tool" {
+			continue
+		}
+		content, ok := trimContent(msg["content"], limit)
+		if !ok {
+			continue
+		}
+		copied := make(map[string]any, len(msg))
+		for k, v := range msg {
+			copied[k] = v
+		}
+		copied["content"] = content
+		trimmed[i] = copied
+	}
+	if len(trimmed) == 0 {
+		return messages, report.TokensBefore
+	}
+	applied := 0
+	build := func(k int) []any {
+		out := append([]any(nil), messages...)
+		applied = 0
+		for i := 0; i < turns[k-1].end; i++ {
+			if msg, ok := trimmed[i]; ok {
+				out[i] = msg
+				applied++
+			}
+		}
+		return out
+	}
+	k, tokens := shortestPrefix(len(turns), opts, build)
+	out := build(k)
+	report.TrimmedToolResults = applied
+	return out, tokens
+}
+
+func summarize(ctx context.Context, messages []any, opts Options, report *Report) ([]any, error) {
+	turns := splitTurns(messages, opts.KeepLastTurns)
+	if len(turns) == 0 || opts.Summarize == nil {
+		return messages, nil
+	}
+	end := turns[len(turns)-1].end
+	var older []any
+	var out []any
+	for _, raw := range messages[:end] {
+		if isPinned(raw) {
+			out = append(out, raw)
+			continue
+		}
+		older = append(older, raw)
+	}
+	if len(older) == 0 {
+		return messages, nil
+	}
+	summary, err := opts.Summarize(ctx, older)
+	if err != nil {
+		return nil, fmt.Errorf("summarize context: %w", err)
+	}
+	summary = strings.TrimSpace(summary)
+	if summary == "" {
+		return messages, nil
+	}
+	report.SummarizedMessages = len(older)
+	out = append(out, map[string]any{"role": "user", "content": SummaryPrefix + summary})
+	return append(out, messages[end:]...), nil
+}
+
+// trimContent keeps the head and tail of an over-long tool result with a
+// marker in between. Only plain string content and text parts are trimmed.
+func trimContent(content any, limit int) (any, bool) {
+	switch v := content.(type) {
+	case string:
+		return trimText(v, limit)
+	case []any:
+		out := make([]any, len(v))
+		changed := false
+		for i, part := range v {
+			out[i] = part
+			block, ok := part.(map[string]any)
+			if !ok {
+				continue
+			}
+			text, ok := block["text"].(string)
+			if !ok {
+				continue
+			}
+			trimmed, ok := trimText(text, limit)
+			if !ok {
+				continue
+			}
+			copied := make(map[string]any, len(block))
+			for k, val := range block {
+				copied[k] = val
+			}
+			copied["text"] = trimmed
+			out[i] = copied
+			changed = true
+		}
+		return out, changed
+	}
+	return content, false
+}
+
+func trimText(text string, limit int) (string, bool) {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text, false
+	}
+	head := limit / 2
+	tail := limit - head
+	omitted := len(runes) - head - tail
+	return string(runes[:head]) + "\n[... " + strconv.Itoa(omitted) + " characters trimmed ...]\n" + string(runes[len(runes)-tail:]), true
+}
+
+func without(messages []any, dropped []bool) []any {
+	out := make([]any, 0, len(messages))
+	for i, raw := range messages {
+		if !dropped[i] {
+			out = append(out, raw)
+		}
+	}
+	return out
+}
+
+func isPinned(raw any) bool {
+	role := roleOf(raw)
+	return role == "system" || role == "developer"
+}
+
+func roleOf(raw any) string {
+	msg, ok := raw.(map[string]any)
+	if !ok {
+		return ""
+	}
+	role, _ := msg["role"].(string)
+	return strings.ToLower(strings.TrimSpace(role))
+}
@@ -0,0 +1,150 @@
+package contextwindow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"ds2api/internal/config"
+)
+
+// charCount treats every content character as one token.
+func charCount(messages []any) int {
+	n := 0
+	for _, raw := range messages {
+		n += len(fmt.Sprint(raw.(map[string]any)["content"]))
+	}
+	return n
+}
+
+func msg(role, content string) map[string]any {
+	return map[string]any{"role": role, "content": content}
+}
+
+func agentSession() []any {
+	return []any{
+		msg("system", "sys"),
+		msg("user", strings.Repeat("a", 100)),
+		map[string]any{"role": "assistant", "content": "", "tool_calls": []any{map[string]any{"id": "call_1"}}},
+		map[string]any{"role": "tool", "tool_call_id": "call_1", "content": strings.Repeat("r", 500)},
+		msg("assistant", "done"),
+		msg("user", strings.Repeat("b", 100)),
+		msg("assistant", "ok"),
+		msg("user", "latest"),
+	}
+}
+
+func roles(messages []any) string {
+	out := make([]string, len(messages))
+	for i, raw := range messages {
+		out[i] = roleOf(raw)
+	}
+	return strings.Join(out, ",")
+}
+
+func TestFitUnderBudgetIsUnchanged(t *testing.T) {
+	in := agentSession()
+	out, report, err := Fit(context.Background(), in, Options{Strategy: config.ContextStrategyDropOldest, Budget: 10000, KeepLastTurns: 1, Count: charCount})
+	if err != nil || report.Applied() || len(out) != len(in) {
+		t.Fatalf("expected no change, got report=%+v len=%d err=%v", report, len(out), err)
+	}
+}
+
+func TestFitDropOldestKeepsSystemAndToolPairs(t *testing.T) {
+	out, report, err := Fit(context.Background(), agentSession(), Options{Strategy: config.ContextStrategyDropOldest, Budget: 200, KeepLastTurns: 1, Count: charCount})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The whole first turn (user, tool call, tool result, answer) goes at once.
+	if got := roles(out); got != "system,user,assistant,user" {
+		t.Fatalf("unexpected messages after drop: %s", got)
+	}
+	if report.DroppedMessages != 4 || report.TokensAfter > 200 || report.TokensBefore <= report.TokensAfter {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if h := report.Header(); !strings.HasPrefix(h, "drop_oldest; tokens_before=") || !strings.Contains(h, "dropped_messages=4") {
+		t.Fatalf("unexpected header: %q", h)
+	}
+}
+
+func TestFitDropOldestCountsLogarithmically(t *testing.T) {
+	in := []any{msg("system", "sys")}
+	for i := 0; i < 256; i++ {
+		in = append(in, msg("user", strings.Repeat("u", 10)), msg("assistant", strings.Repeat("a", 10)))
+	}
+	calls := 0
+	count := func(messages []any) int {
+		calls++
+		return charCount(messages)
+	}
+	out, report, err := Fit(context.Background(), in, Options{Strategy: config.ContextStrategyDropOldest, Budget: 1000, KeepLastTurns: 1, Count: count})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 49 turns of 20 tokens plus the system prompt fit; 50 would not.
+	if len(out) != 1+2*49 || report.TokensAfter != charCount(out) || report.TokensAfter > 1000 {
+		t.Fatalf("unexpected fit: len=%d report=%+v", len(out), report)
+	}
+	if calls > 12 {
+		t.Fatalf("expected a binary search over turns, got %d count calls", calls)
+	}
+}
+
+func TestFitKeepsLastTurnsEvenWhenStillOverBudget(t *testing.T) {
+	in := agentSession()
+	out, report, _ := Fit(context.Background(), in, Options{Strategy: config.ContextStrategyDropOldest, Budget: 1, KeepLastTurns: 3, Count: charCount})
+	if report.Applied() || len(out) != len(in) {
+		t.Fatalf("expected the last three turns to be untouchable, got %s", roles(out))
+	}
+}
+
+func TestFitTrimToolResultsUsesHeadTailMarker(t *testing.T) {
+	out, report, err := Fit(context.Background(), agentSession(), Options{Strategy: config.ContextStrategyTrimToolResults, Budget: 500, KeepLastTurns: 1, ToolResultMaxChars: 40, Count: charCount})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := out[3].(map[string]any)["content"].(string)
+	if report.TrimmedToolResults != 1 || !strings.HasPrefix(content, strings.Repeat("r", 20)+"\n[... 460 characters trimmed ...]\n") {
+		t.Fatalf("unexpected trim: %+v %q", report, content)
+	}
+	if out[3].(map[string]any)["tool_call_id"] != "call_1" || len(out) != len(agentSession()) {
+		t.Fatal("expected tool result to stay paired with its call")
+	}
+	if agentSession()[3].(map[string]any)["content"] == content {
+		t.Fatal("expected a copy, not an in-place edit")
+	}
+}
+
+func TestFitSummarizeReplacesOlderTurns(t *testing.T) {
+	var summarized []any
+	opts := Options{
+		Strategy:      config.ContextStrategySummarize,
+		Budget:        300,
+		KeepLastTurns: 2,
+		Count:         charCount,
+		Summarize: func(_ context.Context, older []any) (string, error) {
+			summarized = older
+			return "user asked for a, tool returned r", nil
+		},
+	}
+	out, report, err := Fit(context.Background(), agentSession(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summarized) != 4 || report.SummarizedMessages != 4 {
+		t.Fatalf("expected the first turn to be summarized, got %d (%+v)", len(summarized), report)
+	}
+	if got := roles(out); got != "system,user,user,assistant,user" {
+		t.Fatalf("unexpected messages after summary: %s", got)
+	}
+	if !strings.HasPrefix(out[1].(map[string]any)["content"].(string), SummaryPrefix) {
+		t.Fatalf("expected summary message, got %#v", out[1])
+	}
+
+	opts.Summarize = func(context.Context, []any) (string, error) { return "", errors.New("upstream down") }
+	if _, _, err := Fit(context.Background(), agentSession(), opts); err == nil {
+		t.Fatal("expected summary failure to be reported")
+	}
+}
@@ -7,10 +7,13 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 	"unicode"
 
 	"ds2api/internal/auth"
 	"ds2api/internal/config"
+	"ds2api/internal/inflight"
+	"ds2api/internal/tracing"
 )
 
 func (c *Client) Login(ctx context.Context, acc config.Account) (string, error) {
@@ -29,7 +32,7 @@ func (c *Client) Login(ctx context.Context, acc config.Account) (string, error)
 	} else {
 		return "", errors.New("missing email/mobile")
 	}
-	resp, err := c.postJSON(ctx, clients.regular, clients.fallback, dsprotocol.DeepSeekLoginURL, dsprotocol.BaseHeaders, payload)
+	resp, err := c.postJSON(ctx, clients.regular, clients.fallback, dsprotocol.URL(dsprotocol.DeepSeekLoginPath), dsprotocol.BaseHeaders, payload)
 	if err != nil {
 		return "", err
 	}
@@ -51,6 +54,23 @@ func (c *Client) Login(ctx context.Context, acc config.Account) (string, error)
 }
 
 func (c *Client) CreateSession(ctx context.Context, a *auth.RequestAuth, maxAttempts int) (string, error) {
+	inflight.FromContext(ctx).SetStage(inflight.StageCreateSession)
+	ctx, span := tracing.StartClient(ctx, "deepseek.create_session", tracing.String("ds2api.account_id", a.AccountID))
+	defer span.End()
+	if sessionID, ok := c.takeWarmSession(a); ok {
+		c.sessions.mark(sessionID)
+		span.SetAttributes(tracing.Bool("ds2api.warm_pool_hit", true))
+		return sessionID, nil
+	}
+	sessionID, err := c.createSession(ctx, a, maxAttempts)
+	if err == nil {
+		c.sessions.mark(sessionID)
+	}
+	span.RecordError(err)
+	return sessionID, err
+}
+
+func (c *Client) createSession(ctx context.Context, a *auth.RequestAuth, maxAttempts int) (string, error) {
 	if maxAttempts <= 0 {
 		maxAttempts = c.maxRetries
 	}
@@ -59,7 +79,7 @@ func (c *Client) CreateSession(ctx context.Context, a *auth.RequestAuth, maxAtte
 	refreshed := false
 	for attempts < maxAttempts {
 		headers := c.authHeaders(a.DeepSeekToken)
-		resp, status, err := c.postJSONWithStatus(ctx, clients.regular, clients.fallback, dsprotocol.DeepSeekCreateSessionURL, headers, map[string]any{"agent": "chat"})
+		resp, status, err := c.postJSONWithStatus(ctx, clients.regular, clients.fallback, dsprotocol.URL(dsprotocol.DeepSeekCreateSessionPath), headers, map[string]any{"agent": "chat"})
 		if err != nil {
 			config.Logger.Warn("[create_session] request error", "error", err, "account", a.AccountID)
 			attempts++
@@ -96,6 +116,24 @@ func (c *Client) GetPow(ctx context.Context, a *auth.RequestAuth, maxAttempts in
 }
 
 func (c *Client) GetPowForTarget(ctx context.Context, a *auth.RequestAuth, targetPath string, maxAttempts int) (string, error) {
+	targetPath = strings.TrimSpace(targetPath)
+	inflight.FromContext(ctx).SetStage(inflight.StagePow)
+	ctx, span := tracing.StartClient(ctx, "deepseek.pow", tracing.String("ds2api.pow.target_path", targetPath))
+	defer span.End()
+	if targetPath == "" || targetPath == dsprotocol.DeepSeekCompletionTargetPath {
+		if header, ok := c.takeWarmPow(a); ok {
+			span.SetAttributes(tracing.Bool("ds2api.warm_pool_hit", true))
+			return header, nil
+		}
+	}
+	header, _, err := c.solvePow(ctx, a, targetPath, maxAttempts)
+	span.RecordError(err)
+	return header, err
+}
+
+// solvePow fetches and solves one challenge, returning the header value and
+// when the challenge expires.
+func (c *Client) solvePow(ctx context.Context, a *auth.RequestAuth, targetPath string, maxAttempts int) (string, time.Time, error) {
 	if maxAttempts <= 0 {
 		maxAttempts = c.maxRetries
 	}
@@ -110,7 +148,7 @@ func (c *Client) GetPowForTarget(ctx context.Context, a *auth.RequestAuth, targe
 	lastFailureMessage := ""
 	for attempts < maxAttempts {
 		headers := c.authHeaders(a.DeepSeekToken)
-		resp, status, err := c.postJSONWithStatus(ctx, clients.regular, clients.fallback, dsprotocol.DeepSeekCreatePowURL, headers, map[string]any{"target_path": targetPath})
+		resp, status, err := c.postJSONWithStatus(ctx, clients.regular, clients.fallback, dsprotocol.URL(dsprotocol.DeepSeekCreatePowPath), headers, map[string]any{"target_path": targetPath})
 		if err != nil {
 			config.Logger.Warn("[get_pow] request error", "error", err, "account", a.AccountID, "target_path", targetPath)
 			lastFailureKind = FailureUnknown
@@ -128,7 +166,8 @@ func (c *Client) GetPowForTarget(ctx context.Context, a *auth.RequestAuth, targe
 				attempts++
 				continue
 			}
-			return BuildPowHeader(challenge, answer)
+			header, err := BuildPowHeader(challenge, answer)
+			return header, challengeExpireAt(challenge), err
 		}
 		config.Logger.Warn("[get_pow] failed", "status", status, "code", code, "biz_code", bizCode, "msg", msg, "biz_msg", bizMsg, "use_config_token", a.UseConfigToken, "account", a.AccountID, "target_path", targetPath)
 		lastFailureMessage = failureMessage(msg, bizMsg, "get pow failed")
@@ -153,9 +192,9 @@ func (c *Client) GetPowForTarget(ctx context.Context, a *auth.RequestAuth, targe
 		attempts++
 	}
 	if lastFailureKind != FailureUnknown {
-		return "", &RequestFailure{Op: "get pow", Kind: lastFailureKind, Message: lastFailureMessage}
+		return "", time.Time{}, &RequestFailure{Op: "get pow", Kind: lastFailureKind, Message: lastFailureMessage}
 	}
-	return "", errors.New("get pow failed")
+	return "", time.Time{}, errors.New("get pow failed")
 }
 
 func (c *Client) authHeaders(token string) map[string]string {
@@ -167,6 +206,10 @@ func (c *Client) authHeaders(token string) map[string]string {
 	return headers
 }
 
+// ErrTokenInvalid is wrapped by errors from calls that upstream rejected
+// because the account token is missing, invalid or expired.
+var ErrTokenInvalid = errors.New("deepseek token invalid")
+
 func isTokenInvalid(status int, code int, bizCode int, msg string, bizMsg string) bool {
 	msg = strings.ToLower(strings.TrimSpace(msg) + " " + strings.TrimSpace(bizMsg))
 	if status == http.StatusUnauthorized || status == http.StatusForbidden {
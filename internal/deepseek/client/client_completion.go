@@ -12,29 +12,43 @@ import (
 	"ds2api/internal/auth"
 	"ds2api/internal/config"
 	trans "ds2api/internal/deepseek/transport"
+	"ds2api/internal/inflight"
+	"ds2api/internal/tracing"
 )
 
 func (c *Client) CallCompletion(ctx context.Context, a *auth.RequestAuth, payload map[string]any, powResp string, maxAttempts int) (*http.Response, error) {
 	if maxAttempts <= 0 {
 		maxAttempts = c.maxRetries
 	}
+	// The span ends when upstream response headers arrive; streaming the body
+	// is covered by the server span and the continue spans.
+	_, span := tracing.StartClient(ctx, "deepseek.completion", tracing.String("ds2api.account_id", a.AccountID))
+	defer span.End()
+	tracked := inflight.FromContext(ctx)
+	tracked.SetStage(inflight.StageWaitingUpstream)
 	clients := c.requestClientsForAuth(ctx, a)
 	headers := c.authHeaders(a.DeepSeekToken)
 	headers["x-ds-pow-response"] = powResp
-	captureSession := c.capture.Start("deepseek_completion", dsprotocol.DeepSeekCompletionURL, a.AccountID, payload)
+	captureSession := c.capture.Start("deepseek_completion", dsprotocol.URL(dsprotocol.DeepSeekCompletionPath), a.AccountID, payload)
+	sessionID, _ := payload["chat_session_id"].(string)
+	c.sessions.mark(sessionID)
 	attempts := 0
 	for attempts < maxAttempts {
-		resp, err := c.streamPost(ctx, clients.stream, dsprotocol.DeepSeekCompletionURL, headers, payload)
+		resp, err := c.streamPost(ctx, clients.stream, dsprotocol.URL(dsprotocol.DeepSeekCompletionPath), headers, payload)
 		if err != nil {
 			attempts++
 			time.Sleep(time.Second)
 			continue
 		}
+		span.SetAttributes(tracing.Int("http.response.status_code", resp.StatusCode), tracing.Int("ds2api.attempt", attempts+1))
 		if resp.StatusCode == http.StatusOK {
 			if captureSession != nil {
 				resp.Body = captureSession.WrapBody(resp.Body, resp.StatusCode)
 			}
 			resp = c.wrapCompletionWithAutoContinue(ctx, a, payload, powResp, resp)
+			c.sessions.releaseOnClose(resp, sessionID)
+			resp.Body = tracked.TrackUpstream(resp.Body)
+			tracked.SetStage(inflight.StageStreaming)
 			return resp, nil
 		}
 		if captureSession != nil {
@@ -44,6 +58,8 @@ func (c *Client) CallCompletion(ctx context.Context, a *auth.RequestAuth, payloa
 		attempts++
 		time.Sleep(time.Second)
 	}
+	c.sessions.release(sessionID)
+	span.SetError("completion failed")
 	return nil, errors.New("completion failed")
 }
 
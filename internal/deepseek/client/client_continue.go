@@ -12,8 +12,11 @@ import (
 	"net/http"
 	"strings"
 
+	"ds2api/internal/accesslog"
 	"ds2api/internal/auth"
 	"ds2api/internal/config"
+	"ds2api/internal/inflight"
+	"ds2api/internal/tracing"
 )
 
 const defaultAutoContinueLimit = 8
@@ -62,8 +65,8 @@ func (c *Client) callContinue(ctx context.Context, a *auth.RequestAuth, sessionI
 		"fallback_to_resume": true,
 	}
 	config.Logger.Info("[auto_continue] calling continue", "session_id", sessionID, "message_id", responseMessageID)
-	captureSession := c.capture.Start("deepseek_continue", dsprotocol.DeepSeekContinueURL, a.AccountID, payload)
-	resp, err := c.streamPost(ctx, clients.stream, dsprotocol.DeepSeekContinueURL, headers, payload)
+	captureSession := c.capture.Start("deepseek_continue", dsprotocol.URL(dsprotocol.DeepSeekContinuePath), a.AccountID, payload)
+	resp, err := c.streamPost(ctx, clients.stream, dsprotocol.URL(dsprotocol.DeepSeekContinuePath), headers, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -98,23 +101,34 @@ func pumpAutoContinue(ctx context.Context, pw *io.PipeWriter, initial io.ReadClo
 	defer func() { _ = pw.Close() }()
 	current := initial
 	rounds := 0
+	// roundSpan covers one continue round, from the continue request until
+	// its stream is fully relayed.
+	var roundSpan *tracing.Span
+	defer func() { roundSpan.End() }()
 	for {
 		hadDone, err := streamBodyWithContinueState(ctx, pw, current, &state)
 		_ = current.Close()
+		roundSpan.RecordError(err)
+		roundSpan.End()
 		if err != nil {
 			_ = pw.CloseWithError(err)
 			return
 		}
 		if state.shouldContinue() && rounds < maxRounds {
 			rounds++
+			accesslog.FromContext(ctx).AddContinue()
+			inflight.FromContext(ctx).SetStage(inflight.StageContinuing)
+			_, roundSpan = tracing.StartClient(ctx, "deepseek.continue", tracing.Int("ds2api.continue.round", rounds), tracing.String("ds2api.continue.status", state.lastStatus))
 			config.Logger.Info("[auto_continue] continuing", "round", rounds, "session_id", state.sessionID, "message_id", state.responseMessageID, "status", state.lastStatus)
 			nextResp, err := openContinue(ctx, state.sessionID, state.responseMessageID)
 			if err != nil {
+				roundSpan.RecordError(err)
 				config.Logger.Warn("[auto_continue] continue request failed", "round", rounds, "error", err)
 				_ = pw.CloseWithError(err)
 				return
 			}
 			current = nextResp.Body
+			inflight.FromContext(ctx).SetStage(inflight.StageStreaming)
 			state.prepareForNextRound()
 			continue
 		}
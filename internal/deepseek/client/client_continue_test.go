@@ -60,8 +60,8 @@ func TestCallContinuePropagatesPowHeaderToFallbackRequest(t *testing.T) {
 	if seenPow != "pow-response-abc" {
 		t.Fatalf("continue request pow header=%q want=%q", seenPow, "pow-response-abc")
 	}
-	if seenURL != dsprotocol.DeepSeekContinueURL {
-		t.Fatalf("continue request url=%q want=%q", seenURL, dsprotocol.DeepSeekContinueURL)
+	if seenURL != dsprotocol.URL(dsprotocol.DeepSeekContinuePath) {
+		t.Fatalf("continue request url=%q want=%q", seenURL, dsprotocol.URL(dsprotocol.DeepSeekContinuePath))
 	}
 }
 
@@ -114,8 +114,8 @@ func TestCallCompletionAutoContinueThreadsPowHeader(t *testing.T) {
 	if seenPow != "pow-response-xyz" {
 		t.Fatalf("threaded continue pow header=%q want=%q", seenPow, "pow-response-xyz")
 	}
-	if seenContinueURL != dsprotocol.DeepSeekContinueURL {
-		t.Fatalf("continue url=%q want=%q", seenContinueURL, dsprotocol.DeepSeekContinueURL)
+	if seenContinueURL != dsprotocol.URL(dsprotocol.DeepSeekContinuePath) {
+		t.Fatalf("continue url=%q want=%q", seenContinueURL, dsprotocol.URL(dsprotocol.DeepSeekContinuePath))
 	}
 	if !bytes.Contains(out, []byte(`"status":"WIP"`)) {
 		t.Fatalf("expected initial stream content in body, got=%s", string(out))
@@ -28,6 +28,9 @@ type Client struct {
 
 	proxyClientsMu sync.RWMutex
 	proxyClients   map[string]requestClients
+
+	warm     *warmPool
+	sessions *sessionTracker
 }
 
 func NewClient(store *config.Store, resolver *auth.Resolver) *Client {
@@ -41,6 +44,8 @@ func NewClient(store *config.Store, resolver *auth.Resolver) *Client {
 		fallbackS:    &http.Client{Timeout: 0},
 		maxRetries:   3,
 		proxyClients: map[string]requestClients{},
+		warm:         newWarmPool(),
+		sessions:     newSessionTracker(),
 	}
 }
 
@@ -50,7 +50,7 @@ func (c *Client) GetSessionCount(ctx context.Context, a *auth.RequestAuth, maxAt
 		headers := c.authHeaders(a.DeepSeekToken)
 
 		// 构建请求 URL
-		reqURL := dsprotocol.DeepSeekFetchSessionURL + "?lte_cursor.pinned=false"
+		reqURL := dsprotocol.URL(dsprotocol.DeepSeekFetchSessionPath) + "?lte_cursor.pinned=false"
 
 		resp, status, err := c.getJSONWithStatus(ctx, clients.regular, reqURL, headers)
 		if err != nil {
@@ -110,7 +110,7 @@ func (c *Client) GetSessionCount(ctx context.Context, a *auth.RequestAuth, maxAt
 func (c *Client) GetSessionCountForToken(ctx context.Context, token string) (*SessionStats, error) {
 	clients := c.requestClientsFromContext(ctx)
 	headers := c.authHeaders(token)
-	reqURL := dsprotocol.DeepSeekFetchSessionURL + "?lte_cursor.pinned=false"
+	reqURL := dsprotocol.URL(dsprotocol.DeepSeekFetchSessionPath) + "?lte_cursor.pinned=false"
 
 	resp, status, err := c.getJSONWithStatus(ctx, clients.regular, reqURL, headers)
 	if err != nil {
@@ -192,7 +192,8 @@ func (c *Client) GetSessionCountAll(ctx context.Context) []*SessionStats {
 	return results
 }
 
-// FetchSessionPage 获取会话列表（支持分页）
+// FetchSessionPage 获取会话列表（支持分页）。会话按更新时间倒序返回，
+// cursor 为上一页最后一个会话的 updated_at（含该时刻），为空时从最新开始。
 func (c *Client) FetchSessionPage(ctx context.Context, a *auth.RequestAuth, cursor string) ([]SessionInfo, bool, error) {
 	clients := c.requestClientsForAuth(ctx, a)
 	headers := c.authHeaders(a.DeepSeekToken)
@@ -203,16 +204,18 @@ func (c *Client) FetchSessionPage(ctx context.Context, a *auth.RequestAuth, curs
 	if cursor != "" {
 		params.Set("lte_cursor", cursor)
 	}
-	reqURL := dsprotocol.DeepSeekFetchSessionURL + "?" + params.Encode()
+	reqURL := dsprotocol.URL(dsprotocol.DeepSeekFetchSessionPath) + "?" + params.Encode()
 
 	resp, status, err := c.getJSONWithStatus(ctx, clients.regular, reqURL, headers)
 	if err != nil {
 		return nil, false, err
 	}
 
-	code := intFrom(resp["code"])
+	code, bizCode, msg, bizMsg := extractResponseStatus(resp)
 	if status != http.StatusOK || code != 0 {
-		msg, _ := resp["msg"].(string)
+		if isTokenInvalid(status, code, bizCode, msg, bizMsg) {
+			return nil, false, fmt.Errorf("%w: status=%d, code=%d, msg=%s", ErrTokenInvalid, status, code, msg)
+		}
 		return nil, false, fmt.Errorf("request failed: status=%d, code=%d, msg=%s", status, code, msg)
 	}
 
@@ -17,6 +17,8 @@ import (
 	"ds2api/internal/auth"
 	"ds2api/internal/config"
 	trans "ds2api/internal/deepseek/transport"
+	"ds2api/internal/inflight"
+	"ds2api/internal/tracing"
 )
 
 type UploadFileRequest struct {
@@ -40,6 +42,15 @@ type UploadFileResult struct {
 }
 
 func (c *Client) UploadFile(ctx context.Context, a *auth.RequestAuth, req UploadFileRequest, maxAttempts int) (*UploadFileResult, error) {
+	inflight.FromContext(ctx).SetStage(inflight.StageUploading)
+	ctx, span := tracing.StartClient(ctx, "deepseek.upload_file", tracing.String("ds2api.account_id", a.AccountID), tracing.Int("ds2api.upload.bytes", len(req.Data)))
+	defer span.End()
+	result, err := c.uploadFile(ctx, a, req, maxAttempts)
+	span.RecordError(err)
+	return result, err
+}
+
+func (c *Client) uploadFile(ctx context.Context, a *auth.RequestAuth, req UploadFileRequest, maxAttempts int) (*UploadFileResult, error) {
 	if maxAttempts <= 0 {
 		maxAttempts = c.maxRetries
 	}
@@ -69,7 +80,7 @@ func (c *Client) UploadFile(ctx context.Context, a *auth.RequestAuth, req Upload
 	if modelType != "" {
 		capturePayload["model_type"] = modelType
 	}
-	captureSession := c.capture.Start("deepseek_upload_file", dsprotocol.DeepSeekUploadFileURL, a.AccountID, capturePayload)
+	captureSession := c.capture.Start("deepseek_upload_file", dsprotocol.URL(dsprotocol.DeepSeekUploadFilePath), a.AccountID, capturePayload)
 	attempts := 0
 	refreshed := false
 	powHeader := ""
@@ -92,7 +103,7 @@ func (c *Client) UploadFile(ctx context.Context, a *auth.RequestAuth, req Upload
 		headers["x-ds-pow-response"] = powHeader
 		headers["x-file-size"] = strconv.Itoa(len(req.Data))
 		headers["x-thinking-enabled"] = "1"
-		resp, err := c.doUpload(ctx, clients.regular, clients.fallback, dsprotocol.DeepSeekUploadFileURL, headers, body)
+		resp, err := c.doUpload(ctx, clients.regular, clients.fallback, dsprotocol.URL(dsprotocol.DeepSeekUploadFilePath), headers, body)
 		if err != nil {
 			config.Logger.Warn("[upload_file] request error", "error", err, "account", a.AccountID, "filename", filename)
 			powHeader = ""
@@ -4,23 +4,41 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
-	"errors"
+	"os"
+	"strconv"
+	"strings"
 
 	"ds2api/pow"
 )
 
-// ComputePow 使用纯 Go 实现求解 PoW challenge (DeepSeekHashV1)。
+// powWorkersEnv 设置同时求解 PoW 的 worker 数，默认等于 CPU 核数。
+const powWorkersEnv = "DS2API_POW_WORKERS"
+
+var powWorkers = pow.NewWorkerPool(powWorkersFromEnv())
+
+func powWorkersFromEnv() int {
+	n, _ := strconv.Atoi(strings.TrimSpace(os.Getenv(powWorkersEnv)))
+	return n
+}
+
+// PowWorkerStats 返回进程内 PoW worker 池的统计。
+func PowWorkerStats() pow.WorkerPoolStats {
+	return powWorkers.Stats()
+}
+
+// ComputePow 在有界 worker 池中求解 PoW challenge，算法按 algorithm 字段从
+// pow 注册表查找。
 func ComputePow(ctx context.Context, challenge map[string]any) (int64, error) {
 	algo, _ := challenge["algorithm"].(string)
-	if algo != "DeepSeekHashV1" {
-		return 0, errors.New("unsupported algorithm")
-	}
 	challengeStr, _ := challenge["challenge"].(string)
 	salt, _ := challenge["salt"].(string)
-	expireAt := toInt64(challenge["expire_at"], 1680000000)
-	difficulty := toInt64FromFloat(challenge["difficulty"], 144000)
-
-	return pow.SolvePow(ctx, challengeStr, salt, expireAt, difficulty)
+	return powWorkers.Solve(ctx, &pow.Challenge{
+		Algorithm:  algo,
+		Challenge:  challengeStr,
+		Salt:       salt,
+		ExpireAt:   toInt64(challenge["expire_at"], 1680000000),
+		Difficulty: toInt64FromFloat(challenge["difficulty"], pow.DefaultDifficulty),
+	})
 }
 
 // BuildPowHeader 序列化 {algorithm,challenge,salt,answer,signature,target_path} 为 base64(JSON)。
@@ -26,8 +26,6 @@ type requestClients struct {
 
 type hostLookupFunc func(ctx context.Context, network, host string) ([]string, error)
 
-var proxyConnectivityTestURL = "https://chat.deepseek.com/"
-
 var defaultHostLookup hostLookupFunc = func(ctx context.Context, _ string, host string) ([]string, error) {
 	return net.DefaultResolver.LookupHost(ctx, host)
 }
@@ -215,7 +213,7 @@ func TestProxyConnectivity(ctx context.Context, proxyCfg config.Proxy) map[strin
 	}
 
 	client := trans.NewFallbackClient(15*time.Second, dialContext)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, proxyConnectivityTestURL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, dsprotocol.URL("/"), nil)
 	if err != nil {
 		result["message"] = err.Error()
 		return result
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// sessionInUseTTL bounds how long a session counts as in use without a
+// completion finishing, e.g. when a caller created one and never used it.
+const sessionInUseTTL = 30 * time.Minute
+
+// sessionTracker remembers the upstream sessions this process has handed out
+// or is streaming from, so background cleanup never deletes them.
+type sessionTracker struct {
+	mu     sync.Mutex
+	active map[string]time.Time
+}
+
+func newSessionTracker() *sessionTracker {
+	return &sessionTracker{active: map[string]time.Time{}}
+}
+
+func (t *sessionTracker) mark(id string) {
+	if t == nil || id == "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	for k, at := range t.active {
+		if now.Sub(at) > sessionInUseTTL {
+			delete(t.active, k)
+		}
+	}
+	t.active[id] = now
+}
+
+func (t *sessionTracker) release(id string) {
+	if t == nil || id == "" {
+		return
+	}
+	t.mu.Lock()
+	delete(t.active, id)
+	t.mu.Unlock()
+}
+
+func (t *sessionTracker) inUse(id string) bool {
+	if t == nil || id == "" {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	at, ok := t.active[id]
+	return ok && time.Since(at) <= sessionInUseTTL
+}
+
+// releaseOnClose keeps id marked until the completion body is closed.
+func (t *sessionTracker) releaseOnClose(resp *http.Response, id string) {
+	if t == nil || id == "" || resp == nil || resp.Body == nil {
+		return
+	}
+	resp.Body = &trackedBody{ReadCloser: resp.Body, release: func() { t.release(id) }}
+}
+
+type trackedBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *trackedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// SessionInUse reports whether sessionID was handed out by CreateSession and
+// its completion has not finished yet, or is waiting in the warm pool.
+func (c *Client) SessionInUse(sessionID string) bool {
+	if c.sessions.inUse(sessionID) {
+		return true
+	}
+	if c.warm == nil {
+		return false
+	}
+	c.warm.mu.Lock()
+	defer c.warm.mu.Unlock()
+	for _, acc := range c.warm.accounts {
+		for _, s := range acc.sessions {
+			if s.id == sessionID {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package client
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSessionInUseUntilCompletionBodyCloses(t *testing.T) {
+	client, _, a := newWarmPoolTestClient(t)
+	ctx := context.Background()
+	direct := *a
+	direct.UseConfigToken = false
+
+	sessionID, err := client.CreateSession(ctx, &direct, 1)
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	if !client.SessionInUse(sessionID) {
+		t.Fatal("expected a handed out session to be in use")
+	}
+	powHeader, err := client.GetPow(ctx, &direct, 1)
+	if err != nil {
+		t.Fatalf("get pow: %v", err)
+	}
+	resp, err := client.CallCompletion(ctx, &direct, map[string]any{"chat_session_id": sessionID, "prompt": "hi"}, powHeader, 1)
+	if err != nil {
+		t.Fatalf("completion: %v", err)
+	}
+	if !client.SessionInUse(sessionID) {
+		t.Fatal("expected the session to stay in use while streaming")
+	}
+	_ = resp.Body.Close()
+	if client.SessionInUse(sessionID) {
+		t.Fatal("expected the session to be released after the body closed")
+	}
+}
@@ -0,0 +1,318 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"ds2api/internal/auth"
+	"ds2api/internal/config"
+	"ds2api/pow"
+)
+
+const (
+	// warmPowExpiryMargin keeps a pre-solved answer out of requests once its
+	// challenge is this close to expiring upstream.
+	warmPowExpiryMargin = 30 * time.Second
+	warmRefillTimeout   = 60 * time.Second
+)
+
+// warmPool keeps pre-created sessions and pre-solved completion PoW answers
+// per managed account. Items are bound to the token that produced them, so a
+// refreshed token makes the old items stale instead of failing a request.
+type warmPool struct {
+	mu       sync.Mutex
+	accounts map[string]*warmAccount
+	sessions warmCounters
+	pows     warmCounters
+}
+
+type warmAccount struct {
+	token     string
+	sessions  []warmSession
+	pows      []warmPow
+	refilling bool
+	// sessionGen is bumped whenever pooled sessions are dropped because they
+	// are about to be deleted upstream. A refill only stores sessions if the
+	// generation it started with is still current.
+	sessionGen uint64
+}
+
+type warmSession struct {
+	id        string
+	createdAt time.Time
+}
+
+type warmPow struct {
+	header   string
+	expireAt time.Time
+}
+
+type warmCounters struct {
+	Hits         int64 `json:"hits"`
+	Misses       int64 `json:"misses"`
+	Stale        int64 `json:"stale"`
+	Refilled     int64 `json:"refilled"`
+	RefillErrors int64 `json:"refill_errors"`
+}
+
+func newWarmPool() *warmPool {
+	return &warmPool{accounts: map[string]*warmAccount{}}
+}
+
+func (c *Client) prefetchEnabled(a *auth.RequestAuth) bool {
+	return c.warm != nil && c.Store != nil && c.Store.PrefetchEnabled() &&
+		a != nil && a.UseConfigToken && a.AccountID != "" && a.DeepSeekToken != ""
+}
+
+// account returns the pool entry for a, dropping items that belong to an
+// older token. Callers hold p.mu.
+func (p *warmPool) account(a *auth.RequestAuth) *warmAccount {
+	acc := p.accounts[a.AccountID]
+	if acc == nil {
+		acc = &warmAccount{token: a.DeepSeekToken}
+		p.accounts[a.AccountID] = acc
+	}
+	if acc.token != a.DeepSeekToken {
+		p.sessions.Stale += int64(len(acc.sessions))
+		p.pows.Stale += int64(len(acc.pows))
+		acc.sessions, acc.pows = nil, nil
+		acc.token = a.DeepSeekToken
+	}
+	return acc
+}
+
+func (c *Client) takeWarmSession(a *auth.RequestAuth) (string, bool) {
+	if !c.prefetchEnabled(a) {
+		return "", false
+	}
+	maxAge := c.Store.PrefetchSessionMaxAge()
+	p := c.warm
+	p.mu.Lock()
+	acc := p.account(a)
+	id := ""
+	for len(acc.sessions) > 0 && id == "" {
+		s := acc.sessions[0]
+		acc.sessions = acc.sessions[1:]
+		if time.Since(s.createdAt) > maxAge {
+			p.sessions.Stale++
+			continue
+		}
+		id = s.id
+	}
+	if id != "" {
+		p.sessions.Hits++
+	} else {
+		p.sessions.Misses++
+	}
+	p.mu.Unlock()
+	c.scheduleWarmRefill(a)
+	return id, id != ""
+}
+
+func (c *Client) takeWarmPow(a *auth.RequestAuth) (string, bool) {
+	if !c.prefetchEnabled(a) {
+		return "", false
+	}
+	p := c.warm
+	p.mu.Lock()
+	acc := p.account(a)
+	header := ""
+	for len(acc.pows) > 0 && header == "" {
+		w := acc.pows[0]
+		acc.pows = acc.pows[1:]
+		if time.Until(w.expireAt) < warmPowExpiryMargin {
+			p.pows.Stale++
+			continue
+		}
+		header = w.header
+	}
+	if header != "" {
+		p.pows.Hits++
+	} else {
+		p.pows.Misses++
+	}
+	p.mu.Unlock()
+	c.scheduleWarmRefill(a)
+	return header, header != ""
+}
+
+// scheduleWarmRefill tops the account back up in the background. The refill
+// runs on a copy of the auth with UseConfigToken off, so a failing token is
+// left for the next foreground request to refresh instead of being refreshed
+// or switched here.
+func (c *Client) scheduleWarmRefill(a *auth.RequestAuth) {
+	p := c.warm
+	p.mu.Lock()
+	acc := p.account(a)
+	if acc.refilling {
+		p.mu.Unlock()
+		return
+	}
+	acc.refilling = true
+	p.mu.Unlock()
+
+	snapshot := *a
+	snapshot.UseConfigToken = false
+	snapshot.TriedAccounts = nil
+	go c.refillWarmPool(&snapshot)
+}
+
+func (c *Client) refillWarmPool(a *auth.RequestAuth) {
+	ctx, cancel := context.WithTimeout(context.Background(), warmRefillTimeout)
+	defer cancel()
+	p := c.warm
+	defer func() {
+		p.mu.Lock()
+		if acc := p.accounts[a.AccountID]; acc != nil {
+			acc.refilling = false
+		}
+		p.mu.Unlock()
+	}()
+
+	gen := c.warmSessionGen(a)
+	for c.warmNeeds(a, func(acc *warmAccount) bool { return len(acc.sessions) < c.Store.PrefetchSessions() }) {
+		id, err := c.createSession(ctx, a, 1)
+		if !c.storeWarmSession(a, gen, id, err) {
+			break
+		}
+	}
+	for c.warmNeeds(a, func(acc *warmAccount) bool { return len(acc.pows) < c.Store.PrefetchPows() }) {
+		header, expireAt, err := c.solvePow(ctx, a, "", 1)
+		if !c.warmStore(a, err, &p.pows, func(acc *warmAccount) bool {
+			acc.pows = append(acc.pows, warmPow{header: header, expireAt: expireAt})
+			return true
+		}) {
+			break
+		}
+	}
+}
+
+func (c *Client) warmSessionGen(a *auth.RequestAuth) uint64 {
+	p := c.warm
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if acc := p.accounts[a.AccountID]; acc != nil {
+		return acc.sessionGen
+	}
+	return 0
+}
+
+// storeWarmSession pools a session created by a refill that started at
+// generation gen. Sessions created before a delete-all are discarded.
+func (c *Client) storeWarmSession(a *auth.RequestAuth, gen uint64, id string, err error) bool {
+	return c.warmStore(a, err, &c.warm.sessions, func(acc *warmAccount) bool {
+		if acc.sessionGen != gen {
+			return false
+		}
+		acc.sessions = append(acc.sessions, warmSession{id: id, createdAt: time.Now()})
+		return true
+	})
+}
+
+// warmNeeds reports whether the account, still on a's token, wants another
+// item according to short.
+func (c *Client) warmNeeds(a *auth.RequestAuth, short func(*warmAccount) bool) bool {
+	p := c.warm
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	acc := p.accounts[a.AccountID]
+	return acc != nil && acc.token == a.DeepSeekToken && short(acc)
+}
+
+// warmStore records a refill result and reports whether refilling should go
+// on. Items fetched with a token that was replaced meanwhile, or that add
+// rejects, are discarded.
+func (c *Client) warmStore(a *auth.RequestAuth, err error, counters *warmCounters, add func(*warmAccount) bool) bool {
+	p := c.warm
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		counters.RefillErrors++
+		config.Logger.Debug("[prefetch] refill failed", "account", a.AccountID, "error", err)
+		return false
+	}
+	acc := p.accounts[a.AccountID]
+	if acc == nil || acc.token != a.DeepSeekToken || !add(acc) {
+		counters.Stale++
+		return false
+	}
+	counters.Refilled++
+	return true
+}
+
+// dropWarmSessionsForToken forgets pooled sessions created with token, e.g.
+// around a delete of all the account's sessions upstream, and bumps the
+// session generation so refills already in flight cannot pool sessions that
+// the delete is about to remove.
+func (c *Client) dropWarmSessionsForToken(token string) {
+	if c.warm == nil || token == "" {
+		return
+	}
+	p := c.warm
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, acc := range p.accounts {
+		if acc.token == token {
+			p.sessions.Stale += int64(len(acc.sessions))
+			acc.sessions = nil
+			acc.sessionGen++
+		}
+	}
+}
+
+// PrefetchStatus reports warm pool hit rates and ready item counts.
+func (c *Client) PrefetchStatus() map[string]any {
+	enabled := c.Store != nil && c.Store.PrefetchEnabled()
+	if c.warm == nil {
+		return map[string]any{"enabled": enabled}
+	}
+	p := c.warm
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	readySessions, readyPows := 0, 0
+	for _, acc := range p.accounts {
+		readySessions += len(acc.sessions)
+		readyPows += len(acc.pows)
+	}
+	return map[string]any{
+		"enabled":  enabled,
+		"accounts": len(p.accounts),
+		"sessions": warmCountersStatus(p.sessions, readySessions),
+		"pows":     warmCountersStatus(p.pows, readyPows),
+	}
+}
+
+func warmCountersStatus(c warmCounters, ready int) map[string]any {
+	hitRate := 0.0
+	if total := c.Hits + c.Misses; total > 0 {
+		hitRate = float64(c.Hits) / float64(total)
+	}
+	return map[string]any{
+		"ready":         ready,
+		"hits":          c.Hits,
+		"misses":        c.Misses,
+		"hit_rate":      hitRate,
+		"stale":         c.Stale,
+		"refilled":      c.Refilled,
+		"refill_errors": c.RefillErrors,
+	}
+}
+
+// challengeExpireAt reads pow.Challenge.ExpireAt, which upstream sends in
+// milliseconds. Values that look like seconds are accepted as well.
+func challengeExpireAt(challenge map[string]any) time.Time {
+	raw, err := json.Marshal(challenge)
+	if err != nil {
+		return time.Time{}
+	}
+	var ch pow.Challenge
+	if err := json.Unmarshal(raw, &ch); err != nil || ch.ExpireAt <= 0 {
+		return time.Time{}
+	}
+	if ch.ExpireAt < 1e12 {
+		return time.Unix(ch.ExpireAt, 0)
+	}
+	return time.UnixMilli(ch.ExpireAt)
+}
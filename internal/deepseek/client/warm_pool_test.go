@@ -0,0 +1,194 @@
+package client
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ds2api/internal/auth"
+	"ds2api/internal/config"
+	"ds2api/internal/deepseek/mockupstream"
+	dsprotocol "ds2api/internal/deepseek/protocol"
+)
+
+func newWarmPoolTestClient(t *testing.T) (*Client, *mockupstream.Server, *auth.RequestAuth) {
+	t.Helper()
+	mock, err := mockupstream.New(mockupstream.Options{})
+	if err != nil {
+		t.Fatalf("new mock: %v", err)
+	}
+	srv := httptest.NewServer(mock)
+	t.Cleanup(srv.Close)
+	t.Setenv(dsprotocol.UpstreamBaseURLEnv, srv.URL)
+	t.Setenv("DS2API_CONFIG_JSON", `{"keys":["k1"],"accounts":[{"email":"u@example.com","password":"p"}],"prefetch":{"enabled":true}}`)
+
+	client := NewClient(config.LoadStore(), nil)
+	acc := config.Account{Email: "u@example.com", Password: "p"}
+	token, err := client.Login(context.Background(), acc)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	return client, mock, &auth.RequestAuth{UseConfigToken: true, DeepSeekToken: token, AccountID: acc.Email, Account: acc}
+}
+
+func waitWarmReady(t *testing.T, c *Client, accountID string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		c.warm.mu.Lock()
+		acc := c.warm.accounts[accountID]
+		ready := acc != nil && !acc.refilling && len(acc.sessions) > 0 && len(acc.pows) > 0
+		c.warm.mu.Unlock()
+		if ready {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("warm pool did not refill")
+}
+
+func TestWarmPoolServesPrefetchedSessionAndPow(t *testing.T) {
+	client, mock, a := newWarmPoolTestClient(t)
+	ctx := context.Background()
+
+	if _, err := client.CreateSession(ctx, a, 1); err != nil {
+		t.Fatalf("cold create session: %v", err)
+	}
+	waitWarmReady(t, client, a.AccountID)
+	before := mock.Stats()
+
+	sessionID, err := client.CreateSession(ctx, a, 1)
+	if err != nil || sessionID == "" {
+		t.Fatalf("warm create session: %q %v", sessionID, err)
+	}
+	powHeader, err := client.GetPow(ctx, a, 1)
+	if err != nil || powHeader == "" {
+		t.Fatalf("warm get pow: %v", err)
+	}
+	resp, err := client.CallCompletion(ctx, a, map[string]any{"chat_session_id": sessionID, "prompt": "hi"}, powHeader, 1)
+	if err != nil {
+		t.Fatalf("completion with warm session and pow: %v", err)
+	}
+	_ = resp.Body.Close()
+	if after := mock.Stats(); after.Completions != before.Completions+1 {
+		t.Fatalf("expected completion to succeed, stats=%+v", after)
+	}
+
+	status := client.PrefetchStatus()
+	sessions := status["sessions"].(map[string]any)
+	pows := status["pows"].(map[string]any)
+	if sessions["hits"] != int64(1) || sessions["misses"] != int64(1) || pows["hits"] != int64(1) {
+		t.Fatalf("unexpected prefetch status: %#v", status)
+	}
+}
+
+func TestWarmPoolDropsItemsFromReplacedToken(t *testing.T) {
+	client, _, a := newWarmPoolTestClient(t)
+	ctx := context.Background()
+	if _, err := client.CreateSession(ctx, a, 1); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	waitWarmReady(t, client, a.AccountID)
+
+	token, err := client.Login(ctx, a.Account)
+	if err != nil {
+		t.Fatalf("relogin: %v", err)
+	}
+	refreshed := *a
+	refreshed.DeepSeekToken = token
+	if _, ok := client.takeWarmSession(&refreshed); ok {
+		t.Fatal("expected sessions from the old token to be discarded")
+	}
+	waitWarmReady(t, client, a.AccountID)
+	if _, ok := client.takeWarmSession(&refreshed); !ok {
+		t.Fatal("expected refill under the refreshed token")
+	}
+	if stale := client.PrefetchStatus()["sessions"].(map[string]any)["stale"]; stale != int64(1) {
+		t.Fatalf("expected one stale session, got %v", stale)
+	}
+}
+
+func TestWarmPoolDiscardsSessionsRefilledAcrossDeleteAll(t *testing.T) {
+	client, mock, a := newWarmPoolTestClient(t)
+	ctx := context.Background()
+	if _, err := client.CreateSession(ctx, a, 1); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	waitWarmReady(t, client, a.AccountID)
+
+	// A refill that created its session before delete-all must not pool it.
+	gen := client.warmSessionGen(a)
+	id, err := client.createSession(ctx, a, 1)
+	if err != nil {
+		t.Fatalf("refill create session: %v", err)
+	}
+	if err := client.DeleteAllSessions(ctx, a); err != nil {
+		t.Fatalf("delete all: %v", err)
+	}
+	if client.storeWarmSession(a, gen, id, nil) {
+		t.Fatal("expected session from before delete-all to be discarded")
+	}
+
+	// Run real refills concurrently with delete-all: whatever ends up pooled
+	// must still exist upstream.
+	for i := 0; i < 20; i++ {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			client.refillWarmPool(a)
+		}()
+		if err := client.DeleteAllSessions(ctx, a); err != nil {
+			t.Fatalf("delete all: %v", err)
+		}
+		<-done
+		live := map[string]bool{}
+		for _, id := range mock.SessionIDs() {
+			live[id] = true
+		}
+		client.warm.mu.Lock()
+		pooled := append([]warmSession(nil), client.warm.accounts[a.AccountID].sessions...)
+		client.warm.mu.Unlock()
+		for _, s := range pooled {
+			if !live[s.id] {
+				t.Fatalf("iteration %d: pooled session %s was deleted upstream", i, s.id)
+			}
+		}
+	}
+}
+
+func TestWarmPoolSkipsExpiringPow(t *testing.T) {
+	client, _, a := newWarmPoolTestClient(t)
+	client.warm.mu.Lock()
+	client.warm.account(a).pows = []warmPow{{header: "old", expireAt: time.Now().Add(5 * time.Second)}}
+	client.warm.account(a).refilling = true
+	client.warm.mu.Unlock()
+
+	if _, ok := client.takeWarmPow(a); ok {
+		t.Fatal("expected pow close to expiry to be discarded")
+	}
+	if stale := client.PrefetchStatus()["pows"].(map[string]any)["stale"]; stale != int64(1) {
+		t.Fatalf("expected one stale pow, got %v", stale)
+	}
+}
+
+func TestWarmPoolIgnoresDirectTokenCallers(t *testing.T) {
+	client, mock, a := newWarmPoolTestClient(t)
+	direct := &auth.RequestAuth{DeepSeekToken: a.DeepSeekToken}
+	if _, err := client.CreateSession(context.Background(), direct, 1); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if stats := mock.Stats(); stats.Sessions != 1 {
+		t.Fatalf("expected no background prefetch for direct tokens, stats=%+v", stats)
+	}
+}
+
+func TestChallengeExpireAtAcceptsMillisAndSeconds(t *testing.T) {
+	if got := challengeExpireAt(map[string]any{"expire_at": float64(1712345678000)}); !got.Equal(time.UnixMilli(1712345678000)) {
+		t.Fatalf("unexpected millis expiry: %v", got)
+	}
+	if got := challengeExpireAt(map[string]any{"expire_at": float64(1712345678)}); !got.Equal(time.Unix(1712345678, 0)) {
+		t.Fatalf("unexpected seconds expiry: %v", got)
+	}
+}
@@ -0,0 +1,77 @@
+package mockupstream
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Sample is one captured upstream stream split into rounds: the completion
+// response first, then one round per continue call.
+type Sample struct {
+	ID     string
+	Rounds [][]string
+}
+
+// LoadSamples reads every <dir>/<id>/upstream.stream.sse.
+func LoadSamples(dir string) (map[string]*Sample, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("mockupstream: read samples dir: %w", err)
+	}
+	samples := map[string]*Sample{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		raw, err := os.ReadFile(filepath.Join(dir, entry.Name(), "upstream.stream.sse"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("mockupstream: read sample %s: %w", entry.Name(), err)
+		}
+		rounds := SplitRounds(string(raw))
+		if len(rounds) == 0 {
+			continue
+		}
+		samples[entry.Name()] = &Sample{ID: entry.Name(), Rounds: rounds}
+	}
+	return samples, nil
+}
+
+// SplitRounds splits a captured stream into SSE blocks, starting a new round
+// at every "event: ready". Anything captured before the first ready event,
+// such as an upload response, is dropped.
+func SplitRounds(raw string) [][]string {
+	raw = strings.ReplaceAll(raw, "\r\n", "\n")
+	var rounds [][]string
+	for _, block := range strings.Split(raw, "\n\n") {
+		block = strings.Trim(block, "\n")
+		if i := strings.Index(block, "\nevent: ready"); i >= 0 {
+			block = block[i+1:]
+		}
+		if block == "" {
+			continue
+		}
+		if strings.HasPrefix(block, "event: ready") {
+			rounds = append(rounds, nil)
+		}
+		if len(rounds) == 0 {
+			continue
+		}
+		rounds[len(rounds)-1] = append(rounds[len(rounds)-1], block)
+	}
+	return rounds
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,419 @@
+// Package mockupstream is an in-process fake of the DeepSeek web API used by
+// ds2api. It implements login, chat sessions, PoW challenges, completion and
+// continue SSE, file upload/fetch and session deletion, replays captured
+// tests/raw_stream_samples corpora and can inject scripted failures, so
+// ds2api can be exercised without network access.
+//
+// Point ds2api at it with DS2API_UPSTREAM_BASE_URL.
+package mockupstream
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	dsprotocol "ds2api/internal/deepseek/protocol"
+	powpkg "ds2api/pow"
+)
+
+// DefaultPowDifficulty keeps challenges cheap to solve in tests.
+const DefaultPowDifficulty = 1000
+
+// Options configures a Server.
+type Options struct {
+	// SamplesDir is a tests/raw_stream_samples style directory. Empty means
+	// only the scripted reply is available.
+	SamplesDir string
+	// Sample is the sample id replayed when the prompt does not pick one.
+	// Empty means the scripted reply.
+	Sample string
+	// Reply is the scripted answer text. Empty uses DefaultReply.
+	Reply string
+	// PowDifficulty bounds the PoW answer search. Zero uses
+	// DefaultPowDifficulty.
+	PowDifficulty int64
+	// SlowFirstByte is the delay applied by the slow_first_byte scenario.
+	// Zero means two seconds.
+	SlowFirstByte time.Duration
+	// ChunkDelay is slept between SSE blocks so streams take real time.
+	ChunkDelay time.Duration
+}
+
+// Server is an http.Handler that behaves like chat.deepseek.com.
+type Server struct {
+	opts    Options
+	samples map[string]*Sample
+	mux     *http.ServeMux
+
+	mu         sync.Mutex
+	nextID     int
+	tokens     map[string]bool
+	sessions   map[string]*session
+	files      map[string]map[string]any
+	challenges map[string]int64
+	scenarios  []string
+	stats      Stats
+}
+
+// Stats counts the requests the server has handled.
+type Stats struct {
+	Logins         int `json:"logins"`
+	Sessions       int `json:"sessions"`
+	PowChallenges  int `json:"pow_challenges"`
+	Completions    int `json:"completions"`
+	Continues      int `json:"continues"`
+	Uploads        int `json:"uploads"`
+	DeletedSession int `json:"deleted_sessions"`
+	AuthFailures   int `json:"auth_failures"`
+	// ScenariosApplied counts scenarios that took effect, from either the
+	// queue or a prompt marker.
+	ScenariosApplied int `json:"scenarios_applied"`
+}
+
+type session struct {
+	id        string
+	rounds    [][]string
+	round     int
+	updatedAt time.Time
+}
+
+// sessionPageSize mirrors the upstream fetch_page page size.
+const sessionPageSize = 20
+
+// New builds a Server and loads the samples in opts.SamplesDir.
+func New(opts Options) (*Server, error) {
+	if opts.PowDifficulty <= 0 {
+		opts.PowDifficulty = DefaultPowDifficulty
+	}
+	if opts.SlowFirstByte <= 0 {
+		opts.SlowFirstByte = 2 * time.Second
+	}
+	if strings.TrimSpace(opts.Reply) == "" {
+		opts.Reply = DefaultReply
+	}
+	s := &Server{
+		opts:       opts,
+		samples:    map[string]*Sample{},
+		tokens:     map[string]bool{},
+		sessions:   map[string]*session{},
+		files:      map[string]map[string]any{},
+		challenges: map[string]int64{},
+	}
+	if strings.TrimSpace(opts.SamplesDir) != "" {
+		samples, err := LoadSamples(opts.SamplesDir)
+		if err != nil {
+			return nil, err
+		}
+		s.samples = samples
+	}
+	if opts.Sample != "" && s.samples[opts.Sample] == nil {
+		return nil, &UnknownSampleError{ID: opts.Sample}
+	}
+	s.routes()
+	return s, nil
+}
+
+// UnknownSampleError reports a sample id missing from SamplesDir.
+type UnknownSampleError struct {
+	ID string
+}
+
+func (e *UnknownSampleError) Error() string {
+	return "mockupstream: unknown sample " + strconv.Quote(e.ID)
+}
+
+func (s *Server) routes() {
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("POST "+dsprotocol.DeepSeekLoginPath, s.handleLogin)
+	s.mux.HandleFunc("POST "+dsprotocol.DeepSeekCreateSessionPath, s.authed(s.handleCreateSession))
+	s.mux.HandleFunc("POST "+dsprotocol.DeepSeekCreatePowPath, s.authed(s.handleCreatePow))
+	s.mux.HandleFunc("POST "+dsprotocol.DeepSeekCompletionPath, s.authed(s.handleCompletion))
+	s.mux.HandleFunc("POST "+dsprotocol.DeepSeekContinuePath, s.authed(s.handleContinue))
+	s.mux.HandleFunc("POST "+dsprotocol.DeepSeekUploadFilePath, s.authed(s.handleUploadFile))
+	s.mux.HandleFunc("GET "+dsprotocol.DeepSeekFetchFilesPath, s.authed(s.handleFetchFiles))
+	s.mux.HandleFunc("GET "+dsprotocol.DeepSeekFetchSessionPath, s.authed(s.handleFetchSessions))
+	s.mux.HandleFunc("POST "+dsprotocol.DeepSeekDeleteSessionPath, s.authed(s.handleDeleteSession))
+	s.mux.HandleFunc("POST "+dsprotocol.DeepSeekDeleteAllSessionsPath, s.authed(s.handleDeleteAllSessions))
+	s.mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	s.mux.HandleFunc("POST /_mock/scenario", s.handleQueueScenario)
+	s.mux.HandleFunc("POST /_mock/reset", s.handleReset)
+	s.mux.HandleFunc("GET /_mock/stats", s.handleStats)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Stats returns a snapshot of the request counters.
+func (s *Server) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// Samples lists the loaded sample ids.
+func (s *Server) Samples() []string {
+	return sortedKeys(s.samples)
+}
+
+func (s *Server) id(prefix string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	return prefix + strconv.Itoa(s.nextID)
+}
+
+// authed only accepts tokens issued by this server's login endpoint, so
+// tokens carried over from a real config always go through a refresh.
+func (s *Server) authed(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		s.mu.Lock()
+		ok := s.tokens[token]
+		if !ok {
+			s.stats.AuthFailures++
+		}
+		s.mu.Unlock()
+		if !ok {
+			writeTokenExpired(w)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	var body map[string]any
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	if strings.TrimSpace(stringValue(body["password"])) == "" {
+		writeBizError(w, 2, "missing password")
+		return
+	}
+	token := s.id("mock-token-")
+	s.mu.Lock()
+	s.tokens[token] = true
+	s.stats.Logins++
+	s.mu.Unlock()
+	writeBizData(w, map[string]any{"user": map[string]any{
+		"token":  token,
+		"email":  stringValue(body["email"]),
+		"mobile": stringValue(body["mobile"]),
+	}})
+}
+
+func (s *Server) handleCreateSession(w http.ResponseWriter, _ *http.Request) {
+	if s.takeScenario(ScenarioTokenExpired) {
+		s.revokeTokens()
+		writeTokenExpired(w)
+		return
+	}
+	id := s.id("mock-session-")
+	s.mu.Lock()
+	s.sessions[id] = &session{id: id, updatedAt: time.Now()}
+	s.stats.Sessions++
+	s.mu.Unlock()
+	writeBizData(w, map[string]any{"id": id, "chat_session": map[string]any{"id": id}})
+}
+
+func (s *Server) handleCreatePow(w http.ResponseWriter, r *http.Request) {
+	if s.takeScenario(ScenarioPowFailure) {
+		writeBizError(w, 1, "create pow challenge failed")
+		return
+	}
+	var body map[string]any
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	targetPath := stringValue(body["target_path"])
+	if targetPath == "" {
+		targetPath = dsprotocol.DeepSeekCompletionTargetPath
+	}
+	salt := s.id("mock-salt-")
+	expireAt := time.Now().Add(5 * time.Minute).UnixMilli()
+	answer := time.Now().UnixNano() % s.opts.PowDifficulty
+	hash := powpkg.DeepSeekHashV1([]byte(powpkg.BuildPrefix(salt, expireAt) + strconv.FormatInt(answer, 10)))
+	s.mu.Lock()
+	s.challenges[salt] = expireAt
+	s.stats.PowChallenges++
+	s.mu.Unlock()
+	writeBizData(w, map[string]any{"challenge": map[string]any{
+		"algorithm":   "DeepSeekHashV1",
+		"challenge":   hex.EncodeToString(hash[:]),
+		"salt":        salt,
+		"expire_at":   expireAt,
+		"difficulty":  s.opts.PowDifficulty,
+		"signature":   "mock-signature",
+		"target_path": targetPath,
+	}})
+}
+
+func (s *Server) handleUploadFile(w http.ResponseWriter, r *http.Request) {
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeBizError(w, 1, "missing file")
+		return
+	}
+	data, _ := io.ReadAll(file)
+	_ = file.Close()
+	id := s.id("file-mock-")
+	record := map[string]any{
+		"id":        id,
+		"status":    "PENDING",
+		"file_name": header.Filename,
+		"file_size": len(data),
+		"is_image":  strings.HasPrefix(header.Header.Get("Content-Type"), "image/"),
+	}
+	s.mu.Lock()
+	s.files[id] = record
+	s.stats.Uploads++
+	s.mu.Unlock()
+	writeBizData(w, record)
+}
+
+// handleFetchFiles reports every uploaded file as parsed, so the client's
+// readiness poll succeeds on its first fetch.
+func (s *Server) handleFetchFiles(w http.ResponseWriter, r *http.Request) {
+	var out []any
+	s.mu.Lock()
+	for _, id := range strings.Split(r.URL.Query().Get("file_ids"), ",") {
+		record, ok := s.files[strings.TrimSpace(id)]
+		if !ok {
+			continue
+		}
+		record["status"] = "SUCCESS"
+		copied := make(map[string]any, len(record))
+		for k, v := range record {
+			copied[k] = v
+		}
+		out = append(out, copied)
+	}
+	s.mu.Unlock()
+	writeBizData(w, map[string]any{"files": out})
+}
+
+// handleFetchSessions lists sessions newest first, one page at a time. The
+// lte_cursor parameter continues from the last page's oldest entry,
+// inclusive like upstream.
+func (s *Server) handleFetchSessions(w http.ResponseWriter, r *http.Request) {
+	cursor, hasCursor := 0.0, false
+	if raw := r.URL.Query().Get("lte_cursor"); raw != "" {
+		if v, err := strconv.ParseFloat(raw, 64); err == nil {
+			cursor, hasCursor = v, true
+		}
+	}
+	s.mu.Lock()
+	all := make([]*session, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		if hasCursor && unixSeconds(sess.updatedAt) > cursor {
+			continue
+		}
+		all = append(all, sess)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if !all[i].updatedAt.Equal(all[j].updatedAt) {
+			return all[i].updatedAt.After(all[j].updatedAt)
+		}
+		return all[i].id > all[j].id
+	})
+	hasMore := len(all) > sessionPageSize
+	if hasMore {
+		all = all[:sessionPageSize]
+	}
+	list := make([]any, 0, len(all))
+	for _, sess := range all {
+		list = append(list, map[string]any{"id": sess.id, "pinned": false, "updated_at": unixSeconds(sess.updatedAt)})
+	}
+	s.mu.Unlock()
+	writeBizData(w, map[string]any{"chat_sessions": list, "has_more": hasMore})
+}
+
+// AddSession registers a session last updated at updatedAt, e.g. to seed
+// old history for cleanup tests.
+func (s *Server) AddSession(updatedAt time.Time) string {
+	id := s.id("mock-session-")
+	s.mu.Lock()
+	s.sessions[id] = &session{id: id, updatedAt: updatedAt}
+	s.mu.Unlock()
+	return id
+}
+
+// SessionIDs lists the sessions that currently exist, sorted by id.
+func (s *Server) SessionIDs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sortedKeys(s.sessions)
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixMicro()) / 1e6
+}
+
+func (s *Server) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
+	var body map[string]any
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	id := stringValue(body["chat_session_id"])
+	s.mu.Lock()
+	_, ok := s.sessions[id]
+	if ok {
+		delete(s.sessions, id)
+		s.stats.DeletedSession++
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeBizError(w, 1, "chat session not found")
+		return
+	}
+	writeBizData(w, map[string]any{})
+}
+
+func (s *Server) handleDeleteAllSessions(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	s.stats.DeletedSession += len(s.sessions)
+	s.sessions = map[string]*session{}
+	s.mu.Unlock()
+	writeBizData(w, map[string]any{})
+}
+
+func (s *Server) revokeTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = map[string]bool{}
+}
+
+func writeTokenExpired(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	_ = json.NewEncoder(w).Encode(map[string]any{"code": 40003, "msg": "token expired", "data": nil})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeBizData(w http.ResponseWriter, bizData any) {
+	writeJSON(w, map[string]any{
+		"code": 0,
+		"msg":  "",
+		"data": map[string]any{"biz_code": 0, "biz_msg": "", "biz_data": bizData},
+	})
+}
+
+func writeBizError(w http.ResponseWriter, bizCode int, msg string) {
+	writeJSON(w, map[string]any{
+		"code": 0,
+		"msg":  "",
+		"data": map[string]any{"biz_code": bizCode, "biz_msg": msg, "biz_data": nil},
+	})
+}
+
+func stringValue(v any) string {
+	s, _ := v.(string)
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,150 @@
+package mockupstream
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ds2api/internal/auth"
+	"ds2api/internal/config"
+	dsclient "ds2api/internal/deepseek/client"
+	dsprotocol "ds2api/internal/deepseek/protocol"
+)
+
+func startMock(t *testing.T, opts Options) (*Server, *dsclient.Client, *auth.RequestAuth) {
+	t.Helper()
+	mock, err := New(opts)
+	if err != nil {
+		t.Fatalf("new mock: %v", err)
+	}
+	srv := httptest.NewServer(mock)
+	t.Cleanup(srv.Close)
+	t.Setenv(dsprotocol.UpstreamBaseURLEnv, srv.URL)
+
+	client := dsclient.NewClient(nil, nil)
+	token, err := client.Login(context.Background(), config.Account{Email: "mock@example.com", Password: "secret"})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	return mock, client, &auth.RequestAuth{DeepSeekToken: token, AccountID: "mock@example.com"}
+}
+
+func complete(t *testing.T, client *dsclient.Client, a *auth.RequestAuth, prompt string) (string, error) {
+	t.Helper()
+	ctx := context.Background()
+	sessionID, err := client.CreateSession(ctx, a, 1)
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	pow, err := client.GetPow(ctx, a, 1)
+	if err != nil {
+		t.Fatalf("get pow: %v", err)
+	}
+	resp, err := client.CallCompletion(ctx, a, map[string]any{"chat_session_id": sessionID, "prompt": prompt}, pow, 1)
+	if err != nil {
+		t.Fatalf("completion: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
+
+func TestScriptedCompletionWithRealClient(t *testing.T) {
+	mock, client, a := startMock(t, Options{Reply: "scripted answer"})
+	body, err := complete(t, client, a, "hi")
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	if !strings.Contains(body, `"v":"scripted"`) || !strings.Contains(body, `"v":"FINISHED"`) {
+		t.Fatalf("unexpected stream: %s", body)
+	}
+	if stats := mock.Stats(); stats.Logins != 1 || stats.Completions != 1 || stats.Continues != 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestSampleReplayDrivesAutoContinue(t *testing.T) {
+	mock, client, a := startMock(t, Options{SamplesDir: "../../../tests/raw_stream_samples"})
+	body, err := complete(t, client, a, "[[mock:sample=continue-thinking-snapshot-replay-20260405]]")
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	if strings.Count(body, "event: ready") != 2 {
+		t.Fatalf("expected completion and continue rounds, got %d", strings.Count(body, "event: ready"))
+	}
+	if stats := mock.Stats(); stats.Continues != 1 {
+		t.Fatalf("expected one continue call, got %+v", stats)
+	}
+}
+
+func TestScenarios(t *testing.T) {
+	mock, client, a := startMock(t, Options{})
+	ctx := context.Background()
+
+	mock.QueueScenario(ScenarioPowFailure)
+	if _, err := client.GetPow(ctx, a, 1); err == nil {
+		t.Fatal("expected pow failure")
+	}
+
+	if _, err := complete(t, client, a, "[[mock:scenario=disconnect]]"); err == nil {
+		t.Fatal("expected mid-stream disconnect to surface as a read error")
+	}
+
+	body, err := complete(t, client, a, "[[mock:scenario=content_filter]]")
+	if err != nil || !strings.Contains(body, "CONTENT_FILTER") || strings.Contains(body, "FINISHED") {
+		t.Fatalf("unexpected content filter stream: err=%v body=%s", err, body)
+	}
+
+	mock.QueueScenario(ScenarioTokenExpired)
+	if _, err := client.CreateSession(ctx, a, 1); err == nil {
+		t.Fatal("expected expired token")
+	}
+	if _, err := client.CreateSession(ctx, a, 1); err == nil {
+		t.Fatal("expected revoked token to stay invalid")
+	}
+	if _, err := client.CreateSession(ctx, &auth.RequestAuth{DeepSeekToken: "never-issued"}, 1); err == nil {
+		t.Fatal("expected tokens not issued by the mock to be rejected")
+	}
+}
+
+func TestUploadFetchAndDeleteSession(t *testing.T) {
+	mock, client, a := startMock(t, Options{})
+	ctx := context.Background()
+	file, err := client.UploadFile(ctx, a, dsclient.UploadFileRequest{Filename: "notes.txt", ContentType: "text/plain", Data: []byte("hello")}, 1)
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	if !strings.HasPrefix(file.ID, "file-mock-") || !strings.EqualFold(file.Status, "SUCCESS") {
+		t.Fatalf("unexpected upload result: %+v", file)
+	}
+	sessionID, err := client.CreateSession(ctx, a, 1)
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	if res, err := client.DeleteSession(ctx, a, sessionID, 1); err != nil || !res.Success {
+		t.Fatalf("delete session: %+v %v", res, err)
+	}
+	if stats := mock.Stats(); stats.Uploads != 1 || stats.DeletedSession != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestToolCallReplyUsesFirstDeclaredTool(t *testing.T) {
+	prompt := "You have access to these tools:\n\nTool: search\nDescription: search documents\nacross lines\nParameters: {\"type\":\"object\",\"properties\":{\"q\":{\"type\":\"string\"},\"n\":{\"type\":\"integer\"}},\"required\":[\"q\",\"n\"]}\n\nTool: other\nDescription: x\nParameters: {}"
+	got := toolCallReply(prompt)
+	if !strings.Contains(got, `<invoke name="search">`) || !strings.Contains(got, `<parameter name="q"><![CDATA[mock]]></parameter>`) || !strings.Contains(got, `<parameter name="n">1</parameter>`) {
+		t.Fatalf("unexpected tool call reply: %s", got)
+	}
+	if toolCallReply("plain prompt") != "" {
+		t.Fatal("expected no tool call without declared tools")
+	}
+}
+
+func TestSplitRoundsDropsCapturedPreamble(t *testing.T) {
+	rounds := SplitRounds("{\"code\":0}\nevent: ready\ndata: {}\n\ndata: {\"v\":\"a\"}\n\nevent: ready\ndata: {}\n\ndata: {\"v\":\"b\"}\n")
+	if len(rounds) != 2 || len(rounds[0]) != 2 || rounds[0][0] != "event: ready\ndata: {}" || rounds[1][1] != `data: {"v":"b"}` {
+		t.Fatalf("unexpected rounds: %#v", rounds)
+	}
+}
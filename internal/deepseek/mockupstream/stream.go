@@ -0,0 +1,358 @@
+package mockupstream
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	dsprotocol "ds2api/internal/deepseek/protocol"
+	powpkg "ds2api/pow"
+)
+
+// DefaultReply is the scripted answer streamed when no sample is selected.
+const DefaultReply = "Hello from the ds2api mock upstream. This reply is scripted and no real model was called."
+
+const defaultThinking = "The mock upstream is replaying a scripted reasoning trace."
+
+// Scenario names accepted by prompt markers and POST /_mock/scenario.
+const (
+	// ScenarioTokenExpired answers the next create_session with HTTP 401
+	// and revokes every issued token, so the caller has to log in again.
+	ScenarioTokenExpired = "token_expired"
+	// ScenarioPowFailure fails the next PoW challenge request.
+	ScenarioPowFailure = "pow_failure"
+	// ScenarioSlowFirstByte delays the completion headers by
+	// Options.SlowFirstByte.
+	ScenarioSlowFirstByte = "slow_first_byte"
+	// ScenarioDisconnect drops the connection halfway through the stream.
+	ScenarioDisconnect = "disconnect"
+	// ScenarioTruncated ends the stream halfway without a final status.
+	ScenarioTruncated = "truncated"
+	// ScenarioContentFilter stops the reply with a CONTENT_FILTER status.
+	ScenarioContentFilter = "content_filter"
+)
+
+var scenarioNames = map[string]bool{
+	ScenarioTokenExpired:  true,
+	ScenarioPowFailure:    true,
+	ScenarioSlowFirstByte: true,
+	ScenarioDisconnect:    true,
+	ScenarioTruncated:     true,
+	ScenarioContentFilter: true,
+}
+
+// Prompt markers pick a sample or a stream scenario for one completion,
+// e.g. "[[mock:sample=markdown-format-example-20260405]]".
+var promptMarkerRe = regexp.MustCompile(`\[\[mock:(sample|scenario)=([A-Za-z0-9_.\-]+)\]\]`)
+
+// IsScenario reports whether name is a known scenario.
+func IsScenario(name string) bool {
+	return scenarioNames[name]
+}
+
+// QueueScenario arms a one-shot scenario for the next request it applies to.
+func (s *Server) QueueScenario(name string) bool {
+	if !IsScenario(name) {
+		return false
+	}
+	s.mu.Lock()
+	s.scenarios = append(s.scenarios, name)
+	s.mu.Unlock()
+	return true
+}
+
+// Reset clears queued scenarios, issued tokens, sessions, files and stats.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scenarios = nil
+	s.tokens = map[string]bool{}
+	s.sessions = map[string]*session{}
+	s.files = map[string]map[string]any{}
+	s.challenges = map[string]int64{}
+	s.stats = Stats{}
+}
+
+func (s *Server) takeScenario(names ...string) bool {
+	return s.takeAnyScenario(names...) != ""
+}
+
+func (s *Server) takeAnyScenario(names ...string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, queued := range s.scenarios {
+		for _, name := range names {
+			if queued == name {
+				s.scenarios = append(s.scenarios[:i], s.scenarios[i+1:]...)
+				s.stats.ScenariosApplied++
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+func (s *Server) handleQueueScenario(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Scenario string `json:"scenario"`
+		Count    int    `json:"count"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || !IsScenario(body.Scenario) {
+		http.Error(w, "unknown scenario", http.StatusBadRequest)
+		return
+	}
+	if body.Count <= 0 {
+		body.Count = 1
+	}
+	for i := 0; i < body.Count; i++ {
+		s.QueueScenario(body.Scenario)
+	}
+	writeJSON(w, map[string]any{"queued": body.Scenario, "count": body.Count})
+}
+
+func (s *Server) handleReset(w http.ResponseWriter, _ *http.Request) {
+	s.Reset()
+	writeJSON(w, map[string]any{"reset": true})
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, map[string]any{"stats": s.Stats(), "samples": s.Samples()})
+}
+
+func (s *Server) handleCompletion(w http.ResponseWriter, r *http.Request) {
+	var body map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if !s.validPowHeader(r.Header.Get("x-ds-pow-response"), dsprotocol.DeepSeekCompletionTargetPath) {
+		http.Error(w, `{"code":40301,"msg":"invalid pow response"}`, http.StatusForbidden)
+		return
+	}
+	sess, ok := s.lookupSession(stringValue(body["chat_session_id"]))
+	if !ok {
+		http.Error(w, `{"code":1,"msg":"chat session not found"}`, http.StatusNotFound)
+		return
+	}
+	prompt := stringValue(body["prompt"])
+	sampleID, scenario := promptMarkers(prompt)
+	if scenario != "" {
+		s.mu.Lock()
+		s.stats.ScenariosApplied++
+		s.mu.Unlock()
+	} else {
+		scenario = s.takeAnyScenario(ScenarioSlowFirstByte, ScenarioDisconnect, ScenarioTruncated, ScenarioContentFilter)
+	}
+	if sampleID == "" {
+		sampleID = s.opts.Sample
+	}
+	var rounds [][]string
+	if sampleID != "" {
+		sample := s.samples[sampleID]
+		if sample == nil {
+			http.Error(w, `{"code":1,"msg":"unknown mock sample"}`, http.StatusBadRequest)
+			return
+		}
+		rounds = sample.Rounds
+	} else {
+		thinking, _ := body["thinking_enabled"].(bool)
+		reply := s.opts.Reply
+		if call := toolCallReply(prompt); call != "" {
+			reply = call
+		}
+		rounds = [][]string{scriptedRound(reply, thinking)}
+	}
+	s.mu.Lock()
+	sess.rounds = rounds
+	sess.round = 0
+	sess.updatedAt = time.Now()
+	s.stats.Completions++
+	s.mu.Unlock()
+	s.writeRound(w, rounds[0], scenario)
+}
+
+func (s *Server) handleContinue(w http.ResponseWriter, r *http.Request) {
+	var body map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	sess, ok := s.lookupSession(stringValue(body["chat_session_id"]))
+	if !ok {
+		http.Error(w, `{"code":1,"msg":"chat session not found"}`, http.StatusNotFound)
+		return
+	}
+	s.mu.Lock()
+	sess.round++
+	var round []string
+	if sess.round < len(sess.rounds) {
+		round = sess.rounds[sess.round]
+	}
+	s.stats.Continues++
+	s.mu.Unlock()
+	if round == nil {
+		round = scriptedRound("", false)
+	}
+	s.writeRound(w, round, "")
+}
+
+func (s *Server) lookupSession(id string) (*session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[id]
+	return sess, ok
+}
+
+// validPowHeader checks that the x-ds-pow-response answer solves the
+// challenge it echoes.
+func (s *Server) validPowHeader(header, targetPath string) bool {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(header))
+	if err != nil {
+		return false
+	}
+	var resp struct {
+		Challenge  string `json:"challenge"`
+		Salt       string `json:"salt"`
+		Answer     int64  `json:"answer"`
+		TargetPath string `json:"target_path"`
+	}
+	if err := json.Unmarshal(raw, &resp); err != nil || resp.TargetPath != targetPath {
+		return false
+	}
+	s.mu.Lock()
+	expireAt, ok := s.challenges[resp.Salt]
+	s.mu.Unlock()
+	if !ok {
+		return false
+	}
+	hash := powpkg.DeepSeekHashV1([]byte(powpkg.BuildPrefix(resp.Salt, expireAt) + strconv.FormatInt(resp.Answer, 10)))
+	return hexEqual(resp.Challenge, hash[:])
+}
+
+// writeRound streams SSE blocks, applying the stream-shaping scenarios.
+func (s *Server) writeRound(w http.ResponseWriter, blocks []string, scenario string) {
+	if scenario == ScenarioSlowFirstByte {
+		time.Sleep(s.opts.SlowFirstByte)
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	switch scenario {
+	case ScenarioDisconnect, ScenarioTruncated:
+		blocks = blocks[:len(blocks)/2]
+	case ScenarioContentFilter:
+		blocks = append(blocks[:len(blocks)/2:len(blocks)/2],
+			dataBlock(map[string]any{"p": "response/status", "o": "SET", "v": "CONTENT_FILTER"}),
+			finishBlock,
+			closeBlock,
+		)
+	}
+	for _, block := range blocks {
+		if _, err := w.Write([]byte(block + "\n\n")); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if s.opts.ChunkDelay > 0 {
+			time.Sleep(s.opts.ChunkDelay)
+		}
+	}
+	if scenario == ScenarioDisconnect {
+		// Abort without the chunked terminator so the client sees an
+		// unexpected EOF instead of a clean end of stream.
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func promptMarkers(prompt string) (sample, scenario string) {
+	for _, m := range promptMarkerRe.FindAllStringSubmatch(prompt, -1) {
+		switch m[1] {
+		case "sample":
+			sample = m[2]
+		case "scenario":
+			if IsScenario(m[2]) {
+				scenario = m[2]
+			}
+		}
+	}
+	return sample, scenario
+}
+
+const (
+	finishBlock = "event: finish\ndata: {}"
+	closeBlock  = "event: close\ndata: {\"click_behavior\":\"none\",\"auto_resume\":false}"
+)
+
+// scriptedRound renders text in the upstream fragment format: an optional
+// THINK fragment followed by a RESPONSE fragment, both appended a few runes
+// at a time, then a FINISHED status.
+func scriptedRound(text string, thinking bool) []string {
+	blocks := []string{
+		"event: ready\ndata: {\"request_message_id\":1,\"response_message_id\":2,\"model_type\":\"default\"}",
+	}
+	fragmentType := "RESPONSE"
+	if thinking {
+		fragmentType = "THINK"
+	}
+	blocks = append(blocks, dataBlock(map[string]any{"v": map[string]any{"response": map[string]any{
+		"message_id":       2,
+		"parent_id":        1,
+		"role":             "ASSISTANT",
+		"thinking_enabled": thinking,
+		"status":           "WIP",
+		"fragments":        []any{map[string]any{"id": 1, "type": fragmentType, "content": ""}},
+	}}}))
+	if thinking {
+		blocks = append(blocks, appendChunks(defaultThinking)...)
+		blocks = append(blocks, dataBlock(map[string]any{
+			"p": "response/fragments",
+			"o": "APPEND",
+			"v": []any{map[string]any{"id": 2, "type": "RESPONSE", "content": ""}},
+		}))
+	}
+	blocks = append(blocks, appendChunks(text)...)
+	return append(blocks,
+		dataBlock(map[string]any{"p": "response/status", "o": "SET", "v": "FINISHED"}),
+		finishBlock,
+		closeBlock,
+	)
+}
+
+func appendChunks(text string) []string {
+	var blocks []string
+	for len(text) > 0 {
+		chunk := firstRunes(text, 8)
+		text = text[len(chunk):]
+		blocks = append(blocks, dataBlock(map[string]any{"p": "response/fragments/-1/content", "o": "APPEND", "v": chunk}))
+	}
+	return blocks
+}
+
+func firstRunes(text string, n int) string {
+	i := 0
+	for pos := range text {
+		if i == n {
+			return text[:pos]
+		}
+		i++
+	}
+	return text
+}
+
+func dataBlock(v any) string {
+	b, _ := json.Marshal(v)
+	return "data: " + string(b)
+}
+
+func hexEqual(encoded string, want []byte) bool {
+	got, err := hex.DecodeString(strings.TrimSpace(encoded))
+	return err == nil && bytes.Equal(got, want)
+}
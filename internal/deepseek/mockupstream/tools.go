@@ -0,0 +1,47 @@
+package mockupstream
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// toolSchemaRe matches the tool declarations ds2api renders into prompts:
+// "Tool: <name>\nDescription: ...\nParameters: <json schema>".
+var toolSchemaRe = regexp.MustCompile(`(?s)Tool: ([^\n]+)\nDescription: .*?\nParameters: (\{[^\n]*\})`)
+
+// toolCallReply scripts a call to the first tool declared in the prompt,
+// filling its required parameters with placeholder values. It returns ""
+// when the prompt declares no tools.
+func toolCallReply(prompt string) string {
+	m := toolSchemaRe.FindStringSubmatch(prompt)
+	if m == nil {
+		return ""
+	}
+	name := strings.TrimSpace(m[1])
+	var schema struct {
+		Properties map[string]struct {
+			Type string `json:"type"`
+		} `json:"properties"`
+		Required []string `json:"required"`
+	}
+	_ = json.Unmarshal([]byte(m[2]), &schema)
+
+	var b strings.Builder
+	b.WriteString("Calling " + name + " with placeholder arguments.\n\n")
+	b.WriteString("<tool_calls>\n  <invoke name=\"" + name + "\">\n")
+	for _, param := range schema.Required {
+		value := "<![CDATA[mock]]>"
+		switch schema.Properties[param].Type {
+		case "integer", "number":
+			value = "1"
+		case "boolean":
+			value = "true"
+		case "array", "object":
+			continue
+		}
+		b.WriteString("    <parameter name=\"" + param + "\">" + value + "</parameter>\n")
+	}
+	b.WriteString("  </invoke>\n</tool_calls>")
+	return b.String()
+}
@@ -4,24 +4,65 @@ import (
 	_ "embed"
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"os"
+	"strings"
 )
 
 const (
-	DeepSeekHost                 = "chat.deepseek.com"
-	DeepSeekLoginURL             = "https://chat.deepseek.com/api/v0/users/login"
-	DeepSeekCreateSessionURL     = "https://chat.deepseek.com/api/v0/chat_session/create"
-	DeepSeekCreatePowURL         = "https://chat.deepseek.com/api/v0/chat/create_pow_challenge"
-	DeepSeekCompletionURL        = "https://chat.deepseek.com/api/v0/chat/completion"
-	DeepSeekContinueURL          = "https://chat.deepseek.com/api/v0/chat/continue"
-	DeepSeekUploadFileURL        = "https://chat.deepseek.com/api/v0/file/upload_file"
-	DeepSeekFetchFilesURL        = "https://chat.deepseek.com/api/v0/file/fetch_files"
-	DeepSeekFetchSessionURL      = "https://chat.deepseek.com/api/v0/chat_session/fetch_page"
-	DeepSeekDeleteSessionURL     = "https://chat.deepseek.com/api/v0/chat_session/delete"
-	DeepSeekDeleteAllSessionsURL = "https://chat.deepseek.com/api/v0/chat_session/delete_all"
-	DeepSeekCompletionTargetPath = "/api/v0/chat/completion"
-	DeepSeekUploadTargetPath     = "/api/v0/file/upload_file"
+	DeepSeekHost = "chat.deepseek.com"
+	// DefaultBaseURL is the upstream origin used when
+	// DS2API_UPSTREAM_BASE_URL is not set.
+	DefaultBaseURL = "https://chat.deepseek.com"
+	// UpstreamBaseURLEnv points ds2api at another DeepSeek-compatible origin,
+	// such as cmd/ds2api-mock-upstream.
+	UpstreamBaseURLEnv = "DS2API_UPSTREAM_BASE_URL"
+
+	DeepSeekLoginPath             = "/api/v0/users/login"
+	DeepSeekCreateSessionPath     = "/api/v0/chat_session/create"
+	DeepSeekCreatePowPath         = "/api/v0/chat/create_pow_challenge"
+	DeepSeekCompletionPath        = "/api/v0/chat/completion"
+	DeepSeekContinuePath          = "/api/v0/chat/continue"
+	DeepSeekUploadFilePath        = "/api/v0/file/upload_file"
+	DeepSeekFetchFilesPath        = "/api/v0/file/fetch_files"
+	DeepSeekFetchSessionPath      = "/api/v0/chat_session/fetch_page"
+	DeepSeekDeleteSessionPath     = "/api/v0/chat_session/delete"
+	DeepSeekDeleteAllSessionsPath = "/api/v0/chat_session/delete_all"
+	DeepSeekCompletionTargetPath  = DeepSeekCompletionPath
+	DeepSeekUploadTargetPath      = DeepSeekUploadFilePath
 )
 
+// BaseURL returns the upstream origin without a trailing slash. It is read
+// on every call so tests and the mock upstream can switch it at runtime.
+func BaseURL() string {
+	raw := strings.TrimRight(strings.TrimSpace(os.Getenv(UpstreamBaseURLEnv)), "/")
+	if raw == "" {
+		return DefaultBaseURL
+	}
+	return raw
+}
+
+// URL joins an upstream API path onto BaseURL.
+func URL(path string) string {
+	return BaseURL() + path
+}
+
+// ValidateBaseURL reports a malformed DS2API_UPSTREAM_BASE_URL.
+func ValidateBaseURL() error {
+	raw := BaseURL()
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%s: %w", UpstreamBaseURLEnv, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s must be an absolute http(s) URL, got %q", UpstreamBaseURLEnv, raw)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("%s must not contain a query or fragment, got %q", UpstreamBaseURLEnv, raw)
+	}
+	return nil
+}
+
 var defaultStaticBaseHeaders = map[string]string{
 	"Host":           "chat.deepseek.com",
 	"Accept":         "application/json",
@@ -54,3 +54,23 @@ func TestClientHeadersDerivedFromSharedVersion(t *testing.T) {
 		t.Fatalf("unexpected derived client version=%q", headers["x-client-version"])
 	}
 }
+
+func TestBaseURLFollowsEnv(t *testing.T) {
+	t.Setenv(UpstreamBaseURLEnv, "")
+	if got := URL(DeepSeekCompletionPath); got != "https://chat.deepseek.com/api/v0/chat/completion" {
+		t.Fatalf("unexpected default url=%q", got)
+	}
+	t.Setenv(UpstreamBaseURLEnv, " http://127.0.0.1:7777/ ")
+	if got := URL(DeepSeekContinuePath); got != "http://127.0.0.1:7777/api/v0/chat/continue" {
+		t.Fatalf("unexpected override url=%q", got)
+	}
+	if err := ValidateBaseURL(); err != nil {
+		t.Fatalf("expected valid base url, got %v", err)
+	}
+	for _, bad := range []string{"127.0.0.1:7777", "ftp://mock", "http://mock/?x=1"} {
+		t.Setenv(UpstreamBaseURLEnv, bad)
+		if err := ValidateBaseURL(); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// Package drain implements graceful drain mode: readiness fails, new work is
+// refused with 503, and shutdown waits for in-flight requests to finish.
+package drain
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ds2api/internal/inflight"
+)
+
+const (
+	// TimeoutEnv overrides DefaultTimeout, in seconds.
+	TimeoutEnv     = "DS2API_DRAIN_TIMEOUT_SECONDS"
+	DefaultTimeout = 5 * time.Minute
+	// RetryAfterSeconds is sent with 503 responses while draining.
+	RetryAfterSeconds = 30
+	pollInterval      = 200 * time.Millisecond
+)
+
+// ActiveCounter reports in-flight work, e.g. *account.Pool.
+type ActiveCounter interface {
+	InUse() int
+}
+
+type Controller struct {
+	active   ActiveCounter
+	requests ActiveCounter
+	timeout  time.Duration
+
+	mu        sync.Mutex
+	draining  bool
+	reason    string
+	startedAt time.Time
+	started   chan struct{}
+}
+
+// New builds a controller that waits for both held account slots (active)
+// and running API requests (requests, e.g. *inflight.Registry), which also
+// covers resumable streams generating on a direct token. Either may be nil.
+func New(active, requests ActiveCounter, timeout time.Duration) *Controller {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Controller{active: active, requests: requests, timeout: timeout, started: make(chan struct{})}
+}
+
+// TimeoutFromEnv reads TimeoutEnv, falling back to DefaultTimeout.
+func TimeoutFromEnv() time.Duration {
+	if raw := strings.TrimSpace(os.Getenv(TimeoutEnv)); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			return time.Duration(n) * time.Second
+		}
+	}
+	return DefaultTimeout
+}
+
+// Start enters drain mode. It reports false if a drain was already started.
+func (c *Controller) Start(reason string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.draining {
+		return false
+	}
+	c.draining = true
+	c.reason = reason
+	c.startedAt = time.Now()
+	close(c.started)
+	return true
+}
+
+func (c *Controller) Draining() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.draining
+}
+
+// Started is closed once drain mode begins.
+func (c *Controller) Started() <-chan struct{} {
+	return c.started
+}
+
+func (c *Controller) Timeout() time.Duration {
+	return c.timeout
+}
+
+// Wait blocks until no work is in flight, the drain deadline passes, or ctx
+// is done. It returns ctx's error or context.DeadlineExceeded on timeout.
+func (c *Controller) Wait(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for c.activeCount() > 0 || c.requestCount() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+func (c *Controller) activeCount() int {
+	if c.active == nil {
+		return 0
+	}
+	return c.active.InUse()
+}
+
+func (c *Controller) requestCount() int {
+	if c.requests == nil {
+		return 0
+	}
+	return c.requests.InUse()
+}
+
+func (c *Controller) Status() map[string]any {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	status := map[string]any{
+		"draining":        c.draining,
+		"active":          c.activeCount(),
+		"requests":        c.requestCount(),
+		"timeout_seconds": int(c.timeout / time.Second),
+	}
+	if c.draining {
+		status["reason"] = c.reason
+		status["started_at"] = c.startedAt
+		status["deadline"] = c.startedAt.Add(c.timeout)
+	}
+	return status
+}
+
+// Middleware refuses new completion requests while draining, so in-flight
+// streams finish but no new completions start. Cancels and token counts
+// still go through.
+func (c *Controller) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if inflight.IsCompletionRequest(r) && c.Draining() {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", strconv.Itoa(RetryAfterSeconds))
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{
+				"message": "server is draining, retry on another instance",
+				"type":    "service_unavailable",
+				"code":    "draining",
+			}})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
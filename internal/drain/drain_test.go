@@ -0,0 +1,92 @@
+package drain
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeActive struct{ n atomic.Int64 }
+
+func (f *fakeActive) InUse() int { return int(f.n.Load()) }
+
+func TestWaitReturnsOnceActiveWorkFinishes(t *testing.T) {
+	active := &fakeActive{}
+	active.n.Store(2)
+	c := New(active, nil, 5*time.Second)
+	if !c.Start("test") || c.Start("again") {
+		t.Fatal("expected only the first Start to begin draining")
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		active.n.Store(0)
+	}()
+	if err := c.Wait(context.Background()); err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+}
+
+func TestWaitCoversRequestsWithoutAccountSlots(t *testing.T) {
+	requests := &fakeActive{}
+	requests.n.Store(1)
+	c := New(&fakeActive{}, requests, time.Second)
+	c.Start("test")
+	if got := c.Status()["requests"]; got != 1 {
+		t.Fatalf("expected one running request in status, got %v", got)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		requests.n.Store(0)
+	}()
+	if err := c.Wait(context.Background()); err != nil || requests.InUse() != 0 {
+		t.Fatalf("expected wait to last until the request finished, err=%v", err)
+	}
+}
+
+func TestWaitStopsAtDeadline(t *testing.T) {
+	active := &fakeActive{}
+	active.n.Store(1)
+	c := New(active, nil, 300*time.Millisecond)
+	c.Start("test")
+	if err := c.Wait(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+}
+
+func TestMiddlewareRefusesNewWorkWhileDraining(t *testing.T) {
+	c := New(nil, nil, time.Second)
+	h := c.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) }))
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+	if rec := serve(http.MethodPost, "/v1/chat/completions"); rec.Code != http.StatusOK {
+		t.Fatalf("expected pass-through before drain, got %d", rec.Code)
+	}
+	c.Start("test")
+	rec := serve(http.MethodPost, "/v1/chat/completions")
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 503 with Retry-After while draining, got %d %v", rec.Code, rec.Header())
+	}
+	if rec := serve(http.MethodGet, "/v1/models"); rec.Code != http.StatusOK {
+		t.Fatalf("expected reads to keep working, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodPost, "/admin/drain"); rec.Code != http.StatusOK {
+		t.Fatalf("expected admin requests to keep working, got %d", rec.Code)
+	}
+	for _, path := range []string{"/v1/responses/resp_1/cancel", "/responses/resp_1/cancel", "/v1/responses/input_tokens", "/responses/input_tokens", "/v1/messages/count_tokens"} {
+		if rec := serve(http.MethodPost, path); rec.Code != http.StatusOK {
+			t.Fatalf("expected %s to keep working while draining, got %d", path, rec.Code)
+		}
+	}
+	for _, path := range []string{"/responses", "/anthropic/v1/messages", "/v1beta/models/gemini-pro:streamGenerateContent"} {
+		if rec := serve(http.MethodPost, path); rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("expected %s to be refused while draining, got %d", path, rec.Code)
+		}
+	}
+}
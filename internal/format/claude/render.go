@@ -7,17 +7,73 @@ import (
 	"time"
 
 	"ds2api/internal/prompt"
+	"ds2api/internal/sse"
 	"ds2api/internal/util"
 )
 
+// WebSearchBlocks renders the sources DeepSeek searched as the server tool
+// blocks Anthropic returns for its hosted web_search tool.
+func WebSearchBlocks(toolUseID string, citations []sse.Citation) []map[string]any {
+	if len(citations) == 0 {
+		return nil
+	}
+	results := make([]map[string]any, 0, len(citations))
+	for _, c := range citations {
+		results = append(results, map[string]any{
+			"type":              "web_search_result",
+			"url":               c.URL,
+			"title":             c.Title,
+			"encrypted_content": "",
+			"page_age":          nil,
+		})
+	}
+	return []map[string]any{
+		{
+			"type":  "server_tool_use",
+			"id":    toolUseID,
+			"name":  "web_search",
+			"input": map[string]any{},
+		},
+		{
+			"type":        "web_search_tool_result",
+			"tool_use_id": toolUseID,
+			"content":     results,
+		},
+	}
+}
+
+// TextCitations renders the cited spans of turn.Text as
+// web_search_result_location citations.
+func TextCitations(turn assistantturn.Turn) []map[string]any {
+	if len(turn.CitationSpans) == 0 {
+		return nil
+	}
+	out := make([]map[string]any, 0, len(turn.CitationSpans))
+	for _, span := range turn.CitationSpans {
+		citedText := ""
+		if span.Start >= 0 && span.Start <= span.End && span.End <= len(turn.Text) {
+			citedText = turn.Text[span.Start:span.End]
+		}
+		out = append(out, map[string]any{
+			"type":            "web_search_result_location",
+			"url":             span.Citation.URL,
+			"title":           span.Citation.Title,
+			"cited_text":      citedText,
+			"encrypted_index": "",
+		})
+	}
+	return out
+}
+
 func BuildMessageResponseFromTurn(messageID, model string, turn assistantturn.Turn, exposeThinking bool) map[string]any {
 	content := make([]map[string]any, 0, 4)
 	if exposeThinking && turn.Thinking != "" {
 		content = append(content, map[string]any{"type": "thinking", "thinking": turn.Thinking})
 	}
-	stopReason := "end_turn"
+	stopReason, stopSequence := LimitStopReason(turn, "end_turn")
 	if len(turn.ToolCalls) > 0 {
 		stopReason = "tool_use"
+		stopSequence = nil
 		for i, tc := range turn.ToolCalls {
 			content = append(content, map[string]any{
 				"type":  "tool_use",
@@ -34,7 +90,19 @@ func BuildMessageResponseFromTurn(messageID, model string, turn assistantturn.Tu
 		if text == "" {
 			text = "抱歉，没有生成有效的响应内容。"
 		}
-		content = append(content, map[string]any{"type": "text", "text": text})
+		content = append(content, WebSearchBlocks(fmt.Sprintf("srvtoolu_%d", time.Now().UnixNano()), turn.Citations)...)
+		block := map[string]any{"type": "text", "text": text}
+		if citations := TextCitations(turn); len(citations) > 0 {
+			block["citations"] = citations
+		}
+		content = append(content, block)
+	}
+	usage := map[string]any{
+		"input_tokens":  turn.Usage.InputTokens,
+		"output_tokens": turn.Usage.OutputTokens,
+	}
+	if serverToolUse := ServerToolUsage(turn); serverToolUse != nil {
+		usage["server_tool_use"] = serverToolUse
 	}
 	return map[string]any{
 		"id":            messageID,
@@ -43,12 +111,31 @@ func BuildMessageResponseFromTurn(messageID, model string, turn assistantturn.Tu
 		"model":         model,
 		"content":       content,
 		"stop_reason":   stopReason,
-		"stop_sequence": nil,
-		"usage": map[string]any{
-			"input_tokens":  turn.Usage.InputTokens,
-			"output_tokens": turn.Usage.OutputTokens,
-		},
+		"stop_sequence": stopSequence,
+		"usage":         usage,
+	}
+}
+
+// LimitStopReason maps a locally enforced output limit to Claude's
+// stop_reason and stop_sequence, falling back to fallback otherwise.
+func LimitStopReason(turn assistantturn.Turn, fallback string) (string, any) {
+	switch turn.LimitReason {
+	case sse.LimitStopSequence:
+		return "stop_sequence", turn.StopSequence
+	case sse.LimitMaxTokens:
+		return "max_tokens", nil
+	default:
+		return fallback, nil
+	}
+}
+
+// ServerToolUsage reports the hosted web_search call made on behalf of the
+// turn, or nil when no search results were returned.
+func ServerToolUsage(turn assistantturn.Turn) map[string]any {
+	if len(turn.Citations) == 0 || len(turn.ToolCalls) > 0 {
+		return nil
 	}
+	return map[string]any{"web_search_requests": 1}
 }
 
 func BuildMessageResponse(messageID, model string, normalizedMessages []any, finalThinking, finalText string, toolNames []string) map[string]any {
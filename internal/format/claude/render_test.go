@@ -1,6 +1,12 @@
 package claude
 
-import "testing"
+import (
+	"testing"
+
+	"ds2api/internal/assistantturn"
+	"ds2api/internal/httpapi/openai/shared"
+	"ds2api/internal/sse"
+)
 
 func TestBuildMessageResponseSkipsThinkingFallbackWhenFinalTextExists(t *testing.T) {
 	resp := BuildMessageResponse(
@@ -34,3 +40,27 @@ func TestBuildMessageResponseSkipsThinkingFallbackWhenFinalTextExists(t *testing
 		t.Fatalf("unexpected tool_use block when finalText exists, got=%#v", resp["content"])
 	}
 }
+
+func TestBuildMessageResponseFromTurnRendersWebSearchCitations(t *testing.T) {
+	citation := sse.Citation{Index: 1, URL: "https://example.com/p", Title: "Paris"}
+	turn := assistantturn.Turn{
+		Text:          "Paris is big.",
+		Citations:     []sse.Citation{citation},
+		CitationSpans: []shared.CitationSpan{{Citation: citation, Start: 0, End: len("Paris is big.")}},
+	}
+	resp := BuildMessageResponseFromTurn("msg_1", "claude-sonnet-4-5", turn, false)
+	content, _ := resp["content"].([]map[string]any)
+	if len(content) != 3 {
+		t.Fatalf("expected search blocks and text, got %#v", content)
+	}
+	if content[0]["type"] != "server_tool_use" || content[1]["type"] != "web_search_tool_result" {
+		t.Fatalf("unexpected search blocks: %#v", content[:2])
+	}
+	if content[1]["tool_use_id"] != content[0]["id"] {
+		t.Fatalf("expected tool result to reference server tool use")
+	}
+	citations, _ := content[2]["citations"].([]map[string]any)
+	if len(citations) != 1 || citations[0]["cited_text"] != "Paris is big." || citations[0]["type"] != "web_search_result_location" {
+		t.Fatalf("unexpected text citations: %#v", content[2]["citations"])
+	}
+}
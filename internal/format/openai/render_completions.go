@@ -0,0 +1,41 @@
+package openai
+
+import "time"
+
+func BuildTextCompletionChoice(index int, text, finishReason string) map[string]any {
+	choice := map[string]any{
+		"text":          text,
+		"index":         index,
+		"logprobs":      nil,
+		"finish_reason": nil,
+	}
+	if finishReason != "" {
+		choice["finish_reason"] = finishReason
+	}
+	return choice
+}
+
+func BuildTextCompletion(completionID, model string, choices []map[string]any, usage map[string]any) map[string]any {
+	return map[string]any{
+		"id":      completionID,
+		"object":  "text_completion",
+		"created": time.Now().Unix(),
+		"model":   model,
+		"choices": choices,
+		"usage":   usage,
+	}
+}
+
+func BuildTextCompletionChunk(completionID string, created int64, model string, choices []map[string]any, usage map[string]any) map[string]any {
+	out := map[string]any{
+		"id":      completionID,
+		"object":  "text_completion",
+		"created": created,
+		"model":   model,
+		"choices": choices,
+	}
+	if len(usage) > 0 {
+		out["usage"] = usage
+	}
+	return out
+}
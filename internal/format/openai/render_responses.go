@@ -1,6 +1,7 @@
 package openai
 
 import (
+	"ds2api/internal/sse"
 	"ds2api/internal/toolcall"
 	"encoding/json"
 	"strings"
@@ -74,6 +75,25 @@ func BuildResponseObjectFromItems(responseID, model, finalPrompt, finalThinking,
 	}
 }
 
+// BuildResponsesWebSearchCallItem renders DeepSeek's native search as the
+// web_search_call output item of the hosted web_search tool.
+func BuildResponsesWebSearchCallItem(itemID, status string, citations []sse.Citation) map[string]any {
+	action := map[string]any{"type": "search"}
+	if len(citations) > 0 {
+		sources := make([]map[string]any, 0, len(citations))
+		for _, c := range citations {
+			sources = append(sources, map[string]any{"type": "url", "url": c.URL})
+		}
+		action["sources"] = sources
+	}
+	return map[string]any{
+		"id":     itemID,
+		"type":   "web_search_call",
+		"status": status,
+		"action": action,
+	}
+}
+
 func toResponsesFunctionCallItems(toolCalls []toolcall.ParsedToolCall, toolsRaw any) []any {
 	if len(toolCalls) == 0 {
 		return nil
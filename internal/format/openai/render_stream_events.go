@@ -83,6 +83,19 @@ func BuildResponsesTextDonePayload(responseID, itemID string, outputIndex, conte
 	}
 }
 
+func BuildResponsesTextAnnotationAddedPayload(responseID, itemID string, outputIndex, contentIndex, annotationIndex int, annotation map[string]any) map[string]any {
+	return map[string]any{
+		"type":             "response.output_text.annotation.added",
+		"id":               responseID,
+		"response_id":      responseID,
+		"item_id":          itemID,
+		"output_index":     outputIndex,
+		"content_index":    contentIndex,
+		"annotation_index": annotationIndex,
+		"annotation":       annotation,
+	}
+}
+
 func BuildResponsesReasoningDeltaPayload(responseID, delta string) map[string]any {
 	return map[string]any{
 		"type":        "response.reasoning.delta",
@@ -167,3 +180,25 @@ func BuildResponsesCompletedPayload(response map[string]any) map[string]any {
 		"response":    response,
 	}
 }
+
+// BuildResponsesIncompletePayload is the terminal event for a response cut
+// off by max_output_tokens.
+func BuildResponsesIncompletePayload(response map[string]any) map[string]any {
+	responseID, _ := response["id"].(string)
+	return map[string]any{
+		"type":        "response.incomplete",
+		"response_id": responseID,
+		"response":    response,
+	}
+}
+
+// BuildResponsesCancelledPayload is the terminal event for a background
+// response cancelled before it produced any output.
+func BuildResponsesCancelledPayload(response map[string]any) map[string]any {
+	responseID, _ := response["id"].(string)
+	return map[string]any{
+		"type":        "response.cancelled",
+		"response_id": responseID,
+		"response":    response,
+	}
+}
@@ -9,11 +9,12 @@ import (
 )
 
 type Handler struct {
-	Store       adminshared.ConfigStore
-	Pool        adminshared.PoolController
-	DS          adminshared.DeepSeekCaller
-	OpenAI      adminshared.OpenAIChatCaller
-	ChatHistory *chathistory.Store
+	Store          adminshared.ConfigStore
+	Pool           adminshared.PoolController
+	DS             adminshared.DeepSeekCaller
+	OpenAI         adminshared.OpenAIChatCaller
+	ChatHistory    *chathistory.Store
+	SessionCleanup adminshared.SessionCleaner
 }
 
 var writeJSON = adminshared.WriteJSON
@@ -1,7 +1,15 @@
 package accounts
 
-import "net/http"
+import (
+	"net/http"
+
+	adminshared "ds2api/internal/httpapi/admin/shared"
+)
 
 func (h *Handler) queueStatus(w http.ResponseWriter, _ *http.Request) {
-	writeJSON(w, http.StatusOK, h.Pool.Status())
+	status := h.Pool.Status()
+	if reporter, ok := h.DS.(adminshared.PrefetchReporter); ok {
+		status["prefetch"] = reporter.PrefetchStatus()
+	}
+	writeJSON(w, http.StatusOK, status)
 }
@@ -41,11 +41,18 @@ func (m *testingDSMock) GetPow(_ context.Context, _ *auth.RequestAuth, _ int) (s
 	return "", errors.New("should not call GetPow in this test")
 }
 
+func (m *testingDSMock) UploadFile(_ context.Context, _ *auth.RequestAuth, _ dsclient.UploadFileRequest, _ int) (*dsclient.UploadFileResult, error) {
+	return &dsclient.UploadFileResult{ID: "file-id"}, nil
+}
+
 func (m *testingDSMock) CallCompletion(_ context.Context, _ *auth.RequestAuth, _ map[string]any, _ string, _ int) (*http.Response, error) {
 	m.callCompletionCalls++
 	return nil, errors.New("should not call CallCompletion in this test")
 }
 
+func (m *testingDSMock) DeleteSessionForToken(_ context.Context, _ string, _ string) (*dsclient.DeleteSessionResult, error) {
+	return &dsclient.DeleteSessionResult{Success: true}, nil
+}
 func (m *testingDSMock) DeleteAllSessionsForToken(_ context.Context, _ string) error {
 	m.deleteAllSessionsCalls++
 	if m.deleteAllSessionsError != nil {
@@ -151,6 +158,10 @@ func (m *completionPayloadDSMock) GetPow(_ context.Context, _ *auth.RequestAuth,
 	return "pow-ok", nil
 }
 
+func (m *completionPayloadDSMock) UploadFile(_ context.Context, _ *auth.RequestAuth, _ dsclient.UploadFileRequest, _ int) (*dsclient.UploadFileResult, error) {
+	return &dsclient.UploadFileResult{ID: "file-id"}, nil
+}
+
 func (m *completionPayloadDSMock) CallCompletion(_ context.Context, _ *auth.RequestAuth, payload map[string]any, _ string, _ int) (*http.Response, error) {
 	m.payload = payload
 	return &http.Response{
@@ -159,6 +170,9 @@ func (m *completionPayloadDSMock) CallCompletion(_ context.Context, _ *auth.Requ
 	}, nil
 }
 
+func (m *completionPayloadDSMock) DeleteSessionForToken(_ context.Context, _ string, _ string) (*dsclient.DeleteSessionResult, error) {
+	return &dsclient.DeleteSessionResult{Success: true}, nil
+}
 func (m *completionPayloadDSMock) DeleteAllSessionsForToken(_ context.Context, _ string) error {
 	return nil
 }
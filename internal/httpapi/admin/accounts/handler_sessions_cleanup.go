@@ -0,0 +1,32 @@
+package accounts
+
+import (
+	"errors"
+	"net/http"
+
+	"ds2api/internal/sessioncleanup"
+)
+
+func (h *Handler) sessionCleanupStatus(w http.ResponseWriter, _ *http.Request) {
+	if h.SessionCleanup == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{"detail": "session cleanup is not available"})
+		return
+	}
+	writeJSON(w, http.StatusOK, h.SessionCleanup.Status())
+}
+
+func (h *Handler) runSessionCleanup(w http.ResponseWriter, _ *http.Request) {
+	if h.SessionCleanup == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{"detail": "session cleanup is not available"})
+		return
+	}
+	if err := h.SessionCleanup.Trigger(); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, sessioncleanup.ErrRunning) {
+			status = http.StatusConflict
+		}
+		writeJSON(w, status, map[string]any{"detail": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]any{"success": true, "message": "session cleanup started"})
+}
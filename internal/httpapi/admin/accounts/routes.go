@@ -18,6 +18,8 @@ func RegisterRoutes(r chi.Router, h *Handler) {
 	r.Post("/accounts/test", h.testSingleAccount)
 	r.Post("/accounts/test-all", h.testAllAccounts)
 	r.Post("/accounts/sessions/delete-all", h.deleteAllSessions)
+	r.Get("/accounts/sessions/cleanup", h.sessionCleanupStatus)
+	r.Post("/accounts/sessions/cleanup/run", h.runSessionCleanup)
 	r.Post("/test", h.testAPI)
 }
 
@@ -0,0 +1,142 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"ds2api/internal/account"
+	"ds2api/internal/config"
+)
+
+func newMountedAdminRouter(t *testing.T, rawConfig string) http.Handler {
+	t.Helper()
+	t.Setenv("DS2API_CONFIG_JSON", rawConfig)
+	t.Setenv("DS2API_ENV_WRITEBACK", "0")
+	store := config.LoadStore()
+	h := &Handler{Store: store, Pool: account.NewPool(store), DS: &testingDSMock{}}
+	r := chi.NewRouter()
+	r.Route("/admin", func(ar chi.Router) {
+		RegisterRoutes(ar, h)
+	})
+	return r
+}
+
+func doAdminJSON(t *testing.T, router http.Handler, method, path, bearer string, body any) (int, map[string]any) {
+	t.Helper()
+	var reader *bytes.Reader
+	if body != nil {
+		b, _ := json.Marshal(body)
+		reader = bytes.NewReader(b)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	var payload map[string]any
+	_ = json.Unmarshal(rec.Body.Bytes(), &payload)
+	return rec.Code, payload
+}
+
+func TestAdminRolesEnforcedPerRouteGroup(t *testing.T) {
+	router := newMountedAdminRouter(t, `{"keys":["k1"],"accounts":[{"email":"u@example.com","password":"p"}]}`)
+
+	code, body := doAdminJSON(t, router, http.MethodPost, "/admin/users", "admin", map[string]any{"username": "Alice", "password": "viewer-pass", "role": "viewer"})
+	if code != http.StatusOK {
+		t.Fatalf("create viewer failed: %d %#v", code, body)
+	}
+	code, body = doAdminJSON(t, router, http.MethodPost, "/admin/login", "", map[string]any{"username": "alice", "password": "viewer-pass"})
+	if code != http.StatusOK || body["role"] != "viewer" {
+		t.Fatalf("viewer login failed: %d %#v", code, body)
+	}
+	viewerJWT, _ := body["token"].(string)
+
+	if code, body = doAdminJSON(t, router, http.MethodGet, "/admin/queue/status", viewerJWT, nil); code != http.StatusOK {
+		t.Fatalf("viewer should read queue status, got %d %#v", code, body)
+	}
+	if code, _ = doAdminJSON(t, router, http.MethodGet, "/admin/config", viewerJWT, nil); code != http.StatusForbidden {
+		t.Fatalf("viewer must not read config, got %d", code)
+	}
+	if code, _ = doAdminJSON(t, router, http.MethodDelete, "/admin/dev/captures", viewerJWT, nil); code != http.StatusForbidden {
+		t.Fatalf("viewer must not clear captures, got %d", code)
+	}
+	if code, body = doAdminJSON(t, router, http.MethodGet, "/admin/me", viewerJWT, nil); code != http.StatusOK || body["username"] != "alice" {
+		t.Fatalf("unexpected /me response: %d %#v", code, body)
+	}
+
+	code, body = doAdminJSON(t, router, http.MethodPost, "/admin/tokens", "admin", map[string]any{"name": "ci", "role": "operator"})
+	if code != http.StatusOK {
+		t.Fatalf("create token failed: %d %#v", code, body)
+	}
+	tokenView, _ := body["token"].(map[string]any)
+	operatorToken, _ := tokenView["token"].(string)
+	if operatorToken == "" {
+		t.Fatalf("expected plaintext token once: %#v", body)
+	}
+	if code, _ = doAdminJSON(t, router, http.MethodDelete, "/admin/dev/captures", operatorToken, nil); code != http.StatusOK {
+		t.Fatalf("operator should clear captures, got %d", code)
+	}
+	if code, _ = doAdminJSON(t, router, http.MethodPost, "/admin/proxies/test", operatorToken, map[string]any{}); code == http.StatusForbidden {
+		t.Fatal("operator override for proxy test was not applied")
+	}
+	if code, _ = doAdminJSON(t, router, http.MethodPost, "/admin/proxies", operatorToken, map[string]any{}); code != http.StatusForbidden {
+		t.Fatalf("operator must not add proxies, got %d", code)
+	}
+	if code, _ = doAdminJSON(t, router, http.MethodGet, "/admin/tokens", operatorToken, nil); code != http.StatusForbidden {
+		t.Fatalf("operator must not list admin tokens, got %d", code)
+	}
+	code, body = doAdminJSON(t, router, http.MethodGet, "/admin/tokens", "admin", nil)
+	if code != http.StatusOK {
+		t.Fatalf("list tokens failed: %d", code)
+	}
+	items, _ := body["items"].([]any)
+	if len(items) != 1 {
+		t.Fatalf("expected one token, got %#v", body)
+	}
+	if first, _ := items[0].(map[string]any); first["token"] != nil {
+		t.Fatalf("token list leaked plaintext: %#v", first)
+	}
+}
+
+func TestAdminUserDisableRevokesSessions(t *testing.T) {
+	router := newMountedAdminRouter(t, `{"keys":["k1"]}`)
+	if code, body := doAdminJSON(t, router, http.MethodPost, "/admin/users", "admin", map[string]any{"username": "bob", "password": "operator-pass", "role": "operator"}); code != http.StatusOK {
+		t.Fatalf("create user failed: %d %#v", code, body)
+	}
+	_, body := doAdminJSON(t, router, http.MethodPost, "/admin/login", "", map[string]any{"username": "bob", "password": "operator-pass"})
+	jwt, _ := body["token"].(string)
+	if code, _ := doAdminJSON(t, router, http.MethodGet, "/admin/verify", jwt, nil); code != http.StatusOK {
+		t.Fatalf("expected fresh jwt to verify, got %d", code)
+	}
+	if code, body := doAdminJSON(t, router, http.MethodPut, "/admin/users/bob", "admin", map[string]any{"disabled": true}); code != http.StatusOK {
+		t.Fatalf("disable user failed: %d %#v", code, body)
+	}
+	if code, _ := doAdminJSON(t, router, http.MethodGet, "/admin/queue/status", jwt, nil); code != http.StatusUnauthorized {
+		t.Fatalf("expected disabled user jwt to be rejected, got %d", code)
+	}
+	if code, _ := doAdminJSON(t, router, http.MethodPost, "/admin/login", "", map[string]any{"username": "bob", "password": "operator-pass"}); code != http.StatusUnauthorized {
+		t.Fatalf("expected disabled user login to fail, got %d", code)
+	}
+}
+
+func TestAdminUserReservedAndDuplicateNames(t *testing.T) {
+	router := newMountedAdminRouter(t, `{"keys":["k1"]}`)
+	if code, _ := doAdminJSON(t, router, http.MethodPost, "/admin/users", "admin", map[string]any{"username": "admin", "password": "long-enough", "role": "owner"}); code != http.StatusBadRequest {
+		t.Fatalf("expected reserved username rejection, got %d", code)
+	}
+	if code, _ := doAdminJSON(t, router, http.MethodPost, "/admin/users", "admin", map[string]any{"username": "carol", "password": "long-enough", "role": "owner"}); code != http.StatusOK {
+		t.Fatalf("create user failed: %d", code)
+	}
+	if code, _ := doAdminJSON(t, router, http.MethodPost, "/admin/users", "admin", map[string]any{"username": "CAROL", "password": "long-enough", "role": "viewer"}); code != http.StatusConflict {
+		t.Fatalf("expected duplicate rejection, got %d", code)
+	}
+}
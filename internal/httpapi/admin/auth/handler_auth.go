@@ -12,11 +12,12 @@ import (
 
 func (h *Handler) requireAdmin(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if err := authn.VerifyAdminRequestWithStore(r, h.Store); err != nil {
+		principal, err := authn.AuthenticateAdminRequest(r, h.Store)
+		if err != nil {
 			writeJSON(w, http.StatusUnauthorized, map[string]any{"detail": err.Error()})
 			return
 		}
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(authn.WithAdminPrincipal(r.Context(), principal)))
 	})
 }
 
@@ -24,12 +25,25 @@ func (h *Handler) login(w http.ResponseWriter, r *http.Request) {
 	var req map[string]any
 	_ = json.NewDecoder(r.Body).Decode(&req)
 	adminKey, _ := req["admin_key"].(string)
+	username, _ := req["username"].(string)
+	password, _ := req["password"].(string)
 	expireHours := intFrom(req["expire_hours"])
-	if !authn.VerifyAdminCredential(adminKey, h.Store) {
-		writeJSON(w, http.StatusUnauthorized, map[string]any{"detail": "Invalid admin key"})
-		return
+	var principal authn.AdminPrincipal
+	if strings.TrimSpace(username) != "" {
+		p, ok := authn.AuthenticateAdminUser(username, password, h.Store)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"detail": "Invalid username or password"})
+			return
+		}
+		principal = p
+	} else {
+		if !authn.VerifyAdminCredential(adminKey, h.Store) {
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"detail": "Invalid admin key"})
+			return
+		}
+		principal = authn.LegacyAdminPrincipal("admin_key")
 	}
-	token, err := authn.CreateJWTWithStore(expireHours, h.Store)
+	token, err := authn.CreateAdminJWT(principal, expireHours, h.Store)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]any{"detail": err.Error()})
 		return
@@ -37,7 +51,13 @@ func (h *Handler) login(w http.ResponseWriter, r *http.Request) {
 	if expireHours <= 0 {
 		expireHours = h.Store.AdminJWTExpireHours()
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"success": true, "token": token, "expires_in": expireHours * 3600})
+	writeJSON(w, http.StatusOK, map[string]any{
+		"success":    true,
+		"token":      token,
+		"expires_in": expireHours * 3600,
+		"username":   principal.Subject,
+		"role":       principal.Role,
+	})
 }
 
 func (h *Handler) verify(w http.ResponseWriter, r *http.Request) {
@@ -52,12 +72,32 @@ func (h *Handler) verify(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusUnauthorized, map[string]any{"detail": err.Error()})
 		return
 	}
+	principal, err := authn.AdminPrincipalFromJWT(payload, h.Store)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"detail": err.Error()})
+		return
+	}
 	exp, _ := payload["exp"].(float64)
 	remaining := int64(exp) - time.Now().Unix()
 	if remaining < 0 {
 		remaining = 0
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"valid": true, "expires_at": int64(exp), "remaining_seconds": remaining})
+	writeJSON(w, http.StatusOK, map[string]any{
+		"valid":             true,
+		"expires_at":        int64(exp),
+		"remaining_seconds": remaining,
+		"username":          principal.Subject,
+		"role":              principal.Role,
+	})
+}
+
+func (h *Handler) me(w http.ResponseWriter, r *http.Request) {
+	principal, ok := authn.AdminPrincipalFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"detail": "authentication required"})
+		return
+	}
+	writeJSON(w, http.StatusOK, principal)
 }
 
 func (h *Handler) getVercelConfig(w http.ResponseWriter, _ *http.Request) {
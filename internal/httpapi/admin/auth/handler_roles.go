@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	authn "ds2api/internal/auth"
+)
+
+// RolePolicy decides the minimum admin role for a matched route. Read covers
+// GET/HEAD, Write covers everything else, and Overrides pins individual
+// routes keyed by "METHOD /pattern" relative to the admin router.
+type RolePolicy struct {
+	Read      string
+	Write     string
+	Overrides map[string]string
+}
+
+func (p RolePolicy) required(r *http.Request) string {
+	if len(p.Overrides) > 0 {
+		if role, ok := p.Overrides[r.Method+" "+adminRoutePattern(r)]; ok {
+			return role
+		}
+	}
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return p.Read
+	}
+	return p.Write
+}
+
+// adminRoutePattern returns the matched pattern relative to the router that
+// admin.RegisterRoutes was mounted on.
+func adminRoutePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil || len(rctx.RoutePatterns) == 0 {
+		return r.URL.Path
+	}
+	return rctx.RoutePatterns[len(rctx.RoutePatterns)-1]
+}
+
+func (h *Handler) requireRole(policy RolePolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := authn.AdminPrincipalFromContext(r.Context())
+			if !ok {
+				writeJSON(w, http.StatusUnauthorized, map[string]any{"detail": "authentication required"})
+				return
+			}
+			need := policy.required(r)
+			if !authn.AdminRoleAllows(principal.Role, need) {
+				writeJSON(w, http.StatusForbidden, map[string]any{
+					"detail":        "insufficient admin role",
+					"role":          principal.Role,
+					"required_role": need,
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -10,11 +10,18 @@ func (h *Handler) RequireAdmin(next http.Handler) http.Handler {
 	return h.requireAdmin(next)
 }
 
+// RequireRole must run behind RequireAdmin, which places the caller's
+// principal on the request context.
+func (h *Handler) RequireRole(policy RolePolicy) func(http.Handler) http.Handler {
+	return h.requireRole(policy)
+}
+
 func RegisterPublicRoutes(r chi.Router, h *Handler) {
 	r.Post("/login", h.login)
 	r.Get("/verify", h.verify)
 }
 
 func RegisterProtectedRoutes(r chi.Router, h *Handler) {
+	r.Get("/me", h.me)
 	r.Get("/vercel/config", h.getVercelConfig)
 }
@@ -40,6 +40,9 @@ func accountDedupeKey(acc config.Account) string { return adminshared.AccountDed
 func normalizeAndDedupeAccounts(accounts []config.Account) []config.Account {
 	return adminshared.NormalizeAndDedupeAccounts(accounts)
 }
+
+var intFrom = adminshared.IntFrom
+
 func newRequestError(detail string) error { return adminshared.NewRequestError(detail) }
 func requestErrorDetail(err error) (string, bool) {
 	return adminshared.RequestErrorDetail(err)
@@ -119,6 +119,12 @@ func (h *Handler) configImport(w http.ResponseWriter, r *http.Request) {
 			if incoming.Runtime.TokenRefreshIntervalHours > 0 {
 				next.Runtime.TokenRefreshIntervalHours = incoming.Runtime.TokenRefreshIntervalHours
 			}
+			if incoming.Runtime.MaxChoices > 0 {
+				next.Runtime.MaxChoices = incoming.Runtime.MaxChoices
+			}
+			if incoming.Runtime.ChoiceAccountPolicy != "" {
+				next.Runtime.ChoiceAccountPolicy = incoming.Runtime.ChoiceAccountPolicy
+			}
 		}
 
 		normalizeSettingsConfig(&next)
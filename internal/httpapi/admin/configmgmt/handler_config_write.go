@@ -82,6 +82,8 @@ func (h *Handler) addKey(w http.ResponseWriter, r *http.Request) {
 	key = strings.TrimSpace(key)
 	name := fieldString(req, "name")
 	remark := fieldString(req, "remark")
+	priority := fieldString(req, "priority")
+	weight := intFrom(req["weight"])
 	if key == "" {
 		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": "Key 不能为空"})
 		return
@@ -92,8 +94,8 @@ func (h *Handler) addKey(w http.ResponseWriter, r *http.Request) {
 				return fmt.Errorf("key 已存在")
 			}
 		}
-		c.APIKeys = append(c.APIKeys, config.APIKey{Key: key, Name: name, Remark: remark})
-		return nil
+		c.APIKeys = append(c.APIKeys, config.APIKey{Key: key, Name: name, Remark: remark, Priority: priority, Weight: weight})
+		return config.ValidateSchedulingConfig(c.Scheduling, c.APIKeys)
 	})
 	if err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": err.Error()})
@@ -116,6 +118,8 @@ func (h *Handler) updateKey(w http.ResponseWriter, r *http.Request) {
 	}
 	name, nameOK := fieldStringOptional(req, "name")
 	remark, remarkOK := fieldStringOptional(req, "remark")
+	priority, priorityOK := fieldStringOptional(req, "priority")
+	_, weightOK := req["weight"]
 
 	err := h.Store.Update(func(c *config.Config) error {
 		idx := -1
@@ -134,8 +138,21 @@ func (h *Handler) updateKey(w http.ResponseWriter, r *http.Request) {
 		if remarkOK {
 			c.APIKeys[idx].Remark = remark
 		}
+		if priorityOK {
+			c.APIKeys[idx].Priority = priority
+		}
+		if weightOK {
+			c.APIKeys[idx].Weight = intFrom(req["weight"])
+		}
+		if err := config.ValidateSchedulingConfig(c.Scheduling, c.APIKeys); err != nil {
+			return newRequestError(err.Error())
+		}
 		return nil
 	})
+	if detail, ok := requestErrorDetail(err); ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": detail})
+		return
+	}
 	if err != nil {
 		writeJSON(w, http.StatusNotFound, map[string]any{"detail": err.Error()})
 		return
@@ -0,0 +1,35 @@
+package drain
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+func (h *Handler) getStatus(w http.ResponseWriter, _ *http.Request) {
+	if h.Drain == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{"detail": "drain is not available"})
+		return
+	}
+	writeJSON(w, http.StatusOK, h.Drain.Status())
+}
+
+// startDrain puts the instance into drain mode. The process exits once
+// in-flight requests finish or the drain deadline passes.
+func (h *Handler) startDrain(w http.ResponseWriter, r *http.Request) {
+	if h.Drain == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{"detail": "drain is not available"})
+		return
+	}
+	var req map[string]any
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	reason, _ := req["reason"].(string)
+	if reason = strings.TrimSpace(reason); reason == "" {
+		reason = "admin"
+	}
+	started := h.Drain.Start(reason)
+	status := h.Drain.Status()
+	status["success"] = true
+	status["started"] = started
+	writeJSON(w, http.StatusAccepted, status)
+}
@@ -0,0 +1,8 @@
+package drain
+
+import "github.com/go-chi/chi/v5"
+
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Get("/drain", h.getStatus)
+	r.Post("/drain", h.startDrain)
+}
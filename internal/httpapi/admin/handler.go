@@ -4,31 +4,40 @@ import (
 	"github.com/go-chi/chi/v5"
 
 	"ds2api/internal/chathistory"
+	"ds2api/internal/config"
 	adminaccounts "ds2api/internal/httpapi/admin/accounts"
 	adminauth "ds2api/internal/httpapi/admin/auth"
 	adminconfig "ds2api/internal/httpapi/admin/configmgmt"
 	admindevcapture "ds2api/internal/httpapi/admin/devcapture"
+	admindrain "ds2api/internal/httpapi/admin/drain"
 	adminhistory "ds2api/internal/httpapi/admin/history"
+	adminoidc "ds2api/internal/httpapi/admin/oidc"
+	adminpow "ds2api/internal/httpapi/admin/pow"
 	adminproxies "ds2api/internal/httpapi/admin/proxies"
 	adminrawsamples "ds2api/internal/httpapi/admin/rawsamples"
+	adminrequests "ds2api/internal/httpapi/admin/requests"
 	adminsettings "ds2api/internal/httpapi/admin/settings"
 	adminshared "ds2api/internal/httpapi/admin/shared"
+	adminusers "ds2api/internal/httpapi/admin/users"
 	adminvercel "ds2api/internal/httpapi/admin/vercel"
 	adminversion "ds2api/internal/httpapi/admin/version"
 )
 
 type Handler struct {
-	Store       adminshared.ConfigStore
-	Pool        adminshared.PoolController
-	DS          adminshared.DeepSeekCaller
-	OpenAI      adminshared.OpenAIChatCaller
-	ChatHistory *chathistory.Store
+	Store          adminshared.ConfigStore
+	Pool           adminshared.PoolController
+	DS             adminshared.DeepSeekCaller
+	OpenAI         adminshared.OpenAIChatCaller
+	ChatHistory    *chathistory.Store
+	SessionCleanup adminshared.SessionCleaner
+	Drain          adminshared.DrainController
+	Requests       adminshared.RequestInspector
 }
 
 func RegisterRoutes(r chi.Router, h *Handler) {
 	deps := adminsharedDeps(h)
 	authHandler := &adminauth.Handler{Store: deps.Store, Pool: deps.Pool, DS: deps.DS, OpenAI: deps.OpenAI, ChatHistory: deps.ChatHistory}
-	accountsHandler := &adminaccounts.Handler{Store: deps.Store, Pool: deps.Pool, DS: deps.DS, OpenAI: deps.OpenAI, ChatHistory: deps.ChatHistory}
+	accountsHandler := &adminaccounts.Handler{Store: deps.Store, Pool: deps.Pool, DS: deps.DS, OpenAI: deps.OpenAI, ChatHistory: deps.ChatHistory, SessionCleanup: deps.SessionCleanup}
 	configHandler := &adminconfig.Handler{Store: deps.Store, Pool: deps.Pool, DS: deps.DS, OpenAI: deps.OpenAI, ChatHistory: deps.ChatHistory}
 	settingsHandler := &adminsettings.Handler{Store: deps.Store, Pool: deps.Pool, DS: deps.DS, OpenAI: deps.OpenAI, ChatHistory: deps.ChatHistory}
 	proxiesHandler := &adminproxies.Handler{Store: deps.Store, Pool: deps.Pool, DS: deps.DS, OpenAI: deps.OpenAI, ChatHistory: deps.ChatHistory}
@@ -37,28 +46,84 @@ func RegisterRoutes(r chi.Router, h *Handler) {
 	historyHandler := &adminhistory.Handler{Store: deps.Store, Pool: deps.Pool, DS: deps.DS, OpenAI: deps.OpenAI, ChatHistory: deps.ChatHistory}
 	devCaptureHandler := &admindevcapture.Handler{Store: deps.Store, Pool: deps.Pool, DS: deps.DS, OpenAI: deps.OpenAI, ChatHistory: deps.ChatHistory}
 	versionHandler := &adminversion.Handler{Store: deps.Store, Pool: deps.Pool, DS: deps.DS, OpenAI: deps.OpenAI, ChatHistory: deps.ChatHistory}
+	usersHandler := &adminusers.Handler{Store: deps.Store, Pool: deps.Pool, DS: deps.DS, OpenAI: deps.OpenAI, ChatHistory: deps.ChatHistory}
+	oidcHandler := &adminoidc.Handler{Store: deps.Store, Pool: deps.Pool, DS: deps.DS, OpenAI: deps.OpenAI, ChatHistory: deps.ChatHistory}
+	powHandler := &adminpow.Handler{Store: deps.Store, Pool: deps.Pool, DS: deps.DS, OpenAI: deps.OpenAI, ChatHistory: deps.ChatHistory}
+	drainHandler := &admindrain.Handler{Store: deps.Store, Pool: deps.Pool, DS: deps.DS, OpenAI: deps.OpenAI, ChatHistory: deps.ChatHistory, Drain: deps.Drain}
+	requestsHandler := &adminrequests.Handler{Store: deps.Store, Pool: deps.Pool, DS: deps.DS, OpenAI: deps.OpenAI, ChatHistory: deps.ChatHistory, Requests: deps.Requests}
 
 	adminauth.RegisterPublicRoutes(r, authHandler)
+	adminoidc.RegisterPublicRoutes(r, oidcHandler)
 	r.Group(func(pr chi.Router) {
 		pr.Use(authHandler.RequireAdmin)
-		adminauth.RegisterProtectedRoutes(pr, authHandler)
-		adminconfig.RegisterRoutes(pr, configHandler)
-		adminsettings.RegisterRoutes(pr, settingsHandler)
-		adminproxies.RegisterRoutes(pr, proxiesHandler)
-		adminaccounts.RegisterRoutes(pr, accountsHandler)
-		adminrawsamples.RegisterRoutes(pr, rawSamplesHandler)
-		adminvercel.RegisterRoutes(pr, vercelHandler)
-		admindevcapture.RegisterRoutes(pr, devCaptureHandler)
-		adminhistory.RegisterRoutes(pr, historyHandler)
-		adminversion.RegisterRoutes(pr, versionHandler)
+		withRole := func(policy adminauth.RolePolicy, register func(chi.Router)) {
+			pr.Group(func(gr chi.Router) {
+				gr.Use(authHandler.RequireRole(policy))
+				register(gr)
+			})
+		}
+		withRole(authRoles, func(gr chi.Router) { adminauth.RegisterProtectedRoutes(gr, authHandler) })
+		withRole(ownerOnly, func(gr chi.Router) { adminconfig.RegisterRoutes(gr, configHandler) })
+		withRole(readViewerWriteOwner, func(gr chi.Router) { adminsettings.RegisterRoutes(gr, settingsHandler) })
+		withRole(proxiesRoles, func(gr chi.Router) { adminproxies.RegisterRoutes(gr, proxiesHandler) })
+		withRole(accountsRoles, func(gr chi.Router) { adminaccounts.RegisterRoutes(gr, accountsHandler) })
+		withRole(operatorOnly, func(gr chi.Router) { adminrawsamples.RegisterRoutes(gr, rawSamplesHandler) })
+		withRole(ownerOnly, func(gr chi.Router) { adminvercel.RegisterRoutes(gr, vercelHandler) })
+		withRole(readViewerWriteOperator, func(gr chi.Router) { admindevcapture.RegisterRoutes(gr, devCaptureHandler) })
+		withRole(historyRoles, func(gr chi.Router) { adminhistory.RegisterRoutes(gr, historyHandler) })
+		withRole(readViewerWriteOwner, func(gr chi.Router) { adminversion.RegisterRoutes(gr, versionHandler) })
+		withRole(ownerOnly, func(gr chi.Router) { adminusers.RegisterRoutes(gr, usersHandler) })
+		withRole(readViewerWriteOperator, func(gr chi.Router) { adminpow.RegisterRoutes(gr, powHandler) })
+		withRole(readViewerWriteOwner, func(gr chi.Router) { admindrain.RegisterRoutes(gr, drainHandler) })
+		withRole(readViewerWriteOperator, func(gr chi.Router) { adminrequests.RegisterRoutes(gr, requestsHandler) })
 	})
 }
 
+// Role policies per admin route group. Viewers can read status and history,
+// operators can additionally run account tests and clear upstream sessions or
+// local captures, and owners manage config, keys, users and Vercel sync.
+var (
+	ownerOnly               = adminauth.RolePolicy{Read: config.AdminRoleOwner, Write: config.AdminRoleOwner}
+	operatorOnly            = adminauth.RolePolicy{Read: config.AdminRoleOperator, Write: config.AdminRoleOperator}
+	readViewerWriteOwner    = adminauth.RolePolicy{Read: config.AdminRoleViewer, Write: config.AdminRoleOwner}
+	readViewerWriteOperator = adminauth.RolePolicy{Read: config.AdminRoleViewer, Write: config.AdminRoleOperator}
+
+	authRoles = adminauth.RolePolicy{
+		Read:      config.AdminRoleViewer,
+		Write:     config.AdminRoleOwner,
+		Overrides: map[string]string{"GET /vercel/config": config.AdminRoleOwner},
+	}
+	accountsRoles = adminauth.RolePolicy{
+		Read:  config.AdminRoleViewer,
+		Write: config.AdminRoleOwner,
+		Overrides: map[string]string{
+			"POST /accounts/test":                 config.AdminRoleOperator,
+			"POST /accounts/test-all":             config.AdminRoleOperator,
+			"POST /accounts/sessions/delete-all":  config.AdminRoleOperator,
+			"POST /accounts/sessions/cleanup/run": config.AdminRoleOperator,
+			"POST /test":                          config.AdminRoleOperator,
+		},
+	}
+	proxiesRoles = adminauth.RolePolicy{
+		Read:      config.AdminRoleViewer,
+		Write:     config.AdminRoleOwner,
+		Overrides: map[string]string{"POST /proxies/test": config.AdminRoleOperator},
+	}
+	historyRoles = adminauth.RolePolicy{
+		Read:  config.AdminRoleViewer,
+		Write: config.AdminRoleOperator,
+		Overrides: map[string]string{
+			"PUT /chat-history/settings": config.AdminRoleOwner,
+			"POST /chat-history/export":  config.AdminRoleViewer,
+		},
+	}
+)
+
 func adminsharedDeps(h *Handler) adminsharedDepsValue {
 	if h == nil {
 		return adminsharedDepsValue{}
 	}
-	return adminsharedDepsValue{Store: h.Store, Pool: h.Pool, DS: h.DS, OpenAI: h.OpenAI, ChatHistory: h.ChatHistory}
+	return adminsharedDepsValue{Store: h.Store, Pool: h.Pool, DS: h.DS, OpenAI: h.OpenAI, ChatHistory: h.ChatHistory, SessionCleanup: h.SessionCleanup, Drain: h.Drain, Requests: h.Requests}
 }
 
 type adminsharedDepsValue struct {
@@ -67,4 +132,8 @@ type adminsharedDepsValue struct {
 	DS          adminshared.DeepSeekCaller
 	OpenAI      adminshared.OpenAIChatCaller
 	ChatHistory *chathistory.Store
+
+	SessionCleanup adminshared.SessionCleaner
+	Drain          adminshared.DrainController
+	Requests       adminshared.RequestInspector
 }
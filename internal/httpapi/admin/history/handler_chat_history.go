@@ -50,11 +50,14 @@ func (h *Handler) getChatHistory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{
-		"version":  snapshot.Version,
-		"limit":    snapshot.Limit,
-		"revision": snapshot.Revision,
-		"items":    snapshot.Items,
-		"path":     store.Path(),
+		"version":   snapshot.Version,
+		"limit":     snapshot.Limit,
+		"retention": snapshot.Retention,
+		"redaction": snapshot.Redaction,
+		"detectors": chathistory.BuiltinDetectors(),
+		"revision":  snapshot.Revision,
+		"items":     snapshot.Items,
+		"path":      store.Path(),
 	})
 }
 
@@ -151,21 +154,60 @@ func (h *Handler) updateChatHistorySettings(w http.ResponseWriter, r *http.Reque
 		return
 	}
 	var body struct {
-		Limit int `json:"limit"`
+		Limit     *int                   `json:"limit"`
+		Retention *chathistory.Retention `json:"retention"`
+		Redaction *chathistory.Redaction `json:"redaction"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": "invalid json"})
 		return
 	}
-	snapshot, err := store.SetLimit(body.Limit)
-	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": err.Error()})
+	if body.Limit == nil && body.Retention == nil && body.Redaction == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": "limit, retention or redaction is required"})
 		return
 	}
+	var (
+		snapshot chathistory.File
+		err      error
+	)
+	if body.Redaction != nil {
+		if snapshot, err = store.SetRedaction(*body.Redaction); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"detail": err.Error()})
+			return
+		}
+	}
+	if body.Retention != nil {
+		if snapshot, err = store.SetRetention(*body.Retention); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"detail": err.Error()})
+			return
+		}
+	}
+	if body.Limit != nil {
+		if snapshot, err = store.SetLimit(*body.Limit); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"detail": err.Error()})
+			return
+		}
+	}
 	writeJSON(w, http.StatusOK, map[string]any{
-		"success":  true,
-		"limit":    snapshot.Limit,
-		"revision": snapshot.Revision,
-		"items":    snapshot.Items,
+		"success":   true,
+		"limit":     snapshot.Limit,
+		"retention": snapshot.Retention,
+		"redaction": snapshot.Redaction,
+		"revision":  snapshot.Revision,
+		"items":     snapshot.Items,
 	})
 }
+
+func (h *Handler) compactChatHistory(w http.ResponseWriter, _ *http.Request) {
+	store := h.ChatHistory
+	if store == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{"detail": "chat history store is not configured"})
+		return
+	}
+	result, err := store.Compact()
+	if err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{"detail": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"success": true, "result": result})
+}
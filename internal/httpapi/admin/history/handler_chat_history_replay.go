@@ -0,0 +1,292 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"ds2api/internal/assistantturn"
+	authn "ds2api/internal/auth"
+	"ds2api/internal/chathistory"
+	"ds2api/internal/completionruntime"
+	"ds2api/internal/config"
+	openaihistory "ds2api/internal/httpapi/openai/history"
+	"ds2api/internal/promptcompat"
+	"ds2api/internal/sse"
+	"ds2api/internal/toolcall"
+)
+
+const replaySurface = "admin.replay"
+
+type replayRequest struct {
+	Model    string `json:"model"`
+	Account  string `json:"account"`
+	Thinking *bool  `json:"thinking"`
+	Search   *bool  `json:"search"`
+	Stream   *bool  `json:"stream"`
+}
+
+type replayResult struct {
+	EntryID          string                    `json:"entry_id,omitempty"`
+	ReplayOf         string                    `json:"replay_of"`
+	Model            string                    `json:"model"`
+	AccountID        string                    `json:"account_id"`
+	Content          string                    `json:"content"`
+	ReasoningContent string                    `json:"reasoning_content,omitempty"`
+	ToolCalls        []toolcall.ParsedToolCall `json:"tool_calls,omitempty"`
+	FinishReason     string                    `json:"finish_reason"`
+	Error            string                    `json:"error,omitempty"`
+	Usage            map[string]any            `json:"usage,omitempty"`
+	ElapsedMs        int64                     `json:"elapsed_ms"`
+}
+
+type modelAliasSnapshotReader struct {
+	aliases map[string]string
+}
+
+func (m modelAliasSnapshotReader) ModelAliases() map[string]string {
+	return m.aliases
+}
+
+// replayChatHistoryItem reruns the stored FinalPrompt of an entry, optionally
+// on another account or model, and records the outcome as a new entry that
+// links back via replay_of. Entries recorded with a history file upload
+// their HistoryText again so the replay sees the same context.
+func (h *Handler) replayChatHistoryItem(w http.ResponseWriter, r *http.Request) {
+	store := h.ChatHistory
+	if store == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{"detail": "chat history store is not configured"})
+		return
+	}
+	if h.DS == nil || h.Pool == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{"detail": "upstream client is not configured"})
+		return
+	}
+	var body replayRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"detail": "invalid json"})
+			return
+		}
+	}
+	source, err := store.Get(strings.TrimSpace(chi.URLParam(r, "id")))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if strings.Contains(strings.ToLower(err.Error()), "not found") {
+			status = http.StatusNotFound
+		}
+		writeJSON(w, status, map[string]any{"detail": err.Error()})
+		return
+	}
+	if strings.TrimSpace(source.FinalPrompt) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": "entry has no final prompt to replay"})
+		return
+	}
+
+	model := strings.TrimSpace(body.Model)
+	if model == "" {
+		model = source.Model
+	}
+	resolved, ok := config.ResolveModel(modelAliasSnapshotReader{aliases: h.Store.Snapshot().ModelAliases}, model)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": fmt.Sprintf("unsupported model: %s", model)})
+		return
+	}
+	thinking, search, _ := config.GetModelConfig(resolved)
+	if body.Thinking != nil {
+		thinking = *body.Thinking
+	}
+	if body.Search != nil {
+		search = *body.Search
+	}
+	stream := body.Stream == nil || *body.Stream
+
+	target := strings.TrimSpace(body.Account)
+	if target != "" {
+		acc, found := h.Store.FindAccount(target)
+		if !found {
+			writeJSON(w, http.StatusNotFound, map[string]any{"detail": "account not found"})
+			return
+		}
+		target = acc.Identifier()
+	}
+	acc, ok := h.Pool.AcquireWait(r.Context(), target, nil)
+	if !ok {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{"detail": authn.ErrNoAccount.Error()})
+		return
+	}
+	authCtx := &authn.RequestAuth{UseConfigToken: true, DeepSeekToken: strings.TrimSpace(acc.Token), AccountID: acc.Identifier(), Account: acc, TriedAccounts: map[string]bool{}}
+	// The DeepSeek client may switch accounts on auth failures; release
+	// whichever slot the replay ends up holding.
+	defer func() { h.Pool.Release(authCtx.AccountID) }()
+	if err := h.ensureReplayToken(r.Context(), authCtx); err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]any{"detail": err.Error()})
+		return
+	}
+	proxyCtx := authn.WithAuth(r.Context(), authCtx)
+
+	startedAt := time.Now()
+	stdReq := promptcompat.StandardRequest{
+		Surface:         replaySurface,
+		RequestedModel:  model,
+		ResolvedModel:   resolved,
+		ResponseModel:   model,
+		FinalPrompt:     source.FinalPrompt,
+		PromptTokenText: source.FinalPrompt,
+		Thinking:        thinking,
+		Search:          search,
+		Stream:          stream,
+	}
+	if strings.TrimSpace(source.HistoryText) != "" {
+		stdReq, err = (openaihistory.Service{DS: h.DS}).AttachHistoryFile(proxyCtx, authCtx, stdReq, source.HistoryText)
+		if err != nil {
+			status, message := openaihistory.MapError(err)
+			writeJSON(w, status, map[string]any{"detail": message})
+			return
+		}
+	}
+
+	linked, startErr := store.Start(chathistory.StartParams{
+		CallerID:    replayCallerID(r),
+		AccountID:   authCtx.AccountID,
+		Surface:     replaySurface,
+		Model:       model,
+		Stream:      stream,
+		UserInput:   source.UserInput,
+		Messages:    source.Messages,
+		HistoryText: source.HistoryText,
+		FinalPrompt: source.FinalPrompt,
+		ReplayOf:    source.ID,
+	})
+	if startErr != nil && !errors.Is(startErr, chathistory.ErrDisabled) {
+		config.Logger.Warn("[chat_history] replay entry start failed", "error", startErr)
+	}
+
+	emit := func(string, any) {}
+	if stream {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher, _ := w.(http.Flusher)
+		emit = func(event string, data any) {
+			b, _ := json.Marshal(data)
+			_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, b)
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		emit("start", map[string]any{"entry_id": linked.ID, "replay_of": source.ID, "model": model, "account_id": authCtx.AccountID})
+	}
+
+	run, outErr := completionruntime.ExecuteNonStreamWithRetry(proxyCtx, h.DS, authCtx, stdReq, completionruntime.Options{
+		RetryEnabled: true,
+		OnPart: func(p sse.ContentPart) {
+			emit("delta", map[string]any{"type": p.Type, "text": p.Text})
+		},
+	})
+	if run.SessionID != "" {
+		defer h.autoDeleteReplaySession(r.Context(), authCtx, run.SessionID)
+	}
+	turn := run.Turn
+	result := replayResult{
+		EntryID:          linked.ID,
+		ReplayOf:         source.ID,
+		Model:            model,
+		AccountID:        authCtx.AccountID,
+		Content:          turn.Text,
+		ReasoningContent: turn.Thinking,
+		ToolCalls:        turn.ToolCalls,
+		FinishReason:     assistantturn.FinalizeTurn(turn, assistantturn.FinalizeOptions{}).FinishReason,
+		Usage:            assistantturn.OpenAIChatUsage(turn),
+		ElapsedMs:        time.Since(startedAt).Milliseconds(),
+	}
+	if outErr != nil {
+		result.Error = outErr.Message
+		result.FinishReason = "error"
+	}
+
+	if linked.ID != "" {
+		update := chathistory.UpdateParams{
+			Status:           "success",
+			ReasoningContent: result.ReasoningContent,
+			Content:          result.Content,
+			StatusCode:       http.StatusOK,
+			ElapsedMs:        result.ElapsedMs,
+			FinishReason:     result.FinishReason,
+			Usage:            result.Usage,
+			Completed:        true,
+		}
+		if outErr != nil {
+			update.Status = "error"
+			update.StatusCode = outErr.Status
+			update.Error = result.Error
+		}
+		if _, err := store.Update(linked.ID, update); err != nil {
+			config.Logger.Warn("[chat_history] replay entry update failed", "error", err)
+		}
+	}
+
+	if stream {
+		emit("done", result)
+		return
+	}
+	if outErr != nil && result.Content == "" {
+		writeJSON(w, http.StatusBadGateway, map[string]any{"detail": outErr.Message, "entry_id": linked.ID})
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// ensureReplayToken logs the replay account in when it has no stored token.
+// Expired tokens are refreshed by the DeepSeek client itself.
+func (h *Handler) ensureReplayToken(ctx context.Context, a *authn.RequestAuth) error {
+	if a.DeepSeekToken != "" {
+		return nil
+	}
+	token, err := h.DS.Login(ctx, a.Account)
+	if err != nil {
+		return fmt.Errorf("login failed: %w", err)
+	}
+	a.DeepSeekToken = token
+	a.Account.Token = token
+	if err := h.Store.UpdateAccountToken(a.AccountID, token); err != nil {
+		config.Logger.Warn("[chat_history] replay token persist failed", "account", a.AccountID, "error", err)
+	}
+	return nil
+}
+
+// autoDeleteReplaySession applies auto_delete to the replay's upstream
+// session, the same way the completion handlers do.
+func (h *Handler) autoDeleteReplaySession(ctx context.Context, a *authn.RequestAuth, sessionID string) {
+	mode := h.Store.AutoDeleteMode()
+	if mode == "none" || a.DeepSeekToken == "" {
+		return
+	}
+	deleteCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	switch mode {
+	case "single":
+		if _, err := h.DS.DeleteSessionForToken(deleteCtx, a.DeepSeekToken, sessionID); err != nil {
+			config.Logger.Warn("[auto_delete_sessions] replay delete failed", "account", a.AccountID, "mode", mode, "session_id", sessionID, "error", err)
+		}
+	case "all":
+		if err := h.DS.DeleteAllSessionsForToken(deleteCtx, a.DeepSeekToken); err != nil {
+			config.Logger.Warn("[auto_delete_sessions] replay delete failed", "account", a.AccountID, "mode", mode, "error", err)
+		}
+	}
+}
+
+func replayCallerID(r *http.Request) string {
+	if p, ok := authn.AdminPrincipalFromContext(r.Context()); ok && strings.TrimSpace(p.Subject) != "" {
+		return "admin:" + p.Subject
+	}
+	return "admin"
+}
@@ -0,0 +1,220 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"ds2api/internal/account"
+	"ds2api/internal/auth"
+	"ds2api/internal/chathistory"
+	"ds2api/internal/config"
+	dsclient "ds2api/internal/deepseek/client"
+)
+
+type replayDSMock struct {
+	payload   map[string]any
+	accountID string
+	body      string
+	deleted   []string
+	uploaded  []string
+}
+
+func (m *replayDSMock) Login(_ context.Context, _ config.Account) (string, error) {
+	return "token", nil
+}
+func (m *replayDSMock) CreateSession(_ context.Context, a *auth.RequestAuth, _ int) (string, error) {
+	m.accountID = a.AccountID
+	return "session-id", nil
+}
+func (m *replayDSMock) GetPow(_ context.Context, _ *auth.RequestAuth, _ int) (string, error) {
+	return "pow", nil
+}
+func (m *replayDSMock) UploadFile(_ context.Context, _ *auth.RequestAuth, req dsclient.UploadFileRequest, _ int) (*dsclient.UploadFileResult, error) {
+	m.uploaded = append(m.uploaded, string(req.Data))
+	return &dsclient.UploadFileResult{ID: "file-history"}, nil
+}
+func (m *replayDSMock) CallCompletion(_ context.Context, _ *auth.RequestAuth, payload map[string]any, _ string, _ int) (*http.Response, error) {
+	m.payload = payload
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(m.body))}, nil
+}
+func (m *replayDSMock) DeleteSessionForToken(_ context.Context, _ string, sessionID string) (*dsclient.DeleteSessionResult, error) {
+	m.deleted = append(m.deleted, sessionID)
+	return &dsclient.DeleteSessionResult{SessionID: sessionID, Success: true}, nil
+}
+func (m *replayDSMock) DeleteAllSessionsForToken(_ context.Context, _ string) error { return nil }
+func (m *replayDSMock) GetSessionCountForToken(_ context.Context, _ string) (*dsclient.SessionStats, error) {
+	return &dsclient.SessionStats{}, nil
+}
+
+func newReplayHarness(t *testing.T, ds *replayDSMock) (http.Handler, *chathistory.Store, chathistory.Entry) {
+	t.Helper()
+	return newReplayHarnessWithConfig(t, ds, "")
+}
+
+// newReplayHarnessWithConfig appends extraConfig, e.g. `,"auto_delete":{...}`,
+// to the test config JSON.
+func newReplayHarnessWithConfig(t *testing.T, ds *replayDSMock, extraConfig string) (http.Handler, *chathistory.Store, chathistory.Entry) {
+	t.Helper()
+	t.Setenv("DS2API_ENV_WRITEBACK", "0")
+	t.Setenv("DS2API_CONFIG_JSON", `{"keys":["k1"],"accounts":[{"email":"a@example.com","token":"ta"},{"email":"b@example.com","token":"tb"}]`+extraConfig+`}`)
+	store := config.LoadStore()
+	historyStore := chathistory.New(filepath.Join(t.TempDir(), "chat_history.json"))
+	source, err := historyStore.Start(chathistory.StartParams{
+		AccountID:   "a@example.com",
+		Surface:     "openai.chat_completions",
+		Model:       "deepseek-v4-flash",
+		UserInput:   "hi",
+		Messages:    []chathistory.Message{{Role: "user", Content: "hi"}},
+		FinalPrompt: "<|User|>hi<|Assistant|>",
+	})
+	if err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	if _, err := historyStore.Update(source.ID, chathistory.UpdateParams{Status: "success", Content: "hello", Completed: true}); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	h := &Handler{Store: store, Pool: account.NewPool(store), DS: ds, ChatHistory: historyStore}
+	r := chi.NewRouter()
+	RegisterRoutes(r, h)
+	return r, historyStore, source
+}
+
+func TestReplayChatHistoryStreamsAndStoresLinkedEntry(t *testing.T) {
+	ds := &replayDSMock{body: "data: {\"v\":\"hello\"}\n\ndata: {\"v\":\" again\"}\n\ndata: {\"p\":\"response/status\",\"v\":\"FINISHED\"}\n\n"}
+	router, historyStore, source := newReplayHarness(t, ds)
+
+	req := httptest.NewRequest(http.MethodPost, "/chat-history/"+source.ID+"/replay", bytes.NewReader([]byte(`{"account":"b@example.com","search":true,"thinking":false}`)))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/event-stream") {
+		t.Fatalf("expected SSE response, got %d %q body=%s", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+	if ds.accountID != "b@example.com" {
+		t.Fatalf("expected target account override, got %q", ds.accountID)
+	}
+	if ds.payload["prompt"] != source.FinalPrompt || ds.payload["search_enabled"] != true {
+		t.Fatalf("expected stored final prompt with search override, got %#v", ds.payload)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "event: delta") || !strings.Contains(body, "event: done") {
+		t.Fatalf("expected delta and done events, got %s", body)
+	}
+
+	snapshot, err := historyStore.Snapshot()
+	if err != nil {
+		t.Fatalf("snapshot failed: %v", err)
+	}
+	var linked chathistory.SummaryEntry
+	for _, item := range snapshot.Items {
+		if item.ReplayOf == source.ID {
+			linked = item
+		}
+	}
+	if linked.ID == "" {
+		t.Fatalf("expected linked replay entry, got %#v", snapshot.Items)
+	}
+	entry, err := historyStore.Get(linked.ID)
+	if err != nil {
+		t.Fatalf("get linked failed: %v", err)
+	}
+	if entry.Content != "hello again" || entry.Status != "success" || entry.AccountID != "b@example.com" || entry.Surface != "admin.replay" {
+		t.Fatalf("unexpected linked entry: %#v", entry)
+	}
+}
+
+func TestReplayChatHistoryAppliesAutoDelete(t *testing.T) {
+	ds := &replayDSMock{body: "data: {\"v\":\"ok\"}\n\ndata: [DONE]\n\n"}
+	router, _, source := newReplayHarnessWithConfig(t, ds, `,"auto_delete":{"mode":"single"}`)
+
+	req := httptest.NewRequest(http.MethodPost, "/chat-history/"+source.ID+"/replay", bytes.NewReader([]byte(`{"stream":false,"thinking":false}`)))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if len(ds.deleted) != 1 || ds.deleted[0] != "session-id" {
+		t.Fatalf("expected the replay session to be deleted, got %v", ds.deleted)
+	}
+
+	ds.deleted = nil
+	router, _, source = newReplayHarness(t, ds)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/chat-history/"+source.ID+"/replay", bytes.NewReader([]byte(`{"stream":false,"thinking":false}`))))
+	if rec.Code != http.StatusOK || len(ds.deleted) != 0 {
+		t.Fatalf("expected no delete without auto_delete, got %d deletes (status %d)", len(ds.deleted), rec.Code)
+	}
+}
+
+func TestReplayChatHistoryNonStreamAndValidation(t *testing.T) {
+	ds := &replayDSMock{body: "data: {\"v\":\"ok\"}\n\ndata: [DONE]\n\n"}
+	router, _, source := newReplayHarness(t, ds)
+
+	req := httptest.NewRequest(http.MethodPost, "/chat-history/"+source.ID+"/replay", bytes.NewReader([]byte(`{"stream":false,"thinking":false}`)))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	var result map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if result["content"] != "ok" || result["replay_of"] != source.ID || result["entry_id"] == "" {
+		t.Fatalf("unexpected result: %#v", result)
+	}
+
+	for body, want := range map[string]int{
+		`{"model":"no-such-model"}`:       http.StatusBadRequest,
+		`{"account":"ghost@example.com"}`: http.StatusNotFound,
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/chat-history/"+source.ID+"/replay", bytes.NewReader([]byte(body))))
+		if rec.Code != want {
+			t.Fatalf("body %s: expected %d, got %d", body, want, rec.Code)
+		}
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/chat-history/chat_missing/replay", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown entry, got %d", rec.Code)
+	}
+}
+
+func TestReplayChatHistoryUploadsHistoryFile(t *testing.T) {
+	ds := &replayDSMock{body: "data: {\"v\":\"ok\"}\n\ndata: [DONE]\n\n"}
+	router, historyStore, _ := newReplayHarness(t, ds)
+	source, err := historyStore.Start(chathistory.StartParams{
+		AccountID:   "a@example.com",
+		Surface:     "openai.chat_completions",
+		Model:       "deepseek-v4-flash",
+		UserInput:   "long question",
+		HistoryText: "[user] earlier turn\n[user] long question",
+		FinalPrompt: "<|User|>Continue from DS2API_HISTORY.txt<|Assistant|>",
+	})
+	if err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/chat-history/"+source.ID+"/replay", bytes.NewReader([]byte(`{"stream":false,"thinking":false}`)))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if len(ds.uploaded) != 1 || ds.uploaded[0] != source.HistoryText {
+		t.Fatalf("expected the history text to be uploaded, got %q", ds.uploaded)
+	}
+	refs, _ := ds.payload["ref_file_ids"].([]any)
+	if len(refs) != 1 || refs[0] != "file-history" || ds.payload["prompt"] != source.FinalPrompt {
+		t.Fatalf("expected the continuation prompt with the history file, got %#v", ds.payload)
+	}
+}
@@ -0,0 +1,181 @@
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ds2api/internal/chathistory"
+)
+
+func (h *Handler) searchChatHistory(w http.ResponseWriter, r *http.Request) {
+	store := h.ChatHistory
+	if store == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{"detail": "chat history store is not configured"})
+		return
+	}
+	q := r.URL.Query()
+	query := chathistory.Query{
+		Text:         q.Get("q"),
+		Surface:      q.Get("surface"),
+		Model:        q.Get("model"),
+		AccountID:    q.Get("account"),
+		CallerID:     q.Get("caller"),
+		Status:       q.Get("status"),
+		FinishReason: q.Get("finish_reason"),
+		Cursor:       q.Get("cursor"),
+	}
+	var err error
+	if query.Since, err = parseTimeParam(q.Get("since")); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": "since: " + err.Error()})
+		return
+	}
+	if query.Until, err = parseTimeParam(q.Get("until")); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": "until: " + err.Error()})
+		return
+	}
+	if raw := strings.TrimSpace(q.Get("limit")); raw != "" {
+		n, convErr := strconv.Atoi(raw)
+		if convErr != nil || n < 1 || n > chathistory.MaxSearchLimit {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"detail": fmt.Sprintf("limit must be between 1 and %d", chathistory.MaxSearchLimit)})
+			return
+		}
+		query.Limit = n
+	}
+	result, err := store.Search(query)
+	if err != nil {
+		status := http.StatusServiceUnavailable
+		if errors.Is(err, chathistory.ErrInvalidCursor) {
+			status = http.StatusBadRequest
+		}
+		writeJSON(w, status, map[string]any{"detail": err.Error()})
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h *Handler) exportChatHistory(w http.ResponseWriter, r *http.Request) {
+	store := h.ChatHistory
+	if store == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{"detail": "chat history store is not configured"})
+		return
+	}
+	var body struct {
+		IDs    []string `json:"ids"`
+		Format string   `json:"format"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": "invalid json"})
+		return
+	}
+	format := strings.ToLower(strings.TrimSpace(body.Format))
+	if format == "" {
+		format = "jsonl"
+	}
+	if format != "jsonl" && format != "openai" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": "format must be jsonl or openai"})
+		return
+	}
+	if len(body.IDs) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": "ids is required"})
+		return
+	}
+	entries, err := store.Export(body.IDs)
+	if err != nil {
+		status := http.StatusServiceUnavailable
+		switch {
+		case errors.Is(err, chathistory.ErrEntryNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, chathistory.ErrExportTooLarge):
+			status = http.StatusBadRequest
+		}
+		writeJSON(w, status, map[string]any{"detail": err.Error()})
+		return
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	skipped := 0
+	for _, entry := range entries {
+		var line any = entry
+		if format == "openai" {
+			example, ok := fineTuneExample(entry)
+			if !ok {
+				skipped++
+				continue
+			}
+			line = example
+		}
+		if err := enc.Encode(line); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"detail": err.Error()})
+			return
+		}
+	}
+	filename := fmt.Sprintf("chat-history-%s-%d.jsonl", format, time.Now().Unix())
+	w.Header().Set("Content-Type", "application/x-ndjson; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Header().Set("X-Ds2api-Export-Count", strconv.Itoa(len(entries)-skipped))
+	w.Header().Set("X-Ds2api-Export-Skipped", strconv.Itoa(skipped))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// fineTuneExample converts a successful entry into the OpenAI chat
+// fine-tuning format. Failed or empty completions are not useful training
+// data and are skipped.
+func fineTuneExample(entry chathistory.Entry) (map[string]any, bool) {
+	content := strings.TrimSpace(entry.Content)
+	if entry.Status != "success" || content == "" {
+		return nil, false
+	}
+	messages := make([]map[string]string, 0, len(entry.Messages)+1)
+	for _, msg := range entry.Messages {
+		role := strings.ToLower(strings.TrimSpace(msg.Role))
+		switch role {
+		case "developer":
+			role = "system"
+		case "system", "user", "assistant":
+		default:
+			continue
+		}
+		if strings.TrimSpace(msg.Content) == "" {
+			continue
+		}
+		messages = append(messages, map[string]string{"role": role, "content": msg.Content})
+	}
+	if len(messages) == 0 {
+		input := strings.TrimSpace(entry.UserInput)
+		if input == "" {
+			return nil, false
+		}
+		messages = append(messages, map[string]string{"role": "user", "content": input})
+	}
+	messages = append(messages, map[string]string{"role": "assistant", "content": content})
+	return map[string]any{"messages": messages}, true
+}
+
+// parseTimeParam accepts unix milliseconds (matching created_at) or RFC3339.
+func parseTimeParam(raw string) (int64, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, nil
+	}
+	if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		if n < 0 {
+			return 0, errors.New("must not be negative")
+		}
+		return n, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return 0, errors.New("expected unix milliseconds or RFC3339 time")
+	}
+	return t.UnixMilli(), nil
+}
@@ -183,3 +183,127 @@ func TestDeleteAndClearChatHistory(t *testing.T) {
 		t.Fatalf("expected empty items after clear, got %d", len(snapshot.Items))
 	}
 }
+
+func TestSearchAndExportChatHistory(t *testing.T) {
+	h, historyStore := newChatHistoryAdminHarness(t)
+	ok, err := historyStore.Start(chathistory.StartParams{
+		Surface:   "openai.chat",
+		Model:     "deepseek-v4-flash",
+		UserInput: "what is the weather",
+		Messages:  []chathistory.Message{{Role: "developer", Content: "be brief"}, {Role: "user", Content: "what is the weather"}},
+	})
+	if err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	if _, err := historyStore.Update(ok.ID, chathistory.UpdateParams{Status: "success", Content: "sunny", Completed: true}); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	failed, err := historyStore.Start(chathistory.StartParams{Surface: "openai.chat", UserInput: "weather tool"})
+	if err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	if _, err := historyStore.Update(failed.ID, chathistory.UpdateParams{Status: "error", Error: "upstream 429", Completed: true}); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+
+	r := chi.NewRouter()
+	RegisterRoutes(r, h)
+
+	searchReq := httptest.NewRequest(http.MethodGet, "/chat-history/search?q=weather&status=error", nil)
+	searchRec := httptest.NewRecorder()
+	r.ServeHTTP(searchRec, searchReq)
+	if searchRec.Code != http.StatusOK {
+		t.Fatalf("expected search 200, got %d body=%s", searchRec.Code, searchRec.Body.String())
+	}
+	var result chathistory.SearchResult
+	if err := json.Unmarshal(searchRec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("decode search failed: %v", err)
+	}
+	if result.Total != 1 || result.Items[0].ID != failed.ID {
+		t.Fatalf("unexpected search result: %#v", result)
+	}
+
+	badReq := httptest.NewRequest(http.MethodGet, "/chat-history/search?since=yesterday", nil)
+	badRec := httptest.NewRecorder()
+	r.ServeHTTP(badRec, badReq)
+	if badRec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for bad since, got %d", badRec.Code)
+	}
+
+	exportReq := httptest.NewRequest(http.MethodPost, "/chat-history/export", bytes.NewReader([]byte(`{"format":"openai","ids":["`+ok.ID+`","`+failed.ID+`"]}`)))
+	exportRec := httptest.NewRecorder()
+	r.ServeHTTP(exportRec, exportReq)
+	if exportRec.Code != http.StatusOK {
+		t.Fatalf("expected export 200, got %d body=%s", exportRec.Code, exportRec.Body.String())
+	}
+	if exportRec.Header().Get("X-Ds2api-Export-Skipped") != "1" {
+		t.Fatalf("expected failed entry to be skipped, headers=%v", exportRec.Header())
+	}
+	want := `{"messages":[{"content":"be brief","role":"system"},{"content":"what is the weather","role":"user"},{"content":"sunny","role":"assistant"}]}` + "\n"
+	if exportRec.Body.String() != want {
+		t.Fatalf("unexpected fine-tune export:\n%s", exportRec.Body.String())
+	}
+
+	jsonlReq := httptest.NewRequest(http.MethodPost, "/chat-history/export", bytes.NewReader([]byte(`{"ids":["`+failed.ID+`"]}`)))
+	jsonlRec := httptest.NewRecorder()
+	r.ServeHTTP(jsonlRec, jsonlReq)
+	var exported chathistory.Entry
+	if err := json.Unmarshal(jsonlRec.Body.Bytes(), &exported); err != nil || exported.Error != "upstream 429" {
+		t.Fatalf("unexpected jsonl export: %s err=%v", jsonlRec.Body.String(), err)
+	}
+
+	missingReq := httptest.NewRequest(http.MethodPost, "/chat-history/export", bytes.NewReader([]byte(`{"ids":["chat_missing"]}`)))
+	missingRec := httptest.NewRecorder()
+	r.ServeHTTP(missingRec, missingReq)
+	if missingRec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown id, got %d", missingRec.Code)
+	}
+}
+
+func TestUpdateChatHistoryRetentionAndCompact(t *testing.T) {
+	h, historyStore := newChatHistoryAdminHarness(t)
+	entry, err := historyStore.Start(chathistory.StartParams{UserInput: "reach me at carol@example.com"})
+	if err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	r := chi.NewRouter()
+	RegisterRoutes(r, h)
+
+	for body, want := range map[string]int{
+		`{}`:                                        http.StatusBadRequest,
+		`{"redaction":{"detectors":["nope"]}}`:      http.StatusBadRequest,
+		`{"retention":{"max_entry_chars":5}}`:       http.StatusBadRequest,
+		`{"retention":{"max_total_bytes":-1}}`:      http.StatusBadRequest,
+		`{"redaction":{"rules":[{"pattern":"["}]}}`: http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/chat-history/settings", bytes.NewReader([]byte(body))))
+		if rec.Code != want {
+			t.Fatalf("body %s: expected %d, got %d", body, want, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/chat-history/settings", bytes.NewReader([]byte(`{"retention":{"max_age_hours":24,"max_entry_chars":4000},"redaction":{"detectors":["email"]}}`))))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	var resp map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	retention, _ := resp["retention"].(map[string]any)
+	if retention["max_age_hours"] != float64(24) || resp["limit"] != float64(chathistory.DefaultLimit) {
+		t.Fatalf("unexpected settings response: %#v", resp)
+	}
+	got, err := historyStore.Get(entry.ID)
+	if err != nil || got.UserInput != "reach me at [REDACTED:email]" {
+		t.Fatalf("expected existing entry to be redacted, got %q err=%v", got.UserInput, err)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/chat-history/compact", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected compact 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+}
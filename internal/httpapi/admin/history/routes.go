@@ -4,8 +4,12 @@ import "github.com/go-chi/chi/v5"
 
 func RegisterRoutes(r chi.Router, h *Handler) {
 	r.Get("/chat-history", h.getChatHistory)
+	r.Get("/chat-history/search", h.searchChatHistory)
+	r.Post("/chat-history/export", h.exportChatHistory)
 	r.Get("/chat-history/{id}", h.getChatHistoryItem)
 	r.Delete("/chat-history", h.clearChatHistory)
 	r.Delete("/chat-history/{id}", h.deleteChatHistoryItem)
+	r.Post("/chat-history/{id}/replay", h.replayChatHistoryItem)
 	r.Put("/chat-history/settings", h.updateChatHistorySettings)
+	r.Post("/chat-history/compact", h.compactChatHistory)
 }
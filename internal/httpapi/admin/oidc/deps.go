@@ -0,0 +1,26 @@
+package oidc
+
+import (
+	"ds2api/internal/chathistory"
+	adminshared "ds2api/internal/httpapi/admin/shared"
+	"ds2api/internal/oidc"
+)
+
+type Handler struct {
+	Store       adminshared.ConfigStore
+	Pool        adminshared.PoolController
+	DS          adminshared.DeepSeekCaller
+	OpenAI      adminshared.OpenAIChatCaller
+	ChatHistory *chathistory.Store
+	// Client talks to the identity provider; nil uses oidc.DefaultClient.
+	Client *oidc.Client
+}
+
+var writeJSON = adminshared.WriteJSON
+
+func (h *Handler) client() *oidc.Client {
+	if h.Client != nil {
+		return h.Client
+	}
+	return oidc.DefaultClient
+}
@@ -0,0 +1,177 @@
+package oidc
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	authn "ds2api/internal/auth"
+	"ds2api/internal/config"
+	"ds2api/internal/oidc"
+)
+
+const (
+	loginStateCookie  = "ds2api_oidc"
+	loginStatePurpose = "oidc_login"
+	loginStateTTL     = 10 * time.Minute
+	callbackPath      = "/admin/oidc/callback"
+	adminUIPath       = "/admin/"
+)
+
+func (h *Handler) getConfig(w http.ResponseWriter, _ *http.Request) {
+	cfg := h.Store.AdminOIDC()
+	displayName := strings.TrimSpace(cfg.DisplayName)
+	if displayName == "" {
+		displayName = "SSO"
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"enabled":      cfg.Enabled,
+		"display_name": displayName,
+		"login_url":    "/admin/oidc/login",
+	})
+}
+
+func (h *Handler) login(w http.ResponseWriter, r *http.Request) {
+	cfg := h.Store.AdminOIDC()
+	if !cfg.Enabled {
+		writeJSON(w, http.StatusNotFound, map[string]any{"detail": "single sign-on is not enabled"})
+		return
+	}
+	doc, err := h.client().Discover(r.Context(), cfg.Issuer)
+	if err != nil {
+		config.Logger.Warn("[admin_oidc] discovery failed", "issuer", cfg.Issuer, "error", err)
+		writeJSON(w, http.StatusBadGateway, map[string]any{"detail": err.Error()})
+		return
+	}
+	state, err1 := oidc.RandomToken()
+	nonce, err2 := oidc.RandomToken()
+	verifier, err3 := oidc.RandomToken()
+	if err1 != nil || err2 != nil || err3 != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"detail": "failed to generate login state"})
+		return
+	}
+	redirectURL := callbackURL(r, cfg)
+	signed, err := authn.SignState(loginStatePurpose, map[string]any{
+		"state":        state,
+		"nonce":        nonce,
+		"verifier":     verifier,
+		"redirect_uri": redirectURL,
+	}, loginStateTTL, h.Store)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"detail": err.Error()})
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginStateCookie,
+		Value:    signed,
+		Path:     "/admin/oidc",
+		MaxAge:   int(loginStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   requestScheme(r) == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, oidc.AuthCodeURL(doc, cfg, redirectURL, state, nonce, verifier), http.StatusFound)
+}
+
+func (h *Handler) callback(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{Name: loginStateCookie, Value: "", Path: "/admin/oidc", MaxAge: -1, HttpOnly: true})
+	cfg := h.Store.AdminOIDC()
+	if !cfg.Enabled {
+		redirectWithError(w, r, "single sign-on is not enabled")
+		return
+	}
+	q := r.URL.Query()
+	if idpErr := strings.TrimSpace(q.Get("error")); idpErr != "" {
+		if desc := strings.TrimSpace(q.Get("error_description")); desc != "" {
+			idpErr += ": " + desc
+		}
+		redirectWithError(w, r, idpErr)
+		return
+	}
+	cookie, err := r.Cookie(loginStateCookie)
+	if err != nil {
+		redirectWithError(w, r, "login state is missing, please retry")
+		return
+	}
+	pending, err := authn.VerifyState(loginStatePurpose, cookie.Value, h.Store)
+	if err != nil {
+		redirectWithError(w, r, err.Error())
+		return
+	}
+	if want, _ := pending["state"].(string); want == "" || want != q.Get("state") {
+		redirectWithError(w, r, "login state mismatch")
+		return
+	}
+	code := strings.TrimSpace(q.Get("code"))
+	if code == "" {
+		redirectWithError(w, r, "authorization code is missing")
+		return
+	}
+	nonce, _ := pending["nonce"].(string)
+	verifier, _ := pending["verifier"].(string)
+	redirectURL, _ := pending["redirect_uri"].(string)
+
+	client := h.client()
+	doc, err := client.Discover(r.Context(), cfg.Issuer)
+	if err != nil {
+		redirectWithError(w, r, err.Error())
+		return
+	}
+	tokens, err := client.Exchange(r.Context(), doc, cfg, redirectURL, code, verifier)
+	if err != nil {
+		config.Logger.Warn("[admin_oidc] code exchange failed", "error", err)
+		redirectWithError(w, r, "code exchange failed")
+		return
+	}
+	claims, err := client.VerifyIDToken(r.Context(), doc, cfg, tokens.IDToken, nonce)
+	if err != nil {
+		config.Logger.Warn("[admin_oidc] id_token rejected", "error", err)
+		redirectWithError(w, r, err.Error())
+		return
+	}
+	identity, err := oidc.ResolveIdentity(cfg, claims)
+	if err != nil {
+		config.Logger.Warn("[admin_oidc] access denied", "error", err)
+		redirectWithError(w, r, err.Error())
+		return
+	}
+	expireHours := h.Store.AdminJWTExpireHours()
+	token, err := authn.CreateOIDCAdminJWT(identity.Subject, identity.Role, expireHours, h.Store)
+	if err != nil {
+		redirectWithError(w, r, err.Error())
+		return
+	}
+	config.Logger.Info("[admin_oidc] login", "subject", identity.Subject, "role", identity.Role)
+	// The token travels in the fragment so it never reaches server logs.
+	frag := url.Values{}
+	frag.Set("oidc_token", token)
+	frag.Set("expires_in", strconv.Itoa(expireHours*3600))
+	frag.Set("username", identity.Subject)
+	frag.Set("role", identity.Role)
+	http.Redirect(w, r, adminUIPath+"#"+frag.Encode(), http.StatusFound)
+}
+
+func redirectWithError(w http.ResponseWriter, r *http.Request, detail string) {
+	frag := url.Values{}
+	frag.Set("oidc_error", detail)
+	http.Redirect(w, r, adminUIPath+"#"+frag.Encode(), http.StatusFound)
+}
+
+func callbackURL(r *http.Request, cfg config.AdminOIDCConfig) string {
+	if v := strings.TrimSpace(cfg.RedirectURL); v != "" {
+		return v
+	}
+	return requestScheme(r) + "://" + r.Host + callbackPath
+}
+
+func requestScheme(r *http.Request) string {
+	if proto := strings.TrimSpace(strings.Split(r.Header.Get("X-Forwarded-Proto"), ",")[0]); proto != "" {
+		return strings.ToLower(proto)
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
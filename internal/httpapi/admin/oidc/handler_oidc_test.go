@@ -0,0 +1,256 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	authn "ds2api/internal/auth"
+	"ds2api/internal/config"
+	"ds2api/internal/oidc"
+)
+
+type fakeProvider struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu     sync.Mutex
+	codes  map[string]fakeGrant
+	claims map[string]any
+}
+
+type fakeGrant struct {
+	nonce     string
+	challenge string
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	p := &fakeProvider{t: t, key: key, codes: map[string]fakeGrant{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                 p.server.URL,
+			"authorization_endpoint": p.server.URL + "/authorize",
+			"token_endpoint":         p.server.URL + "/token",
+			"jwks_uri":               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]any{{
+			"kty": "RSA",
+			"kid": "k1",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if id, secret, ok := r.BasicAuth(); !ok || id != "ds2api" || secret != "shh" {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+		p.mu.Lock()
+		grant, ok := p.codes[r.Form.Get("code")]
+		delete(p.codes, r.Form.Get("code"))
+		claims := p.claims
+		p.mu.Unlock()
+		if !ok || oidc.PKCEChallenge(r.Form.Get("code_verifier")) != grant.challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		full := map[string]any{
+			"iss":   p.server.URL,
+			"aud":   "ds2api",
+			"iat":   time.Now().Unix(),
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"nonce": grant.nonce,
+		}
+		for k, v := range claims {
+			full[k] = v
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "at", "token_type": "Bearer", "id_token": p.sign(full)})
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *fakeProvider) sign(claims map[string]any) string {
+	header, _ := json.Marshal(map[string]any{"alg": "RS256", "kid": "k1", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	msg := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	sum := sha256.Sum256([]byte(msg))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, sum[:])
+	if err != nil {
+		p.t.Fatalf("sign: %v", err)
+	}
+	return msg + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// authorize plays the browser + IdP: it accepts the authorization request and
+// returns the callback URL the provider would redirect to.
+func (p *fakeProvider) authorize(location string, claims map[string]any) string {
+	u, err := url.Parse(location)
+	if err != nil {
+		p.t.Fatalf("parse authorize url: %v", err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("client_id") != "ds2api" {
+		p.t.Fatalf("unexpected authorization request: %s", location)
+	}
+	p.mu.Lock()
+	p.codes["code-1"] = fakeGrant{nonce: q.Get("nonce"), challenge: q.Get("code_challenge")}
+	p.claims = claims
+	p.mu.Unlock()
+	return q.Get("redirect_uri") + "?code=code-1&state=" + url.QueryEscape(q.Get("state"))
+}
+
+func newOIDCTestRouter(t *testing.T, provider *fakeProvider, extra string) (http.Handler, *config.Store) {
+	t.Helper()
+	t.Setenv("DS2API_ENV_WRITEBACK", "0")
+	t.Setenv("DS2API_CONFIG_JSON", `{"admin":{"oidc":{
+		"enabled":true,
+		"issuer":"`+provider.server.URL+`",
+		"client_id":"ds2api",
+		"client_secret":"shh",
+		"allowed_email_domains":["corp.example"],
+		"role_mappings":[{"claim":"groups","value":"ds2api-admins","role":"owner"},{"claim":"groups","value":"ds2api-ops","role":"operator"}]`+extra+`
+	}}}`)
+	store := config.LoadStore()
+	h := &Handler{Store: store, Client: oidc.NewClient(provider.server.Client())}
+	r := chi.NewRouter()
+	r.Route("/admin", func(ar chi.Router) { RegisterPublicRoutes(ar, h) })
+	return r, store
+}
+
+func runOIDCLogin(t *testing.T, router http.Handler, provider *fakeProvider, claims map[string]any) url.Values {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://ds2api.test/admin/oidc/login", nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("expected redirect to provider, got %d: %s", rec.Code, rec.Body.String())
+	}
+	cookies := rec.Result().Cookies()
+	callback := provider.authorize(rec.Header().Get("Location"), claims)
+	if !strings.HasPrefix(callback, "http://ds2api.test/admin/oidc/callback") {
+		t.Fatalf("unexpected redirect_uri: %s", callback)
+	}
+	req := httptest.NewRequest(http.MethodGet, callback, nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusFound {
+		t.Fatalf("expected redirect back to admin UI, got %d", rec.Code)
+	}
+	loc, _ := url.Parse(rec.Header().Get("Location"))
+	frag, _ := url.ParseQuery(loc.Fragment)
+	return frag
+}
+
+func TestOIDCLoginIssuesAdminJWTWithMappedRole(t *testing.T) {
+	provider := newFakeProvider(t)
+	router, store := newOIDCTestRouter(t, provider, "")
+
+	frag := runOIDCLogin(t, router, provider, map[string]any{
+		"sub":            "u-1",
+		"email":          "Dana@corp.example",
+		"email_verified": true,
+		"groups":         []string{"everyone", "ds2api-ops"},
+	})
+	if frag.Get("oidc_error") != "" {
+		t.Fatalf("unexpected oidc error: %s", frag.Get("oidc_error"))
+	}
+	if frag.Get("role") != "operator" || frag.Get("username") != "dana@corp.example" {
+		t.Fatalf("unexpected identity: %v", frag)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/admin/me", nil)
+	req.Header.Set("Authorization", "Bearer "+frag.Get("oidc_token"))
+	principal, err := authn.AuthenticateAdminRequest(req, store)
+	if err != nil {
+		t.Fatalf("issued token rejected: %v", err)
+	}
+	if principal.Role != "operator" || principal.Method != "oidc" {
+		t.Fatalf("unexpected principal: %#v", principal)
+	}
+
+	if err := store.Update(func(c *config.Config) error {
+		c.Admin.OIDC.Enabled = false
+		return nil
+	}); err != nil {
+		t.Fatalf("disable oidc: %v", err)
+	}
+	if _, err := authn.AuthenticateAdminRequest(req, store); err == nil {
+		t.Fatal("expected sso token to be rejected once sso is disabled")
+	}
+}
+
+func TestOIDCLoginRejectsDisallowedDomainAndUnmappedUser(t *testing.T) {
+	provider := newFakeProvider(t)
+	router, _ := newOIDCTestRouter(t, provider, "")
+
+	frag := runOIDCLogin(t, router, provider, map[string]any{"sub": "u-2", "email": "eve@evil.example", "email_verified": true, "groups": []string{"ds2api-admins"}})
+	if frag.Get("oidc_token") != "" || !strings.Contains(frag.Get("oidc_error"), "not allowed") {
+		t.Fatalf("expected domain rejection, got %v", frag)
+	}
+	frag = runOIDCLogin(t, router, provider, map[string]any{"sub": "u-3", "email": "fred@corp.example", "email_verified": "true", "groups": []string{"everyone"}})
+	if frag.Get("oidc_token") != "" || !strings.Contains(frag.Get("oidc_error"), "no admin role mapping") {
+		t.Fatalf("expected unmapped rejection, got %v", frag)
+	}
+}
+
+func TestOIDCCallbackRejectsStateMismatch(t *testing.T) {
+	provider := newFakeProvider(t)
+	router, _ := newOIDCTestRouter(t, provider, `,"default_role":"viewer"`)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://ds2api.test/admin/oidc/login", nil))
+	cookies := rec.Result().Cookies()
+	_ = provider.authorize(rec.Header().Get("Location"), map[string]any{"sub": "u-4", "email": "gail@corp.example"})
+
+	req := httptest.NewRequest(http.MethodGet, "http://ds2api.test/admin/oidc/callback?code=code-1&state=forged", nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	loc, _ := url.Parse(rec.Header().Get("Location"))
+	frag, _ := url.ParseQuery(loc.Fragment)
+	if frag.Get("oidc_token") != "" || frag.Get("oidc_error") != "login state mismatch" {
+		t.Fatalf("expected state mismatch, got %v", frag)
+	}
+}
+
+func TestOIDCStateBlobIsNotAnAdminJWT(t *testing.T) {
+	provider := newFakeProvider(t)
+	_, store := newOIDCTestRouter(t, provider, "")
+	blob, err := authn.SignState(loginStatePurpose, map[string]any{"state": "x"}, time.Minute, store)
+	if err != nil {
+		t.Fatalf("sign state: %v", err)
+	}
+	if _, err := authn.VerifyJWTWithStore(blob, store); err == nil {
+		t.Fatal("state blob must not verify as an admin session")
+	}
+}
@@ -0,0 +1,11 @@
+package oidc
+
+import "github.com/go-chi/chi/v5"
+
+// RegisterPublicRoutes mounts the SSO flow. These routes run before admin
+// authentication because they are how a browser obtains an admin JWT.
+func RegisterPublicRoutes(r chi.Router, h *Handler) {
+	r.Get("/oidc/config", h.getConfig)
+	r.Get("/oidc/login", h.login)
+	r.Get("/oidc/callback", h.callback)
+}
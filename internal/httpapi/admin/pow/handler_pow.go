@@ -0,0 +1,83 @@
+package pow
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+
+	dsclient "ds2api/internal/deepseek/client"
+	powpkg "ds2api/pow"
+)
+
+// Benchmark limits keep a single admin request from pinning the host for
+// minutes.
+const (
+	maxBenchmarkDifficulty = 10_000_000
+	maxBenchmarkRounds     = 20
+	defaultBenchmarkRounds = 3
+)
+
+func (h *Handler) getStatus(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"algorithms":         powpkg.Algorithms(),
+		"workers":            dsclient.PowWorkerStats(),
+		"min_hash_rate":      powpkg.MinHashRate,
+		"default_difficulty": powpkg.DefaultDifficulty,
+	})
+}
+
+func (h *Handler) benchmark(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Algorithm  string `json:"algorithm"`
+		Difficulty int64  `json:"difficulty"`
+		Rounds     int    `json:"rounds"`
+		Parallel   int    `json:"parallel"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"detail": "invalid json"})
+			return
+		}
+	}
+	if req.Algorithm == "" {
+		req.Algorithm = powpkg.AlgorithmDeepSeekHashV1
+	}
+	if req.Difficulty == 0 {
+		req.Difficulty = powpkg.DefaultDifficulty
+	}
+	if req.Rounds == 0 {
+		req.Rounds = defaultBenchmarkRounds
+	}
+	if req.Parallel == 0 {
+		req.Parallel = dsclient.PowWorkerStats().Workers
+	}
+	maxParallel := 2 * runtime.NumCPU()
+	switch {
+	case req.Difficulty < 1 || req.Difficulty > maxBenchmarkDifficulty:
+		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": fmt.Sprintf("difficulty must be between 1 and %d", maxBenchmarkDifficulty)})
+		return
+	case req.Rounds < 1 || req.Rounds > maxBenchmarkRounds:
+		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": fmt.Sprintf("rounds must be between 1 and %d", maxBenchmarkRounds)})
+		return
+	case req.Parallel < 1 || req.Parallel > maxParallel:
+		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": fmt.Sprintf("parallel must be between 1 and %d", maxParallel)})
+		return
+	}
+	if _, ok := powpkg.Lookup(req.Algorithm); !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": "unsupported algorithm: " + req.Algorithm})
+		return
+	}
+	result, err := powpkg.Benchmark(r.Context(), req.Algorithm, req.Difficulty, req.Rounds, req.Parallel)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"detail": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"success": true,
+		"result":  result,
+		"cpus":    runtime.NumCPU(),
+		// Worst-case single solve at the default difficulty on one worker.
+		"estimated_solve_ms": int64(float64(powpkg.DefaultDifficulty) / result.PerWorkerHashesPerSecond * 1000),
+	})
+}
@@ -0,0 +1,8 @@
+package pow
+
+import "github.com/go-chi/chi/v5"
+
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Get("/pow/status", h.getStatus)
+	r.Post("/pow/benchmark", h.benchmark)
+}
@@ -0,0 +1,86 @@
+package requests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// streamInterval refreshes byte and token counters on the SSE feed
+	// between start/finish notifications.
+	streamInterval = time.Second
+	// cancelWait bounds how long a cancel waits for the handler to return
+	// and release its account slot.
+	cancelWait = 10 * time.Second
+)
+
+func (h *Handler) list(w http.ResponseWriter, _ *http.Request) {
+	if h.Requests == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{"detail": "request inspector is not available"})
+		return
+	}
+	items := h.Requests.List()
+	writeJSON(w, http.StatusOK, map[string]any{"items": items, "total": len(items)})
+}
+
+// stream is the SSE feed behind the WebUI request table. Each "requests"
+// event carries the full list.
+func (h *Handler) stream(w http.ResponseWriter, r *http.Request) {
+	if h.Requests == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{"detail": "request inspector is not available"})
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	ticker := time.NewTicker(streamInterval)
+	defer ticker.Stop()
+	for {
+		changed := h.Requests.Changed()
+		items := h.Requests.List()
+		b, _ := json.Marshal(map[string]any{"items": items, "total": len(items)})
+		if _, err := fmt.Fprintf(w, "event: requests\ndata: %s\n\n", b); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-changed:
+		case <-ticker.C:
+		}
+	}
+}
+
+// cancel aborts a running request: its context is cancelled and its upstream
+// body closed, so the handler returns and releases the account slot.
+func (h *Handler) cancel(w http.ResponseWriter, r *http.Request) {
+	if h.Requests == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{"detail": "request inspector is not available"})
+		return
+	}
+	id := strings.TrimSpace(chi.URLParam(r, "request_id"))
+	done, ok := h.Requests.Cancel(id)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]any{"detail": "request not found"})
+		return
+	}
+	released := false
+	select {
+	case <-done:
+		released = true
+	case <-time.After(cancelWait):
+	case <-r.Context().Done():
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"success": true, "id": id, "released": released})
+}
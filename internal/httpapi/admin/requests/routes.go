@@ -0,0 +1,9 @@
+package requests
+
+import "github.com/go-chi/chi/v5"
+
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Get("/requests", h.list)
+	r.Get("/requests/stream", h.stream)
+	r.Delete("/requests/{request_id}", h.cancel)
+}
@@ -20,12 +20,15 @@ func (h *Handler) getSettings(w http.ResponseWriter, _ *http.Request) {
 			"jwt_expire_hours":         h.Store.AdminJWTExpireHours(),
 			"jwt_valid_after_unix":     snap.Admin.JWTValidAfterUnix,
 			"default_password_warning": authn.UsingDefaultAdminKey(h.Store),
+			"oidc":                     oidcSettingsView(h.Store.AdminOIDC()),
 		},
 		"runtime": map[string]any{
 			"account_max_inflight":         h.Store.RuntimeAccountMaxInflight(),
 			"account_max_queue":            h.Store.RuntimeAccountMaxQueue(recommended),
 			"global_max_inflight":          h.Store.RuntimeGlobalMaxInflight(recommended),
 			"token_refresh_interval_hours": h.Store.RuntimeTokenRefreshIntervalHours(),
+			"max_choices":                  h.Store.RuntimeMaxChoices(),
+			"choice_account_policy":        h.Store.RuntimeChoiceAccountPolicy(),
 		},
 		"responses":   snap.Responses,
 		"embeddings":  snap.Embeddings,
@@ -44,3 +47,21 @@ func (h *Handler) getSettings(w http.ResponseWriter, _ *http.Request) {
 		"needs_vercel_sync": needsSync,
 	})
 }
+
+// oidcSettingsView exposes the SSO block without the client secret.
+func oidcSettingsView(cfg config.AdminOIDCConfig) map[string]any {
+	return map[string]any{
+		"enabled":               cfg.Enabled,
+		"display_name":          cfg.DisplayName,
+		"issuer":                cfg.Issuer,
+		"client_id":             cfg.ClientID,
+		"has_client_secret":     strings.TrimSpace(cfg.ClientSecret) != "",
+		"redirect_url":          cfg.RedirectURL,
+		"scopes":                cfg.Scopes,
+		"allowed_email_domains": cfg.AllowedEmailDomains,
+		"allowed_groups":        cfg.AllowedGroups,
+		"groups_claim":          cfg.GroupsClaim,
+		"role_mappings":         cfg.RoleMappings,
+		"default_role":          cfg.DefaultRole,
+	}
+}
@@ -17,6 +17,12 @@ func validateMergedRuntimeSettings(current config.RuntimeConfig, incoming *confi
 		if incoming.TokenRefreshIntervalHours > 0 {
 			merged.TokenRefreshIntervalHours = incoming.TokenRefreshIntervalHours
 		}
+		if incoming.MaxChoices > 0 {
+			merged.MaxChoices = incoming.MaxChoices
+		}
+		if incoming.ChoiceAccountPolicy != "" {
+			merged.ChoiceAccountPolicy = incoming.ChoiceAccountPolicy
+		}
 	}
 	return validateRuntimeSettings(merged)
 }
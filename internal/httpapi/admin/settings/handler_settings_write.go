@@ -52,6 +52,12 @@ func (h *Handler) updateSettings(w http.ResponseWriter, r *http.Request) {
 			if runtimeCfg.TokenRefreshIntervalHours > 0 {
 				c.Runtime.TokenRefreshIntervalHours = runtimeCfg.TokenRefreshIntervalHours
 			}
+			if runtimeCfg.MaxChoices > 0 {
+				c.Runtime.MaxChoices = runtimeCfg.MaxChoices
+			}
+			if runtimeCfg.ChoiceAccountPolicy != "" {
+				c.Runtime.ChoiceAccountPolicy = runtimeCfg.ChoiceAccountPolicy
+			}
 		}
 		if responsesCfg != nil && responsesCfg.StoreTTLSeconds > 0 {
 			c.Responses.StoreTTLSeconds = responsesCfg.StoreTTLSeconds
@@ -8,6 +8,9 @@ import (
 	"ds2api/internal/auth"
 	"ds2api/internal/config"
 	dsclient "ds2api/internal/deepseek/client"
+	"ds2api/internal/drain"
+	"ds2api/internal/inflight"
+	"ds2api/internal/sessioncleanup"
 )
 
 type ConfigStore interface {
@@ -28,10 +31,16 @@ type ConfigStore interface {
 	AdminPasswordHash() string
 	AdminJWTExpireHours() int
 	AdminJWTValidAfterUnix() int64
+	AdminUsers() []config.AdminUser
+	AdminAPITokens() []config.AdminAPIToken
+	AdminOIDC() config.AdminOIDCConfig
+	AdminOIDCEnabled() bool
 	RuntimeAccountMaxInflight() int
 	RuntimeAccountMaxQueue(defaultSize int) int
 	RuntimeGlobalMaxInflight(defaultSize int) int
 	RuntimeTokenRefreshIntervalHours() int
+	RuntimeMaxChoices() int
+	RuntimeChoiceAccountPolicy() string
 	AutoDeleteMode() string
 	CurrentInputFileEnabled() bool
 	CurrentInputFileMinChars() int
@@ -41,6 +50,8 @@ type ConfigStore interface {
 }
 
 type PoolController interface {
+	AcquireWait(ctx context.Context, target string, exclude map[string]bool) (config.Account, bool)
+	Release(accountID string)
 	Reset()
 	Status() map[string]any
 	ApplyRuntimeLimits(maxInflightPerAccount, maxQueueSize, globalMaxInflight int)
@@ -54,11 +65,42 @@ type DeepSeekCaller interface {
 	Login(ctx context.Context, acc config.Account) (string, error)
 	CreateSession(ctx context.Context, a *auth.RequestAuth, maxAttempts int) (string, error)
 	GetPow(ctx context.Context, a *auth.RequestAuth, maxAttempts int) (string, error)
+	UploadFile(ctx context.Context, a *auth.RequestAuth, req dsclient.UploadFileRequest, maxAttempts int) (*dsclient.UploadFileResult, error)
 	CallCompletion(ctx context.Context, a *auth.RequestAuth, payload map[string]any, powResp string, maxAttempts int) (*http.Response, error)
 	GetSessionCountForToken(ctx context.Context, token string) (*dsclient.SessionStats, error)
+	DeleteSessionForToken(ctx context.Context, token string, sessionID string) (*dsclient.DeleteSessionResult, error)
 	DeleteAllSessionsForToken(ctx context.Context, token string) error
 }
 
+// PrefetchReporter is implemented by DeepSeek callers that keep a warm pool
+// of sessions and PoW answers.
+type PrefetchReporter interface {
+	PrefetchStatus() map[string]any
+}
+
+// SessionCleaner runs the scheduled upstream session cleanup.
+type SessionCleaner interface {
+	Status() map[string]any
+	Trigger() error
+}
+
+// DrainController switches the instance into graceful drain mode.
+type DrainController interface {
+	Start(reason string) bool
+	Status() map[string]any
+}
+
+// RequestInspector lists and cancels in-flight API requests.
+type RequestInspector interface {
+	List() []inflight.Snapshot
+	Cancel(id string) (<-chan struct{}, bool)
+	Changed() <-chan struct{}
+}
+
 var _ ConfigStore = (*config.Store)(nil)
 var _ PoolController = (*account.Pool)(nil)
 var _ DeepSeekCaller = (*dsclient.Client)(nil)
+var _ PrefetchReporter = (*dsclient.Client)(nil)
+var _ SessionCleaner = (*sessioncleanup.Runner)(nil)
+var _ DrainController = (*drain.Controller)(nil)
+var _ RequestInspector = (*inflight.Registry)(nil)
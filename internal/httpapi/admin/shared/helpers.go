@@ -190,9 +190,11 @@ func toAPIKeys(v any) ([]config.APIKey, bool) {
 			}
 			seen[key] = struct{}{}
 			out = append(out, config.APIKey{
-				Key:    key,
-				Name:   fieldString(x, "name"),
-				Remark: fieldString(x, "remark"),
+				Key:      key,
+				Name:     fieldString(x, "name"),
+				Remark:   fieldString(x, "remark"),
+				Priority: fieldString(x, "priority"),
+				Weight:   intFrom(x["weight"]),
 			})
 		default:
 			key := strings.TrimSpace(fmt.Sprintf("%v", item))
@@ -211,14 +213,17 @@ func toAPIKeys(v any) ([]config.APIKey, bool) {
 
 func normalizeAPIKeyForStorage(item config.APIKey) config.APIKey {
 	return config.APIKey{
-		Key:    strings.TrimSpace(item.Key),
-		Name:   strings.TrimSpace(item.Name),
-		Remark: strings.TrimSpace(item.Remark),
+		Key:      strings.TrimSpace(item.Key),
+		Name:     strings.TrimSpace(item.Name),
+		Remark:   strings.TrimSpace(item.Remark),
+		Priority: strings.TrimSpace(item.Priority),
+		Weight:   item.Weight,
 	}
 }
 
 func apiKeyHasMetadata(item config.APIKey) bool {
-	return strings.TrimSpace(item.Name) != "" || strings.TrimSpace(item.Remark) != ""
+	return strings.TrimSpace(item.Name) != "" || strings.TrimSpace(item.Remark) != "" ||
+		strings.TrimSpace(item.Priority) != "" || item.Weight > 0
 }
 
 func mergeAPIKeysPreferStructured(existing, incoming []config.APIKey) ([]config.APIKey, int) {
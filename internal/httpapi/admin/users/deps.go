@@ -0,0 +1,28 @@
+package users
+
+import (
+	"ds2api/internal/chathistory"
+	adminshared "ds2api/internal/httpapi/admin/shared"
+)
+
+type Handler struct {
+	Store       adminshared.ConfigStore
+	Pool        adminshared.PoolController
+	DS          adminshared.DeepSeekCaller
+	OpenAI      adminshared.OpenAIChatCaller
+	ChatHistory *chathistory.Store
+}
+
+var writeJSON = adminshared.WriteJSON
+var intFrom = adminshared.IntFrom
+
+func fieldString(m map[string]any, key string) string {
+	return adminshared.FieldString(m, key)
+}
+func fieldStringOptional(m map[string]any, key string) (string, bool) {
+	return adminshared.FieldStringOptional(m, key)
+}
+func newRequestError(detail string) error { return adminshared.NewRequestError(detail) }
+func requestErrorDetail(err error) (string, bool) {
+	return adminshared.RequestErrorDetail(err)
+}
@@ -0,0 +1,111 @@
+package users
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	authn "ds2api/internal/auth"
+	"ds2api/internal/config"
+)
+
+const maxAdminTokenTTLDays = 3650
+
+func (h *Handler) listTokens(w http.ResponseWriter, _ *http.Request) {
+	tokens := h.Store.AdminAPITokens()
+	items := make([]map[string]any, 0, len(tokens))
+	for _, token := range tokens {
+		items = append(items, tokenView(token))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items, "total": len(items)})
+}
+
+func (h *Handler) addToken(w http.ResponseWriter, r *http.Request) {
+	var req map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": "invalid json"})
+		return
+	}
+	role := config.NormalizeAdminRole(fieldString(req, "role"))
+	if role == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": "role must be one of viewer, operator, owner"})
+		return
+	}
+	creator, _ := authn.AdminPrincipalFromContext(r.Context())
+	if !authn.AdminRoleAllows(creator.Role, role) {
+		writeJSON(w, http.StatusForbidden, map[string]any{"detail": "cannot issue a token with a higher role than your own"})
+		return
+	}
+	ttlDays := intFrom(req["expires_in_days"])
+	if ttlDays < 0 || ttlDays > maxAdminTokenTTLDays {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": "expires_in_days must be between 0 and 3650"})
+		return
+	}
+	plain, hash, err := authn.GenerateAdminAPIToken()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"detail": err.Error()})
+		return
+	}
+	now := time.Now()
+	token := config.AdminAPIToken{
+		ID:            "tok_" + strings.ReplaceAll(uuid.NewString(), "-", "")[:16],
+		Name:          fieldString(req, "name"),
+		TokenHash:     hash,
+		Role:          role,
+		CreatedBy:     creator.Subject,
+		CreatedAtUnix: now.Unix(),
+	}
+	if ttlDays > 0 {
+		token.ExpiresAtUnix = now.Add(time.Duration(ttlDays) * 24 * time.Hour).Unix()
+	}
+	if err := h.Store.Update(func(c *config.Config) error {
+		c.Admin.APITokens = append(c.Admin.APITokens, token)
+		return config.ValidateAdminConfig(c.Admin)
+	}); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": err.Error()})
+		return
+	}
+	view := tokenView(token)
+	// The plaintext is never stored, so this response is the only chance to read it.
+	view["token"] = plain
+	writeJSON(w, http.StatusOK, map[string]any{"success": true, "token": view})
+}
+
+func (h *Handler) deleteToken(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if decoded, err := url.PathUnescape(id); err == nil {
+		id = decoded
+	}
+	id = strings.TrimSpace(id)
+	err := h.Store.Update(func(c *config.Config) error {
+		for i, token := range c.Admin.APITokens {
+			if token.ID == id {
+				c.Admin.APITokens = append(c.Admin.APITokens[:i], c.Admin.APITokens[i+1:]...)
+				return nil
+			}
+		}
+		return newRequestError("admin api token not found")
+	})
+	if err != nil {
+		writeUserMutationError(w, err, http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"success": true})
+}
+
+func tokenView(token config.AdminAPIToken) map[string]any {
+	return map[string]any{
+		"id":              token.ID,
+		"name":            token.Name,
+		"role":            config.NormalizeAdminRole(token.Role),
+		"created_by":      token.CreatedBy,
+		"created_at_unix": token.CreatedAtUnix,
+		"expires_at_unix": token.ExpiresAtUnix,
+		"expired":         token.ExpiresAtUnix > 0 && token.ExpiresAtUnix <= time.Now().Unix(),
+	}
+}
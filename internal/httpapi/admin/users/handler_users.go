@@ -0,0 +1,193 @@
+package users
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	authn "ds2api/internal/auth"
+	"ds2api/internal/config"
+)
+
+const minAdminUserPasswordLen = 8
+
+func (h *Handler) listUsers(w http.ResponseWriter, _ *http.Request) {
+	users := h.Store.AdminUsers()
+	items := make([]map[string]any, 0, len(users))
+	for _, user := range users {
+		items = append(items, userView(user))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items, "total": len(items)})
+}
+
+func (h *Handler) addUser(w http.ResponseWriter, r *http.Request) {
+	var req map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": "invalid json"})
+		return
+	}
+	username := authn.NormalizeAdminUsername(fieldString(req, "username"))
+	role := config.NormalizeAdminRole(fieldString(req, "role"))
+	password, _ := req["password"].(string)
+	if err := validateUsername(username); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": err.Error()})
+		return
+	}
+	if role == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": "role must be one of viewer, operator, owner"})
+		return
+	}
+	if len(password) < minAdminUserPasswordLen {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": "password must be at least 8 characters"})
+		return
+	}
+	hash, err := authn.HashAdminUserPassword(password)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"detail": err.Error()})
+		return
+	}
+	user := config.AdminUser{Username: username, PasswordHash: hash, Role: role}
+	err = h.Store.Update(func(c *config.Config) error {
+		if findUserIndex(c.Admin.Users, username) >= 0 {
+			return newRequestError("admin user already exists")
+		}
+		c.Admin.Users = append(c.Admin.Users, user)
+		return config.ValidateAdminConfig(c.Admin)
+	})
+	if err != nil {
+		writeUserMutationError(w, err, http.StatusConflict)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"success": true, "user": userView(user)})
+}
+
+func (h *Handler) updateUser(w http.ResponseWriter, r *http.Request) {
+	username := usernameParam(r)
+	var req map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": "invalid json"})
+		return
+	}
+	role, roleSet := fieldStringOptional(req, "role")
+	if roleSet {
+		role = config.NormalizeAdminRole(role)
+		if role == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"detail": "role must be one of viewer, operator, owner"})
+			return
+		}
+	}
+	password, _ := req["password"].(string)
+	passwordHash := ""
+	if password != "" {
+		if len(password) < minAdminUserPasswordLen {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"detail": "password must be at least 8 characters"})
+			return
+		}
+		hash, err := authn.HashAdminUserPassword(password)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"detail": err.Error()})
+			return
+		}
+		passwordHash = hash
+	}
+	disabled, disabledSet := req["disabled"].(bool)
+
+	var updated config.AdminUser
+	err := h.Store.Update(func(c *config.Config) error {
+		idx := findUserIndex(c.Admin.Users, username)
+		if idx < 0 {
+			return newRequestError("admin user not found")
+		}
+		user := c.Admin.Users[idx]
+		revoke := false
+		if roleSet && role != config.NormalizeAdminRole(user.Role) {
+			user.Role = role
+		}
+		if passwordHash != "" {
+			user.PasswordHash = passwordHash
+			revoke = true
+		}
+		if disabledSet && disabled != user.Disabled {
+			user.Disabled = disabled
+			revoke = revoke || disabled
+		}
+		if revoke {
+			user.JWTValidAfterUnix = time.Now().Unix()
+		}
+		c.Admin.Users[idx] = user
+		updated = user
+		return config.ValidateAdminConfig(c.Admin)
+	})
+	if err != nil {
+		writeUserMutationError(w, err, http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"success": true, "user": userView(updated)})
+}
+
+func (h *Handler) deleteUser(w http.ResponseWriter, r *http.Request) {
+	username := usernameParam(r)
+	err := h.Store.Update(func(c *config.Config) error {
+		idx := findUserIndex(c.Admin.Users, username)
+		if idx < 0 {
+			return newRequestError("admin user not found")
+		}
+		c.Admin.Users = append(c.Admin.Users[:idx], c.Admin.Users[idx+1:]...)
+		return nil
+	})
+	if err != nil {
+		writeUserMutationError(w, err, http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"success": true})
+}
+
+func userView(user config.AdminUser) map[string]any {
+	return map[string]any{
+		"username": authn.NormalizeAdminUsername(user.Username),
+		"role":     config.NormalizeAdminRole(user.Role),
+		"disabled": user.Disabled,
+	}
+}
+
+func usernameParam(r *http.Request) string {
+	raw := chi.URLParam(r, "username")
+	if decoded, err := url.PathUnescape(raw); err == nil {
+		raw = decoded
+	}
+	return authn.NormalizeAdminUsername(raw)
+}
+
+func validateUsername(username string) error {
+	if username == "" {
+		return newRequestError("username cannot be empty")
+	}
+	if username == "admin" {
+		return newRequestError(`username "admin" is reserved for the shared admin key`)
+	}
+	if len(username) > 64 || strings.ContainsAny(username, " \t\r\n/") {
+		return newRequestError("username must be at most 64 characters without spaces or slashes")
+	}
+	return nil
+}
+
+func findUserIndex(users []config.AdminUser, username string) int {
+	for i, user := range users {
+		if authn.NormalizeAdminUsername(user.Username) == username {
+			return i
+		}
+	}
+	return -1
+}
+
+func writeUserMutationError(w http.ResponseWriter, err error, requestErrStatus int) {
+	if detail, ok := requestErrorDetail(err); ok {
+		writeJSON(w, requestErrStatus, map[string]any{"detail": detail})
+		return
+	}
+	writeJSON(w, http.StatusBadRequest, map[string]any{"detail": err.Error()})
+}
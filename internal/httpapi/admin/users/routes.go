@@ -0,0 +1,13 @@
+package users
+
+import "github.com/go-chi/chi/v5"
+
+func RegisterRoutes(r chi.Router, h *Handler) {
+	r.Get("/users", h.listUsers)
+	r.Post("/users", h.addUser)
+	r.Put("/users/{username}", h.updateUser)
+	r.Delete("/users/{username}", h.deleteUser)
+	r.Get("/tokens", h.listTokens)
+	r.Post("/tokens", h.addToken)
+	r.Delete("/tokens/{id}", h.deleteToken)
+}
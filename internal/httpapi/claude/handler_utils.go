@@ -57,6 +57,10 @@ func normalizeClaudeMessages(messages []any) []any {
 					if raw := strings.TrimSpace(formatClaudeUnknownBlockForPrompt(b)); raw != "" {
 						textParts = append(textParts, raw)
 					}
+				case "server_tool_use", "web_search_tool_result":
+					// Hosted search blocks echoed back from an earlier reply;
+					// the cited answer text already carries their content.
+					continue
 				case "tool_result":
 					flushText()
 					if toolMsg := normalizeClaudeToolResultToToolMessage(b, state); toolMsg != nil {
@@ -21,13 +21,24 @@ func normalizeClaudeRequest(store ConfigReader, req map[string]any) (claudeNorma
 	if strings.TrimSpace(model) == "" || len(messagesRaw) == 0 {
 		return claudeNormalizedRequest{}, fmt.Errorf("request must include 'model' and 'messages'")
 	}
+	// Only an explicit max_tokens is enforced locally; the default below just
+	// keeps the upstream payload well-formed.
+	maxTokens, err := promptcompat.ParseMaxOutputTokens(req, "max_tokens")
+	if err != nil {
+		return claudeNormalizedRequest{}, err
+	}
+	stops, err := promptcompat.ParseStopSequences(req["stop_sequences"], "stop_sequences")
+	if err != nil {
+		return claudeNormalizedRequest{}, err
+	}
 	if _, ok := req["max_tokens"]; !ok {
 		req["max_tokens"] = 8192
 	}
 	normalizedMessages := normalizeClaudeMessages(messagesRaw)
 	payload := cloneMap(req)
 	payload["messages"] = normalizedMessages
-	toolsRequested, _ := req["tools"].([]any)
+	toolsFiltered, hostedSearch := promptcompat.SplitHostedSearchTools(req["tools"])
+	toolsRequested, _ := toolsFiltered.([]any)
 	payload["messages"] = injectClaudeToolPrompt(payload, normalizedMessages, toolsRequested)
 
 	dsPayload := convertClaudeToDeepSeek(payload, store)
@@ -36,6 +47,9 @@ func normalizeClaudeRequest(store ConfigReader, req map[string]any) (claudeNorma
 	if !ok {
 		searchEnabled = false
 	}
+	if hostedSearch {
+		searchEnabled = true
+	}
 	thinkingEnabled := util.ResolveThinkingEnabled(req, defaultThinkingEnabled)
 	if config.IsNoThinkingModel(dsModel) {
 		thinkingEnabled = false
@@ -60,6 +74,8 @@ func normalizeClaudeRequest(store ConfigReader, req map[string]any) (claudeNorma
 			Stream:          util.ToBool(req["stream"]),
 			Thinking:        thinkingEnabled,
 			Search:          searchEnabled,
+			StopSequences:   stops,
+			MaxOutputTokens: maxTokens,
 		},
 		NormalizedMessages: normalizedMessages,
 	}, nil
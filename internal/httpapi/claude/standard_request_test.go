@@ -118,3 +118,27 @@ func TestNormalizeClaudeRequestInjectsToolsIntoTopLevelSystem(t *testing.T) {
 		t.Fatalf("expected tool prompt injected, got=%q", norm.Standard.FinalPrompt)
 	}
 }
+
+func TestNormalizeClaudeRequestMapsWebSearchServerTool(t *testing.T) {
+	t.Setenv("DS2API_CONFIG_JSON", `{}`)
+	store := config.LoadStore()
+	req := map[string]any{
+		"model": "claude-sonnet-4-5",
+		"messages": []any{
+			map[string]any{"role": "user", "content": "latest news"},
+		},
+		"tools": []any{
+			map[string]any{"type": "web_search_20250305", "name": "web_search", "max_uses": 3},
+		},
+	}
+	norm, err := normalizeClaudeRequest(store, req)
+	if err != nil {
+		t.Fatalf("normalize failed: %v", err)
+	}
+	if !norm.Standard.Search {
+		t.Fatalf("expected web_search server tool to enable search")
+	}
+	if tools, _ := norm.Standard.ToolsRaw.([]any); len(norm.Standard.ToolNames) != 0 || len(tools) != 0 {
+		t.Fatalf("expected hosted tool to be removed, names=%#v tools=%#v", norm.Standard.ToolNames, norm.Standard.ToolsRaw)
+	}
+}
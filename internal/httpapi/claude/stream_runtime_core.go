@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"ds2api/internal/httpapi/openai/shared"
 	"ds2api/internal/responsehistory"
 	"ds2api/internal/sse"
 	streamengine "ds2api/internal/stream"
@@ -38,6 +39,9 @@ type claudeStreamRuntime struct {
 	rawThinking           strings.Builder
 	toolDetectionThinking strings.Builder
 	toolCallsDetected     bool
+	citations             *sse.CitationCollector
+	citationMarks         []shared.CitationMark
+	limiter               *sse.OutputLimiter
 
 	nextBlockIndex     int
 	thinkingBlockOpen  bool
@@ -81,6 +85,7 @@ func newClaudeStreamRuntime(
 		messageID:             fmt.Sprintf("msg_%d", time.Now().UnixNano()),
 		thinkingBlockIndex:    -1,
 		textBlockIndex:        -1,
+		citations:             sse.NewCitationCollector(),
 	}
 }
 
@@ -95,6 +100,7 @@ func (s *claudeStreamRuntime) onParsed(parsed sse.LineResult) streamengine.Parse
 	if parsed.Stop {
 		return streamengine.ParsedDecision{Stop: true}
 	}
+	s.citations.Add(parsed.SearchResults)
 
 	contentSeen := false
 	for _, p := range parsed.ToolDetectionThinkingParts {
@@ -108,139 +114,170 @@ func (s *claudeStreamRuntime) onParsed(parsed sse.LineResult) streamengine.Parse
 		if p.Type == "thinking" {
 			rawTrimmed = sse.TrimContinuationOverlapFromBuilder(&s.rawThinking, p.Text)
 		} else {
-			rawTrimmed = sse.TrimContinuationOverlapFromBuilder(&s.rawText, p.Text)
+			rawTrimmed = s.limiter.Push(sse.TrimContinuationOverlapFromBuilder(&s.rawText, p.Text))
 		}
-		if rawTrimmed == "" {
-			continue
+		if s.emitPart(p.Type, rawTrimmed) {
+			contentSeen = true
 		}
-		if p.Type == "thinking" {
-			s.rawThinking.WriteString(rawTrimmed)
-		} else {
-			s.rawText.WriteString(rawTrimmed)
+		if s.limiter.Done() {
+			break
 		}
-		cleanedText := cleanVisibleOutput(rawTrimmed, s.stripReferenceMarkers)
-		if cleanedText == "" {
-			continue
+	}
+
+	if s.history != nil {
+		s.history.Progress(
+			responsehistory.ThinkingForArchive(s.rawThinking.String(), s.toolDetectionThinking.String(), s.thinking.String()),
+			responsehistory.TextForArchive(s.rawText.String(), s.text.String()),
+		)
+	}
+	if s.limiter.Done() {
+		return streamengine.ParsedDecision{ContentSeen: contentSeen, Stop: true, StopReason: streamengine.StopReasonHandlerRequested}
+	}
+	return streamengine.ParsedDecision{ContentSeen: contentSeen}
+}
+
+// emitPart records one overlap-trimmed part and streams its visible text. It
+// reports whether the part carried visible content.
+func (s *claudeStreamRuntime) emitPart(partType, rawTrimmed string) bool {
+	if rawTrimmed == "" {
+		return false
+	}
+	if partType == "thinking" {
+		s.rawThinking.WriteString(rawTrimmed)
+	} else {
+		s.rawText.WriteString(rawTrimmed)
+	}
+	cleanedText := cleanVisibleOutput(rawTrimmed, s.stripReferenceMarkers)
+	if partType != "thinking" && s.searchEnabled && s.stripReferenceMarkers {
+		s.recordCitationMarks(rawTrimmed, cleanedText)
+	}
+	if cleanedText == "" {
+		return false
+	}
+	if partType != "thinking" && s.searchEnabled && sse.IsCitation(cleanedText) {
+		return false
+	}
+
+	if partType == "thinking" {
+		if !s.thinkingEnabled {
+			return true
 		}
-		if p.Type != "thinking" && s.searchEnabled && sse.IsCitation(cleanedText) {
-			continue
+		trimmed := sse.TrimContinuationOverlapFromBuilder(&s.thinking, cleanedText)
+		if trimmed == "" {
+			return true
 		}
-		contentSeen = true
-
-		if p.Type == "thinking" {
-			if !s.thinkingEnabled {
-				continue
-			}
-			trimmed := sse.TrimContinuationOverlapFromBuilder(&s.thinking, cleanedText)
-			if trimmed == "" {
-				continue
-			}
-			s.thinking.WriteString(trimmed)
-			s.closeTextBlock()
-			if !s.thinkingBlockOpen {
-				s.thinkingBlockIndex = s.nextBlockIndex
-				s.nextBlockIndex++
-				s.send("content_block_start", map[string]any{
-					"type":  "content_block_start",
-					"index": s.thinkingBlockIndex,
-					"content_block": map[string]any{
-						"type":     "thinking",
-						"thinking": "",
-					},
-				})
-				s.thinkingBlockOpen = true
-			}
-			s.send("content_block_delta", map[string]any{
-				"type":  "content_block_delta",
+		s.thinking.WriteString(trimmed)
+		s.closeTextBlock()
+		if !s.thinkingBlockOpen {
+			s.thinkingBlockIndex = s.nextBlockIndex
+			s.nextBlockIndex++
+			s.send("content_block_start", map[string]any{
+				"type":  "content_block_start",
 				"index": s.thinkingBlockIndex,
-				"delta": map[string]any{
-					"type":     "thinking_delta",
-					"thinking": trimmed,
+				"content_block": map[string]any{
+					"type":     "thinking",
+					"thinking": "",
 				},
 			})
-			continue
+			s.thinkingBlockOpen = true
 		}
+		s.send("content_block_delta", map[string]any{
+			"type":  "content_block_delta",
+			"index": s.thinkingBlockIndex,
+			"delta": map[string]any{
+				"type":     "thinking_delta",
+				"thinking": trimmed,
+			},
+		})
+		return true
+	}
 
-		s.text.WriteString(cleanedText)
+	s.text.WriteString(cleanedText)
 
-		if !s.bufferToolContent {
-			s.closeThinkingBlock()
-			if !s.textBlockOpen {
-				s.textBlockIndex = s.nextBlockIndex
-				s.nextBlockIndex++
-				s.send("content_block_start", map[string]any{
-					"type":  "content_block_start",
-					"index": s.textBlockIndex,
-					"content_block": map[string]any{
-						"type": "text",
-						"text": "",
-					},
-				})
-				s.textBlockOpen = true
-			}
-			s.send("content_block_delta", map[string]any{
-				"type":  "content_block_delta",
+	if !s.bufferToolContent {
+		s.closeThinkingBlock()
+		if !s.textBlockOpen {
+			s.textBlockIndex = s.nextBlockIndex
+			s.nextBlockIndex++
+			s.send("content_block_start", map[string]any{
+				"type":  "content_block_start",
 				"index": s.textBlockIndex,
-				"delta": map[string]any{
-					"type": "text_delta",
-					"text": cleanedText,
+				"content_block": map[string]any{
+					"type": "text",
+					"text": "",
 				},
 			})
-			s.textEmitted = true
-			continue
+			s.textBlockOpen = true
 		}
+		s.send("content_block_delta", map[string]any{
+			"type":  "content_block_delta",
+			"index": s.textBlockIndex,
+			"delta": map[string]any{
+				"type": "text_delta",
+				"text": cleanedText,
+			},
+		})
+		s.textEmitted = true
+		return true
+	}
 
-		events := toolstream.ProcessChunk(&s.sieve, rawTrimmed, s.toolNames)
-		for _, evt := range events {
-			if len(evt.ToolCalls) > 0 {
-				s.closeTextBlock()
-				s.toolCallsDetected = true
-				normalized := toolcall.NormalizeParsedToolCallsForSchemas(evt.ToolCalls, s.toolsRaw)
-				for _, tc := range normalized {
-					idx := s.nextBlockIndex
-					s.nextBlockIndex++
-					s.sendToolUseBlock(idx, tc)
-				}
-				continue
-			}
-			if evt.Content == "" {
-				continue
-			}
-			cleaned := cleanVisibleOutput(evt.Content, s.stripReferenceMarkers)
-			if cleaned == "" || (s.searchEnabled && sse.IsCitation(cleaned)) {
-				continue
-			}
-			s.closeThinkingBlock()
-			if !s.textBlockOpen {
-				s.textBlockIndex = s.nextBlockIndex
+	events := toolstream.ProcessChunk(&s.sieve, rawTrimmed, s.toolNames)
+	for _, evt := range events {
+		if len(evt.ToolCalls) > 0 {
+			s.closeTextBlock()
+			s.toolCallsDetected = true
+			normalized := toolcall.NormalizeParsedToolCallsForSchemas(evt.ToolCalls, s.toolsRaw)
+			for _, tc := range normalized {
+				idx := s.nextBlockIndex
 				s.nextBlockIndex++
-				s.send("content_block_start", map[string]any{
-					"type":  "content_block_start",
-					"index": s.textBlockIndex,
-					"content_block": map[string]any{
-						"type": "text",
-						"text": "",
-					},
-				})
-				s.textBlockOpen = true
+				s.sendToolUseBlock(idx, tc)
 			}
-			s.send("content_block_delta", map[string]any{
-				"type":  "content_block_delta",
+			continue
+		}
+		if evt.Content == "" {
+			continue
+		}
+		cleaned := cleanVisibleOutput(evt.Content, s.stripReferenceMarkers)
+		if cleaned == "" || (s.searchEnabled && sse.IsCitation(cleaned)) {
+			continue
+		}
+		s.closeThinkingBlock()
+		if !s.textBlockOpen {
+			s.textBlockIndex = s.nextBlockIndex
+			s.nextBlockIndex++
+			s.send("content_block_start", map[string]any{
+				"type":  "content_block_start",
 				"index": s.textBlockIndex,
-				"delta": map[string]any{
-					"type": "text_delta",
-					"text": cleaned,
+				"content_block": map[string]any{
+					"type": "text",
+					"text": "",
 				},
 			})
-			s.textEmitted = true
+			s.textBlockOpen = true
 		}
+		s.send("content_block_delta", map[string]any{
+			"type":  "content_block_delta",
+			"index": s.textBlockIndex,
+			"delta": map[string]any{
+				"type": "text_delta",
+				"text": cleaned,
+			},
+		})
+		s.textEmitted = true
 	}
+	return true
+}
 
-	if s.history != nil {
-		s.history.Progress(
-			responsehistory.ThinkingForArchive(s.rawThinking.String(), s.toolDetectionThinking.String(), s.thinking.String()),
-			responsehistory.TextForArchive(s.rawText.String(), s.text.String()),
-		)
+// recordCitationMarks remembers where stripped citation markers sat relative
+// to the visible text, so finalize can attach citations to the text block.
+func (s *claudeStreamRuntime) recordCitationMarks(rawText, cleanedText string) {
+	_, marks := shared.StripAndCleanCitationMarkers(rawText, true)
+	base := s.text.Len()
+	for _, m := range marks {
+		if m.Offset > len(cleanedText) {
+			m.Offset = len(cleanedText)
+		}
+		m.Offset += base
+		s.citationMarks = append(s.citationMarks, m)
 	}
-	return streamengine.ParsedDecision{ContentSeen: contentSeen}
 }
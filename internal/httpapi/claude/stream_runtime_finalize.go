@@ -2,6 +2,7 @@ package claude
 
 import (
 	"ds2api/internal/assistantturn"
+	claudefmt "ds2api/internal/format/claude"
 	"ds2api/internal/responsehistory"
 	"ds2api/internal/sse"
 	"ds2api/internal/toolcall"
@@ -63,12 +64,26 @@ func (s *claudeStreamRuntime) sendToolUseBlock(idx int, tc toolcall.ParsedToolCa
 	})
 }
 
+func (s *claudeStreamRuntime) sendCitationDeltas(idx int, citations []map[string]any) {
+	for _, citation := range citations {
+		s.send("content_block_delta", map[string]any{
+			"type":  "content_block_delta",
+			"index": idx,
+			"delta": map[string]any{
+				"type":     "citations_delta",
+				"citation": citation,
+			},
+		})
+	}
+}
+
 func (s *claudeStreamRuntime) finalize(stopReason string) {
 	if s.ended {
 		return
 	}
 	s.ended = true
 
+	s.emitPart("text", s.limiter.Flush())
 	s.closeThinkingBlock()
 
 	if s.bufferToolContent {
@@ -115,16 +130,18 @@ func (s *claudeStreamRuntime) finalize(stopReason string) {
 		}
 	}
 
-	s.closeTextBlock()
-
 	turn := assistantturn.BuildTurnFromStreamSnapshot(assistantturn.StreamSnapshot{
 		RawText:               s.rawText.String(),
 		VisibleText:           s.text.String(),
 		RawThinking:           s.rawThinking.String(),
 		VisibleThinking:       s.thinking.String(),
 		DetectionThinking:     s.toolDetectionThinking.String(),
+		Citations:             s.citations.Citations(),
+		CitationMarks:         s.citationMarks,
 		AlreadyEmittedCalls:   s.toolCallsDetected,
 		AlreadyEmittedToolRaw: s.toolCallsDetected,
+		LimitReason:           s.limiter.Reason(),
+		StopSequence:          s.limiter.StopSequence(),
 	}, assistantturn.BuildOptions{
 		Model:                 s.model,
 		Prompt:                s.promptTokenText,
@@ -133,6 +150,11 @@ func (s *claudeStreamRuntime) finalize(stopReason string) {
 		ToolNames:             s.toolNames,
 		ToolsRaw:              s.toolsRaw,
 	})
+	citations := claudefmt.TextCitations(turn)
+	if s.textBlockOpen {
+		s.sendCitationDeltas(s.textBlockIndex, citations)
+	}
+	s.closeTextBlock()
 	finalText := turn.Text
 	outcome := assistantturn.FinalizeTurn(turn, assistantturn.FinalizeOptions{
 		AlreadyEmittedToolCalls: s.toolCallsDetected,
@@ -165,6 +187,7 @@ func (s *claudeStreamRuntime) finalize(stopReason string) {
 					"text": finalText,
 				},
 			})
+			s.sendCitationDeltas(idx, citations)
 			s.textEmitted = true
 			s.send("content_block_stop", map[string]any{
 				"type":  "content_block_stop",
@@ -173,8 +196,24 @@ func (s *claudeStreamRuntime) finalize(stopReason string) {
 		}
 	}
 
+	stopReason, stopSequence := claudefmt.LimitStopReason(turn, stopReason)
 	if outcome.HasToolCalls {
 		stopReason = "tool_use"
+		stopSequence = nil
+	} else {
+		for _, block := range claudefmt.WebSearchBlocks(fmt.Sprintf("srvtoolu_%d", time.Now().UnixNano()), turn.Citations) {
+			idx := s.nextBlockIndex
+			s.nextBlockIndex++
+			s.send("content_block_start", map[string]any{
+				"type":          "content_block_start",
+				"index":         idx,
+				"content_block": block,
+			})
+			s.send("content_block_stop", map[string]any{
+				"type":  "content_block_stop",
+				"index": idx,
+			})
+		}
 	}
 	if s.history != nil {
 		s.history.Success(
@@ -186,15 +225,19 @@ func (s *claudeStreamRuntime) finalize(stopReason string) {
 		)
 	}
 
+	usage := map[string]any{
+		"output_tokens": outcome.Usage.OutputTokens,
+	}
+	if serverToolUse := claudefmt.ServerToolUsage(turn); serverToolUse != nil {
+		usage["server_tool_use"] = serverToolUse
+	}
 	s.send("message_delta", map[string]any{
 		"type": "message_delta",
 		"delta": map[string]any{
 			"stop_reason":   stopReason,
-			"stop_sequence": nil,
-		},
-		"usage": map[string]any{
-			"output_tokens": outcome.Usage.OutputTokens,
+			"stop_sequence": stopSequence,
 		},
+		"usage": usage,
 	})
 	s.send("message_stop", map[string]any{"type": "message_stop"})
 }
@@ -0,0 +1,195 @@
+package gemini
+
+import (
+	"fmt"
+	"strings"
+
+	"ds2api/internal/promptcompat"
+)
+
+const maxGeminiStopSequences = 5
+
+// parseGeminiToolConfig maps toolConfig.functionCallingConfig onto the shared
+// tool choice policy: AUTO/VALIDATED -> auto, NONE -> none, ANY -> required,
+// narrowed to allowedFunctionNames (a single name forces that function).
+func parseGeminiToolConfig(req map[string]any, toolsRaw any) (promptcompat.ToolChoicePolicy, error) {
+	toolConfig, _ := geminiField(req, "toolConfig", "tool_config").(map[string]any)
+	if toolConfig == nil {
+		return promptcompat.ParseToolChoicePolicy(nil, toolsRaw)
+	}
+	cfg, _ := geminiField(toolConfig, "functionCallingConfig", "function_calling_config").(map[string]any)
+	if cfg == nil {
+		return promptcompat.ParseToolChoicePolicy(nil, toolsRaw)
+	}
+	names, err := geminiStringList(geminiField(cfg, "allowedFunctionNames", "allowed_function_names"), "toolConfig.functionCallingConfig.allowedFunctionNames")
+	if err != nil {
+		return promptcompat.ToolChoicePolicy{}, err
+	}
+	mode := strings.ToUpper(strings.TrimSpace(asString(cfg["mode"])))
+	var choice any
+	switch mode {
+	case "", "MODE_UNSPECIFIED", "AUTO", "VALIDATED":
+		choice = "auto"
+	case "NONE":
+		choice = "none"
+	case "ANY":
+		switch len(names) {
+		case 0:
+			choice = "required"
+		case 1:
+			choice = map[string]any{"type": "function", "name": names[0]}
+		default:
+			choice = map[string]any{"type": "required", "allowed_tools": names}
+		}
+	default:
+		return promptcompat.ToolChoicePolicy{}, fmt.Errorf("unsupported toolConfig.functionCallingConfig.mode: %q", mode)
+	}
+	if len(names) > 0 && mode != "ANY" {
+		return promptcompat.ToolChoicePolicy{}, fmt.Errorf("toolConfig.functionCallingConfig.allowedFunctionNames requires mode ANY")
+	}
+	policy, err := promptcompat.ParseToolChoicePolicy(choice, toolsRaw)
+	if err != nil {
+		return promptcompat.ToolChoicePolicy{}, fmt.Errorf("invalid toolConfig.functionCallingConfig: %w", err)
+	}
+	return policy, nil
+}
+
+// parseGeminiResponseFormat maps responseMimeType/responseSchema onto the
+// shared JSON output instruction.
+func parseGeminiResponseFormat(req map[string]any) (promptcompat.ResponseFormat, error) {
+	cfg := geminiGenerationConfig(req)
+	if cfg == nil {
+		return promptcompat.ResponseFormat{}, nil
+	}
+	mimeType := strings.ToLower(strings.TrimSpace(asString(geminiField(cfg, "responseMimeType", "response_mime_type"))))
+	schema := geminiField(cfg, "responseJsonSchema", "response_json_schema")
+	if schema == nil {
+		schema = geminiField(cfg, "responseSchema", "response_schema")
+	}
+	switch mimeType {
+	case "", "text/plain":
+		if schema != nil {
+			return promptcompat.ResponseFormat{}, fmt.Errorf("generationConfig.responseSchema requires responseMimeType application/json or text/x.enum")
+		}
+		return promptcompat.ResponseFormat{}, nil
+	case "application/json":
+		if schema == nil {
+			return promptcompat.ResponseFormat{Type: promptcompat.ResponseFormatJSONObject}, nil
+		}
+		if _, ok := schema.(map[string]any); !ok {
+			return promptcompat.ResponseFormat{}, fmt.Errorf("generationConfig.responseSchema must be an object")
+		}
+		return promptcompat.ResponseFormat{Type: promptcompat.ResponseFormatJSONSchema, Schema: schema}, nil
+	case "text/x.enum":
+		obj, _ := schema.(map[string]any)
+		values, err := geminiStringList(obj["enum"], "generationConfig.responseSchema.enum")
+		if err != nil {
+			return promptcompat.ResponseFormat{}, err
+		}
+		if len(values) == 0 {
+			return promptcompat.ResponseFormat{}, fmt.Errorf("responseMimeType text/x.enum requires responseSchema.enum")
+		}
+		return promptcompat.ResponseFormat{Type: promptcompat.ResponseFormatEnum, Enum: values}, nil
+	default:
+		return promptcompat.ResponseFormat{}, fmt.Errorf("unsupported generationConfig.responseMimeType: %q", mimeType)
+	}
+}
+
+// validateGeminiGenerationConfig rejects candidate controls the DeepSeek
+// backend cannot honour instead of silently ignoring them.
+func validateGeminiGenerationConfig(req map[string]any) error {
+	cfg := geminiGenerationConfig(req)
+	if cfg == nil {
+		return nil
+	}
+	// Stop sequences are matched verbatim, so whitespace is significant.
+	if raw := geminiField(cfg, "stopSequences", "stop_sequences"); raw != nil {
+		stops, err := promptcompat.ParseStopSequences(raw, "generationConfig.stopSequences")
+		if err != nil {
+			return err
+		}
+		if len(stops) > maxGeminiStopSequences {
+			return fmt.Errorf("generationConfig.stopSequences supports at most %d entries", maxGeminiStopSequences)
+		}
+	}
+	if raw := geminiField(cfg, "candidateCount", "candidate_count"); raw != nil {
+		n, ok := numericAny(raw)
+		if !ok || n < 1 || n != float64(int(n)) {
+			return fmt.Errorf("generationConfig.candidateCount must be a positive integer")
+		}
+	}
+	if enabled, _ := geminiField(cfg, "responseLogprobs", "response_logprobs").(bool); enabled {
+		return fmt.Errorf("generationConfig.responseLogprobs is not supported")
+	}
+	if geminiField(cfg, "logprobs", "logprobs") != nil {
+		return fmt.Errorf("generationConfig.logprobs is not supported")
+	}
+	if raw := geminiField(cfg, "responseModalities", "response_modalities"); raw != nil {
+		modalities, err := geminiStringList(raw, "generationConfig.responseModalities")
+		if err != nil {
+			return err
+		}
+		for _, m := range modalities {
+			if !strings.EqualFold(m, "TEXT") {
+				return fmt.Errorf("generationConfig.responseModalities %q is not supported", m)
+			}
+		}
+	}
+	return nil
+}
+
+func geminiGenerationConfig(req map[string]any) map[string]any {
+	cfg, _ := geminiField(req, "generationConfig", "generation_config").(map[string]any)
+	return cfg
+}
+
+func geminiField(m map[string]any, camel, snake string) any {
+	if v, ok := m[camel]; ok {
+		return v
+	}
+	return m[snake]
+}
+
+func geminiStringList(raw any, field string) ([]string, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	items, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s must be an array of strings", field)
+	}
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok || strings.TrimSpace(s) == "" {
+			return nil, fmt.Errorf("%s must be an array of non-empty strings", field)
+		}
+		out = append(out, strings.TrimSpace(s))
+	}
+	return out, nil
+}
+
+// parseGeminiOutputLimits reads stopSequences and maxOutputTokens for local
+// enforcement.
+func parseGeminiOutputLimits(req map[string]any) ([]string, int, error) {
+	cfg := geminiGenerationConfig(req)
+	if cfg == nil {
+		return nil, 0, nil
+	}
+	stops, err := promptcompat.ParseStopSequences(geminiField(cfg, "stopSequences", "stop_sequences"), "generationConfig.stopSequences")
+	if err != nil {
+		return nil, 0, err
+	}
+	maxTokens, err := promptcompat.ParseMaxOutputTokens(cfg, "maxOutputTokens", "max_output_tokens")
+	if err != nil {
+		return nil, 0, fmt.Errorf("generationConfig.%w", err)
+	}
+	return stops, maxTokens, nil
+}
+
+// geminiCandidateCount returns generationConfig.candidateCount, already
+// validated by validateGeminiGenerationConfig; zero when unset.
+func geminiCandidateCount(req map[string]any) int {
+	n, _ := numericAny(geminiField(geminiGenerationConfig(req), "candidateCount", "candidate_count"))
+	return int(n)
+}
@@ -31,9 +31,29 @@ func normalizeGeminiRequest(store ConfigReader, routeModel string, req map[strin
 		return promptcompat.StandardRequest{}, fmt.Errorf("request must include non-empty contents")
 	}
 
-	toolsRaw := convertGeminiTools(req["tools"])
-	finalPrompt, toolNames := promptcompat.BuildOpenAIPromptForAdapter(messagesRaw, toolsRaw, "", thinkingEnabled)
-	if len(toolNames) == 0 && len(toolsRaw) > 0 {
+	geminiTools, hostedSearch := promptcompat.SplitHostedSearchTools(req["tools"])
+	if hostedSearch {
+		searchEnabled = true
+	}
+	toolsRaw := convertGeminiTools(geminiTools)
+	toolPolicy, err := parseGeminiToolConfig(req, toolsRaw)
+	if err != nil {
+		return promptcompat.StandardRequest{}, err
+	}
+	responseFormat, err := parseGeminiResponseFormat(req)
+	if err != nil {
+		return promptcompat.StandardRequest{}, err
+	}
+	if err := validateGeminiGenerationConfig(req); err != nil {
+		return promptcompat.StandardRequest{}, err
+	}
+	stops, maxTokens, err := parseGeminiOutputLimits(req)
+	if err != nil {
+		return promptcompat.StandardRequest{}, err
+	}
+	promptMessages := promptcompat.InjectResponseFormat(messagesRaw, responseFormat)
+	finalPrompt, toolNames := promptcompat.BuildOpenAIPrompt(promptMessages, toolsRaw, "", toolPolicy, thinkingEnabled)
+	if len(toolNames) == 0 && len(toolsRaw) > 0 && !toolPolicy.IsNone() {
 		toolNames = []string{"__any_tool__"}
 	}
 	passThrough := collectGeminiPassThrough(req)
@@ -43,11 +63,16 @@ func normalizeGeminiRequest(store ConfigReader, routeModel string, req map[strin
 		RequestedModel:  requestedModel,
 		ResolvedModel:   resolvedModel,
 		ResponseModel:   requestedModel,
-		Messages:        messagesRaw,
+		Messages:        promptMessages,
 		PromptTokenText: finalPrompt,
 		ToolsRaw:        toolsRaw,
 		FinalPrompt:     finalPrompt,
 		ToolNames:       toolNames,
+		ToolChoice:      toolPolicy,
+		ResponseFormat:  responseFormat,
+		StopSequences:   stops,
+		MaxOutputTokens: maxTokens,
+		Choices:         geminiCandidateCount(req),
 		Stream:          stream,
 		Thinking:        thinkingEnabled,
 		Search:          searchEnabled,
@@ -1,6 +1,12 @@
 package gemini
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"ds2api/internal/assistantturn"
+	"ds2api/internal/promptcompat"
+)
 
 func TestNormalizeGeminiRequestNoThinkingModelForcesThinkingOff(t *testing.T) {
 	req := map[string]any{
@@ -26,3 +32,138 @@ func TestNormalizeGeminiRequestNoThinkingModelForcesThinkingOff(t *testing.T) {
 		t.Fatalf("expected search=false, got=%v", out.Search)
 	}
 }
+
+func TestNormalizeGeminiRequestGoogleSearchEnablesSearch(t *testing.T) {
+	req := map[string]any{
+		"contents": []any{
+			map[string]any{
+				"role":  "user",
+				"parts": []any{map[string]any{"text": "latest news"}},
+			},
+		},
+		"tools": []any{map[string]any{"googleSearch": map[string]any{}}},
+	}
+	out, err := normalizeGeminiRequest(testGeminiConfig{}, "gemini-2.5-pro", req, false)
+	if err != nil {
+		t.Fatalf("normalizeGeminiRequest error: %v", err)
+	}
+	if !out.Search {
+		t.Fatalf("expected googleSearch tool to enable search")
+	}
+	if len(out.ToolNames) != 0 {
+		t.Fatalf("expected no function tools, got %#v", out.ToolNames)
+	}
+}
+
+func geminiToolRequest(extra map[string]any) map[string]any {
+	req := map[string]any{
+		"contents": []any{
+			map[string]any{
+				"role":  "user",
+				"parts": []any{map[string]any{"text": "weather?"}},
+			},
+		},
+		"tools": []any{map[string]any{
+			"functionDeclarations": []any{
+				map[string]any{"name": "get_weather", "parameters": map[string]any{"type": "object"}},
+				map[string]any{"name": "get_time", "parameters": map[string]any{"type": "object"}},
+			},
+		}},
+	}
+	for k, v := range extra {
+		req[k] = v
+	}
+	return req
+}
+
+func TestNormalizeGeminiRequestMapsFunctionCallingConfig(t *testing.T) {
+	cases := []struct {
+		name      string
+		config    map[string]any
+		mode      promptcompat.ToolChoiceMode
+		forced    string
+		toolNames int
+	}{
+		{name: "auto", config: map[string]any{"mode": "AUTO"}, mode: promptcompat.ToolChoiceAuto, toolNames: 2},
+		{name: "any", config: map[string]any{"mode": "ANY"}, mode: promptcompat.ToolChoiceRequired, toolNames: 2},
+		{name: "any single", config: map[string]any{"mode": "ANY", "allowedFunctionNames": []any{"get_time"}}, mode: promptcompat.ToolChoiceForced, forced: "get_time", toolNames: 1},
+		{name: "none", config: map[string]any{"mode": "NONE"}, mode: promptcompat.ToolChoiceNone, toolNames: 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := geminiToolRequest(map[string]any{"toolConfig": map[string]any{"functionCallingConfig": tc.config}})
+			out, err := normalizeGeminiRequest(testGeminiConfig{}, "gemini-2.5-pro", req, false)
+			if err != nil {
+				t.Fatalf("normalizeGeminiRequest error: %v", err)
+			}
+			if out.ToolChoice.Mode != tc.mode || out.ToolChoice.ForcedName != tc.forced {
+				t.Fatalf("unexpected policy: %#v", out.ToolChoice)
+			}
+			if len(out.ToolNames) != tc.toolNames {
+				t.Fatalf("expected %d tool names, got %#v", tc.toolNames, out.ToolNames)
+			}
+		})
+	}
+}
+
+func TestNormalizeGeminiRequestRejectsInvalidGenerationControls(t *testing.T) {
+	cases := map[string]map[string]any{
+		"unknown mode":        {"toolConfig": map[string]any{"functionCallingConfig": map[string]any{"mode": "SOMETIMES"}}},
+		"undeclared allowed":  {"toolConfig": map[string]any{"functionCallingConfig": map[string]any{"mode": "ANY", "allowedFunctionNames": []any{"nope"}}}},
+		"allowed without any": {"toolConfig": map[string]any{"functionCallingConfig": map[string]any{"mode": "AUTO", "allowedFunctionNames": []any{"get_time"}}}},
+		"schema without mime": {"generationConfig": map[string]any{"responseSchema": map[string]any{"type": "object"}}},
+		"bad mime":            {"generationConfig": map[string]any{"responseMimeType": "text/html"}},
+		"too many stops":      {"generationConfig": map[string]any{"stopSequences": []any{"a", "b", "c", "d", "e", "f"}}},
+		"candidate count":     {"generationConfig": map[string]any{"candidateCount": 0}},
+		"logprobs":            {"generationConfig": map[string]any{"responseLogprobs": true}},
+		"image modality":      {"generationConfig": map[string]any{"responseModalities": []any{"IMAGE"}}},
+	}
+	for name, extra := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := normalizeGeminiRequest(testGeminiConfig{}, "gemini-2.5-pro", geminiToolRequest(extra), false); err == nil {
+				t.Fatalf("expected validation error")
+			}
+		})
+	}
+}
+
+func TestNormalizeGeminiRequestKeepsWhitespaceStopSequences(t *testing.T) {
+	req := geminiToolRequest(map[string]any{
+		"generationConfig": map[string]any{"stopSequences": []any{"\n\n", " END"}},
+	})
+	out, err := normalizeGeminiRequest(testGeminiConfig{}, "gemini-2.5-pro", req, false)
+	if err != nil {
+		t.Fatalf("normalizeGeminiRequest error: %v", err)
+	}
+	if len(out.StopSequences) != 2 || out.StopSequences[0] != "\n\n" || out.StopSequences[1] != " END" {
+		t.Fatalf("expected stop sequences kept verbatim, got %#v", out.StopSequences)
+	}
+}
+
+func TestNormalizeGeminiRequestResponseSchemaInjectsJSONInstruction(t *testing.T) {
+	req := geminiToolRequest(map[string]any{
+		"generationConfig": map[string]any{
+			"responseMimeType": "application/json",
+			"responseSchema":   map[string]any{"type": "object", "properties": map[string]any{"city": map[string]any{"type": "string"}}},
+			"stopSequences":    []any{"END"},
+		},
+	})
+	out, err := normalizeGeminiRequest(testGeminiConfig{}, "gemini-2.5-pro", req, false)
+	if err != nil {
+		t.Fatalf("normalizeGeminiRequest error: %v", err)
+	}
+	if out.ResponseFormat.Type != promptcompat.ResponseFormatJSONSchema {
+		t.Fatalf("unexpected response format: %#v", out.ResponseFormat)
+	}
+	if !strings.Contains(out.FinalPrompt, `"city"`) || !strings.Contains(out.FinalPrompt, "valid JSON") {
+		t.Fatalf("expected schema instruction in prompt, got %q", out.FinalPrompt)
+	}
+	if stops, _ := out.PassThrough["stop"].([]any); len(stops) != 1 {
+		t.Fatalf("expected stop sequences to pass through, got %#v", out.PassThrough)
+	}
+
+	turn := applyGeminiResponseFormat(assistantturn.Turn{Text: "```json\n{\"city\":\"Paris\"}\n```"}, out.ResponseFormat)
+	if turn.Text != `{"city":"Paris"}` {
+		t.Fatalf("expected fenced JSON to be unwrapped, got %q", turn.Text)
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 type AuthResolver interface {
 	Determine(req *http.Request) (*auth.RequestAuth, error)
+	DetermineAdditional(req *http.Request) (*auth.RequestAuth, error)
 	Release(a *auth.RequestAuth)
 }
 
@@ -25,6 +26,8 @@ type ConfigReader interface {
 	ModelAliases() map[string]string
 	CurrentInputFileEnabled() bool
 	CurrentInputFileMinChars() int
+	RuntimeMaxChoices() int
+	RuntimeChoiceAccountPolicy() string
 }
 
 type OpenAIChatRunner interface {
@@ -3,26 +3,29 @@ package gemini
 import "net/http"
 
 func writeGeminiError(w http.ResponseWriter, status int, message string) {
-	errorStatus := "INVALID_ARGUMENT"
+	writeJSON(w, status, map[string]any{
+		"error": map[string]any{
+			"code":    status,
+			"message": message,
+			"status":  geminiErrorStatus(status),
+		},
+	})
+}
+
+func geminiErrorStatus(status int) string {
 	switch status {
 	case http.StatusUnauthorized:
-		errorStatus = "UNAUTHENTICATED"
+		return "UNAUTHENTICATED"
 	case http.StatusForbidden:
-		errorStatus = "PERMISSION_DENIED"
+		return "PERMISSION_DENIED"
 	case http.StatusTooManyRequests:
-		errorStatus = "RESOURCE_EXHAUSTED"
+		return "RESOURCE_EXHAUSTED"
 	case http.StatusNotFound:
-		errorStatus = "NOT_FOUND"
+		return "NOT_FOUND"
 	default:
 		if status >= 500 {
-			errorStatus = "INTERNAL"
+			return "INTERNAL"
 		}
+		return "INVALID_ARGUMENT"
 	}
-	writeJSON(w, status, map[string]any{
-		"error": map[string]any{
-			"code":    status,
-			"message": message,
-			"status":  errorStatus,
-		},
-	})
 }
@@ -16,6 +16,7 @@ import (
 	"ds2api/internal/auth"
 	"ds2api/internal/completionruntime"
 	"ds2api/internal/httpapi/openai/history"
+	"ds2api/internal/httpapi/openai/shared"
 	"ds2api/internal/httpapi/requestbody"
 	"ds2api/internal/promptcompat"
 	"ds2api/internal/responsehistory"
@@ -69,6 +70,9 @@ func (h *Handler) handleGeminiDirect(w http.ResponseWriter, r *http.Request, str
 		return true
 	}
 	stdReq, err := normalizeGeminiRequest(h.Store, routeModel, req, stream)
+	if err == nil {
+		err = shared.ChoiceLimitError(stdReq, h.Store.RuntimeMaxChoices(), "generationConfig.candidateCount")
+	}
 	if err != nil {
 		writeGeminiError(w, http.StatusBadRequest, err.Error())
 		return true
@@ -92,6 +96,10 @@ func (h *Handler) handleGeminiDirect(w http.ResponseWriter, r *http.Request, str
 		Surface:  "gemini.generate_content",
 		Standard: stdReq,
 	})
+	if stdReq.ChoiceCount() > 1 {
+		h.generateGeminiCandidates(w, r, a, stdReq, historySession)
+		return true
+	}
 	if stream {
 		h.handleGeminiDirectStream(w, r, a, stdReq, historySession)
 		return true
@@ -107,6 +115,7 @@ func (h *Handler) handleGeminiDirect(w http.ResponseWriter, r *http.Request, str
 		writeGeminiError(w, outErr.Status, outErr.Message)
 		return true
 	}
+	result.Turn = applyGeminiResponseFormat(result.Turn, stdReq.ResponseFormat)
 	if historySession != nil {
 		historySession.SuccessTurn(http.StatusOK, result.Turn, responsehistory.GenericUsage(result.Turn))
 	}
@@ -137,7 +146,7 @@ func (h *Handler) handleGeminiDirectStream(w http.ResponseWriter, r *http.Reques
 		return
 	}
 	streamReq := start.Request
-	h.handleStreamGenerateContent(w, r, start.Response, streamReq.ResponseModel, streamReq.PromptTokenText, streamReq.Thinking, streamReq.Search, streamReq.ToolNames, streamReq.ToolsRaw, historySession)
+	h.handleStreamGenerateContent(w, r, start.Response, streamReq.ResponseModel, streamReq.PromptTokenText, streamReq.Thinking, streamReq.Search, streamReq.ToolNames, streamReq.ToolsRaw, streamReq.ToolChoice, streamReq.ResponseFormat, streamReq.OutputLimits(), historySession)
 }
 
 func (h *Handler) proxyViaOpenAI(w http.ResponseWriter, r *http.Request, stream bool) bool {
@@ -167,6 +176,7 @@ func (h *Handler) proxyViaOpenAI(w http.ResponseWriter, r *http.Request, stream
 		}
 	}
 	translatedReq = applyGeminiThinkingPolicyToOpenAIRequest(translatedReq, req)
+	translatedReq = applyGeminiHostedSearchToOpenAIRequest(translatedReq, req)
 
 	isVercelPrepare := strings.TrimSpace(r.URL.Query().Get("__stream_prepare")) == "1"
 	isVercelRelease := strings.TrimSpace(r.URL.Query().Get("__stream_release")) == "1"
@@ -258,6 +268,24 @@ func applyGeminiThinkingPolicyToOpenAIRequest(translated []byte, original map[st
 	return out
 }
 
+// applyGeminiHostedSearchToOpenAIRequest carries a googleSearch tool over to
+// the OpenAI request as web_search_options, which the translator drops.
+func applyGeminiHostedSearchToOpenAIRequest(translated []byte, original map[string]any) []byte {
+	if _, hostedSearch := promptcompat.SplitHostedSearchTools(original["tools"]); !hostedSearch {
+		return translated
+	}
+	req := map[string]any{}
+	if err := json.Unmarshal(translated, &req); err != nil {
+		return translated
+	}
+	req["web_search_options"] = map[string]any{}
+	out, err := json.Marshal(req)
+	if err != nil {
+		return translated
+	}
+	return out
+}
+
 func resolveGeminiThinkingOverride(req map[string]any) (bool, bool) {
 	generationConfig, ok := req["generationConfig"].(map[string]any)
 	if !ok {
@@ -360,23 +388,64 @@ func buildGeminiGenerateContentResponse(model, finalPrompt, finalThinking, final
 
 func buildGeminiGenerateContentResponseFromTurn(turn assistantturn.Turn) map[string]any {
 	parts := buildGeminiPartsFromTurn(turn)
+	candidate := map[string]any{
+		"index": 0,
+		"content": map[string]any{
+			"role":  "model",
+			"parts": parts,
+		},
+		"finishReason": geminiFinishReason(turn),
+	}
+	if grounding := buildGeminiGroundingMetadata(turn); grounding != nil {
+		candidate["groundingMetadata"] = grounding
+	}
 	return map[string]any{
-		"candidates": []map[string]any{
-			{
-				"index": 0,
-				"content": map[string]any{
-					"role":  "model",
-					"parts": parts,
-				},
-				"finishReason": "STOP",
+		"candidates":    []map[string]any{candidate},
+		"modelVersion":  turn.Model,
+		"usageMetadata": buildGeminiUsageMetadata(turn.Usage),
+	}
+}
+
+func buildGeminiUsageMetadata(usage assistantturn.Usage) map[string]any {
+	return map[string]any{
+		"promptTokenCount":     usage.InputTokens,
+		"candidatesTokenCount": usage.OutputTokens,
+		"totalTokenCount":      usage.TotalTokens,
+	}
+}
+
+// buildGeminiGroundingMetadata maps search sources to groundingChunks and
+// cited spans to groundingSupports. Segment indexes are UTF-8 byte offsets.
+func buildGeminiGroundingMetadata(turn assistantturn.Turn) map[string]any {
+	if len(turn.Citations) == 0 || len(turn.ToolCalls) > 0 {
+		return nil
+	}
+	chunks := make([]map[string]any, 0, len(turn.Citations))
+	chunkIndex := make(map[int]int, len(turn.Citations))
+	for i, c := range turn.Citations {
+		chunkIndex[c.Index] = i
+		chunks = append(chunks, map[string]any{
+			"web": map[string]any{"uri": c.URL, "title": c.Title},
+		})
+	}
+	supports := make([]map[string]any, 0, len(turn.CitationSpans))
+	for _, span := range turn.CitationSpans {
+		idx, ok := chunkIndex[span.Citation.Index]
+		if !ok || span.Start < 0 || span.Start > span.End || span.End > len(turn.Text) {
+			continue
+		}
+		supports = append(supports, map[string]any{
+			"segment": map[string]any{
+				"startIndex": span.Start,
+				"endIndex":   span.End,
+				"text":       turn.Text[span.Start:span.End],
 			},
-		},
-		"modelVersion": turn.Model,
-		"usageMetadata": map[string]any{
-			"promptTokenCount":     turn.Usage.InputTokens,
-			"candidatesTokenCount": turn.Usage.OutputTokens,
-			"totalTokenCount":      turn.Usage.TotalTokens,
-		},
+			"groundingChunkIndices": []int{idx},
+		})
+	}
+	return map[string]any{
+		"groundingChunks":   chunks,
+		"groundingSupports": supports,
 	}
 }
 
@@ -461,3 +530,12 @@ func buildGeminiPartsFromFinal(finalText, finalThinking string, toolNames []stri
 	}
 	return parts
 }
+
+// geminiFinishReason reports MAX_TOKENS when the local output cap cut the
+// turn short. Stop sequences end the turn normally in Gemini.
+func geminiFinishReason(turn assistantturn.Turn) string {
+	if turn.LimitReason == sse.LimitMaxTokens {
+		return "MAX_TOKENS"
+	}
+	return "STOP"
+}
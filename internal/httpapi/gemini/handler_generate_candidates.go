@@ -0,0 +1,161 @@
+package gemini
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"ds2api/internal/assistantturn"
+	"ds2api/internal/auth"
+	"ds2api/internal/completionruntime"
+	"ds2api/internal/httpapi/openai/shared"
+	"ds2api/internal/promptcompat"
+	"ds2api/internal/responsehistory"
+)
+
+// generateGeminiCandidates serves candidateCount > 1 by running one upstream
+// session per candidate, concurrently when each has its own account. Only
+// candidate 0 is recorded in history.
+func (h *Handler) generateGeminiCandidates(w http.ResponseWriter, r *http.Request, a *auth.RequestAuth, stdReq promptcompat.StandardRequest, historySession *responsehistory.Session) {
+	accounts, release, err := shared.AcquireChoiceAccounts(r, h.Auth, h.Store.RuntimeChoiceAccountPolicy(), a, stdReq)
+	if err != nil {
+		status := http.StatusUnauthorized
+		if err == auth.ErrNoAccount {
+			status = http.StatusTooManyRequests
+		}
+		if historySession != nil {
+			historySession.Error(status, err.Error(), "error", "", "")
+		}
+		writeGeminiError(w, status, err.Error())
+		return
+	}
+	defer release()
+	if stdReq.Stream {
+		h.streamGeminiCandidates(w, r, accounts, stdReq, historySession)
+		return
+	}
+
+	results := make([]completionruntime.NonStreamResult, len(accounts))
+	outErrs := make([]*assistantturn.OutputError, len(accounts))
+	shared.RunChoices(accounts, func(i int, acc *auth.RequestAuth) {
+		results[i], outErrs[i] = completionruntime.ExecuteNonStreamWithRetry(auth.WithAuth(r.Context(), acc), h.DS, acc, stdReq, completionruntime.Options{
+			RetryEnabled:     true,
+			CurrentInputFile: h.Store,
+		})
+	})
+	for _, outErr := range outErrs {
+		if outErr == nil {
+			continue
+		}
+		if historySession != nil {
+			historySession.ErrorTurn(outErr.Status, outErr.Message, outErr.Code, results[0].Turn)
+		}
+		writeGeminiError(w, outErr.Status, outErr.Message)
+		return
+	}
+
+	var out map[string]any
+	candidates := make([]map[string]any, 0, len(results))
+	usage := assistantturn.Usage{}
+	for i, result := range results {
+		turn := applyGeminiResponseFormat(result.Turn, stdReq.ResponseFormat)
+		body := buildGeminiGenerateContentResponseFromTurn(turn)
+		candidate := body["candidates"].([]map[string]any)[0]
+		candidate["index"] = i
+		candidates = append(candidates, candidate)
+		usage = addGeminiCandidateUsage(usage, turn.Usage)
+		if i == 0 {
+			out = body
+			if historySession != nil {
+				historySession.SuccessTurn(http.StatusOK, turn, responsehistory.GenericUsage(turn))
+			}
+		}
+	}
+	out["candidates"] = candidates
+	out["usageMetadata"] = buildGeminiUsageMetadata(usage)
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *Handler) streamGeminiCandidates(w http.ResponseWriter, r *http.Request, accounts []*auth.RequestAuth, stdReq promptcompat.StandardRequest, historySession *responsehistory.Session) {
+	starts := make([]completionruntime.StartResult, len(accounts))
+	startErrs := make([]*assistantturn.OutputError, len(accounts))
+	start := func(i int) bool {
+		starts[i], startErrs[i] = completionruntime.StartCompletion(auth.WithAuth(r.Context(), accounts[i]), h.DS, accounts[i], stdReq, completionruntime.Options{
+			CurrentInputFile: h.Store,
+		})
+		return startErrs[i] == nil
+	}
+	// Candidates past opened start their sessions when their turn comes.
+	opened := shared.StartChoices(accounts, start)
+	if outErr := startErrs[0]; outErr != nil && historySession != nil {
+		historySession.Error(outErr.Status, outErr.Message, outErr.Code, "", "")
+	}
+	started := false
+	for _, outErr := range startErrs[:opened] {
+		started = started || outErr == nil
+	}
+	if !started {
+		writeGeminiError(w, startErrs[0].Status, startErrs[0].Message)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache, no-transform")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	stream := shared.NewChoiceStream(w)
+	runtimes := make([]*geminiStreamRuntime, len(starts))
+	shared.RunChoices(accounts, func(i int, acc *auth.RequestAuth) {
+		cw := stream.Writer(i)
+		if i >= opened {
+			start(i)
+		}
+		if outErr := startErrs[i]; outErr != nil {
+			writeGeminiChoiceError(cw, outErr.Status, outErr.Message)
+			return
+		}
+		var candidateHistory *responsehistory.Session
+		if i == 0 {
+			candidateHistory = historySession
+		}
+		req := starts[i].Request
+		runtimes[i] = h.handleStreamGenerateContent(cw, r.WithContext(auth.WithAuth(r.Context(), acc)), starts[i].Response, req.ResponseModel, req.PromptTokenText, req.Thinking, req.Search, req.ToolNames, req.ToolsRaw, req.ToolChoice, req.ResponseFormat, req.OutputLimits(), candidateHistory)
+	})
+	if r.Context().Err() != nil {
+		return
+	}
+	usage := assistantturn.Usage{}
+	for _, rt := range runtimes {
+		if rt != nil && rt.finalUsage != nil {
+			usage = addGeminiCandidateUsage(usage, *rt.finalUsage)
+		}
+	}
+	b, _ := json.Marshal(map[string]any{
+		"modelVersion":  stdReq.ResponseModel,
+		"usageMetadata": buildGeminiUsageMetadata(usage),
+	})
+	stream.WriteFrame([]byte("data: " + string(b) + "\n\n"))
+}
+
+// addGeminiCandidateUsage sums candidate output; the prompt is shared and
+// counted once.
+func addGeminiCandidateUsage(total, usage assistantturn.Usage) assistantturn.Usage {
+	total.InputTokens = max(total.InputTokens, usage.InputTokens)
+	total.OutputTokens += usage.OutputTokens
+	total.ReasoningTokens += usage.ReasoningTokens
+	total.TotalTokens = total.InputTokens + total.OutputTokens
+	return total
+}
+
+// writeGeminiChoiceError reports a failed candidate as an error chunk tagged
+// with its index; the other candidates keep streaming.
+func writeGeminiChoiceError(cw *shared.ChoiceWriter, status int, message string) {
+	b, _ := json.Marshal(map[string]any{
+		"index": cw.Index,
+		"error": map[string]any{
+			"code":    status,
+			"message": message,
+			"status":  geminiErrorStatus(status),
+		},
+	})
+	_, _ = cw.Write([]byte("data: " + string(b) + "\n\n"))
+}
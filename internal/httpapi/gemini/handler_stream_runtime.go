@@ -9,13 +9,15 @@ import (
 
 	"ds2api/internal/assistantturn"
 	dsprotocol "ds2api/internal/deepseek/protocol"
+	"ds2api/internal/httpapi/openai/shared"
+	"ds2api/internal/promptcompat"
 	"ds2api/internal/responsehistory"
 	"ds2api/internal/sse"
 	streamengine "ds2api/internal/stream"
 )
 
 //nolint:unused // retained for native Gemini stream handling path.
-func (h *Handler) handleStreamGenerateContent(w http.ResponseWriter, r *http.Request, resp *http.Response, model, finalPrompt string, thinkingEnabled, searchEnabled bool, toolNames []string, toolsRaw any, historySessions ...*responsehistory.Session) {
+func (h *Handler) handleStreamGenerateContent(w http.ResponseWriter, r *http.Request, resp *http.Response, model, finalPrompt string, thinkingEnabled, searchEnabled bool, toolNames []string, toolsRaw any, toolChoice promptcompat.ToolChoicePolicy, responseFormat promptcompat.ResponseFormat, outputLimits sse.OutputLimits, historySessions ...*responsehistory.Session) *geminiStreamRuntime {
 	var historySession *responsehistory.Session
 	if len(historySessions) > 0 {
 		historySession = historySessions[0]
@@ -26,8 +28,12 @@ func (h *Handler) handleStreamGenerateContent(w http.ResponseWriter, r *http.Req
 		if historySession != nil {
 			historySession.Error(resp.StatusCode, strings.TrimSpace(string(body)), "error", "", "")
 		}
+		if cw, ok := w.(*shared.ChoiceWriter); ok {
+			writeGeminiChoiceError(cw, resp.StatusCode, strings.TrimSpace(string(body)))
+			return nil
+		}
 		writeGeminiError(w, resp.StatusCode, strings.TrimSpace(string(body)))
-		return
+		return nil
 	}
 
 	w.Header().Set("Content-Type", "text/event-stream")
@@ -37,7 +43,11 @@ func (h *Handler) handleStreamGenerateContent(w http.ResponseWriter, r *http.Req
 
 	rc := http.NewResponseController(w)
 	_, canFlush := w.(http.Flusher)
-	runtime := newGeminiStreamRuntime(w, rc, canFlush, model, finalPrompt, thinkingEnabled, searchEnabled, stripReferenceMarkersEnabled(), toolNames, toolsRaw, historySession)
+	runtime := newGeminiStreamRuntime(w, rc, canFlush, model, finalPrompt, thinkingEnabled, searchEnabled, stripReferenceMarkersEnabled(), toolNames, toolsRaw, toolChoice, responseFormat, outputLimits, historySession)
+	if cw, ok := w.(*shared.ChoiceWriter); ok {
+		runtime.candidateIndex = cw.Index
+		runtime.fanout = true
+	}
 
 	initialType := "text"
 	if thinkingEnabled {
@@ -57,6 +67,7 @@ func (h *Handler) handleStreamGenerateContent(w http.ResponseWriter, r *http.Req
 			runtime.finalize()
 		},
 	})
+	return runtime
 }
 
 //nolint:unused // retained for native Gemini stream handling path.
@@ -65,8 +76,13 @@ type geminiStreamRuntime struct {
 	rc       *http.ResponseController
 	canFlush bool
 
-	model       string
-	finalPrompt string
+	model          string
+	finalPrompt    string
+	candidateIndex int
+	// fanout marks one candidate of a candidateCount > 1 stream; the fan-out
+	// handler sends the aggregated usageMetadata.
+	fanout     bool
+	finalUsage *assistantturn.Usage
 
 	thinkingEnabled       bool
 	searchEnabled         bool
@@ -74,8 +90,11 @@ type geminiStreamRuntime struct {
 	stripReferenceMarkers bool
 	toolNames             []string
 	toolsRaw              any
+	toolChoice            promptcompat.ToolChoicePolicy
+	responseFormat        promptcompat.ResponseFormat
 
 	accumulator       *assistantturn.Accumulator
+	citations         *sse.CitationCollector
 	contentFilter     bool
 	responseMessageID int
 	history           *responsehistory.Session
@@ -93,6 +112,9 @@ func newGeminiStreamRuntime(
 	stripReferenceMarkers bool,
 	toolNames []string,
 	toolsRaw any,
+	toolChoice promptcompat.ToolChoicePolicy,
+	responseFormat promptcompat.ResponseFormat,
+	outputLimits sse.OutputLimits,
 	history *responsehistory.Session,
 ) *geminiStreamRuntime {
 	return &geminiStreamRuntime{
@@ -103,15 +125,19 @@ func newGeminiStreamRuntime(
 		finalPrompt:           finalPrompt,
 		thinkingEnabled:       thinkingEnabled,
 		searchEnabled:         searchEnabled,
-		bufferContent:         len(toolNames) > 0,
+		bufferContent:         len(toolNames) > 0 || responseFormat.Enabled(),
 		stripReferenceMarkers: stripReferenceMarkers,
 		toolNames:             toolNames,
 		toolsRaw:              toolsRaw,
+		toolChoice:            toolChoice,
+		responseFormat:        responseFormat,
 		history:               history,
+		citations:             sse.NewCitationCollector(),
 		accumulator: assistantturn.NewAccumulator(assistantturn.AccumulatorOptions{
 			ThinkingEnabled:       thinkingEnabled,
 			SearchEnabled:         searchEnabled,
 			StripReferenceMarkers: stripReferenceMarkers,
+			Limits:                outputLimits,
 		}),
 	}
 }
@@ -135,6 +161,7 @@ func (s *geminiStreamRuntime) onParsed(parsed sse.LineResult) streamengine.Parse
 	if parsed.ResponseMessageID > 0 {
 		s.responseMessageID = parsed.ResponseMessageID
 	}
+	s.citations.Add(parsed.SearchResults)
 	if parsed.ContentFilter || parsed.ErrorMessage != "" || parsed.Stop {
 		if parsed.ContentFilter {
 			s.contentFilter = true
@@ -143,6 +170,22 @@ func (s *geminiStreamRuntime) onParsed(parsed sse.LineResult) streamengine.Parse
 	}
 
 	accumulated := s.accumulator.Apply(parsed)
+	s.emitAccumulated(accumulated)
+	if s.history != nil {
+		rawText, text, rawThinking, thinking, detectionThinking := s.accumulator.Snapshot()
+		s.history.Progress(
+			responsehistory.ThinkingForArchive(rawThinking, detectionThinking, thinking),
+			responsehistory.TextForArchive(rawText, text),
+		)
+	}
+	if accumulated.Limited {
+		return streamengine.ParsedDecision{ContentSeen: accumulated.ContentSeen, Stop: true, StopReason: streamengine.StopReasonHandlerRequested}
+	}
+	return streamengine.ParsedDecision{ContentSeen: accumulated.ContentSeen}
+}
+
+//nolint:unused // retained for native Gemini stream handling path.
+func (s *geminiStreamRuntime) emitAccumulated(accumulated shared.StreamAccumulatorResult) {
 	for _, p := range accumulated.Parts {
 		if p.Type == "thinking" {
 			if p.VisibleText == "" || s.bufferContent {
@@ -151,7 +194,7 @@ func (s *geminiStreamRuntime) onParsed(parsed sse.LineResult) streamengine.Parse
 			s.sendChunk(map[string]any{
 				"candidates": []map[string]any{
 					{
-						"index": 0,
+						"index": s.candidateIndex,
 						"content": map[string]any{
 							"role":  "model",
 							"parts": []map[string]any{{"text": p.VisibleText, "thought": true}},
@@ -171,7 +214,7 @@ func (s *geminiStreamRuntime) onParsed(parsed sse.LineResult) streamengine.Parse
 		s.sendChunk(map[string]any{
 			"candidates": []map[string]any{
 				{
-					"index": 0,
+					"index": s.candidateIndex,
 					"content": map[string]any{
 						"role":  "model",
 						"parts": []map[string]any{{"text": p.VisibleText}},
@@ -181,18 +224,12 @@ func (s *geminiStreamRuntime) onParsed(parsed sse.LineResult) streamengine.Parse
 			"modelVersion": s.model,
 		})
 	}
-	if s.history != nil {
-		rawText, text, rawThinking, thinking, detectionThinking := s.accumulator.Snapshot()
-		s.history.Progress(
-			responsehistory.ThinkingForArchive(rawThinking, detectionThinking, thinking),
-			responsehistory.TextForArchive(rawText, text),
-		)
-	}
-	return streamengine.ParsedDecision{ContentSeen: accumulated.ContentSeen}
 }
 
 //nolint:unused // retained for native Gemini stream handling path.
 func (s *geminiStreamRuntime) finalize() {
+	s.emitAccumulated(s.accumulator.Flush())
+	limitReason, stopSequence := s.accumulator.Limit()
 	rawText, text, rawThinking, thinking, detectionThinking := s.accumulator.Snapshot()
 	turn := assistantturn.BuildTurnFromStreamSnapshot(assistantturn.StreamSnapshot{
 		RawText:           rawText,
@@ -200,8 +237,12 @@ func (s *geminiStreamRuntime) finalize() {
 		RawThinking:       rawThinking,
 		VisibleThinking:   thinking,
 		DetectionThinking: detectionThinking,
+		Citations:         s.citations.Citations(),
+		CitationMarks:     s.accumulator.CitationMarks(),
 		ContentFilter:     s.contentFilter,
 		ResponseMessageID: s.responseMessageID,
+		LimitReason:       limitReason,
+		StopSequence:      stopSequence,
 	}, assistantturn.BuildOptions{
 		Model:                 s.model,
 		Prompt:                s.finalPrompt,
@@ -209,8 +250,23 @@ func (s *geminiStreamRuntime) finalize() {
 		StripReferenceMarkers: s.stripReferenceMarkers,
 		ToolNames:             s.toolNames,
 		ToolsRaw:              s.toolsRaw,
+		ToolChoice:            s.toolChoice,
 	})
+	turn = applyGeminiResponseFormat(turn, s.responseFormat)
 	outcome := assistantturn.FinalizeTurn(turn, assistantturn.FinalizeOptions{})
+	if outcome.Error != nil && outcome.Error.Code == "tool_choice_violation" {
+		if s.history != nil {
+			s.history.ErrorTurn(outcome.Error.Status, outcome.Error.Message, outcome.Error.Code, turn)
+		}
+		s.sendChunk(map[string]any{
+			"error": map[string]any{
+				"code":    outcome.Error.Status,
+				"message": outcome.Error.Message,
+				"status":  "FAILED_PRECONDITION",
+			},
+		})
+		return
+	}
 	if s.history != nil {
 		s.history.Success(
 			http.StatusOK,
@@ -226,7 +282,7 @@ func (s *geminiStreamRuntime) finalize() {
 		s.sendChunk(map[string]any{
 			"candidates": []map[string]any{
 				{
-					"index": 0,
+					"index": s.candidateIndex,
 					"content": map[string]any{
 						"role":  "model",
 						"parts": parts,
@@ -237,24 +293,26 @@ func (s *geminiStreamRuntime) finalize() {
 		})
 	}
 
-	s.sendChunk(map[string]any{
-		"candidates": []map[string]any{
-			{
-				"index": 0,
-				"content": map[string]any{
-					"role": "model",
-					"parts": []map[string]any{
-						{"text": ""},
-					},
-				},
-				"finishReason": "STOP",
+	candidate := map[string]any{
+		"index": s.candidateIndex,
+		"content": map[string]any{
+			"role": "model",
+			"parts": []map[string]any{
+				{"text": ""},
 			},
 		},
+		"finishReason": geminiFinishReason(turn),
+	}
+	if grounding := buildGeminiGroundingMetadata(turn); grounding != nil {
+		candidate["groundingMetadata"] = grounding
+	}
+	s.finalUsage = &outcome.Usage
+	chunk := map[string]any{
+		"candidates":   []map[string]any{candidate},
 		"modelVersion": s.model,
-		"usageMetadata": map[string]any{
-			"promptTokenCount":     outcome.Usage.InputTokens,
-			"candidatesTokenCount": outcome.Usage.OutputTokens,
-			"totalTokenCount":      outcome.Usage.TotalTokens,
-		},
-	})
+	}
+	if !s.fanout {
+		chunk["usageMetadata"] = buildGeminiUsageMetadata(outcome.Usage)
+	}
+	s.sendChunk(chunk)
 }
@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/go-chi/chi/v5"
@@ -16,6 +17,8 @@ import (
 	"ds2api/internal/auth"
 	"ds2api/internal/chathistory"
 	dsclient "ds2api/internal/deepseek/client"
+	"ds2api/internal/promptcompat"
+	"ds2api/internal/sse"
 )
 
 type testGeminiConfig struct{}
@@ -23,6 +26,10 @@ type testGeminiConfig struct{}
 func (testGeminiConfig) ModelAliases() map[string]string { return nil }
 func (testGeminiConfig) CurrentInputFileEnabled() bool   { return true }
 func (testGeminiConfig) CurrentInputFileMinChars() int   { return 0 }
+func (testGeminiConfig) RuntimeMaxChoices() int          { return 4 }
+func (testGeminiConfig) RuntimeChoiceAccountPolicy() string {
+	return "same"
+}
 
 type testGeminiAuth struct {
 	a   *auth.RequestAuth
@@ -44,6 +51,10 @@ func (m testGeminiAuth) Determine(_ *http.Request) (*auth.RequestAuth, error) {
 	}, nil
 }
 
+func (m testGeminiAuth) DetermineAdditional(r *http.Request) (*auth.RequestAuth, error) {
+	return m.Determine(r)
+}
+
 func (testGeminiAuth) Release(_ *auth.RequestAuth) {}
 
 //nolint:unused // reserved test double for native Gemini DS-call path coverage.
@@ -345,7 +356,7 @@ func TestNativeStreamGenerateContentEmitsThoughtParts(t *testing.T) {
 	rec := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodPost, "/v1beta/models/gemini-2.5-pro:streamGenerateContent", nil)
 
-	h.handleStreamGenerateContent(rec, req, resp, "gemini-2.5-pro", "prompt", true, false, nil, nil)
+	h.handleStreamGenerateContent(rec, req, resp, "gemini-2.5-pro", "prompt", true, false, nil, nil, promptcompat.DefaultToolChoicePolicy(), promptcompat.ResponseFormat{}, sse.OutputLimits{})
 
 	frames := extractGeminiSSEFrames(t, rec.Body.String())
 	if len(frames) < 2 {
@@ -369,6 +380,38 @@ func TestNativeStreamGenerateContentEmitsThoughtParts(t *testing.T) {
 	}
 }
 
+func TestNativeStreamGenerateContentEmitsGroundingMetadata(t *testing.T) {
+	h := &Handler{}
+	resp := makeGeminiUpstreamResponse(
+		`data: {"p":"response/fragments/-1/results","v":[{"url":"https://example.com/p","title":"Paris","cite_index":1}]}`,
+		`data: {"p":"response/content","v":"Paris is big.[citation:1]"}`,
+		`data: [DONE]`,
+	)
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1beta/models/gemini-2.5-pro:streamGenerateContent", nil)
+
+	h.handleStreamGenerateContent(rec, req, resp, "gemini-2.5-pro", "prompt", false, true, nil, nil, promptcompat.DefaultToolChoicePolicy(), promptcompat.ResponseFormat{}, sse.OutputLimits{})
+
+	frames := extractGeminiSSEFrames(t, rec.Body.String())
+	last := frames[len(frames)-1]
+	candidates, _ := last["candidates"].([]any)
+	c0, _ := candidates[0].(map[string]any)
+	grounding, _ := c0["groundingMetadata"].(map[string]any)
+	chunks, _ := grounding["groundingChunks"].([]any)
+	supports, _ := grounding["groundingSupports"].([]any)
+	if len(chunks) != 1 || len(supports) != 1 {
+		t.Fatalf("expected one grounding chunk and support, got %#v", grounding)
+	}
+	web, _ := chunks[0].(map[string]any)["web"].(map[string]any)
+	if web["uri"] != "https://example.com/p" || web["title"] != "Paris" {
+		t.Fatalf("unexpected grounding chunk: %#v", chunks[0])
+	}
+	segment, _ := supports[0].(map[string]any)["segment"].(map[string]any)
+	if segment["text"] != "Paris is big." || segment["startIndex"] != float64(0) || segment["endIndex"] != float64(13) {
+		t.Fatalf("unexpected grounding segment: %#v", segment)
+	}
+}
+
 func TestBuildGeminiPartsFromFinalIncludesThoughtPart(t *testing.T) {
 	parts := buildGeminiPartsFromFinal("answer", "think", nil)
 	if len(parts) != 2 {
@@ -542,3 +585,98 @@ func geminiPartsFromFrame(frame map[string]any) []map[string]any {
 	}
 	return parts
 }
+
+// geminiCandidatesDS answers concurrent completion calls with a fresh stream.
+type geminiCandidatesDS struct {
+	testGeminiDS
+	mu    sync.Mutex
+	lines []string
+	calls int
+}
+
+func (m *geminiCandidatesDS) CallCompletion(_ context.Context, _ *auth.RequestAuth, _ map[string]any, _ string, _ int) (*http.Response, error) {
+	m.mu.Lock()
+	m.calls++
+	m.mu.Unlock()
+	return makeGeminiUpstreamResponse(m.lines...), nil
+}
+
+func TestGenerateContentCandidateCountFansOut(t *testing.T) {
+	ds := &geminiCandidatesDS{lines: []string{`data: {"p":"response/content","v":"ok"}`, `data: [DONE]`}}
+	h := &Handler{Store: testGeminiConfig{}, Auth: testGeminiAuth{}, DS: ds}
+	r := chi.NewRouter()
+	RegisterRoutes(r, h)
+
+	reqBody := `{"contents":[{"role":"user","parts":[{"text":"hi"}]}],"generationConfig":{"candidateCount":3}}`
+	req := httptest.NewRequest(http.MethodPost, "/v1beta/models/gemini-2.5-pro:generateContent", strings.NewReader(reqBody))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	var out map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	candidates, _ := out["candidates"].([]any)
+	if len(candidates) != 3 || ds.calls != 3 {
+		t.Fatalf("expected 3 candidates from 3 sessions, got %d candidates, %d calls", len(candidates), ds.calls)
+	}
+	for i, raw := range candidates {
+		if idx := raw.(map[string]any)["index"]; idx != float64(i) {
+			t.Fatalf("candidate %d has index %v", i, idx)
+		}
+	}
+	usage, _ := out["usageMetadata"].(map[string]any)
+	if usage["totalTokenCount"] != usage["promptTokenCount"].(float64)+usage["candidatesTokenCount"].(float64) {
+		t.Fatalf("unexpected usage: %v", usage)
+	}
+}
+
+func TestStreamGenerateContentCandidateCountTagsIndexes(t *testing.T) {
+	ds := &geminiCandidatesDS{lines: []string{`data: {"p":"response/content","v":"ok"}`, `data: [DONE]`}}
+	h := &Handler{Store: testGeminiConfig{}, Auth: testGeminiAuth{}, DS: ds}
+	r := chi.NewRouter()
+	RegisterRoutes(r, h)
+
+	reqBody := `{"contents":[{"role":"user","parts":[{"text":"hi"}]}],"generationConfig":{"candidateCount":2}}`
+	req := httptest.NewRequest(http.MethodPost, "/v1beta/models/gemini-2.5-pro:streamGenerateContent?alt=sse", strings.NewReader(reqBody))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	frames := extractGeminiSSEFrames(t, rec.Body.String())
+	finished := map[float64]bool{}
+	usageFrames := 0
+	for _, frame := range frames {
+		if _, ok := frame["usageMetadata"]; ok {
+			usageFrames++
+		}
+		candidates, _ := frame["candidates"].([]any)
+		for _, raw := range candidates {
+			candidate := raw.(map[string]any)
+			if candidate["finishReason"] != nil {
+				finished[candidate["index"].(float64)] = true
+			}
+		}
+	}
+	if !finished[0] || !finished[1] || usageFrames != 1 {
+		t.Fatalf("expected both candidates to finish and one usage frame, got %v / %d body=%s", finished, usageFrames, rec.Body.String())
+	}
+}
+
+func TestGenerateContentRejectsCandidateCountAboveMax(t *testing.T) {
+	ds := &geminiCandidatesDS{}
+	h := &Handler{Store: testGeminiConfig{}, Auth: testGeminiAuth{}, DS: ds}
+	r := chi.NewRouter()
+	RegisterRoutes(r, h)
+
+	reqBody := `{"contents":[{"role":"user","parts":[{"text":"hi"}]}],"generationConfig":{"candidateCount":5}}`
+	req := httptest.NewRequest(http.MethodPost, "/v1beta/models/gemini-2.5-pro:generateContent", strings.NewReader(reqBody))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest || ds.calls != 0 {
+		t.Fatalf("expected 400 without upstream calls, got %d calls=%d body=%s", rec.Code, ds.calls, rec.Body.String())
+	}
+}
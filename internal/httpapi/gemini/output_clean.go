@@ -1,6 +1,10 @@
 package gemini
 
-import textclean "ds2api/internal/textclean"
+import (
+	"ds2api/internal/assistantturn"
+	"ds2api/internal/promptcompat"
+	textclean "ds2api/internal/textclean"
+)
 
 //nolint:unused // retained for native Gemini output post-processing path.
 func cleanVisibleOutput(text string, stripReferenceMarkers bool) string {
@@ -12,3 +16,21 @@ func cleanVisibleOutput(text string, stripReferenceMarkers bool) string {
 	}
 	return text
 }
+
+// applyGeminiResponseFormat unwraps fenced JSON when a structured response
+// was requested. Cited spans no longer line up once the text moves, so they
+// are dropped in that case.
+func applyGeminiResponseFormat(turn assistantturn.Turn, format promptcompat.ResponseFormat) assistantturn.Turn {
+	if !format.Enabled() || len(turn.ToolCalls) > 0 {
+		return turn
+	}
+	cleaned := textclean.StripJSONCodeFence(turn.Text)
+	if format.Type == promptcompat.ResponseFormatEnum {
+		cleaned = textclean.StripEnumQuotes(cleaned)
+	}
+	if cleaned != turn.Text {
+		turn.Text = cleaned
+		turn.CitationSpans = nil
+	}
+	return turn
+}
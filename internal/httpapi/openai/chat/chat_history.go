@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"ds2api/internal/accesslog"
 	"ds2api/internal/auth"
 	"ds2api/internal/chathistory"
 	"ds2api/internal/config"
@@ -14,7 +15,10 @@ import (
 	"ds2api/internal/promptcompat"
 )
 
+// chatHistorySession also copies the final outcome to the request's access
+// log entry, which is why it exists even when history capture is off.
 type chatHistorySession struct {
+	access      *accesslog.Entry
 	store       *chathistory.Store
 	entryID     string
 	startedAt   time.Time
@@ -25,14 +29,12 @@ type chatHistorySession struct {
 }
 
 func startChatHistory(store *chathistory.Store, r *http.Request, a *auth.RequestAuth, stdReq promptcompat.StandardRequest) *chatHistorySession {
-	if store == nil || r == nil || a == nil {
+	if r == nil || a == nil {
 		return nil
 	}
-	if !store.Enabled() {
-		return nil
-	}
-	if !shouldCaptureChatHistory(r) {
-		return nil
+	access := accesslog.FromContext(r.Context())
+	if store == nil || !store.Enabled() || !shouldCaptureChatHistory(r) {
+		return accessOnlyChatHistory(access, stdReq.FinalPrompt)
 	}
 	entry, err := store.Start(chathistory.StartParams{
 		CallerID:    strings.TrimSpace(a.CallerID),
@@ -57,6 +59,7 @@ func startChatHistory(store *chathistory.Store, r *http.Request, a *auth.Request
 		FinalPrompt: stdReq.FinalPrompt,
 	}
 	session := &chatHistorySession{
+		access:      access,
 		store:       store,
 		entryID:     entry.ID,
 		startedAt:   time.Now(),
@@ -67,13 +70,20 @@ func startChatHistory(store *chathistory.Store, r *http.Request, a *auth.Request
 	if err != nil {
 		if entry.ID == "" {
 			config.Logger.Warn("[chat_history] start failed", "error", err)
-			return nil
+			return accessOnlyChatHistory(access, stdReq.FinalPrompt)
 		}
 		config.Logger.Warn("[chat_history] start persisted in memory after write failure", "error", err)
 	}
 	return session
 }
 
+func accessOnlyChatHistory(access *accesslog.Entry, finalPrompt string) *chatHistorySession {
+	if access == nil {
+		return nil
+	}
+	return &chatHistorySession{access: access, finalPrompt: finalPrompt}
+}
+
 func shouldCaptureChatHistory(r *http.Request) bool {
 	if r == nil {
 		return false
@@ -140,7 +150,11 @@ func (s *chatHistorySession) progress(thinking, content string) {
 }
 
 func (s *chatHistorySession) success(statusCode int, thinking, content, finishReason string, usage map[string]any) {
-	if s == nil || s.store == nil || s.disabled {
+	if s == nil {
+		return
+	}
+	s.access.Finish(finishReason, usage)
+	if s.store == nil || s.disabled {
 		return
 	}
 	s.persistUpdate(chathistory.UpdateParams{
@@ -156,7 +170,11 @@ func (s *chatHistorySession) success(statusCode int, thinking, content, finishRe
 }
 
 func (s *chatHistorySession) error(statusCode int, message, finishReason, thinking, content string) {
-	if s == nil || s.store == nil || s.disabled {
+	if s == nil {
+		return
+	}
+	s.access.Finish(finishReason, nil)
+	if s.store == nil || s.disabled {
 		return
 	}
 	s.persistUpdate(chathistory.UpdateParams{
@@ -172,7 +190,12 @@ func (s *chatHistorySession) error(statusCode int, message, finishReason, thinki
 }
 
 func (s *chatHistorySession) stopped(thinking, content, finishReason string) {
-	if s == nil || s.store == nil || s.disabled {
+	if s == nil {
+		return
+	}
+	usage := openaifmt.BuildChatUsage(s.finalPrompt, thinking, content)
+	s.access.Finish(finishReason, usage)
+	if s.store == nil || s.disabled {
 		return
 	}
 	s.persistUpdate(chathistory.UpdateParams{
@@ -182,7 +205,7 @@ func (s *chatHistorySession) stopped(thinking, content, finishReason string) {
 		StatusCode:       http.StatusOK,
 		ElapsedMs:        time.Since(s.startedAt).Milliseconds(),
 		FinishReason:     finishReason,
-		Usage:            openaifmt.BuildChatUsage(s.finalPrompt, thinking, content),
+		Usage:            usage,
 		Completed:        true,
 	})
 }
@@ -0,0 +1,53 @@
+package chat
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestChatCompletionsResumableStreamReplaysAfterLastEventID(t *testing.T) {
+	ds := &autoDeleteModeDSStub{
+		resp: makeOpenAISSEHTTPResponse(
+			`data: {"p":"response/content","v":"hello"}`,
+			`data: {"p":"response/content","v":" world"}`,
+			"data: [DONE]",
+		),
+	}
+	h := &Handler{Store: mockOpenAIConfig{autoDeleteMode: "none"}, Auth: streamStatusAuthStub{}, DS: ds}
+
+	reqBody := `{"model":"deepseek-v4-flash","messages":[{"role":"user","content":"hi"}],"stream":true,"stream_options":{"resumable":true}}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(reqBody))
+	req.Header.Set("Authorization", "Bearer direct-token")
+	rec := httptest.NewRecorder()
+	h.ChatCompletions(rec, req)
+
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.Contains(body, "id: session-id:1\n") || !strings.Contains(body, "data: [DONE]") {
+		t.Fatalf("expected event ids on the live stream, status=%d body=%s", rec.Code, body)
+	}
+
+	resumeReq := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(reqBody))
+	resumeReq.Header.Set("Authorization", "Bearer direct-token")
+	resumeReq.Header.Set("Last-Event-ID", "session-id:1")
+	resumeRec := httptest.NewRecorder()
+	h.ChatCompletions(resumeRec, resumeReq)
+
+	resumed := resumeRec.Body.String()
+	if strings.Contains(resumed, "id: session-id:1\n") || !strings.Contains(resumed, "id: session-id:2\n") {
+		t.Fatalf("expected replay to start after event 1, body=%s", resumed)
+	}
+	if !strings.HasSuffix(body, resumed[strings.Index(resumed, "id: session-id:2"):]) {
+		t.Fatalf("expected replay to match the original tail\noriginal=%s\nresumed=%s", body, resumed)
+	}
+
+	missing := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	missing.Header.Set("Authorization", "Bearer direct-token")
+	missing.Header.Set("Last-Event-ID", "unknown:3")
+	missingRec := httptest.NewRecorder()
+	h.ChatCompletions(missingRec, missing)
+	if missingRec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown stream, got %d", missingRec.Code)
+	}
+}
@@ -19,14 +19,21 @@ type chatStreamRuntime struct {
 	rc       *http.ResponseController
 	canFlush bool
 
-	completionID  string
-	created       int64
-	model         string
-	finalPrompt   string
-	refFileTokens int
-	toolNames     []string
-	toolsRaw      any
-	toolChoice    promptcompat.ToolChoicePolicy
+	completionID string
+	created      int64
+	choiceIndex  int
+	// fanout marks one choice of an n > 1 stream: the fan-out handler owns
+	// the aggregated usage chunk and the [DONE] terminator.
+	fanout bool
+	// textCompletion renders legacy /v1/completions chunks: visible text
+	// only, as text_completion choices.
+	textCompletion bool
+	model          string
+	finalPrompt    string
+	refFileTokens  int
+	toolNames      []string
+	toolsRaw       any
+	toolChoice     promptcompat.ToolChoicePolicy
 
 	thinkingEnabled       bool
 	searchEnabled         bool
@@ -42,6 +49,7 @@ type chatStreamRuntime struct {
 	streamToolCallIDs map[int]string
 	streamToolNames   map[int]string
 	accumulator       shared.StreamAccumulator
+	citations         *sse.CitationCollector
 	responseMessageID int
 
 	finalThinking     string
@@ -114,6 +122,7 @@ func newChatStreamRuntime(
 		emitEarlyToolDeltas:   emitEarlyToolDeltas,
 		streamToolCallIDs:     map[int]string{},
 		streamToolNames:       map[int]string{},
+		citations:             sse.NewCitationCollector(),
 		accumulator: shared.StreamAccumulator{
 			ThinkingEnabled:       thinkingEnabled,
 			SearchEnabled:         searchEnabled,
@@ -144,6 +153,12 @@ func (s *chatStreamRuntime) sendDelta(delta map[string]any) {
 	if len(delta) == 0 {
 		return
 	}
+	if s.textCompletion {
+		if text, _ := delta["content"].(string); text != "" {
+			s.sendChunk(openaifmt.BuildTextCompletionChunk(s.completionID, s.created, s.model, []map[string]any{openaifmt.BuildTextCompletionChoice(s.choiceIndex, text, "")}, nil))
+		}
+		return
+	}
 	if !s.firstChunkSent {
 		delta["role"] = "assistant"
 		s.firstChunkSent = true
@@ -152,12 +167,15 @@ func (s *chatStreamRuntime) sendDelta(delta map[string]any) {
 		s.completionID,
 		s.created,
 		s.model,
-		[]map[string]any{openaifmt.BuildChatStreamDeltaChoice(0, delta)},
+		[]map[string]any{openaifmt.BuildChatStreamDeltaChoice(s.choiceIndex, delta)},
 		nil,
 	))
 }
 
 func (s *chatStreamRuntime) sendDone() {
+	if s.fanout {
+		return
+	}
 	_, _ = s.w.Write([]byte("data: [DONE]\n\n"))
 	if s.canFlush {
 		_ = s.rc.Flush()
@@ -168,7 +186,7 @@ func (s *chatStreamRuntime) sendFailedChunk(status int, message, code string) {
 	s.finalErrorStatus = status
 	s.finalErrorMessage = message
 	s.finalErrorCode = code
-	s.sendChunk(map[string]any{
+	chunk := map[string]any{
 		"status_code": status,
 		"error": map[string]any{
 			"message": message,
@@ -176,7 +194,11 @@ func (s *chatStreamRuntime) sendFailedChunk(status int, message, code string) {
 			"code":    code,
 			"param":   nil,
 		},
-	})
+	}
+	if s.fanout {
+		chunk["index"] = s.choiceIndex
+	}
+	s.sendChunk(chunk)
 	s.sendDone()
 }
 
@@ -216,6 +238,8 @@ func (s *chatStreamRuntime) finalize(finishReason string, deferEmptyOutput bool)
 	s.finalErrorStatus = 0
 	s.finalErrorMessage = ""
 	s.finalErrorCode = ""
+	s.emitAccumulated(s.accumulator.Flush())
+	limitReason, stopSequence := s.accumulator.Limiter.Reason(), s.accumulator.Limiter.StopSequence()
 	finalThinking := s.accumulator.Thinking.String()
 	finalToolDetectionThinking := s.accumulator.ToolDetectionThinking.String()
 	finalText := s.accumulator.Text.String()
@@ -225,10 +249,14 @@ func (s *chatStreamRuntime) finalize(finishReason string, deferEmptyOutput bool)
 		RawThinking:           s.accumulator.RawThinking.String(),
 		VisibleThinking:       finalThinking,
 		DetectionThinking:     finalToolDetectionThinking,
+		Citations:             s.citations.Citations(),
+		CitationMarks:         s.accumulator.CitationMarks,
 		ContentFilter:         finishReason == "content_filter",
 		ResponseMessageID:     s.responseMessageID,
 		AlreadyEmittedCalls:   s.toolCallsEmitted,
 		AlreadyEmittedToolRaw: s.toolCallsDoneEmitted,
+		LimitReason:           limitReason,
+		StopSequence:          stopSequence,
 	}, assistantturn.BuildOptions{
 		Model:                 s.model,
 		Prompt:                s.finalPrompt,
@@ -285,16 +313,27 @@ func (s *chatStreamRuntime) finalize(finishReason string, deferEmptyOutput bool)
 		s.sendFailedChunk(status, message, code)
 		return true
 	}
+	if annotations := shared.ChatURLAnnotations(turn.Text, turn.CitationSpans); len(annotations) > 0 && len(turn.ToolCalls) == 0 {
+		s.sendDelta(map[string]any{"annotations": annotations})
+	}
 	usage := assistantturn.OpenAIChatUsage(turn)
 	s.finalFinishReason = outcome.FinishReason
 	s.finalUsage = usage
-	s.sendChunk(openaifmt.BuildChatStreamChunk(
-		s.completionID,
-		s.created,
-		s.model,
-		[]map[string]any{openaifmt.BuildChatStreamFinishChoice(0, outcome.FinishReason)},
-		usage,
-	))
+	chunkUsage := usage
+	if s.fanout {
+		chunkUsage = nil
+	}
+	if s.textCompletion {
+		s.sendChunk(openaifmt.BuildTextCompletionChunk(s.completionID, s.created, s.model, []map[string]any{openaifmt.BuildTextCompletionChoice(s.choiceIndex, "", outcome.FinishReason)}, chunkUsage))
+	} else {
+		s.sendChunk(openaifmt.BuildChatStreamChunk(
+			s.completionID,
+			s.created,
+			s.model,
+			[]map[string]any{openaifmt.BuildChatStreamFinishChoice(s.choiceIndex, outcome.FinishReason)},
+			chunkUsage,
+		))
+	}
 	s.sendDone()
 	return true
 }
@@ -306,6 +345,7 @@ func (s *chatStreamRuntime) onParsed(parsed sse.LineResult) streamengine.ParsedD
 	if parsed.ResponseMessageID > 0 {
 		s.responseMessageID = parsed.ResponseMessageID
 	}
+	s.citations.Add(parsed.SearchResults)
 	if parsed.ContentFilter {
 		if strings.TrimSpace(s.accumulator.Text.String()) == "" {
 			return streamengine.ParsedDecision{Stop: true, StopReason: streamengine.StopReason("content_filter")}
@@ -319,8 +359,16 @@ func (s *chatStreamRuntime) onParsed(parsed sse.LineResult) streamengine.ParsedD
 		return streamengine.ParsedDecision{Stop: true, StopReason: streamengine.StopReasonHandlerRequested}
 	}
 
-	batch := chatDeltaBatch{runtime: s}
 	accumulated := s.accumulator.Apply(parsed)
+	s.emitAccumulated(accumulated)
+	if accumulated.Limited {
+		return streamengine.ParsedDecision{ContentSeen: accumulated.ContentSeen, Stop: true, StopReason: streamengine.StopReasonHandlerRequested}
+	}
+	return streamengine.ParsedDecision{ContentSeen: accumulated.ContentSeen}
+}
+
+func (s *chatStreamRuntime) emitAccumulated(accumulated shared.StreamAccumulatorResult) {
+	batch := chatDeltaBatch{runtime: s}
 	for _, p := range accumulated.Parts {
 		if p.Type == "thinking" {
 			batch.append("reasoning_content", p.VisibleText)
@@ -379,5 +427,4 @@ func (s *chatStreamRuntime) onParsed(parsed sse.LineResult) streamengine.ParsedD
 		}
 	}
 	batch.flush()
-	return streamengine.ParsedDecision{ContentSeen: accumulated.ContentSeen}
 }
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"ds2api/internal/promptcompat"
+	"ds2api/internal/sse"
 )
 
 func TestChatStreamKeepAliveUsesCommentOnly(t *testing.T) {
@@ -75,3 +76,89 @@ func TestChatStreamFinalizeEnforcesRequiredToolChoice(t *testing.T) {
 		t.Fatalf("expected tool choice error in stream body, got %s", rec.Body.String())
 	}
 }
+
+func TestChatStreamFinalizeEmitsURLCitationAnnotations(t *testing.T) {
+	rec := httptest.NewRecorder()
+	runtime := newChatStreamRuntime(
+		rec,
+		http.NewResponseController(rec),
+		true,
+		"chatcmpl-test",
+		time.Now().Unix(),
+		"deepseek-v4-flash-search",
+		"prompt",
+		false,
+		true,
+		true,
+		nil,
+		nil,
+		promptcompat.DefaultToolChoicePolicy(),
+		false,
+		false,
+	)
+
+	runtime.onParsed(sse.LineResult{Parsed: true, SearchResults: []sse.SearchResult{{URL: "https://example.com/p", Title: "Paris", CiteIndex: 1, HasCiteIndex: true}}})
+	runtime.onParsed(sse.LineResult{Parsed: true, Parts: []sse.ContentPart{{Type: "text", Text: "Paris is big.[citation:1]"}}})
+	runtime.finalize("stop", false)
+
+	frames, _ := parseSSEDataFrames(t, rec.Body.String())
+	var annotations []any
+	for _, frame := range frames {
+		choices, _ := frame["choices"].([]any)
+		for _, choice := range choices {
+			delta, _ := choice.(map[string]any)["delta"].(map[string]any)
+			if list, ok := delta["annotations"].([]any); ok {
+				annotations = list
+			}
+		}
+	}
+	if len(annotations) != 1 {
+		t.Fatalf("expected one annotation, body=%s", rec.Body.String())
+	}
+	citation, _ := annotations[0].(map[string]any)["url_citation"].(map[string]any)
+	if citation["url"] != "https://example.com/p" || citation["title"] != "Paris" || citation["start_index"] != float64(0) || citation["end_index"] != float64(13) {
+		t.Fatalf("unexpected annotation: %#v", citation)
+	}
+}
+
+func TestChatStreamMaxTokensFinishesWithLength(t *testing.T) {
+	rec := httptest.NewRecorder()
+	runtime := newChatStreamRuntime(
+		rec,
+		http.NewResponseController(rec),
+		true,
+		"chatcmpl-test",
+		time.Now().Unix(),
+		"deepseek-v4-flash",
+		"prompt",
+		false,
+		false,
+		true,
+		nil,
+		nil,
+		promptcompat.DefaultToolChoicePolicy(),
+		false,
+		false,
+	)
+	runtime.accumulator.Limiter = sse.NewOutputLimiter(sse.OutputLimits{MaxTokens: 2, Model: "deepseek-v4-flash"})
+
+	decision := runtime.onParsed(sse.LineResult{Parsed: true, Parts: []sse.ContentPart{{Type: "text", Text: strings.Repeat("word ", 50)}}})
+	if !decision.Stop {
+		t.Fatalf("expected the upstream to be cancelled once the cap is reached")
+	}
+	runtime.finalize("stop", false)
+
+	frames, _ := parseSSEDataFrames(t, rec.Body.String())
+	finishReason := ""
+	for _, frame := range frames {
+		choices, _ := frame["choices"].([]any)
+		for _, choice := range choices {
+			if reason, ok := choice.(map[string]any)["finish_reason"].(string); ok && reason != "" {
+				finishReason = reason
+			}
+		}
+	}
+	if finishReason != "length" {
+		t.Fatalf("expected finish_reason length, body=%s", rec.Body.String())
+	}
+}
@@ -7,11 +7,13 @@ import (
 	"strings"
 	"time"
 
+	"ds2api/internal/accesslog"
 	"ds2api/internal/assistantturn"
 	"ds2api/internal/auth"
 	"ds2api/internal/config"
 	dsprotocol "ds2api/internal/deepseek/protocol"
 	openaifmt "ds2api/internal/format/openai"
+	"ds2api/internal/httpapi/openai/shared"
 	"ds2api/internal/promptcompat"
 	"ds2api/internal/sse"
 	streamengine "ds2api/internal/stream"
@@ -23,6 +25,7 @@ type chatNonStreamResult struct {
 	thinking              string
 	toolDetectionThinking string
 	text                  string
+	citationSpans         []shared.CitationSpan
 	contentFilter         bool
 	detectedCalls         int
 	body                  map[string]any
@@ -60,6 +63,7 @@ func (h *Handler) handleNonStreamWithRetry(w http.ResponseWriter, ctx context.Co
 		detected := detectAssistantToolCalls(result.rawText, result.text, result.rawThinking, result.toolDetectionThinking, toolNames)
 		result.detectedCalls = len(detected.Calls)
 		result.body = openaifmt.BuildChatCompletionWithToolCalls(completionID, model, usagePrompt, result.thinking, result.text, detected.Calls, toolsRaw)
+		shared.AttachChatAnnotations(result.body, result.text, result.citationSpans)
 		addRefFileTokensToUsage(result.body, refFileTokens)
 		result.finishReason = chatFinishReason(result.body)
 		if !shouldRetryChatNonStream(result, attempts) {
@@ -68,6 +72,7 @@ func (h *Handler) handleNonStreamWithRetry(w http.ResponseWriter, ctx context.Co
 		}
 
 		attempts++
+		accesslog.FromContext(ctx).AddRetry()
 		config.Logger.Info("[openai_empty_retry] attempting synthetic retry", "surface", "chat.completions", "stream", false, "retry_attempt", attempts, "parent_message_id", result.responseMessageID)
 		retryPow, powErr := h.DS.GetPow(ctx, a, 3)
 		if powErr != nil {
@@ -105,12 +110,14 @@ func (h *Handler) collectChatNonStreamAttempt(w http.ResponseWriter, resp *http.
 		ToolsRaw:      toolsRaw,
 	})
 	respBody := openaifmt.BuildChatCompletionWithToolCalls(completionID, model, usagePrompt, turn.Thinking, turn.Text, turn.ToolCalls, toolsRaw)
+	shared.AttachChatAnnotations(respBody, turn.Text, turn.CitationSpans)
 	return chatNonStreamResult{
 		rawThinking:           result.Thinking,
 		rawText:               result.Text,
 		thinking:              turn.Thinking,
 		toolDetectionThinking: result.ToolDetectionThinking,
 		text:                  turn.Text,
+		citationSpans:         turn.CitationSpans,
 		contentFilter:         result.ContentFilter,
 		detectedCalls:         len(turn.ToolCalls),
 		body:                  respBody,
@@ -161,26 +168,28 @@ func shouldRetryChatNonStream(result chatNonStreamResult, attempts int) bool {
 		strings.TrimSpace(result.text) == ""
 }
 
-func (h *Handler) handleStreamWithRetry(w http.ResponseWriter, r *http.Request, a *auth.RequestAuth, resp *http.Response, payload map[string]any, pow, completionID, model, finalPrompt string, refFileTokens int, thinkingEnabled, searchEnabled bool, toolNames []string, toolsRaw any, toolChoice promptcompat.ToolChoicePolicy, historySession *chatHistorySession) {
-	streamRuntime, initialType, ok := h.prepareChatStreamRuntime(w, resp, completionID, model, finalPrompt, refFileTokens, thinkingEnabled, searchEnabled, toolNames, toolsRaw, toolChoice, historySession)
+func (h *Handler) handleStreamWithRetry(w http.ResponseWriter, r *http.Request, a *auth.RequestAuth, resp *http.Response, payload map[string]any, pow, completionID, model, finalPrompt string, refFileTokens int, thinkingEnabled, searchEnabled bool, toolNames []string, toolsRaw any, toolChoice promptcompat.ToolChoicePolicy, outputLimits sse.OutputLimits, historySession *chatHistorySession) *chatStreamRuntime {
+	streamRuntime, initialType, ok := h.prepareChatStreamRuntime(w, resp, completionID, model, finalPrompt, refFileTokens, thinkingEnabled, searchEnabled, toolNames, toolsRaw, toolChoice, outputLimits, historySession)
 	if !ok {
-		return
+		return nil
 	}
+	defer streamRuntime.toolSieve.RecordSpan(r.Context())
 	attempts := 0
 	currentResp := resp
 	for {
 		terminalWritten, retryable := h.consumeChatStreamAttempt(r, currentResp, streamRuntime, initialType, thinkingEnabled, historySession, attempts < emptyOutputRetryMaxAttempts())
 		if terminalWritten {
 			logChatStreamTerminal(streamRuntime, attempts)
-			return
+			return streamRuntime
 		}
 		if !retryable || !emptyOutputRetryEnabled() || attempts >= emptyOutputRetryMaxAttempts() {
 			streamRuntime.finalize("stop", false)
 			recordChatStreamHistory(streamRuntime, historySession)
 			config.Logger.Info("[openai_empty_retry] terminal empty output", "surface", "chat.completions", "stream", true, "retry_attempts", attempts, "success_source", "none")
-			return
+			return streamRuntime
 		}
 		attempts++
+		accesslog.FromContext(r.Context()).AddRetry()
 		config.Logger.Info("[openai_empty_retry] attempting synthetic retry", "surface", "chat.completions", "stream", true, "retry_attempt", attempts, "parent_message_id", streamRuntime.responseMessageID)
 		retryPow, powErr := h.DS.GetPow(r.Context(), a, 3)
 		if powErr != nil {
@@ -191,26 +200,34 @@ func (h *Handler) handleStreamWithRetry(w http.ResponseWriter, r *http.Request,
 		if err != nil {
 			failChatStreamRetry(streamRuntime, historySession, http.StatusInternalServerError, "Failed to get completion.", "error")
 			config.Logger.Warn("[openai_empty_retry] retry request failed", "surface", "chat.completions", "stream", true, "retry_attempt", attempts, "error", err)
-			return
+			return streamRuntime
 		}
 		if nextResp.StatusCode != http.StatusOK {
 			defer func() { _ = nextResp.Body.Close() }()
 			body, _ := io.ReadAll(nextResp.Body)
 			failChatStreamRetry(streamRuntime, historySession, nextResp.StatusCode, string(body), "error")
-			return
+			return streamRuntime
 		}
 		streamRuntime.finalPrompt = usagePromptWithEmptyOutputRetry(finalPrompt, attempts)
 		currentResp = nextResp
 	}
 }
 
-func (h *Handler) prepareChatStreamRuntime(w http.ResponseWriter, resp *http.Response, completionID, model, finalPrompt string, refFileTokens int, thinkingEnabled, searchEnabled bool, toolNames []string, toolsRaw any, toolChoice promptcompat.ToolChoicePolicy, historySession *chatHistorySession) (*chatStreamRuntime, string, bool) {
+func (h *Handler) prepareChatStreamRuntime(w http.ResponseWriter, resp *http.Response, completionID, model, finalPrompt string, refFileTokens int, thinkingEnabled, searchEnabled bool, toolNames []string, toolsRaw any, toolChoice promptcompat.ToolChoicePolicy, outputLimits sse.OutputLimits, historySession *chatHistorySession) (*chatStreamRuntime, string, bool) {
 	if resp.StatusCode != http.StatusOK {
 		defer func() { _ = resp.Body.Close() }()
 		body, _ := io.ReadAll(resp.Body)
 		if historySession != nil {
 			historySession.error(resp.StatusCode, string(body), "error", "", "")
 		}
+		if cw, ok := w.(*shared.ChoiceWriter); ok {
+			writeChatChoiceError(cw, resp.StatusCode, string(body), "error")
+			return nil, "", false
+		}
+		if lw, ok := w.(*legacyChoiceWriter); ok {
+			lw.stream.fail(resp.StatusCode, string(body), "error")
+			return nil, "", false
+		}
 		writeOpenAIError(w, resp.StatusCode, string(body))
 		return nil, "", false
 	}
@@ -234,6 +251,16 @@ func (h *Handler) prepareChatStreamRuntime(w http.ResponseWriter, resp *http.Res
 		len(toolNames) > 0, h.toolcallFeatureMatchEnabled() && h.toolcallEarlyEmitHighConfidence(),
 	)
 	streamRuntime.refFileTokens = refFileTokens
+	streamRuntime.accumulator.Limiter = sse.NewOutputLimiter(outputLimits)
+	if cw, ok := w.(*shared.ChoiceWriter); ok {
+		streamRuntime.choiceIndex = cw.Index
+		streamRuntime.fanout = true
+	}
+	if lw, ok := w.(*legacyChoiceWriter); ok {
+		streamRuntime.choiceIndex = lw.index
+		streamRuntime.fanout = true
+		streamRuntime.textCompletion = true
+	}
 	return streamRuntime, initialType, true
 }
 
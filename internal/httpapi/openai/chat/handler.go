@@ -8,10 +8,13 @@ import (
 
 	"ds2api/internal/auth"
 	"ds2api/internal/chathistory"
+	"ds2api/internal/completionruntime"
+	"ds2api/internal/contextwindow"
 	"ds2api/internal/httpapi/openai/files"
 	"ds2api/internal/httpapi/openai/history"
 	"ds2api/internal/httpapi/openai/shared"
 	"ds2api/internal/promptcompat"
+	"ds2api/internal/streamresume"
 	"ds2api/internal/textclean"
 	"ds2api/internal/toolcall"
 	"ds2api/internal/toolstream"
@@ -29,6 +32,9 @@ type Handler struct {
 
 	leaseMu      sync.Mutex
 	streamLeases map[string]streamLease
+
+	resumeMu  sync.Mutex
+	resumeHub *streamresume.Hub
 }
 
 type streamLease struct {
@@ -36,6 +42,15 @@ type streamLease struct {
 	ExpiresAt time.Time
 }
 
+func (h *Handler) getResumeHub() *streamresume.Hub {
+	h.resumeMu.Lock()
+	defer h.resumeMu.Unlock()
+	if h.resumeHub == nil {
+		h.resumeHub = shared.NewResumeHub(h.Store)
+	}
+	return h.resumeHub
+}
+
 func stripReferenceMarkersEnabled() bool {
 	return textclean.StripReferenceMarkersEnabled()
 }
@@ -53,6 +68,16 @@ func (h *Handler) applyCurrentInputFile(ctx context.Context, a *auth.RequestAuth
 	return out, nil
 }
 
+func (h *Handler) fitContextWindow(ctx context.Context, a *auth.RequestAuth, stdReq promptcompat.StandardRequest) (promptcompat.StandardRequest, contextwindow.Report, error) {
+	if h == nil {
+		return stdReq, contextwindow.Report{}, nil
+	}
+	summarize := completionruntime.ContextSummarizer(h.DS, h.Store, a, func(ctx context.Context, sessionID string) {
+		h.autoDeleteRemoteSession(ctx, a, sessionID)
+	})
+	return history.FitContextWindow(ctx, h.Store, stdReq, summarize)
+}
+
 func (h *Handler) preprocessInlineFileInputs(ctx context.Context, a *auth.RequestAuth, req map[string]any) error {
 	if h == nil {
 		return nil
@@ -90,6 +115,14 @@ func writeOpenAIInlineFileError(w http.ResponseWriter, err error) {
 	files.WriteInlineFileError(w, err)
 }
 
+func setContextWindowHeader(w http.ResponseWriter, report contextwindow.Report) {
+	history.SetContextWindowHeader(w, report)
+}
+
+func writeContextLimitError(w http.ResponseWriter, err error) {
+	history.WriteContextLimitError(w, err)
+}
+
 func mapCurrentInputFileError(err error) (int, string) {
 	return history.MapError(err)
 }
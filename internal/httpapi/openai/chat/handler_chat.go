@@ -14,9 +14,11 @@ import (
 	"ds2api/internal/config"
 	dsprotocol "ds2api/internal/deepseek/protocol"
 	openaifmt "ds2api/internal/format/openai"
+	"ds2api/internal/httpapi/openai/shared"
 	"ds2api/internal/promptcompat"
 	"ds2api/internal/sse"
 	streamengine "ds2api/internal/stream"
+	"ds2api/internal/streamresume"
 )
 
 func (h *Handler) ChatCompletions(w http.ResponseWriter, r *http.Request) {
@@ -32,6 +34,10 @@ func (h *Handler) ChatCompletions(w http.ResponseWriter, r *http.Request) {
 		h.handleVercelStreamPrepare(w, r)
 		return
 	}
+	if lastEventID := strings.TrimSpace(r.Header.Get("Last-Event-ID")); lastEventID != "" {
+		h.resumeChatStream(w, r, lastEventID)
+		return
+	}
 
 	a, err := h.Auth.Determine(r)
 	if err != nil {
@@ -66,10 +72,19 @@ func (h *Handler) ChatCompletions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	stdReq, err := promptcompat.NormalizeOpenAIChatRequest(h.Store, req, requestTraceID(r))
+	if err == nil {
+		err = shared.ChoiceLimitError(stdReq, h.Store.RuntimeMaxChoices(), "n")
+	}
 	if err != nil {
 		writeOpenAIError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	stdReq, contextReport, err := h.fitContextWindow(r.Context(), a, stdReq)
+	setContextWindowHeader(w, contextReport)
+	if err != nil {
+		writeContextLimitError(w, err)
+		return
+	}
 	stdReq, err = h.applyCurrentInputFile(r.Context(), a, stdReq)
 	if err != nil {
 		status, message := mapCurrentInputFileError(err)
@@ -77,6 +92,10 @@ func (h *Handler) ChatCompletions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	historySession := startChatHistory(h.ChatHistory, r, a, stdReq)
+	if stdReq.ChoiceCount() > 1 {
+		h.chatCompletionsChoices(w, r, a, stdReq, historySession)
+		return
+	}
 
 	if !stdReq.Stream {
 		result, outErr := completionruntime.ExecuteNonStreamWithRetry(r.Context(), h.DS, a, stdReq, completionruntime.Options{
@@ -92,6 +111,7 @@ func (h *Handler) ChatCompletions(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		respBody := openaifmt.BuildChatCompletionWithToolCalls(result.SessionID, stdReq.ResponseModel, result.Turn.Prompt, result.Turn.Thinking, result.Turn.Text, result.Turn.ToolCalls, stdReq.ToolsRaw)
+		shared.AttachChatAnnotations(respBody, result.Turn.Text, result.Turn.CitationSpans)
 		respBody["usage"] = assistantturn.OpenAIChatUsage(result.Turn)
 		finishReason := assistantturn.FinalizeTurn(result.Turn, assistantturn.FinalizeOptions{}).FinishReason
 		if historySession != nil {
@@ -114,7 +134,30 @@ func (h *Handler) ChatCompletions(w http.ResponseWriter, r *http.Request) {
 	}
 	streamReq := start.Request
 	refFileTokens := streamReq.RefFileTokens
-	h.handleStreamWithRetry(w, r, a, start.Response, start.Payload, start.Pow, sessionID, streamReq.ResponseModel, streamReq.PromptTokenText, refFileTokens, streamReq.Thinking, streamReq.Search, streamReq.ToolNames, streamReq.ToolsRaw, streamReq.ToolChoice, historySession)
+	generate := func(w http.ResponseWriter, r *http.Request) {
+		h.handleStreamWithRetry(w, r, a, start.Response, start.Payload, start.Pow, sessionID, streamReq.ResponseModel, streamReq.PromptTokenText, refFileTokens, streamReq.Thinking, streamReq.Search, streamReq.ToolNames, streamReq.ToolsRaw, streamReq.ToolChoice, streamReq.OutputLimits(), historySession)
+	}
+	if streamReq.Resumable {
+		shared.RunResumable(w, r, h.getResumeHub(), a.CallerID, sessionID, generate)
+		return
+	}
+	generate(w, r)
+}
+
+// resumeChatStream replays a resumable stream for a client reconnecting with
+// Last-Event-ID. The request body is ignored.
+func (h *Handler) resumeChatStream(w http.ResponseWriter, r *http.Request, lastEventID string) {
+	a, err := h.Auth.DetermineCaller(r)
+	if err != nil {
+		writeOpenAIError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	streamID, seq, ok := streamresume.ParseEventID(lastEventID)
+	if !ok || streamID == "" {
+		writeOpenAIError(w, http.StatusBadRequest, "Last-Event-ID must look like <completion_id>:<sequence>.")
+		return
+	}
+	shared.ServeResumedStream(w, r, h.getResumeHub(), a.CallerID, streamID, seq)
 }
 
 func (h *Handler) autoDeleteRemoteSession(ctx context.Context, a *auth.RequestAuth, sessionID string) {
@@ -181,6 +224,7 @@ func (h *Handler) handleNonStream(w http.ResponseWriter, resp *http.Response, co
 		return
 	}
 	respBody := openaifmt.BuildChatCompletionWithToolCalls(completionID, model, finalPrompt, turn.Thinking, turn.Text, turn.ToolCalls, toolsRaw)
+	shared.AttachChatAnnotations(respBody, turn.Text, turn.CitationSpans)
 	respBody["usage"] = assistantturn.OpenAIChatUsage(turn)
 	if historySession != nil {
 		historySession.success(http.StatusOK, historyThinkingForArchive(turn.RawThinking, turn.DetectionThinking, turn.Thinking), historyTextForArchive(turn.RawText, turn.Text), outcome.FinishReason, assistantturn.OpenAIChatUsage(turn))
@@ -0,0 +1,252 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"ds2api/internal/assistantturn"
+	"ds2api/internal/auth"
+	"ds2api/internal/completionruntime"
+	openaifmt "ds2api/internal/format/openai"
+	"ds2api/internal/httpapi/openai/shared"
+	"ds2api/internal/promptcompat"
+	"ds2api/internal/util"
+)
+
+// chatCompletionsChoices serves n > 1 by running one upstream session per
+// choice and merging them into indexed choices. Choices run concurrently
+// when each has its own account. Only choice 0 is recorded in chat history.
+func (h *Handler) chatCompletionsChoices(w http.ResponseWriter, r *http.Request, a *auth.RequestAuth, stdReq promptcompat.StandardRequest, historySession *chatHistorySession) {
+	accounts, release, err := shared.AcquireChoiceAccounts(r, h.Auth, h.Store.RuntimeChoiceAccountPolicy(), a, stdReq)
+	if err != nil {
+		status := http.StatusUnauthorized
+		if err == auth.ErrNoAccount {
+			status = http.StatusTooManyRequests
+		}
+		if historySession != nil {
+			historySession.error(status, err.Error(), "error", "", "")
+		}
+		writeOpenAIError(w, status, err.Error())
+		return
+	}
+	defer release()
+	if !stdReq.Stream {
+		h.chatChoicesNonStream(w, r, accounts, stdReq, historySession)
+		return
+	}
+	h.chatChoicesStream(w, r, accounts, stdReq, historySession)
+}
+
+func (h *Handler) chatChoicesNonStream(w http.ResponseWriter, r *http.Request, accounts []*auth.RequestAuth, stdReq promptcompat.StandardRequest, historySession *chatHistorySession) {
+	results := make([]completionruntime.NonStreamResult, len(accounts))
+	outErrs := make([]*assistantturn.OutputError, len(accounts))
+	shared.RunChoices(accounts, func(i int, acc *auth.RequestAuth) {
+		results[i], outErrs[i] = completionruntime.ExecuteNonStreamWithRetry(auth.WithAuth(r.Context(), acc), h.DS, acc, stdReq, completionruntime.Options{
+			RetryEnabled:     true,
+			CurrentInputFile: h.Store,
+		})
+	})
+	sessionIDs := make([]string, len(results))
+	for i, result := range results {
+		sessionIDs[i] = result.SessionID
+	}
+	defer h.autoDeleteChoiceSessions(r.Context(), accounts, sessionIDs)
+
+	if outErr := outErrs[0]; outErr != nil && historySession != nil {
+		turn := results[0].Turn
+		historySession.error(outErr.Status, outErr.Message, outErr.Code, historyThinkingForArchive(turn.RawThinking, turn.DetectionThinking, turn.Thinking), historyTextForArchive(turn.RawText, turn.Text))
+	}
+	ok := -1
+	for i, outErr := range outErrs {
+		if outErr == nil {
+			ok = i
+			break
+		}
+	}
+	if ok < 0 {
+		outErr := outErrs[0]
+		writeOpenAIErrorWithCode(w, outErr.Status, outErr.Message, outErr.Code)
+		return
+	}
+
+	// Failed choices keep their index with finish_reason "error", like the
+	// error chunks of the streaming path.
+	completionID := results[ok].SessionID
+	choices := make([]map[string]any, 0, len(results))
+	usages := make([]map[string]any, 0, len(results))
+	for i, result := range results {
+		if outErr := outErrs[i]; outErr != nil {
+			choices = append(choices, map[string]any{
+				"index":         i,
+				"message":       map[string]any{"role": "assistant", "content": nil},
+				"finish_reason": "error",
+				"status_code":   outErr.Status,
+				"error":         chatChoiceError(outErr.Status, outErr.Message, outErr.Code),
+			})
+			continue
+		}
+		turn := result.Turn
+		body := openaifmt.BuildChatCompletionWithToolCalls(completionID, stdReq.ResponseModel, turn.Prompt, turn.Thinking, turn.Text, turn.ToolCalls, stdReq.ToolsRaw)
+		shared.AttachChatAnnotations(body, turn.Text, turn.CitationSpans)
+		choice := body["choices"].([]map[string]any)[0]
+		choice["index"] = i
+		choices = append(choices, choice)
+		usages = append(usages, assistantturn.OpenAIChatUsage(turn))
+	}
+	first := results[ok].Turn
+	respBody := openaifmt.BuildChatCompletionWithToolCalls(completionID, stdReq.ResponseModel, first.Prompt, first.Thinking, first.Text, first.ToolCalls, stdReq.ToolsRaw)
+	respBody["choices"] = choices
+	respBody["usage"] = mergeChatChoiceUsage(usages)
+	if historySession != nil && ok == 0 {
+		finishReason := assistantturn.FinalizeTurn(first, assistantturn.FinalizeOptions{}).FinishReason
+		historySession.success(http.StatusOK, historyThinkingForArchive(first.RawThinking, first.DetectionThinking, first.Thinking), historyTextForArchive(first.RawText, first.Text), finishReason, usages[0])
+	}
+	writeJSON(w, http.StatusOK, respBody)
+}
+
+func (h *Handler) chatChoicesStream(w http.ResponseWriter, r *http.Request, accounts []*auth.RequestAuth, stdReq promptcompat.StandardRequest, historySession *chatHistorySession) {
+	starts := make([]completionruntime.StartResult, len(accounts))
+	startErrs := make([]*assistantturn.OutputError, len(accounts))
+	start := func(ctx context.Context, i int) bool {
+		starts[i], startErrs[i] = completionruntime.StartCompletion(auth.WithAuth(ctx, accounts[i]), h.DS, accounts[i], stdReq, completionruntime.Options{
+			CurrentInputFile: h.Store,
+		})
+		return startErrs[i] == nil
+	}
+	started := shared.StartChoices(accounts, func(i int) bool { return start(r.Context(), i) })
+	// Choices past started open their sessions while streaming, so the IDs
+	// are only collected once everything has finished.
+	defer func() {
+		sessionIDs := make([]string, len(starts))
+		for i, start := range starts {
+			sessionIDs[i] = start.SessionID
+		}
+		h.autoDeleteChoiceSessions(r.Context(), accounts, sessionIDs)
+	}()
+
+	completionID := ""
+	for i, start := range starts {
+		if startErrs[i] == nil {
+			completionID = start.SessionID
+			break
+		}
+	}
+	if outErr := startErrs[0]; outErr != nil && historySession != nil {
+		historySession.error(outErr.Status, outErr.Message, outErr.Code, "", "")
+	}
+	if completionID == "" {
+		outErr := startErrs[0]
+		writeOpenAIErrorWithCode(w, outErr.Status, outErr.Message, outErr.Code)
+		return
+	}
+	generate := func(w http.ResponseWriter, r *http.Request) {
+		h.streamChatChoices(w, r, accounts, starts, startErrs, started, start, completionID, stdReq.ResponseModel, historySession)
+	}
+	if stdReq.Resumable {
+		shared.RunResumable(w, r, h.getResumeHub(), accounts[0].CallerID, completionID, generate)
+		return
+	}
+	generate(w, r)
+}
+
+// streamChatChoices relays every choice through its own stream runtime,
+// interleaving their chunks by index, then ends the stream with one
+// aggregated usage chunk and [DONE]. Choices from started on are opened with
+// start when their turn comes.
+func (h *Handler) streamChatChoices(w http.ResponseWriter, r *http.Request, accounts []*auth.RequestAuth, starts []completionruntime.StartResult, startErrs []*assistantturn.OutputError, started int, start func(context.Context, int) bool, completionID, model string, historySession *chatHistorySession) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache, no-transform")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	stream := shared.NewChoiceStream(w)
+	runtimes := make([]*chatStreamRuntime, len(starts))
+	shared.RunChoices(accounts, func(i int, acc *auth.RequestAuth) {
+		cw := stream.Writer(i)
+		if i >= started {
+			start(r.Context(), i)
+		}
+		if outErr := startErrs[i]; outErr != nil {
+			writeChatChoiceError(cw, outErr.Status, outErr.Message, outErr.Code)
+			return
+		}
+		var choiceHistory *chatHistorySession
+		if i == 0 {
+			choiceHistory = historySession
+		}
+		st := starts[i]
+		req := st.Request
+		runtimes[i] = h.handleStreamWithRetry(cw, r.WithContext(auth.WithAuth(r.Context(), acc)), acc, st.Response, st.Payload, st.Pow, completionID, req.ResponseModel, req.PromptTokenText, req.RefFileTokens, req.Thinking, req.Search, req.ToolNames, req.ToolsRaw, req.ToolChoice, req.OutputLimits(), choiceHistory)
+	})
+	if r.Context().Err() != nil {
+		return
+	}
+	usages := make([]map[string]any, 0, len(runtimes))
+	for _, rt := range runtimes {
+		if rt != nil && rt.finalUsage != nil {
+			usages = append(usages, rt.finalUsage)
+		}
+	}
+	b, _ := json.Marshal(openaifmt.BuildChatStreamChunk(completionID, time.Now().Unix(), model, []map[string]any{}, mergeChatChoiceUsage(usages)))
+	stream.WriteFrame([]byte("data: " + string(b) + "\n\n"))
+	stream.WriteFrame([]byte("data: [DONE]\n\n"))
+}
+
+// writeChatChoiceError reports a failed choice as an error chunk tagged with
+// its index; the other choices keep streaming.
+func writeChatChoiceError(cw *shared.ChoiceWriter, status int, message, code string) {
+	b, _ := json.Marshal(map[string]any{
+		"index":       cw.Index,
+		"status_code": status,
+		"error":       chatChoiceError(status, message, code),
+	})
+	_, _ = cw.Write([]byte("data: " + string(b) + "\n\n"))
+}
+
+func chatChoiceError(status int, message, code string) map[string]any {
+	return map[string]any{
+		"message": message,
+		"type":    openAIErrorType(status),
+		"code":    code,
+		"param":   nil,
+	}
+}
+
+// mergeChatChoiceUsage sums completion usage across choices. The prompt is
+// shared, so it is counted once.
+func mergeChatChoiceUsage(usages []map[string]any) map[string]any {
+	prompt, completion, reasoning := 0, 0, 0
+	for _, usage := range usages {
+		if n := util.IntFrom(usage["prompt_tokens"]); n > prompt {
+			prompt = n
+		}
+		completion += util.IntFrom(usage["completion_tokens"])
+		if details, ok := usage["completion_tokens_details"].(map[string]any); ok {
+			reasoning += util.IntFrom(details["reasoning_tokens"])
+		}
+	}
+	return map[string]any{
+		"prompt_tokens":     prompt,
+		"completion_tokens": completion,
+		"total_tokens":      prompt + completion,
+		"completion_tokens_details": map[string]any{
+			"reasoning_tokens": reasoning,
+		},
+	}
+}
+
+// autoDeleteChoiceSessions runs auto-delete once every choice has finished,
+// so "all" mode cannot wipe a session another choice is still using.
+func (h *Handler) autoDeleteChoiceSessions(ctx context.Context, accounts []*auth.RequestAuth, sessionIDs []string) {
+	deletedAll := map[string]bool{}
+	for i, a := range accounts {
+		if h.Store.AutoDeleteMode() == "all" {
+			if deletedAll[a.DeepSeekToken] {
+				continue
+			}
+			deletedAll[a.DeepSeekToken] = true
+		}
+		h.autoDeleteRemoteSession(ctx, a, sessionIDs[i])
+	}
+}
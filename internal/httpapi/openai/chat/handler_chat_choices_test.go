@@ -0,0 +1,184 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"ds2api/internal/auth"
+)
+
+// choicesDSStub answers concurrent completion calls with a fresh stream each.
+type choicesDSStub struct {
+	autoDeleteModeDSStub
+	mu       sync.Mutex
+	lines    []string
+	sessions int
+	// failFirst makes the first completion call fail upstream.
+	failFirst bool
+}
+
+func (m *choicesDSStub) CallCompletion(_ context.Context, _ *auth.RequestAuth, _ map[string]any, _ string, _ int) (*http.Response, error) {
+	m.mu.Lock()
+	m.sessions++
+	fail := m.failFirst && m.sessions == 1
+	m.mu.Unlock()
+	if fail {
+		return nil, errors.New("upstream unavailable")
+	}
+	return makeOpenAISSEHTTPResponse(m.lines...), nil
+}
+
+func TestChatCompletionsNFansOutIndexedChoices(t *testing.T) {
+	ds := &choicesDSStub{lines: []string{`data: {"p":"response/content","v":"hi there"}`, "data: [DONE]"}}
+	h := &Handler{Store: mockOpenAIConfig{autoDeleteMode: "single"}, Auth: streamStatusAuthStub{}, DS: ds}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"deepseek-v4-flash","messages":[{"role":"user","content":"hi"}],"n":3}`))
+	rec := httptest.NewRecorder()
+	h.ChatCompletions(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	var out struct {
+		Choices []struct {
+			Index   int `json:"index"`
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+			TotalTokens      int `json:"total_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(out.Choices) != 3 {
+		t.Fatalf("expected 3 choices, got %s", rec.Body.String())
+	}
+	for i, choice := range out.Choices {
+		if choice.Index != i || choice.Message.Content != "hi there" {
+			t.Fatalf("unexpected choice %d: %+v", i, choice)
+		}
+	}
+	if out.Usage.CompletionTokens%3 != 0 || out.Usage.TotalTokens != out.Usage.PromptTokens+out.Usage.CompletionTokens {
+		t.Fatalf("expected summed completion usage, got %+v", out.Usage)
+	}
+	if ds.sessions != 3 || ds.singleCalls != 3 {
+		t.Fatalf("expected one session and delete per choice, got %d sessions and %d deletes", ds.sessions, ds.singleCalls)
+	}
+}
+
+func TestChatCompletionsNNonStreamKeepsSuccessfulChoicesOnPartialFailure(t *testing.T) {
+	ds := &choicesDSStub{lines: []string{`data: {"p":"response/content","v":"hi there"}`, "data: [DONE]"}, failFirst: true}
+	h := &Handler{Store: mockOpenAIConfig{autoDeleteMode: "none"}, Auth: streamStatusAuthStub{}, DS: ds}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"deepseek-v4-flash","messages":[{"role":"user","content":"hi"}],"n":3}`))
+	rec := httptest.NewRecorder()
+	h.ChatCompletions(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 when only one choice fails, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	var out struct {
+		Choices []struct {
+			Index        int             `json:"index"`
+			FinishReason string          `json:"finish_reason"`
+			Error        *map[string]any `json:"error"`
+			Message      struct {
+				Content *string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(out.Choices) != 3 {
+		t.Fatalf("expected 3 choices, got %s", rec.Body.String())
+	}
+	failed := 0
+	for i, choice := range out.Choices {
+		if choice.Index != i {
+			t.Fatalf("unexpected index for choice %d: %+v", i, choice)
+		}
+		if choice.FinishReason == "error" {
+			failed++
+			if choice.Error == nil || (*choice.Error)["message"] == "" || choice.Message.Content != nil {
+				t.Fatalf("expected failed choice %d to carry an error, got %s", i, rec.Body.String())
+			}
+			continue
+		}
+		if choice.Message.Content == nil || *choice.Message.Content != "hi there" {
+			t.Fatalf("unexpected successful choice %d: %s", i, rec.Body.String())
+		}
+	}
+	if failed != 1 {
+		t.Fatalf("expected exactly one failed choice, got %d in %s", failed, rec.Body.String())
+	}
+}
+
+func TestChatCompletionsNStreamInterleavesChoicesAndAggregatesUsage(t *testing.T) {
+	ds := &choicesDSStub{lines: []string{`data: {"p":"response/content","v":"hello"}`, "data: [DONE]"}}
+	h := &Handler{Store: mockOpenAIConfig{autoDeleteMode: "none"}, Auth: streamStatusAuthStub{}, DS: ds}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"deepseek-v4-flash","messages":[{"role":"user","content":"hi"}],"n":2,"stream":true}`))
+	rec := httptest.NewRecorder()
+	h.ChatCompletions(rec, req)
+
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || strings.Count(body, "data: [DONE]") != 1 || !strings.HasSuffix(body, "data: [DONE]\n\n") {
+		t.Fatalf("unexpected stream: status=%d body=%s", rec.Code, body)
+	}
+	finished := map[int]bool{}
+	var usageChunks []map[string]any
+	for _, frame := range strings.Split(body, "\n\n") {
+		payload, ok := strings.CutPrefix(frame, "data: ")
+		if !ok || payload == "[DONE]" {
+			continue
+		}
+		var chunk map[string]any
+		if err := json.Unmarshal([]byte(payload), &chunk); err != nil {
+			t.Fatalf("decode chunk %q: %v", payload, err)
+		}
+		if _, ok := chunk["usage"]; ok {
+			usageChunks = append(usageChunks, chunk)
+		}
+		choices, _ := chunk["choices"].([]any)
+		for _, raw := range choices {
+			choice := raw.(map[string]any)
+			if choice["finish_reason"] != nil {
+				finished[int(choice["index"].(float64))] = true
+			}
+		}
+	}
+	if !finished[0] || !finished[1] {
+		t.Fatalf("expected both choices to finish, got %v in %s", finished, body)
+	}
+	if len(usageChunks) != 1 || len(usageChunks[0]["choices"].([]any)) != 0 {
+		t.Fatalf("expected one aggregated usage chunk, got %v", usageChunks)
+	}
+}
+
+func TestChatCompletionsRejectsNAboveConfiguredMax(t *testing.T) {
+	ds := &choicesDSStub{}
+	h := &Handler{Store: mockOpenAIConfig{autoDeleteMode: "none", maxChoices: 2}, Auth: streamStatusAuthStub{}, DS: ds}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"deepseek-v4-flash","messages":[{"role":"user","content":"hi"}],"n":3}`))
+	rec := httptest.NewRecorder()
+	h.ChatCompletions(rec, req)
+
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "n must not exceed 2") {
+		t.Fatalf("expected 400, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if ds.sessions != 0 {
+		t.Fatalf("expected no upstream calls, got %d", ds.sessions)
+	}
+}
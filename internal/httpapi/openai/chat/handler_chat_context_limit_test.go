@@ -0,0 +1,48 @@
+package chat
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestChatCompletionsContextLimitPreflight(t *testing.T) {
+	ds := &choicesDSStub{lines: []string{`data: {"p":"response/content","v":"ok"}`, "data: [DONE]"}}
+	h := &Handler{Store: mockOpenAIConfig{contextLimit: 1024}, Auth: streamStatusAuthStub{}, DS: ds}
+
+	long := strings.Repeat("lorem ipsum dolor sit amet ", 400)
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"deepseek-v4-flash","messages":[{"role":"user","content":"`+long+`"}]}`))
+	rec := httptest.NewRecorder()
+	h.ChatCompletions(rec, req)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"code":"context_length_exceeded"`) {
+		t.Fatalf("expected context_length_exceeded, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if ds.sessions != 0 {
+		t.Fatalf("expected no upstream calls, got %d", ds.sessions)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"deepseek-v4-flash","messages":[{"role":"user","content":"hi"}]}`))
+	rec = httptest.NewRecorder()
+	h.ChatCompletions(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected short prompt to pass, got %d body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestChatCompletionsContextWindowDropsOldestTurns(t *testing.T) {
+	ds := &choicesDSStub{lines: []string{`data: {"p":"response/content","v":"ok"}`, "data: [DONE]"}}
+	h := &Handler{Store: mockOpenAIConfig{contextLimit: 1024, contextStrategy: "drop_oldest", keepLastTurns: 1}, Auth: streamStatusAuthStub{}, DS: ds}
+
+	long := strings.Repeat("lorem ipsum dolor sit amet ", 400)
+	body := `{"model":"deepseek-v4-flash","messages":[{"role":"system","content":"be brief"},{"role":"user","content":"` + long + `"},{"role":"assistant","content":"noted"},{"role":"user","content":"hi"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ChatCompletions(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected trimmed request to succeed, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("X-Ds2api-Context-Window"); !strings.HasPrefix(got, "drop_oldest;") || !strings.Contains(got, "dropped_messages=2") {
+		t.Fatalf("unexpected context window header: %q", got)
+	}
+}
@@ -0,0 +1,257 @@
+package chat
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"ds2api/internal/accesslog"
+	"ds2api/internal/assistantturn"
+	"ds2api/internal/auth"
+	"ds2api/internal/completionruntime"
+	openaifmt "ds2api/internal/format/openai"
+	"ds2api/internal/promptcompat"
+	streamengine "ds2api/internal/stream"
+	"ds2api/internal/util"
+)
+
+// Completions serves the legacy text completions API on top of the chat
+// runtime. Every choice is its own upstream session; choices are generated
+// one after another on the account acquired for the request.
+func (h *Handler) Completions(w http.ResponseWriter, r *http.Request) {
+	a, err := h.Auth.Determine(r)
+	if err != nil {
+		status := http.StatusUnauthorized
+		detail := err.Error()
+		if err == auth.ErrNoAccount {
+			status = http.StatusTooManyRequests
+		}
+		writeOpenAIError(w, status, detail)
+		return
+	}
+	defer h.Auth.Release(a)
+	r = r.WithContext(auth.WithAuth(r.Context(), a))
+
+	r.Body = http.MaxBytesReader(w, r.Body, openAIGeneralMaxSize)
+	var req map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "too large") {
+			writeOpenAIError(w, http.StatusRequestEntityTooLarge, "request body too large")
+			return
+		}
+		writeOpenAIError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	legacyReq, err := promptcompat.NormalizeOpenAICompletionsRequest(h.Store, req, requestTraceID(r))
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	completionID := "cmpl-" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if legacyReq.Stream {
+		h.streamLegacyCompletions(w, r, a, completionID, legacyReq)
+		return
+	}
+
+	choices := make([]map[string]any, 0, legacyReq.Choices())
+	usage := legacyCompletionUsage{}
+	finishReasons := make([]string, 0, legacyReq.Choices())
+	for i, stdReq := range legacyReq.Requests {
+		for j := 0; j < legacyReq.N; j++ {
+			result, outErr := completionruntime.ExecuteNonStreamWithRetry(r.Context(), h.DS, a, stdReq, completionruntime.Options{
+				RetryEnabled:     true,
+				CurrentInputFile: h.Store,
+			})
+			h.autoDeleteRemoteSession(r.Context(), a, result.SessionID)
+			if outErr != nil {
+				writeOpenAIErrorWithCode(w, outErr.Status, outErr.Message, outErr.Code)
+				return
+			}
+			text := result.Turn.Text
+			if legacyReq.Echo {
+				text = legacyReq.Prompts[i] + text
+			}
+			finishReason := assistantturn.FinishReason(result.Turn)
+			choices = append(choices, openaifmt.BuildTextCompletionChoice(i*legacyReq.N+j, text, finishReason))
+			usage.add(result.Turn.Usage.InputTokens, result.Turn.Usage.OutputTokens, j == 0)
+			finishReasons = append(finishReasons, finishReason)
+		}
+	}
+	accesslog.FromContext(r.Context()).Finish(mergeLegacyFinishReason(finishReasons), usage.body())
+	writeJSON(w, http.StatusOK, openaifmt.BuildTextCompletion(completionID, legacyReq.ResponseModel, choices, usage.body()))
+}
+
+// legacyCompletionUsage sums usage across choices. Each prompt is counted
+// once no matter how many choices it produced, as upstream OpenAI does.
+type legacyCompletionUsage struct {
+	prompt     int
+	completion int
+}
+
+func (u *legacyCompletionUsage) add(promptTokens, completionTokens int, countPrompt bool) {
+	if countPrompt {
+		u.prompt += promptTokens
+	}
+	u.completion += completionTokens
+}
+
+func (u legacyCompletionUsage) body() map[string]any {
+	return map[string]any{
+		"prompt_tokens":     u.prompt,
+		"completion_tokens": u.completion,
+		"total_tokens":      u.prompt + u.completion,
+	}
+}
+
+type legacyCompletionStream struct {
+	w        http.ResponseWriter
+	rc       *http.ResponseController
+	canFlush bool
+	started  bool
+
+	completionID string
+	created      int64
+	model        string
+}
+
+// legacyChoiceWriter hands one choice's output from the chat stream runtime
+// to the legacy stream, which owns the headers, the usage chunk and [DONE].
+// The runtime recognizes it and renders text_completion chunks for index.
+type legacyChoiceWriter struct {
+	stream *legacyCompletionStream
+	index  int
+	header http.Header
+}
+
+func (w *legacyChoiceWriter) Header() http.Header { return w.header }
+
+func (w *legacyChoiceWriter) WriteHeader(int) {}
+
+func (w *legacyChoiceWriter) Write(p []byte) (int, error) {
+	w.stream.start()
+	return w.stream.w.Write(p)
+}
+
+func (w *legacyChoiceWriter) Flush() { w.stream.flush() }
+
+func (h *Handler) streamLegacyCompletions(w http.ResponseWriter, r *http.Request, a *auth.RequestAuth, completionID string, legacyReq promptcompat.LegacyCompletionRequest) {
+	_, canFlush := w.(http.Flusher)
+	s := &legacyCompletionStream{
+		w:            w,
+		rc:           http.NewResponseController(w),
+		canFlush:     canFlush,
+		completionID: completionID,
+		created:      time.Now().Unix(),
+		model:        legacyReq.ResponseModel,
+	}
+	usage := legacyCompletionUsage{}
+	finishReasons := make([]string, 0, legacyReq.Choices())
+	for i, stdReq := range legacyReq.Requests {
+		for j := 0; j < legacyReq.N; j++ {
+			index := i*legacyReq.N + j
+			start, outErr := completionruntime.StartCompletion(r.Context(), h.DS, a, stdReq, completionruntime.Options{
+				CurrentInputFile: h.Store,
+			})
+			if outErr != nil {
+				h.autoDeleteRemoteSession(r.Context(), a, start.SessionID)
+				s.fail(outErr.Status, outErr.Message, outErr.Code)
+				return
+			}
+			if legacyReq.Echo {
+				s.sendText(index, legacyReq.Prompts[i])
+			}
+			req := start.Request
+			cw := &legacyChoiceWriter{stream: s, index: index, header: http.Header{}}
+			rt := h.handleStreamWithRetry(cw, r, a, start.Response, start.Payload, start.Pow, completionID, legacyReq.ResponseModel, req.PromptTokenText, req.RefFileTokens, req.Thinking, req.Search, req.ToolNames, req.ToolsRaw, req.ToolChoice, req.OutputLimits(), nil)
+			h.autoDeleteRemoteSession(r.Context(), a, start.SessionID)
+			if rt == nil {
+				return
+			}
+			if rt.finalErrorMessage != "" {
+				if rt.finalErrorCode != string(streamengine.StopReasonContextCancelled) {
+					s.sendDone()
+				}
+				return
+			}
+			usage.add(util.IntFrom(rt.finalUsage["prompt_tokens"]), util.IntFrom(rt.finalUsage["completion_tokens"]), j == 0)
+			finishReasons = append(finishReasons, rt.finalFinishReason)
+		}
+	}
+	accesslog.FromContext(r.Context()).Finish(mergeLegacyFinishReason(finishReasons), usage.body())
+	s.sendChunk(openaifmt.BuildTextCompletionChunk(s.completionID, s.created, s.model, []map[string]any{}, usage.body()))
+	s.sendDone()
+}
+
+// mergeLegacyFinishReason picks the one finish_reason logged for a request:
+// the first choice that did not simply stop, so a truncated or filtered
+// choice is not hidden by the others.
+func mergeLegacyFinishReason(reasons []string) string {
+	for _, reason := range reasons {
+		if reason != "" && reason != "stop" {
+			return reason
+		}
+	}
+	if len(reasons) == 0 {
+		return ""
+	}
+	return "stop"
+}
+
+func (s *legacyCompletionStream) start() {
+	if s.started {
+		return
+	}
+	s.started = true
+	s.w.Header().Set("Content-Type", "text/event-stream")
+	s.w.Header().Set("Cache-Control", "no-cache, no-transform")
+	s.w.Header().Set("Connection", "keep-alive")
+	s.w.Header().Set("X-Accel-Buffering", "no")
+	s.w.WriteHeader(http.StatusOK)
+}
+
+func (s *legacyCompletionStream) sendText(index int, text string) {
+	s.sendChunk(openaifmt.BuildTextCompletionChunk(s.completionID, s.created, s.model, []map[string]any{openaifmt.BuildTextCompletionChoice(index, text, "")}, nil))
+}
+
+func (s *legacyCompletionStream) sendChunk(v any) {
+	s.start()
+	b, _ := json.Marshal(v)
+	_, _ = s.w.Write([]byte("data: "))
+	_, _ = s.w.Write(b)
+	_, _ = s.w.Write([]byte("\n\n"))
+	s.flush()
+}
+
+func (s *legacyCompletionStream) sendDone() {
+	s.start()
+	_, _ = s.w.Write([]byte("data: [DONE]\n\n"))
+	s.flush()
+}
+
+func (s *legacyCompletionStream) flush() {
+	if s.canFlush {
+		_ = s.rc.Flush()
+	}
+}
+
+// fail reports an error as a plain JSON response while nothing has been
+// streamed yet, and as a terminal error chunk afterwards.
+func (s *legacyCompletionStream) fail(status int, message, code string) {
+	if !s.started {
+		writeOpenAIErrorWithCode(s.w, status, message, code)
+		return
+	}
+	s.sendChunk(map[string]any{
+		"status_code": status,
+		"error": map[string]any{
+			"message": message,
+			"type":    openAIErrorType(status),
+			"code":    code,
+			"param":   nil,
+		},
+	})
+	s.sendDone()
+}
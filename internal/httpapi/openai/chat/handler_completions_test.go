@@ -0,0 +1,133 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ds2api/internal/auth"
+)
+
+// legacyCompletionsDSStub answers every completion call with a fresh stream.
+// Calls past the scripted ones in attempts fall back to lines.
+type legacyCompletionsDSStub struct {
+	autoDeleteModeDSStub
+	lines    []string
+	attempts [][]string
+	prompts  []string
+	sessions int
+}
+
+func (m *legacyCompletionsDSStub) CallCompletion(_ context.Context, _ *auth.RequestAuth, payload map[string]any, _ string, _ int) (*http.Response, error) {
+	m.sessions++
+	prompt, _ := payload["prompt"].(string)
+	m.prompts = append(m.prompts, prompt)
+	if len(m.attempts) > 0 {
+		lines := m.attempts[0]
+		m.attempts = m.attempts[1:]
+		return makeOpenAISSEHTTPResponse(lines...), nil
+	}
+	return makeOpenAISSEHTTPResponse(m.lines...), nil
+}
+
+func TestCompletionsFansOutPromptArrayWithEcho(t *testing.T) {
+	ds := &legacyCompletionsDSStub{lines: []string{`data: {"p":"response/content","v":" world"}`, "data: [DONE]"}}
+	h := &Handler{Store: mockOpenAIConfig{autoDeleteMode: "single"}, Auth: streamStatusAuthStub{}, DS: ds}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/completions", strings.NewReader(`{"model":"deepseek-v4-flash","prompt":["hello","bye"],"n":2,"echo":true}`))
+	rec := httptest.NewRecorder()
+	h.Completions(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	var out struct {
+		Object  string `json:"object"`
+		Choices []struct {
+			Text         string `json:"text"`
+			Index        int    `json:"index"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if out.Object != "text_completion" || len(out.Choices) != 4 {
+		t.Fatalf("unexpected response: %s", rec.Body.String())
+	}
+	if out.Choices[0].Text != "hello world" || out.Choices[3].Text != "bye world" || out.Choices[3].Index != 3 || out.Choices[0].FinishReason != "stop" {
+		t.Fatalf("unexpected choices: %+v", out.Choices)
+	}
+	if ds.sessions != 4 || ds.singleCalls != 4 {
+		t.Fatalf("expected one session per choice, got %d sessions and %d deletes", ds.sessions, ds.singleCalls)
+	}
+	if !strings.Contains(ds.prompts[0], "Continue the text below") || !strings.Contains(ds.prompts[0], "hello") {
+		t.Fatalf("expected prompt to be wrapped, got %q", ds.prompts[0])
+	}
+}
+
+func TestCompletionsStreamEmitsTextChunksAndStops(t *testing.T) {
+	ds := &legacyCompletionsDSStub{lines: []string{
+		`data: {"p":"response/content","v":"def add(a, b):\n"}`,
+		`data: {"p":"response/content","v":"    return a + b\n\n\nprint"}`,
+		"data: [DONE]",
+	}}
+	h := &Handler{Store: mockOpenAIConfig{autoDeleteMode: "none"}, Auth: streamStatusAuthStub{}, DS: ds}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/completions", strings.NewReader(`{"model":"deepseek-v4-flash","prompt":"def add","suffix":"\n","stream":true,"stop":"\n\n\n"}`))
+	rec := httptest.NewRecorder()
+	h.Completions(rec, req)
+
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.HasSuffix(body, "data: [DONE]\n\n") {
+		t.Fatalf("unexpected stream: status=%d body=%s", rec.Code, body)
+	}
+	if !strings.Contains(body, `"object":"text_completion"`) || strings.Contains(body, "print") {
+		t.Fatalf("expected text_completion chunks cut at the stop sequence, body=%s", body)
+	}
+	if !strings.Contains(body, `"finish_reason":"stop"`) || !strings.Contains(body, `"total_tokens"`) {
+		t.Fatalf("expected finish chunk and usage, body=%s", body)
+	}
+	if !strings.Contains(ds.prompts[0], "PREFIX:\ndef add") {
+		t.Fatalf("expected fill-in-the-middle prompt, got %q", ds.prompts[0])
+	}
+}
+
+func TestCompletionsStreamRetriesEmptyOutput(t *testing.T) {
+	ds := &legacyCompletionsDSStub{
+		attempts: [][]string{{"data: [DONE]"}},
+		lines:    []string{`data: {"p":"response/content","v":"retried"}`, "data: [DONE]"},
+	}
+	h := &Handler{Store: mockOpenAIConfig{autoDeleteMode: "none"}, Auth: streamStatusAuthStub{}, DS: ds}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/completions", strings.NewReader(`{"model":"deepseek-v4-flash","prompt":"hi","stream":true,"echo":true}`))
+	rec := httptest.NewRecorder()
+	h.Completions(rec, req)
+
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.HasSuffix(body, "data: [DONE]\n\n") || strings.Contains(body, `"error"`) {
+		t.Fatalf("unexpected stream: status=%d body=%s", rec.Code, body)
+	}
+	if ds.sessions != 2 {
+		t.Fatalf("expected an empty-output retry, got %d upstream calls", ds.sessions)
+	}
+	if !strings.Contains(body, `"text":"hi"`) || !strings.Contains(body, `"text":"retried"`) || !strings.Contains(body, `"finish_reason":"stop"`) {
+		t.Fatalf("expected echo, retried text and finish chunk, body=%s", body)
+	}
+	if strings.Contains(body, `"object":"chat.completion.chunk"`) || strings.Count(body, "data: [DONE]") != 1 {
+		t.Fatalf("expected only text_completion chunks and one [DONE], body=%s", body)
+	}
+}
+
+func TestCompletionsRejectsTokenPrompts(t *testing.T) {
+	h := &Handler{Store: mockOpenAIConfig{}, Auth: streamStatusAuthStub{}, DS: &legacyCompletionsDSStub{}}
+	req := httptest.NewRequest(http.MethodPost, "/v1/completions", strings.NewReader(`{"model":"deepseek-v4-flash","prompt":[1,2,3]}`))
+	rec := httptest.NewRecorder()
+	h.Completions(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d body=%s", rec.Code, rec.Body.String())
+	}
+}
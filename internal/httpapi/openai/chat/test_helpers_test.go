@@ -21,6 +21,10 @@ type mockOpenAIConfig struct {
 	currentInputMin     int
 	thinkingInjection   *bool
 	thinkingPrompt      string
+	maxChoices          int
+	contextLimit        int
+	contextStrategy     string
+	keepLastTurns       int
 }
 
 func (m mockOpenAIConfig) ModelAliases() map[string]string     { return m.aliases }
@@ -46,6 +50,28 @@ func (m mockOpenAIConfig) ThinkingInjectionEnabled() bool {
 	return *m.thinkingInjection
 }
 func (m mockOpenAIConfig) ThinkingInjectionPrompt() string { return m.thinkingPrompt }
+func (m mockOpenAIConfig) RuntimeMaxChoices() int {
+	if m.maxChoices > 0 {
+		return m.maxChoices
+	}
+	return 4
+}
+func (m mockOpenAIConfig) RuntimeChoiceAccountPolicy() string { return "same" }
+func (m mockOpenAIConfig) ContextLimitEnabled() bool          { return m.contextLimit > 0 }
+func (m mockOpenAIConfig) ContextLimitTokens(string) int      { return m.contextLimit }
+func (m mockOpenAIConfig) ContextLimitStrategy() string {
+	if m.contextStrategy == "" {
+		return "reject"
+	}
+	return m.contextStrategy
+}
+func (m mockOpenAIConfig) ContextLimitKeepLastTurns() int {
+	if m.keepLastTurns > 0 {
+		return m.keepLastTurns
+	}
+	return 4
+}
+func (m mockOpenAIConfig) ContextLimitToolResultMaxChars() int { return 4000 }
 
 type streamStatusAuthStub struct{}
 
@@ -58,6 +84,10 @@ func (streamStatusAuthStub) Determine(_ *http.Request) (*auth.RequestAuth, error
 	}, nil
 }
 
+func (s streamStatusAuthStub) DetermineAdditional(r *http.Request) (*auth.RequestAuth, error) {
+	return s.Determine(r)
+}
+
 func (streamStatusAuthStub) DetermineCaller(_ *http.Request) (*auth.RequestAuth, error) {
 	return (&streamStatusAuthStub{}).Determine(nil)
 }
@@ -76,6 +106,10 @@ func (streamStatusManagedAuthStub) Determine(_ *http.Request) (*auth.RequestAuth
 	}, nil
 }
 
+func (s streamStatusManagedAuthStub) DetermineAdditional(r *http.Request) (*auth.RequestAuth, error) {
+	return s.Determine(r)
+}
+
 func (streamStatusManagedAuthStub) DetermineCaller(_ *http.Request) (*auth.RequestAuth, error) {
 	return (&streamStatusManagedAuthStub{}).Determine(nil)
 }
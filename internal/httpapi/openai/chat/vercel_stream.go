@@ -69,6 +69,12 @@ func (h *Handler) handleVercelStreamPrepare(w http.ResponseWriter, r *http.Reque
 		writeOpenAIError(w, http.StatusBadRequest, "stream must be true")
 		return
 	}
+	stdReq, contextReport, err := h.fitContextWindow(r.Context(), a, stdReq)
+	setContextWindowHeader(w, contextReport)
+	if err != nil {
+		writeContextLimitError(w, err)
+		return
+	}
 	stdReq, err = h.applyCurrentInputFile(r.Context(), a, stdReq)
 	if err != nil {
 		status, message := mapCurrentInputFileError(err)
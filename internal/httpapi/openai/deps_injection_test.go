@@ -18,6 +18,10 @@ type mockOpenAIConfig struct {
 	currentInputMin     int
 	thinkingInjection   *bool
 	thinkingPrompt      string
+	maxChoices          int
+	contextLimit        int
+	contextStrategy     string
+	keepLastTurns       int
 }
 
 func (m mockOpenAIConfig) ModelAliases() map[string]string     { return m.aliases }
@@ -43,6 +47,28 @@ func (m mockOpenAIConfig) ThinkingInjectionEnabled() bool {
 	return *m.thinkingInjection
 }
 func (m mockOpenAIConfig) ThinkingInjectionPrompt() string { return m.thinkingPrompt }
+func (m mockOpenAIConfig) RuntimeMaxChoices() int {
+	if m.maxChoices > 0 {
+		return m.maxChoices
+	}
+	return 4
+}
+func (m mockOpenAIConfig) RuntimeChoiceAccountPolicy() string { return "same" }
+func (m mockOpenAIConfig) ContextLimitEnabled() bool          { return m.contextLimit > 0 }
+func (m mockOpenAIConfig) ContextLimitTokens(string) int      { return m.contextLimit }
+func (m mockOpenAIConfig) ContextLimitStrategy() string {
+	if m.contextStrategy == "" {
+		return "reject"
+	}
+	return m.contextStrategy
+}
+func (m mockOpenAIConfig) ContextLimitKeepLastTurns() int {
+	if m.keepLastTurns > 0 {
+		return m.keepLastTurns
+	}
+	return 4
+}
+func (m mockOpenAIConfig) ContextLimitToolResultMaxChars() int { return 4000 }
 
 func TestNormalizeOpenAIChatRequestWithConfigInterface(t *testing.T) {
 	cfg := mockOpenAIConfig{
@@ -107,3 +133,42 @@ func TestNormalizeOpenAIResponsesRequestAlwaysAcceptsWideInput(t *testing.T) {
 		t.Fatalf("unexpected final prompt: %q", out.FinalPrompt)
 	}
 }
+
+func TestNormalizeOpenAIRequestsMapHostedWebSearchToSearchMode(t *testing.T) {
+	cfg := mockOpenAIConfig{}
+	chatReq := map[string]any{
+		"model":              "deepseek-v4-flash",
+		"messages":           []any{map[string]any{"role": "user", "content": "news"}},
+		"web_search_options": map[string]any{},
+	}
+	chat, err := promptcompat.NormalizeOpenAIChatRequest(cfg, chatReq, "")
+	if err != nil {
+		t.Fatalf("promptcompat.NormalizeOpenAIChatRequest error: %v", err)
+	}
+	if !chat.Search || chat.ResolvedModel != "deepseek-v4-flash" {
+		t.Fatalf("expected search on base model, got search=%v model=%q", chat.Search, chat.ResolvedModel)
+	}
+
+	respReq := map[string]any{
+		"model": "deepseek-v4-flash",
+		"input": "news",
+		"tools": []any{
+			map[string]any{"type": "web_search_preview"},
+			map[string]any{"type": "function", "name": "lookup", "parameters": map[string]any{"type": "object"}},
+		},
+	}
+	resp, err := promptcompat.NormalizeOpenAIResponsesRequest(cfg, respReq, "")
+	if err != nil {
+		t.Fatalf("promptcompat.NormalizeOpenAIResponsesRequest error: %v", err)
+	}
+	if !resp.Search {
+		t.Fatalf("expected web_search tool to enable search")
+	}
+	tools, _ := resp.ToolsRaw.([]any)
+	if len(tools) != 1 || len(resp.ToolNames) != 1 || resp.ToolNames[0] != "lookup" {
+		t.Fatalf("expected hosted tool to be removed, tools=%#v names=%#v", resp.ToolsRaw, resp.ToolNames)
+	}
+	if strings.Contains(resp.FinalPrompt, "web_search") {
+		t.Fatalf("hosted tool must not be advertised in the prompt")
+	}
+}
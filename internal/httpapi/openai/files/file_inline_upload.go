@@ -94,10 +94,16 @@ func (h *Handler) PreprocessInlineFileInputs(ctx context.Context, a *auth.Reques
 }
 
 func WriteInlineFileError(w http.ResponseWriter, err error) {
+	status, message := InlineFileErrorStatus(err)
+	shared.WriteOpenAIError(w, status, message)
+}
+
+// InlineFileErrorStatus maps an inline file preprocessing error to the HTTP
+// status and message reported to the client.
+func InlineFileErrorStatus(err error) (int, string) {
 	inlineErr, ok := err.(*inlineFileUploadError)
 	if !ok || inlineErr == nil {
-		shared.WriteOpenAIError(w, http.StatusInternalServerError, "Failed to process file input.")
-		return
+		return http.StatusInternalServerError, "Failed to process file input."
 	}
 	status := inlineErr.status
 	if status == 0 {
@@ -107,7 +113,7 @@ func WriteInlineFileError(w http.ResponseWriter, err error) {
 	if message == "" {
 		message = "Failed to process file input."
 	}
-	shared.WriteOpenAIError(w, status, message)
+	return status, message
 }
 
 func (s *inlineUploadState) walk(raw any) (any, error) {
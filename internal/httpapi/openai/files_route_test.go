@@ -28,6 +28,10 @@ func (managedFilesAuthStub) Determine(_ *http.Request) (*auth.RequestAuth, error
 	}, nil
 }
 
+func (s managedFilesAuthStub) DetermineAdditional(r *http.Request) (*auth.RequestAuth, error) {
+	return s.Determine(r)
+}
+
 func (managedFilesAuthStub) DetermineCaller(_ *http.Request) (*auth.RequestAuth, error) {
 	return &auth.RequestAuth{
 		UseConfigToken: true,
@@ -0,0 +1,111 @@
+package history
+
+import (
+	"context"
+	"net/http"
+
+	"ds2api/internal/config"
+	"ds2api/internal/contextwindow"
+	"ds2api/internal/httpapi/openai/shared"
+	"ds2api/internal/promptcompat"
+)
+
+// ContextWindowHeader reports what the context manager did to a request.
+const ContextWindowHeader = "X-Ds2api-Context-Window"
+
+const contextSummaryMaxTokens = 2048
+
+// SummarizeFunc runs the side completion used by the summarize strategy and
+// returns its text.
+type SummarizeFunc func(ctx context.Context, req promptcompat.StandardRequest) (string, error)
+
+// FitContextWindow enforces the context_limit preflight. A request over its
+// model's token budget is first shrunk with the configured
+// context_limit.strategy; the error is a *ContextLengthError when what is
+// left is still over budget, as CheckContextLimit would report. The prompt
+// is counted exactly as PromptTokens does, once for the request and once
+// more only if it was modified. A failed summary leaves the request
+// unchanged, so it is rejected.
+func FitContextWindow(ctx context.Context, store shared.ConfigReader, stdReq promptcompat.StandardRequest, summarize SummarizeFunc) (promptcompat.StandardRequest, contextwindow.Report, error) {
+	if store == nil || !store.ContextLimitEnabled() {
+		return stdReq, contextwindow.Report{}, nil
+	}
+	tokens, err := PromptTokens(store, stdReq)
+	if err != nil {
+		return stdReq, contextwindow.Report{}, err
+	}
+	budget := store.ContextLimitTokens(stdReq.ResolvedModel)
+	strategy := store.ContextLimitStrategy()
+	if strategy == config.ContextStrategyReject || budget <= 0 || tokens <= budget {
+		return stdReq, contextwindow.Report{}, contextLengthError(budget, tokens)
+	}
+	opts := contextwindow.Options{
+		Strategy:           strategy,
+		Budget:             budget,
+		KeepLastTurns:      store.ContextLimitKeepLastTurns(),
+		ToolResultMaxChars: store.ContextLimitToolResultMaxChars(),
+		Count: func(messages []any) int {
+			tokens, _ := PromptTokens(store, withMessages(stdReq, messages))
+			return tokens
+		},
+		Tokens: tokens,
+	}
+	if summarize != nil {
+		opts.Summarize = func(ctx context.Context, older []any) (string, error) {
+			return summarize(ctx, contextSummaryRequest(stdReq, older))
+		}
+	}
+	messages, report, err := contextwindow.Fit(ctx, stdReq.Messages, opts)
+	if err != nil {
+		config.Logger.Warn("[context_window] summarize failed", "model", stdReq.ResolvedModel, "error", err)
+		return stdReq, report, contextLengthError(budget, tokens)
+	}
+	if !report.Applied() {
+		return stdReq, report, contextLengthError(budget, tokens)
+	}
+	config.Logger.Info("[context_window] applied", "model", stdReq.ResolvedModel, "summary", report.Header())
+	return withMessages(stdReq, messages), report, contextLengthError(budget, report.TokensAfter)
+}
+
+// SetContextWindowHeader adds the context window report to the response
+// headers when the request was modified.
+func SetContextWindowHeader(w http.ResponseWriter, report contextwindow.Report) {
+	if report.Applied() {
+		w.Header().Set(ContextWindowHeader, report.Header())
+	}
+}
+
+func withMessages(stdReq promptcompat.StandardRequest, messages []any) promptcompat.StandardRequest {
+	stdReq.Messages = messages
+	stdReq.FinalPrompt, _ = promptcompat.BuildOpenAIPrompt(messages, stdReq.ToolsRaw, "", stdReq.ToolChoice, stdReq.Thinking)
+	stdReq.PromptTokenText = stdReq.FinalPrompt
+	return stdReq
+}
+
+// contextSummaryRequest builds the side completion that condenses older
+// turns. It runs on the same model without thinking, tools or files.
+func contextSummaryRequest(base promptcompat.StandardRequest, older []any) promptcompat.StandardRequest {
+	messages := []any{
+		map[string]any{
+			"role":    "system",
+			"content": "Summarize the conversation transcript below so it can replace the original turns. Keep every fact, decision, file path, identifier, tool result and open task the assistant needs to continue. Reply with the summary only.",
+		},
+		map[string]any{
+			"role":    "user",
+			"content": promptcompat.BuildOpenAICurrentInputContextTranscript(older),
+		},
+	}
+	policy := promptcompat.DefaultToolChoicePolicy()
+	finalPrompt, _ := promptcompat.BuildOpenAIPrompt(messages, nil, "", policy, false)
+	return promptcompat.StandardRequest{
+		Surface:         base.Surface,
+		RequestedModel:  base.RequestedModel,
+		ResolvedModel:   base.ResolvedModel,
+		ResponseModel:   base.ResponseModel,
+		Messages:        messages,
+		FinalPrompt:     finalPrompt,
+		PromptTokenText: finalPrompt,
+		ToolChoice:      policy,
+		MaxOutputTokens: contextSummaryMaxTokens,
+	}
+}
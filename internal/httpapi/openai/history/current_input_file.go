@@ -11,6 +11,7 @@ import (
 	dsclient "ds2api/internal/deepseek/client"
 	"ds2api/internal/httpapi/openai/shared"
 	"ds2api/internal/promptcompat"
+	"ds2api/internal/tracing"
 )
 
 const (
@@ -34,24 +35,45 @@ type Service struct {
 }
 
 func (s Service) ApplyCurrentInputFile(ctx context.Context, a *auth.RequestAuth, stdReq promptcompat.StandardRequest) (promptcompat.StandardRequest, error) {
-	if stdReq.CurrentInputFileApplied || s.DS == nil || s.Store == nil || a == nil || !s.Store.CurrentInputFileEnabled() {
+	if s.DS == nil || a == nil {
 		return stdReq, nil
 	}
-	threshold := s.Store.CurrentInputFileMinChars()
-
-	index, text := latestUserInputForFile(stdReq.Messages)
-	if index < 0 {
-		return stdReq, nil
+	fileText, ok, err := s.currentInputFileText(stdReq)
+	if err != nil || !ok {
+		return stdReq, err
 	}
-	if len([]rune(text)) < threshold {
+	fileID, err := s.uploadHistoryFile(ctx, a, stdReq.ResolvedModel, fileText)
+	if err != nil {
+		return stdReq, err
+	}
+	stdReq = applyCurrentInputPrompt(stdReq, fileText)
+	stdReq.RefFileIDs = prependUniqueRefFileID(stdReq.RefFileIDs, fileID)
+	return stdReq, nil
+}
+
+// AttachHistoryFile uploads an already built transcript as
+// DS2API_HISTORY.txt for a request whose FinalPrompt is the continuation
+// prompt, as when replaying a recorded request.
+func (s Service) AttachHistoryFile(ctx context.Context, a *auth.RequestAuth, stdReq promptcompat.StandardRequest, fileText string) (promptcompat.StandardRequest, error) {
+	if s.DS == nil || a == nil || strings.TrimSpace(fileText) == "" {
 		return stdReq, nil
 	}
-	fileText := promptcompat.BuildOpenAICurrentInputContextTranscript(stdReq.Messages)
-	if strings.TrimSpace(fileText) == "" {
-		return stdReq, errors.New("current user input file produced empty transcript")
+	fileID, err := s.uploadHistoryFile(ctx, a, stdReq.ResolvedModel, fileText)
+	if err != nil {
+		return stdReq, err
 	}
+	stdReq.HistoryText = fileText
+	stdReq.CurrentInputFileApplied = true
+	stdReq.PromptTokenText = fileText + "\n" + stdReq.FinalPrompt
+	stdReq.RefFileIDs = prependUniqueRefFileID(stdReq.RefFileIDs, fileID)
+	return stdReq, nil
+}
+
+func (s Service) uploadHistoryFile(ctx context.Context, a *auth.RequestAuth, resolvedModel, fileText string) (string, error) {
+	ctx, span := tracing.Start(ctx, "current_input_file", tracing.Int("ds2api.current_input_file.bytes", len(fileText)))
+	defer span.End()
 	modelType := "default"
-	if resolvedType, ok := config.GetModelType(stdReq.ResolvedModel); ok {
+	if resolvedType, ok := config.GetModelType(resolvedModel); ok {
 		modelType = resolvedType
 	}
 	result, err := s.DS.UploadFile(ctx, a, dsclient.UploadFileRequest{
@@ -62,13 +84,49 @@ func (s Service) ApplyCurrentInputFile(ctx context.Context, a *auth.RequestAuth,
 		Data:        []byte(fileText),
 	}, 3)
 	if err != nil {
-		return stdReq, fmt.Errorf("upload current user input file: %w", err)
+		return "", fmt.Errorf("upload current user input file: %w", err)
 	}
 	fileID := strings.TrimSpace(result.ID)
 	if fileID == "" {
-		return stdReq, errors.New("upload current user input file returned empty file id")
+		return "", errors.New("upload current user input file returned empty file id")
+	}
+	return fileID, nil
+}
+
+// PreviewCurrentInputFile makes the same history file decision as
+// ApplyCurrentInputFile without uploading anything, so token counts match
+// what a real request would send.
+func (s Service) PreviewCurrentInputFile(stdReq promptcompat.StandardRequest) (promptcompat.StandardRequest, error) {
+	fileText, ok, err := s.currentInputFileText(stdReq)
+	if err != nil || !ok {
+		return stdReq, err
+	}
+	return applyCurrentInputPrompt(stdReq, fileText), nil
+}
+
+// currentInputFileText returns the history transcript when the latest user
+// input is long enough to be moved into DS2API_HISTORY.txt.
+func (s Service) currentInputFileText(stdReq promptcompat.StandardRequest) (string, bool, error) {
+	if stdReq.CurrentInputFileApplied || s.Store == nil || !s.Store.CurrentInputFileEnabled() {
+		return "", false, nil
+	}
+	threshold := s.Store.CurrentInputFileMinChars()
+
+	index, text := latestUserInputForFile(stdReq.Messages)
+	if index < 0 {
+		return "", false, nil
+	}
+	if len([]rune(text)) < threshold {
+		return "", false, nil
+	}
+	fileText := promptcompat.BuildOpenAICurrentInputContextTranscript(stdReq.Messages)
+	if strings.TrimSpace(fileText) == "" {
+		return "", false, errors.New("current user input file produced empty transcript")
 	}
+	return fileText, true, nil
+}
 
+func applyCurrentInputPrompt(stdReq promptcompat.StandardRequest, fileText string) promptcompat.StandardRequest {
 	messages := []any{
 		map[string]any{
 			"role":    "user",
@@ -79,12 +137,11 @@ func (s Service) ApplyCurrentInputFile(ctx context.Context, a *auth.RequestAuth,
 	stdReq.Messages = messages
 	stdReq.HistoryText = fileText
 	stdReq.CurrentInputFileApplied = true
-	stdReq.RefFileIDs = prependUniqueRefFileID(stdReq.RefFileIDs, fileID)
 	stdReq.FinalPrompt, stdReq.ToolNames = promptcompat.BuildOpenAIPrompt(messages, stdReq.ToolsRaw, "", stdReq.ToolChoice, stdReq.Thinking)
 	// Token accounting must reflect the actual downstream context:
 	// the uploaded DS2API_HISTORY.txt file content + the continuation live prompt.
 	stdReq.PromptTokenText = fileText + "\n" + stdReq.FinalPrompt
-	return stdReq, nil
+	return stdReq
 }
 
 func latestUserInputForFile(messages []any) (int, string) {
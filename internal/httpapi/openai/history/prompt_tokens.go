@@ -0,0 +1,74 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"ds2api/internal/httpapi/openai/shared"
+	"ds2api/internal/promptcompat"
+	"ds2api/internal/util"
+)
+
+// ContextLengthErrorCode is the OpenAI error code for prompts over the
+// model's context budget.
+const ContextLengthErrorCode = "context_length_exceeded"
+
+// PromptTokens counts the prompt tokens a normalized request would send
+// upstream: the thinking injection and history file decision are applied
+// exactly as for a real request, but nothing is uploaded.
+func PromptTokens(store shared.ConfigReader, stdReq promptcompat.StandardRequest) (int, error) {
+	stdReq = shared.ApplyThinkingInjection(store, stdReq)
+	preview, err := Service{Store: store}.PreviewCurrentInputFile(stdReq)
+	if err != nil {
+		return 0, err
+	}
+	text := preview.FinalPrompt
+	if preview.CurrentInputFileApplied && strings.TrimSpace(preview.PromptTokenText) != "" {
+		text = preview.PromptTokenText
+	}
+	return util.CountPromptTokens(text, preview.ResolvedModel) + preview.RefFileTokens, nil
+}
+
+// ContextLengthError reports a prompt over the model's token budget.
+type ContextLengthError struct {
+	Limit  int
+	Tokens int
+}
+
+func (e *ContextLengthError) Error() string {
+	return fmt.Sprintf("This model's maximum context length is %d tokens. However, your messages resulted in %d tokens. Please reduce the length of the messages.", e.Limit, e.Tokens)
+}
+
+// CheckContextLimit returns a *ContextLengthError when the preflight is
+// enabled and the request's prompt is over the model's token budget.
+func CheckContextLimit(store shared.ConfigReader, stdReq promptcompat.StandardRequest) error {
+	if store == nil || !store.ContextLimitEnabled() {
+		return nil
+	}
+	tokens, err := PromptTokens(store, stdReq)
+	if err != nil {
+		return err
+	}
+	return contextLengthError(store.ContextLimitTokens(stdReq.ResolvedModel), tokens)
+}
+
+func contextLengthError(limit, tokens int) error {
+	if limit > 0 && tokens > limit {
+		return &ContextLengthError{Limit: limit, Tokens: tokens}
+	}
+	return nil
+}
+
+// WriteContextLimitError writes the error returned by CheckContextLimit or
+// FitContextWindow.
+func WriteContextLimitError(w http.ResponseWriter, err error) {
+	var lengthErr *ContextLengthError
+	if errors.As(err, &lengthErr) {
+		shared.WriteOpenAIErrorWithCode(w, http.StatusBadRequest, lengthErr.Error(), ContextLengthErrorCode)
+		return
+	}
+	status, message := MapError(err)
+	shared.WriteOpenAIError(w, status, message)
+}
@@ -55,6 +55,10 @@ func (streamStatusManagedAuthStub) Determine(_ *http.Request) (*auth.RequestAuth
 	}, nil
 }
 
+func (s streamStatusManagedAuthStub) DetermineAdditional(r *http.Request) (*auth.RequestAuth, error) {
+	return s.Determine(r)
+}
+
 func (streamStatusManagedAuthStub) DetermineCaller(_ *http.Request) (*auth.RequestAuth, error) {
 	return (&streamStatusManagedAuthStub{}).Determine(nil)
 }
@@ -0,0 +1,348 @@
+package responses
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"ds2api/internal/auth"
+	"ds2api/internal/completionruntime"
+	"ds2api/internal/config"
+	openaifmt "ds2api/internal/format/openai"
+	"ds2api/internal/httpapi/openai/files"
+	"ds2api/internal/httpapi/openai/history"
+	"ds2api/internal/httpapi/openai/shared"
+	"ds2api/internal/inflight"
+	"ds2api/internal/promptcompat"
+	"ds2api/internal/responsehistory"
+	"ds2api/internal/streamresume"
+)
+
+// backgroundCancelWait bounds how long a cancel request waits for the worker
+// to stop before returning the stored response.
+const backgroundCancelWait = 10 * time.Second
+
+// maxBackgroundJobsPerCaller bounds the background responses one caller can
+// have queued or running at once. Each job holds a worker goroutine and a
+// resume buffer until it finishes.
+const maxBackgroundJobsPerCaller = 8
+
+type backgroundJob struct {
+	cancel context.CancelFunc
+	// finish removes the job's entry from the in-flight request registry.
+	finish func()
+	done   chan struct{}
+}
+
+// registerBackgroundJob records job unless owner already has
+// maxBackgroundJobsPerCaller jobs in progress.
+func (h *Handler) registerBackgroundJob(owner, id string, job *backgroundJob) bool {
+	h.jobsMu.Lock()
+	defer h.jobsMu.Unlock()
+	if h.jobs == nil {
+		h.jobs = map[string]*backgroundJob{}
+		h.jobCounts = map[string]int{}
+	}
+	if h.jobCounts[owner] >= maxBackgroundJobsPerCaller {
+		return false
+	}
+	h.jobs[responseStoreKey(owner, id)] = job
+	h.jobCounts[owner]++
+	return true
+}
+
+func (h *Handler) backgroundJob(owner, id string) (*backgroundJob, bool) {
+	h.jobsMu.Lock()
+	defer h.jobsMu.Unlock()
+	job, ok := h.jobs[responseStoreKey(owner, id)]
+	return job, ok
+}
+
+func (h *Handler) removeBackgroundJob(owner, id string) {
+	h.jobsMu.Lock()
+	defer h.jobsMu.Unlock()
+	key := responseStoreKey(owner, id)
+	if _, ok := h.jobs[key]; !ok {
+		return
+	}
+	delete(h.jobs, key)
+	if h.jobCounts[owner]--; h.jobCounts[owner] <= 0 {
+		delete(h.jobCounts, owner)
+	}
+}
+
+// startBackgroundResponse validates the request, stores a queued response
+// and hands the completion to a worker. Streaming requests follow the
+// worker's event buffer; the others return the queued object immediately.
+func (h *Handler) startBackgroundResponse(w http.ResponseWriter, r *http.Request, owner string, req map[string]any) {
+	if store, ok := req["store"].(bool); ok && !store {
+		writeOpenAIError(w, http.StatusBadRequest, "background responses require store to be enabled.")
+		return
+	}
+	traceID := requestTraceID(r)
+	stdReq, err := promptcompat.NormalizeOpenAIResponsesRequest(h.Store, req, traceID)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// Other strategies need an account to run, so the worker applies them.
+	if h.Store.ContextLimitStrategy() == config.ContextStrategyReject {
+		if err := h.checkContextLimit(stdReq); err != nil {
+			writeContextLimitError(w, err)
+			return
+		}
+	}
+
+	responseID := "resp_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	// The worker is registered as its own in-flight request, so it shows up
+	// in /admin/requests, can be cancelled there, and drain waits for it.
+	spawned, finish := inflight.Spawn(r.Context())
+	ctx, cancel := context.WithCancel(spawned)
+	job := &backgroundJob{cancel: cancel, finish: finish, done: make(chan struct{})}
+	if !h.registerBackgroundJob(owner, responseID, job) {
+		cancel()
+		finish()
+		writeOpenAIErrorWithCode(w, http.StatusTooManyRequests, fmt.Sprintf("Too many background responses in progress; at most %d per caller.", maxBackgroundJobsPerCaller), "too_many_background_responses")
+		return
+	}
+	buf, err := h.getResumeHub().Start(owner, responseID)
+	if err != nil {
+		h.removeBackgroundJob(owner, responseID)
+		cancel()
+		finish()
+		writeOpenAIErrorWithCode(w, http.StatusServiceUnavailable, "Too many resumable streams in flight, retry later.", "resume_buffers_full")
+		return
+	}
+	queued := backgroundResponseObject(responseID, stdReq.ResponseModel, "queued")
+	h.getResponseStore().put(owner, responseID, queued)
+	go h.runBackgroundResponse(r.Clone(ctx), buf, job, owner, responseID, stdReq.ResponseModel, req, traceID)
+
+	if stdReq.Stream {
+		_ = buf.Serve(r.Context(), w, 0, shared.ResumeKeepAliveInterval)
+		return
+	}
+	writeJSON(w, http.StatusOK, queued)
+}
+
+func (h *Handler) runBackgroundResponse(r *http.Request, buf *streamresume.Buffer, job *backgroundJob, owner, responseID, model string, req map[string]any, traceID string) {
+	defer func() {
+		buf.Close()
+		h.removeBackgroundJob(owner, responseID)
+		job.cancel()
+		job.finish()
+		close(job.done)
+	}()
+	fail := func(status int, message, code string) {
+		if r.Context().Err() != nil {
+			h.cancelBackgroundResponse(buf, owner, responseID, model)
+			return
+		}
+		h.failBackgroundResponse(buf, owner, responseID, model, status, message, code)
+	}
+
+	// Determine waits in the account pool queue like any other request, so
+	// background workers never exceed the configured concurrency.
+	a, err := h.Auth.Determine(r)
+	if err != nil {
+		status, code := http.StatusUnauthorized, "unauthorized"
+		if errors.Is(err, auth.ErrNoAccount) {
+			status, code = http.StatusTooManyRequests, "no_account_available"
+		}
+		fail(status, err.Error(), code)
+		return
+	}
+	defer h.Auth.Release(a)
+	r = r.WithContext(auth.WithAuth(r.Context(), a))
+	h.updateBackgroundStatus(owner, responseID, "in_progress")
+
+	if err := h.preprocessInlineFileInputs(r.Context(), a, req); err != nil {
+		status, message := files.InlineFileErrorStatus(err)
+		fail(status, message, "invalid_file")
+		return
+	}
+	stdReq, err := promptcompat.NormalizeOpenAIResponsesRequest(h.Store, req, traceID)
+	if err != nil {
+		fail(http.StatusBadRequest, err.Error(), "invalid_request_error")
+		return
+	}
+	stdReq, _, err = h.fitContextWindow(r.Context(), a, stdReq)
+	if err != nil {
+		var lengthErr *history.ContextLengthError
+		if errors.As(err, &lengthErr) {
+			fail(http.StatusBadRequest, lengthErr.Error(), history.ContextLengthErrorCode)
+			return
+		}
+		status, message := mapCurrentInputFileError(err)
+		fail(status, message, "error")
+		return
+	}
+	stdReq, err = h.applyCurrentInputFile(r.Context(), a, stdReq)
+	if err != nil {
+		status, message := mapCurrentInputFileError(err)
+		fail(status, message, "error")
+		return
+	}
+	historySession := responsehistory.Start(responsehistory.StartParams{
+		Store:    h.ChatHistory,
+		Request:  r,
+		Auth:     a,
+		Surface:  "openai.responses",
+		Standard: stdReq,
+	})
+	start, outErr := completionruntime.StartCompletion(r.Context(), h.DS, a, stdReq, completionruntime.Options{
+		CurrentInputFile: h.Store,
+	})
+	if outErr != nil {
+		if historySession != nil {
+			historySession.Error(outErr.Status, outErr.Message, outErr.Code, "", "")
+		}
+		fail(outErr.Status, outErr.Message, outErr.Code)
+		return
+	}
+	streamReq := start.Request
+	h.handleResponsesStreamWithRetry(buf, r, a, start.Response, start.Payload, start.Pow, owner, responseID, streamReq.ResponseModel, streamReq.PromptTokenText, streamReq.RefFileTokens, streamReq.Thinking, streamReq.Search, streamReq.ToolNames, streamReq.ToolsRaw, streamReq.ToolChoice, streamReq.OutputLimits(), traceID, historySession)
+	if r.Context().Err() != nil {
+		h.markBackgroundCancelled(owner, responseID, model)
+		return
+	}
+	h.finishBackgroundResponse(owner, responseID, model)
+}
+
+// CancelResponse aborts a running background response. The worker's
+// context is cancelled, which stops the upstream read and releases the
+// pooled account. Responses that already completed or failed cannot be
+// cancelled.
+func (h *Handler) CancelResponse(w http.ResponseWriter, r *http.Request) {
+	a, err := h.Auth.DetermineCaller(r)
+	if err != nil {
+		writeOpenAIError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	owner := responseStoreOwner(a)
+	if owner == "" {
+		writeOpenAIError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	id := strings.TrimSpace(chi.URLParam(r, "response_id"))
+	st := h.getResponseStore()
+	if job, ok := h.backgroundJob(owner, id); ok {
+		job.cancel()
+		select {
+		case <-job.done:
+		case <-time.After(backgroundCancelWait):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	item, ok := st.get(owner, id)
+	if !ok {
+		writeOpenAIError(w, http.StatusNotFound, "Response not found.")
+		return
+	}
+	if background, _ := item["background"].(bool); !background {
+		writeOpenAIError(w, http.StatusBadRequest, "Only background responses can be cancelled.")
+		return
+	}
+	switch status, _ := item["status"].(string); status {
+	case "completed", "failed", "incomplete":
+		writeOpenAIErrorWithCode(w, http.StatusConflict, "Cannot cancel a "+status+" response.", "invalid_state")
+		return
+	}
+	writeJSON(w, http.StatusOK, item)
+}
+
+func backgroundResponseObject(responseID, model, status string) map[string]any {
+	return map[string]any{
+		"id":          responseID,
+		"type":        "response",
+		"object":      "response",
+		"created_at":  time.Now().Unix(),
+		"model":       model,
+		"status":      status,
+		"background":  true,
+		"output":      []any{},
+		"output_text": "",
+	}
+}
+
+func (h *Handler) updateBackgroundStatus(owner, responseID, status string) {
+	st := h.getResponseStore()
+	item, ok := st.get(owner, responseID)
+	if !ok {
+		return
+	}
+	item["status"] = status
+	st.put(owner, responseID, item)
+}
+
+// finishBackgroundResponse flags the stored object as a background response.
+// The stream runtime persists completed and failed responses itself; an
+// upstream error returned before streaming leaves it in_progress, which is
+// recorded as failed here.
+func (h *Handler) finishBackgroundResponse(owner, responseID, model string) {
+	st := h.getResponseStore()
+	item, ok := st.get(owner, responseID)
+	if !ok {
+		return
+	}
+	if status, _ := item["status"].(string); status == "queued" || status == "in_progress" {
+		item = backgroundResponseObject(responseID, model, "failed")
+		item["error"] = map[string]any{
+			"message": "Upstream request failed.",
+			"type":    openAIErrorType(http.StatusBadGateway),
+			"code":    "upstream_error",
+			"param":   nil,
+		}
+	}
+	item["background"] = true
+	st.put(owner, responseID, item)
+}
+
+func (h *Handler) markBackgroundCancelled(owner, responseID, model string) map[string]any {
+	st := h.getResponseStore()
+	item, ok := st.get(owner, responseID)
+	if !ok {
+		item = backgroundResponseObject(responseID, model, "cancelled")
+	}
+	item["background"] = true
+	item["status"] = "cancelled"
+	delete(item, "error")
+	st.put(owner, responseID, item)
+	config.Logger.Info("[responses] background response cancelled", "response_id", responseID)
+	return item
+}
+
+// cancelBackgroundResponse records a cancel that happened before the stream
+// runtime started, so followers get a terminal event instead of an empty
+// stream.
+func (h *Handler) cancelBackgroundResponse(buf *streamresume.Buffer, owner, responseID, model string) {
+	item := h.markBackgroundCancelled(owner, responseID, model)
+	buf.Header().Set("Content-Type", "text/event-stream")
+	rt := &responsesStreamRuntime{w: buf}
+	rt.sendEvent("response.cancelled", openaifmt.BuildResponsesCancelledPayload(item))
+	rt.sendDone()
+}
+
+// failBackgroundResponse records a failure that happened before the stream
+// runtime started, and mirrors it into the event buffer for followers.
+func (h *Handler) failBackgroundResponse(buf *streamresume.Buffer, owner, responseID, model string, status int, message, code string) {
+	item := backgroundResponseObject(responseID, model, "failed")
+	item["error"] = map[string]any{
+		"message": message,
+		"type":    openAIErrorType(status),
+		"code":    code,
+		"param":   nil,
+	}
+	h.getResponseStore().put(owner, responseID, item)
+	buf.Header().Set("Content-Type", "text/event-stream")
+	payload := openaifmt.BuildResponsesFailedPayload(responseID, model, status, message, code)
+	rt := &responsesStreamRuntime{w: buf}
+	rt.sendEvent("response.failed", payload)
+	rt.sendDone()
+}
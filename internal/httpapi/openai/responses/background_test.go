@@ -0,0 +1,313 @@
+package responses
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"ds2api/internal/auth"
+	"ds2api/internal/inflight"
+)
+
+type blockingResponsesDS struct {
+	responsesHistoryDS
+	started chan struct{}
+}
+
+func (d *blockingResponsesDS) CallCompletion(ctx context.Context, _ *auth.RequestAuth, _ map[string]any, _ string, _ int) (*http.Response, error) {
+	pr, pw := io.Pipe()
+	go func() {
+		_, _ = pw.Write([]byte("data: {\"p\":\"response/content\",\"v\":\"partial\"}\n\n"))
+		<-ctx.Done()
+		_ = pw.CloseWithError(ctx.Err())
+	}()
+	close(d.started)
+	return &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: pr}, nil
+}
+
+func postBackgroundResponse(t *testing.T, r http.Handler, body string) map[string]any {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/v1/responses", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer direct-token")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	return decodeJSONBody(t, rec.Body.String())
+}
+
+func pollResponseStatus(t *testing.T, r http.Handler, id string, want string) map[string]any {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		req := httptest.NewRequest(http.MethodGet, "/v1/responses/"+id, nil)
+		req.Header.Set("Authorization", "Bearer direct-token")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+		}
+		out := decodeJSONBody(t, rec.Body.String())
+		if asString(out["status"]) == want {
+			return out
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for status %q, last=%v", want, out)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestBackgroundResponseCompletesAndIsPollable(t *testing.T) {
+	store, resolver := newDirectTokenResolver(t)
+	h := &Handler{Store: store, Auth: resolver, DS: &responsesHistoryDS{}}
+	r := chi.NewRouter()
+	RegisterRoutes(r, h)
+
+	queued := postBackgroundResponse(t, r, `{"model":"deepseek-v4-flash","input":"hello","background":true}`)
+	if asString(queued["status"]) != "queued" || queued["background"] != true {
+		t.Fatalf("expected queued background response, got %v", queued)
+	}
+	id := asString(queued["id"])
+	done := pollResponseStatus(t, r, id, "completed")
+	if asString(done["output_text"]) != "ok" || done["background"] != true {
+		t.Fatalf("unexpected completed response: %v", done)
+	}
+}
+
+func TestBackgroundResponseRejectsStoreFalse(t *testing.T) {
+	store, resolver := newDirectTokenResolver(t)
+	h := &Handler{Store: store, Auth: resolver, DS: &responsesHistoryDS{}}
+	r := chi.NewRouter()
+	RegisterRoutes(r, h)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/responses", strings.NewReader(`{"model":"deepseek-v4-flash","input":"hello","background":true,"store":false}`))
+	req.Header.Set("Authorization", "Bearer direct-token")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCancelBackgroundResponseStopsWorker(t *testing.T) {
+	store, resolver := newDirectTokenResolver(t)
+	ds := &blockingResponsesDS{started: make(chan struct{})}
+	h := &Handler{Store: store, Auth: resolver, DS: ds}
+	r := chi.NewRouter()
+	RegisterRoutes(r, h)
+
+	queued := postBackgroundResponse(t, r, `{"model":"deepseek-v4-flash","input":"hello","background":true}`)
+	id := asString(queued["id"])
+	select {
+	case <-ds.started:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("worker did not call upstream")
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/responses/"+id+"/cancel", nil)
+	req.Header.Set("Authorization", "Bearer direct-token")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	out := decodeJSONBody(t, rec.Body.String())
+	if asString(out["status"]) != "cancelled" {
+		t.Fatalf("expected cancelled response, got %v", out)
+	}
+	h.jobsMu.Lock()
+	running := len(h.jobs)
+	h.jobsMu.Unlock()
+	if running != 0 {
+		t.Fatalf("expected worker to be removed after cancel")
+	}
+}
+
+func TestCancelRejectsForegroundResponse(t *testing.T) {
+	store, resolver := newDirectTokenResolver(t)
+	h := &Handler{Store: store, Auth: resolver, DS: &responsesHistoryDS{}}
+	r := chi.NewRouter()
+	RegisterRoutes(r, h)
+
+	created := postBackgroundResponse(t, r, `{"model":"deepseek-v4-flash","input":"hello"}`)
+	req := httptest.NewRequest(http.MethodPost, "/v1/responses/"+asString(created["id"])+"/cancel", nil)
+	req.Header.Set("Authorization", "Bearer direct-token")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+type stalledSessionResponsesDS struct {
+	responsesHistoryDS
+	started chan struct{}
+}
+
+func (d *stalledSessionResponsesDS) CreateSession(ctx context.Context, _ *auth.RequestAuth, _ int) (string, error) {
+	close(d.started)
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func cancelResponse(t *testing.T, r http.Handler, id string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/v1/responses/"+id+"/cancel", nil)
+	req.Header.Set("Authorization", "Bearer direct-token")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCancelRejectsCompletedBackgroundResponse(t *testing.T) {
+	store, resolver := newDirectTokenResolver(t)
+	h := &Handler{Store: store, Auth: resolver, DS: &responsesHistoryDS{}}
+	r := chi.NewRouter()
+	RegisterRoutes(r, h)
+
+	queued := postBackgroundResponse(t, r, `{"model":"deepseek-v4-flash","input":"hello","background":true}`)
+	id := asString(queued["id"])
+	pollResponseStatus(t, r, id, "completed")
+
+	rec := cancelResponse(t, r, id)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), "invalid_state") {
+		t.Fatalf("expected invalid_state code, got %s", rec.Body.String())
+	}
+}
+
+func TestCancelBeforeStartSendsCancelledEventToFollowers(t *testing.T) {
+	store, resolver := newDirectTokenResolver(t)
+	ds := &stalledSessionResponsesDS{started: make(chan struct{})}
+	h := &Handler{Store: store, Auth: resolver, DS: ds}
+	r := chi.NewRouter()
+	RegisterRoutes(r, h)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/responses", strings.NewReader(`{"model":"deepseek-v4-flash","input":"hello","background":true,"stream":true}`))
+	req.Header.Set("Authorization", "Bearer direct-token")
+	follower := httptest.NewRecorder()
+	followed := make(chan struct{})
+	go func() {
+		defer close(followed)
+		r.ServeHTTP(follower, req)
+	}()
+	select {
+	case <-ds.started:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("worker did not create a session")
+	}
+
+	h.jobsMu.Lock()
+	var id string
+	for key := range h.jobs {
+		id = key[strings.IndexByte(key, 0)+1:]
+	}
+	h.jobsMu.Unlock()
+	rec := cancelResponse(t, r, id)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if out := decodeJSONBody(t, rec.Body.String()); asString(out["status"]) != "cancelled" {
+		t.Fatalf("expected cancelled response, got %v", out)
+	}
+
+	select {
+	case <-followed:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("follower did not finish after cancel")
+	}
+	body := follower.Body.String()
+	if !strings.Contains(body, "event: response.cancelled") || !strings.Contains(body, `"status":"cancelled"`) {
+		t.Fatalf("expected cancelled event for follower, got %q", body)
+	}
+}
+
+// parkedSessionResponsesDS holds every worker in CreateSession until its
+// context is cancelled.
+type parkedSessionResponsesDS struct {
+	responsesHistoryDS
+	parked chan struct{}
+}
+
+func (d *parkedSessionResponsesDS) CreateSession(ctx context.Context, _ *auth.RequestAuth, _ int) (string, error) {
+	d.parked <- struct{}{}
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func stopBackgroundJobs(h *Handler) {
+	h.jobsMu.Lock()
+	jobs := make([]*backgroundJob, 0, len(h.jobs))
+	for _, job := range h.jobs {
+		jobs = append(jobs, job)
+	}
+	h.jobsMu.Unlock()
+	for _, job := range jobs {
+		job.cancel()
+		<-job.done
+	}
+}
+
+func TestBackgroundResponsesAreLimitedPerCaller(t *testing.T) {
+	store, resolver := newDirectTokenResolver(t)
+	ds := &parkedSessionResponsesDS{parked: make(chan struct{}, maxBackgroundJobsPerCaller)}
+	h := &Handler{Store: store, Auth: resolver, DS: ds}
+	r := chi.NewRouter()
+	RegisterRoutes(r, h)
+	defer stopBackgroundJobs(h)
+
+	for i := 0; i < maxBackgroundJobsPerCaller; i++ {
+		postBackgroundResponse(t, r, `{"model":"deepseek-v4-flash","input":"hello","background":true}`)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/v1/responses", strings.NewReader(`{"model":"deepseek-v4-flash","input":"hello","background":true}`))
+	req.Header.Set("Authorization", "Bearer direct-token")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusTooManyRequests || !strings.Contains(rec.Body.String(), "too_many_background_responses") {
+		t.Fatalf("expected 429 past the per-caller limit, got %d body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestBackgroundWorkerIsRegisteredForAdminCancel(t *testing.T) {
+	store, resolver := newDirectTokenResolver(t)
+	ds := &parkedSessionResponsesDS{parked: make(chan struct{}, 1)}
+	h := &Handler{Store: store, Auth: resolver, DS: ds}
+	r := chi.NewRouter()
+	RegisterRoutes(r, h)
+	requests := inflight.NewRegistry()
+	served := requests.Middleware(r)
+
+	queued := postBackgroundResponse(t, served, `{"model":"deepseek-v4-flash","input":"hello","background":true}`)
+	select {
+	case <-ds.parked:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("worker did not create a session")
+	}
+	running := requests.List()
+	if len(running) != 1 || !running[0].Background || requests.InUse() != 1 {
+		t.Fatalf("expected the worker to stay registered after its request returned, got %+v", running)
+	}
+	done, ok := requests.Cancel(running[0].ID)
+	if !ok {
+		t.Fatal("expected admin cancel to find the worker")
+	}
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("worker did not stop after admin cancel")
+	}
+	pollResponseStatus(t, served, asString(queued["id"]), "cancelled")
+	if requests.InUse() != 0 {
+		t.Fatalf("expected no running requests after the worker stopped, got %d", requests.InUse())
+	}
+}
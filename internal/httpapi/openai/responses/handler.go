@@ -7,10 +7,13 @@ import (
 
 	"ds2api/internal/auth"
 	"ds2api/internal/chathistory"
+	"ds2api/internal/completionruntime"
+	"ds2api/internal/contextwindow"
 	"ds2api/internal/httpapi/openai/files"
 	"ds2api/internal/httpapi/openai/history"
 	"ds2api/internal/httpapi/openai/shared"
 	"ds2api/internal/promptcompat"
+	"ds2api/internal/streamresume"
 	"ds2api/internal/textclean"
 	"ds2api/internal/toolstream"
 )
@@ -27,6 +30,11 @@ type Handler struct {
 
 	responsesMu sync.Mutex
 	responses   *responseStore
+	resumeHub   *streamresume.Hub
+
+	jobsMu    sync.Mutex
+	jobs      map[string]*backgroundJob
+	jobCounts map[string]int
 }
 
 func stripReferenceMarkersEnabled() bool {
@@ -46,6 +54,20 @@ func (h *Handler) applyCurrentInputFile(ctx context.Context, a *auth.RequestAuth
 	return out, nil
 }
 
+func (h *Handler) fitContextWindow(ctx context.Context, a *auth.RequestAuth, stdReq promptcompat.StandardRequest) (promptcompat.StandardRequest, contextwindow.Report, error) {
+	if h == nil {
+		return stdReq, contextwindow.Report{}, nil
+	}
+	return history.FitContextWindow(ctx, h.Store, stdReq, completionruntime.ContextSummarizer(h.DS, h.Store, a, nil))
+}
+
+func (h *Handler) checkContextLimit(stdReq promptcompat.StandardRequest) error {
+	if h == nil {
+		return nil
+	}
+	return history.CheckContextLimit(h.Store, stdReq)
+}
+
 func (h *Handler) preprocessInlineFileInputs(ctx context.Context, a *auth.RequestAuth, req map[string]any) error {
 	if h == nil {
 		return nil
@@ -83,6 +105,14 @@ func writeOpenAIInlineFileError(w http.ResponseWriter, err error) {
 	files.WriteInlineFileError(w, err)
 }
 
+func setContextWindowHeader(w http.ResponseWriter, report contextwindow.Report) {
+	history.SetContextWindowHeader(w, report)
+}
+
+func writeContextLimitError(w http.ResponseWriter, err error) {
+	history.WriteContextLimitError(w, err)
+}
+
 func mapCurrentInputFileError(err error) (int, string) {
 	return history.MapError(err)
 }
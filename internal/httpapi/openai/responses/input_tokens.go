@@ -0,0 +1,47 @@
+package responses
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"ds2api/internal/httpapi/openai/history"
+	"ds2api/internal/promptcompat"
+)
+
+// InputTokens counts the prompt tokens a Responses request would send
+// upstream. The prompt is built exactly as for POST /v1/responses (tools,
+// injected thinking prompt, history file decision) but nothing is uploaded
+// and no account slot is taken.
+func (h *Handler) InputTokens(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.Auth.DetermineCaller(r); err != nil {
+		writeOpenAIError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, openAIGeneralMaxSize)
+	var req map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "too large") {
+			writeOpenAIError(w, http.StatusRequestEntityTooLarge, "request body too large")
+			return
+		}
+		writeOpenAIError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	stdReq, err := promptcompat.NormalizeOpenAIResponsesRequest(h.Store, req, requestTraceID(r))
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	tokens, err := history.PromptTokens(h.Store, stdReq)
+	if err != nil {
+		status, message := mapCurrentInputFileError(err)
+		writeOpenAIError(w, status, message)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"object":       "response.input_tokens",
+		"input_tokens": tokens,
+	})
+}
@@ -0,0 +1,80 @@
+package responses
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"ds2api/internal/account"
+	"ds2api/internal/auth"
+	"ds2api/internal/config"
+)
+
+func newInputTokensRouter(t *testing.T, cfg string) http.Handler {
+	t.Helper()
+	t.Setenv("DS2API_CONFIG_JSON", cfg)
+	store := config.LoadStore()
+	resolver := auth.NewResolver(store, account.NewPool(store), func(_ context.Context, _ config.Account) (string, error) {
+		return "unused", nil
+	})
+	r := chi.NewRouter()
+	RegisterRoutes(r, &Handler{Store: store, Auth: resolver})
+	return r
+}
+
+func postInputTokens(t *testing.T, r http.Handler, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer token-a")
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestResponsesInputTokensCountsFullPrompt(t *testing.T) {
+	r := newInputTokensRouter(t, `{"keys":[],"accounts":[],"current_input_file":{"enabled":false}}`)
+
+	plain := postInputTokens(t, r, "/v1/responses/input_tokens", `{"model":"gpt-4o","input":"hello there"}`)
+	if plain.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", plain.Code, plain.Body.String())
+	}
+	out := decodeJSONBody(t, plain.Body.String())
+	if out["object"] != "response.input_tokens" {
+		t.Fatalf("unexpected object: %#v", out)
+	}
+	plainTokens, _ := out["input_tokens"].(float64)
+	if plainTokens <= 0 {
+		t.Fatalf("expected positive input_tokens, got %#v", out)
+	}
+
+	withTools := postInputTokens(t, r, "/v1/responses/input_tokens", `{"model":"gpt-4o","input":"hello there","tools":[{"type":"function","name":"lookup_weather","description":"Look up the current weather for a city.","parameters":{"type":"object","properties":{"city":{"type":"string"}}}}]}`)
+	toolTokens, _ := decodeJSONBody(t, withTools.Body.String())["input_tokens"].(float64)
+	if toolTokens <= plainTokens {
+		t.Fatalf("expected tool schema to add tokens: plain=%v tools=%v", plainTokens, toolTokens)
+	}
+}
+
+func TestResponsesContextLimitPreflightRejectsLongPrompt(t *testing.T) {
+	r := newInputTokensRouter(t, `{"keys":[],"accounts":[],"current_input_file":{"enabled":false},"context_limit":{"enabled":true,"default_tokens":1024}}`)
+
+	body := `{"model":"gpt-4o","input":"` + strings.Repeat("lorem ipsum dolor sit amet ", 400) + `"}`
+	rec := postInputTokens(t, r, "/v1/responses", body)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	errObj, _ := decodeJSONBody(t, rec.Body.String())["error"].(map[string]any)
+	if errObj["code"] != "context_length_exceeded" || !strings.Contains(asString(errObj["message"]), "maximum context length is 1024 tokens") {
+		t.Fatalf("unexpected error: %#v", errObj)
+	}
+
+	short := postInputTokens(t, r, "/v1/responses/input_tokens", `{"model":"gpt-4o","input":"hi"}`)
+	if short.Code != http.StatusOK {
+		t.Fatalf("input_tokens must not be subject to the preflight, got %d", short.Code)
+	}
+}
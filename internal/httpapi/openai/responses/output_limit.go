@@ -0,0 +1,14 @@
+package responses
+
+import "ds2api/internal/assistantturn"
+
+// markResponseIncomplete flags a response cut off by max_output_tokens the
+// way the Responses API reports it. It returns true when the status changed.
+func markResponseIncomplete(obj map[string]any, turn assistantturn.Turn) bool {
+	if obj == nil || turn.StopReason != assistantturn.StopReasonLength {
+		return false
+	}
+	obj["status"] = "incomplete"
+	obj["incomplete_details"] = map[string]any{"reason": "max_output_tokens"}
+	return true
+}
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"ds2api/internal/auth"
+	"ds2api/internal/httpapi/openai/shared"
+	"ds2api/internal/streamresume"
 )
 
 type storedResponse struct {
@@ -92,6 +94,15 @@ func cloneAnyMap(in map[string]any) map[string]any {
 	return out
 }
 
+func (h *Handler) getResumeHub() *streamresume.Hub {
+	h.responsesMu.Lock()
+	defer h.responsesMu.Unlock()
+	if h.resumeHub == nil {
+		h.resumeHub = shared.NewResumeHub(h.Store)
+	}
+	return h.resumeHub
+}
+
 func (h *Handler) getResponseStore() *responseStore {
 	if h == nil {
 		return nil
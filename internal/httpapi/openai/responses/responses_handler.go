@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,10 +18,13 @@ import (
 	"ds2api/internal/config"
 	dsprotocol "ds2api/internal/deepseek/protocol"
 	openaifmt "ds2api/internal/format/openai"
+	"ds2api/internal/httpapi/openai/shared"
 	"ds2api/internal/promptcompat"
 	"ds2api/internal/responsehistory"
 	"ds2api/internal/sse"
 	streamengine "ds2api/internal/stream"
+	"ds2api/internal/streamresume"
+	"ds2api/internal/util"
 )
 
 func (h *Handler) GetResponseByID(w http.ResponseWriter, r *http.Request) {
@@ -40,6 +44,15 @@ func (h *Handler) GetResponseByID(w http.ResponseWriter, r *http.Request) {
 		writeOpenAIError(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
+	if r.URL.Query().Get("stream") == "true" {
+		afterSeq, ok := resumeStartingAfter(r)
+		if !ok {
+			writeOpenAIError(w, http.StatusBadRequest, "starting_after must be a non-negative integer.")
+			return
+		}
+		shared.ServeResumedStream(w, r, h.getResumeHub(), owner, id, afterSeq)
+		return
+	}
 	st := h.getResponseStore()
 	item, ok := st.get(owner, id)
 	if !ok {
@@ -50,19 +63,12 @@ func (h *Handler) GetResponseByID(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) Responses(w http.ResponseWriter, r *http.Request) {
-	a, err := h.Auth.Determine(r)
+	caller, err := h.Auth.DetermineCaller(r)
 	if err != nil {
-		status := http.StatusUnauthorized
-		detail := err.Error()
-		if err == auth.ErrNoAccount {
-			status = http.StatusTooManyRequests
-		}
-		writeOpenAIError(w, status, detail)
+		writeOpenAIError(w, http.StatusUnauthorized, err.Error())
 		return
 	}
-	defer h.Auth.Release(a)
-	r = r.WithContext(auth.WithAuth(r.Context(), a))
-	owner := responseStoreOwner(a)
+	owner := responseStoreOwner(caller)
 	if owner == "" {
 		writeOpenAIError(w, http.StatusUnauthorized, "unauthorized")
 		return
@@ -78,6 +84,25 @@ func (h *Handler) Responses(w http.ResponseWriter, r *http.Request) {
 		writeOpenAIError(w, http.StatusBadRequest, "invalid json")
 		return
 	}
+	// Background responses acquire their account in the worker, so the
+	// request returns without waiting for a free pool slot.
+	if util.ToBool(req["background"]) {
+		h.startBackgroundResponse(w, r, owner, req)
+		return
+	}
+
+	a, err := h.Auth.Determine(r)
+	if err != nil {
+		status := http.StatusUnauthorized
+		detail := err.Error()
+		if err == auth.ErrNoAccount {
+			status = http.StatusTooManyRequests
+		}
+		writeOpenAIError(w, status, detail)
+		return
+	}
+	defer h.Auth.Release(a)
+	r = r.WithContext(auth.WithAuth(r.Context(), a))
 	if err := h.preprocessInlineFileInputs(r.Context(), a, req); err != nil {
 		writeOpenAIInlineFileError(w, err)
 		return
@@ -88,6 +113,12 @@ func (h *Handler) Responses(w http.ResponseWriter, r *http.Request) {
 		writeOpenAIError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	stdReq, contextReport, err := h.fitContextWindow(r.Context(), a, stdReq)
+	setContextWindowHeader(w, contextReport)
+	if err != nil {
+		writeContextLimitError(w, err)
+		return
+	}
 	stdReq, err = h.applyCurrentInputFile(r.Context(), a, stdReq)
 	if err != nil {
 		status, message := mapCurrentInputFileError(err)
@@ -119,7 +150,10 @@ func (h *Handler) Responses(w http.ResponseWriter, r *http.Request) {
 			historySession.SuccessTurn(http.StatusOK, result.Turn, assistantturn.OpenAIResponsesUsage(result.Turn))
 		}
 		responseObj := openaifmt.BuildResponseObjectWithToolCalls(responseID, stdReq.ResponseModel, result.Turn.Prompt, result.Turn.Thinking, result.Turn.Text, result.Turn.ToolCalls, stdReq.ToolsRaw)
+		shared.AttachResponsesAnnotations(responseObj, result.Turn.Text, result.Turn.CitationSpans)
+		attachWebSearchCall(responseObj, result.Turn.Citations)
 		responseObj["usage"] = assistantturn.OpenAIResponsesUsage(result.Turn)
+		markResponseIncomplete(responseObj, result.Turn)
 		h.getResponseStore().put(owner, responseID, responseObj)
 		writeJSON(w, http.StatusOK, responseObj)
 		return
@@ -138,7 +172,29 @@ func (h *Handler) Responses(w http.ResponseWriter, r *http.Request) {
 
 	streamReq := start.Request
 	refFileTokens := streamReq.RefFileTokens
-	h.handleResponsesStreamWithRetry(w, r, a, start.Response, start.Payload, start.Pow, owner, responseID, streamReq.ResponseModel, streamReq.PromptTokenText, refFileTokens, streamReq.Thinking, streamReq.Search, streamReq.ToolNames, streamReq.ToolsRaw, streamReq.ToolChoice, traceID, historySession)
+	generate := func(w http.ResponseWriter, r *http.Request) {
+		h.handleResponsesStreamWithRetry(w, r, a, start.Response, start.Payload, start.Pow, owner, responseID, streamReq.ResponseModel, streamReq.PromptTokenText, refFileTokens, streamReq.Thinking, streamReq.Search, streamReq.ToolNames, streamReq.ToolsRaw, streamReq.ToolChoice, streamReq.OutputLimits(), traceID, historySession)
+	}
+	if streamReq.Resumable {
+		shared.RunResumable(w, r, h.getResumeHub(), owner, responseID, generate)
+		return
+	}
+	generate(w, r)
+}
+
+// resumeStartingAfter reads the replay point from starting_after, falling
+// back to Last-Event-ID. Buffer sequence numbers match the events'
+// sequence_number.
+func resumeStartingAfter(r *http.Request) (int, bool) {
+	if raw := strings.TrimSpace(r.URL.Query().Get("starting_after")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		return n, err == nil && n >= 0
+	}
+	if raw := strings.TrimSpace(r.Header.Get("Last-Event-ID")); raw != "" {
+		_, seq, ok := streamresume.ParseEventID(raw)
+		return seq, ok
+	}
+	return 0, true
 }
 
 func (h *Handler) handleResponsesNonStream(w http.ResponseWriter, resp *http.Response, owner, responseID, model, finalPrompt string, refFileTokens int, thinkingEnabled, searchEnabled bool, toolNames []string, toolsRaw any, toolChoice promptcompat.ToolChoicePolicy, traceID string) {
@@ -167,6 +223,8 @@ func (h *Handler) handleResponsesNonStream(w http.ResponseWriter, resp *http.Res
 	}
 
 	responseObj := openaifmt.BuildResponseObjectWithToolCalls(responseID, model, finalPrompt, turn.Thinking, turn.Text, turn.ToolCalls, toolsRaw)
+	shared.AttachResponsesAnnotations(responseObj, turn.Text, turn.CitationSpans)
+	attachWebSearchCall(responseObj, turn.Citations)
 	responseObj["usage"] = assistantturn.OpenAIResponsesUsage(turn)
 	h.getResponseStore().put(owner, responseID, responseObj)
 	writeJSON(w, http.StatusOK, responseObj)
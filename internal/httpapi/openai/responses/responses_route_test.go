@@ -174,3 +174,37 @@ func TestGetResponseByIDManagedKeySkipsAccountPoolPressure(t *testing.T) {
 		t.Fatalf("expected 200 under pool pressure, got %d body=%s", rec.Code, rec.Body.String())
 	}
 }
+
+func TestGetResponseByIDStreamResumesBufferedEvents(t *testing.T) {
+	store, resolver := newDirectTokenResolver(t)
+	h := &Handler{Store: store, Auth: resolver}
+	r := chi.NewRouter()
+	RegisterRoutes(r, h)
+
+	owner := responseStoreOwner(authForToken(t, resolver, "token-a"))
+	buf, err := h.getResumeHub().Start(owner, "resp_live")
+	if err != nil {
+		t.Fatalf("start buffer: %v", err)
+	}
+	buf.Header().Set("Content-Type", "text/event-stream")
+	_, _ = buf.Write([]byte("event: response.created\ndata: {\"sequence_number\":1}\n\n"))
+	_, _ = buf.Write([]byte("event: response.completed\ndata: {\"sequence_number\":2}\n\n"))
+	buf.Close()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/responses/resp_live?stream=true&starting_after=1", nil)
+	req.Header.Set("Authorization", "Bearer token-a")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	want := "id: resp_live:2\nevent: response.completed\ndata: {\"sequence_number\":2}\n\n"
+	if rec.Code != http.StatusOK || rec.Body.String() != want {
+		t.Fatalf("unexpected resume: %d %q", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/responses/resp_live?stream=true", nil)
+	req.Header.Set("Authorization", "Bearer token-b")
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected other tenants to get 404, got %d", rec.Code)
+	}
+}
@@ -41,6 +41,8 @@ type responsesStreamRuntime struct {
 
 	sieve             toolstream.State
 	accumulator       shared.StreamAccumulator
+	citations         *sse.CitationCollector
+	annotations       []map[string]any
 	visibleText       strings.Builder
 	responseMessageID int
 	streamToolCallIDs map[int]string
@@ -52,6 +54,9 @@ type responsesStreamRuntime struct {
 	functionNames     map[int]string
 	messageItemID     string
 	messageOutputID   int
+	webSearchItemID   string
+	webSearchOutputID int
+	webSearchItem     map[string]any
 	nextOutputID      int
 	messageAdded      bool
 	messagePartAdded  bool
@@ -110,6 +115,7 @@ func newResponsesStreamRuntime(
 		traceID:               traceID,
 		persistResponse:       persistResponse,
 		history:               history,
+		citations:             sse.NewCitationCollector(),
 		accumulator: shared.StreamAccumulator{
 			ThinkingEnabled:       thinkingEnabled,
 			SearchEnabled:         searchEnabled,
@@ -161,6 +167,7 @@ func (s *responsesStreamRuntime) finalize(finishReason string, deferEmptyOutput
 	s.finalErrorStatus = 0
 	s.finalErrorMessage = ""
 	s.finalErrorCode = ""
+	s.emitAccumulated(s.accumulator.Flush())
 	if s.bufferToolContent {
 		s.processToolStreamEvents(toolstream.Flush(&s.sieve, s.toolNames), true, true)
 	}
@@ -174,10 +181,14 @@ func (s *responsesStreamRuntime) finalize(finishReason string, deferEmptyOutput
 		RawThinking:           s.accumulator.RawThinking.String(),
 		VisibleThinking:       finalThinking,
 		DetectionThinking:     finalToolDetectionThinking,
+		Citations:             s.citations.Citations(),
+		CitationMarks:         s.accumulator.CitationMarks,
 		ContentFilter:         finishReason == "content_filter",
 		ResponseMessageID:     s.responseMessageID,
 		AlreadyEmittedCalls:   s.toolCallsEmitted,
 		AlreadyEmittedToolRaw: s.toolCallsDoneEmitted,
+		LimitReason:           s.accumulator.Limiter.Reason(),
+		StopSequence:          s.accumulator.Limiter.StopSequence(),
 	}, assistantturn.BuildOptions{
 		Model:                 s.model,
 		Prompt:                s.finalPrompt,
@@ -199,6 +210,8 @@ func (s *responsesStreamRuntime) finalize(finishReason string, deferEmptyOutput
 		}
 	}
 
+	s.annotations = shared.ResponsesURLAnnotations(turn.Text, turn.CitationSpans)
+	s.closeWebSearchCall(turn.Citations)
 	s.closeMessageItem()
 
 	outcome := assistantturn.FinalizeTurn(turn, assistantturn.FinalizeOptions{
@@ -218,6 +231,7 @@ func (s *responsesStreamRuntime) finalize(finishReason string, deferEmptyOutput
 	s.closeIncompleteFunctionItems()
 
 	obj := s.buildCompletedResponseObject(turn.Thinking, turn.Text, detected)
+	incomplete := markResponseIncomplete(obj, turn)
 	if s.persistResponse != nil {
 		s.persistResponse(obj)
 	}
@@ -230,7 +244,11 @@ func (s *responsesStreamRuntime) finalize(finishReason string, deferEmptyOutput
 			assistantturn.OpenAIResponsesUsage(turn),
 		)
 	}
-	s.sendEvent("response.completed", openaifmt.BuildResponsesCompletedPayload(obj))
+	if incomplete {
+		s.sendEvent("response.incomplete", openaifmt.BuildResponsesIncompletePayload(obj))
+	} else {
+		s.sendEvent("response.completed", openaifmt.BuildResponsesCompletedPayload(obj))
+	}
 	s.sendDone()
 	return true
 }
@@ -259,6 +277,10 @@ func (s *responsesStreamRuntime) onParsed(parsed sse.LineResult) streamengine.Pa
 	if parsed.ResponseMessageID > 0 {
 		s.responseMessageID = parsed.ResponseMessageID
 	}
+	s.citations.Add(parsed.SearchResults)
+	if len(parsed.SearchResults) > 0 {
+		s.ensureWebSearchCallAdded()
+	}
 	if parsed.ContentFilter || parsed.ErrorMessage != "" {
 		return streamengine.ParsedDecision{Stop: true, StopReason: streamengine.StopReason("content_filter")}
 	}
@@ -266,8 +288,22 @@ func (s *responsesStreamRuntime) onParsed(parsed sse.LineResult) streamengine.Pa
 		return streamengine.ParsedDecision{Stop: true}
 	}
 
-	batch := responsesDeltaBatch{runtime: s}
 	accumulated := s.accumulator.Apply(parsed)
+	s.emitAccumulated(accumulated)
+	if s.history != nil {
+		s.history.Progress(
+			responsehistory.ThinkingForArchive(s.accumulator.RawThinking.String(), s.accumulator.ToolDetectionThinking.String(), s.accumulator.Thinking.String()),
+			responsehistory.TextForArchive(s.accumulator.RawText.String(), s.accumulator.Text.String()),
+		)
+	}
+	if accumulated.Limited {
+		return streamengine.ParsedDecision{ContentSeen: accumulated.ContentSeen, Stop: true, StopReason: streamengine.StopReasonHandlerRequested}
+	}
+	return streamengine.ParsedDecision{ContentSeen: accumulated.ContentSeen}
+}
+
+func (s *responsesStreamRuntime) emitAccumulated(accumulated shared.StreamAccumulatorResult) {
+	batch := responsesDeltaBatch{runtime: s}
 	for _, p := range accumulated.Parts {
 		if p.Type == "thinking" {
 			batch.append("reasoning", p.VisibleText)
@@ -288,11 +324,4 @@ func (s *responsesStreamRuntime) onParsed(parsed sse.LineResult) streamengine.Pa
 	}
 
 	batch.flush()
-	if s.history != nil {
-		s.history.Progress(
-			responsehistory.ThinkingForArchive(s.accumulator.RawThinking.String(), s.accumulator.ToolDetectionThinking.String(), s.accumulator.Thinking.String()),
-			responsehistory.TextForArchive(s.accumulator.RawText.String(), s.accumulator.Text.String()),
-		)
-	}
-	return streamengine.ParsedDecision{ContentSeen: accumulated.ContentSeen}
 }
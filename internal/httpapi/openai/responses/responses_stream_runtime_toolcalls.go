@@ -94,7 +94,14 @@ func (s *responsesStreamRuntime) closeMessageItem() {
 	itemID := s.ensureMessageItemID()
 	outputIndex := s.ensureMessageOutputIndex()
 	text := s.visibleText.String()
+	part := s.messageTextPart(text)
 	if s.messagePartAdded {
+		for i, annotation := range s.annotations {
+			s.sendEvent(
+				"response.output_text.annotation.added",
+				openaifmt.BuildResponsesTextAnnotationAddedPayload(s.responseID, itemID, outputIndex, 0, i, annotation),
+			)
+		}
 		s.sendEvent(
 			"response.output_text.done",
 			openaifmt.BuildResponsesTextDonePayload(
@@ -112,22 +119,17 @@ func (s *responsesStreamRuntime) closeMessageItem() {
 				itemID,
 				outputIndex,
 				0,
-				map[string]any{"type": "output_text", "text": text},
+				part,
 			),
 		)
 		s.messagePartAdded = false
 	}
 	item := map[string]any{
-		"id":     itemID,
-		"type":   "message",
-		"role":   "assistant",
-		"status": "completed",
-		"content": []map[string]any{
-			{
-				"type": "output_text",
-				"text": text,
-			},
-		},
+		"id":      itemID,
+		"type":    "message",
+		"role":    "assistant",
+		"status":  "completed",
+		"content": []map[string]any{part},
 	}
 	s.sendEvent(
 		"response.output_item.done",
@@ -255,3 +257,13 @@ func (s *responsesStreamRuntime) emitFunctionCallDoneEvents(calls []toolcall.Par
 		s.toolCallsDoneEmitted = true
 	}
 }
+
+// messageTextPart builds the output_text part, carrying any citation
+// annotations resolved at finalize time.
+func (s *responsesStreamRuntime) messageTextPart(text string) map[string]any {
+	part := map[string]any{"type": "output_text", "text": text}
+	if len(s.annotations) > 0 {
+		part["annotations"] = s.annotations
+	}
+	return part
+}
@@ -62,23 +62,20 @@ func (s *responsesStreamRuntime) buildCompletedResponseObject(finalThinking, fin
 		index int
 		item  map[string]any
 	}
-	indexed := make([]indexedItem, 0, len(calls)+1)
+	indexed := make([]indexedItem, 0, len(calls)+2)
+	if s.webSearchItem != nil {
+		indexed = append(indexed, indexedItem{index: s.webSearchOutputID, item: s.webSearchItem})
+	}
 
 	if s.messageAdded {
-		text := s.visibleText.String()
 		indexed = append(indexed, indexedItem{
 			index: s.ensureMessageOutputIndex(),
 			item: map[string]any{
-				"id":     s.ensureMessageItemID(),
-				"type":   "message",
-				"role":   "assistant",
-				"status": "completed",
-				"content": []map[string]any{
-					{
-						"type": "output_text",
-						"text": text,
-					},
-				},
+				"id":      s.ensureMessageItemID(),
+				"type":    "message",
+				"role":    "assistant",
+				"status":  "completed",
+				"content": []map[string]any{s.messageTextPart(s.visibleText.String())},
 			},
 		})
 	} else if len(calls) == 0 {
@@ -90,10 +87,7 @@ func (s *responsesStreamRuntime) buildCompletedResponseObject(finalThinking, fin
 			})
 		}
 		if finalText != "" {
-			content = append(content, map[string]any{
-				"type": "output_text",
-				"text": finalText,
-			})
+			content = append(content, s.messageTextPart(finalText))
 		}
 		if len(content) > 0 {
 			indexed = append(indexed, indexedItem{
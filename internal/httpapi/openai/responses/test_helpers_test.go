@@ -24,5 +24,7 @@ func decodeJSONBody(t *testing.T, body string) map[string]any {
 
 func RegisterRoutes(r chi.Router, h *Handler) {
 	r.Post("/v1/responses", h.Responses)
+	r.Post("/v1/responses/input_tokens", h.InputTokens)
 	r.Get("/v1/responses/{response_id}", h.GetResponseByID)
+	r.Post("/v1/responses/{response_id}/cancel", h.CancelResponse)
 }
@@ -0,0 +1,51 @@
+package responses
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+
+	openaifmt "ds2api/internal/format/openai"
+	"ds2api/internal/sse"
+)
+
+func newWebSearchCallID() string {
+	return "ws_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+}
+
+// attachWebSearchCall prepends a completed web_search_call item when the
+// upstream search produced sources.
+func attachWebSearchCall(obj map[string]any, citations []sse.Citation) {
+	if len(citations) == 0 {
+		return
+	}
+	output, _ := obj["output"].([]any)
+	item := openaifmt.BuildResponsesWebSearchCallItem(newWebSearchCallID(), "completed", citations)
+	obj["output"] = append([]any{item}, output...)
+}
+
+// ensureWebSearchCallAdded opens the web_search_call item the first time
+// search results arrive, so it precedes the message item.
+func (s *responsesStreamRuntime) ensureWebSearchCallAdded() {
+	if s.webSearchItemID != "" {
+		return
+	}
+	s.webSearchItemID = newWebSearchCallID()
+	s.webSearchOutputID = s.allocateOutputIndex()
+	item := openaifmt.BuildResponsesWebSearchCallItem(s.webSearchItemID, "in_progress", nil)
+	s.sendEvent(
+		"response.output_item.added",
+		openaifmt.BuildResponsesOutputItemAddedPayload(s.responseID, s.webSearchItemID, s.webSearchOutputID, item),
+	)
+}
+
+func (s *responsesStreamRuntime) closeWebSearchCall(citations []sse.Citation) {
+	if s.webSearchItemID == "" || s.webSearchItem != nil {
+		return
+	}
+	s.webSearchItem = openaifmt.BuildResponsesWebSearchCallItem(s.webSearchItemID, "completed", citations)
+	s.sendEvent(
+		"response.output_item.done",
+		openaifmt.BuildResponsesOutputItemDonePayload(s.responseID, s.webSearchItemID, s.webSearchOutputID, s.webSearchItem),
+	)
+}
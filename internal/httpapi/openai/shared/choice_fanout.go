@@ -0,0 +1,165 @@
+package shared
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"ds2api/internal/auth"
+	"ds2api/internal/config"
+	"ds2api/internal/promptcompat"
+)
+
+// AccountAcquirer is the part of an auth resolver needed to take extra pool
+// slots for fanned-out choices.
+type AccountAcquirer interface {
+	DetermineAdditional(req *http.Request) (*auth.RequestAuth, error)
+	Release(a *auth.RequestAuth)
+}
+
+// AcquireChoiceAccounts returns one auth per choice, with primary at index 0.
+// Under the "spread" policy every extra choice of a managed-key caller takes
+// its own pool slot; otherwise all choices share primary and RunChoices runs
+// them one after another. Requests that reference uploaded files always stay
+// on primary, which owns the files. The returned release func frees only the
+// extra slots.
+func AcquireChoiceAccounts(r *http.Request, resolver AccountAcquirer, policy string, primary *auth.RequestAuth, stdReq promptcompat.StandardRequest) ([]*auth.RequestAuth, func(), error) {
+	n := stdReq.ChoiceCount()
+	out := make([]*auth.RequestAuth, n)
+	out[0] = primary
+	var extra []*auth.RequestAuth
+	release := func() {
+		for _, a := range extra {
+			resolver.Release(a)
+		}
+	}
+	spread := policy == config.ChoiceAccountPolicySpread && primary != nil && primary.UseConfigToken &&
+		len(stdReq.RefFileIDs) == 0 && !stdReq.CurrentInputFileApplied
+	for i := 1; i < n; i++ {
+		if !spread {
+			out[i] = primary
+			continue
+		}
+		a, err := resolver.DetermineAdditional(r)
+		if err != nil {
+			release()
+			return nil, func() {}, err
+		}
+		extra = append(extra, a)
+		out[i] = a
+	}
+	return out, release, nil
+}
+
+// ChoicesRunConcurrently reports whether every choice holds its own auth.
+// Choices sharing one auth must not overlap: token refreshes and account
+// switches update it in place, and the account's slot covers one session.
+func ChoicesRunConcurrently(accounts []*auth.RequestAuth) bool {
+	return len(accounts) < 2 || accounts[0] != accounts[1]
+}
+
+// RunChoices calls run once per choice, concurrently when every choice holds
+// its own auth and in index order otherwise.
+func RunChoices(accounts []*auth.RequestAuth, run func(i int, a *auth.RequestAuth)) {
+	if !ChoicesRunConcurrently(accounts) {
+		for i, a := range accounts {
+			run(i, a)
+		}
+		return
+	}
+	var wg sync.WaitGroup
+	for i, a := range accounts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			run(i, a)
+		}()
+	}
+	wg.Wait()
+}
+
+// StartChoices opens the upstream sessions a streamed fan-out needs before
+// its first chunk and returns how many choices it attempted; the caller
+// starts the rest when their turn comes. Concurrent choices all start here.
+// Choices sharing one auth start in order until one succeeds, so the
+// account never has more than one session open.
+func StartChoices(accounts []*auth.RequestAuth, start func(i int) bool) int {
+	if ChoicesRunConcurrently(accounts) {
+		RunChoices(accounts, func(i int, _ *auth.RequestAuth) { start(i) })
+		return len(accounts)
+	}
+	for i := range accounts {
+		if start(i) {
+			return i + 1
+		}
+	}
+	return len(accounts)
+}
+
+// ChoiceLimitError reports n / candidateCount above the configured maximum,
+// or nil when the request is within it.
+func ChoiceLimitError(stdReq promptcompat.StandardRequest, max int, field string) error {
+	if stdReq.Choices > max {
+		return fmt.Errorf("%s must not exceed %d", field, max)
+	}
+	return nil
+}
+
+// ChoiceStream serializes SSE frames written by several concurrently
+// generated choices onto one response, so frames from different choices
+// interleave but never split.
+type ChoiceStream struct {
+	mu       sync.Mutex
+	w        http.ResponseWriter
+	rc       *http.ResponseController
+	canFlush bool
+}
+
+func NewChoiceStream(w http.ResponseWriter) *ChoiceStream {
+	_, canFlush := w.(http.Flusher)
+	return &ChoiceStream{w: w, rc: http.NewResponseController(w), canFlush: canFlush}
+}
+
+// Writer returns the response writer for one choice's stream runtime.
+func (s *ChoiceStream) Writer(index int) *ChoiceWriter {
+	return &ChoiceWriter{stream: s, Index: index, header: http.Header{}}
+}
+
+// WriteFrame writes one complete SSE frame and flushes it.
+func (s *ChoiceStream) WriteFrame(frame []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, _ = s.w.Write(frame)
+	if s.canFlush {
+		_ = s.rc.Flush()
+	}
+}
+
+// ChoiceWriter buffers one choice's output and forwards it to the shared
+// stream a whole "\n\n"-terminated frame at a time. Headers and status are
+// owned by the fan-out handler and ignored here.
+type ChoiceWriter struct {
+	stream *ChoiceStream
+	Index  int
+	header http.Header
+	buf    bytes.Buffer
+}
+
+func (w *ChoiceWriter) Header() http.Header { return w.header }
+
+func (w *ChoiceWriter) WriteHeader(int) {}
+
+func (w *ChoiceWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		i := bytes.Index(w.buf.Bytes(), []byte("\n\n"))
+		if i < 0 {
+			return len(p), nil
+		}
+		w.stream.WriteFrame(append([]byte(nil), w.buf.Next(i+2)...))
+	}
+}
+
+// Flush is a no-op: frames are flushed as soon as they are complete.
+func (w *ChoiceWriter) Flush() {}
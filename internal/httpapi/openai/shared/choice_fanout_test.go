@@ -0,0 +1,51 @@
+package shared
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ds2api/internal/auth"
+)
+
+func TestRunChoicesSerializesSharedAuth(t *testing.T) {
+	primary := &auth.RequestAuth{DeepSeekToken: "direct-token"}
+	var running, peak atomic.Int32
+	var mu sync.Mutex
+	var order []int
+	RunChoices([]*auth.RequestAuth{primary, primary, primary}, func(i int, a *auth.RequestAuth) {
+		if n := running.Add(1); n > peak.Load() {
+			peak.Store(n)
+		}
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		order = append(order, i)
+		mu.Unlock()
+		running.Add(-1)
+	})
+	if peak.Load() != 1 {
+		t.Fatalf("expected choices sharing an auth to run one at a time, peak=%d", peak.Load())
+	}
+	if len(order) != 3 || order[0] != 0 || order[1] != 1 || order[2] != 2 {
+		t.Fatalf("expected choices in index order, got %v", order)
+	}
+}
+
+func TestStartChoicesOpensOneSharedSessionAhead(t *testing.T) {
+	primary := &auth.RequestAuth{DeepSeekToken: "direct-token"}
+	var started []int
+	n := StartChoices([]*auth.RequestAuth{primary, primary, primary}, func(i int) bool {
+		started = append(started, i)
+		return i == 1
+	})
+	if n != 2 || len(started) != 2 {
+		t.Fatalf("expected starts to stop after the first success, got n=%d started=%v", n, started)
+	}
+
+	var count atomic.Int32
+	accounts := []*auth.RequestAuth{{AccountID: "a"}, {AccountID: "b"}, {AccountID: "c"}}
+	if n := StartChoices(accounts, func(int) bool { count.Add(1); return true }); n != 3 || count.Load() != 3 {
+		t.Fatalf("expected every choice on its own account to start, got n=%d count=%d", n, count.Load())
+	}
+}
@@ -0,0 +1,89 @@
+package shared
+
+// ChatURLAnnotations renders citation spans as Chat Completions
+// url_citation annotations. Indexes are character offsets into text.
+func ChatURLAnnotations(text string, spans []CitationSpan) []map[string]any {
+	if len(spans) == 0 {
+		return nil
+	}
+	out := make([]map[string]any, 0, len(spans))
+	for _, span := range spans {
+		out = append(out, map[string]any{
+			"type": "url_citation",
+			"url_citation": map[string]any{
+				"url":         span.Citation.URL,
+				"title":       span.Citation.Title,
+				"start_index": RuneOffset(text, span.Start),
+				"end_index":   RuneOffset(text, span.End),
+			},
+		})
+	}
+	return out
+}
+
+// ResponsesURLAnnotations renders citation spans as Responses API
+// output_text annotations.
+func ResponsesURLAnnotations(text string, spans []CitationSpan) []map[string]any {
+	if len(spans) == 0 {
+		return nil
+	}
+	out := make([]map[string]any, 0, len(spans))
+	for _, span := range spans {
+		out = append(out, map[string]any{
+			"type":        "url_citation",
+			"start_index": RuneOffset(text, span.Start),
+			"end_index":   RuneOffset(text, span.End),
+			"url":         span.Citation.URL,
+			"title":       span.Citation.Title,
+		})
+	}
+	return out
+}
+
+// AttachChatAnnotations adds annotations to the first choice of a chat
+// completion body. Tool-call responses carry no text and are left alone.
+func AttachChatAnnotations(body map[string]any, text string, spans []CitationSpan) {
+	annotations := ChatURLAnnotations(text, spans)
+	if len(annotations) == 0 {
+		return
+	}
+	choices, _ := body["choices"].([]map[string]any)
+	if len(choices) == 0 {
+		return
+	}
+	message, _ := choices[0]["message"].(map[string]any)
+	if message == nil || message["content"] == nil {
+		return
+	}
+	message["annotations"] = annotations
+}
+
+// AttachResponsesAnnotations adds annotations to every output_text part of a
+// response object.
+func AttachResponsesAnnotations(obj map[string]any, text string, spans []CitationSpan) {
+	annotations := ResponsesURLAnnotations(text, spans)
+	if len(annotations) == 0 {
+		return
+	}
+	output, _ := obj["output"].([]any)
+	for _, item := range output {
+		m, _ := item.(map[string]any)
+		if m == nil || m["type"] != "message" {
+			continue
+		}
+		switch content := m["content"].(type) {
+		case []any:
+			for _, part := range content {
+				if p, _ := part.(map[string]any); p != nil && p["type"] == "output_text" {
+					p["annotations"] = annotations
+				}
+			}
+		case []map[string]any:
+			for _, p := range content {
+				if p["type"] == "output_text" {
+					p["annotations"] = annotations
+				}
+			}
+		}
+	}
+}
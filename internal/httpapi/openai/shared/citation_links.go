@@ -3,36 +3,281 @@ package shared
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"ds2api/internal/sse"
 )
 
 var citationMarkerPattern = regexp.MustCompile(`(?i)\[(citation|reference):\s*(\d+)\]`)
 
+// CitationMark records where a [citation:N] / [reference:N] marker sat in the
+// visible text after it was stripped.
+type CitationMark struct {
+	Number    int
+	Reference bool
+	Offset    int
+}
+
+// CitationSpan is a cited range of the final visible text. Start and End are
+// byte offsets; the range covers the sentence the marker was attached to.
+type CitationSpan struct {
+	Citation sse.Citation
+	Start    int
+	End      int
+}
+
 func ReplaceCitationMarkersWithLinks(text string, links map[int]string) string {
-	if strings.TrimSpace(text) == "" || len(links) == 0 {
-		return text
+	out, _ := LinkCitationMarkers(text, CitationsFromLinks(links))
+	return out
+}
+
+// LinkCitationMarkers rewrites known markers into inline markdown links and
+// reports the cited span of each one. Unknown markers are left untouched.
+func LinkCitationMarkers(text string, citations []sse.Citation) (string, []CitationSpan) {
+	if strings.TrimSpace(text) == "" || len(citations) == 0 {
+		return text, nil
 	}
+	byIndex := citationsByIndex(citations)
 	zeroBasedReference := hasZeroBasedReferenceMarker(text)
-	return citationMarkerPattern.ReplaceAllStringFunc(text, func(match string) string {
-		sub := citationMarkerPattern.FindStringSubmatch(match)
-		if len(sub) < 3 {
-			return match
-		}
-		idx, err := strconv.Atoi(strings.TrimSpace(sub[2]))
+	var b strings.Builder
+	var spans []CitationSpan
+	pos := 0
+	for _, loc := range citationMarkerPattern.FindAllStringSubmatchIndex(text, -1) {
+		b.WriteString(text[pos:loc[0]])
+		pos = loc[1]
+		match := text[loc[0]:loc[1]]
+		idx, err := strconv.Atoi(strings.TrimSpace(text[loc[4]:loc[5]]))
 		if err != nil || idx < 0 {
-			return match
+			b.WriteString(match)
+			continue
 		}
 		lookupIdx := idx
-		if strings.EqualFold(sub[1], "reference") && zeroBasedReference {
+		if strings.EqualFold(text[loc[2]:loc[3]], "reference") && zeroBasedReference {
 			lookupIdx = idx + 1
 		}
-		url := strings.TrimSpace(links[lookupIdx])
-		if url == "" {
-			return match
+		citation, ok := byIndex[lookupIdx]
+		if !ok || strings.TrimSpace(citation.URL) == "" {
+			b.WriteString(match)
+			continue
+		}
+		start := citedSegmentStart(b.String(), b.Len())
+		b.WriteString(fmt.Sprintf("[%d](%s)", idx, strings.TrimSpace(citation.URL)))
+		spans = append(spans, CitationSpan{Citation: citation, Start: start, End: b.Len()})
+	}
+	b.WriteString(text[pos:])
+	return b.String(), spans
+}
+
+// StripCitationMarkers removes every marker and returns where each one was.
+func StripCitationMarkers(text string) (string, []CitationMark) {
+	if text == "" {
+		return text, nil
+	}
+	locs := citationMarkerPattern.FindAllStringSubmatchIndex(text, -1)
+	if len(locs) == 0 {
+		return text, nil
+	}
+	var b strings.Builder
+	marks := make([]CitationMark, 0, len(locs))
+	pos := 0
+	for _, loc := range locs {
+		b.WriteString(text[pos:loc[0]])
+		pos = loc[1]
+		idx, err := strconv.Atoi(strings.TrimSpace(text[loc[4]:loc[5]]))
+		if err != nil || idx < 0 {
+			continue
+		}
+		marks = append(marks, CitationMark{
+			Number:    idx,
+			Reference: strings.EqualFold(text[loc[2]:loc[3]], "reference"),
+			Offset:    b.Len(),
+		})
+	}
+	b.WriteString(text[pos:])
+	return b.String(), marks
+}
+
+// citationMarkSentinel stands in for a stripped marker while the text is
+// cleaned, so mark offsets follow whatever the cleaning removes.
+const citationMarkSentinel = "\uE000"
+
+// StripAndCleanCitationMarkers strips every marker, cleans the rest like
+// CleanVisibleOutput and returns each marker's offset in the cleaned text.
+// If cleaning drops a marker's position, the offsets stay relative to the
+// stripped text and callers clamp them.
+func StripAndCleanCitationMarkers(text string, stripReferenceMarkers bool) (string, []CitationMark) {
+	visible, marks := StripCitationMarkers(text)
+	if len(marks) == 0 || strings.Contains(visible, citationMarkSentinel) {
+		return CleanVisibleOutput(visible, stripReferenceMarkers), marks
+	}
+	var b strings.Builder
+	pos := 0
+	for _, m := range marks {
+		b.WriteString(visible[pos:m.Offset])
+		b.WriteString(citationMarkSentinel)
+		pos = m.Offset
+	}
+	b.WriteString(visible[pos:])
+	cleaned := CleanVisibleOutput(b.String(), stripReferenceMarkers)
+	if strings.Count(cleaned, citationMarkSentinel) != len(marks) {
+		return CleanVisibleOutput(visible, stripReferenceMarkers), marks
+	}
+	out := make([]CitationMark, len(marks))
+	b.Reset()
+	for i := range marks {
+		j := strings.Index(cleaned, citationMarkSentinel)
+		b.WriteString(cleaned[:j])
+		cleaned = cleaned[j+len(citationMarkSentinel):]
+		out[i] = marks[i]
+		out[i].Offset = b.Len()
+	}
+	b.WriteString(cleaned)
+	return b.String(), out
+}
+
+// SplitPartialCitationMarker splits off a trailing "[citation:" or
+// "[reference:" marker that the next delta may still complete.
+func SplitPartialCitationMarker(text string) (string, string) {
+	i := strings.LastIndexByte(text, '[')
+	if i < 0 || len(text)-i > maxPartialCitationMarkerLen {
+		return text, ""
+	}
+	tail := strings.ToLower(text[i:])
+	for _, prefix := range []string{"[citation:", "[reference:"} {
+		if strings.HasPrefix(prefix, tail) {
+			return text[:i], text[i:]
+		}
+		if rest, ok := strings.CutPrefix(tail, prefix); ok && strings.Trim(strings.TrimLeft(rest, " \t\r\n"), "0123456789") == "" {
+			return text[:i], text[i:]
+		}
+	}
+	return text, ""
+}
+
+// maxPartialCitationMarkerLen bounds the tail held back for a marker, so a
+// stray "[citation:" followed by whitespace cannot stall the stream.
+const maxPartialCitationMarkerLen = 32
+
+// CitationSpansFromMarks resolves stripped markers against the collected
+// sources. Offsets are clamped to text so late sanitising cannot break them.
+func CitationSpansFromMarks(text string, marks []CitationMark, citations []sse.Citation) []CitationSpan {
+	if len(marks) == 0 || len(citations) == 0 {
+		return nil
+	}
+	byIndex := citationsByIndex(citations)
+	zeroBasedReference := false
+	for _, m := range marks {
+		if m.Reference && m.Number == 0 {
+			zeroBasedReference = true
+			break
+		}
+	}
+	spans := make([]CitationSpan, 0, len(marks))
+	for _, m := range marks {
+		lookupIdx := m.Number
+		if m.Reference && zeroBasedReference {
+			lookupIdx++
+		}
+		citation, ok := byIndex[lookupIdx]
+		if !ok {
+			continue
+		}
+		end := m.Offset
+		if end > len(text) {
+			end = len(text)
+		}
+		for end > 0 && end < len(text) && !utf8.RuneStart(text[end]) {
+			end--
+		}
+		spans = append(spans, CitationSpan{Citation: citation, Start: citedSegmentStart(text, end), End: end})
+	}
+	return spans
+}
+
+// CitationsFromLinks adapts a bare index->URL map to citations.
+func CitationsFromLinks(links map[int]string) []sse.Citation {
+	if len(links) == 0 {
+		return nil
+	}
+	out := make([]sse.Citation, 0, len(links))
+	for idx, u := range links {
+		out = append(out, sse.Citation{Index: idx, URL: u})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Index < out[j].Index })
+	return out
+}
+
+// RuneOffset converts a byte offset in text to a character offset.
+func RuneOffset(text string, byteOffset int) int {
+	if byteOffset > len(text) {
+		byteOffset = len(text)
+	}
+	if byteOffset <= 0 {
+		return 0
+	}
+	return utf8.RuneCountInString(text[:byteOffset])
+}
+
+func citationsByIndex(citations []sse.Citation) map[int]sse.Citation {
+	out := make(map[int]sse.Citation, len(citations))
+	for _, c := range citations {
+		out[c.Index] = c
+	}
+	return out
+}
+
+// citedSegmentStart walks back from end to the previous sentence boundary or
+// earlier citation link, ignoring the punctuation that closes the cited
+// sentence itself.
+func citedSegmentStart(text string, end int) int {
+	i := end
+	for i > 0 {
+		r, size := utf8.DecodeLastRuneInString(text[:i])
+		if !unicode.IsSpace(r) && !isSentenceBoundary(r) && !isCitationLinkTail(text[:i]) {
+			break
+		}
+		if isCitationLinkTail(text[:i]) {
+			i = strings.LastIndex(text[:i], "[")
+			continue
+		}
+		i -= size
+	}
+	for i > 0 {
+		r, size := utf8.DecodeLastRuneInString(text[:i])
+		if isSentenceBoundary(r) || r == '\n' || (r == ')' && isCitationLinkTail(text[:i])) {
+			break
+		}
+		i -= size
+	}
+	for i < end {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if !unicode.IsSpace(r) {
+			break
 		}
-		return fmt.Sprintf("[%d](%s)", idx, url)
-	})
+		i += size
+	}
+	return i
+}
+
+var citationLinkTailPattern = regexp.MustCompile(`\[\d+\]\(https?://[^)\s]*\)$`)
+
+// isCitationLinkTail reports whether text ends with an inline citation link
+// written earlier, so adjacent citations share one cited sentence.
+func isCitationLinkTail(text string) bool {
+	return strings.HasSuffix(text, ")") && citationLinkTailPattern.MatchString(text)
+}
+
+func isSentenceBoundary(r rune) bool {
+	switch r {
+	case '.', '!', '?', '\n', '。', '！', '？', '；', ';':
+		return true
+	default:
+		return false
+	}
 }
 
 func hasZeroBasedReferenceMarker(text string) bool {
@@ -0,0 +1,50 @@
+package shared
+
+import (
+	"testing"
+
+	"ds2api/internal/sse"
+)
+
+func TestLinkCitationMarkersReportsSentenceSpans(t *testing.T) {
+	citations := []sse.Citation{
+		{Index: 1, URL: "https://example.com/a", Title: "A"},
+		{Index: 2, URL: "https://example.com/b", Title: "B"},
+	}
+	text, spans := LinkCitationMarkers("Paris is big.[citation:1] It rains often[citation:2][citation:1].", citations)
+	want := "Paris is big.[1](https://example.com/a) It rains often[2](https://example.com/b)[1](https://example.com/a)."
+	if text != want {
+		t.Fatalf("text = %q", text)
+	}
+	if len(spans) != 3 {
+		t.Fatalf("expected three spans, got %#v", spans)
+	}
+	if got := text[spans[0].Start:spans[0].End]; got != "Paris is big.[1](https://example.com/a)" {
+		t.Fatalf("first span = %q", got)
+	}
+	if got := text[spans[1].Start:spans[1].End]; got != "It rains often[2](https://example.com/b)" {
+		t.Fatalf("second span = %q", got)
+	}
+	if spans[2].Start != spans[1].Start || spans[2].Citation.Title != "A" {
+		t.Fatalf("expected adjacent citation to share the sentence, got %#v", spans[2])
+	}
+}
+
+func TestCitationSpansFromMarksUsesCharacterOffsets(t *testing.T) {
+	text, marks := StripCitationMarkers("第一句。广州天气多云[reference:0]。")
+	if text != "第一句。广州天气多云。" {
+		t.Fatalf("text = %q", text)
+	}
+	spans := CitationSpansFromMarks(text, marks, []sse.Citation{{Index: 1, URL: "https://example.com/w", Title: "Weather"}})
+	if len(spans) != 1 {
+		t.Fatalf("expected one span, got %#v", spans)
+	}
+	if got := text[spans[0].Start:spans[0].End]; got != "广州天气多云" {
+		t.Fatalf("span = %q", got)
+	}
+	annotations := ChatURLAnnotations(text, spans)
+	urlCitation := annotations[0]["url_citation"].(map[string]any)
+	if urlCitation["start_index"] != 4 || urlCitation["end_index"] != 10 || urlCitation["title"] != "Weather" {
+		t.Fatalf("unexpected annotation: %#v", urlCitation)
+	}
+}
@@ -20,6 +20,7 @@ const (
 
 type AuthResolver interface {
 	Determine(req *http.Request) (*auth.RequestAuth, error)
+	DetermineAdditional(req *http.Request) (*auth.RequestAuth, error)
 	DetermineCaller(req *http.Request) (*auth.RequestAuth, error)
 	Release(a *auth.RequestAuth)
 }
@@ -45,6 +46,13 @@ type ConfigReader interface {
 	CurrentInputFileMinChars() int
 	ThinkingInjectionEnabled() bool
 	ThinkingInjectionPrompt() string
+	RuntimeMaxChoices() int
+	RuntimeChoiceAccountPolicy() string
+	ContextLimitEnabled() bool
+	ContextLimitTokens(model string) int
+	ContextLimitStrategy() string
+	ContextLimitKeepLastTurns() int
+	ContextLimitToolResultMaxChars() int
 }
 
 type Deps struct {
@@ -10,12 +10,21 @@ type StreamAccumulator struct {
 	ThinkingEnabled       bool
 	SearchEnabled         bool
 	StripReferenceMarkers bool
+	// Limiter enforces client stop sequences and max tokens on text parts.
+	Limiter *sse.OutputLimiter
 
 	RawThinking           strings.Builder
 	Thinking              strings.Builder
 	ToolDetectionThinking strings.Builder
 	RawText               strings.Builder
 	Text                  strings.Builder
+	// CitationMarks records stripped citation markers against Text offsets
+	// so the final turn can report structured citation spans.
+	CitationMarks []CitationMark
+
+	// citationTail holds a trailing partial citation marker until the next
+	// delta completes or refutes it.
+	citationTail string
 }
 
 type StreamPartDelta struct {
@@ -28,10 +37,17 @@ type StreamPartDelta struct {
 type StreamAccumulatorResult struct {
 	ContentSeen bool
 	Parts       []StreamPartDelta
+	// Limited reports that a stop sequence or the token cap was reached and
+	// the upstream stream should be cancelled.
+	Limited bool
 }
 
 func (a *StreamAccumulator) Apply(parsed sse.LineResult) StreamAccumulatorResult {
 	out := StreamAccumulatorResult{}
+	if a.Limiter.Done() {
+		out.Limited = true
+		return out
+	}
 	for _, p := range parsed.ToolDetectionThinkingParts {
 		trimmed := sse.TrimContinuationOverlapFromBuilder(&a.ToolDetectionThinking, p.Text)
 		if trimmed != "" {
@@ -56,10 +72,30 @@ func (a *StreamAccumulator) Apply(parsed sse.LineResult) StreamAccumulatorResult
 		if delta.RawText != "" || delta.VisibleText != "" || delta.CitationOnly {
 			out.Parts = append(out.Parts, delta)
 		}
+		if a.Limiter.Done() {
+			out.Limited = true
+			break
+		}
 	}
 	return out
 }
 
+// Flush releases text the limiter held back while waiting to see whether it
+// starts a stop sequence. Call it once the upstream stream has ended.
+func (a *StreamAccumulator) Flush() StreamAccumulatorResult {
+	text := a.Limiter.Flush()
+	if text == "" && a.citationTail == "" {
+		return StreamAccumulatorResult{}
+	}
+	a.RawText.WriteString(text)
+	delta := a.appendVisibleText(text, true)
+	delta.RawText = text
+	if delta.RawText == "" && delta.VisibleText == "" && !delta.CitationOnly {
+		return StreamAccumulatorResult{}
+	}
+	return StreamAccumulatorResult{ContentSeen: true, Parts: []StreamPartDelta{delta}}
+}
+
 func (a *StreamAccumulator) applyThinkingPart(text string) StreamPartDelta {
 	rawTrimmed := sse.TrimContinuationOverlapFromBuilder(&a.RawThinking, text)
 	if rawTrimmed != "" {
@@ -84,17 +120,51 @@ func (a *StreamAccumulator) applyThinkingPart(text string) StreamPartDelta {
 
 func (a *StreamAccumulator) applyTextPart(text string) StreamPartDelta {
 	rawTrimmed := sse.TrimContinuationOverlapFromBuilder(&a.RawText, text)
+	return a.appendTextPart(a.Limiter.Push(rawTrimmed))
+}
+
+func (a *StreamAccumulator) appendTextPart(rawTrimmed string) StreamPartDelta {
 	if rawTrimmed == "" {
 		return StreamPartDelta{Type: "text"}
 	}
 	a.RawText.WriteString(rawTrimmed)
-	delta := StreamPartDelta{Type: "text", RawText: rawTrimmed}
-	if a.SearchEnabled && sse.IsCitation(rawTrimmed) {
+	delta := a.appendVisibleText(rawTrimmed, false)
+	delta.RawText = rawTrimmed
+	return delta
+}
+
+// appendVisibleText cleans one text delta into Text. While markers are
+// stripped, a trailing partial marker is held back unless final is set, so
+// a marker split across deltas is still recognized.
+func (a *StreamAccumulator) appendVisibleText(text string, final bool) StreamPartDelta {
+	delta := StreamPartDelta{Type: "text"}
+	if !a.SearchEnabled || !a.StripReferenceMarkers {
+		if a.SearchEnabled && sse.IsCitation(text) {
+			delta.CitationOnly = true
+			return delta
+		}
+		return a.appendCleanedText(delta, CleanVisibleOutput(text, a.StripReferenceMarkers), nil)
+	}
+	text = a.citationTail + text
+	a.citationTail = ""
+	if !final {
+		text, a.citationTail = SplitPartialCitationMarker(text)
+	}
+	if text == "" {
+		return delta
+	}
+	cleanedText, marks := StripAndCleanCitationMarkers(text, a.StripReferenceMarkers)
+	if len(marks) > 0 && strings.TrimSpace(cleanedText) == "" {
+		a.recordCitationMarks(marks, 0, 0)
 		delta.CitationOnly = true
 		return delta
 	}
-	cleanedText := CleanVisibleOutput(rawTrimmed, a.StripReferenceMarkers)
+	return a.appendCleanedText(delta, cleanedText, marks)
+}
+
+func (a *StreamAccumulator) appendCleanedText(delta StreamPartDelta, cleanedText string, marks []CitationMark) StreamPartDelta {
 	trimmed := sse.TrimContinuationOverlapFromBuilder(&a.Text, cleanedText)
+	a.recordCitationMarks(marks, len(cleanedText)-len(trimmed), len(trimmed))
 	if trimmed == "" {
 		return delta
 	}
@@ -102,3 +172,21 @@ func (a *StreamAccumulator) applyTextPart(text string) StreamPartDelta {
 	delta.VisibleText = trimmed
 	return delta
 }
+
+// recordCitationMarks maps marker offsets within one delta onto the visible
+// text. overlap is the prefix dropped by continuation trimming and written is
+// the number of bytes about to be appended.
+func (a *StreamAccumulator) recordCitationMarks(marks []CitationMark, overlap, written int) {
+	base := a.Text.Len()
+	for _, m := range marks {
+		offset := m.Offset - overlap
+		if offset < 0 {
+			offset = 0
+		}
+		if offset > written {
+			offset = written
+		}
+		m.Offset = base + offset
+		a.CitationMarks = append(a.CitationMarks, m)
+	}
+}
@@ -113,3 +113,64 @@ func TestStreamAccumulatorStripsInlineCitationAndReferenceMarkers(t *testing.T)
 		t.Fatalf("unexpected parts: %#v", result.Parts)
 	}
 }
+
+func TestStreamAccumulatorRecordsCitationMarks(t *testing.T) {
+	acc := StreamAccumulator{SearchEnabled: true, StripReferenceMarkers: true}
+	acc.Apply(sse.LineResult{Parsed: true, Parts: []sse.ContentPart{{Type: "text", Text: "Paris is big."}}})
+	acc.Apply(sse.LineResult{Parsed: true, Parts: []sse.ContentPart{{Type: "text", Text: "[citation:1]"}}})
+	acc.Apply(sse.LineResult{Parsed: true, Parts: []sse.ContentPart{{Type: "text", Text: " It rains[citation:2] often."}}})
+
+	text := acc.Text.String()
+	if text != "Paris is big. It rains often." {
+		t.Fatalf("visible text = %q", text)
+	}
+	if len(acc.CitationMarks) != 2 {
+		t.Fatalf("expected two marks, got %#v", acc.CitationMarks)
+	}
+	if acc.CitationMarks[0].Number != 1 || acc.CitationMarks[0].Offset != len("Paris is big.") {
+		t.Fatalf("unexpected first mark: %#v", acc.CitationMarks[0])
+	}
+	if acc.CitationMarks[1].Number != 2 || acc.CitationMarks[1].Offset != len("Paris is big. It rains") {
+		t.Fatalf("unexpected second mark: %#v", acc.CitationMarks[1])
+	}
+}
+
+func TestStreamAccumulatorJoinsCitationMarkerSplitAcrossDeltas(t *testing.T) {
+	acc := StreamAccumulator{SearchEnabled: true, StripReferenceMarkers: true}
+	first := acc.Apply(sse.LineResult{Parsed: true, Parts: []sse.ContentPart{{Type: "text", Text: "Paris is big.[cita"}}})
+	acc.Apply(sse.LineResult{Parsed: true, Parts: []sse.ContentPart{{Type: "text", Text: "tion:3] It rains."}}})
+
+	if len(first.Parts) != 1 || first.Parts[0].VisibleText != "Paris is big." {
+		t.Fatalf("expected the partial marker to be held back, got %#v", first.Parts)
+	}
+	if got := acc.Text.String(); got != "Paris is big. It rains." {
+		t.Fatalf("visible text = %q", got)
+	}
+	if len(acc.CitationMarks) != 1 || acc.CitationMarks[0].Number != 3 || acc.CitationMarks[0].Offset != len("Paris is big.") {
+		t.Fatalf("unexpected marks: %#v", acc.CitationMarks)
+	}
+}
+
+func TestStreamAccumulatorFlushReleasesUnfinishedMarker(t *testing.T) {
+	acc := StreamAccumulator{SearchEnabled: true, StripReferenceMarkers: true}
+	acc.Apply(sse.LineResult{Parsed: true, Parts: []sse.ContentPart{{Type: "text", Text: "see [ref"}}})
+	flushed := acc.Flush()
+	if got := acc.Text.String(); got != "see [ref" {
+		t.Fatalf("visible text = %q", got)
+	}
+	if len(flushed.Parts) != 1 || flushed.Parts[0].VisibleText != "[ref" {
+		t.Fatalf("unexpected flush: %#v", flushed.Parts)
+	}
+}
+
+func TestStreamAccumulatorPlacesCitationMarksOnCleanedText(t *testing.T) {
+	acc := StreamAccumulator{SearchEnabled: true, StripReferenceMarkers: true}
+	acc.Apply(sse.LineResult{Parsed: true, Parts: []sse.ContentPart{{Type: "text", Text: "<｜begin▁of▁sentence｜>Rain[citation:1] today."}}})
+
+	if got := acc.Text.String(); got != "Rain today." {
+		t.Fatalf("visible text = %q", got)
+	}
+	if len(acc.CitationMarks) != 1 || acc.CitationMarks[0].Offset != len("Rain") {
+		t.Fatalf("expected the mark right after Rain, got %#v", acc.CitationMarks)
+	}
+}
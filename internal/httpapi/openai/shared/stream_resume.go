@@ -0,0 +1,50 @@
+package shared
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"ds2api/internal/config"
+	dsprotocol "ds2api/internal/deepseek/protocol"
+	"ds2api/internal/streamresume"
+)
+
+// ResumeKeepAliveInterval matches the keep-alive cadence of direct streams.
+var ResumeKeepAliveInterval = time.Duration(dsprotocol.KeepAliveTimeout) * time.Second
+
+// NewResumeHub builds the buffer index for resumable streams. Finished
+// streams stay replayable for as long as stored responses do.
+func NewResumeHub(store ConfigReader) *streamresume.Hub {
+	ttl := streamresume.DefaultTTL
+	if store != nil {
+		ttl = time.Duration(store.ResponsesStoreTTLSeconds()) * time.Second
+	}
+	return streamresume.NewHub(ttl, streamresume.DefaultMaxBytes, streamresume.DefaultMaxBuffers)
+}
+
+// RunResumable runs generate behind a resume buffer registered under owner
+// and streamID. When the hub is full the stream is served directly and
+// cannot be resumed.
+func RunResumable(w http.ResponseWriter, r *http.Request, hub *streamresume.Hub, owner, streamID string, generate func(http.ResponseWriter, *http.Request)) {
+	buf, err := hub.Start(owner, streamID)
+	if err != nil {
+		config.Logger.Warn("[stream_resume] serving stream without resume buffer", "stream_id", streamID, "error", err)
+		generate(w, r)
+		return
+	}
+	streamresume.Run(w, r, buf, ResumeKeepAliveInterval, generate)
+}
+
+// ServeResumedStream replays a buffered stream after afterSeq and follows
+// it live. Errors are only reported while nothing has been written yet.
+func ServeResumedStream(w http.ResponseWriter, r *http.Request, hub *streamresume.Hub, owner, streamID string, afterSeq int) {
+	buf, ok := hub.Get(owner, streamID)
+	if !ok {
+		WriteOpenAIErrorWithCode(w, http.StatusNotFound, "Stream not found or expired.", "stream_not_found")
+		return
+	}
+	if err := buf.Serve(r.Context(), w, afterSeq, ResumeKeepAliveInterval); errors.Is(err, streamresume.ErrEventsDropped) {
+		WriteOpenAIErrorWithCode(w, http.StatusConflict, "Requested events are no longer buffered.", "stream_events_dropped")
+	}
+}
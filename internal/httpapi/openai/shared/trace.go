@@ -5,8 +5,13 @@ import (
 	"strings"
 
 	"github.com/go-chi/chi/v5/middleware"
+
+	"ds2api/internal/tracing"
 )
 
+// RequestTraceID identifies the request in log lines. Explicit test trace ids
+// win; otherwise the OpenTelemetry trace id links logs to the exported trace,
+// falling back to the chi request id when tracing is off.
 func RequestTraceID(r *http.Request) string {
 	if r == nil {
 		return ""
@@ -17,5 +22,8 @@ func RequestTraceID(r *http.Request) string {
 	if h := strings.TrimSpace(r.Header.Get("X-Ds2-Test-Trace")); h != "" {
 		return h
 	}
+	if traceID := tracing.TraceIDFromContext(r.Context()); traceID != "" {
+		return traceID
+	}
 	return strings.TrimSpace(middleware.GetReqID(r.Context()))
 }
@@ -27,6 +27,10 @@ func (streamStatusAuthStub) Determine(_ *http.Request) (*auth.RequestAuth, error
 	}, nil
 }
 
+func (s streamStatusAuthStub) DetermineAdditional(r *http.Request) (*auth.RequestAuth, error) {
+	return s.Determine(r)
+}
+
 func (streamStatusAuthStub) DetermineCaller(_ *http.Request) (*auth.RequestAuth, error) {
 	return &auth.RequestAuth{
 		UseConfigToken: false,
@@ -101,8 +101,11 @@ func registerOpenAITestRoutes(r chi.Router, h *openAITestSurface) {
 	r.Get("/v1/models", h.modelsHandler().ListModels)
 	r.Get("/v1/models/{model_id}", h.modelsHandler().GetModel)
 	r.Post("/v1/chat/completions", h.chatHandler().ChatCompletions)
+	r.Post("/v1/completions", h.chatHandler().Completions)
 	r.Post("/v1/responses", h.responsesHandler().Responses)
+	r.Post("/v1/responses/input_tokens", h.responsesHandler().InputTokens)
 	r.Get("/v1/responses/{response_id}", h.responsesHandler().GetResponseByID)
+	r.Post("/v1/responses/{response_id}/cancel", h.responsesHandler().CancelResponse)
 	r.Post("/v1/files", h.filesHandler().UploadFile)
 	r.Get("/v1/files/{file_id}", h.filesHandler().RetrieveFile)
 	r.Post("/v1/embeddings", h.embeddingsHandler().Embeddings)
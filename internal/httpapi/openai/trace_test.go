@@ -1,11 +1,14 @@
 package openai
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/go-chi/chi/v5/middleware"
+
+	"ds2api/internal/tracing"
 )
 
 func traceIDViaMiddleware(req *http.Request) string {
@@ -45,3 +48,23 @@ func TestRequestTraceIDReqIDFallback(t *testing.T) {
 		t.Fatal("expected middleware request id fallback to be non-empty")
 	}
 }
+
+func TestRequestTraceIDUsesOpenTelemetryTrace(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+	tracing.Init(tracing.Config{Endpoint: collector.URL + "/v1/traces", SampleRatio: 1})
+	defer func() { _ = tracing.Shutdown(context.Background()) }()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/chat/completions", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	var got string
+	h := middleware.RequestID(tracing.Middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = requestTraceID(r)
+	})))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("expected trace id from traceparent, got %q", got)
+	}
+}
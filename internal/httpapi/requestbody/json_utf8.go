@@ -70,6 +70,8 @@ func isKnownJSONRequestPath(method, path string) bool {
 		return true
 	case path == "/v1/responses" || path == "/responses":
 		return true
+	case path == "/v1/responses/input_tokens" || path == "/responses/input_tokens":
+		return true
 	case path == "/v1/embeddings" || path == "/embeddings":
 		return true
 	case path == "/anthropic/v1/messages" || path == "/v1/messages" || path == "/messages":
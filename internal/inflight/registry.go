@@ -0,0 +1,180 @@
+// Package inflight keeps an in-memory registry of running API requests so
+// the admin API can list them and cancel one: its context is cancelled, its
+// upstream bodies are closed, and the handler returns and releases its
+// account slot.
+package inflight
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+
+	"ds2api/internal/tracing"
+)
+
+// ErrCancelled is the context cause of a request cancelled by an admin.
+var ErrCancelled = errors.New("request cancelled by admin")
+
+type Registry struct {
+	mu       sync.Mutex
+	requests map[string]*Request
+	changed  chan struct{}
+}
+
+func NewRegistry() *Registry {
+	return &Registry{requests: map[string]*Request{}, changed: make(chan struct{})}
+}
+
+// Middleware registers completion requests for their lifetime.
+func (g *Registry) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsCompletionRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx, cancel := context.WithCancelCause(r.Context())
+		defer cancel(nil)
+		req := &Request{
+			id:        "req_" + strings.ReplaceAll(uuid.NewString(), "-", ""),
+			requestID: middleware.GetReqID(ctx),
+			traceID:   tracing.TraceIDFromContext(ctx),
+			method:    r.Method,
+			path:      r.URL.Path,
+			startedAt: time.Now(),
+			cancel:    cancel,
+			done:      make(chan struct{}),
+			stage:     StageStarted,
+			registry:  g,
+		}
+		g.add(req)
+		defer g.remove(req)
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		ww.Tee(byteCounter{req})
+		next.ServeHTTP(ww, r.WithContext(withRequest(ctx, req)))
+	})
+}
+
+// Spawn registers work that keeps running after the request in ctx has
+// returned, such as a background response, as its own entry in the same
+// registry. The returned context keeps ctx's values but not its
+// cancellation and is cancelled when the new entry is cancelled from the
+// admin API. finish removes the entry; call it once the work is done.
+// Outside the middleware it returns a detached context with no entry.
+func Spawn(ctx context.Context) (spawned context.Context, finish func()) {
+	parent := FromContext(ctx)
+	if parent == nil || parent.registry == nil {
+		return Detach(ctx)
+	}
+	spawned, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+	req := &Request{
+		id:         "req_" + strings.ReplaceAll(uuid.NewString(), "-", ""),
+		requestID:  parent.requestID,
+		traceID:    parent.traceID,
+		method:     parent.method,
+		path:       parent.path,
+		startedAt:  time.Now(),
+		cancel:     cancel,
+		done:       make(chan struct{}),
+		stage:      StageStarted,
+		registry:   parent.registry,
+		background: true,
+	}
+	parent.mu.Lock()
+	req.callerID = parent.callerID
+	req.surface = parent.surface
+	req.requestedModel = parent.requestedModel
+	req.resolvedModel = parent.resolvedModel
+	req.stream = parent.stream
+	parent.mu.Unlock()
+	req.registry.add(req)
+	var once sync.Once
+	return withRequest(spawned, req), func() {
+		once.Do(func() {
+			cancel(nil)
+			req.registry.remove(req)
+		})
+	}
+}
+
+type byteCounter struct{ req *Request }
+
+func (c byteCounter) Write(p []byte) (int, error) {
+	c.req.clientBytes.Add(int64(len(p)))
+	return len(p), nil
+}
+
+func (g *Registry) add(req *Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.requests[req.id] = req
+	g.notifyLocked()
+}
+
+func (g *Registry) remove(req *Request) {
+	g.mu.Lock()
+	delete(g.requests, req.id)
+	g.notifyLocked()
+	g.mu.Unlock()
+	close(req.done)
+}
+
+func (g *Registry) notifyLocked() {
+	close(g.changed)
+	g.changed = make(chan struct{})
+}
+
+// Changed is closed the next time a request starts or finishes.
+func (g *Registry) Changed() <-chan struct{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.changed
+}
+
+// InUse reports how many requests are running, so drain can wait for them.
+func (g *Registry) InUse() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.requests)
+}
+
+// List returns the running requests, oldest first.
+func (g *Registry) List() []Snapshot {
+	g.mu.Lock()
+	reqs := make([]*Request, 0, len(g.requests))
+	for _, req := range g.requests {
+		reqs = append(reqs, req)
+	}
+	g.mu.Unlock()
+	now := time.Now()
+	out := make([]Snapshot, 0, len(reqs))
+	for _, req := range reqs {
+		out = append(out, req.snapshot(now))
+	}
+	slices.SortFunc(out, func(a, b Snapshot) int {
+		if c := a.StartedAt.Compare(b.StartedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return out
+}
+
+// Cancel aborts the request with id. The returned channel is closed once
+// its handler has returned, which is when the account slot is released.
+func (g *Registry) Cancel(id string) (<-chan struct{}, bool) {
+	g.mu.Lock()
+	req, ok := g.requests[id]
+	g.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+	req.abort()
+	return req.done, true
+}
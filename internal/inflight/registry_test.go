@@ -0,0 +1,166 @@
+package inflight
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// blockingBody blocks reads until it is closed, like a stalled upstream.
+type blockingBody struct{ closed chan struct{} }
+
+func (b *blockingBody) Read([]byte) (int, error) {
+	<-b.closed
+	return 0, io.ErrClosedPipe
+}
+
+func (b *blockingBody) Close() error {
+	select {
+	case <-b.closed:
+	default:
+		close(b.closed)
+	}
+	return nil
+}
+
+func TestMiddlewareRegistersAndCancels(t *testing.T) {
+	g := NewRegistry()
+	entered := make(chan struct{})
+	readErr := make(chan error, 1)
+	h := g.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := FromContext(r.Context())
+		req.SetCaller("caller:abc")
+		req.SetAccount("acc@example.com")
+		req.SetRequest("openai_chat", "gpt-4o", "deepseek-chat", true)
+		req.SetStage(StageStreaming)
+		req.AddOutput("hello world!")
+		_, _ = w.Write([]byte("data: hi\n\n"))
+		body := req.TrackUpstream(&blockingBody{closed: make(chan struct{})})
+		close(entered)
+		_, err := body.Read(make([]byte, 8))
+		readErr <- err
+		<-r.Context().Done()
+		if !errors.Is(context.Cause(r.Context()), ErrCancelled) {
+			t.Errorf("unexpected cause: %v", context.Cause(r.Context()))
+		}
+	}))
+	served := make(chan struct{})
+	go func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil))
+		close(served)
+	}()
+	<-entered
+
+	items := g.List()
+	if len(items) != 1 {
+		t.Fatalf("expected one running request, got %v", items)
+	}
+	got := items[0]
+	if got.CallerID != "caller:abc" || got.AccountID != "acc@example.com" || got.Surface != "openai_chat" ||
+		got.ResolvedModel != "deepseek-chat" || !got.Stream || got.Stage != StageStreaming {
+		t.Fatalf("unexpected snapshot: %+v", got)
+	}
+	if got.BytesSent != int64(len("data: hi\n\n")) || got.OutputTokens != 3 {
+		t.Fatalf("unexpected counters: %+v", got)
+	}
+
+	done, ok := g.Cancel(got.ID)
+	if !ok {
+		t.Fatal("expected cancel to find the request")
+	}
+	if err := <-readErr; err == nil {
+		t.Fatal("expected upstream read to fail after cancel")
+	}
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler did not return after cancel")
+	}
+	<-served
+	if items := g.List(); len(items) != 0 {
+		t.Fatalf("expected registry to be empty, got %v", items)
+	}
+	if _, ok := g.Cancel(got.ID); ok {
+		t.Fatal("finished request should not be cancellable")
+	}
+}
+
+func TestMiddlewareSkipsNonCompletions(t *testing.T) {
+	g := NewRegistry()
+	h := g.Middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		if FromContext(r.Context()) != nil {
+			t.Errorf("%s %s should not be registered", r.Method, r.URL.Path)
+		}
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/models", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/admin/drain", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/responses/resp_1/cancel", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/responses/input_tokens", nil))
+}
+
+func TestChangedFiresOnStartAndFinish(t *testing.T) {
+	g := NewRegistry()
+	changed := g.Changed()
+	var during <-chan struct{}
+	h := g.Middleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		select {
+		case <-changed:
+		default:
+			t.Error("expected Changed to fire when the request started")
+		}
+		during = g.Changed()
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/messages", nil))
+	select {
+	case <-during:
+	default:
+		t.Fatal("expected Changed to fire when the request finished")
+	}
+}
+
+func TestCancelReachesDetachedContext(t *testing.T) {
+	g := NewRegistry()
+	client, leave := context.WithCancel(context.Background())
+	entered := make(chan context.Context, 1)
+	h := g.Middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		ctx, stop := Detach(r.Context())
+		defer stop()
+		entered <- ctx
+		<-ctx.Done()
+	}))
+	served := make(chan struct{})
+	go func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil).WithContext(client))
+		close(served)
+	}()
+	ctx := <-entered
+	leave()
+	if ctx.Err() != nil || g.InUse() != 1 {
+		t.Fatalf("detached work must outlive the client: err=%v running=%d", ctx.Err(), g.InUse())
+	}
+	if _, ok := g.Cancel(g.List()[0].ID); !ok {
+		t.Fatal("expected cancel to find the request")
+	}
+	select {
+	case <-served:
+	case <-time.After(2 * time.Second):
+		t.Fatal("admin cancel did not stop the detached work")
+	}
+	if !errors.Is(context.Cause(ctx), ErrCancelled) || g.InUse() != 0 {
+		t.Fatalf("unexpected state: cause=%v running=%d", context.Cause(ctx), g.InUse())
+	}
+}
+
+func TestNilRequestIsNoop(t *testing.T) {
+	req := FromContext(context.Background())
+	req.SetStage(StagePow)
+	req.AddOutput("x")
+	body := io.NopCloser(nil)
+	if req.TrackUpstream(body) != body {
+		t.Fatal("nil request should return the body unchanged")
+	}
+}
@@ -0,0 +1,257 @@
+package inflight
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stage names the part of the pipeline a request is currently in.
+type Stage string
+
+const (
+	StageStarted         Stage = "started"
+	StageWaitingAccount  Stage = "waiting_account"
+	StageUploading       Stage = "uploading_input_file"
+	StageCreateSession   Stage = "creating_session"
+	StagePow             Stage = "solving_pow"
+	StageWaitingUpstream Stage = "waiting_upstream"
+	StageStreaming       Stage = "streaming"
+	StageContinuing      Stage = "auto_continue"
+)
+
+// Request is one running API request. All methods are safe on a nil
+// *Request, which is what FromContext returns outside the middleware.
+type Request struct {
+	id        string
+	requestID string
+	traceID   string
+	method    string
+	path      string
+	startedAt time.Time
+	cancel    context.CancelCauseFunc
+	done      chan struct{}
+	registry  *Registry
+	// background marks an entry created by Spawn.
+	background bool
+
+	clientBytes   atomic.Int64
+	upstreamBytes atomic.Int64
+	asciiChars    atomic.Int64
+	otherChars    atomic.Int64
+
+	mu             sync.Mutex
+	callerID       string
+	accountID      string
+	surface        string
+	requestedModel string
+	resolvedModel  string
+	stream         bool
+	stage          Stage
+	cancelling     bool
+	upstream       []io.Closer
+	detached       []context.CancelCauseFunc
+}
+
+// Snapshot is the JSON view of a Request served by /admin/requests.
+type Snapshot struct {
+	ID             string    `json:"id"`
+	RequestID      string    `json:"request_id,omitempty"`
+	TraceID        string    `json:"trace_id,omitempty"`
+	Method         string    `json:"method"`
+	Path           string    `json:"path"`
+	CallerID       string    `json:"caller_id,omitempty"`
+	AccountID      string    `json:"account_id,omitempty"`
+	Surface        string    `json:"surface,omitempty"`
+	RequestedModel string    `json:"requested_model,omitempty"`
+	ResolvedModel  string    `json:"resolved_model,omitempty"`
+	Stream         bool      `json:"stream"`
+	Stage          Stage     `json:"stage"`
+	StartedAt      time.Time `json:"started_at"`
+	ElapsedMs      int64     `json:"elapsed_ms"`
+	BytesSent      int64     `json:"bytes_sent"`
+	UpstreamBytes  int64     `json:"upstream_bytes"`
+	OutputTokens   int       `json:"output_tokens"`
+	Cancelling     bool      `json:"cancelling,omitempty"`
+	Background     bool      `json:"background,omitempty"`
+}
+
+type ctxKey struct{}
+
+func withRequest(ctx context.Context, req *Request) context.Context {
+	return context.WithValue(ctx, ctxKey{}, req)
+}
+
+// FromContext returns the request registered by Registry.Middleware, or nil.
+func FromContext(ctx context.Context) *Request {
+	if ctx == nil {
+		return nil
+	}
+	req, _ := ctx.Value(ctxKey{}).(*Request)
+	return req
+}
+
+func (r *Request) SetCaller(callerID string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.callerID = callerID
+	r.mu.Unlock()
+}
+
+func (r *Request) SetAccount(accountID string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.accountID = accountID
+	r.mu.Unlock()
+}
+
+func (r *Request) SetRequest(surface, requestedModel, resolvedModel string, stream bool) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.surface = surface
+	r.requestedModel = requestedModel
+	r.resolvedModel = resolvedModel
+	r.stream = stream
+	r.mu.Unlock()
+}
+
+func (r *Request) SetStage(stage Stage) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.stage = stage
+	r.mu.Unlock()
+}
+
+// AddOutput counts generated text toward the output token estimate.
+func (r *Request) AddOutput(text string) {
+	if r == nil || text == "" {
+		return
+	}
+	var ascii, other int64
+	for _, ch := range text {
+		if ch < 128 {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	r.asciiChars.Add(ascii)
+	r.otherChars.Add(other)
+}
+
+// TrackUpstream counts bytes read from an upstream body and closes it when
+// the request is cancelled from the admin API.
+func (r *Request) TrackUpstream(body io.ReadCloser) io.ReadCloser {
+	if r == nil || body == nil {
+		return body
+	}
+	r.mu.Lock()
+	cancelling := r.cancelling
+	if !cancelling {
+		r.upstream = append(r.upstream, body)
+	}
+	r.mu.Unlock()
+	if cancelling {
+		_ = body.Close()
+	}
+	return &upstreamBody{ReadCloser: body, req: r}
+}
+
+type upstreamBody struct {
+	io.ReadCloser
+	req *Request
+}
+
+func (b *upstreamBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.req.upstreamBytes.Add(int64(n))
+	return n, err
+}
+
+// Detach returns a context that keeps ctx's values but not its
+// cancellation, for work that outlives the client connection. It is still
+// cancelled when the request registered in ctx is cancelled from the admin
+// API. Call stop once the work is done.
+func Detach(ctx context.Context) (detached context.Context, stop context.CancelFunc) {
+	detached, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+	stop = func() { cancel(nil) }
+	r := FromContext(ctx)
+	if r == nil {
+		return detached, stop
+	}
+	r.mu.Lock()
+	cancelling := r.cancelling
+	if !cancelling {
+		r.detached = append(r.detached, cancel)
+	}
+	r.mu.Unlock()
+	if cancelling {
+		cancel(ErrCancelled)
+	}
+	return detached, stop
+}
+
+// abort cancels the request context and any detached contexts, and closes
+// tracked upstream bodies so blocked reads return at once.
+func (r *Request) abort() {
+	r.mu.Lock()
+	if r.cancelling {
+		r.mu.Unlock()
+		return
+	}
+	r.cancelling = true
+	closers := r.upstream
+	r.upstream = nil
+	detached := r.detached
+	r.detached = nil
+	r.mu.Unlock()
+	r.cancel(ErrCancelled)
+	for _, cancel := range detached {
+		cancel(ErrCancelled)
+	}
+	for _, c := range closers {
+		_ = c.Close()
+	}
+}
+
+func (r *Request) snapshot(now time.Time) Snapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return Snapshot{
+		ID:             r.id,
+		RequestID:      r.requestID,
+		TraceID:        r.traceID,
+		Method:         r.method,
+		Path:           r.path,
+		CallerID:       r.callerID,
+		AccountID:      r.accountID,
+		Surface:        r.surface,
+		RequestedModel: r.requestedModel,
+		ResolvedModel:  r.resolvedModel,
+		Stream:         r.stream,
+		Stage:          r.stage,
+		StartedAt:      r.startedAt,
+		ElapsedMs:      now.Sub(r.startedAt).Milliseconds(),
+		BytesSent:      r.clientBytes.Load(),
+		UpstreamBytes:  r.upstreamBytes.Load(),
+		OutputTokens:   estimateTokens(r.asciiChars.Load(), r.otherChars.Load()),
+		Cancelling:     r.cancelling,
+		Background:     r.background,
+	}
+}
+
+// estimateTokens uses the same ratios as util.EstimateTokens: about four
+// ASCII characters or 1.3 CJK characters per token.
+func estimateTokens(ascii, other int64) int {
+	return int(ascii/4 + (other*10+7)/13)
+}
@@ -0,0 +1,33 @@
+package inflight
+
+import (
+	"net/http"
+	"strings"
+)
+
+// completionSuffixes are the path endings of routes that start a model call,
+// covering the /v1, root and /anthropic aliases alike.
+var completionSuffixes = []string{
+	"/chat/completions",
+	"/completions",
+	"/responses",
+	"/messages",
+	":generateContent",
+	":streamGenerateContent",
+}
+
+// IsCompletionRequest reports whether r starts a model call. Cancels, token
+// counts and everything under /admin are not, so they keep working while
+// draining and stay out of the request registry.
+func IsCompletionRequest(r *http.Request) bool {
+	if r.Method != http.MethodPost || strings.HasPrefix(r.URL.Path, "/admin") {
+		return false
+	}
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	for _, suffix := range completionSuffixes {
+		if strings.HasSuffix(path, suffix) {
+			return true
+		}
+	}
+	return false
+}
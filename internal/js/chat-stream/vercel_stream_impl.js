@@ -9,7 +9,7 @@ const {
   parseStandaloneToolCalls,
   formatOpenAIStreamToolCalls,
 } = require('../helpers/stream-tool-sieve');
-const { BASE_HEADERS } = require('../shared/deepseek-constants');
+const { BASE_HEADERS, deepseekURL } = require('../shared/deepseek-constants');
 const { writeOpenAIError, openAIErrorType } = require('./error_shape');
 const { parseChunkForContent, isCitation } = require('./sse_parse');
 const { buildUsage } = require('./token_usage');
@@ -32,8 +32,8 @@ const {
   trimContinuationOverlap,
 } = require('./dedupe');
 
-const DEEPSEEK_COMPLETION_URL = 'https://chat.deepseek.com/api/v0/chat/completion';
-const DEEPSEEK_CONTINUE_URL = 'https://chat.deepseek.com/api/v0/chat/continue';
+const DEEPSEEK_COMPLETION_PATH = '/api/v0/chat/completion';
+const DEEPSEEK_CONTINUE_PATH = '/api/v0/chat/continue';
 const EMPTY_OUTPUT_RETRY_SUFFIX = 'Previous reply had no visible output. Please regenerate the visible final answer or tool call now.';
 const EMPTY_OUTPUT_RETRY_MAX_ATTEMPTS = 1;
 const AUTO_CONTINUE_MAX_ROUNDS = 8;
@@ -132,13 +132,13 @@ async function handleVercelStream(req, res, rawBody, payload) {
         throw err;
       }
     };
-    const fetchCompletion = (bodyPayload) => fetchDeepSeekStream(DEEPSEEK_COMPLETION_URL, bodyPayload, currentPowHeader);
+    const fetchCompletion = (bodyPayload) => fetchDeepSeekStream(deepseekURL(DEEPSEEK_COMPLETION_PATH), bodyPayload, currentPowHeader);
     const fetchContinue = async (messageID) => {
       const powHeader = await refreshPowHeader('continue');
       if (!powHeader) {
         return null;
       }
-      return fetchDeepSeekStream(DEEPSEEK_CONTINUE_URL, {
+      return fetchDeepSeekStream(deepseekURL(DEEPSEEK_CONTINUE_PATH), {
         chat_session_id: sessionID,
         message_id: messageID,
         fallback_to_resume: true,
@@ -435,7 +435,7 @@ async function handleVercelStream(req, res, rawBody, payload) {
         return;
       }
       completionRes = await fetchDeepSeekStream(
-        DEEPSEEK_COMPLETION_URL,
+        deepseekURL(DEEPSEEK_COMPLETION_PATH),
         clonePayloadForEmptyOutputRetry(completionPayload, processed.responseMessageID),
         retryPowHeader,
       );
@@ -113,7 +113,16 @@ function loadSharedConstants() {
 
 const shared = loadSharedConstants();
 
+const DEFAULT_BASE_URL = 'https://chat.deepseek.com';
+
+// Mirrors protocol.URL in Go: DS2API_UPSTREAM_BASE_URL overrides the origin.
+function deepseekURL(apiPath) {
+  const base = String(process.env.DS2API_UPSTREAM_BASE_URL || '').trim().replace(/\/+$/, '');
+  return (base || DEFAULT_BASE_URL) + apiPath;
+}
+
 module.exports = {
+  deepseekURL,
   CLIENT: Object.freeze({ ...shared.client }),
   CLIENT_VERSION: shared.client.version,
   BASE_HEADERS: Object.freeze(shared.baseHeaders),
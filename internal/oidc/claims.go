@@ -0,0 +1,155 @@
+package oidc
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"ds2api/internal/auth"
+	"ds2api/internal/config"
+)
+
+// Identity is the admin identity derived from verified ID token claims.
+type Identity struct {
+	Subject string
+	Email   string
+	Role    string
+}
+
+// ResolveIdentity applies the configured email-domain and group allow-lists
+// and maps claims onto an admin role. The highest matching role wins; when
+// nothing matches DefaultRole is used, and an empty default denies access.
+// Email-domain rules and role mappings on the email claim only trust
+// addresses whose email_verified claim is present and true.
+func ResolveIdentity(cfg config.AdminOIDCConfig, claims map[string]any) (Identity, error) {
+	email := strings.ToLower(strings.TrimSpace(claimString(claims["email"])))
+	verified := emailVerified(claims["email_verified"])
+	if len(cfg.AllowedEmailDomains) > 0 {
+		if email == "" {
+			return Identity{}, errors.New("id_token has no email claim")
+		}
+		if !verified {
+			return Identity{}, errors.New("email address is not verified")
+		}
+		if !emailDomainAllowed(email, cfg.AllowedEmailDomains) {
+			return Identity{}, fmt.Errorf("email domain of %s is not allowed", email)
+		}
+	}
+	groupsClaim := strings.TrimSpace(cfg.GroupsClaim)
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+	if len(cfg.AllowedGroups) > 0 {
+		groups := claimValues(claims[groupsClaim])
+		allowed := false
+		for _, want := range cfg.AllowedGroups {
+			if containsFold(groups, want) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return Identity{}, errors.New("user is not in an allowed group")
+		}
+	}
+
+	role := ""
+	for _, m := range cfg.RoleMappings {
+		mapped := config.NormalizeAdminRole(m.Role)
+		claim := strings.TrimSpace(m.Claim)
+		if mapped == "" || (strings.EqualFold(claim, "email") && !verified) || !containsFold(claimValues(claims[claim]), m.Value) {
+			continue
+		}
+		if role == "" || !auth.AdminRoleAllows(role, mapped) {
+			role = mapped
+		}
+	}
+	if role == "" {
+		role = config.NormalizeAdminRole(cfg.DefaultRole)
+	}
+	if role == "" {
+		return Identity{}, errors.New("no admin role mapping matched")
+	}
+
+	subject := email
+	if subject == "" {
+		subject = strings.TrimSpace(claimString(claims["preferred_username"]))
+	}
+	if subject == "" {
+		subject = strings.TrimSpace(claimString(claims["sub"]))
+	}
+	if subject == "" {
+		return Identity{}, errors.New("id_token has no subject")
+	}
+	return Identity{Subject: subject, Email: email, Role: role}, nil
+}
+
+// emailVerified reports whether an email_verified claim is true. Some
+// providers send it as the string "true" instead of a boolean.
+func emailVerified(v any) bool {
+	switch x := v.(type) {
+	case bool:
+		return x
+	case string:
+		verified, err := strconv.ParseBool(strings.TrimSpace(x))
+		return err == nil && verified
+	}
+	return false
+}
+
+func emailDomainAllowed(email string, domains []string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := email[at+1:]
+	for _, allowed := range domains {
+		allowed = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(allowed), "@"))
+		if allowed != "" && domain == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func claimString(v any) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case bool:
+		if x {
+			return "true"
+		}
+		return "false"
+	case float64:
+		return fmt.Sprintf("%v", x)
+	}
+	return ""
+}
+
+func claimValues(v any) []string {
+	if arr, ok := v.([]any); ok {
+		out := make([]string, 0, len(arr))
+		for _, item := range arr {
+			if s := claimString(item); s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	if s := claimString(v); s != "" {
+		return []string{s}
+	}
+	return nil
+}
+
+func containsFold(values []string, want string) bool {
+	want = strings.TrimSpace(want)
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), want) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package oidc
+
+import (
+	"strings"
+	"testing"
+
+	"ds2api/internal/config"
+)
+
+func TestResolveIdentityPicksHighestMappedRole(t *testing.T) {
+	cfg := config.AdminOIDCConfig{
+		GroupsClaim: "roles",
+		RoleMappings: []config.AdminOIDCRoleMapping{
+			{Claim: "roles", Value: "viewers", Role: "viewer"},
+			{Claim: "roles", Value: "Owners", Role: "owner"},
+			{Claim: "roles", Value: "ops", Role: "operator"},
+		},
+	}
+	got, err := ResolveIdentity(cfg, map[string]any{
+		"sub":   "abc",
+		"email": "A@Example.com",
+		"roles": []any{"viewers", "owners", "ops"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Role != config.AdminRoleOwner || got.Subject != "a@example.com" {
+		t.Fatalf("unexpected identity: %#v", got)
+	}
+}
+
+func TestResolveIdentityAllowListsAndDefaultRole(t *testing.T) {
+	cfg := config.AdminOIDCConfig{AllowedGroups: []string{"staff"}, DefaultRole: "viewer"}
+	if _, err := ResolveIdentity(cfg, map[string]any{"sub": "x", "groups": []any{"guests"}}); err == nil {
+		t.Fatal("expected group allow-list rejection")
+	}
+	got, err := ResolveIdentity(cfg, map[string]any{"sub": "x", "preferred_username": "xavier", "groups": "staff"})
+	if err != nil || got.Role != config.AdminRoleViewer || got.Subject != "xavier" {
+		t.Fatalf("unexpected result: %#v err=%v", got, err)
+	}
+
+	cfg = config.AdminOIDCConfig{AllowedEmailDomains: []string{"@corp.example"}, DefaultRole: "viewer"}
+	if _, err := ResolveIdentity(cfg, map[string]any{"sub": "x", "email": "x@corp.example", "email_verified": false}); err == nil || !strings.Contains(err.Error(), "not verified") {
+		t.Fatalf("expected unverified email rejection, got %v", err)
+	}
+	if _, err := ResolveIdentity(config.AdminOIDCConfig{}, map[string]any{"sub": "x"}); err == nil {
+		t.Fatal("expected denial when no role maps and no default role is set")
+	}
+}
+
+func TestResolveIdentityRequiresVerifiedEmailForEmailRules(t *testing.T) {
+	cfg := config.AdminOIDCConfig{AllowedEmailDomains: []string{"corp.example"}, DefaultRole: "viewer"}
+	for _, verified := range []any{"false", "FALSE", nil, "yes"} {
+		claims := map[string]any{"sub": "x", "email": "x@corp.example"}
+		if verified != nil {
+			claims["email_verified"] = verified
+		}
+		if _, err := ResolveIdentity(cfg, claims); err == nil || !strings.Contains(err.Error(), "not verified") {
+			t.Fatalf("email_verified=%v: expected unverified email rejection, got %v", verified, err)
+		}
+	}
+	if got, err := ResolveIdentity(cfg, map[string]any{"sub": "x", "email": "x@corp.example", "email_verified": "true"}); err != nil || got.Subject != "x@corp.example" {
+		t.Fatalf("expected string \"true\" to count as verified, got %#v err=%v", got, err)
+	}
+
+	cfg = config.AdminOIDCConfig{
+		RoleMappings: []config.AdminOIDCRoleMapping{{Claim: "email", Value: "boss@corp.example", Role: "owner"}},
+		DefaultRole:  "viewer",
+	}
+	got, err := ResolveIdentity(cfg, map[string]any{"sub": "x", "email": "boss@corp.example", "email_verified": "false"})
+	if err != nil || got.Role != config.AdminRoleViewer {
+		t.Fatalf("expected email mapping to be skipped for an unverified address, got %#v err=%v", got, err)
+	}
+	got, err = ResolveIdentity(cfg, map[string]any{"sub": "x", "email": "boss@corp.example", "email_verified": true})
+	if err != nil || got.Role != config.AdminRoleOwner {
+		t.Fatalf("expected email mapping for a verified address, got %#v err=%v", got, err)
+	}
+}
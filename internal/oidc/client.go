@@ -0,0 +1,201 @@
+// Package oidc implements the small slice of OpenID Connect needed for admin
+// single sign-on: discovery, JWKS, the authorization-code + PKCE exchange and
+// ID token verification.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"ds2api/internal/config"
+)
+
+const (
+	discoveryTTL = time.Hour
+	jwksTTL      = time.Hour
+)
+
+var defaultScopes = []string{"openid", "email", "profile"}
+
+// Discovery is the subset of the provider metadata document ds2api uses.
+type Discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// TokenResponse is the token endpoint reply for the authorization-code grant.
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	IDToken     string `json:"id_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+type cachedDiscovery struct {
+	doc       Discovery
+	fetchedAt time.Time
+}
+
+type cachedKeys struct {
+	keys      map[string]any
+	fetchedAt time.Time
+}
+
+// Client caches discovery documents and signing keys per issuer.
+type Client struct {
+	HTTP *http.Client
+
+	mu        sync.Mutex
+	discovery map[string]cachedDiscovery
+	jwks      map[string]cachedKeys
+	now       func() time.Time
+}
+
+func NewClient(httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Client{
+		HTTP:      httpClient,
+		discovery: map[string]cachedDiscovery{},
+		jwks:      map[string]cachedKeys{},
+		now:       time.Now,
+	}
+}
+
+var DefaultClient = NewClient(nil)
+
+func normalizeIssuer(issuer string) string {
+	return strings.TrimRight(strings.TrimSpace(issuer), "/")
+}
+
+// Discover loads (or returns the cached) provider metadata for issuer.
+func (c *Client) Discover(ctx context.Context, issuer string) (Discovery, error) {
+	issuer = normalizeIssuer(issuer)
+	if issuer == "" {
+		return Discovery{}, errors.New("oidc issuer is not configured")
+	}
+	c.mu.Lock()
+	if cached, ok := c.discovery[issuer]; ok && c.now().Sub(cached.fetchedAt) < discoveryTTL {
+		c.mu.Unlock()
+		return cached.doc, nil
+	}
+	c.mu.Unlock()
+
+	var doc Discovery
+	if err := c.getJSON(ctx, issuer+"/.well-known/openid-configuration", &doc); err != nil {
+		return Discovery{}, fmt.Errorf("oidc discovery failed: %w", err)
+	}
+	if normalizeIssuer(doc.Issuer) != issuer {
+		return Discovery{}, fmt.Errorf("oidc discovery issuer mismatch: %q", doc.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return Discovery{}, errors.New("oidc discovery document is missing endpoints")
+	}
+	c.mu.Lock()
+	c.discovery[issuer] = cachedDiscovery{doc: doc, fetchedAt: c.now()}
+	c.mu.Unlock()
+	return doc, nil
+}
+
+// AuthCodeURL builds the authorization request URL with an S256 PKCE challenge.
+func AuthCodeURL(doc Discovery, cfg config.AdminOIDCConfig, redirectURL, state, nonce, verifier string) string {
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = defaultScopes
+	}
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", strings.TrimSpace(cfg.ClientID))
+	q.Set("redirect_uri", redirectURL)
+	q.Set("scope", strings.Join(scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", PKCEChallenge(verifier))
+	q.Set("code_challenge_method", "S256")
+	sep := "?"
+	if strings.Contains(doc.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return doc.AuthorizationEndpoint + sep + q.Encode()
+}
+
+// Exchange redeems an authorization code at the token endpoint.
+func (c *Client) Exchange(ctx context.Context, doc Discovery, cfg config.AdminOIDCConfig, redirectURL, code, verifier string) (TokenResponse, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURL)
+	form.Set("code_verifier", verifier)
+	form.Set("client_id", strings.TrimSpace(cfg.ClientID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, doc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return TokenResponse{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if secret := strings.TrimSpace(cfg.ClientSecret); secret != "" {
+		req.SetBasicAuth(url.QueryEscape(strings.TrimSpace(cfg.ClientID)), url.QueryEscape(secret))
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return TokenResponse{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return TokenResponse{}, fmt.Errorf("oidc token endpoint returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var out TokenResponse
+	if err := json.Unmarshal(body, &out); err != nil {
+		return TokenResponse{}, fmt.Errorf("oidc token response: %w", err)
+	}
+	if out.IDToken == "" {
+		return TokenResponse{}, errors.New("oidc token response has no id_token")
+	}
+	return out, nil
+}
+
+func (c *Client) getJSON(ctx context.Context, rawURL string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %d", rawURL, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
+
+// RandomToken returns a URL-safe random string for state, nonce and PKCE.
+func RandomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,205 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"ds2api/internal/config"
+)
+
+const clockSkew = 2 * time.Minute
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// VerifyIDToken checks the signature and standard claims of an ID token and
+// returns its claims.
+func (c *Client) VerifyIDToken(ctx context.Context, doc Discovery, cfg config.AdminOIDCConfig, raw, nonce string) (map[string]any, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("id_token is not a JWS")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("id_token header: %w", err)
+	}
+	key, err := c.signingKey(ctx, doc, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("id_token signature is not base64url")
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	switch header.Alg {
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok || rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) != nil {
+			return nil, errors.New("id_token signature is invalid")
+		}
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(sig) != 64 {
+			return nil, errors.New("id_token signature is invalid")
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return nil, errors.New("id_token signature is invalid")
+		}
+	default:
+		return nil, fmt.Errorf("id_token alg %q is not supported", header.Alg)
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("id_token payload: %w", err)
+	}
+	if iss, _ := claims["iss"].(string); normalizeIssuer(iss) != normalizeIssuer(doc.Issuer) {
+		return nil, errors.New("id_token issuer mismatch")
+	}
+	if !audienceContains(claims["aud"], strings.TrimSpace(cfg.ClientID)) {
+		return nil, errors.New("id_token audience mismatch")
+	}
+	now := c.now()
+	exp, _ := claims["exp"].(float64)
+	if time.Unix(int64(exp), 0).Add(clockSkew).Before(now) {
+		return nil, errors.New("id_token expired")
+	}
+	if iat, ok := claims["iat"].(float64); ok && time.Unix(int64(iat), 0).Add(-clockSkew).After(now) {
+		return nil, errors.New("id_token issued in the future")
+	}
+	if got, _ := claims["nonce"].(string); nonce == "" || got != nonce {
+		return nil, errors.New("id_token nonce mismatch")
+	}
+	return claims, nil
+}
+
+func (c *Client) signingKey(ctx context.Context, doc Discovery, kid string) (any, error) {
+	issuer := normalizeIssuer(doc.Issuer)
+	c.mu.Lock()
+	cached, ok := c.jwks[issuer]
+	c.mu.Unlock()
+	fresh := ok && c.now().Sub(cached.fetchedAt) < jwksTTL
+	if fresh {
+		if key := pickKey(cached.keys, kid); key != nil {
+			return key, nil
+		}
+	}
+	// Unknown kid usually means the provider rotated keys; refetch once.
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := c.getJSON(ctx, doc.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("oidc jwks fetch failed: %w", err)
+	}
+	keys := make(map[string]any, len(set.Keys))
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		id := k.Kid
+		if id == "" {
+			id = fmt.Sprintf("#%d", i)
+		}
+		keys[id] = pub
+	}
+	c.mu.Lock()
+	c.jwks[issuer] = cachedKeys{keys: keys, fetchedAt: c.now()}
+	c.mu.Unlock()
+	if key := pickKey(keys, kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("oidc signing key %q not found", kid)
+}
+
+func pickKey(keys map[string]any, kid string) any {
+	if kid != "" {
+		return keys[kid]
+	}
+	if len(keys) == 1 {
+		for _, k := range keys {
+			return k
+		}
+	}
+	return nil
+}
+
+func (k jwk) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func decodeSegment(seg string, out any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+func audienceContains(aud any, clientID string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientID
+	case []any:
+		for _, item := range v {
+			if s, _ := item.(string); s == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
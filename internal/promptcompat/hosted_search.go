@@ -0,0 +1,51 @@
+package promptcompat
+
+import "strings"
+
+// SplitHostedSearchTools removes provider-hosted web search declarations from
+// a tool list and reports whether any were present. DeepSeek runs search
+// natively, so these tools turn on search mode instead of being advertised in
+// the tool prompt. Recognised forms:
+//   - OpenAI Responses: {"type":"web_search"} and its preview/dated variants
+//   - Claude server tool: {"type":"web_search_20250305","name":"web_search"}
+//   - Gemini: {"googleSearch":{}} / {"googleSearchRetrieval":{}}
+func SplitHostedSearchTools(toolsRaw any) (any, bool) {
+	tools, _ := toolsRaw.([]any)
+	if len(tools) == 0 {
+		return toolsRaw, false
+	}
+	out := make([]any, 0, len(tools))
+	found := false
+	for _, item := range tools {
+		tool, ok := item.(map[string]any)
+		if ok && isHostedSearchTool(tool) {
+			found = true
+			continue
+		}
+		out = append(out, item)
+	}
+	if !found {
+		return toolsRaw, false
+	}
+	if len(out) == 0 {
+		return nil, true
+	}
+	return out, true
+}
+
+// HasOpenAIWebSearchOptions reports whether a Chat Completions request asked
+// for search through web_search_options.
+func HasOpenAIWebSearchOptions(req map[string]any) bool {
+	v, ok := req["web_search_options"]
+	return ok && v != nil
+}
+
+func isHostedSearchTool(tool map[string]any) bool {
+	for _, key := range []string{"googleSearch", "google_search", "googleSearchRetrieval", "google_search_retrieval"} {
+		if _, ok := tool[key]; ok {
+			return true
+		}
+	}
+	typ := strings.ToLower(strings.TrimSpace(asString(tool["type"])))
+	return typ == "web_search" || strings.HasPrefix(typ, "web_search_")
+}
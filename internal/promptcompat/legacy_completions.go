@@ -0,0 +1,138 @@
+package promptcompat
+
+import (
+	"fmt"
+	"strings"
+
+	"ds2api/internal/config"
+	"ds2api/internal/util"
+)
+
+// MaxLegacyCompletionChoices caps len(prompt) * n for one /v1/completions
+// request, since every choice is a separate upstream generation.
+const MaxLegacyCompletionChoices = 16
+
+// LegacyCompletionRequest is a normalized /v1/completions request. Each
+// prompt becomes its own StandardRequest and is generated N times; choice
+// index i*N+j belongs to prompt i.
+type LegacyCompletionRequest struct {
+	ResponseModel string
+	Prompts       []string
+	Requests      []StandardRequest
+	N             int
+	Echo          bool
+	Stream        bool
+}
+
+// Choices returns the total number of choices the request produces.
+func (r LegacyCompletionRequest) Choices() int {
+	return len(r.Requests) * r.N
+}
+
+// NormalizeOpenAICompletionsRequest wraps legacy text prompts into chat
+// requests. Thinking is off unless explicitly requested, since completion
+// clients expect the continuation text only.
+func NormalizeOpenAICompletionsRequest(store ConfigReader, req map[string]any, traceID string) (LegacyCompletionRequest, error) {
+	model, _ := req["model"].(string)
+	model = strings.TrimSpace(model)
+	if model == "" {
+		return LegacyCompletionRequest{}, fmt.Errorf("request must include 'model'")
+	}
+	resolvedModel, ok := config.ResolveModel(store, model)
+	if !ok {
+		return LegacyCompletionRequest{}, fmt.Errorf("model %q is not available", model)
+	}
+	prompts, err := parseLegacyPrompts(req["prompt"])
+	if err != nil {
+		return LegacyCompletionRequest{}, err
+	}
+	suffix, _ := req["suffix"].(string)
+	n, err := ParsePositiveIntField(req, "n")
+	if err != nil {
+		return LegacyCompletionRequest{}, err
+	}
+	if n == 0 {
+		n = 1
+	}
+	if len(prompts)*n > MaxLegacyCompletionChoices {
+		return LegacyCompletionRequest{}, fmt.Errorf("len(prompt) * n must not exceed %d", MaxLegacyCompletionChoices)
+	}
+	stops, err := ParseStopSequences(req["stop"], "stop")
+	if err != nil {
+		return LegacyCompletionRequest{}, err
+	}
+	maxTokens, err := ParseMaxOutputTokens(req, "max_tokens")
+	if err != nil {
+		return LegacyCompletionRequest{}, err
+	}
+	_, searchEnabled, _ := config.GetModelConfig(resolvedModel)
+	thinkingEnabled := util.ResolveThinkingEnabled(req, false)
+	if config.IsNoThinkingModel(resolvedModel) {
+		thinkingEnabled = false
+	}
+	passThrough := collectOpenAIChatPassThrough(req)
+	stream := util.ToBool(req["stream"])
+
+	out := LegacyCompletionRequest{
+		ResponseModel: model,
+		Prompts:       prompts,
+		N:             n,
+		Echo:          util.ToBool(req["echo"]),
+		Stream:        stream,
+	}
+	for _, prompt := range prompts {
+		messages := []any{map[string]any{"role": "user", "content": legacyCompletionInstruction(prompt, suffix)}}
+		finalPrompt, _ := BuildOpenAIPrompt(messages, nil, traceID, DefaultToolChoicePolicy(), thinkingEnabled)
+		out.Requests = append(out.Requests, StandardRequest{
+			Surface:         "openai_completions",
+			RequestedModel:  model,
+			ResolvedModel:   resolvedModel,
+			ResponseModel:   model,
+			Messages:        messages,
+			PromptTokenText: finalPrompt,
+			FinalPrompt:     finalPrompt,
+			ToolChoice:      DefaultToolChoicePolicy(),
+			Stream:          stream,
+			Thinking:        thinkingEnabled,
+			Search:          searchEnabled,
+			StopSequences:   stops,
+			MaxOutputTokens: maxTokens,
+			PassThrough:     passThrough,
+		})
+	}
+	return out, nil
+}
+
+func parseLegacyPrompts(raw any) ([]string, error) {
+	switch v := raw.(type) {
+	case string:
+		if v == "" {
+			return nil, fmt.Errorf("request must include a non-empty 'prompt'")
+		}
+		return []string{v}, nil
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("prompt must be a string or an array of strings; token id prompts are not supported")
+			}
+			out = append(out, s)
+		}
+		if len(out) == 0 {
+			return nil, fmt.Errorf("request must include a non-empty 'prompt'")
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("request must include 'prompt' as a string or an array of strings")
+	}
+}
+
+// legacyCompletionInstruction turns a raw prompt (and optional suffix for
+// fill-in-the-middle) into a chat turn asking for the continuation only.
+func legacyCompletionInstruction(prompt, suffix string) string {
+	if suffix == "" {
+		return "Continue the text below. Reply with the continuation only: do not repeat the text, add commentary or wrap it in code fences.\n\n" + prompt
+	}
+	return "Write the text that belongs between PREFIX and SUFFIX. Reply with the missing text only: do not repeat either part, add commentary or wrap it in code fences.\n\nPREFIX:\n" + prompt + "\n\nSUFFIX:\n" + suffix
+}
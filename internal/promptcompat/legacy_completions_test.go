@@ -0,0 +1,35 @@
+package promptcompat
+
+import "testing"
+
+func TestNormalizeOpenAICompletionsRequestBuildsOneRequestPerPrompt(t *testing.T) {
+	got, err := NormalizeOpenAICompletionsRequest(nil, map[string]any{
+		"model":      "deepseek-v4-pro",
+		"prompt":     []any{"a", "b"},
+		"n":          float64(3),
+		"max_tokens": float64(32),
+	}, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got.Requests) != 2 || got.N != 3 || got.Choices() != 6 {
+		t.Fatalf("unexpected fan-out: %d requests, n=%d", len(got.Requests), got.N)
+	}
+	if got.Requests[0].Thinking {
+		t.Fatalf("expected thinking to default to off")
+	}
+	if got.Requests[1].MaxOutputTokens != 32 {
+		t.Fatalf("expected max_tokens on every request, got %d", got.Requests[1].MaxOutputTokens)
+	}
+}
+
+func TestNormalizeOpenAICompletionsRequestCapsChoices(t *testing.T) {
+	_, err := NormalizeOpenAICompletionsRequest(nil, map[string]any{
+		"model":  "deepseek-v4-flash",
+		"prompt": "a",
+		"n":      float64(MaxLegacyCompletionChoices + 1),
+	}, "")
+	if err == nil {
+		t.Fatalf("expected oversized n to be rejected")
+	}
+}
@@ -0,0 +1,96 @@
+package promptcompat
+
+import (
+	"fmt"
+	"math"
+
+	"ds2api/internal/sse"
+)
+
+// OutputLimits returns the stop strings and token cap that the stream
+// runtimes enforce locally.
+func (r StandardRequest) OutputLimits() sse.OutputLimits {
+	model := r.ResponseModel
+	if model == "" {
+		model = r.ResolvedModel
+	}
+	return sse.OutputLimits{
+		StopSequences: r.StopSequences,
+		MaxTokens:     r.MaxOutputTokens,
+		Model:         model,
+	}
+}
+
+// ParseStopSequences accepts a single string or an array of strings.
+func ParseStopSequences(raw any, field string) ([]string, error) {
+	switch v := raw.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		if v == "" {
+			return nil, nil
+		}
+		return []string{v}, nil
+	case []string:
+		return compactStops(v), nil
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a string or an array of strings", field)
+			}
+			out = append(out, s)
+		}
+		return compactStops(out), nil
+	default:
+		return nil, fmt.Errorf("%s must be a string or an array of strings", field)
+	}
+}
+
+// ParseMaxOutputTokens reads the first present output-limit key as a
+// positive integer.
+func ParseMaxOutputTokens(req map[string]any, keys ...string) (int, error) {
+	return ParsePositiveIntField(req, keys...)
+}
+
+// ParsePositiveIntField reads the first present key as a positive integer.
+// It returns 0 when none of the keys are set.
+func ParsePositiveIntField(req map[string]any, keys ...string) (int, error) {
+	for _, key := range keys {
+		raw, ok := req[key]
+		if !ok || raw == nil {
+			continue
+		}
+		var n float64
+		switch v := raw.(type) {
+		case float64:
+			n = v
+		case int:
+			n = float64(v)
+		case int64:
+			n = float64(v)
+		default:
+			return 0, fmt.Errorf("%s must be a positive integer", key)
+		}
+		if n < 1 || n != math.Trunc(n) || n > math.MaxInt32 {
+			return 0, fmt.Errorf("%s must be a positive integer", key)
+		}
+		return int(n), nil
+	}
+	return 0, nil
+}
+
+func compactStops(in []string) []string {
+	out := make([]string, 0, len(in))
+	for _, s := range in {
+		if s == "" {
+			continue
+		}
+		out = append(out, s)
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
@@ -0,0 +1,32 @@
+package promptcompat
+
+import "testing"
+
+func TestParseStopSequencesAcceptsStringOrArray(t *testing.T) {
+	got, err := ParseStopSequences("END", "stop")
+	if err != nil || len(got) != 1 || got[0] != "END" {
+		t.Fatalf("unexpected single stop: %#v err=%v", got, err)
+	}
+	got, err = ParseStopSequences([]any{"a", "", "b"}, "stop")
+	if err != nil || len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Fatalf("unexpected stop list: %#v err=%v", got, err)
+	}
+	if _, err := ParseStopSequences([]any{"a", 1}, "stop"); err == nil {
+		t.Fatalf("expected non-string stop to fail")
+	}
+}
+
+func TestParseMaxOutputTokensUsesFirstPresentKey(t *testing.T) {
+	n, err := ParseMaxOutputTokens(map[string]any{"max_tokens": float64(10), "max_completion_tokens": float64(20)}, "max_completion_tokens", "max_tokens")
+	if err != nil || n != 20 {
+		t.Fatalf("expected max_completion_tokens to win, got %d err=%v", n, err)
+	}
+	for _, bad := range []any{float64(0), float64(1.5), "10"} {
+		if _, err := ParseMaxOutputTokens(map[string]any{"max_tokens": bad}, "max_tokens"); err == nil {
+			t.Fatalf("expected %#v to be rejected", bad)
+		}
+	}
+	if n, err := ParseMaxOutputTokens(map[string]any{}, "max_tokens"); err != nil || n != 0 {
+		t.Fatalf("expected absent cap to be zero, got %d err=%v", n, err)
+	}
+}
@@ -27,15 +27,31 @@ func NormalizeOpenAIChatRequest(store ConfigReader, req map[string]any, traceID
 	if config.IsNoThinkingModel(resolvedModel) {
 		thinkingEnabled = false
 	}
+	toolsRaw, hostedSearch := SplitHostedSearchTools(req["tools"])
+	if hostedSearch || HasOpenAIWebSearchOptions(req) {
+		searchEnabled = true
+	}
 	responseModel := strings.TrimSpace(model)
 	if responseModel == "" {
 		responseModel = resolvedModel
 	}
 	toolPolicy := DefaultToolChoicePolicy()
-	finalPrompt, toolNames := BuildOpenAIPrompt(messagesRaw, req["tools"], traceID, toolPolicy, thinkingEnabled)
-	toolNames = ensureToolDetectionEnabled(toolNames, req["tools"])
+	finalPrompt, toolNames := BuildOpenAIPrompt(messagesRaw, toolsRaw, traceID, toolPolicy, thinkingEnabled)
+	toolNames = ensureToolDetectionEnabled(toolNames, toolsRaw)
 	passThrough := collectOpenAIChatPassThrough(req)
 	refFileIDs := CollectOpenAIRefFileIDs(req)
+	stops, err := ParseStopSequences(req["stop"], "stop")
+	if err != nil {
+		return StandardRequest{}, err
+	}
+	maxTokens, err := ParseMaxOutputTokens(req, "max_completion_tokens", "max_tokens")
+	if err != nil {
+		return StandardRequest{}, err
+	}
+	choices, err := ParsePositiveIntField(req, "n")
+	if err != nil {
+		return StandardRequest{}, err
+	}
 
 	return StandardRequest{
 		Surface:         "openai_chat",
@@ -44,15 +60,19 @@ func NormalizeOpenAIChatRequest(store ConfigReader, req map[string]any, traceID
 		ResponseModel:   responseModel,
 		Messages:        messagesRaw,
 		PromptTokenText: finalPrompt,
-		ToolsRaw:        req["tools"],
+		ToolsRaw:        toolsRaw,
 		FinalPrompt:     finalPrompt,
 		ToolNames:       toolNames,
 		ToolChoice:      toolPolicy,
 		Stream:          util.ToBool(req["stream"]),
+		Resumable:       resumableStreamRequested(req),
 		Thinking:        thinkingEnabled,
 		Search:          searchEnabled,
 		RefFileIDs:      refFileIDs,
 		RefFileTokens:   estimateInlineFileTokens(req),
+		StopSequences:   stops,
+		MaxOutputTokens: maxTokens,
+		Choices:         choices,
 		PassThrough:     passThrough,
 	}, nil
 }
@@ -73,21 +93,34 @@ func NormalizeOpenAIResponsesRequest(store ConfigReader, req map[string]any, tra
 		thinkingEnabled = false
 	}
 
+	toolsRaw, hostedSearch := SplitHostedSearchTools(req["tools"])
+	if hostedSearch {
+		searchEnabled = true
+	}
+
 	messagesRaw := ResponsesMessagesFromRequest(req)
 	if len(messagesRaw) == 0 {
 		return StandardRequest{}, fmt.Errorf("request must include 'input' or 'messages'")
 	}
-	toolPolicy, err := parseToolChoicePolicy(req["tool_choice"], req["tools"])
+	toolPolicy, err := parseToolChoicePolicy(req["tool_choice"], toolsRaw)
 	if err != nil {
 		return StandardRequest{}, err
 	}
-	finalPrompt, toolNames := BuildOpenAIPrompt(messagesRaw, req["tools"], traceID, toolPolicy, thinkingEnabled)
-	toolNames = ensureToolDetectionEnabled(toolNames, req["tools"])
+	finalPrompt, toolNames := BuildOpenAIPrompt(messagesRaw, toolsRaw, traceID, toolPolicy, thinkingEnabled)
+	toolNames = ensureToolDetectionEnabled(toolNames, toolsRaw)
 	if !toolPolicy.IsNone() {
 		toolPolicy.Allowed = namesToSet(toolNames)
 	}
 	passThrough := collectOpenAIChatPassThrough(req)
 	refFileIDs := CollectOpenAIRefFileIDs(req)
+	stops, err := ParseStopSequences(req["stop"], "stop")
+	if err != nil {
+		return StandardRequest{}, err
+	}
+	maxTokens, err := ParseMaxOutputTokens(req, "max_output_tokens", "max_tokens")
+	if err != nil {
+		return StandardRequest{}, err
+	}
 
 	return StandardRequest{
 		Surface:         "openai_responses",
@@ -96,19 +129,32 @@ func NormalizeOpenAIResponsesRequest(store ConfigReader, req map[string]any, tra
 		ResponseModel:   model,
 		Messages:        messagesRaw,
 		PromptTokenText: finalPrompt,
-		ToolsRaw:        req["tools"],
+		ToolsRaw:        toolsRaw,
 		FinalPrompt:     finalPrompt,
 		ToolNames:       toolNames,
 		ToolChoice:      toolPolicy,
 		Stream:          util.ToBool(req["stream"]),
+		Resumable:       resumableStreamRequested(req),
 		Thinking:        thinkingEnabled,
 		Search:          searchEnabled,
 		RefFileIDs:      refFileIDs,
 		RefFileTokens:   estimateInlineFileTokens(req),
+		StopSequences:   stops,
+		MaxOutputTokens: maxTokens,
 		PassThrough:     passThrough,
 	}, nil
 }
 
+// resumableStreamRequested reports the stream_options.resumable opt-in that
+// detaches a stream from its connection so it can be resumed later.
+func resumableStreamRequested(req map[string]any) bool {
+	if !util.ToBool(req["stream"]) {
+		return false
+	}
+	opts, _ := req["stream_options"].(map[string]any)
+	return util.ToBool(opts["resumable"])
+}
+
 func ensureToolDetectionEnabled(toolNames []string, toolsRaw any) []string {
 	if len(toolNames) > 0 {
 		return toolNames
@@ -376,3 +422,10 @@ func estimateInlineFileTokens(req map[string]any) int {
 	}
 	return bytes / 3
 }
+
+// ParseToolChoicePolicy resolves an OpenAI-shaped tool_choice against the
+// declared tools. Other adapters translate their native setting into this
+// shape so every surface validates forced tools the same way.
+func ParseToolChoicePolicy(toolChoiceRaw any, toolsRaw any) (ToolChoicePolicy, error) {
+	return parseToolChoicePolicy(toolChoiceRaw, toolsRaw)
+}
@@ -0,0 +1,52 @@
+package promptcompat
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	ResponseFormatJSONObject = "json_object"
+	ResponseFormatJSONSchema = "json_schema"
+	ResponseFormatEnum       = "enum"
+)
+
+// ResponseFormat asks the model for a machine-readable body instead of free
+// text. DeepSeek has no native structured output, so the request is turned
+// into a system instruction and the reply is cleaned on the way out.
+type ResponseFormat struct {
+	Type   string
+	Schema any
+	Enum   []string
+}
+
+func (f ResponseFormat) Enabled() bool {
+	return f.Type != ""
+}
+
+// Instruction returns the system prompt text enforcing the format.
+func (f ResponseFormat) Instruction() string {
+	switch f.Type {
+	case ResponseFormatJSONObject:
+		return "Respond with a single valid JSON value only. Do not wrap it in markdown code fences and do not add any text before or after it."
+	case ResponseFormatJSONSchema:
+		schema, _ := json.Marshal(f.Schema)
+		return fmt.Sprintf("Respond with a single valid JSON value only, conforming to this JSON schema:\n%s\nDo not wrap it in markdown code fences and do not add any text before or after it.", schema)
+	case ResponseFormatEnum:
+		return "Respond with exactly one of the following values and nothing else: " + strings.Join(f.Enum, ", ")
+	default:
+		return ""
+	}
+}
+
+// InjectResponseFormat prepends the format instruction as a system message.
+func InjectResponseFormat(messagesRaw []any, f ResponseFormat) []any {
+	instruction := f.Instruction()
+	if instruction == "" {
+		return messagesRaw
+	}
+	out := make([]any, 0, len(messagesRaw)+1)
+	out = append(out, map[string]any{"role": "system", "content": instruction})
+	return append(out, messagesRaw...)
+}
@@ -15,7 +15,12 @@ type StandardRequest struct {
 	FinalPrompt             string
 	ToolNames               []string
 	ToolChoice              ToolChoicePolicy
+	ResponseFormat          ResponseFormat
+	StopSequences           []string
+	MaxOutputTokens         int
+	Choices                 int
 	Stream                  bool
+	Resumable               bool
 	Thinking                bool
 	Search                  bool
 	RefFileIDs              []string
@@ -23,6 +28,15 @@ type StandardRequest struct {
 	PassThrough             map[string]any
 }
 
+// ChoiceCount returns how many independent generations the request asks
+// for; unset means one.
+func (r StandardRequest) ChoiceCount() int {
+	if r.Choices < 1 {
+		return 1
+	}
+	return r.Choices
+}
+
 type ToolChoiceMode string
 
 const (
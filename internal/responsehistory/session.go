@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"ds2api/internal/accesslog"
 	"ds2api/internal/assistantturn"
 	"ds2api/internal/auth"
 	"ds2api/internal/chathistory"
@@ -14,7 +15,11 @@ import (
 	"ds2api/internal/promptcompat"
 )
 
+// Session records a request's outcome in chat history. It is also where every
+// surface reports its final finish reason and usage, so the outcome is copied
+// to the access log entry even when history capture is off.
 type Session struct {
+	access      *accesslog.Entry
 	store       *chathistory.Store
 	entryID     string
 	startedAt   time.Time
@@ -32,11 +37,12 @@ type StartParams struct {
 }
 
 func Start(params StartParams) *Session {
-	if params.Store == nil || params.Request == nil || params.Auth == nil {
+	if params.Request == nil || params.Auth == nil {
 		return nil
 	}
-	if !params.Store.Enabled() || !shouldCapture(params.Request) {
-		return nil
+	access := accesslog.FromContext(params.Request.Context())
+	if params.Store == nil || !params.Store.Enabled() || !shouldCapture(params.Request) {
+		return accessOnly(access)
 	}
 	startParams := chathistory.StartParams{
 		CallerID:    strings.TrimSpace(params.Auth.CallerID),
@@ -51,6 +57,7 @@ func Start(params StartParams) *Session {
 	}
 	entry, err := params.Store.Start(startParams)
 	session := &Session{
+		access:      access,
 		store:       params.Store,
 		entryID:     entry.ID,
 		startedAt:   time.Now(),
@@ -60,13 +67,20 @@ func Start(params StartParams) *Session {
 	if err != nil {
 		if entry.ID == "" {
 			config.Logger.Warn("[response_history] start failed", "surface", startParams.Surface, "error", err)
-			return nil
+			return accessOnly(access)
 		}
 		config.Logger.Warn("[response_history] start persisted in memory after write failure", "surface", startParams.Surface, "error", err)
 	}
 	return session
 }
 
+func accessOnly(access *accesslog.Entry) *Session {
+	if access == nil {
+		return nil
+	}
+	return &Session{access: access}
+}
+
 func shouldCapture(r *http.Request) bool {
 	if r == nil || r.URL == nil {
 		return false
@@ -136,7 +150,11 @@ func (s *Session) Progress(thinking, content string) {
 }
 
 func (s *Session) Success(statusCode int, thinking, content, finishReason string, usage map[string]any) {
-	if s == nil || s.store == nil || s.disabled {
+	if s == nil {
+		return
+	}
+	s.access.Finish(finishReason, usage)
+	if s.store == nil || s.disabled {
 		return
 	}
 	s.persistUpdate(chathistory.UpdateParams{
@@ -152,7 +170,11 @@ func (s *Session) Success(statusCode int, thinking, content, finishReason string
 }
 
 func (s *Session) Error(statusCode int, message, finishReason, thinking, content string) {
-	if s == nil || s.store == nil || s.disabled {
+	if s == nil {
+		return
+	}
+	s.access.Finish(finishReason, nil)
+	if s.store == nil || s.disabled {
 		return
 	}
 	s.persistUpdate(chathistory.UpdateParams{
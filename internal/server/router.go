@@ -14,11 +14,14 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 
+	"ds2api/internal/accesslog"
 	"ds2api/internal/account"
 	"ds2api/internal/auth"
 	"ds2api/internal/chathistory"
 	"ds2api/internal/config"
 	dsclient "ds2api/internal/deepseek/client"
+	dsprotocol "ds2api/internal/deepseek/protocol"
+	"ds2api/internal/drain"
 	"ds2api/internal/httpapi/admin"
 	"ds2api/internal/httpapi/claude"
 	"ds2api/internal/httpapi/gemini"
@@ -29,6 +32,9 @@ import (
 	"ds2api/internal/httpapi/openai/responses"
 	"ds2api/internal/httpapi/openai/shared"
 	"ds2api/internal/httpapi/requestbody"
+	"ds2api/internal/inflight"
+	"ds2api/internal/sessioncleanup"
+	"ds2api/internal/tracing"
 	"ds2api/internal/webui"
 )
 
@@ -37,10 +43,14 @@ type App struct {
 	Pool     *account.Pool
 	Resolver *auth.Resolver
 	DS       *dsclient.Client
+	Drain    *drain.Controller
 	Router   http.Handler
 }
 
 func NewApp() (*App, error) {
+	if err := dsprotocol.ValidateBaseURL(); err != nil {
+		return nil, err
+	}
 	store, err := config.LoadStoreWithError()
 	if err != nil {
 		return nil, fmt.Errorf("load config: %w", err)
@@ -51,6 +61,9 @@ func NewApp() (*App, error) {
 		return dsClient.Login(ctx, acc)
 	})
 	dsClient = dsclient.NewClient(store, resolver)
+	if tracing.Init(tracing.ConfigFromEnv()) != nil {
+		config.Logger.Info("[tracing] exporting spans over OTLP/HTTP")
+	}
 	if err := dsClient.PreloadPow(context.Background()); err != nil {
 		config.Logger.Warn("[PoW] init failed", "error", err)
 	} else {
@@ -59,6 +72,8 @@ func NewApp() (*App, error) {
 	chatHistoryStore := chathistory.New(config.ChatHistoryPath())
 	if err := chatHistoryStore.Err(); err != nil {
 		config.Logger.Warn("[chat_history] unavailable", "path", chatHistoryStore.Path(), "error", err)
+	} else {
+		chatHistoryStore.StartCompactor(context.Background(), chathistory.DefaultCompactInterval)
 	}
 
 	modelsHandler := &shared.ModelsHandler{Store: store}
@@ -68,16 +83,23 @@ func NewApp() (*App, error) {
 	embeddingsHandler := &embeddings.Handler{Store: store, Auth: resolver, DS: dsClient, ChatHistory: chatHistoryStore}
 	claudeHandler := &claude.Handler{Store: store, Auth: resolver, DS: dsClient, OpenAI: chatHandler, ChatHistory: chatHistoryStore}
 	geminiHandler := &gemini.Handler{Store: store, Auth: resolver, DS: dsClient, OpenAI: chatHandler, ChatHistory: chatHistoryStore}
-	adminHandler := &admin.Handler{Store: store, Pool: pool, DS: dsClient, OpenAI: chatHandler, ChatHistory: chatHistoryStore}
+	sessionCleanup := sessioncleanup.New(store, dsClient, resolver)
+	sessionCleanup.Start(context.Background())
+	requests := inflight.NewRegistry()
+	drainer := drain.New(pool, requests, drain.TimeoutFromEnv())
+	adminHandler := &admin.Handler{Store: store, Pool: pool, DS: dsClient, OpenAI: chatHandler, ChatHistory: chatHistoryStore, SessionCleanup: sessionCleanup, Drain: drainer, Requests: requests}
 	ollamaHandler := &ollama.Handler{Store: store}
 	webuiHandler := webui.NewHandler()
 
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
+	r.Use(tracing.Middleware)
 	r.Use(filteredLogger())
 	r.Use(middleware.Recoverer)
 	r.Use(cors)
+	r.Use(drainer.Middleware)
+	r.Use(requests.Middleware)
 	r.Use(requestbody.ValidateJSONUTF8)
 	r.Use(timeout(0))
 
@@ -87,9 +109,17 @@ func NewApp() (*App, error) {
 		_, _ = w.Write([]byte(`{"status":"ok"}`))
 	}
 	readyzHandler := func(w http.ResponseWriter, _ *http.Request) {
+		checks := readinessChecks(store, drainer)
+		status, code := "ready", http.StatusOK
+		for _, ok := range checks {
+			if !ok {
+				status, code = "not_ready", http.StatusServiceUnavailable
+				break
+			}
+		}
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte(`{"status":"ready"}`))
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(map[string]any{"status": status, "checks": checks})
 	}
 	r.Get("/healthz", healthzHandler)
 	r.Head("/healthz", healthzHandler)
@@ -98,8 +128,11 @@ func NewApp() (*App, error) {
 	r.Get("/v1/models", modelsHandler.ListModels)
 	r.Get("/v1/models/{model_id}", modelsHandler.GetModel)
 	r.Post("/v1/chat/completions", chatHandler.ChatCompletions)
+	r.Post("/v1/completions", chatHandler.Completions)
 	r.Post("/v1/responses", responsesHandler.Responses)
+	r.Post("/v1/responses/input_tokens", responsesHandler.InputTokens)
 	r.Get("/v1/responses/{response_id}", responsesHandler.GetResponseByID)
+	r.Post("/v1/responses/{response_id}/cancel", responsesHandler.CancelResponse)
 	r.Post("/v1/files", filesHandler.UploadFile)
 	r.Get("/v1/files/{file_id}", filesHandler.RetrieveFile)
 	r.Post("/v1/embeddings", embeddingsHandler.Embeddings)
@@ -107,8 +140,11 @@ func NewApp() (*App, error) {
 	r.Get("/models", modelsHandler.ListModels)
 	r.Get("/models/{model_id}", modelsHandler.GetModel)
 	r.Post("/chat/completions", chatHandler.ChatCompletions)
+	r.Post("/completions", chatHandler.Completions)
 	r.Post("/responses", responsesHandler.Responses)
+	r.Post("/responses/input_tokens", responsesHandler.InputTokens)
 	r.Get("/responses/{response_id}", responsesHandler.GetResponseByID)
+	r.Post("/responses/{response_id}/cancel", responsesHandler.CancelResponse)
 	r.Post("/files", filesHandler.UploadFile)
 	r.Get("/files/{file_id}", filesHandler.RetrieveFile)
 	r.Post("/embeddings", embeddingsHandler.Embeddings)
@@ -126,7 +162,27 @@ func NewApp() (*App, error) {
 		http.NotFound(w, req)
 	})
 
-	return &App{Store: store, Pool: pool, Resolver: resolver, DS: dsClient, Router: r}, nil
+	return &App{Store: store, Pool: pool, Resolver: resolver, DS: dsClient, Drain: drainer, Router: r}, nil
+}
+
+// readinessChecks reports whether config is loaded, at least one managed
+// account is not marked failed by the last account test, and the instance is
+// not draining.
+func readinessChecks(store *config.Store, drainer *drain.Controller) map[string]bool {
+	healthy := false
+	if store != nil {
+		for _, acc := range store.Accounts() {
+			if status, _ := store.AccountTestStatus(acc.Identifier()); status != "failed" {
+				healthy = true
+				break
+			}
+		}
+	}
+	return map[string]bool{
+		"config":       store != nil,
+		"accounts":     healthy,
+		"not_draining": !drainer.Draining(),
+	}
 }
 
 func timeout(d time.Duration) func(http.Handler) http.Handler {
@@ -137,6 +193,11 @@ func timeout(d time.Duration) func(http.Handler) http.Handler {
 }
 
 func filteredLogger() func(http.Handler) http.Handler {
+	if config.LogFormatJSON() {
+		opts := accesslog.OptionsFromEnv()
+		opts.Skip = skipAccessLog
+		return accesslog.Middleware(opts)
+	}
 	color := !isWindowsRuntime()
 	base := &middleware.DefaultLogFormatter{
 		Logger:  log.New(os.Stdout, "", log.LstdFlags),
@@ -154,15 +215,21 @@ type filteredLogFormatter struct {
 }
 
 func (f *filteredLogFormatter) NewLogEntry(r *http.Request) middleware.LogEntry {
-	if r != nil && r.Method == http.MethodGet {
-		path := strings.TrimSpace(r.URL.Path)
-		if path == "/admin/chat-history" || strings.HasPrefix(path, "/admin/chat-history/") {
-			return noopLogEntry{}
-		}
+	if skipAccessLog(r) {
+		return noopLogEntry{}
 	}
 	return f.base.NewLogEntry(r)
 }
 
+// skipAccessLog drops the admin panel's chat history polling from access logs.
+func skipAccessLog(r *http.Request) bool {
+	if r == nil || r.Method != http.MethodGet {
+		return false
+	}
+	path := strings.TrimSpace(r.URL.Path)
+	return path == "/admin/chat-history" || strings.HasPrefix(path, "/admin/chat-history/")
+}
+
 type noopLogEntry struct{}
 
 func (noopLogEntry) Write(_ int, _ int, _ http.Header, _ time.Duration, _ interface{}) {}
@@ -175,10 +242,13 @@ var defaultCORSAllowHeaders = []string{
 	"X-API-Key",
 	"X-Ds2-Target-Account",
 	"X-Ds2-Source",
+	"X-Ds2-Priority",
 	"X-Vercel-Protection-Bypass",
 	"X-Goog-Api-Key",
 	"Anthropic-Version",
 	"Anthropic-Beta",
+	"Traceparent",
+	"Tracestate",
 }
 
 var blockedCORSRequestHeaders = map[string]struct{}{
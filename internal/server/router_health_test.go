@@ -1,6 +1,7 @@
 package server
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -24,3 +25,43 @@ func TestHealthEndpointsSupportHEAD(t *testing.T) {
 		}
 	}
 }
+
+func TestReadyzReflectsAccountsAndDrain(t *testing.T) {
+	t.Setenv("DS2API_CONFIG_JSON", `{"keys":["k1"],"accounts":[{"email":"u@example.com","password":"p"}]}`)
+	t.Setenv("DS2API_ENV_WRITEBACK", "0")
+
+	app, err := NewApp()
+	if err != nil {
+		t.Fatalf("NewApp() error: %v", err)
+	}
+	readyz := func() (int, map[string]any) {
+		rec := httptest.NewRecorder()
+		app.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var body map[string]any
+		_ = json.Unmarshal(rec.Body.Bytes(), &body)
+		return rec.Code, body
+	}
+
+	if code, body := readyz(); code != http.StatusOK || body["status"] != "ready" {
+		t.Fatalf("expected ready, got %d %v", code, body)
+	}
+	if err := app.Store.UpdateAccountTestStatus("u@example.com", "failed"); err != nil {
+		t.Fatalf("update test status: %v", err)
+	}
+	if code, body := readyz(); code != http.StatusServiceUnavailable || body["checks"].(map[string]any)["accounts"] != false {
+		t.Fatalf("expected not ready without a healthy account, got %d %v", code, body)
+	}
+	if err := app.Store.UpdateAccountTestStatus("u@example.com", "ok"); err != nil {
+		t.Fatalf("update test status: %v", err)
+	}
+
+	app.Drain.Start("test")
+	if code, body := readyz(); code != http.StatusServiceUnavailable || body["checks"].(map[string]any)["not_draining"] != false {
+		t.Fatalf("expected not ready while draining, got %d %v", code, body)
+	}
+	rec := httptest.NewRecorder()
+	app.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected completions to be refused while draining, got %d", rec.Code)
+	}
+}
@@ -33,16 +33,22 @@ func TestAPIRoutesRemainRegistered(t *testing.T) {
 		"GET /v1/models",
 		"GET /v1/models/{model_id}",
 		"POST /v1/chat/completions",
+		"POST /v1/completions",
 		"POST /v1/responses",
+		"POST /v1/responses/input_tokens",
 		"GET /v1/responses/{response_id}",
+		"POST /v1/responses/{response_id}/cancel",
 		"POST /v1/files",
 		"GET /v1/files/{file_id}",
 		"POST /v1/embeddings",
 		"GET /models",
 		"GET /models/{model_id}",
 		"POST /chat/completions",
+		"POST /completions",
 		"POST /responses",
+		"POST /responses/input_tokens",
 		"GET /responses/{response_id}",
+		"POST /responses/{response_id}/cancel",
 		"POST /files",
 		"GET /files/{file_id}",
 		"POST /embeddings",
@@ -59,6 +65,9 @@ func TestAPIRoutesRemainRegistered(t *testing.T) {
 		"POST /v1/models/{model}:streamGenerateContent",
 		"POST /admin/login",
 		"GET /admin/verify",
+		"GET /admin/oidc/config",
+		"GET /admin/oidc/login",
+		"GET /admin/oidc/callback",
 		"GET /admin/config",
 		"POST /admin/config",
 		"GET /admin/settings",
@@ -83,11 +92,20 @@ func TestAPIRoutesRemainRegistered(t *testing.T) {
 		"POST /admin/accounts/test",
 		"POST /admin/accounts/test-all",
 		"POST /admin/accounts/sessions/delete-all",
+		"GET /admin/accounts/sessions/cleanup",
+		"POST /admin/accounts/sessions/cleanup/run",
 		"POST /admin/import",
 		"POST /admin/test",
 		"POST /admin/dev/raw-samples/capture",
 		"GET /admin/dev/raw-samples/query",
 		"POST /admin/dev/raw-samples/save",
+		"GET /admin/pow/status",
+		"POST /admin/pow/benchmark",
+		"GET /admin/drain",
+		"POST /admin/drain",
+		"GET /admin/requests",
+		"GET /admin/requests/stream",
+		"DELETE /admin/requests/{request_id}",
 		"POST /admin/vercel/sync",
 		"GET /admin/vercel/status",
 		"POST /admin/vercel/status",
@@ -95,11 +113,23 @@ func TestAPIRoutesRemainRegistered(t *testing.T) {
 		"GET /admin/dev/captures",
 		"DELETE /admin/dev/captures",
 		"GET /admin/chat-history",
+		"GET /admin/chat-history/search",
+		"POST /admin/chat-history/export",
 		"GET /admin/chat-history/{id}",
 		"DELETE /admin/chat-history",
 		"DELETE /admin/chat-history/{id}",
+		"POST /admin/chat-history/{id}/replay",
 		"PUT /admin/chat-history/settings",
+		"POST /admin/chat-history/compact",
 		"GET /admin/version",
+		"GET /admin/me",
+		"GET /admin/users",
+		"POST /admin/users",
+		"PUT /admin/users/{username}",
+		"DELETE /admin/users/{username}",
+		"GET /admin/tokens",
+		"POST /admin/tokens",
+		"DELETE /admin/tokens/{id}",
 	} {
 		if !got[want] {
 			t.Fatalf("expected route %s to be registered", want)
@@ -0,0 +1,301 @@
+// Package sessioncleanup periodically deletes old DeepSeek chat sessions on
+// managed accounts, keeping sessions that requests are still using.
+package sessioncleanup
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"ds2api/internal/auth"
+	"ds2api/internal/config"
+	dsclient "ds2api/internal/deepseek/client"
+)
+
+// historySize is how many past runs Status reports.
+const historySize = 10
+
+var (
+	ErrRunning     = errors.New("session cleanup is already running")
+	ErrNoCriteria  = errors.New("session_cleanup needs max_age_hours or keep_newest")
+	errLoginFailed = errors.New("login failed")
+)
+
+// DS is the subset of the DeepSeek client the runner needs.
+type DS interface {
+	FetchSessionPage(ctx context.Context, a *auth.RequestAuth, cursor string) ([]dsclient.SessionInfo, bool, error)
+	DeleteSessionForToken(ctx context.Context, token string, sessionID string) (*dsclient.DeleteSessionResult, error)
+	SessionInUse(sessionID string) bool
+}
+
+// Auth refreshes managed account tokens the same way the request path does:
+// it logs in again and persists the token with Store.UpdateAccountToken.
+type Auth interface {
+	RefreshToken(ctx context.Context, a *auth.RequestAuth) bool
+}
+
+// Store is the subset of the config store the runner reads on every run.
+type Store interface {
+	Accounts() []config.Account
+	SessionCleanupEnabled() bool
+	SessionCleanupInterval() time.Duration
+	SessionCleanupMaxAge() time.Duration
+	SessionCleanupKeepNewest() int
+	SessionCleanupDeletesPerSecond() int
+	SessionCleanupMaxPages() int
+}
+
+// RunResult summarizes one cleanup pass over all accounts.
+type RunResult struct {
+	Trigger    string          `json:"trigger"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt time.Time       `json:"finished_at"`
+	Scanned    int             `json:"scanned"`
+	Deleted    int             `json:"deleted"`
+	Skipped    int             `json:"skipped"`
+	Errors     int             `json:"errors"`
+	Accounts   []AccountResult `json:"accounts"`
+}
+
+// AccountResult is the per-account part of a RunResult.
+type AccountResult struct {
+	Account       string `json:"account"`
+	Scanned       int    `json:"scanned"`
+	Kept          int    `json:"kept"`
+	Deleted       int    `json:"deleted"`
+	SkippedInUse  int    `json:"skipped_in_use"`
+	SkippedPinned int    `json:"skipped_pinned"`
+	Failed        int    `json:"failed"`
+	Error         string `json:"error,omitempty"`
+}
+
+type Runner struct {
+	store Store
+	ds    DS
+	auth  Auth
+	now   func() time.Time
+
+	mu      sync.Mutex
+	running bool
+	nextRun time.Time
+	history []RunResult
+}
+
+func New(store Store, ds DS, authn Auth) *Runner {
+	return &Runner{store: store, ds: ds, auth: authn, now: time.Now}
+}
+
+// Start runs scheduled cleanups until ctx is cancelled. Enabled state and
+// interval are re-read after every tick, so config changes apply without a
+// restart.
+func (r *Runner) Start(ctx context.Context) {
+	if r == nil {
+		return
+	}
+	go func() {
+		for {
+			interval := r.store.SessionCleanupInterval()
+			r.mu.Lock()
+			r.nextRun = r.now().Add(interval)
+			r.mu.Unlock()
+			timer := time.NewTimer(interval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			if !r.store.SessionCleanupEnabled() {
+				continue
+			}
+			result, err := r.RunOnce(ctx, "schedule")
+			if err != nil {
+				config.Logger.Warn("[session_cleanup] run skipped", "error", err)
+				continue
+			}
+			config.Logger.Info("[session_cleanup] finished", "scanned", result.Scanned, "deleted", result.Deleted, "skipped", result.Skipped, "errors", result.Errors)
+		}
+	}()
+}
+
+// Trigger starts a manual run in the background. It returns ErrRunning when a
+// run is already in progress.
+func (r *Runner) Trigger() error {
+	if err := r.begin(); err != nil {
+		return err
+	}
+	go func() {
+		result := r.run(context.Background(), "manual")
+		config.Logger.Info("[session_cleanup] manual run finished", "scanned", result.Scanned, "deleted", result.Deleted, "skipped", result.Skipped, "errors", result.Errors)
+	}()
+	return nil
+}
+
+// RunOnce cleans up every managed account and records the result.
+func (r *Runner) RunOnce(ctx context.Context, trigger string) (RunResult, error) {
+	if err := r.begin(); err != nil {
+		return RunResult{}, err
+	}
+	return r.run(ctx, trigger), nil
+}
+
+func (r *Runner) begin() error {
+	if r.store.SessionCleanupMaxAge() == 0 && r.store.SessionCleanupKeepNewest() == 0 {
+		return ErrNoCriteria
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running {
+		return ErrRunning
+	}
+	r.running = true
+	return nil
+}
+
+func (r *Runner) run(ctx context.Context, trigger string) RunResult {
+	result := RunResult{Trigger: trigger, StartedAt: r.now(), Accounts: []AccountResult{}}
+	for _, acc := range r.store.Accounts() {
+		if ctx.Err() != nil {
+			break
+		}
+		ar := r.cleanAccount(ctx, acc)
+		result.Scanned += ar.Scanned
+		result.Deleted += ar.Deleted
+		result.Skipped += ar.SkippedInUse + ar.SkippedPinned
+		result.Errors += ar.Failed
+		if ar.Error != "" {
+			result.Errors++
+		}
+		result.Accounts = append(result.Accounts, ar)
+	}
+	result.FinishedAt = r.now()
+
+	r.mu.Lock()
+	r.running = false
+	r.history = append(r.history, result)
+	if len(r.history) > historySize {
+		r.history = r.history[len(r.history)-historySize:]
+	}
+	r.mu.Unlock()
+	return result
+}
+
+func (r *Runner) cleanAccount(ctx context.Context, acc config.Account) AccountResult {
+	ar := AccountResult{Account: acc.Identifier()}
+	a := &auth.RequestAuth{UseConfigToken: true, AccountID: acc.Identifier(), Account: acc, DeepSeekToken: acc.Token}
+	ctx = auth.WithAuth(ctx, a)
+
+	var sessions []dsclient.SessionInfo
+	var err error
+	if a.DeepSeekToken == "" {
+		err = r.refreshToken(ctx, a)
+	}
+	if err == nil {
+		sessions, err = r.listSessions(ctx, a)
+	}
+	if errors.Is(err, dsclient.ErrTokenInvalid) && acc.Token != "" {
+		// The stored token has expired; retry once with a fresh login.
+		if err = r.refreshToken(ctx, a); err == nil {
+			sessions, err = r.listSessions(ctx, a)
+		}
+	}
+	if err != nil {
+		ar.Error = err.Error()
+		return ar
+	}
+	ar.Scanned = len(sessions)
+
+	picked := selectForDeletion(sessions, r.now(), r.store.SessionCleanupMaxAge(), r.store.SessionCleanupKeepNewest(), r.ds.SessionInUse)
+	ar.Kept, ar.SkippedInUse, ar.SkippedPinned = picked.Kept, picked.SkippedInUse, picked.SkippedPinned
+
+	gap := time.Second / time.Duration(r.store.SessionCleanupDeletesPerSecond())
+	for i, id := range picked.Delete {
+		if i > 0 && !sleep(ctx, gap) {
+			break
+		}
+		// A request may have picked the session up since it was listed.
+		if r.ds.SessionInUse(id) {
+			ar.SkippedInUse++
+			continue
+		}
+		if _, err := r.ds.DeleteSessionForToken(ctx, a.DeepSeekToken, id); err != nil {
+			ar.Failed++
+			config.Logger.Debug("[session_cleanup] delete failed", "account", ar.Account, "session", id, "error", err)
+			continue
+		}
+		ar.Deleted++
+	}
+	return ar
+}
+
+func (r *Runner) refreshToken(ctx context.Context, a *auth.RequestAuth) error {
+	if r.auth == nil || !r.auth.RefreshToken(ctx, a) {
+		return errLoginFailed
+	}
+	return nil
+}
+
+// listSessions pages through the account's sessions. Paging stops at
+// max_pages or once a page adds nothing new.
+func (r *Runner) listSessions(ctx context.Context, a *auth.RequestAuth) ([]dsclient.SessionInfo, error) {
+	seen := map[string]struct{}{}
+	var out []dsclient.SessionInfo
+	cursor := ""
+	for page := 0; page < r.store.SessionCleanupMaxPages(); page++ {
+		items, hasMore, err := r.ds.FetchSessionPage(ctx, a, cursor)
+		if err != nil {
+			return nil, err
+		}
+		added := 0
+		for _, s := range items {
+			if _, ok := seen[s.ID]; ok || s.ID == "" {
+				continue
+			}
+			seen[s.ID] = struct{}{}
+			out = append(out, s)
+			added++
+		}
+		if !hasMore || added == 0 || items[len(items)-1].UpdatedAt <= 0 {
+			break
+		}
+		cursor = strconv.FormatFloat(items[len(items)-1].UpdatedAt, 'f', -1, 64)
+	}
+	return out, nil
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// Status reports the schedule and the most recent runs, newest first.
+func (r *Runner) Status() map[string]any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	runs := make([]RunResult, 0, len(r.history))
+	for i := len(r.history) - 1; i >= 0; i-- {
+		runs = append(runs, r.history[i])
+	}
+	status := map[string]any{
+		"enabled":            r.store.SessionCleanupEnabled(),
+		"running":            r.running,
+		"interval_seconds":   int(r.store.SessionCleanupInterval() / time.Second),
+		"max_age_hours":      int(r.store.SessionCleanupMaxAge() / time.Hour),
+		"keep_newest":        r.store.SessionCleanupKeepNewest(),
+		"deletes_per_second": r.store.SessionCleanupDeletesPerSecond(),
+		"max_pages":          r.store.SessionCleanupMaxPages(),
+		"runs":               runs,
+	}
+	if !r.nextRun.IsZero() && r.store.SessionCleanupEnabled() {
+		status["next_run_at"] = r.nextRun
+	}
+	return status
+}
@@ -0,0 +1,166 @@
+package sessioncleanup
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"ds2api/internal/account"
+	"ds2api/internal/auth"
+	"ds2api/internal/config"
+	dsclient "ds2api/internal/deepseek/client"
+	"ds2api/internal/deepseek/mockupstream"
+	dsprotocol "ds2api/internal/deepseek/protocol"
+)
+
+func newMockRunner(t *testing.T, cleanupJSON string) (*Runner, *dsclient.Client, *mockupstream.Server) {
+	t.Helper()
+	return newMockRunnerWithToken(t, cleanupJSON, "")
+}
+
+func newMockRunnerWithToken(t *testing.T, cleanupJSON, token string) (*Runner, *dsclient.Client, *mockupstream.Server) {
+	t.Helper()
+	mock, err := mockupstream.New(mockupstream.Options{})
+	if err != nil {
+		t.Fatalf("new mock: %v", err)
+	}
+	srv := httptest.NewServer(mock)
+	t.Cleanup(srv.Close)
+	t.Setenv(dsprotocol.UpstreamBaseURLEnv, srv.URL)
+	t.Setenv("DS2API_CONFIG_JSON", `{"keys":["k1"],"accounts":[{"email":"u@example.com","password":"p"}],"session_cleanup":`+cleanupJSON+`}`)
+	store := config.LoadStore()
+	if token != "" {
+		if err := store.UpdateAccountToken("u@example.com", token); err != nil {
+			t.Fatalf("seed token: %v", err)
+		}
+	}
+	var client *dsclient.Client
+	resolver := auth.NewResolver(store, account.NewPool(store), func(ctx context.Context, acc config.Account) (string, error) {
+		return client.Login(ctx, acc)
+	})
+	client = dsclient.NewClient(store, resolver)
+	return New(store, client, resolver), client, mock
+}
+
+func TestRunnerPagesAndDeletesOldSessions(t *testing.T) {
+	runner, _, mock := newMockRunner(t, `{"enabled":true,"max_age_hours":24,"deletes_per_second":20}`)
+	now := time.Now()
+	var recent, old []string
+	for i := 0; i < 25; i++ {
+		recent = append(recent, mock.AddSession(now.Add(-time.Duration(i)*time.Minute)))
+	}
+	for i := 0; i < 5; i++ {
+		old = append(old, mock.AddSession(now.Add(-48*time.Hour-time.Duration(i)*time.Minute)))
+	}
+
+	result, err := runner.RunOnce(context.Background(), "manual")
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if result.Scanned != 30 || result.Deleted != 5 || result.Errors != 0 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	remaining := mock.SessionIDs()
+	for _, id := range old {
+		if slices.Contains(remaining, id) {
+			t.Fatalf("old session %s survived: %v", id, remaining)
+		}
+	}
+	if len(remaining) != len(recent) {
+		t.Fatalf("expected %d sessions left, got %v", len(recent), remaining)
+	}
+	if runs := runner.Status()["runs"].([]RunResult); len(runs) != 1 || runs[0].Trigger != "manual" {
+		t.Fatalf("unexpected run history: %+v", runs)
+	}
+}
+
+func TestRunnerSkipsSessionsInUse(t *testing.T) {
+	runner, client, mock := newMockRunner(t, `{"enabled":true,"keep_newest":1,"deletes_per_second":20}`)
+	ctx := context.Background()
+	acc := config.Account{Email: "u@example.com", Password: "p"}
+	token, err := client.Login(ctx, acc)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	old := mock.AddSession(time.Now().Add(-time.Hour))
+	mock.AddSession(time.Now().Add(-2 * time.Hour))
+	busy, err := client.CreateSession(ctx, &auth.RequestAuth{DeepSeekToken: token, AccountID: acc.Email, Account: acc}, 1)
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	result, err := runner.RunOnce(ctx, "manual")
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	acct := result.Accounts[0]
+	if acct.Kept != 1 || acct.SkippedInUse != 0 || acct.Deleted != 2 {
+		t.Fatalf("unexpected account result: %+v", acct)
+	}
+	if remaining := mock.SessionIDs(); !slices.Equal(remaining, []string{busy}) || slices.Contains(remaining, old) {
+		t.Fatalf("expected only the in-use session to remain, got %v", remaining)
+	}
+
+	mock.AddSession(time.Now())
+	result, err = runner.RunOnce(ctx, "manual")
+	if err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	if acct := result.Accounts[0]; acct.SkippedInUse != 1 || acct.Deleted != 0 {
+		t.Fatalf("expected the in-use session to be skipped, got %+v", acct)
+	}
+}
+
+func TestRunnerRequiresCriteria(t *testing.T) {
+	runner, _, _ := newMockRunner(t, `{}`)
+	if _, err := runner.RunOnce(context.Background(), "manual"); !errors.Is(err, ErrNoCriteria) {
+		t.Fatalf("expected ErrNoCriteria, got %v", err)
+	}
+}
+
+func TestRunnerRefreshesExpiredStoredToken(t *testing.T) {
+	runner, _, mock := newMockRunnerWithToken(t, `{"enabled":true,"keep_newest":1,"deletes_per_second":20}`, "expired-token")
+	mock.AddSession(time.Now())
+	mock.AddSession(time.Now().Add(-time.Hour))
+
+	result, err := runner.RunOnce(context.Background(), "manual")
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if acct := result.Accounts[0]; acct.Error != "" || acct.Deleted != 1 {
+		t.Fatalf("unexpected account result: %+v", acct)
+	}
+	if token := runner.store.Accounts()[0].Token; token == "" || token == "expired-token" {
+		t.Fatalf("expected refreshed token to be persisted, got %q", token)
+	}
+}
+
+type failingDS struct{ DS }
+
+func (failingDS) FetchSessionPage(context.Context, *auth.RequestAuth, string) ([]dsclient.SessionInfo, bool, error) {
+	return nil, false, errors.New("request failed: status=502")
+}
+
+type countingAuth struct{ calls int }
+
+func (c *countingAuth) RefreshToken(context.Context, *auth.RequestAuth) bool {
+	c.calls++
+	return true
+}
+
+func TestRunnerDoesNotReloginOnOtherErrors(t *testing.T) {
+	runner, _, _ := newMockRunnerWithToken(t, `{"enabled":true,"keep_newest":1}`, "stored-token")
+	authn := &countingAuth{}
+	runner.ds, runner.auth = failingDS{}, authn
+
+	result, err := runner.RunOnce(context.Background(), "manual")
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if result.Accounts[0].Error == "" || authn.calls != 0 {
+		t.Fatalf("expected a failed account without relogin, got %+v after %d refreshes", result.Accounts[0], authn.calls)
+	}
+}
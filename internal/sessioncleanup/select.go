@@ -0,0 +1,62 @@
+package sessioncleanup
+
+import (
+	"sort"
+	"time"
+
+	dsclient "ds2api/internal/deepseek/client"
+)
+
+// selection is the outcome of applying the cleanup policy to one account's
+// session list.
+type selection struct {
+	Delete        []string
+	Kept          int
+	SkippedInUse  int
+	SkippedPinned int
+}
+
+// selectForDeletion picks the sessions to delete: unpinned sessions beyond the
+// keepNewest most recent ones, or last updated more than maxAge ago. A zero
+// keepNewest or maxAge disables that criterion. Pinned sessions never count
+// toward keepNewest and are never deleted; sessions reported by inUse are
+// counted toward keepNewest but skipped.
+func selectForDeletion(sessions []dsclient.SessionInfo, now time.Time, maxAge time.Duration, keepNewest int, inUse func(string) bool) selection {
+	ordered := append([]dsclient.SessionInfo(nil), sessions...)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].UpdatedAt > ordered[j].UpdatedAt })
+
+	var out selection
+	rank := 0
+	for _, s := range ordered {
+		if s.Pinned {
+			out.SkippedPinned++
+			continue
+		}
+		beyondCount := keepNewest > 0 && rank >= keepNewest
+		rank++
+		updatedAt := sessionTime(s.UpdatedAt)
+		tooOld := maxAge > 0 && !updatedAt.IsZero() && now.Sub(updatedAt) > maxAge
+		if !beyondCount && !tooOld {
+			out.Kept++
+			continue
+		}
+		if inUse != nil && inUse(s.ID) {
+			out.SkippedInUse++
+			continue
+		}
+		out.Delete = append(out.Delete, s.ID)
+	}
+	return out
+}
+
+// sessionTime converts upstream updated_at, in seconds with a fractional
+// part, to a time. Millisecond values are accepted as well.
+func sessionTime(v float64) time.Time {
+	if v <= 0 {
+		return time.Time{}
+	}
+	if v >= 1e12 {
+		return time.UnixMilli(int64(v))
+	}
+	return time.UnixMicro(int64(v * 1e6))
+}
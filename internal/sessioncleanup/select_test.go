@@ -0,0 +1,49 @@
+package sessioncleanup
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	dsclient "ds2api/internal/deepseek/client"
+)
+
+func TestSelectForDeletionByCountAndAge(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	at := func(ago time.Duration) float64 { return float64(now.Add(-ago).Unix()) }
+	sessions := []dsclient.SessionInfo{
+		{ID: "old", UpdatedAt: at(72 * time.Hour)},
+		{ID: "new", UpdatedAt: at(time.Minute)},
+		{ID: "pinned", UpdatedAt: at(100 * time.Hour), Pinned: true},
+		{ID: "mid", UpdatedAt: at(2 * time.Hour)},
+		{ID: "busy", UpdatedAt: at(3 * time.Hour)},
+	}
+	inUse := func(id string) bool { return id == "busy" }
+
+	got := selectForDeletion(sessions, now, 0, 2, inUse)
+	if !reflect.DeepEqual(got.Delete, []string{"old"}) || got.SkippedInUse != 1 || got.SkippedPinned != 1 || got.Kept != 2 {
+		t.Fatalf("keep_newest selection: %+v", got)
+	}
+
+	got = selectForDeletion(sessions, now, 24*time.Hour, 0, inUse)
+	if !reflect.DeepEqual(got.Delete, []string{"old"}) || got.Kept != 3 {
+		t.Fatalf("max_age selection: %+v", got)
+	}
+
+	got = selectForDeletion(sessions, now, 90*time.Minute, 0, nil)
+	if !reflect.DeepEqual(got.Delete, []string{"mid", "busy", "old"}) {
+		t.Fatalf("max_age selection without tracker: %+v", got)
+	}
+}
+
+func TestSessionTimeAcceptsSecondsAndMillis(t *testing.T) {
+	if got := sessionTime(1712345678.5); !got.Equal(time.UnixMilli(1712345678500)) {
+		t.Fatalf("seconds: %v", got)
+	}
+	if got := sessionTime(1712345678000); !got.Equal(time.UnixMilli(1712345678000)) {
+		t.Fatalf("millis: %v", got)
+	}
+	if !sessionTime(0).IsZero() {
+		t.Fatal("expected zero time for missing updated_at")
+	}
+}
@@ -1,30 +1,117 @@
 package sse
 
 import (
+	"math"
+	"sort"
 	"strconv"
 	"strings"
 )
 
-type citationLinkCollector struct {
+// SearchResult is one web source as it appears in the upstream search payload.
+type SearchResult struct {
+	URL          string
+	Title        string
+	Snippet      string
+	CiteIndex    int
+	HasCiteIndex bool
+}
+
+// Citation is a resolved search source keyed by the one-based marker number
+// used in [citation:N] markers.
+type Citation struct {
+	Index   int    `json:"index"`
+	URL     string `json:"url"`
+	Title   string `json:"title,omitempty"`
+	Snippet string `json:"snippet,omitempty"`
+}
+
+type citationMeta struct {
+	title   string
+	snippet string
+}
+
+// CitationCollector accumulates search results across a stream and resolves
+// them to marker indices once the stream is complete.
+type CitationCollector struct {
 	ordered     []string
 	explicitRaw map[int]string
 	hasZeroIdx  bool
+	meta        map[string]citationMeta
 }
 
-func newCitationLinkCollector() *citationLinkCollector {
-	return &citationLinkCollector{
+func NewCitationCollector() *CitationCollector {
+	return &CitationCollector{
 		explicitRaw: map[int]string{},
+		meta:        map[string]citationMeta{},
 	}
 }
 
-func (c *citationLinkCollector) ingestChunk(chunk map[string]any) {
+func (c *CitationCollector) ingestChunk(chunk map[string]any) {
 	if c == nil || len(chunk) == 0 {
 		return
 	}
-	c.walkValue(chunk)
+	c.Add(ExtractSearchResults(chunk))
+}
+
+// Add records search results in the order they were received.
+func (c *CitationCollector) Add(results []SearchResult) {
+	if c == nil {
+		return
+	}
+	for _, r := range results {
+		c.captureResult(r)
+	}
 }
 
-func (c *citationLinkCollector) build() map[int]string {
+// Links returns marker index to URL.
+func (c *CitationCollector) Links() map[int]string {
+	if c == nil {
+		return map[int]string{}
+	}
+	return c.build()
+}
+
+// Citations returns resolved sources ordered by marker index.
+func (c *CitationCollector) Citations() []Citation {
+	if c == nil {
+		return nil
+	}
+	links := c.build()
+	if len(links) == 0 {
+		return nil
+	}
+	out := make([]Citation, 0, len(links))
+	for idx, u := range links {
+		meta := c.meta[u]
+		out = append(out, Citation{Index: idx, URL: u, Title: meta.title, Snippet: meta.snippet})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Index < out[j].Index })
+	return out
+}
+
+// ExtractSearchResults walks an upstream chunk and returns every object that
+// carries a web URL, ordered by cite index. Results without one follow in
+// walk order: array order, with object keys visited alphabetically.
+func ExtractSearchResults(chunk map[string]any) []SearchResult {
+	if len(chunk) == 0 {
+		return nil
+	}
+	var out []SearchResult
+	walkSearchResults(chunk, &out)
+	sort.SliceStable(out, func(i, j int) bool {
+		return citeSortKey(out[i]) < citeSortKey(out[j])
+	})
+	return out
+}
+
+func citeSortKey(r SearchResult) int {
+	if !r.HasCiteIndex {
+		return math.MaxInt
+	}
+	return r.CiteIndex
+}
+
+func (c *CitationCollector) build() map[int]string {
 	out := make(map[int]string, len(c.explicitRaw)+len(c.ordered))
 	for idx, u := range c.buildNormalizedExplicit() {
 		out[idx] = u
@@ -38,7 +125,7 @@ func (c *citationLinkCollector) build() map[int]string {
 	return out
 }
 
-func (c *citationLinkCollector) buildNormalizedExplicit() map[int]string {
+func (c *CitationCollector) buildNormalizedExplicit() map[int]string {
 	out := make(map[int]string, len(c.explicitRaw))
 
 	// Default behavior keeps positive indices as-is (one-based payloads).
@@ -74,7 +161,7 @@ func (c *citationLinkCollector) buildNormalizedExplicit() map[int]string {
 	return out
 }
 
-func (c *citationLinkCollector) preferURLForIndex(idx int, current, candidate string) string {
+func (c *CitationCollector) preferURLForIndex(idx int, current, candidate string) string {
 	if idx <= 0 || idx > len(c.ordered) {
 		return current
 	}
@@ -89,31 +176,63 @@ func (c *citationLinkCollector) preferURLForIndex(idx int, current, candidate st
 	}
 }
 
-func (c *citationLinkCollector) walkValue(v any) {
+func walkSearchResults(v any, out *[]SearchResult) {
 	switch x := v.(type) {
 	case []any:
 		for _, item := range x {
-			c.walkValue(item)
+			walkSearchResults(item, out)
 		}
 	case map[string]any:
-		c.captureURLAndIndex(x)
-		for _, vv := range x {
-			c.walkValue(vv)
+		if r, ok := searchResultFromMap(x); ok {
+			*out = append(*out, r)
+		}
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			walkSearchResults(x[k], out)
 		}
 	}
 }
 
-func (c *citationLinkCollector) captureURLAndIndex(m map[string]any) {
+func searchResultFromMap(m map[string]any) (SearchResult, bool) {
 	url := strings.TrimSpace(asString(m["url"]))
+	if !isWebURL(url) {
+		return SearchResult{}, false
+	}
+	r := SearchResult{
+		URL:     url,
+		Title:   strings.TrimSpace(asString(m["title"])),
+		Snippet: strings.TrimSpace(firstString(m, "snippet", "summary", "content")),
+	}
+	r.CiteIndex, r.HasCiteIndex = citationIndexFromAny(m["cite_index"])
+	return r, true
+}
+
+func (c *CitationCollector) captureResult(r SearchResult) {
+	url := strings.TrimSpace(r.URL)
 	if !isWebURL(url) {
 		return
 	}
 	c.addOrdered(url)
+	if c.meta == nil {
+		c.meta = map[string]citationMeta{}
+	}
+	meta := c.meta[url]
+	if meta.title == "" {
+		meta.title = r.Title
+	}
+	if meta.snippet == "" {
+		meta.snippet = r.Snippet
+	}
+	c.meta[url] = meta
 
-	idx, hasIdx := citationIndexFromAny(m["cite_index"])
-	if !hasIdx {
+	if !r.HasCiteIndex {
 		return
 	}
+	idx := r.CiteIndex
 	if idx < 0 {
 		return
 	}
@@ -126,7 +245,7 @@ func (c *citationLinkCollector) captureURLAndIndex(m map[string]any) {
 	c.explicitRaw[idx] = url
 }
 
-func (c *citationLinkCollector) addOrdered(url string) {
+func (c *CitationCollector) addOrdered(url string) {
 	c.ordered = append(c.ordered, url)
 }
 
@@ -166,3 +285,12 @@ func asString(v any) string {
 	s, _ := v.(string)
 	return s
 }
+
+func firstString(m map[string]any, keys ...string) string {
+	for _, key := range keys {
+		if s := asString(m[key]); strings.TrimSpace(s) != "" {
+			return s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,19 @@
+package sse
+
+import "testing"
+
+func TestExtractSearchResultsOrdersByCiteIndex(t *testing.T) {
+	chunk := map[string]any{
+		"v": map[string]any{
+			"later":   []any{map[string]any{"url": "https://b.example", "cite_index": 2}},
+			"earlier": []any{map[string]any{"url": "https://a.example", "cite_index": 1}},
+			"plain":   map[string]any{"url": "https://c.example"},
+		},
+	}
+	for i := 0; i < 20; i++ {
+		got := ExtractSearchResults(chunk)
+		if len(got) != 3 || got[0].URL != "https://a.example" || got[1].URL != "https://b.example" || got[2].URL != "https://c.example" {
+			t.Fatalf("unexpected order: %#v", got)
+		}
+	}
+}
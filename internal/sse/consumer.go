@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	dsprotocol "ds2api/internal/deepseek/protocol"
+	"ds2api/internal/inflight"
 	"ds2api/internal/util"
 )
 
@@ -16,7 +17,10 @@ type CollectResult struct {
 	ToolDetectionThinking string
 	ContentFilter         bool
 	CitationLinks         map[int]string
+	Citations             []Citation
 	ResponseMessageID     int
+	LimitReason           LimitReason
+	StopSequence          string
 }
 
 // CollectStream fully consumes a DeepSeek SSE response and separates
@@ -26,6 +30,19 @@ type CollectResult struct {
 //
 // The caller is responsible for closing resp.Body unless closeBody is true.
 func CollectStream(resp *http.Response, thinkingEnabled bool, closeBody bool) CollectResult {
+	return CollectStreamWithLimits(resp, thinkingEnabled, closeBody, OutputLimits{})
+}
+
+// CollectStreamWithLimits is CollectStream with local stop-sequence and
+// max-token enforcement. Scanning stops as soon as a limit is reached so the
+// caller can close the upstream body early.
+func CollectStreamWithLimits(resp *http.Response, thinkingEnabled bool, closeBody bool, limits OutputLimits) CollectResult {
+	return CollectStreamObserved(resp, thinkingEnabled, closeBody, limits, nil)
+}
+
+// CollectStreamObserved is CollectStreamWithLimits that also reports each
+// new piece of thinking or visible text to onPart as it is collected.
+func CollectStreamObserved(resp *http.Response, thinkingEnabled bool, closeBody bool, limits OutputLimits, onPart func(ContentPart)) CollectResult {
 	if closeBody {
 		defer func() { _ = resp.Body.Close() }()
 	}
@@ -34,8 +51,13 @@ func CollectStream(resp *http.Response, thinkingEnabled bool, closeBody bool) Co
 	toolDetectionThinking := strings.Builder{}
 	contentFilter := false
 	stopped := false
-	collector := newCitationLinkCollector()
+	collector := NewCitationCollector()
 	responseMessageID := 0
+	limiter := NewOutputLimiter(limits)
+	var tracked *inflight.Request
+	if resp.Request != nil {
+		tracked = inflight.FromContext(resp.Request.Context())
+	}
 	currentType := "text"
 	if thinkingEnabled {
 		currentType = "thinking"
@@ -68,27 +90,41 @@ func CollectStream(resp *http.Response, thinkingEnabled bool, closeBody bool) Co
 			return true
 		}
 		for _, p := range result.Parts {
+			tracked.AddOutput(p.Text)
+			var delta string
 			if p.Type == "thinking" {
-				trimmed := TrimContinuationOverlap(thinking.String(), p.Text)
-				thinking.WriteString(trimmed)
+				delta = TrimContinuationOverlap(thinking.String(), p.Text)
+				thinking.WriteString(delta)
 			} else {
-				trimmed := TrimContinuationOverlap(text.String(), p.Text)
-				text.WriteString(trimmed)
+				delta = limiter.Push(TrimContinuationOverlap(text.String(), p.Text))
+				text.WriteString(delta)
+			}
+			if onPart != nil && delta != "" {
+				onPart(ContentPart{Type: p.Type, Text: delta})
 			}
 		}
 		for _, p := range result.ToolDetectionThinkingParts {
 			trimmed := TrimContinuationOverlap(toolDetectionThinking.String(), p.Text)
 			toolDetectionThinking.WriteString(trimmed)
 		}
-		return true
+		return !limiter.Done()
 	})
+	if tail := limiter.Flush(); tail != "" {
+		text.WriteString(tail)
+		if onPart != nil {
+			onPart(ContentPart{Type: "text", Text: tail})
+		}
+	}
 	return CollectResult{
 		Text:                  text.String(),
 		Thinking:              thinking.String(),
 		ToolDetectionThinking: toolDetectionThinking.String(),
 		ContentFilter:         contentFilter,
-		CitationLinks:         collector.build(),
+		CitationLinks:         collector.Links(),
+		Citations:             collector.Citations(),
 		ResponseMessageID:     responseMessageID,
+		LimitReason:           limiter.Reason(),
+		StopSequence:          limiter.StopSequence(),
 	}
 }
 
@@ -296,3 +296,23 @@ func TestCollectStreamStopsOnContentFilterStatus(t *testing.T) {
 		t.Fatalf("expected stream to stop before blocked tail, got %q", result.Text)
 	}
 }
+
+func TestCollectStreamExtractsCitationMetadata(t *testing.T) {
+	resp := makeHTTPResponse(
+		"data: {\"p\":\"response/fragments/-1/results\",\"v\":[{\"url\":\"https://example.com/a\",\"title\":\"Alpha\",\"snippet\":\"first\",\"cite_index\":1},{\"url\":\"https://example.com/b\",\"title\":\"Beta\",\"summary\":\"second\",\"cite_index\":2}]}\n" +
+			"data: {\"p\":\"response/content\",\"v\":\"结论[citation:1][citation:2]\"}\n" +
+			"data: [DONE]\n",
+	)
+	result := CollectStream(resp, false, false)
+
+	if len(result.Citations) != 2 {
+		t.Fatalf("expected two citations, got %#v", result.Citations)
+	}
+	first, second := result.Citations[0], result.Citations[1]
+	if first.Index != 1 || first.URL != "https://example.com/a" || first.Title != "Alpha" || first.Snippet != "first" {
+		t.Fatalf("unexpected first citation: %#v", first)
+	}
+	if second.Index != 2 || second.Title != "Beta" || second.Snippet != "second" {
+		t.Fatalf("unexpected second citation: %#v", second)
+	}
+}
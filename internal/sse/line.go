@@ -14,6 +14,7 @@ type LineResult struct {
 	ToolDetectionThinkingParts []ContentPart
 	NextType                   string
 	ResponseMessageID          int
+	SearchResults              []SearchResult
 }
 
 // ParseDeepSeekContentLine centralizes one-line DeepSeek SSE parsing for both
@@ -62,5 +63,6 @@ func ParseDeepSeekContentLine(raw []byte, thinkingEnabled bool, currentType stri
 		ToolDetectionThinkingParts: detectionThinkingParts,
 		NextType:                   nextType,
 		ResponseMessageID:          respMsgID,
+		SearchResults:              ExtractSearchResults(chunk),
 	}
 }
@@ -0,0 +1,182 @@
+package sse
+
+import (
+	"strings"
+
+	"ds2api/internal/util"
+)
+
+// LimitReason records why local output enforcement ended a response early.
+type LimitReason string
+
+const (
+	LimitNone         LimitReason = ""
+	LimitStopSequence LimitReason = "stop_sequence"
+	LimitMaxTokens    LimitReason = "max_tokens"
+)
+
+// OutputLimits are the client's stop strings and output token cap. The
+// DeepSeek web backend ignores both, so they are enforced on our side.
+type OutputLimits struct {
+	StopSequences []string
+	MaxTokens     int
+	Model         string
+}
+
+func (l OutputLimits) Enabled() bool {
+	return len(l.StopSequences) > 0 || l.MaxTokens > 0
+}
+
+// OutputLimiter applies OutputLimits to the visible text stream. Text that
+// could be the start of a stop sequence is held back until the next delta
+// disambiguates it, so stop strings split across chunks never leak. A nil
+// limiter passes everything through.
+type OutputLimiter struct {
+	limits       OutputLimits
+	maxStopLen   int
+	pending      string
+	emitted      strings.Builder
+	tokens       int
+	reason       LimitReason
+	stopSequence string
+}
+
+func NewOutputLimiter(limits OutputLimits) *OutputLimiter {
+	if !limits.Enabled() {
+		return nil
+	}
+	l := &OutputLimiter{limits: limits}
+	stops := make([]string, 0, len(limits.StopSequences))
+	for _, s := range limits.StopSequences {
+		if s == "" {
+			continue
+		}
+		stops = append(stops, s)
+		if len(s) > l.maxStopLen {
+			l.maxStopLen = len(s)
+		}
+	}
+	l.limits.StopSequences = stops
+	return l
+}
+
+// Push feeds one text delta and returns the part that may be emitted now.
+func (l *OutputLimiter) Push(text string) string {
+	if l == nil {
+		return text
+	}
+	if l.reason != LimitNone || text == "" {
+		return ""
+	}
+	buf := l.pending + text
+	l.pending = ""
+	if idx, stop := l.firstStop(buf); idx >= 0 {
+		out := l.admit(buf[:idx])
+		if l.reason == LimitNone {
+			l.reason = LimitStopSequence
+			l.stopSequence = stop
+		}
+		return out
+	}
+	hold := l.partialStopSuffix(buf)
+	l.pending = buf[len(buf)-hold:]
+	return l.admit(buf[:len(buf)-hold])
+}
+
+// Flush releases text held back for stop-sequence matching once the stream
+// has ended without completing one.
+func (l *OutputLimiter) Flush() string {
+	if l == nil || l.reason != LimitNone {
+		return ""
+	}
+	out := l.admit(l.pending)
+	l.pending = ""
+	return out
+}
+
+// Done reports that a limit was reached and the upstream can be cancelled.
+func (l *OutputLimiter) Done() bool {
+	return l != nil && l.reason != LimitNone
+}
+
+func (l *OutputLimiter) Reason() LimitReason {
+	if l == nil {
+		return LimitNone
+	}
+	return l.reason
+}
+
+func (l *OutputLimiter) StopSequence() string {
+	if l == nil {
+		return ""
+	}
+	return l.stopSequence
+}
+
+func (l *OutputLimiter) firstStop(buf string) (int, string) {
+	best, match := -1, ""
+	for _, s := range l.limits.StopSequences {
+		if idx := strings.Index(buf, s); idx >= 0 && (best < 0 || idx < best) {
+			best, match = idx, s
+		}
+	}
+	return best, match
+}
+
+// partialStopSuffix returns the length of the longest suffix of buf that is
+// a proper prefix of some stop sequence.
+func (l *OutputLimiter) partialStopSuffix(buf string) int {
+	maxHold := l.maxStopLen - 1
+	if maxHold > len(buf) {
+		maxHold = len(buf)
+	}
+	for n := maxHold; n > 0; n-- {
+		suffix := buf[len(buf)-n:]
+		for _, s := range l.limits.StopSequences {
+			if strings.HasPrefix(s, suffix) {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+// admit applies the token cap to text. Per-delta counts overestimate, so the
+// exact total is only recounted once the running sum crosses the cap.
+func (l *OutputLimiter) admit(text string) string {
+	if text == "" || l.limits.MaxTokens <= 0 {
+		return text
+	}
+	l.tokens += util.CountOutputTokens(text, l.limits.Model)
+	if l.tokens <= l.limits.MaxTokens {
+		l.emitted.WriteString(text)
+		return text
+	}
+	prior := l.emitted.String()
+	l.tokens = util.CountOutputTokens(prior+text, l.limits.Model)
+	if l.tokens <= l.limits.MaxTokens {
+		l.emitted.WriteString(text)
+		return text
+	}
+	// Binary search the longest rune prefix of text that still fits.
+	runes := []int{}
+	for i := range text {
+		runes = append(runes, i)
+	}
+	runes = append(runes, len(text))
+	lo, hi := 0, len(runes)-1
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if util.CountOutputTokens(prior+text[:runes[mid]], l.limits.Model) <= l.limits.MaxTokens {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	out := text[:runes[lo]]
+	l.emitted.WriteString(out)
+	l.tokens = l.limits.MaxTokens
+	l.reason = LimitMaxTokens
+	l.pending = ""
+	return out
+}
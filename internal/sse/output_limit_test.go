@@ -0,0 +1,87 @@
+package sse
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"ds2api/internal/util"
+)
+
+func TestOutputLimiterHoldsBackSplitStopSequence(t *testing.T) {
+	l := NewOutputLimiter(OutputLimits{StopSequences: []string{"END"}})
+	var out strings.Builder
+	for _, chunk := range []string{"hello E", "N", "D tail"} {
+		out.WriteString(l.Push(chunk))
+	}
+	out.WriteString(l.Flush())
+	if out.String() != "hello " {
+		t.Fatalf("expected text before stop sequence, got %q", out.String())
+	}
+	if l.Reason() != LimitStopSequence || l.StopSequence() != "END" {
+		t.Fatalf("unexpected limit state: %q %q", l.Reason(), l.StopSequence())
+	}
+}
+
+func TestOutputLimiterReleasesFalseStopPrefix(t *testing.T) {
+	l := NewOutputLimiter(OutputLimits{StopSequences: []string{"END"}})
+	first := l.Push("hello EN")
+	if first != "hello " {
+		t.Fatalf("expected partial stop to be held back, got %q", first)
+	}
+	if got := first + l.Push("ough") + l.Flush(); got != "hello ENough" {
+		t.Fatalf("expected held text to be released, got %q", got)
+	}
+	if l.Done() {
+		t.Fatalf("expected limiter to stay open")
+	}
+}
+
+func TestOutputLimiterCutsAtMaxTokens(t *testing.T) {
+	l := NewOutputLimiter(OutputLimits{MaxTokens: 3, Model: "deepseek-chat"})
+	var out strings.Builder
+	for i := 0; i < 20 && !l.Done(); i++ {
+		out.WriteString(l.Push("word "))
+	}
+	if l.Reason() != LimitMaxTokens {
+		t.Fatalf("expected max_tokens limit, got %q", l.Reason())
+	}
+	if n := util.CountOutputTokens(out.String(), "deepseek-chat"); n > 3 || out.Len() == 0 {
+		t.Fatalf("expected output within cap, got %d tokens in %q", n, out.String())
+	}
+	if l.Push("more") != "" {
+		t.Fatalf("expected no output after the limit")
+	}
+}
+
+func TestNilOutputLimiterPassesThrough(t *testing.T) {
+	l := NewOutputLimiter(OutputLimits{})
+	if l != nil {
+		t.Fatalf("expected disabled limits to yield a nil limiter")
+	}
+	if l.Push("abc") != "abc" || l.Flush() != "" || l.Done() {
+		t.Fatalf("expected nil limiter to pass text through")
+	}
+}
+
+func TestCollectStreamWithLimitsStopsAtStopSequence(t *testing.T) {
+	body := strings.Join([]string{
+		`data: {"v":"first line\nST"}`,
+		``,
+		`data: {"v":"OP second line"}`,
+		``,
+		`data: {"v":"never seen"}`,
+		``,
+		`data: {"p":"response/status","v":"FINISHED"}`,
+		``,
+	}, "\n")
+	resp := &http.Response{Body: io.NopCloser(strings.NewReader(body))}
+	got := CollectStreamWithLimits(resp, false, true, OutputLimits{StopSequences: []string{"STOP"}})
+	if got.Text != "first line\n" {
+		t.Fatalf("unexpected text: %q", got.Text)
+	}
+	if got.LimitReason != LimitStopSequence || got.StopSequence != "STOP" {
+		t.Fatalf("unexpected limit result: %q %q", got.LimitReason, got.StopSequence)
+	}
+}
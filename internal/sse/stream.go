@@ -194,6 +194,16 @@ func startParsedLinePumpWithConfig(ctx context.Context, body io.Reader, thinking
 			if result.ResponseMessageID > 0 {
 				pendingResponseMessageID = result.ResponseMessageID
 			}
+			// Search results are forwarded as soon as they arrive; they carry
+			// no text, so they never disturb the aggregation buffers.
+			if len(result.SearchResults) > 0 {
+				select {
+				case out <- LineResult{Parsed: true, SearchResults: result.SearchResults, NextType: currentType}:
+				case <-ctx.Done():
+					pumpErr = ctx.Err()
+					return false
+				}
+			}
 
 			if result.Stop {
 				if cfg.Enabled && cfg.FlushOnFinish {
@@ -5,6 +5,7 @@ import (
 	"io"
 	"time"
 
+	"ds2api/internal/inflight"
 	"ds2api/internal/sse"
 )
 
@@ -62,6 +63,7 @@ func ConsumeSSE(cfg ConsumeConfig, hooks ConsumeHooks) {
 		defer ticker.Stop()
 	}
 
+	tracked := inflight.FromContext(cfg.Context)
 	hasContent := false
 	lastContent := time.Now()
 	keepaliveCount := 0
@@ -120,6 +122,9 @@ func ConsumeSSE(cfg ConsumeConfig, hooks ConsumeHooks) {
 			if hooks.OnParsed == nil {
 				continue
 			}
+			for _, p := range parsed.Parts {
+				tracked.AddOutput(p.Text)
+			}
 			decision := hooks.OnParsed(parsed)
 			if decision.ContentSeen {
 				hasContent = true
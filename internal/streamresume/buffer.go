@@ -0,0 +1,300 @@
+// Package streamresume keeps opted-in SSE streams running detached from the
+// client connection. Generated events are recorded in a bounded in-memory
+// buffer so a client that reconnects with Last-Event-ID can replay what it
+// missed and continue following the live stream.
+package streamresume
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxBytes bounds the events retained per stream. Once exceeded the
+// oldest events are dropped and can no longer be replayed.
+const DefaultMaxBytes = 8 << 20
+
+// ErrEventsDropped reports that the requested resume point has already been
+// evicted from the buffer.
+var ErrEventsDropped = errors.New("requested events are no longer buffered")
+
+var errReaderLagged = errors.New("reader fell behind the stream buffer")
+
+type event struct {
+	seq   int
+	frame []byte
+}
+
+// Buffer is an http.ResponseWriter that records SSE frames instead of
+// sending them. Runtimes write into it exactly as they would to a client;
+// Serve relays the recorded frames, each tagged with an "id:" line.
+type Buffer struct {
+	id       string
+	owner    string
+	maxBytes int
+
+	mu       sync.Mutex
+	header   http.Header
+	status   int
+	started  bool
+	stream   bool
+	pending  []byte
+	body     []byte
+	events   []event
+	size     int
+	firstSeq int
+	nextSeq  int
+	done     bool
+	doneAt   time.Time
+	changed  chan struct{}
+}
+
+func newBuffer(owner, id string, maxBytes int) *Buffer {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	return &Buffer{
+		id:       id,
+		owner:    owner,
+		maxBytes: maxBytes,
+		header:   http.Header{},
+		firstSeq: 1,
+		nextSeq:  1,
+		changed:  make(chan struct{}),
+	}
+}
+
+func (b *Buffer) Header() http.Header { return b.header }
+
+func (b *Buffer) WriteHeader(status int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.startLocked(status)
+}
+
+func (b *Buffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.done {
+		return 0, errors.New("stream buffer is closed")
+	}
+	b.startLocked(http.StatusOK)
+	if !b.stream {
+		b.body = append(b.body, p...)
+		b.notifyLocked()
+		return len(p), nil
+	}
+	b.pending = append(b.pending, p...)
+	for {
+		idx := bytes.Index(b.pending, []byte("\n\n"))
+		if idx < 0 {
+			break
+		}
+		frame := append([]byte(nil), b.pending[:idx+2]...)
+		b.pending = b.pending[idx+2:]
+		// Keep-alive comments are regenerated per connection by Serve.
+		if frame[0] == ':' {
+			continue
+		}
+		b.appendLocked(frame)
+	}
+	return len(p), nil
+}
+
+// Flush satisfies http.Flusher; frames become visible as soon as they are
+// complete, so there is nothing to do.
+func (b *Buffer) Flush() {}
+
+// Close marks the generation finished. Any partial frame is kept as-is.
+func (b *Buffer) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.done {
+		return
+	}
+	b.startLocked(http.StatusOK)
+	if len(b.pending) > 0 {
+		b.appendLocked(append(b.pending, '\n', '\n'))
+		b.pending = nil
+	}
+	b.done = true
+	b.doneAt = time.Now()
+	b.notifyLocked()
+}
+
+// Done reports whether the generation has finished.
+func (b *Buffer) Done() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.done
+}
+
+func (b *Buffer) startLocked(status int) {
+	if b.started {
+		return
+	}
+	b.started = true
+	b.status = status
+	b.stream = strings.HasPrefix(b.header.Get("Content-Type"), "text/event-stream")
+	b.notifyLocked()
+}
+
+func (b *Buffer) appendLocked(frame []byte) {
+	b.events = append(b.events, event{seq: b.nextSeq, frame: frame})
+	b.nextSeq++
+	b.size += len(frame)
+	for b.size > b.maxBytes && len(b.events) > 1 {
+		b.size -= len(b.events[0].frame)
+		b.events = b.events[1:]
+		b.firstSeq = b.events[0].seq
+	}
+	b.notifyLocked()
+}
+
+func (b *Buffer) notifyLocked() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// Serve writes the events after afterSeq to w and then follows the live
+// stream until it finishes or ctx is cancelled. Cancelling ctx only detaches
+// the reader; the generation keeps running. Non-SSE responses (errors
+// written before the stream started) are relayed once complete.
+func (b *Buffer) Serve(ctx context.Context, w http.ResponseWriter, afterSeq int, keepAlive time.Duration) error {
+	if !b.waitStarted(ctx) {
+		return ctx.Err()
+	}
+	b.mu.Lock()
+	stream := b.stream
+	if stream && afterSeq+1 < b.firstSeq {
+		b.mu.Unlock()
+		return ErrEventsDropped
+	}
+	for k, v := range b.header {
+		w.Header()[k] = append([]string(nil), v...)
+	}
+	b.mu.Unlock()
+	if !stream {
+		if !b.waitDone(ctx) {
+			return ctx.Err()
+		}
+		b.mu.Lock()
+		status, body := b.status, append([]byte(nil), b.body...)
+		b.mu.Unlock()
+		w.WriteHeader(status)
+		_, err := w.Write(body)
+		return err
+	}
+
+	rc := http.NewResponseController(w)
+	w.WriteHeader(http.StatusOK)
+	_ = rc.Flush()
+	var ticker <-chan time.Time
+	if keepAlive > 0 {
+		t := time.NewTicker(keepAlive)
+		defer t.Stop()
+		ticker = t.C
+	}
+	next := afterSeq + 1
+	for {
+		b.mu.Lock()
+		if next < b.firstSeq {
+			// The reader fell further behind than the buffer holds. End the
+			// stream so the client reconnects and learns what was lost.
+			b.mu.Unlock()
+			return errReaderLagged
+		}
+		var batch []event
+		if start := next - b.firstSeq; start < len(b.events) {
+			batch = append(batch, b.events[start:]...)
+		}
+		done, changed := b.done, b.changed
+		b.mu.Unlock()
+
+		for _, ev := range batch {
+			if _, err := w.Write([]byte("id: " + FormatEventID(b.id, ev.seq) + "\n")); err != nil {
+				return err
+			}
+			if _, err := w.Write(ev.frame); err != nil {
+				return err
+			}
+			next = ev.seq + 1
+		}
+		if len(batch) > 0 {
+			_ = rc.Flush()
+		}
+		if done {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		case <-ticker:
+			if _, err := w.Write([]byte(": keep-alive\n\n")); err != nil {
+				return err
+			}
+			_ = rc.Flush()
+		}
+	}
+}
+
+func (b *Buffer) waitStarted(ctx context.Context) bool {
+	for {
+		b.mu.Lock()
+		started, changed := b.started, b.changed
+		b.mu.Unlock()
+		if started {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-changed:
+		}
+	}
+}
+
+func (b *Buffer) waitDone(ctx context.Context) bool {
+	for {
+		b.mu.Lock()
+		done, changed := b.done, b.changed
+		b.mu.Unlock()
+		if done {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-changed:
+		}
+	}
+}
+
+// FormatEventID renders the SSE id of an event: "<stream id>:<sequence>".
+func FormatEventID(streamID string, seq int) string {
+	return streamID + ":" + strconv.Itoa(seq)
+}
+
+// ParseEventID splits a Last-Event-ID value. A bare sequence number is
+// accepted when the stream is already identified by the URL.
+func ParseEventID(raw string) (string, int, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", 0, false
+	}
+	streamID, seqText := "", raw
+	if idx := strings.LastIndex(raw, ":"); idx >= 0 {
+		streamID, seqText = raw[:idx], raw[idx+1:]
+	}
+	seq, err := strconv.Atoi(seqText)
+	if err != nil || seq < 0 {
+		return "", 0, false
+	}
+	return streamID, seq, true
+}
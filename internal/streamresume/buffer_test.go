@@ -0,0 +1,128 @@
+package streamresume
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFrames(b *Buffer, frames ...string) {
+	b.Header().Set("Content-Type", "text/event-stream")
+	for _, f := range frames {
+		_, _ = b.Write([]byte(f))
+	}
+}
+
+func startBuffer(t *testing.T, hub *Hub, owner, id string) *Buffer {
+	t.Helper()
+	b, err := hub.Start(owner, id)
+	if err != nil {
+		t.Fatalf("start buffer: %v", err)
+	}
+	return b
+}
+
+func TestBufferReplaysEventsAfterSequence(t *testing.T) {
+	hub := NewHub(time.Minute, 0, 0)
+	b := startBuffer(t, hub, "owner", "resp_1")
+	writeFrames(b, "data: one\n\n", ": keep-alive\n\n", "data: t", "wo\n\ndata: three\n\n")
+	b.Close()
+
+	rec := httptest.NewRecorder()
+	if err := b.Serve(context.Background(), rec, 1, 0); err != nil {
+		t.Fatalf("serve failed: %v", err)
+	}
+	want := "id: resp_1:2\ndata: two\n\nid: resp_1:3\ndata: three\n\n"
+	if rec.Body.String() != want {
+		t.Fatalf("unexpected replay:\n%q\nwant\n%q", rec.Body.String(), want)
+	}
+	if rec.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected SSE content type, got %q", rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestBufferFollowsLiveStream(t *testing.T) {
+	b := startBuffer(t, NewHub(time.Minute, 0, 0), "owner", "s")
+	writeFrames(b, "data: first\n\n")
+	rec := httptest.NewRecorder()
+	done := make(chan error, 1)
+	go func() { done <- b.Serve(context.Background(), rec, 0, 0) }()
+	_, _ = b.Write([]byte("data: second\n\n"))
+	b.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("serve failed: %v", err)
+	}
+	if !strings.Contains(rec.Body.String(), "id: s:2\ndata: second") {
+		t.Fatalf("expected live event, got %q", rec.Body.String())
+	}
+}
+
+func TestBufferDropsOldestEventsPastLimit(t *testing.T) {
+	b := startBuffer(t, NewHub(time.Minute, 20, 0), "owner", "s")
+	writeFrames(b, "data: aaaaaaaa\n\n", "data: bbbbbbbb\n\n")
+	b.Close()
+	if err := b.Serve(context.Background(), httptest.NewRecorder(), 0, 0); !errors.Is(err, ErrEventsDropped) {
+		t.Fatalf("expected dropped events error, got %v", err)
+	}
+	rec := httptest.NewRecorder()
+	if err := b.Serve(context.Background(), rec, 1, 0); err != nil || !strings.Contains(rec.Body.String(), "bbbbbbbb") {
+		t.Fatalf("expected newest event to remain, err=%v body=%q", err, rec.Body.String())
+	}
+}
+
+func TestRunKeepsGeneratingAfterClientLeaves(t *testing.T) {
+	hub := NewHub(time.Minute, 0, 0)
+	b := startBuffer(t, hub, "owner", "s")
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodPost, "/", nil).WithContext(ctx)
+	Run(httptest.NewRecorder(), req, b, 0, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: before\n\n"))
+		cancel()
+		if r.Context().Err() != nil {
+			t.Errorf("generation context must not follow the client")
+		}
+		_, _ = w.Write([]byte("data: after\n\n"))
+	})
+	got, ok := hub.Get("owner", "s")
+	if !ok || !got.Done() {
+		t.Fatalf("expected finished buffer to stay registered")
+	}
+	if _, ok := hub.Get("other", "s"); ok {
+		t.Fatalf("expected buffers to be isolated by owner")
+	}
+	rec := httptest.NewRecorder()
+	_ = got.Serve(context.Background(), rec, 1, 0)
+	if rec.Body.String() != "id: s:2\ndata: after\n\n" {
+		t.Fatalf("unexpected resumed body: %q", rec.Body.String())
+	}
+}
+
+func TestBufferRelaysNonStreamError(t *testing.T) {
+	b := startBuffer(t, NewHub(time.Minute, 0, 0), "owner", "s")
+	b.Header().Set("Content-Type", "application/json")
+	b.WriteHeader(http.StatusBadGateway)
+	_, _ = b.Write([]byte(`{"error":"x"}`))
+	b.Close()
+	rec := httptest.NewRecorder()
+	_ = b.Serve(context.Background(), rec, 0, 0)
+	if rec.Code != http.StatusBadGateway || rec.Body.String() != `{"error":"x"}` {
+		t.Fatalf("unexpected relay: %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestParseEventID(t *testing.T) {
+	if id, seq, ok := ParseEventID("chatcmpl:abc:7"); !ok || id != "chatcmpl:abc" || seq != 7 {
+		t.Fatalf("unexpected parse: %q %d %v", id, seq, ok)
+	}
+	if id, seq, ok := ParseEventID("12"); !ok || id != "" || seq != 12 {
+		t.Fatalf("unexpected bare parse: %q %d %v", id, seq, ok)
+	}
+	if _, _, ok := ParseEventID("resp:x"); ok {
+		t.Fatalf("expected invalid sequence to fail")
+	}
+}
@@ -0,0 +1,141 @@
+package streamresume
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"ds2api/internal/inflight"
+)
+
+// DefaultTTL is how long a finished stream stays replayable.
+const DefaultTTL = 15 * time.Minute
+
+// DefaultMaxBuffers bounds how many buffers a hub holds at once. Together
+// with the per-buffer byte limit it caps the memory kept for resumption.
+const DefaultMaxBuffers = 256
+
+// ErrHubFull reports that every buffer in the hub is still live, so no
+// finished one could be evicted to make room for a new stream.
+var ErrHubFull = errors.New("too many resumable streams in flight")
+
+// Hub indexes live and recently finished buffers by owner and stream id.
+type Hub struct {
+	ttl        time.Duration
+	maxBytes   int
+	maxBuffers int
+
+	mu    sync.Mutex
+	items map[string]*Buffer
+}
+
+func NewHub(ttl time.Duration, maxBytes, maxBuffers int) *Hub {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	if maxBuffers <= 0 {
+		maxBuffers = DefaultMaxBuffers
+	}
+	return &Hub{ttl: ttl, maxBytes: maxBytes, maxBuffers: maxBuffers, items: map[string]*Buffer{}}
+}
+
+func hubKey(owner, id string) string {
+	return owner + "\x00" + id
+}
+
+// Start registers a new buffer for id, replacing any previous one. When
+// the hub is full the oldest finished buffers are evicted first; if all of
+// them are still live it returns ErrHubFull.
+func (h *Hub) Start(owner, id string) (*Buffer, error) {
+	key := hubKey(owner, id)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sweepLocked(time.Now())
+	n := len(h.items)
+	if _, ok := h.items[key]; ok {
+		n--
+	}
+	if n >= h.maxBuffers && !h.evictFinishedLocked(n-h.maxBuffers+1, key) {
+		return nil, ErrHubFull
+	}
+	b := newBuffer(owner, id, h.maxBytes)
+	h.items[key] = b
+	return b, nil
+}
+
+// Get returns the buffer for id if owner started it and it has not expired.
+func (h *Hub) Get(owner, id string) (*Buffer, bool) {
+	if h == nil || owner == "" || id == "" {
+		return nil, false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sweepLocked(time.Now())
+	b, ok := h.items[hubKey(owner, id)]
+	if !ok || b.owner != owner {
+		return nil, false
+	}
+	return b, true
+}
+
+// evictFinishedLocked drops the n oldest finished buffers other than keep,
+// or nothing if fewer than n have finished.
+func (h *Hub) evictFinishedLocked(n int, keep string) bool {
+	type finished struct {
+		key    string
+		doneAt time.Time
+	}
+	var candidates []finished
+	for k, b := range h.items {
+		if k == keep {
+			continue
+		}
+		b.mu.Lock()
+		if b.done {
+			candidates = append(candidates, finished{key: k, doneAt: b.doneAt})
+		}
+		b.mu.Unlock()
+	}
+	if len(candidates) < n {
+		return false
+	}
+	slices.SortFunc(candidates, func(a, b finished) int { return a.doneAt.Compare(b.doneAt) })
+	for _, c := range candidates[:n] {
+		delete(h.items, c.key)
+	}
+	return true
+}
+
+func (h *Hub) sweepLocked(now time.Time) {
+	for k, b := range h.items {
+		b.mu.Lock()
+		expired := b.done && now.Sub(b.doneAt) > h.ttl
+		b.mu.Unlock()
+		if expired {
+			delete(h.items, k)
+		}
+	}
+}
+
+// Run executes generate against buf using a context detached from the
+// client, while relaying the buffer to w until the client disconnects. The
+// generation still stops when the request is cancelled from the admin API.
+// It returns once the generation has finished, so the caller's cleanup
+// (account release, session deletion) still runs after the upstream is done
+// and the request stays registered for drain to wait on.
+func Run(w http.ResponseWriter, r *http.Request, buf *Buffer, keepAlive time.Duration, generate func(http.ResponseWriter, *http.Request)) {
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		_ = buf.Serve(r.Context(), w, 0, keepAlive)
+	}()
+	defer func() {
+		buf.Close()
+		<-served
+	}()
+	ctx, stop := inflight.Detach(r.Context())
+	defer stop()
+	generate(buf, r.WithContext(ctx))
+}
@@ -0,0 +1,40 @@
+package streamresume
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHubEvictsOldestFinishedBufferWhenFull(t *testing.T) {
+	hub := NewHub(time.Minute, 0, 2)
+	first := startBuffer(t, hub, "owner", "a")
+	second := startBuffer(t, hub, "owner", "b")
+	second.Close()
+	time.Sleep(time.Millisecond)
+	first.Close()
+
+	startBuffer(t, hub, "owner", "c")
+	if _, ok := hub.Get("owner", "b"); ok {
+		t.Fatal("expected the oldest finished buffer to be evicted")
+	}
+	if _, ok := hub.Get("owner", "a"); !ok {
+		t.Fatal("expected the newer finished buffer to stay replayable")
+	}
+}
+
+func TestHubRejectsNewStreamWhileAllBuffersLive(t *testing.T) {
+	hub := NewHub(time.Minute, 0, 1)
+	startBuffer(t, hub, "owner", "a")
+	if _, err := hub.Start("owner", "b"); !errors.Is(err, ErrHubFull) {
+		t.Fatalf("expected ErrHubFull, got %v", err)
+	}
+	replaced, err := hub.Start("owner", "a")
+	if err != nil {
+		t.Fatalf("replacing a stream must not count against the cap: %v", err)
+	}
+	replaced.Close()
+	if _, err := hub.Start("other", "b"); err != nil {
+		t.Fatalf("expected room once the live buffer finished: %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package testsuite
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"ds2api/internal/deepseek/mockupstream"
+)
+
+// The cases below need an upstream scenario armed on the mock; runCase
+// queues c.Scenario before they start and skips them in live mode.
+
+func (r *Runner) upstreamChat(ctx context.Context, cc *caseContext, prompt string, stream bool) (*responseResult, error) {
+	return cc.request(ctx, requestSpec{
+		Method: http.MethodPost,
+		Path:   "/v1/chat/completions",
+		Headers: map[string]string{
+			"Authorization": "Bearer " + r.apiKey,
+		},
+		Body: map[string]any{
+			"model": "deepseek-v4-flash",
+			"messages": []map[string]any{
+				{"role": "user", "content": prompt},
+			},
+			"stream": stream,
+		},
+		Stream:    stream,
+		Retryable: false,
+	})
+}
+
+func (r *Runner) mockStats() mockupstream.Stats {
+	if r.mock == nil {
+		return mockupstream.Stats{}
+	}
+	return r.mock.Stats()
+}
+
+func (r *Runner) caseUpstreamSlowFirstByte(ctx context.Context, cc *caseContext) error {
+	before := r.mockStats()
+	start := time.Now()
+	resp, err := r.upstreamChat(ctx, cc, "slow first byte", true)
+	if err != nil {
+		return err
+	}
+	elapsed := time.Since(start)
+	cc.assert("status_200", resp.StatusCode == http.StatusOK, fmt.Sprintf("status=%d", resp.StatusCode))
+	_, done := parseSSEFrames(resp.Body)
+	cc.assert("done_terminated", done, "expected [DONE]")
+	cc.assert("waited_for_first_byte", elapsed >= offlineSlowFirstByte, fmt.Sprintf("elapsed=%s", elapsed))
+	cc.assert("scenario_applied", r.mockStats().ScenariosApplied > before.ScenariosApplied, "slow_first_byte was not consumed")
+	return nil
+}
+
+func (r *Runner) caseUpstreamMidstreamDisconnect(ctx context.Context, cc *caseContext) error {
+	before := r.mockStats()
+	resp, err := r.upstreamChat(ctx, cc, "midstream disconnect", true)
+	if err != nil {
+		return err
+	}
+	cc.assert("status_200", resp.StatusCode == http.StatusOK, fmt.Sprintf("status=%d", resp.StatusCode))
+	frames, done := parseSSEFrames(resp.Body)
+	hasError := false
+	for _, f := range frames {
+		if _, ok := f["error"]; ok {
+			hasError = true
+			break
+		}
+	}
+	cc.assert("stream_terminated", done || hasError, fmt.Sprintf("done=%v error_frame=%v", done, hasError))
+	cc.assert("scenario_applied", r.mockStats().ScenariosApplied > before.ScenariosApplied, "disconnect was not consumed")
+
+	deadline := time.Now().Add(10 * time.Second)
+	inUse := -1
+	for time.Now().Before(deadline) {
+		st, err := r.fetchQueueStatus(ctx, cc)
+		if err != nil {
+			return err
+		}
+		inUse = toInt(st["in_use"])
+		if inUse == 0 {
+			break
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	cc.assert("slot_released", inUse == 0, fmt.Sprintf("in_use=%d", inUse))
+	return nil
+}
+
+func (r *Runner) caseUpstreamTokenRefresh(ctx context.Context, cc *caseContext) error {
+	before := r.mockStats()
+	resp, err := r.upstreamChat(ctx, cc, "token refresh after 401", false)
+	if err != nil {
+		return err
+	}
+	after := r.mockStats()
+	cc.assert("status_200", resp.StatusCode == http.StatusOK, fmt.Sprintf("status=%d body=%s", resp.StatusCode, string(resp.Body)))
+	cc.assert("scenario_applied", after.ScenariosApplied > before.ScenariosApplied, "token_expired was not consumed")
+	cc.assert("relogin_after_401", after.Logins > before.Logins, fmt.Sprintf("logins before=%d after=%d", before.Logins, after.Logins))
+	return nil
+}
+
+func (r *Runner) caseUpstreamPowFailure(ctx context.Context, cc *caseContext) error {
+	before := r.mockStats()
+	resp, err := r.upstreamChat(ctx, cc, "pow failure retry", false)
+	if err != nil {
+		return err
+	}
+	after := r.mockStats()
+	cc.assert("status_200", resp.StatusCode == http.StatusOK, fmt.Sprintf("status=%d body=%s", resp.StatusCode, string(resp.Body)))
+	cc.assert("scenario_applied", after.ScenariosApplied > before.ScenariosApplied, "pow_failure was not consumed")
+	cc.assert("pow_retried", after.PowChallenges > before.PowChallenges, fmt.Sprintf("pow challenges before=%d after=%d", before.PowChallenges, after.PowChallenges))
+	return nil
+}
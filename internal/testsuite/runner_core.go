@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -11,6 +12,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"ds2api/internal/deepseek/mockupstream"
 )
 
 type Options struct {
@@ -22,6 +25,9 @@ type Options struct {
 	Retries     int
 	NoPreflight bool
 	MaxKeepRuns int
+	// Offline boots ds2api in-process against the mock DeepSeek upstream
+	// with a generated config instead of using real accounts.
+	Offline bool
 }
 
 type runSummary struct {
@@ -37,6 +43,8 @@ type runSummary struct {
 
 type caseResult struct {
 	CaseID       string            `json:"case_id"`
+	Mode         string            `json:"mode"`
+	Scenario     string            `json:"scenario,omitempty"`
 	Passed       bool              `json:"passed"`
 	DurationMS   int64             `json:"duration_ms"`
 	TraceIDs     []string          `json:"trace_ids"`
@@ -119,6 +127,10 @@ type Runner struct {
 	serverCmd   *exec.Cmd
 	serverLogFd *os.File
 
+	mock         *mockupstream.Server
+	mockServer   *httptest.Server
+	inProcServer *http.Server
+
 	configCopyPath     string
 	originalConfigPath string
 	originalConfigHash string
@@ -164,7 +176,11 @@ func Run(ctx context.Context, opts Options) error {
 		}
 	}
 
-	if err := r.prepareConfigIsolation(); err != nil {
+	prepareConfig := r.prepareConfigIsolation
+	if r.opts.Offline {
+		prepareConfig = r.prepareOfflineConfig
+	}
+	if err := prepareConfig(); err != nil {
 		_ = r.writeSummary(start, time.Now())
 		return err
 	}
@@ -249,6 +265,27 @@ func (r *Runner) runCase(ctx context.Context, c caseDef) {
 		startedAt:   time.Now(),
 		traceIDsSet: map[string]struct{}{},
 	}
+	mode := r.caseMode(c)
+	if mode == modeSkipped {
+		cs := caseResult{
+			CaseID:       c.ID,
+			Mode:         mode,
+			Scenario:     c.Scenario,
+			Passed:       true,
+			ArtifactPath: caseDir,
+			Assertions: []assertionResult{{
+				Name:   "requires_offline_scenario",
+				Passed: true,
+				Detail: "needs mock upstream scenario " + c.Scenario + "; run with --offline",
+			}},
+		}
+		_ = cc.flushArtifacts(cs)
+		r.results = append(r.results, cs)
+		return
+	}
+	if c.Scenario != "" && r.mock != nil {
+		r.mock.QueueScenario(c.Scenario)
+	}
 	err := c.Run(ctx, cc)
 	duration := time.Since(cc.startedAt).Milliseconds()
 
@@ -275,6 +312,8 @@ func (r *Runner) runCase(ctx context.Context, c caseDef) {
 	statuses := uniqueStatusCodes(cc.responses)
 	cs := caseResult{
 		CaseID:       c.ID,
+		Mode:         mode,
+		Scenario:     c.Scenario,
 		Passed:       passed,
 		DurationMS:   duration,
 		TraceIDs:     traceIDs,
@@ -164,6 +164,12 @@ func (r *Runner) startServer(ctx context.Context) error {
 		return err
 	}
 	r.serverLogFd = logFd
+	if r.opts.Offline {
+		if err := r.startOfflineServer(port); err != nil {
+			return err
+		}
+		return r.waitReady()
+	}
 	cmd := exec.CommandContext(ctx, "go", "run", "./cmd/ds2api")
 	cmd.Stdout = logFd
 	cmd.Stderr = logFd
@@ -178,7 +184,10 @@ func (r *Runner) startServer(ctx context.Context) error {
 		return err
 	}
 	r.serverCmd = cmd
+	return r.waitReady()
+}
 
+func (r *Runner) waitReady() error {
 	deadline := time.Now().Add(90 * time.Second)
 	for time.Now().Before(deadline) {
 		if r.ping("/healthz") == nil && r.ping("/readyz") == nil {
@@ -191,6 +200,7 @@ func (r *Runner) startServer(ctx context.Context) error {
 
 func (r *Runner) stopServer() error {
 	var errs []string
+	r.stopOfflineServer()
 	if r.serverCmd != nil && r.serverCmd.Process != nil {
 		_ = r.serverCmd.Process.Signal(os.Interrupt)
 		done := make(chan error, 1)
@@ -250,6 +260,10 @@ func (r *Runner) prepareAuth(ctx context.Context) error {
 }
 
 func (r *Runner) ensureOriginalConfigUntouched() error {
+	if r.originalConfigPath == "" {
+		// Offline runs use a generated config.
+		return nil
+	}
 	raw, err := os.ReadFile(r.originalConfigPath)
 	if err != nil {
 		return err
@@ -0,0 +1,156 @@
+package testsuite
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"ds2api/internal/config"
+	"ds2api/internal/deepseek/mockupstream"
+	dsprotocol "ds2api/internal/deepseek/protocol"
+	"ds2api/internal/server"
+)
+
+// Case modes reported in the summary.
+const (
+	modeLive      = "live"
+	modeSimulated = "simulated"
+	modeSkipped   = "skipped"
+)
+
+const (
+	offlineAPIKey        = "offline-testsuite-key"
+	offlineAccountCount  = 2
+	offlineSlowFirstByte = 1500 * time.Millisecond
+	// offlineChunkDelay makes scripted streams last long enough for the
+	// concurrency and abort cases to observe busy slots.
+	offlineChunkDelay  = 40 * time.Millisecond
+	offlineSamplesRoot = "tests/raw_stream_samples"
+)
+
+// offlineConfig builds the config used in offline mode: one API key and a
+// few password accounts that the mock upstream will log in.
+func offlineConfig() ([]byte, error) {
+	accounts := make([]map[string]any, 0, offlineAccountCount)
+	for i := 1; i <= offlineAccountCount; i++ {
+		accounts = append(accounts, map[string]any{
+			"email":    "offline-" + strconv.Itoa(i) + "@mock.ds2api.local",
+			"password": "offline-password",
+		})
+	}
+	return json.MarshalIndent(map[string]any{
+		"keys":     []string{offlineAPIKey},
+		"accounts": accounts,
+	}, "", "  ")
+}
+
+func (r *Runner) prepareOfflineConfig() error {
+	raw, err := offlineConfig()
+	if err != nil {
+		return err
+	}
+	tmpDir := filepath.Join(r.runDir, "tmp")
+	if err := os.MkdirAll(tmpDir, 0o755); err != nil {
+		return err
+	}
+	r.configCopyPath = filepath.Join(tmpDir, "config.json")
+	if err := os.WriteFile(r.configCopyPath, raw, 0o644); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, &r.configRaw); err != nil {
+		return err
+	}
+	r.apiKey = offlineAPIKey
+	r.accountID = r.configRaw.Accounts[0].Email
+	return nil
+}
+
+// startOfflineServer boots the mock upstream and ds2api in this process.
+// ds2api is pointed at the mock through DS2API_UPSTREAM_BASE_URL and logs
+// to server.log like the live subprocess does.
+func (r *Runner) startOfflineServer(port int) error {
+	opts := mockupstream.Options{
+		SlowFirstByte: offlineSlowFirstByte,
+		ChunkDelay:    offlineChunkDelay,
+	}
+	if st, err := os.Stat(offlineSamplesRoot); err == nil && st.IsDir() {
+		opts.SamplesDir = offlineSamplesRoot
+	}
+	mock, err := mockupstream.New(opts)
+	if err != nil {
+		return err
+	}
+	r.mock = mock
+	r.mockServer = httptest.NewServer(mock)
+
+	env := map[string]string{
+		dsprotocol.UpstreamBaseURLEnv: r.mockServer.URL,
+		"DS2API_CONFIG_PATH":          r.configCopyPath,
+		"DS2API_CHAT_HISTORY_PATH":    filepath.Join(r.runDir, "tmp", "chat_history.json"),
+		"DS2API_AUTO_BUILD_WEBUI":     "false",
+	}
+	for k, v := range env {
+		if err := os.Setenv(k, v); err != nil {
+			return err
+		}
+	}
+	if err := os.Unsetenv("DS2API_CONFIG_JSON"); err != nil {
+		return err
+	}
+	config.Logger = slog.New(slog.NewTextHandler(r.serverLogFd, nil))
+
+	app, err := server.NewApp()
+	if err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:"+strconv.Itoa(port))
+	if err != nil {
+		return err
+	}
+	r.inProcServer = &http.Server{Handler: app.Router, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := r.inProcServer.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			config.Logger.Error("offline server stopped unexpectedly", "error", err)
+		}
+	}()
+	return nil
+}
+
+func (r *Runner) stopOfflineServer() {
+	if r.inProcServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		_ = r.inProcServer.Shutdown(ctx)
+		cancel()
+	}
+	if r.mockServer != nil {
+		r.mockServer.CloseClientConnections()
+		r.mockServer.Close()
+	}
+}
+
+// caseMode decides how a case runs. Cases that need an upstream scenario
+// can only run against the mock.
+func (r *Runner) caseMode(c caseDef) string {
+	if r.opts.Offline {
+		return modeSimulated
+	}
+	if c.Scenario != "" {
+		return modeSkipped
+	}
+	return modeLive
+}
+
+func (r *Runner) upstreamDescription() string {
+	if r.mockServer != nil {
+		return r.mockServer.URL + " (mock)"
+	}
+	return dsprotocol.BaseURL()
+}
@@ -1,10 +1,17 @@
 package testsuite
 
-import "context"
+import (
+	"context"
+
+	"ds2api/internal/deepseek/mockupstream"
+)
 
 type caseDef struct {
 	ID  string
 	Run func(context.Context, *caseContext) error
+	// Scenario names the mock upstream scenario the case needs. Such cases
+	// only run with --offline and are reported as skipped otherwise.
+	Scenario string
 }
 
 func (r *Runner) cases() []caseDef {
@@ -39,5 +46,9 @@ func (r *Runner) cases() []caseDef {
 		{ID: "config_write_isolated", Run: r.caseConfigWriteIsolated},
 		{ID: "token_refresh_managed_account", Run: r.caseTokenRefreshManagedAccount},
 		{ID: "error_contract_invalid_key", Run: r.caseInvalidKey},
+		{ID: "upstream_slow_first_byte", Run: r.caseUpstreamSlowFirstByte, Scenario: mockupstream.ScenarioSlowFirstByte},
+		{ID: "upstream_midstream_disconnect", Run: r.caseUpstreamMidstreamDisconnect, Scenario: mockupstream.ScenarioDisconnect},
+		{ID: "upstream_token_refresh_401", Run: r.caseUpstreamTokenRefresh, Scenario: mockupstream.ScenarioTokenExpired},
+		{ID: "upstream_pow_failure_retry", Run: r.caseUpstreamPowFailure, Scenario: mockupstream.ScenarioPowFailure},
 	}
 }
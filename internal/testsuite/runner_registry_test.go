@@ -39,6 +39,10 @@ func TestRunnerCasesRegistryExactSet(t *testing.T) {
 		"config_write_isolated",
 		"token_refresh_managed_account",
 		"error_contract_invalid_key",
+		"upstream_slow_first_byte",
+		"upstream_midstream_disconnect",
+		"upstream_token_refresh_401",
+		"upstream_pow_failure_retry",
 	}
 
 	if len(got) != len(wantIDs) {
@@ -12,13 +12,21 @@ import (
 func (r *Runner) writeSummary(start, end time.Time) error {
 	passed := 0
 	failed := 0
+	modes := map[string]int{}
 	for _, cs := range r.results {
-		if cs.Passed {
+		modes[cs.Mode]++
+		switch {
+		case cs.Mode == modeSkipped:
+		case cs.Passed:
 			passed++
-		} else {
+		default:
 			failed++
 		}
 	}
+	runMode := "live"
+	if r.opts.Offline {
+		runMode = "offline"
+	}
 	summary := runSummary{
 		RunID:      r.runID,
 		StartedAt:  start.Format(time.RFC3339Nano),
@@ -28,11 +36,18 @@ func (r *Runner) writeSummary(start, end time.Time) error {
 			"total":  len(r.results),
 			"passed": passed,
 			"failed": failed,
+			// Per-mode case counts: live, simulated (mock upstream) and
+			// skipped (needs a scenario that only --offline provides).
+			modeLive:      modes[modeLive],
+			modeSimulated: modes[modeSimulated],
+			modeSkipped:   modes[modeSkipped],
 		},
 		Environment: map[string]any{
 			"go_version":      runtime.Version(),
 			"os":              runtime.GOOS,
 			"arch":            runtime.GOARCH,
+			"mode":            runMode,
+			"upstream":        r.upstreamDescription(),
 			"base_url":        r.baseURL,
 			"config_source":   r.originalConfigPath,
 			"config_isolated": r.configCopyPath,
@@ -58,6 +73,8 @@ func (r *Runner) summaryMarkdown(s runSummary) string {
 	fmt.Fprintf(&b, "- Started: `%s`\n", s.StartedAt)
 	fmt.Fprintf(&b, "- Ended: `%s`\n", s.EndedAt)
 	fmt.Fprintf(&b, "- Duration: `%d ms`\n", s.DurationMS)
+	fmt.Fprintf(&b, "- Mode: `%s` (upstream `%s`)\n", s.Environment["mode"], s.Environment["upstream"])
+	fmt.Fprintf(&b, "- Live/Simulated/Skipped: `%d/%d/%d`\n", s.Stats[modeLive], s.Stats[modeSimulated], s.Stats[modeSkipped])
 	fmt.Fprintf(&b, "- Passed/Failed: `%d/%d`\n\n", s.Stats["passed"], s.Stats["failed"])
 	if len(s.Warnings) > 0 {
 		b.WriteString("## Warnings\n\n")
@@ -84,14 +101,21 @@ func (r *Runner) summaryMarkdown(s runSummary) string {
 		b.WriteString("- none\n")
 	}
 	b.WriteString("\n## Case Table\n\n")
-	b.WriteString("| case_id | status | duration_ms | statuses | artifact |\n")
-	b.WriteString("|---|---:|---:|---|---|\n")
+	b.WriteString("| case_id | mode | scenario | status | duration_ms | statuses | artifact |\n")
+	b.WriteString("|---|---|---|---:|---:|---|---|\n")
 	for _, c := range s.Cases {
 		status := "PASS"
-		if !c.Passed {
+		switch {
+		case c.Mode == modeSkipped:
+			status = "SKIP"
+		case !c.Passed:
 			status = "FAIL"
 		}
-		fmt.Fprintf(&b, "| %s | %s | %d | %v | `%s` |\n", c.CaseID, status, c.DurationMS, c.StatusCodes, c.ArtifactPath)
+		scenario := c.Scenario
+		if scenario == "" {
+			scenario = "-"
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %d | %v | `%s` |\n", c.CaseID, c.Mode, scenario, status, c.DurationMS, c.StatusCodes, c.ArtifactPath)
 	}
 	return b.String()
 }
@@ -0,0 +1,29 @@
+package textclean
+
+import "strings"
+
+// StripJSONCodeFence unwraps a reply that was fenced as ```json ... ``` even
+// though plain JSON was requested. Other text is returned trimmed.
+func StripJSONCodeFence(text string) string {
+	trimmed := strings.TrimSpace(text)
+	if !strings.HasPrefix(trimmed, "```") || !strings.HasSuffix(trimmed, "```") || len(trimmed) < 6 {
+		return trimmed
+	}
+	body := strings.TrimSuffix(trimmed[3:], "```")
+	if nl := strings.IndexByte(body, '\n'); nl >= 0 {
+		lang := strings.TrimSpace(body[:nl])
+		if lang == "" || strings.EqualFold(lang, "json") {
+			body = body[nl+1:]
+		}
+	}
+	return strings.TrimSpace(body)
+}
+
+// StripEnumQuotes removes a single pair of quotes around an enum answer.
+func StripEnumQuotes(text string) string {
+	trimmed := strings.TrimSpace(text)
+	if len(trimmed) >= 2 && (trimmed[0] == '"' && trimmed[len(trimmed)-1] == '"' || trimmed[0] == '`' && trimmed[len(trimmed)-1] == '`') {
+		return strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+	}
+	return trimmed
+}
@@ -1,11 +1,23 @@
 package toolstream
 
-import "ds2api/internal/toolcall"
+import (
+	"time"
+
+	"ds2api/internal/toolcall"
+)
 
 func ProcessChunk(state *State, chunk string, toolNames []string) []Event {
 	if state == nil {
 		return nil
 	}
+	defer state.noteWork(time.Now())
+	if chunk != "" {
+		state.chunks++
+	}
+	return processChunk(state, chunk, toolNames)
+}
+
+func processChunk(state *State, chunk string, toolNames []string) []Event {
 	if chunk != "" {
 		state.pending.WriteString(chunk)
 	}
@@ -87,7 +99,8 @@ func Flush(state *State, toolNames []string) []Event {
 	if state == nil {
 		return nil
 	}
-	events := ProcessChunk(state, "", toolNames)
+	defer state.noteWork(time.Now())
+	events := processChunk(state, "", toolNames)
 	if len(state.pendingToolCalls) > 0 {
 		events = append(events, Event{ToolCalls: state.pendingToolCalls})
 		state.pendingToolRaw = ""
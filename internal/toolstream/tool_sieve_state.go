@@ -1,8 +1,12 @@
 package toolstream
 
 import (
+	"context"
 	"ds2api/internal/toolcall"
 	"strings"
+	"time"
+
+	"ds2api/internal/tracing"
 )
 
 type State struct {
@@ -22,6 +26,12 @@ type State struct {
 	toolArgsSent           int
 	toolArgsString         bool
 	toolArgsDone           bool
+
+	// Work timing for the request trace, see RecordSpan.
+	firstWork time.Time
+	lastWork  time.Time
+	busy      time.Duration
+	chunks    int
 }
 
 type Event struct {
@@ -36,6 +46,27 @@ type ToolCallDelta struct {
 	Arguments string
 }
 
+func (s *State) noteWork(start time.Time) {
+	now := time.Now()
+	if s.firstWork.IsZero() {
+		s.firstWork = start
+	}
+	s.lastWork = now
+	s.busy += now.Sub(start)
+}
+
+// RecordSpan reports the sieve on the request trace as a "tool_sieve" span
+// from the first chunk to the flush, with the time actually spent scanning
+// as an attribute.
+func (s *State) RecordSpan(ctx context.Context) {
+	if s == nil || s.firstWork.IsZero() {
+		return
+	}
+	tracing.Record(ctx, "tool_sieve", s.firstWork, s.lastWork,
+		tracing.Int("ds2api.tool_sieve.chunks", s.chunks),
+		tracing.Int("ds2api.tool_sieve.busy_us", int(s.busy.Microseconds())))
+}
+
 func (s *State) resetIncrementalToolState() {
 	s.disableDeltas = false
 	s.toolNameSent = false
@@ -0,0 +1,257 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ds2api/internal/config"
+	"ds2api/internal/version"
+)
+
+const (
+	exportBatchSize = 256
+	exportQueueSize = 4096
+	exportInterval  = 5 * time.Second
+	exportTimeout   = 10 * time.Second
+)
+
+// exporter batches finished spans and posts them as OTLP/HTTP JSON. Spans
+// are dropped, never blocked on, when the queue is full.
+type exporter struct {
+	cfg            Config
+	serviceVersion string
+	client         *http.Client
+	queue          chan *Span
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+	dropped  atomic.Int64
+}
+
+func newExporter(cfg Config) *exporter {
+	serviceVersion, _ := version.Current()
+	e := &exporter{
+		cfg:            cfg,
+		serviceVersion: serviceVersion,
+		client:         &http.Client{Timeout: exportTimeout},
+		queue:          make(chan *Span, exportQueueSize),
+		stop:           make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+func (e *exporter) enqueue(s *Span) {
+	select {
+	case e.queue <- s:
+	default:
+		e.dropped.Add(1)
+	}
+}
+
+func (e *exporter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+	batch := make([]*Span, 0, exportBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.send(batch); err != nil {
+			config.Logger.Warn("[tracing] export failed", "spans", len(batch), "error", err)
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case s := <-e.queue:
+			batch = append(batch, s)
+			if len(batch) >= exportBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+			if n := e.dropped.Swap(0); n > 0 {
+				config.Logger.Warn("[tracing] export queue full, spans dropped", "dropped", n)
+			}
+		case <-e.stop:
+			for {
+				select {
+				case s := <-e.queue:
+					batch = append(batch, s)
+					if len(batch) >= exportBatchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (e *exporter) shutdown(ctx context.Context) error {
+	e.stopOnce.Do(func() { close(e.stop) })
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *exporter) send(spans []*Span) error {
+	body, err := json.Marshal(encodeRequest(e.cfg.ServiceName, e.serviceVersion, spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("collector returned %s", resp.Status)
+	}
+	return nil
+}
+
+// The types below mirror the OTLP protobuf JSON mapping: ids are hex, 64-bit
+// integers are decimal strings.
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Events            []otlpEvent    `json:"events,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string `json:"timeUnixNano"`
+	Name         string `json:"name"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+func encodeRequest(service, serviceVersion string, spans []*Span) otlpRequest {
+	out := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		out = append(out, encodeSpan(s))
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpKeyValue{
+			encodeAttr(String("service.name", service)),
+			encodeAttr(String("service.version", serviceVersion)),
+		}},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "ds2api/internal/tracing"},
+			Spans: out,
+		}},
+	}}}
+}
+
+func encodeSpan(s *Span) otlpSpan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := otlpSpan{
+		TraceID:           s.traceID.String(),
+		SpanID:            s.spanID.String(),
+		Name:              s.name,
+		Kind:              s.kind,
+		StartTimeUnixNano: unixNano(s.start),
+		EndTimeUnixNano:   unixNano(s.end),
+	}
+	if s.parentID.IsValid() {
+		out.ParentSpanID = s.parentID.String()
+	}
+	for _, a := range s.attrs {
+		out.Attributes = append(out.Attributes, encodeAttr(a))
+	}
+	for _, ev := range s.events {
+		out.Events = append(out.Events, otlpEvent{TimeUnixNano: unixNano(ev.at), Name: ev.name})
+	}
+	if s.errored {
+		out.Status = otlpStatus{Code: 2, Message: s.statusMsg}
+	}
+	return out
+}
+
+func encodeAttr(a Attr) otlpKeyValue {
+	var v map[string]any
+	switch x := a.Value.(type) {
+	case string:
+		v = map[string]any{"stringValue": x}
+	case bool:
+		v = map[string]any{"boolValue": x}
+	case int:
+		v = map[string]any{"intValue": strconv.Itoa(x)}
+	case int64:
+		v = map[string]any{"intValue": strconv.FormatInt(x, 10)}
+	case float64:
+		v = map[string]any{"doubleValue": x}
+	default:
+		v = map[string]any{"stringValue": fmt.Sprint(x)}
+	}
+	return otlpKeyValue{Key: a.Key, Value: v}
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
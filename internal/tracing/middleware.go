@@ -0,0 +1,60 @@
+package tracing
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Middleware opens a server span per request, joining the caller's trace
+// when a traceparent header is present. The span gets a "first_byte" event
+// when the response body starts.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx := Extract(r.Context(), r.Header)
+		ctx, span := startSpan(ctx, "HTTP "+r.Method, KindServer, time.Now(), []Attr{
+			String("http.request.method", r.Method),
+			String("url.path", r.URL.Path),
+			String("ds2api.request_id", middleware.GetReqID(r.Context())),
+		})
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		ww.Tee(&firstByteEvent{span: span})
+		defer func() {
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if rc := chi.RouteContext(r.Context()); rc != nil {
+				if pattern := rc.RoutePattern(); pattern != "" {
+					span.SetName("HTTP " + r.Method + " " + pattern)
+					span.SetAttributes(String("http.route", pattern))
+				}
+			}
+			span.SetAttributes(Int("http.response.status_code", status))
+			if status >= http.StatusInternalServerError {
+				span.SetError(http.StatusText(status))
+			}
+			span.End()
+		}()
+		next.ServeHTTP(ww, r.WithContext(ctx))
+	})
+}
+
+type firstByteEvent struct {
+	span *Span
+	seen bool
+}
+
+func (f *firstByteEvent) Write(p []byte) (int, error) {
+	if !f.seen && len(p) > 0 {
+		f.seen = true
+		f.span.AddEvent("first_byte")
+	}
+	return len(p), nil
+}
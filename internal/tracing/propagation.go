@@ -0,0 +1,64 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+const traceparentHeader = "traceparent"
+
+// Extract returns ctx carrying the remote parent from a W3C traceparent
+// header, so the next Start joins the caller's trace. Malformed headers are
+// ignored.
+func Extract(ctx context.Context, h http.Header) context.Context {
+	p := current()
+	if p == nil {
+		return ctx
+	}
+	traceID, spanID, sampled, ok := parseTraceparent(h.Get(traceparentHeader))
+	if !ok {
+		return ctx
+	}
+	return contextWithSpan(ctx, &Span{provider: p, traceID: traceID, spanID: spanID, sampled: sampled, remote: true})
+}
+
+// Traceparent formats the span as a W3C traceparent value.
+func (s *Span) Traceparent() string {
+	if s == nil {
+		return ""
+	}
+	flags := "00"
+	if s.sampled {
+		flags = "01"
+	}
+	return "00-" + s.traceID.String() + "-" + s.spanID.String() + "-" + flags
+}
+
+func parseTraceparent(v string) (TraceID, SpanID, bool, bool) {
+	var traceID TraceID
+	var spanID SpanID
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return traceID, spanID, false, false
+	}
+	// Version 00 has exactly four fields; later versions may append more.
+	if parts[0] == "00" && len(parts) != 4 {
+		return traceID, spanID, false, false
+	}
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return traceID, spanID, false, false
+	}
+	if _, err := hex.Decode(traceID[:], []byte(parts[1])); err != nil || !traceID.IsValid() {
+		return TraceID{}, SpanID{}, false, false
+	}
+	if _, err := hex.Decode(spanID[:], []byte(parts[2])); err != nil || !spanID.IsValid() {
+		return TraceID{}, SpanID{}, false, false
+	}
+	var flags [1]byte
+	if _, err := hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return TraceID{}, SpanID{}, false, false
+	}
+	return traceID, spanID, flags[0]&0x01 == 1, true
+}
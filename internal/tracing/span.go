@@ -0,0 +1,209 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+type TraceID [16]byte
+
+type SpanID [8]byte
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+func (t TraceID) IsValid() bool  { return t != TraceID{} }
+func (s SpanID) String() string  { return hex.EncodeToString(s[:]) }
+func (s SpanID) IsValid() bool   { return s != SpanID{} }
+
+func newTraceID() TraceID {
+	var id TraceID
+	_, _ = rand.Read(id[:])
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	_, _ = rand.Read(id[:])
+	return id
+}
+
+// SpanKind follows the OTLP enum values.
+type SpanKind int
+
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+)
+
+// Attr is a span attribute. Value must be a string, bool, int, int64 or
+// float64.
+type Attr struct {
+	Key   string
+	Value any
+}
+
+func String(key, value string) Attr    { return Attr{Key: key, Value: value} }
+func Int(key string, value int) Attr   { return Attr{Key: key, Value: int64(value)} }
+func Bool(key string, value bool) Attr { return Attr{Key: key, Value: value} }
+
+type event struct {
+	name string
+	at   time.Time
+}
+
+// Span is one timed stage of a request. A nil *Span is valid and does
+// nothing, which is what Start returns while tracing is disabled.
+type Span struct {
+	provider *Provider
+	traceID  TraceID
+	spanID   SpanID
+	parentID SpanID
+	kind     SpanKind
+	sampled  bool
+	remote   bool
+	start    time.Time
+
+	mu        sync.Mutex
+	name      string
+	end       time.Time
+	attrs     []Attr
+	events    []event
+	errored   bool
+	statusMsg string
+	ended     bool
+}
+
+type spanCtxKey struct{}
+
+func contextWithSpan(ctx context.Context, s *Span) context.Context {
+	return context.WithValue(ctx, spanCtxKey{}, s)
+}
+
+// SpanFromContext returns the innermost span on ctx, including a remote
+// parent extracted from traceparent.
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(spanCtxKey{}).(*Span)
+	return s
+}
+
+// TraceIDFromContext returns the hex trace id on ctx, or "".
+func TraceIDFromContext(ctx context.Context) string {
+	if s := SpanFromContext(ctx); s != nil {
+		return s.traceID.String()
+	}
+	return ""
+}
+
+// Start begins a child of the span on ctx, or a new root span.
+func Start(ctx context.Context, name string, attrs ...Attr) (context.Context, *Span) {
+	return startSpan(ctx, name, KindInternal, time.Now(), attrs)
+}
+
+// StartClient is Start for spans that wrap an outbound upstream call.
+func StartClient(ctx context.Context, name string, attrs ...Attr) (context.Context, *Span) {
+	return startSpan(ctx, name, KindClient, time.Now(), attrs)
+}
+
+// Record reports a stage that was timed elsewhere as a finished span.
+func Record(ctx context.Context, name string, start, end time.Time, attrs ...Attr) {
+	_, s := startSpan(ctx, name, KindInternal, start, attrs)
+	s.endAt(end)
+}
+
+func startSpan(ctx context.Context, name string, kind SpanKind, start time.Time, attrs []Attr) (context.Context, *Span) {
+	p := current()
+	if p == nil {
+		return ctx, nil
+	}
+	s := &Span{provider: p, spanID: newSpanID(), kind: kind, start: start, name: name, attrs: attrs}
+	if parent := SpanFromContext(ctx); parent != nil {
+		s.traceID = parent.traceID
+		s.parentID = parent.spanID
+		s.sampled = parent.sampled
+	} else {
+		s.traceID = newTraceID()
+		s.sampled = p.sample(s.traceID)
+	}
+	return contextWithSpan(ctx, s), s
+}
+
+func (s *Span) TraceID() TraceID {
+	if s == nil {
+		return TraceID{}
+	}
+	return s.traceID
+}
+
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.name = name
+	s.mu.Unlock()
+}
+
+func (s *Span) SetAttributes(attrs ...Attr) {
+	if s == nil || !s.sampled {
+		return
+	}
+	s.mu.Lock()
+	s.attrs = append(s.attrs, attrs...)
+	s.mu.Unlock()
+}
+
+// AddEvent records a point in time inside the span, e.g. "first_byte".
+func (s *Span) AddEvent(name string) {
+	if s == nil || !s.sampled {
+		return
+	}
+	s.mu.Lock()
+	s.events = append(s.events, event{name: name, at: time.Now()})
+	s.mu.Unlock()
+}
+
+// RecordError marks the span failed. A nil err is ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.SetError(err.Error())
+}
+
+func (s *Span) SetError(message string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.errored = true
+	s.statusMsg = message
+	s.mu.Unlock()
+}
+
+// End finishes the span and queues it for export. Later calls are ignored.
+func (s *Span) End() {
+	s.endAt(time.Now())
+}
+
+func (s *Span) endAt(end time.Time) {
+	if s == nil || s.remote {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = end
+	s.mu.Unlock()
+	if s.sampled {
+		s.provider.export(s)
+	}
+}
@@ -0,0 +1,137 @@
+// Package tracing records request pipeline stages as spans and exports them
+// to an OpenTelemetry collector over OTLP/HTTP JSON. It is a deliberately
+// small subset of the OpenTelemetry SDK: W3C traceparent propagation,
+// parent-based ratio sampling and a batching exporter.
+package tracing
+
+import (
+	"context"
+	"encoding/binary"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+const defaultServiceName = "ds2api"
+
+type Config struct {
+	// Endpoint is the full OTLP/HTTP traces URL, e.g.
+	// http://collector:4318/v1/traces. Tracing is off when it is empty.
+	Endpoint    string
+	Headers     map[string]string
+	ServiceName string
+	// SampleRatio applies to traces started here; a caller's traceparent
+	// sampled flag always wins.
+	SampleRatio float64
+}
+
+// ConfigFromEnv reads the standard OTEL_* exporter variables.
+func ConfigFromEnv() Config {
+	cfg := Config{
+		ServiceName: strings.TrimSpace(os.Getenv("OTEL_SERVICE_NAME")),
+		SampleRatio: 1,
+	}
+	if strings.EqualFold(strings.TrimSpace(os.Getenv("OTEL_SDK_DISABLED")), "true") {
+		return cfg
+	}
+	if v := strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")); v != "" {
+		cfg.Endpoint = v
+	} else if v := strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")); v != "" {
+		cfg.Endpoint = strings.TrimRight(v, "/") + "/v1/traces"
+	}
+	cfg.Headers = parseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"))
+	if v := strings.TrimSpace(os.Getenv("OTEL_TRACES_SAMPLER_ARG")); v != "" {
+		if ratio, err := strconv.ParseFloat(v, 64); err == nil && ratio >= 0 && ratio <= 1 {
+			cfg.SampleRatio = ratio
+		}
+	}
+	return cfg
+}
+
+// parseHeaders decodes the OTEL "k1=v1,k2=v2" header list.
+func parseHeaders(raw string) map[string]string {
+	out := map[string]string{}
+	for _, pair := range strings.Split(raw, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			continue
+		}
+		if decoded, err := url.QueryUnescape(strings.TrimSpace(v)); err == nil {
+			v = decoded
+		}
+		out[k] = strings.TrimSpace(v)
+	}
+	return out
+}
+
+// Provider owns the exporter for the process.
+type Provider struct {
+	cfg      Config
+	exporter *exporter
+}
+
+var global atomic.Pointer[Provider]
+
+func current() *Provider {
+	return global.Load()
+}
+
+// Enabled reports whether spans are being recorded.
+func Enabled() bool {
+	return current() != nil
+}
+
+// Init installs a process-wide provider for cfg and returns it, or returns
+// nil and leaves tracing off when cfg has no endpoint. A previous provider is
+// shut down.
+func Init(cfg Config) *Provider {
+	if strings.TrimSpace(cfg.Endpoint) == "" {
+		if old := global.Swap(nil); old != nil {
+			_ = old.Shutdown(context.Background())
+		}
+		return nil
+	}
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = defaultServiceName
+	}
+	p := &Provider{cfg: cfg, exporter: newExporter(cfg)}
+	if old := global.Swap(p); old != nil {
+		_ = old.Shutdown(context.Background())
+	}
+	return p
+}
+
+// Shutdown flushes queued spans of the installed provider.
+func Shutdown(ctx context.Context) error {
+	if p := global.Swap(nil); p != nil {
+		return p.Shutdown(ctx)
+	}
+	return nil
+}
+
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	return p.exporter.shutdown(ctx)
+}
+
+// sample keeps a trace when the top 63 bits of its id fall under the ratio,
+// so every service sampling the same trace id agrees.
+func (p *Provider) sample(id TraceID) bool {
+	switch {
+	case p.cfg.SampleRatio >= 1:
+		return true
+	case p.cfg.SampleRatio <= 0:
+		return false
+	}
+	bound := uint64(p.cfg.SampleRatio * (1 << 63))
+	return binary.BigEndian.Uint64(id[8:])>>1 < bound
+}
+
+func (p *Provider) export(s *Span) {
+	p.exporter.enqueue(s)
+}
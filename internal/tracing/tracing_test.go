@@ -0,0 +1,209 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// collectorStub accepts OTLP/HTTP JSON posts and keeps the decoded spans.
+type collectorStub struct {
+	mu      sync.Mutex
+	headers http.Header
+	spans   []map[string]any
+}
+
+func (c *collectorStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	var req struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []map[string]any `json:"attributes"`
+			} `json:"resource"`
+			ScopeSpans []struct {
+				Spans []map[string]any `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if r.URL.Path != "/v1/traces" || json.Unmarshal(body, &req) != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.headers = r.Header.Clone()
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			c.spans = append(c.spans, ss.Spans...)
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (c *collectorStub) byName() map[string]map[string]any {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := map[string]map[string]any{}
+	for _, s := range c.spans {
+		out[s["name"].(string)] = s
+	}
+	return out
+}
+
+func startCollector(t *testing.T) *collectorStub {
+	t.Helper()
+	stub := &collectorStub{}
+	srv := httptest.NewServer(stub)
+	t.Cleanup(srv.Close)
+	Init(Config{Endpoint: srv.URL + "/v1/traces", Headers: map[string]string{"X-Api-Key": "secret"}, SampleRatio: 1})
+	t.Cleanup(func() { _ = Shutdown(context.Background()) })
+	return stub
+}
+
+func TestParseTraceparent(t *testing.T) {
+	cases := []struct {
+		in      string
+		ok      bool
+		sampled bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true, false},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false, false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01", false, false},
+		{"", false, false},
+	}
+	for _, tc := range cases {
+		_, _, sampled, ok := parseTraceparent(tc.in)
+		if ok != tc.ok || sampled != tc.sampled {
+			t.Fatalf("parseTraceparent(%q) = sampled %v ok %v, want %v %v", tc.in, sampled, ok, tc.sampled, tc.ok)
+		}
+	}
+}
+
+func TestMiddlewareJoinsCallerTraceAndExports(t *testing.T) {
+	stub := startCollector(t)
+	var innerTrace string
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := Start(r.Context(), "deepseek.create_session", Bool("ds2api.warm_pool_hit", true))
+		innerTrace = TraceIDFromContext(ctx)
+		span.End()
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	if innerTrace != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("child span did not join caller trace: %q", innerTrace)
+	}
+	spans := stub.byName()
+	server, ok := spans["HTTP POST"]
+	if !ok {
+		t.Fatalf("server span not exported: %v", spans)
+	}
+	child := spans["deepseek.create_session"]
+	if server["traceId"] != "4bf92f3577b34da6a3ce929d0e0e4736" || server["parentSpanId"] != "00f067aa0ba902b7" {
+		t.Fatalf("server span not parented to traceparent: %v", server)
+	}
+	if child["parentSpanId"] != server["spanId"] || child["traceId"] != server["traceId"] {
+		t.Fatalf("child span not parented to server span: child=%v server=%v", child, server)
+	}
+	if server["kind"] != float64(KindServer) {
+		t.Fatalf("unexpected server kind: %v", server["kind"])
+	}
+	events, _ := server["events"].([]any)
+	if len(events) != 1 || events[0].(map[string]any)["name"] != "first_byte" {
+		t.Fatalf("expected first_byte event, got %v", server["events"])
+	}
+	if stub.headers.Get("X-Api-Key") != "secret" {
+		t.Fatalf("exporter headers not sent: %v", stub.headers)
+	}
+}
+
+func TestUnsampledParentIsNotExported(t *testing.T) {
+	stub := startCollector(t)
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, span := Start(r.Context(), "tool_sieve")
+		span.End()
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	_ = Shutdown(context.Background())
+	if spans := stub.byName(); len(spans) != 0 {
+		t.Fatalf("expected no exported spans, got %v", spans)
+	}
+}
+
+func TestServerErrorMarksSpanAndRecordIsRetroactive(t *testing.T) {
+	stub := startCollector(t)
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, span := Start(r.Context(), "auth.refresh_token")
+		span.RecordError(io.ErrUnexpectedEOF)
+		span.End()
+		span.End()
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/models", nil))
+	_ = Shutdown(context.Background())
+
+	spans := stub.byName()
+	if len(stub.spans) != 2 {
+		t.Fatalf("expected 2 spans (End is idempotent), got %d", len(stub.spans))
+	}
+	status, _ := spans["auth.refresh_token"]["status"].(map[string]any)
+	if status["code"] != float64(2) || status["message"] != io.ErrUnexpectedEOF.Error() {
+		t.Fatalf("unexpected child status: %v", status)
+	}
+	status, _ = spans["HTTP GET"]["status"].(map[string]any)
+	if status["code"] != float64(2) {
+		t.Fatalf("5xx response should mark the server span failed: %v", status)
+	}
+}
+
+func TestDisabledTracingIsNoop(t *testing.T) {
+	if p := Init(Config{}); p != nil {
+		t.Fatal("expected nil provider without endpoint")
+	}
+	ctx, span := Start(context.Background(), "noop")
+	span.SetAttributes(String("k", "v"))
+	span.End()
+	if span != nil || TraceIDFromContext(ctx) != "" {
+		t.Fatal("expected no span while tracing is disabled")
+	}
+	if got := Extract(context.Background(), http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}); SpanFromContext(got) != nil {
+		t.Fatal("expected Extract to be a no-op while tracing is disabled")
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://collector:4318/")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "authorization=Bearer%20abc, x-tenant = t1")
+	t.Setenv("OTEL_TRACES_SAMPLER_ARG", "0.25")
+	cfg := ConfigFromEnv()
+	if cfg.Endpoint != "http://collector:4318/v1/traces" {
+		t.Fatalf("endpoint = %q", cfg.Endpoint)
+	}
+	if cfg.Headers["authorization"] != "Bearer abc" || cfg.Headers["x-tenant"] != "t1" {
+		t.Fatalf("headers = %v", cfg.Headers)
+	}
+	if cfg.SampleRatio != 0.25 {
+		t.Fatalf("sample ratio = %v", cfg.SampleRatio)
+	}
+	t.Setenv("OTEL_SDK_DISABLED", "true")
+	if cfg := ConfigFromEnv(); cfg.Endpoint != "" {
+		t.Fatalf("OTEL_SDK_DISABLED should clear the endpoint, got %q", cfg.Endpoint)
+	}
+}
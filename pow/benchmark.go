@@ -0,0 +1,84 @@
+package pow
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// BenchmarkResult 是一次本机算力测试的结果。
+type BenchmarkResult struct {
+	Algorithm       string  `json:"algorithm"`
+	Difficulty      int64   `json:"difficulty"`
+	Rounds          int     `json:"rounds"`
+	Parallel        int     `json:"parallel"`
+	Hashes          int64   `json:"hashes"`
+	DurationMS      int64   `json:"duration_ms"`
+	HashesPerSecond float64 `json:"hashes_per_second"`
+	// PerWorkerHashesPerSecond 是单个 goroutine 的平均算力。
+	PerWorkerHashesPerSecond float64 `json:"per_worker_hashes_per_second"`
+}
+
+// Benchmark 用 parallel 个 goroutine 各求解 rounds 个答案为 difficulty-1 的
+// challenge（即每次都遍历完整区间），统计本机 hashes/s。
+func Benchmark(ctx context.Context, algorithm string, difficulty int64, rounds, parallel int) (BenchmarkResult, error) {
+	a, ok := Lookup(algorithm)
+	if !ok {
+		return BenchmarkResult{}, unsupported(algorithm)
+	}
+	if a.Hash == nil {
+		return BenchmarkResult{}, errors.New("pow: algorithm " + algorithm + " has no hash function to benchmark")
+	}
+	if difficulty <= 0 {
+		difficulty = DefaultDifficulty
+	}
+	rounds = max(rounds, 1)
+	parallel = max(parallel, 1)
+
+	const salt, expireAt = "benchmarksalt", int64(1712345678000)
+	target := a.Hash([]byte(BuildPrefix(salt, expireAt) + strconv.FormatInt(difficulty-1, 10)))
+	c := &Challenge{
+		Algorithm:  algorithm,
+		Challenge:  hex.EncodeToString(target),
+		Salt:       salt,
+		ExpireAt:   expireAt,
+		Difficulty: difficulty,
+	}
+
+	errs := make([]error, parallel)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < parallel; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				if _, err := a.Solve(ctx, c); err != nil {
+					errs[w] = err
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	if err := errors.Join(errs...); err != nil {
+		return BenchmarkResult{}, err
+	}
+
+	hashes := difficulty * int64(rounds) * int64(parallel)
+	rate := float64(hashes) / elapsed.Seconds()
+	return BenchmarkResult{
+		Algorithm:                algorithm,
+		Difficulty:               difficulty,
+		Rounds:                   rounds,
+		Parallel:                 parallel,
+		Hashes:                   hashes,
+		DurationMS:               elapsed.Milliseconds(),
+		HashesPerSecond:          rate,
+		PerWorkerHashesPerSecond: rate / float64(parallel),
+	}, nil
+}
@@ -131,17 +131,27 @@ func BuildPowHeader(c *Challenge, answer int64) (string, error) {
 }
 
 // SolveAndBuildHeader 端到端: Challenge → x-ds-pow-response header string。
+// 求解算法按 c.Algorithm 从注册表中查找。
 func SolveAndBuildHeader(ctx context.Context, c *Challenge) (string, error) {
-	if c.Algorithm != "DeepSeekHashV1" {
-		return "", errors.New("pow: unsupported algorithm: " + c.Algorithm)
-	}
-	d := c.Difficulty
-	if d == 0 {
-		d = 144000
-	}
-	answer, err := SolvePow(ctx, c.Challenge, c.Salt, c.ExpireAt, d)
+	answer, err := Solve(ctx, c)
 	if err != nil {
 		return "", err
 	}
 	return BuildPowHeader(c, answer)
 }
+
+// AlgorithmDeepSeekHashV1 是当前上游唯一使用的 PoW 算法名。
+const AlgorithmDeepSeekHashV1 = "DeepSeekHashV1"
+
+func init() {
+	Register(Algorithm{
+		Name: AlgorithmDeepSeekHashV1,
+		Hash: func(data []byte) []byte {
+			h := DeepSeekHashV1(data)
+			return h[:]
+		},
+		Solve: func(ctx context.Context, c *Challenge) (int64, error) {
+			return SolvePow(ctx, c.Challenge, c.Salt, c.ExpireAt, c.Difficulty)
+		},
+	})
+}
@@ -0,0 +1,77 @@
+package pow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// DefaultDifficulty 是 challenge 未给出 difficulty 时使用的搜索上限。
+const DefaultDifficulty = 144000
+
+// ErrUnsupportedAlgorithm 表示 Challenge.Algorithm 没有注册求解器。
+var ErrUnsupportedAlgorithm = errors.New("pow: unsupported algorithm")
+
+// Algorithm 描述一种 PoW 方案。Hash 计算 challenge 所比对的摘要（用于基准测试
+// 构造已知答案），Solve 在 [0, c.Difficulty) 内搜索 answer。
+type Algorithm struct {
+	Name  string
+	Hash  func(data []byte) []byte
+	Solve func(ctx context.Context, c *Challenge) (int64, error)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Algorithm{}
+)
+
+// Register 按 Name 注册（或替换）一种算法。上游新增方案时只需在此注册，
+// 不必改动 client。
+func Register(a Algorithm) {
+	if a.Name == "" || a.Solve == nil {
+		panic("pow: Register requires a name and a solver")
+	}
+	registryMu.Lock()
+	registry[a.Name] = a
+	registryMu.Unlock()
+}
+
+// Lookup 返回已注册的算法。
+func Lookup(name string) (Algorithm, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	a, ok := registry[name]
+	return a, ok
+}
+
+// Algorithms 返回已注册算法名（已排序）。
+func Algorithms() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Solve 按 c.Algorithm 选择求解器，difficulty 为 0 时使用 DefaultDifficulty。
+func Solve(ctx context.Context, c *Challenge) (int64, error) {
+	a, ok := Lookup(c.Algorithm)
+	if !ok {
+		return 0, unsupported(c.Algorithm)
+	}
+	if c.Difficulty == 0 {
+		cc := *c
+		cc.Difficulty = DefaultDifficulty
+		c = &cc
+	}
+	return a.Solve(ctx, c)
+}
+
+func unsupported(name string) error {
+	return fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, name)
+}
@@ -0,0 +1,107 @@
+package pow
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"time"
+)
+
+const (
+	// MinHashRate 是估算求解超时所用的保守算力下限 (hashes/s)。
+	MinHashRate = 20000
+	// solveTimeoutBase 是在 difficulty 估算之外额外给出的余量。
+	solveTimeoutBase = 5 * time.Second
+)
+
+// ErrSolveTimeout 表示求解超过了按 difficulty 估算的时限。
+var ErrSolveTimeout = errors.New("pow: solve timed out")
+
+// SolveTimeout 按 difficulty 估算单次求解时限：遍历整个区间在 MinHashRate
+// 下所需的时间，再加固定余量。
+func SolveTimeout(difficulty int64) time.Duration {
+	if difficulty <= 0 {
+		difficulty = DefaultDifficulty
+	}
+	return solveTimeoutBase + time.Duration(float64(difficulty)/MinHashRate*float64(time.Second))
+}
+
+// WorkerPool 限制同时进行的求解数量。突发请求在 Solve 中排队等待空闲 worker，
+// 等待与求解都跟随调用方 ctx 取消。
+type WorkerPool struct {
+	slots chan struct{}
+
+	mu    sync.Mutex
+	stats WorkerPoolStats
+}
+
+// WorkerPoolStats 是 WorkerPool 的运行统计。
+type WorkerPoolStats struct {
+	Workers  int   `json:"workers"`
+	Active   int   `json:"active"`
+	Waiting  int   `json:"waiting"`
+	Solved   int64 `json:"solved"`
+	Failed   int64 `json:"failed"`
+	TimedOut int64 `json:"timed_out"`
+	Canceled int64 `json:"canceled"`
+}
+
+// NewWorkerPool 创建最多 workers 个并发求解的池；workers <= 0 时取 CPU 核数。
+func NewWorkerPool(workers int) *WorkerPool {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	return &WorkerPool{
+		slots: make(chan struct{}, workers),
+		stats: WorkerPoolStats{Workers: workers},
+	}
+}
+
+// Solve 等待空闲 worker 后求解 c，求解时限为 SolveTimeout(c.Difficulty)。
+func (p *WorkerPool) Solve(ctx context.Context, c *Challenge) (int64, error) {
+	if _, ok := Lookup(c.Algorithm); !ok {
+		return 0, unsupported(c.Algorithm)
+	}
+	p.update(func(s *WorkerPoolStats) { s.Waiting++ })
+	select {
+	case p.slots <- struct{}{}:
+		p.update(func(s *WorkerPoolStats) { s.Waiting--; s.Active++ })
+	case <-ctx.Done():
+		p.update(func(s *WorkerPoolStats) { s.Waiting--; s.Canceled++ })
+		return 0, ctx.Err()
+	}
+	defer func() {
+		<-p.slots
+		p.update(func(s *WorkerPoolStats) { s.Active-- })
+	}()
+
+	solveCtx, cancel := context.WithTimeout(ctx, SolveTimeout(c.Difficulty))
+	defer cancel()
+	answer, err := Solve(solveCtx, c)
+	switch {
+	case err == nil:
+		p.update(func(s *WorkerPoolStats) { s.Solved++ })
+	case ctx.Err() != nil:
+		p.update(func(s *WorkerPoolStats) { s.Canceled++ })
+	case errors.Is(err, context.DeadlineExceeded):
+		p.update(func(s *WorkerPoolStats) { s.TimedOut++ })
+		err = ErrSolveTimeout
+	default:
+		p.update(func(s *WorkerPoolStats) { s.Failed++ })
+	}
+	return answer, err
+}
+
+// Stats 返回当前统计快照。
+func (p *WorkerPool) Stats() WorkerPoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stats
+}
+
+func (p *WorkerPool) update(fn func(*WorkerPoolStats)) {
+	p.mu.Lock()
+	fn(&p.stats)
+	p.mu.Unlock()
+}
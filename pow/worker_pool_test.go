@@ -0,0 +1,94 @@
+package pow
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func registerTestAlgorithm(t *testing.T, name string, solve func(context.Context, *Challenge) (int64, error)) {
+	t.Helper()
+	Register(Algorithm{Name: name, Solve: solve})
+	t.Cleanup(func() {
+		registryMu.Lock()
+		delete(registry, name)
+		registryMu.Unlock()
+	})
+}
+
+func TestSolveDispatchesByAlgorithm(t *testing.T) {
+	registerTestAlgorithm(t, "TestAlgoV9", func(_ context.Context, c *Challenge) (int64, error) {
+		return c.Difficulty, nil
+	})
+	got, err := Solve(context.Background(), &Challenge{Algorithm: "TestAlgoV9"})
+	if err != nil || got != DefaultDifficulty {
+		t.Fatalf("expected registered solver with default difficulty, got %d %v", got, err)
+	}
+	if _, err := Solve(context.Background(), &Challenge{Algorithm: "Nope"}); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Fatalf("expected ErrUnsupportedAlgorithm, got %v", err)
+	}
+}
+
+func TestWorkerPoolBoundsConcurrencyAndHonorsCancellation(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	registerTestAlgorithm(t, "TestBlocking", func(ctx context.Context, _ *Challenge) (int64, error) {
+		started <- struct{}{}
+		select {
+		case <-release:
+			return 7, nil
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	})
+	p := NewWorkerPool(1)
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.Solve(context.Background(), &Challenge{Algorithm: "TestBlocking", Difficulty: 1})
+		done <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := p.Solve(ctx, &Challenge{Algorithm: "TestBlocking", Difficulty: 1}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected queued solve to give up with its context, got %v", err)
+	}
+	if len(started) != 0 {
+		t.Fatal("second solve must not start while the only worker is busy")
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("first solve: %v", err)
+	}
+	if s := p.Stats(); s.Solved != 1 || s.Canceled != 1 || s.Active != 0 || s.Waiting != 0 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+}
+
+func TestWorkerPoolReportsSolveTimeout(t *testing.T) {
+	registerTestAlgorithm(t, "TestDeadline", func(context.Context, *Challenge) (int64, error) {
+		return 0, context.DeadlineExceeded
+	})
+	p := NewWorkerPool(1)
+	if _, err := p.Solve(context.Background(), &Challenge{Algorithm: "TestDeadline", Difficulty: 1}); !errors.Is(err, ErrSolveTimeout) {
+		t.Fatalf("expected ErrSolveTimeout, got %v", err)
+	}
+	if p.Stats().TimedOut != 1 {
+		t.Fatalf("unexpected stats: %+v", p.Stats())
+	}
+	if SolveTimeout(2*DefaultDifficulty) <= SolveTimeout(DefaultDifficulty) {
+		t.Fatal("expected timeout to grow with difficulty")
+	}
+}
+
+func TestBenchmarkReportsHashRate(t *testing.T) {
+	res, err := Benchmark(context.Background(), AlgorithmDeepSeekHashV1, 2000, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Hashes != 4000 || res.HashesPerSecond <= 0 || res.PerWorkerHashesPerSecond <= 0 {
+		t.Fatalf("unexpected benchmark result: %+v", res)
+	}
+}
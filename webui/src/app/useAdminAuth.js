@@ -33,7 +33,26 @@ export function useAdminAuth({ isProduction, location, t }) {
             return
         }
 
+        const consumeSSORedirect = () => {
+            const hash = window.location.hash.replace(/^#/, '')
+            if (!hash.includes('oidc_')) return
+            const params = new URLSearchParams(hash)
+            window.history.replaceState(null, '', window.location.pathname + window.location.search)
+            const ssoError = params.get('oidc_error')
+            if (ssoError) {
+                showMessage('error', t('login.ssoFailed', { error: ssoError }))
+                return
+            }
+            const ssoToken = params.get('oidc_token')
+            if (ssoToken) {
+                const expiresIn = parseInt(params.get('expires_in') || '0')
+                localStorage.setItem('ds2api_token', ssoToken)
+                localStorage.setItem('ds2api_token_expires', Date.now() + expiresIn * 1000)
+            }
+        }
+
         const checkAuth = async () => {
+            consumeSSORedirect()
             const storedToken = localStorage.getItem('ds2api_token') || sessionStorage.getItem('ds2api_token')
             const expiresAt = parseInt(localStorage.getItem('ds2api_token_expires') || sessionStorage.getItem('ds2api_token_expires') || '0')
 
@@ -55,7 +74,7 @@ export function useAdminAuth({ isProduction, location, t }) {
         }
 
         checkAuth()
-    }, [handleLogout, isAdminRoute, t])
+    }, [handleLogout, isAdminRoute, showMessage, t])
 
     return {
         token,
@@ -1,14 +1,27 @@
-import { useState } from 'react'
-import { Key, ArrowRight, ShieldCheck, Lock, Check } from 'lucide-react'
+import { useEffect, useState } from 'react'
+import { Key, ArrowRight, ShieldCheck, Lock, Check, User } from 'lucide-react'
 import clsx from 'clsx'
 import { useI18n } from '../i18n'
 import LanguageToggle from './LanguageToggle'
 
 export default function Login({ onLogin, onMessage }) {
     const { t } = useI18n()
+    const [username, setUsername] = useState('')
     const [adminKey, setAdminKey] = useState('')
     const [loading, setLoading] = useState(false)
     const [remember, setRemember] = useState(true)
+    const [sso, setSSO] = useState(null)
+
+    useEffect(() => {
+        let cancelled = false
+        fetch('/admin/oidc/config')
+            .then(res => (res.ok ? res.json() : null))
+            .then(data => {
+                if (!cancelled && data?.enabled) setSSO(data)
+            })
+            .catch(() => {})
+        return () => { cancelled = true }
+    }, [])
 
     const handleLogin = async (e) => {
         e.preventDefault()
@@ -20,7 +33,9 @@ export default function Login({ onLogin, onMessage }) {
             const res = await fetch('/admin/login', {
                 method: 'POST',
                 headers: { 'Content-Type': 'application/json' },
-                body: JSON.stringify({ admin_key: adminKey }),
+                body: JSON.stringify(username.trim()
+                    ? { username: username.trim(), password: adminKey }
+                    : { admin_key: adminKey }),
             })
 
             const data = await res.json()
@@ -62,7 +77,25 @@ export default function Login({ onLogin, onMessage }) {
 
                     <form onSubmit={handleLogin} className="space-y-5 animate-in fade-in slide-in-from-bottom-4 duration-700 delay-150">
                         <div className="space-y-2">
-                            <label className="text-xs font-semibold text-muted-foreground uppercase tracking-widest ml-1">{t('login.adminKeyLabel')}</label>
+                            <label className="text-xs font-semibold text-muted-foreground uppercase tracking-widest ml-1">{t('login.usernameLabel')}</label>
+                            <div className="relative group">
+                                <div className="absolute inset-y-0 left-0 pl-3.5 flex items-center pointer-events-none text-muted-foreground group-focus-within:text-primary transition-colors">
+                                    <User className="w-4 h-4" />
+                                </div>
+                                <input
+                                    type="text"
+                                    autoComplete="username"
+                                    className="w-full bg-[#09090b] border border-border rounded-xl pl-10 pr-4 py-3 text-sm focus:ring-2 focus:ring-primary/20 focus:border-primary transition-all placeholder:text-muted-foreground/30 text-foreground"
+                                    placeholder={t('login.usernamePlaceholder')}
+                                    value={username}
+                                    onChange={e => setUsername(e.target.value)}
+                                    autoFocus
+                                />
+                            </div>
+                        </div>
+
+                        <div className="space-y-2">
+                            <label className="text-xs font-semibold text-muted-foreground uppercase tracking-widest ml-1">{username.trim() ? t('login.passwordLabel') : t('login.adminKeyLabel')}</label>
                             <div className="relative group">
                                 <div className="absolute inset-y-0 left-0 pl-3.5 flex items-center pointer-events-none text-muted-foreground group-focus-within:text-primary transition-colors">
                                     <Key className="w-4 h-4" />
@@ -73,7 +106,6 @@ export default function Login({ onLogin, onMessage }) {
                                     placeholder={t('login.adminKeyPlaceholder')}
                                     value={adminKey}
                                     onChange={e => setAdminKey(e.target.value)}
-                                    autoFocus
                                 />
                             </div>
                         </div>
@@ -110,6 +142,23 @@ export default function Login({ onLogin, onMessage }) {
                         </button>
                     </form>
 
+                    {sso && (
+                        <div className="mt-5 space-y-3">
+                            <div className="flex items-center gap-3 text-[10px] uppercase tracking-widest text-muted-foreground/60">
+                                <div className="flex-1 h-px bg-border" />
+                                <span>{t('login.or')}</span>
+                                <div className="flex-1 h-px bg-border" />
+                            </div>
+                            <a
+                                href={sso.login_url}
+                                className="w-full h-12 flex items-center justify-center gap-2 bg-secondary text-foreground border border-border rounded-xl hover:bg-secondary/80 transition-all font-semibold text-sm"
+                            >
+                                <ShieldCheck className="w-4 h-4" />
+                                <span>{t('login.ssoSignIn', { name: sso.display_name })}</span>
+                            </a>
+                        </div>
+                    )}
+
                     <div className="mt-6 pt-6 border-t border-border flex justify-center">
                         <div className="flex items-center gap-1.5 text-[10px] text-muted-foreground/60 font-medium tracking-wide uppercase">
                             <ShieldCheck className="w-3 h-3" />
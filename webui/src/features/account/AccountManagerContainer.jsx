@@ -1,7 +1,9 @@
 import { useI18n } from '../../i18n'
 import { useAccountsData } from './useAccountsData'
 import { useAccountActions } from './useAccountActions'
+import { useInflightRequests } from './useInflightRequests'
 import QueueCards from './QueueCards'
+import InflightRequestsPanel from './InflightRequestsPanel'
 import ApiKeysPanel from './ApiKeysPanel'
 import AccountsTable from './AccountsTable'
 import AddKeyModal from './AddKeyModal'
@@ -29,6 +31,8 @@ export default function AccountManagerContainer({ config, onRefresh, onMessage,
         handleSearchChange,
     } = useAccountsData({ apiFetch })
 
+    const { requests, cancelling, cancelRequest } = useInflightRequests({ apiFetch, t, onMessage })
+
     const {
         showAddKey,
         openAddKey,
@@ -101,6 +105,13 @@ export default function AccountManagerContainer({ config, onRefresh, onMessage,
 
             <QueueCards queueStatus={queueStatus} t={t} />
 
+            <InflightRequestsPanel
+                t={t}
+                requests={requests}
+                cancelling={cancelling}
+                onCancel={cancelRequest}
+            />
+
             <ApiKeysPanel
                 t={t}
                 config={config}
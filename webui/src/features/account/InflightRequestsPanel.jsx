@@ -0,0 +1,75 @@
+import { Activity, XCircle } from 'lucide-react'
+
+function formatElapsed(ms) {
+    const seconds = Math.floor((ms || 0) / 1000)
+    if (seconds < 60) return `${seconds}s`
+    return `${Math.floor(seconds / 60)}m ${seconds % 60}s`
+}
+
+function formatBytes(bytes) {
+    if (!bytes) return '0 B'
+    if (bytes < 1024) return `${bytes} B`
+    if (bytes < 1024 * 1024) return `${(bytes / 1024).toFixed(1)} KB`
+    return `${(bytes / 1024 / 1024).toFixed(1)} MB`
+}
+
+export default function InflightRequestsPanel({ t, requests, cancelling, onCancel }) {
+    return (
+        <div className="bg-card border border-border rounded-xl overflow-hidden shadow-sm">
+            <div className="p-6 border-b border-border flex items-center gap-3">
+                <Activity className="w-5 h-5 text-muted-foreground" />
+                <div>
+                    <h2 className="text-lg font-semibold">{t('accountManager.inflightTitle', { count: requests.length })}</h2>
+                    <p className="text-sm text-muted-foreground">{t('accountManager.inflightDesc')}</p>
+                </div>
+            </div>
+            {requests.length === 0 ? (
+                <p className="p-6 text-sm text-muted-foreground">{t('accountManager.inflightEmpty')}</p>
+            ) : (
+                <div className="overflow-x-auto">
+                    <table className="w-full text-sm">
+                        <thead className="bg-muted/50 text-xs uppercase tracking-wider text-muted-foreground">
+                            <tr>
+                                <th className="px-4 py-2 text-left font-medium">{t('accountManager.inflightColumnRequest')}</th>
+                                <th className="px-4 py-2 text-left font-medium">{t('accountManager.inflightColumnAccount')}</th>
+                                <th className="px-4 py-2 text-left font-medium">{t('accountManager.inflightColumnStage')}</th>
+                                <th className="px-4 py-2 text-right font-medium">{t('accountManager.inflightColumnElapsed')}</th>
+                                <th className="px-4 py-2 text-right font-medium">{t('accountManager.inflightColumnOutput')}</th>
+                                <th className="px-4 py-2" />
+                            </tr>
+                        </thead>
+                        <tbody className="divide-y divide-border">
+                            {requests.map(req => (
+                                <tr key={req.id}>
+                                    <td className="px-4 py-2">
+                                        <div className="font-medium">{req.requested_model || req.path}</div>
+                                        <div className="text-xs text-muted-foreground">
+                                            {[req.surface, req.stream ? 'stream' : '', req.caller_id].filter(Boolean).join(' · ')}
+                                        </div>
+                                    </td>
+                                    <td className="px-4 py-2 font-mono text-xs">{req.account_id || '-'}</td>
+                                    <td className="px-4 py-2 text-xs">{req.cancelling ? t('accountManager.inflightCancelling') : req.stage}</td>
+                                    <td className="px-4 py-2 text-right tabular-nums">{formatElapsed(req.elapsed_ms)}</td>
+                                    <td className="px-4 py-2 text-right tabular-nums">
+                                        <div>{formatBytes(req.bytes_sent)}</div>
+                                        <div className="text-xs text-muted-foreground">~{req.output_tokens || 0} tokens</div>
+                                    </td>
+                                    <td className="px-4 py-2 text-right">
+                                        <button
+                                            onClick={() => onCancel(req.id)}
+                                            disabled={Boolean(cancelling[req.id] || req.cancelling)}
+                                            className="p-1.5 text-muted-foreground hover:text-destructive hover:bg-destructive/10 rounded-md transition-colors disabled:opacity-50"
+                                            title={t('accountManager.inflightCancel')}
+                                        >
+                                            <XCircle className="w-4 h-4" />
+                                        </button>
+                                    </td>
+                                </tr>
+                            ))}
+                        </tbody>
+                    </table>
+                </div>
+            )}
+        </div>
+    )
+}
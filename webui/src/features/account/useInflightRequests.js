@@ -0,0 +1,73 @@
+import { useEffect, useState } from 'react'
+
+const RECONNECT_DELAY_MS = 3000
+
+// Follows the /admin/requests/stream SSE feed. fetch is used instead of
+// EventSource so the admin Authorization header is sent.
+export function useInflightRequests({ apiFetch, t, onMessage }) {
+    const [requests, setRequests] = useState([])
+    const [cancelling, setCancelling] = useState({})
+
+    useEffect(() => {
+        const controller = new AbortController()
+        let timer = null
+
+        const connect = async () => {
+            try {
+                const res = await apiFetch('/admin/requests/stream', { signal: controller.signal })
+                if (!res.ok || !res.body) throw new Error(`HTTP ${res.status}`)
+                const reader = res.body.getReader()
+                const decoder = new TextDecoder()
+                let buffer = ''
+                while (true) {
+                    const { done, value } = await reader.read()
+                    if (done) break
+                    buffer += decoder.decode(value, { stream: true })
+                    const events = buffer.split('\n\n')
+                    buffer = events.pop() || ''
+                    for (const block of events) {
+                        const dataLine = block.split('\n').find(line => line.startsWith('data: '))
+                        if (!dataLine) continue
+                        try {
+                            setRequests(JSON.parse(dataLine.slice(6)).items || [])
+                        } catch (e) {
+                            console.error('Invalid requests event:', e)
+                        }
+                    }
+                }
+            } catch (e) {
+                if (controller.signal.aborted) return
+                console.error('Requests feed disconnected:', e)
+            }
+            if (!controller.signal.aborted) {
+                timer = setTimeout(connect, RECONNECT_DELAY_MS)
+            }
+        }
+
+        connect()
+        return () => {
+            controller.abort()
+            clearTimeout(timer)
+        }
+    }, [])
+
+    const cancelRequest = async (id) => {
+        if (!confirm(t('accountManager.inflightCancelConfirm'))) return
+        setCancelling(prev => ({ ...prev, [id]: true }))
+        try {
+            const res = await apiFetch(`/admin/requests/${encodeURIComponent(id)}`, { method: 'DELETE' })
+            const data = await res.json().catch(() => ({}))
+            if (res.ok && data.success) {
+                onMessage('success', data.released ? t('accountManager.inflightCancelSuccess') : t('accountManager.inflightCancelPending'))
+            } else {
+                onMessage('error', data.detail || t('messages.requestFailed'))
+            }
+        } catch (e) {
+            onMessage('error', t('messages.networkError'))
+        } finally {
+            setCancelling(prev => ({ ...prev, [id]: false }))
+        }
+    }
+
+    return { requests, cancelling, cancelRequest }
+}
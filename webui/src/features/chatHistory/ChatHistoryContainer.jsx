@@ -1,17 +1,21 @@
-import { Loader2, RefreshCcw, Trash2 } from 'lucide-react'
+import { Loader2, RefreshCcw, SlidersHorizontal, Trash2 } from 'lucide-react'
 import { useEffect, useRef, useState } from 'react'
 import clsx from 'clsx'
 
 import { useI18n } from '../../i18n'
 import { ChatHistoryListPane, ConfirmClearDialog, DesktopDetailPane, MobileDetailModal } from './ChatHistoryPanels'
+import ChatHistoryPolicyPanel from './ChatHistoryPolicyPanel'
+import ChatHistorySearchBar, { EMPTY_FILTERS, buildSearchQuery, hasActiveFilters } from './ChatHistorySearchBar'
 import {
     DISABLED_LIMIT,
     LIMIT_OPTIONS,
     VIEW_MODE_KEY,
+    downloadTextFile,
 } from './chatHistoryUtils'
 
 const LIST_REFRESH_MS = 1500
 const STREAMING_DETAIL_REFRESH_MS = 750
+const SEARCH_DEBOUNCE_MS = 300
 
 export default function ChatHistoryContainer({ authFetch, onMessage }) {
     const { t, lang } = useI18n()
@@ -38,6 +42,15 @@ export default function ChatHistoryContainer({ authFetch, onMessage }) {
     const [mobileDetailVisible, setMobileDetailVisible] = useState(false)
     const [mobileOrigin, setMobileOrigin] = useState({ x: 50, y: 50 })
     const [pendingJumpToAssistant, setPendingJumpToAssistant] = useState(false)
+    const [filters, setFilters] = useState(EMPTY_FILTERS)
+    const [exporting, setExporting] = useState(false)
+    const [replayingId, setReplayingId] = useState('')
+    const [replayOriginal, setReplayOriginal] = useState(null)
+    const [policy, setPolicy] = useState(null)
+    const [detectors, setDetectors] = useState([])
+    const [policyOpen, setPolicyOpen] = useState(false)
+    const [savingPolicy, setSavingPolicy] = useState(false)
+    const [compacting, setCompacting] = useState(false)
 
     const inFlightRef = useRef(false)
     const detailInFlightRef = useRef(false)
@@ -46,6 +59,7 @@ export default function ChatHistoryContainer({ authFetch, onMessage }) {
     const assistantStartRef = useRef(null)
     const detailScrollRef = useRef(null)
     const mobileCloseTimerRef = useRef(null)
+    const filtersRef = useRef(EMPTY_FILTERS)
 
     const selectedSummary = items.find(item => item.id === selectedId) || items[0] || null
     const selectedItem = selectedDetail && selectedDetail.id === selectedId ? selectedDetail : null
@@ -71,6 +85,15 @@ export default function ChatHistoryContainer({ authFetch, onMessage }) {
             setDetail('')
         }
         try {
+            if (hasActiveFilters(filtersRef.current)) {
+                const res = await apiFetch(`/admin/chat-history/search?${buildSearchQuery(filtersRef.current)}`)
+                const data = await res.json()
+                if (!res.ok) {
+                    throw new Error(data?.detail || t('chatHistory.loadFailed'))
+                }
+                syncItems(Array.isArray(data.items) ? data.items : [])
+                return
+            }
             const headers = {}
             if (listETagRef.current) {
                 headers['If-None-Match'] = listETagRef.current
@@ -85,6 +108,8 @@ export default function ChatHistoryContainer({ authFetch, onMessage }) {
             }
             listETagRef.current = res.headers.get('ETag') || ''
             setLimit(typeof data.limit === 'number' ? data.limit : 20)
+            setPolicy({ retention: data.retention || {}, redaction: data.redaction || {} })
+            setDetectors(Array.isArray(data.detectors) ? data.detectors : [])
             syncItems(Array.isArray(data.items) ? data.items : [])
         } catch (error) {
             setDetail(error.message || t('chatHistory.loadFailed'))
@@ -135,6 +160,16 @@ export default function ChatHistoryContainer({ authFetch, onMessage }) {
         })
     }, [])
 
+    useEffect(() => {
+        filtersRef.current = filters
+        listETagRef.current = ''
+        if (!autoRefreshReady) return undefined
+        const timer = window.setTimeout(() => {
+            loadList({ mode: 'silent', announceError: true })
+        }, SEARCH_DEBOUNCE_MS)
+        return () => window.clearTimeout(timer)
+    }, [filters])
+
     useEffect(() => {
         if (!autoRefreshReady || limit === DISABLED_LIMIT) return undefined
         const timer = window.setInterval(() => {
@@ -158,6 +193,27 @@ export default function ChatHistoryContainer({ authFetch, onMessage }) {
         loadDetail(selectedId, { announceError: false })
     }, [selectedId, mobileDetailOpen])
 
+    useEffect(() => {
+        const originalId = selectedItem?.replay_of
+        if (!originalId) {
+            setReplayOriginal(null)
+            return undefined
+        }
+        if (replayOriginal?.id === originalId) return undefined
+        let cancelled = false
+        apiFetch(`/admin/chat-history/${encodeURIComponent(originalId)}`)
+            .then(res => (res.ok ? res.json() : null))
+            .then(data => {
+                if (!cancelled) setReplayOriginal(data?.item || null)
+            })
+            .catch(() => {
+                if (!cancelled) setReplayOriginal(null)
+            })
+        return () => {
+            cancelled = true
+        }
+    }, [selectedItem?.replay_of])
+
     useEffect(() => {
         if (!pendingJumpToAssistant || !selectedItem || selectedItem.id !== selectedId) return undefined
         const frame = window.requestAnimationFrame(() => {
@@ -233,6 +289,52 @@ export default function ChatHistoryContainer({ authFetch, onMessage }) {
         }
     }
 
+    const handlePolicySave = async (nextPolicy) => {
+        if (savingPolicy) return
+        setSavingPolicy(true)
+        try {
+            const res = await apiFetch('/admin/chat-history/settings', {
+                method: 'PUT',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify(nextPolicy),
+            })
+            const data = await res.json()
+            if (!res.ok) {
+                throw new Error(data?.detail || t('chatHistory.policySaveFailed'))
+            }
+            setPolicy({ retention: data.retention || {}, redaction: data.redaction || {} })
+            listETagRef.current = ''
+            syncItems(Array.isArray(data.items) ? data.items : [])
+            onMessage?.('success', t('chatHistory.policySaved'))
+        } catch (error) {
+            onMessage?.('error', error.message || t('chatHistory.policySaveFailed'))
+        } finally {
+            setSavingPolicy(false)
+        }
+    }
+
+    const handleCompact = async () => {
+        if (compacting) return
+        setCompacting(true)
+        try {
+            const res = await apiFetch('/admin/chat-history/compact', { method: 'POST' })
+            const data = await res.json()
+            if (!res.ok) {
+                throw new Error(data?.detail || t('chatHistory.compactFailed'))
+            }
+            onMessage?.('success', t('chatHistory.compactSuccess', {
+                removed: data.result?.removed || 0,
+                rewritten: data.result?.rewritten || 0,
+            }))
+            listETagRef.current = ''
+            await loadList({ mode: 'silent', announceError: false })
+        } catch (error) {
+            onMessage?.('error', error.message || t('chatHistory.compactFailed'))
+        } finally {
+            setCompacting(false)
+        }
+    }
+
     const handleDeleteItem = async (id) => {
         if (!id || deletingId) return
         setDeletingId(id)
@@ -276,6 +378,62 @@ export default function ChatHistoryContainer({ authFetch, onMessage }) {
         }
     }
 
+    const handleExport = async (format) => {
+        if (exporting || !items.length) return
+        setExporting(true)
+        try {
+            const res = await apiFetch('/admin/chat-history/export', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ format, ids: items.map(item => item.id) }),
+            })
+            if (!res.ok) {
+                const data = await res.json().catch(() => ({}))
+                throw new Error(data?.detail || t('chatHistory.exportFailed'))
+            }
+            const text = await res.text()
+            downloadTextFile(`chat-history-${format}-${Date.now()}.jsonl`, text)
+            onMessage?.('success', t('chatHistory.exportSuccess', {
+                count: res.headers.get('X-Ds2api-Export-Count') || items.length,
+                skipped: res.headers.get('X-Ds2api-Export-Skipped') || 0,
+            }))
+        } catch (error) {
+            onMessage?.('error', error.message || t('chatHistory.exportFailed'))
+        } finally {
+            setExporting(false)
+        }
+    }
+
+    const handleReplay = async (id) => {
+        if (!id || replayingId) return
+        setReplayingId(id)
+        try {
+            const res = await apiFetch(`/admin/chat-history/${encodeURIComponent(id)}/replay`, {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ stream: false }),
+            })
+            const data = await res.json()
+            if (!res.ok) {
+                throw new Error(data?.detail || t('chatHistory.replayFailed'))
+            }
+            if (data.error) {
+                onMessage?.('error', t('chatHistory.replayFailed') + ': ' + data.error)
+            } else {
+                onMessage?.('success', t('chatHistory.replaySuccess'))
+            }
+            listETagRef.current = ''
+            await loadList({ mode: 'silent', announceError: false })
+            if (data.entry_id) {
+                setSelectedId(data.entry_id)
+            }
+        } catch (error) {
+            onMessage?.('error', error.message || t('chatHistory.replayFailed'))
+        } finally {
+            setReplayingId('')
+        }
+    }
+
     const openMobileDetail = (itemId, event) => {
         const x = typeof window !== 'undefined' && event?.clientX ? (event.clientX / window.innerWidth) * 100 : 50
         const y = typeof window !== 'undefined' && event?.clientY ? (event.clientY / window.innerHeight) * 100 : 50
@@ -351,6 +509,17 @@ export default function ChatHistoryContainer({ authFetch, onMessage }) {
                             {option === DISABLED_LIMIT ? t('chatHistory.off') : option}
                         </button>
                     ))}
+                    <button
+                        type="button"
+                        onClick={() => setPolicyOpen(open => !open)}
+                        className={clsx(
+                            'h-9 w-9 rounded-lg border flex items-center justify-center',
+                            policyOpen ? 'border-primary bg-primary text-primary-foreground' : 'border-border bg-background text-muted-foreground hover:text-foreground hover:bg-secondary/70'
+                        )}
+                        title={t('chatHistory.policyTitle')}
+                    >
+                        <SlidersHorizontal className="w-4 h-4" />
+                    </button>
                     <button
                         type="button"
                         onClick={() => handleRefresh({ manual: true })}
@@ -375,6 +544,28 @@ export default function ChatHistoryContainer({ authFetch, onMessage }) {
                 </div>
             </div>
 
+            {policyOpen && (
+                <ChatHistoryPolicyPanel
+                    policy={policy}
+                    detectors={detectors}
+                    saving={savingPolicy}
+                    compacting={compacting}
+                    onSave={handlePolicySave}
+                    onCompact={handleCompact}
+                    t={t}
+                />
+            )}
+
+            <ChatHistorySearchBar
+                filters={filters}
+                onChange={setFilters}
+                onReset={() => setFilters(EMPTY_FILTERS)}
+                onExport={handleExport}
+                exporting={exporting}
+                canExport={items.length > 0}
+                t={t}
+            />
+
             {detail && (
                 <div className="rounded-xl border border-destructive/20 bg-destructive/10 text-destructive px-4 py-3 text-sm">
                     {detail}
@@ -395,6 +586,9 @@ export default function ChatHistoryContainer({ authFetch, onMessage }) {
                 <DesktopDetailPane
                     selectedSummary={selectedSummary}
                     selectedItem={selectedItem}
+                    replayOriginal={replayOriginal}
+                    replaying={Boolean(replayingId)}
+                    onReplay={handleReplay}
                     t={t}
                     lang={lang}
                     viewMode={viewMode}
@@ -410,6 +604,9 @@ export default function ChatHistoryContainer({ authFetch, onMessage }) {
                 visible={mobileDetailVisible}
                 origin={mobileOrigin}
                 selectedItem={selectedItem}
+                replayOriginal={replayOriginal}
+                replaying={Boolean(replayingId)}
+                onReplay={handleReplay}
                 t={t}
                 lang={lang}
                 viewMode={viewMode}
@@ -2,6 +2,7 @@ import { ArrowDown, Bot, ChevronDown, Clock3, Copy, Download, Sparkles, UserRoun
 import { useEffect, useRef, useState } from 'react'
 import clsx from 'clsx'
 
+import ReplayDiff from './ReplayDiff'
 import {
     MESSAGE_COLLAPSE_AT,
     buildListModeMessages,
@@ -227,12 +228,18 @@ function MetaGrid({ selectedItem, t }) {
                     <div className="text-[11px] text-muted-foreground">{t('chatHistory.metaCaller')}</div>
                     <div className="text-sm font-medium text-foreground break-all">{selectedItem.caller_id || t('chatHistory.metaUnknown')}</div>
                 </div>
+                {selectedItem.replay_of && (
+                    <div className="rounded-lg border border-border bg-card px-3 py-2">
+                        <div className="text-[11px] text-muted-foreground">{t('chatHistory.replayOf')}</div>
+                        <div className="text-sm font-medium text-foreground break-all">{selectedItem.replay_of}</div>
+                    </div>
+                )}
             </div>
         </div>
     )
 }
 
-export default function DetailConversation({ selectedItem, t, viewMode, detailScrollRef, assistantStartRef, bottomButtonClassName, onMessage }) {
+export default function DetailConversation({ selectedItem, replayOriginal, t, viewMode, detailScrollRef, assistantStartRef, bottomButtonClassName, onMessage }) {
     if (!selectedItem) return null
     const listModeState = viewMode === 'list' ? buildListModeMessages(selectedItem, t) : null
     const showHistoryAtTop = viewMode !== 'list' || !listModeState?.historyMerged
@@ -273,6 +280,10 @@ export default function DetailConversation({ selectedItem, t, viewMode, detailSc
                 </div>
             </div>
 
+            {selectedItem.replay_of && replayOriginal?.id === selectedItem.replay_of && (
+                <ReplayDiff original={replayOriginal} replay={selectedItem} t={t} />
+            )}
+
             <MetaGrid selectedItem={selectedItem} t={t} />
 
             <button
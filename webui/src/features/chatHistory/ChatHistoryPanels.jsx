@@ -1,4 +1,4 @@
-import { ArrowUp, Loader2, MessageSquareText, Trash2, X } from 'lucide-react'
+import { ArrowUp, Loader2, MessageSquareText, RotateCcw, Trash2, X } from 'lucide-react'
 import clsx from 'clsx'
 
 import DetailConversation from './ChatHistoryDetail'
@@ -102,7 +102,22 @@ export function ChatHistoryListPane({ items, selectedItem, deletingId, t, lang,
     )
 }
 
-export function DesktopDetailPane({ selectedSummary, selectedItem, t, lang, viewMode, setViewMode, detailScrollRef, assistantStartRef, onMessage }) {
+function ReplayButton({ selectedItem, replaying, onReplay, t, className }) {
+    if (!selectedItem?.final_prompt || !onReplay) return null
+    return (
+        <button
+            type="button"
+            onClick={() => onReplay(selectedItem.id)}
+            disabled={replaying}
+            className={clsx('rounded-lg border border-border bg-background text-muted-foreground hover:text-foreground hover:bg-secondary/70 disabled:opacity-50 flex items-center justify-center', className)}
+            title={replaying ? t('chatHistory.replayRunning') : t('chatHistory.replay')}
+        >
+            {replaying ? <Loader2 className="w-4 h-4 animate-spin" /> : <RotateCcw className="w-4 h-4" />}
+        </button>
+    )
+}
+
+export function DesktopDetailPane({ selectedSummary, selectedItem, replayOriginal, replaying, onReplay, t, lang, viewMode, setViewMode, detailScrollRef, assistantStartRef, onMessage }) {
     return (
         <div className="hidden lg:flex rounded-2xl border border-border bg-card shadow-sm min-h-0 overflow-hidden flex-col relative">
             <div className="px-5 py-4 border-b border-border flex items-center justify-between gap-3">
@@ -114,6 +129,7 @@ export function DesktopDetailPane({ selectedSummary, selectedItem, t, lang, view
                 </div>
                 <div className="flex items-center gap-2">
                     <ViewModeToggle t={t} viewMode={viewMode} setViewMode={setViewMode} />
+                    <ReplayButton selectedItem={selectedItem} replaying={replaying} onReplay={onReplay} t={t} className="h-8 w-8" />
                     <button
                         type="button"
                         onClick={() => detailScrollRef.current?.scrollTo({ top: 0, behavior: 'smooth' })}
@@ -140,6 +156,7 @@ export function DesktopDetailPane({ selectedSummary, selectedItem, t, lang, view
                 {selectedItem && (
                     <DetailConversation
                         selectedItem={selectedItem}
+                        replayOriginal={replayOriginal}
                         t={t}
                         viewMode={viewMode}
                         detailScrollRef={detailScrollRef}
@@ -153,7 +170,7 @@ export function DesktopDetailPane({ selectedSummary, selectedItem, t, lang, view
     )
 }
 
-export function MobileDetailModal({ open, visible, origin, selectedItem, t, lang, viewMode, setViewMode, detailScrollRef, assistantStartRef, onClose }) {
+export function MobileDetailModal({ open, visible, origin, selectedItem, replayOriginal, replaying, onReplay, t, lang, viewMode, setViewMode, detailScrollRef, assistantStartRef, onClose }) {
     if (!open || !selectedItem) return null
 
     return (
@@ -181,6 +198,7 @@ export function MobileDetailModal({ open, visible, origin, selectedItem, t, lang
                     </div>
                     <div className="flex items-center gap-2">
                         <ViewModeToggle t={t} viewMode={viewMode} setViewMode={setViewMode} mobile />
+                        <ReplayButton selectedItem={selectedItem} replaying={replaying} onReplay={onReplay} t={t} className="h-9 w-9" />
                         <button
                             type="button"
                             onClick={() => detailScrollRef.current?.scrollTo({ top: 0, behavior: 'smooth' })}
@@ -203,6 +221,7 @@ export function MobileDetailModal({ open, visible, origin, selectedItem, t, lang
                 <div ref={detailScrollRef} className="flex-1 overflow-y-auto p-5 space-y-6">
                     <DetailConversation
                         selectedItem={selectedItem}
+                        replayOriginal={replayOriginal}
                         t={t}
                         viewMode={viewMode}
                         detailScrollRef={detailScrollRef}
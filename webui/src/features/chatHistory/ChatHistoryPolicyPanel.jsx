@@ -0,0 +1,130 @@
+import { useEffect, useState } from 'react'
+import { Eraser, Loader2, Save } from 'lucide-react'
+import clsx from 'clsx'
+
+const MB = 1024 * 1024
+
+function rulesToText(rules = []) {
+    return rules.map(rule => [rule.name || '', rule.pattern, rule.replacement || ''].join(' | ').replace(/( \| )+$/, '')).join('\n')
+}
+
+function textToRules(text) {
+    return String(text || '')
+        .split('\n')
+        .map(line => line.trim())
+        .filter(Boolean)
+        .map(line => {
+            const parts = line.split(' | ')
+            if (parts.length === 1) return { pattern: parts[0] }
+            return { name: parts[0].trim(), pattern: parts[1] || '', replacement: parts.slice(2).join(' | ') }
+        })
+}
+
+export default function ChatHistoryPolicyPanel({ policy, detectors = [], saving, compacting, onSave, onCompact, t }) {
+    const [form, setForm] = useState({ maxAgeHours: '', maxTotalMb: '', maxEntryChars: '', detectors: [], rules: '' })
+
+    useEffect(() => {
+        const retention = policy?.retention || {}
+        const redaction = policy?.redaction || {}
+        setForm({
+            maxAgeHours: retention.max_age_hours ? String(retention.max_age_hours) : '',
+            maxTotalMb: retention.max_total_bytes ? String(Math.round((retention.max_total_bytes / MB) * 100) / 100) : '',
+            maxEntryChars: retention.max_entry_chars ? String(retention.max_entry_chars) : '',
+            detectors: Array.isArray(redaction.detectors) ? redaction.detectors : [],
+            rules: rulesToText(redaction.rules),
+        })
+    }, [policy])
+
+    const toggleDetector = (name) => {
+        setForm(prev => ({
+            ...prev,
+            detectors: prev.detectors.includes(name) ? prev.detectors.filter(item => item !== name) : [...prev.detectors, name],
+        }))
+    }
+
+    const submit = () => {
+        onSave({
+            retention: {
+                max_age_hours: Number(form.maxAgeHours) || 0,
+                max_total_bytes: Math.round((Number(form.maxTotalMb) || 0) * MB),
+                max_entry_chars: Number(form.maxEntryChars) || 0,
+            },
+            redaction: { detectors: form.detectors, rules: textToRules(form.rules) },
+        })
+    }
+
+    const inputClass = 'h-9 w-full rounded-lg border border-border bg-background px-3 text-sm text-foreground placeholder:text-muted-foreground/50 focus:outline-none focus:ring-2 focus:ring-primary/20'
+
+    return (
+        <div className="rounded-2xl border border-border bg-card shadow-sm p-4 lg:p-5 space-y-4">
+            <div>
+                <div className="text-sm font-semibold text-foreground">{t('chatHistory.policyTitle')}</div>
+                <div className="text-xs text-muted-foreground mt-1">{t('chatHistory.policyDesc')}</div>
+            </div>
+            <div className="grid grid-cols-1 md:grid-cols-3 gap-3">
+                <label className="space-y-1">
+                    <span className="text-[11px] text-muted-foreground">{t('chatHistory.policyMaxAge')}</span>
+                    <input type="number" min="0" value={form.maxAgeHours} onChange={e => setForm({ ...form, maxAgeHours: e.target.value })} placeholder="0" className={inputClass} />
+                </label>
+                <label className="space-y-1">
+                    <span className="text-[11px] text-muted-foreground">{t('chatHistory.policyMaxBytes')}</span>
+                    <input type="number" min="0" step="0.5" value={form.maxTotalMb} onChange={e => setForm({ ...form, maxTotalMb: e.target.value })} placeholder="0" className={inputClass} />
+                </label>
+                <label className="space-y-1">
+                    <span className="text-[11px] text-muted-foreground">{t('chatHistory.policyMaxChars')}</span>
+                    <input type="number" min="0" value={form.maxEntryChars} onChange={e => setForm({ ...form, maxEntryChars: e.target.value })} placeholder="0" className={inputClass} />
+                </label>
+            </div>
+            <div className="space-y-2">
+                <div className="text-[11px] text-muted-foreground">{t('chatHistory.policyDetectors')}</div>
+                <div className="flex flex-wrap gap-2">
+                    {detectors.map(name => (
+                        <button
+                            key={name}
+                            type="button"
+                            onClick={() => toggleDetector(name)}
+                            className={clsx(
+                                'h-8 px-3 rounded-lg border text-xs transition-colors',
+                                form.detectors.includes(name)
+                                    ? 'border-primary bg-primary text-primary-foreground'
+                                    : 'border-border bg-background text-muted-foreground hover:text-foreground'
+                            )}
+                        >
+                            {t(`chatHistory.detector.${name}`)}
+                        </button>
+                    ))}
+                </div>
+            </div>
+            <label className="block space-y-1">
+                <span className="text-[11px] text-muted-foreground">{t('chatHistory.policyRules')}</span>
+                <textarea
+                    value={form.rules}
+                    onChange={e => setForm({ ...form, rules: e.target.value })}
+                    rows={3}
+                    placeholder="ticket | TICKET-\d+ | [TICKET]"
+                    className="w-full rounded-lg border border-border bg-background px-3 py-2 text-sm font-mono text-foreground placeholder:text-muted-foreground/50 focus:outline-none focus:ring-2 focus:ring-primary/20"
+                />
+            </label>
+            <div className="flex flex-wrap justify-end gap-2">
+                <button
+                    type="button"
+                    onClick={onCompact}
+                    disabled={compacting}
+                    className="h-9 px-3 rounded-lg border border-border bg-background text-sm text-muted-foreground hover:text-foreground disabled:opacity-50 flex items-center gap-2"
+                >
+                    {compacting ? <Loader2 className="w-4 h-4 animate-spin" /> : <Eraser className="w-4 h-4" />}
+                    {t('chatHistory.compactNow')}
+                </button>
+                <button
+                    type="button"
+                    onClick={submit}
+                    disabled={saving}
+                    className="h-9 px-3 rounded-lg border border-primary bg-primary text-sm text-primary-foreground disabled:opacity-50 flex items-center gap-2"
+                >
+                    {saving ? <Loader2 className="w-4 h-4 animate-spin" /> : <Save className="w-4 h-4" />}
+                    {t('chatHistory.policySave')}
+                </button>
+            </div>
+        </div>
+    )
+}
@@ -0,0 +1,78 @@
+import { Download, Loader2, Search, X } from 'lucide-react'
+
+const STATUS_OPTIONS = ['', 'success', 'error', 'stopped', 'streaming']
+
+export const EMPTY_FILTERS = { q: '', status: '', surface: '', model: '' }
+
+export function hasActiveFilters(filters) {
+    return Object.values(filters).some(value => String(value || '').trim() !== '')
+}
+
+export function buildSearchQuery(filters) {
+    const params = new URLSearchParams()
+    Object.entries(filters).forEach(([key, value]) => {
+        const trimmed = String(value || '').trim()
+        if (trimmed) params.set(key, trimmed)
+    })
+    params.set('limit', '100')
+    return params.toString()
+}
+
+export default function ChatHistorySearchBar({ filters, onChange, onReset, onExport, exporting, canExport, t }) {
+    const update = (key) => (event) => onChange({ ...filters, [key]: event.target.value })
+    const inputClass = 'h-9 rounded-lg border border-border bg-background px-3 text-sm text-foreground placeholder:text-muted-foreground/50 focus:outline-none focus:ring-2 focus:ring-primary/20'
+
+    return (
+        <div className="rounded-2xl border border-border bg-card shadow-sm p-4 flex flex-col gap-3 lg:flex-row lg:items-center">
+            <div className="relative flex-1 min-w-0">
+                <Search className="w-4 h-4 absolute left-3 top-1/2 -translate-y-1/2 text-muted-foreground" />
+                <input
+                    type="search"
+                    value={filters.q}
+                    onChange={update('q')}
+                    placeholder={t('chatHistory.searchPlaceholder')}
+                    className={`${inputClass} w-full pl-9`}
+                />
+            </div>
+            <div className="flex flex-wrap gap-2 items-center">
+                <select value={filters.status} onChange={update('status')} className={inputClass}>
+                    {STATUS_OPTIONS.map(option => (
+                        <option key={option || 'all'} value={option}>
+                            {option ? option : t('chatHistory.filterAnyStatus')}
+                        </option>
+                    ))}
+                </select>
+                <input value={filters.surface} onChange={update('surface')} placeholder={t('chatHistory.filterSurface')} className={`${inputClass} w-36`} />
+                <input value={filters.model} onChange={update('model')} placeholder={t('chatHistory.filterModel')} className={`${inputClass} w-40`} />
+                {hasActiveFilters(filters) && (
+                    <button
+                        type="button"
+                        onClick={onReset}
+                        className="h-9 w-9 rounded-lg border border-border bg-background text-muted-foreground hover:text-foreground flex items-center justify-center"
+                        title={t('chatHistory.clearFilters')}
+                    >
+                        <X className="w-4 h-4" />
+                    </button>
+                )}
+                <button
+                    type="button"
+                    onClick={() => onExport('jsonl')}
+                    disabled={!canExport || exporting}
+                    className="h-9 px-3 rounded-lg border border-border bg-background text-sm text-muted-foreground hover:text-foreground disabled:opacity-50 flex items-center gap-2"
+                >
+                    {exporting ? <Loader2 className="w-4 h-4 animate-spin" /> : <Download className="w-4 h-4" />}
+                    {t('chatHistory.exportJsonl')}
+                </button>
+                <button
+                    type="button"
+                    onClick={() => onExport('openai')}
+                    disabled={!canExport || exporting}
+                    className="h-9 px-3 rounded-lg border border-border bg-background text-sm text-muted-foreground hover:text-foreground disabled:opacity-50 flex items-center gap-2"
+                >
+                    <Download className="w-4 h-4" />
+                    {t('chatHistory.exportFineTune')}
+                </button>
+            </div>
+        </div>
+    )
+}
@@ -0,0 +1,44 @@
+import clsx from 'clsx'
+
+import { diffLines } from './chatHistoryUtils'
+
+function outputText(item) {
+    if (!item) return ''
+    return item.status === 'error' ? (item.error || '') : (item.content || '')
+}
+
+export default function ReplayDiff({ original, replay, t }) {
+    if (!original || !replay) return null
+    const rows = diffLines(outputText(original), outputText(replay))
+    const left = rows.filter(row => row.type !== 'added')
+    const right = rows.filter(row => row.type !== 'removed')
+
+    const column = (title, subtitle, lines, changedType, tone) => (
+        <div className="rounded-lg border border-border bg-card min-w-0">
+            <div className="px-3 py-2 border-b border-border">
+                <div className="text-[11px] text-muted-foreground">{title}</div>
+                <div className="text-xs font-medium text-foreground break-all">{subtitle}</div>
+            </div>
+            <div className="p-3 font-mono text-[12px] leading-6 max-h-80 overflow-y-auto custom-scrollbar">
+                {lines.map((row, index) => (
+                    <div
+                        key={index}
+                        className={clsx('whitespace-pre-wrap break-words px-1 rounded', row.type === changedType && tone)}
+                    >
+                        {row.text || ' '}
+                    </div>
+                ))}
+            </div>
+        </div>
+    )
+
+    return (
+        <div className="max-w-4xl mx-auto rounded-xl border border-border bg-background/70 p-4 space-y-3">
+            <div className="text-xs font-semibold uppercase tracking-[0.12em] text-muted-foreground">{t('chatHistory.diffTitle')}</div>
+            <div className="grid grid-cols-1 md:grid-cols-2 gap-3">
+                {column(t('chatHistory.diffOriginal'), `${original.model || '-'} · ${original.account_id || '-'}`, left, 'removed', 'bg-destructive/10 text-destructive')}
+                {column(t('chatHistory.diffReplay'), `${replay.model || '-'} · ${replay.account_id || '-'}`, right, 'added', 'bg-emerald-500/10 text-emerald-600')}
+            </div>
+        </div>
+    )
+}
@@ -72,6 +72,38 @@ export function statusTone(status) {
     }
 }
 
+// diffLines returns a line-level LCS diff between two texts. Each row is
+// tagged same/removed/added so the replay view can render it side by side.
+export function diffLines(before = '', after = '') {
+    const a = String(before || '').split('\n')
+    const b = String(after || '').split('\n')
+    const table = Array.from({ length: a.length + 1 }, () => new Array(b.length + 1).fill(0))
+    for (let i = a.length - 1; i >= 0; i -= 1) {
+        for (let j = b.length - 1; j >= 0; j -= 1) {
+            table[i][j] = a[i] === b[j] ? table[i + 1][j + 1] + 1 : Math.max(table[i + 1][j], table[i][j + 1])
+        }
+    }
+    const rows = []
+    let i = 0
+    let j = 0
+    while (i < a.length && j < b.length) {
+        if (a[i] === b[j]) {
+            rows.push({ type: 'same', text: a[i] })
+            i += 1
+            j += 1
+        } else if (table[i + 1][j] >= table[i][j + 1]) {
+            rows.push({ type: 'removed', text: a[i] })
+            i += 1
+        } else {
+            rows.push({ type: 'added', text: b[j] })
+            j += 1
+        }
+    }
+    while (i < a.length) rows.push({ type: 'removed', text: a[i++] })
+    while (j < b.length) rows.push({ type: 'added', text: b[j++] })
+    return rows
+}
+
 export function downloadTextFile(filename, text) {
     const blob = new Blob([text], { type: 'text/plain;charset=utf-8' })
     const url = URL.createObjectURL(blob)
@@ -3,6 +3,7 @@ import { AlertTriangle, Save } from 'lucide-react'
 import { useI18n } from '../../i18n'
 import { useSettingsForm } from './useSettingsForm'
 import SecuritySection from './SecuritySection'
+import UsersSection from './UsersSection'
 import RuntimeSection from './RuntimeSection'
 import BehaviorSection from './BehaviorSection'
 import CurrentInputFileSection from './CurrentInputFileSection'
@@ -91,6 +92,8 @@ export default function SettingsContainer({ onRefresh, onMessage, authFetch, onF
                 onUpdatePassword={updatePassword}
             />
 
+            <UsersSection t={t} apiFetch={apiFetch} onMessage={onMessage} />
+
             <RuntimeSection t={t} form={form} setForm={setForm} />
 
             <BehaviorSection t={t} form={form} setForm={setForm} />
@@ -0,0 +1,238 @@
+import { useCallback, useEffect, useState } from 'react'
+import { KeyRound, Trash2, UserPlus, Users } from 'lucide-react'
+
+import {
+    deleteAdminToken,
+    deleteAdminUser,
+    fetchAdminTokens,
+    fetchAdminUsers,
+    postAdminToken,
+    postAdminUser,
+    putAdminUser,
+} from './settingsApi'
+
+const ROLES = ['viewer', 'operator', 'owner']
+
+function roleLabel(t, role) {
+    if (role === 'owner') return t('settings.roleOwner')
+    if (role === 'operator') return t('settings.roleOperator')
+    return t('settings.roleViewer')
+}
+
+export default function UsersSection({ t, apiFetch, onMessage }) {
+    const [users, setUsers] = useState([])
+    const [tokens, setTokens] = useState([])
+    const [forbidden, setForbidden] = useState(false)
+    const [newUser, setNewUser] = useState({ username: '', password: '', role: 'viewer' })
+    const [newToken, setNewToken] = useState({ name: '', role: 'viewer', expires_in_days: 0 })
+    const [createdToken, setCreatedToken] = useState('')
+    const [busy, setBusy] = useState(false)
+
+    const load = useCallback(async () => {
+        try {
+            const [usersResult, tokensResult] = await Promise.all([fetchAdminUsers(apiFetch), fetchAdminTokens(apiFetch)])
+            if (usersResult.res.status === 403 || tokensResult.res.status === 403) {
+                setForbidden(true)
+                return
+            }
+            setUsers(usersResult.data.items || [])
+            setTokens(tokensResult.data.items || [])
+        } catch (e) {
+            onMessage('error', e.message)
+        }
+    }, [apiFetch, onMessage])
+
+    useEffect(() => {
+        load()
+    }, [load])
+
+    const run = async (action) => {
+        setBusy(true)
+        try {
+            const { res, data } = await action()
+            if (!res.ok) {
+                onMessage('error', data.detail || t('settings.userSaveFailed'))
+                return null
+            }
+            await load()
+            return data
+        } catch (e) {
+            onMessage('error', e.message)
+            return null
+        } finally {
+            setBusy(false)
+        }
+    }
+
+    const addUser = async () => {
+        const data = await run(() => postAdminUser(apiFetch, newUser))
+        if (data) {
+            setNewUser({ username: '', password: '', role: 'viewer' })
+            onMessage('success', t('settings.userSaved'))
+        }
+    }
+
+    const addToken = async () => {
+        const data = await run(() => postAdminToken(apiFetch, { ...newToken, expires_in_days: Number(newToken.expires_in_days || 0) }))
+        if (data) {
+            setCreatedToken(data.token?.token || '')
+            setNewToken({ name: '', role: 'viewer', expires_in_days: 0 })
+        }
+    }
+
+    if (forbidden) {
+        return (
+            <div className="bg-card border border-border rounded-xl p-5 space-y-2">
+                <h3 className="font-semibold">{t('settings.usersTitle')}</h3>
+                <p className="text-sm text-muted-foreground">{t('settings.forbidden')}</p>
+            </div>
+        )
+    }
+
+    const inputClass = 'w-full bg-background border border-border rounded-lg px-3 py-2 text-sm'
+
+    return (
+        <div className="bg-card border border-border rounded-xl p-5 space-y-6">
+            <div className="space-y-2">
+                <div className="flex items-center gap-2">
+                    <Users className="w-4 h-4 text-muted-foreground" />
+                    <h3 className="font-semibold">{t('settings.usersTitle')}</h3>
+                </div>
+                <p className="text-sm text-muted-foreground">{t('settings.usersDesc')}</p>
+            </div>
+
+            <div className="space-y-2">
+                {users.length === 0 && <p className="text-sm text-muted-foreground">{t('settings.noUsers')}</p>}
+                {users.map((user) => (
+                    <div key={user.username} className="flex flex-wrap items-center gap-2 border border-border rounded-lg px-3 py-2 text-sm">
+                        <span className={`font-medium ${user.disabled ? 'line-through text-muted-foreground' : ''}`}>{user.username}</span>
+                        <select
+                            value={user.role}
+                            disabled={busy}
+                            onChange={(e) => run(() => putAdminUser(apiFetch, user.username, { role: e.target.value }))}
+                            className="bg-background border border-border rounded-md px-2 py-1 text-xs"
+                        >
+                            {ROLES.map((role) => <option key={role} value={role}>{roleLabel(t, role)}</option>)}
+                        </select>
+                        <div className="ml-auto flex gap-2">
+                            <button
+                                type="button"
+                                disabled={busy}
+                                onClick={() => run(() => putAdminUser(apiFetch, user.username, { disabled: !user.disabled }))}
+                                className="px-2 py-1 rounded-md border border-border text-xs hover:bg-secondary"
+                            >
+                                {user.disabled ? t('settings.enableUser') : t('settings.disableUser')}
+                            </button>
+                            <button
+                                type="button"
+                                disabled={busy}
+                                onClick={() => run(() => deleteAdminUser(apiFetch, user.username))}
+                                className="px-2 py-1 rounded-md border border-destructive/40 text-destructive text-xs hover:bg-destructive/10 flex items-center gap-1"
+                            >
+                                <Trash2 className="w-3 h-3" />
+                                {t('settings.deleteUser')}
+                            </button>
+                        </div>
+                    </div>
+                ))}
+                <div className="grid grid-cols-1 md:grid-cols-4 gap-2">
+                    <input
+                        type="text"
+                        value={newUser.username}
+                        onChange={(e) => setNewUser((prev) => ({ ...prev, username: e.target.value }))}
+                        placeholder={t('settings.username')}
+                        className={inputClass}
+                    />
+                    <input
+                        type="password"
+                        value={newUser.password}
+                        onChange={(e) => setNewUser((prev) => ({ ...prev, password: e.target.value }))}
+                        placeholder={t('settings.password')}
+                        className={inputClass}
+                    />
+                    <select
+                        value={newUser.role}
+                        onChange={(e) => setNewUser((prev) => ({ ...prev, role: e.target.value }))}
+                        className={inputClass}
+                    >
+                        {ROLES.map((role) => <option key={role} value={role}>{roleLabel(t, role)}</option>)}
+                    </select>
+                    <button
+                        type="button"
+                        onClick={addUser}
+                        disabled={busy}
+                        className="px-3 py-2 rounded-lg bg-secondary border border-border hover:bg-secondary/80 text-sm flex items-center justify-center gap-1"
+                    >
+                        <UserPlus className="w-4 h-4" />
+                        {t('settings.addUser')}
+                    </button>
+                </div>
+            </div>
+
+            <div className="space-y-2 pt-4 border-t border-border">
+                <div className="flex items-center gap-2">
+                    <KeyRound className="w-4 h-4 text-muted-foreground" />
+                    <h4 className="font-semibold text-sm">{t('settings.tokensTitle')}</h4>
+                </div>
+                <p className="text-sm text-muted-foreground">{t('settings.tokensDesc')}</p>
+                {createdToken && (
+                    <div className="p-3 rounded-lg border border-amber-300/30 bg-amber-500/10 text-amber-700 text-xs space-y-1">
+                        <div>{t('settings.tokenCreated')}</div>
+                        <code className="break-all">{createdToken}</code>
+                    </div>
+                )}
+                {tokens.length === 0 && <p className="text-sm text-muted-foreground">{t('settings.noTokens')}</p>}
+                {tokens.map((token) => (
+                    <div key={token.id} className="flex flex-wrap items-center gap-2 border border-border rounded-lg px-3 py-2 text-sm">
+                        <span className="font-medium">{token.name || token.id}</span>
+                        <span className="text-xs text-muted-foreground">{roleLabel(t, token.role)}</span>
+                        {token.expired && <span className="text-xs text-destructive">{t('settings.tokenExpired')}</span>}
+                        <button
+                            type="button"
+                            disabled={busy}
+                            onClick={() => run(() => deleteAdminToken(apiFetch, token.id))}
+                            className="ml-auto px-2 py-1 rounded-md border border-destructive/40 text-destructive text-xs hover:bg-destructive/10"
+                        >
+                            {t('settings.revokeToken')}
+                        </button>
+                    </div>
+                ))}
+                <div className="grid grid-cols-1 md:grid-cols-4 gap-2">
+                    <input
+                        type="text"
+                        value={newToken.name}
+                        onChange={(e) => setNewToken((prev) => ({ ...prev, name: e.target.value }))}
+                        placeholder={t('settings.tokenName')}
+                        className={inputClass}
+                    />
+                    <select
+                        value={newToken.role}
+                        onChange={(e) => setNewToken((prev) => ({ ...prev, role: e.target.value }))}
+                        className={inputClass}
+                    >
+                        {ROLES.map((role) => <option key={role} value={role}>{roleLabel(t, role)}</option>)}
+                    </select>
+                    <input
+                        type="number"
+                        min={0}
+                        max={3650}
+                        value={newToken.expires_in_days}
+                        onChange={(e) => setNewToken((prev) => ({ ...prev, expires_in_days: e.target.value }))}
+                        title={t('settings.tokenExpiresDays')}
+                        placeholder={t('settings.tokenExpiresDays')}
+                        className={inputClass}
+                    />
+                    <button
+                        type="button"
+                        onClick={addToken}
+                        disabled={busy}
+                        className="px-3 py-2 rounded-lg bg-secondary border border-border hover:bg-secondary/80 text-sm flex items-center justify-center gap-1"
+                    >
+                        <KeyRound className="w-4 h-4" />
+                        {t('settings.createToken')}
+                    </button>
+                </div>
+            </div>
+        </div>
+    )
+}
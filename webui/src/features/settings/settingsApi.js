@@ -47,3 +47,57 @@ export async function postImportData(apiFetch, mode, config) {
     const data = await res.json()
     return { res, data }
 }
+
+export async function fetchAdminUsers(apiFetch) {
+    const res = await apiFetch('/admin/users')
+    const data = await res.json()
+    return { res, data }
+}
+
+export async function postAdminUser(apiFetch, payload) {
+    const res = await apiFetch('/admin/users', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(payload),
+    })
+    const data = await res.json()
+    return { res, data }
+}
+
+export async function putAdminUser(apiFetch, username, payload) {
+    const res = await apiFetch(`/admin/users/${encodeURIComponent(username)}`, {
+        method: 'PUT',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(payload),
+    })
+    const data = await res.json()
+    return { res, data }
+}
+
+export async function deleteAdminUser(apiFetch, username) {
+    const res = await apiFetch(`/admin/users/${encodeURIComponent(username)}`, { method: 'DELETE' })
+    const data = await res.json()
+    return { res, data }
+}
+
+export async function fetchAdminTokens(apiFetch) {
+    const res = await apiFetch('/admin/tokens')
+    const data = await res.json()
+    return { res, data }
+}
+
+export async function postAdminToken(apiFetch, payload) {
+    const res = await apiFetch('/admin/tokens', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(payload),
+    })
+    const data = await res.json()
+    return { res, data }
+}
+
+export async function deleteAdminToken(apiFetch, id) {
+    const res = await apiFetch(`/admin/tokens/${encodeURIComponent(id)}`, { method: 'DELETE' })
+    const data = await res.json()
+    return { res, data }
+}